@@ -0,0 +1,109 @@
+// Command audit-verify walks the audit log hash chain from the oldest event,
+// checks every signed checkpoint and reports the first broken link. It reads
+// the same environment as the server and exits with 1 when the chain is
+// broken, 2 when it could not be checked and 3 when it is intact but some
+// checkpoints were signed with keys the keyring no longer has. Pass an
+// archived copy of the JWKS with -jwks to verify those.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/keys"
+
+	"go.uber.org/fx"
+)
+
+const stopTimeout = 10 * time.Second
+
+func main() {
+	jwksPath := flag.String("jwks", "", "archived JWKS to verify checkpoints signed with deleted keys")
+	flag.Parse()
+
+	var verifier audit.VerifierInterface
+
+	app := fx.New(
+		config.Module,
+		database.Module,
+		keys.KeyringModule,
+		fx.Provide(
+			audit.NewRepository,
+			audit.NewVerifier,
+		),
+		fx.Populate(&verifier),
+		fx.NopLogger,
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start:", err)
+		os.Exit(2)
+	}
+
+	code := 2
+	if err := pin(verifier, *jwksPath); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to load JWKS:", err)
+	} else {
+		code = run(ctx, verifier)
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, stopTimeout)
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop:", err)
+	}
+
+	os.Exit(code)
+}
+
+func pin(verifier audit.VerifierInterface, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var set keys.JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+	return verifier.Pin(set)
+}
+
+func run(ctx context.Context, verifier audit.VerifierInterface) int {
+	report, err := verifier.Verify(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to verify audit chain:", err)
+		return 2
+	}
+
+	fmt.Printf("chained events verified: %d\n", report.Verified)
+	fmt.Printf("unchained events before the chain: %d\n", report.Unchained)
+	fmt.Printf("checkpoints verified: %d\n", report.Checkpoints)
+	if report.Unverified > 0 {
+		fmt.Printf("checkpoints signed with unknown keys: %d (pass an archived JWKS with -jwks)\n", report.Unverified)
+	}
+
+	if report.Broken != nil {
+		fmt.Printf("BROKEN at seq %d: %s\n", report.Broken.Seq, report.Broken.Reason)
+		return 1
+	}
+
+	if report.Unverified > 0 {
+		fmt.Printf("UNVERIFIED: chain consistent up to seq %d, but not every checkpoint could be verified\n", report.Head)
+		return 3
+	}
+
+	fmt.Printf("chain intact up to seq %d\n", report.Head)
+	return 0
+}
@@ -1,10 +1,35 @@
 package main
 
 import (
+	"github.com/felipeversiane/auth-service/internal/accounttoken"
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/federation"
 	"github.com/felipeversiane/auth-service/internal/infra/config"
 	"github.com/felipeversiane/auth-service/internal/infra/database"
 	"github.com/felipeversiane/auth-service/internal/infra/http"
+	"github.com/felipeversiane/auth-service/internal/infra/mailer"
+	"github.com/felipeversiane/auth-service/internal/infra/ratelimit"
 	"github.com/felipeversiane/auth-service/internal/infra/telemetry"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/ldapauth"
+	"github.com/felipeversiane/auth-service/internal/mfa"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/oauth"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/revocation"
+	"github.com/felipeversiane/auth-service/internal/saml"
+	"github.com/felipeversiane/auth-service/internal/samlidp"
+	"github.com/felipeversiane/auth-service/internal/scim"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/serviceaccount"
+	"github.com/felipeversiane/auth-service/internal/throttle"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/internal/webhook"
 
 	"go.uber.org/fx"
 )
@@ -15,7 +40,32 @@ func main() {
 		config.Module,
 		database.Module,
 		telemetry.Module,
+		mailer.Module,
+		security.Module,
+		keys.Module,
+		revocation.Module,
+		token.Module,
+		middleware.Module,
+		ratelimit.Module,
 		http.Module,
+		audit.Module,
+		outbox.Module,
+		webhook.Module,
+		accounttoken.Module,
+		user.Module,
+		rbac.Module,
+		org.Module,
+		scim.Module,
+		passkey.Module,
+		mfa.Module,
+		throttle.Module,
+		auth.Module,
+		saml.Module,
+		samlidp.Module,
+		federation.Module,
+		ldapauth.Module,
+		serviceaccount.Module,
+		oauth.Module,
 		fx.NopLogger,
 	)
 
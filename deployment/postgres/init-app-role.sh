@@ -0,0 +1,12 @@
+#!/bin/sh
+# Creates the login role the server connects as (DB_USER) as a member of
+# auth_service_app, which migration 000053 grants table access to. The
+# postgres image runs this once, when the data volume is first initialized;
+# an existing volume needs the same statements run by hand.
+set -eu
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	--set app_user="$DB_USER" --set app_password="$DB_PASSWORD" <<'SQL'
+CREATE ROLE auth_service_app NOLOGIN NOSUPERUSER NOBYPASSRLS;
+CREATE ROLE :"app_user" LOGIN NOSUPERUSER NOBYPASSRLS PASSWORD :'app_password' IN ROLE auth_service_app;
+SQL
@@ -0,0 +1,9 @@
+package accounttoken
+
+import "go.uber.org/fx"
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+	),
+)
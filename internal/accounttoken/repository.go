@@ -0,0 +1,95 @@
+package accounttoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAccountTokenNotFound covers unknown, expired and already used tokens,
+// which callers report the same way.
+var ErrAccountTokenNotFound = errors.New("account token not found")
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, token domain.AccountTokenInterface) error
+	Consume(ctx context.Context, tokenHash, purpose string) (domain.AccountTokenInterface, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+// Create stores the token and drops any unused token the user still holds
+// for the same purpose, so only the most recent link works.
+func (r *repository) Create(ctx context.Context, token domain.AccountTokenInterface) error {
+	query := `
+		WITH superseded AS (
+			DELETE FROM account_tokens
+			WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL
+		)
+		INSERT INTO account_tokens (id, user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		token.GetID(),
+		token.GetUserID(),
+		token.GetPurpose(),
+		token.GetTokenHash(),
+		token.GetExpiresAt(),
+		token.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert account token: %w", err)
+	}
+
+	return nil
+}
+
+// Consume marks a live token as used in a single statement, so concurrent
+// requests with the same link cannot both succeed.
+func (r *repository) Consume(ctx context.Context, tokenHash, purpose string) (domain.AccountTokenInterface, error) {
+	query := `
+		UPDATE account_tokens
+		SET used_at = $3
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at`
+
+	var (
+		id, userID           uuid.UUID
+		tokenPurpose, hash   string
+		expiresAt, createdAt time.Time
+		usedAt               *time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash, purpose, time.Now().UTC()).Scan(
+		&id, &userID, &tokenPurpose, &hash, &expiresAt, &usedAt, &createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to consume account token: %w", err)
+	}
+
+	return domain.RestoreAccountToken(id, userID, tokenPurpose, hash, expiresAt, usedAt, createdAt), nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM account_tokens WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired account tokens: %w", err)
+	}
+	return nil
+}
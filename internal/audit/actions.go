@@ -0,0 +1,93 @@
+package audit
+
+// Audited actions, named "<area>.<verb>".
+const (
+	ActionUserRegister    = "user.register"
+	ActionUserVerifyEmail = "user.verify_email"
+	ActionUserDelete      = "user.delete"
+
+	ActionLogin         = "auth.login"
+	ActionLoginLockout  = "auth.lockout"
+	ActionLoginUnlock   = "auth.unlock"
+	ActionPasswordReset = "auth.password_reset"
+	ActionTokenRevoke   = "auth.token_revoke"
+	ActionTokenReuse    = "auth.token_reuse"
+	ActionSwitchOrg     = "auth.switch_org"
+	ActionMFAEnroll     = "mfa.totp_enroll"
+	ActionMFAConfirm    = "mfa.totp_confirm"
+	ActionMFADisable    = "mfa.totp_disable"
+	ActionMFARecovery   = "mfa.recovery_codes_regenerate"
+	ActionPasskeyAdd    = "passkey.register"
+	ActionPasskeyRemove = "passkey.delete"
+	ActionPasskeyCloned = "passkey.clone_detected"
+
+	ActionRoleCreate       = "rbac.role_create"
+	ActionRoleDelete       = "rbac.role_delete"
+	ActionPermissionGrant  = "rbac.permission_grant"
+	ActionPermissionRevoke = "rbac.permission_revoke"
+	ActionParentAdd        = "rbac.parent_add"
+	ActionParentRemove     = "rbac.parent_remove"
+	ActionRoleAssign       = "rbac.role_assign"
+	ActionRoleUnassign     = "rbac.role_unassign"
+
+	ActionOrgCreate          = "org.create"
+	ActionMemberRemove       = "org.member_remove"
+	ActionMemberRoleAssign   = "org.member_role_assign"
+	ActionMemberRoleUnassign = "org.member_role_unassign"
+	ActionInvite             = "org.invite"
+	ActionInvitationRevoke   = "org.invitation_revoke"
+	ActionInvitationAccept   = "org.invitation_accept"
+
+	ActionServiceAccountCreate    = "service_account.create"
+	ActionServiceAccountRotate    = "service_account.rotate_secret"
+	ActionServiceAccountPublicKey = "service_account.set_public_key"
+	ActionServiceAccountDisable   = "service_account.disable"
+	ActionClientRegister          = "oauth.client_register"
+
+	ActionWebhookCreate       = "webhook.create"
+	ActionWebhookUpdate       = "webhook.update"
+	ActionWebhookDelete       = "webhook.delete"
+	ActionWebhookRotateSecret = "webhook.rotate_secret"
+	ActionWebhookRedeliver    = "webhook.redeliver"
+
+	ActionSCIMTokenCreate = "scim.token_create"
+	ActionSCIMTokenRevoke = "scim.token_revoke"
+	ActionSCIMUserCreate  = "scim.user_create"
+	ActionSCIMUserUpdate  = "scim.user_update"
+	ActionSCIMGroupCreate = "scim.group_create"
+	ActionSCIMGroupUpdate = "scim.group_update"
+	ActionSCIMGroupDelete = "scim.group_delete"
+
+	ActionSAMLConnectionCreate      = "saml.connection_create"
+	ActionSAMLConnectionUpdate      = "saml.connection_update"
+	ActionSAMLConnectionDelete      = "saml.connection_delete"
+	ActionSAMLIdentityLink          = "saml.identity_link"
+	ActionSAMLServiceProviderCreate = "saml.service_provider_create"
+	ActionSAMLServiceProviderUpdate = "saml.service_provider_update"
+	ActionSAMLServiceProviderDelete = "saml.service_provider_delete"
+	ActionSAMLAssertionIssue        = "saml.assertion_issue"
+	ActionSAMLLogout                = "saml.logout"
+
+	ActionIdentityLink   = "federation.identity_link"
+	ActionIdentityUnlink = "federation.identity_unlink"
+)
+
+// Kinds of object an action is performed on.
+const (
+	TargetUser                = "user"
+	TargetRole                = "role"
+	TargetOrganization        = "organization"
+	TargetInvitation          = "invitation"
+	TargetPasskey             = "passkey"
+	TargetServiceAccount      = "service_account"
+	TargetClient              = "client"
+	TargetToken               = "token"
+	TargetLogin               = "login"
+	TargetWebhook             = "webhook"
+	TargetDelivery            = "webhook_delivery"
+	TargetSCIMToken           = "scim_token"
+	TargetGroup               = "group"
+	TargetSAMLConnection      = "saml_connection"
+	TargetSAMLServiceProvider = "saml_service_provider"
+	TargetIdentityProvider    = "identity_provider"
+)
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+// genesisHash is the previous hash of the first chained event.
+var genesisHash = make([]byte, sha256.Size)
+
+// canonicalEvent fixes the fields of an event that are hashed and their
+// order. encoding/json sorts the metadata keys, so equal events always
+// serialize to the same bytes.
+type canonicalEvent struct {
+	Seq        int64             `json:"seq"`
+	ID         string            `json:"id"`
+	OccurredAt string            `json:"occurred_at"`
+	Action     string            `json:"action"`
+	Outcome    string            `json:"outcome"`
+	ActorType  string            `json:"actor_type"`
+	ActorID    string            `json:"actor_id"`
+	TargetType string            `json:"target_type"`
+	TargetID   string            `json:"target_id"`
+	OrgID      string            `json:"org_id"`
+	IP         string            `json:"ip"`
+	UserAgent  string            `json:"user_agent"`
+	TraceID    string            `json:"trace_id"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+// chainHash links an event at seq to the hash of the event before it:
+// SHA-256 over the previous hash followed by the canonical serialization.
+func chainHash(prevHash []byte, seq int64, event domain.AuditEventInterface) ([]byte, error) {
+	canonical := canonicalEvent{
+		Seq:        seq,
+		ID:         event.GetID().String(),
+		OccurredAt: event.GetOccurredAt().UTC().Format(time.RFC3339Nano),
+		Action:     event.GetAction(),
+		Outcome:    event.GetOutcome(),
+		ActorType:  event.GetActorType(),
+		ActorID:    event.GetActorID(),
+		TargetType: event.GetTargetType(),
+		TargetID:   event.GetTargetID(),
+		IP:         event.GetIP(),
+		UserAgent:  event.GetUserAgent(),
+		TraceID:    event.GetTraceID(),
+		Metadata:   event.GetMetadata(),
+	}
+	if orgID := event.GetOrgID(); orgID != nil {
+		canonical.OrgID = orgID.String()
+	}
+	if canonical.Metadata == nil {
+		canonical.Metadata = map[string]string{}
+	}
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize audit event: %w", err)
+	}
+
+	sum := sha256.New()
+	sum.Write(prevHash)
+	sum.Write(data)
+	return sum.Sum(nil), nil
+}
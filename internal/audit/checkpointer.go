@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// checkpointType is the JWS typ of a checkpoint, which keeps it from being
+// mistaken for a token signed with the same key.
+const checkpointType = "audit-checkpoint+jwt"
+
+// CheckpointClaims is the signed payload of a checkpoint. Hash is the
+// base64url encoded hash of the event at Seq.
+type CheckpointClaims struct {
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+	jwt.RegisteredClaims
+}
+
+type checkpointer struct {
+	config     config.AuditConfig
+	repository RepositoryInterface
+	keyring    keys.KeyringInterface
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// CheckpointerInterface signs the head of the audit chain with the active
+// service signing key on a schedule. A checkpoint commits to every event up
+// to its seq, so trimming events off the end of the log is caught as well.
+type CheckpointerInterface interface {
+	Checkpoint(ctx context.Context) error
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewCheckpointer(config config.AuditConfig, repository RepositoryInterface, keyring keys.KeyringInterface) CheckpointerInterface {
+	return &checkpointer{
+		config:     config,
+		repository: repository,
+		keyring:    keyring,
+	}
+}
+
+// Checkpoint signs the current head unless it is unchained or already
+// covered by the last checkpoint.
+func (c *checkpointer) Checkpoint(ctx context.Context) error {
+	seq, hash, err := c.repository.Head(ctx)
+	if err != nil || hash == nil {
+		return err
+	}
+
+	last, err := c.repository.FindLastCheckpoint(ctx)
+	if err != nil && !errors.Is(err, ErrCheckpointNotFound) {
+		return err
+	}
+	if last != nil && last.GetSeq() >= seq {
+		return nil
+	}
+
+	key, err := c.keyring.ActiveKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := keys.MarshalPublicKey(key.GetPublicKey())
+	if err != nil {
+		return err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.GetAlgorithm()), CheckpointClaims{
+		Seq:  seq,
+		Hash: base64.RawURLEncoding.EncodeToString(hash),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
+		},
+	})
+	token.Header["kid"] = key.GetID()
+	token.Header["typ"] = checkpointType
+
+	signature, err := token.SignedString(key.GetPrivateKey())
+	if err != nil {
+		return fmt.Errorf("failed to sign audit checkpoint: %w", err)
+	}
+
+	checkpoint := domain.NewAuditCheckpoint(seq, hash, key.GetID(), string(publicKey), signature)
+	if err := c.repository.CreateCheckpoint(ctx, checkpoint); err != nil {
+		return err
+	}
+
+	slog.Info("audit checkpoint signed", slog.Int64("seq", seq), slog.String("kid", key.GetID()))
+	return nil
+}
+
+func (c *checkpointer) Start(ctx context.Context) error {
+	if c.config.CheckpointInterval <= 0 {
+		return nil
+	}
+
+	slog.Info("starting audit checkpointer", slog.Int("interval", c.config.CheckpointInterval))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.done.Add(1)
+	go c.run(runCtx)
+
+	return nil
+}
+
+func (c *checkpointer) Stop(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		slog.Info("audit checkpointer stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *checkpointer) run(ctx context.Context) {
+	defer c.done.Done()
+
+	ticker := time.NewTicker(time.Duration(c.config.CheckpointInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Checkpoint(ctx); err != nil {
+				slog.Error("failed to sign audit checkpoint", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type ListRequest struct {
+	Action     string     `form:"action" binding:"max=64"`
+	Outcome    string     `form:"outcome" binding:"omitempty,oneof=success failure"`
+	ActorID    string     `form:"actor_id" binding:"max=255"`
+	TargetType string     `form:"target_type" binding:"max=64"`
+	TargetID   string     `form:"target_id" binding:"max=255"`
+	OrgID      string     `form:"org_id" binding:"omitempty,uuid"`
+	Since      *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until      *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor     int64      `form:"cursor" binding:"omitempty,min=1"`
+	Limit      int        `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type EventResponse struct {
+	Seq        int64             `json:"seq"`
+	ID         string            `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Action     string            `json:"action"`
+	Outcome    string            `json:"outcome"`
+	ActorType  string            `json:"actor_type,omitempty"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	TargetType string            `json:"target_type,omitempty"`
+	TargetID   string            `json:"target_id,omitempty"`
+	OrgID      string            `json:"org_id,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	TraceID    string            `json:"trace_id,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+type ListResponse struct {
+	Events []EventResponse `json:"events"`
+	// NextCursor fetches the next, older page; it is empty on the last one.
+	NextCursor int64 `json:"next_cursor,omitempty"`
+}
+
+func NewEventResponse(event domain.AuditEventInterface) EventResponse {
+	res := EventResponse{
+		Seq:        event.GetSeq(),
+		ID:         event.GetID().String(),
+		OccurredAt: event.GetOccurredAt(),
+		Action:     event.GetAction(),
+		Outcome:    event.GetOutcome(),
+		ActorType:  event.GetActorType(),
+		ActorID:    event.GetActorID(),
+		TargetType: event.GetTargetType(),
+		TargetID:   event.GetTargetID(),
+		IP:         event.GetIP(),
+		UserAgent:  event.GetUserAgent(),
+		TraceID:    event.GetTraceID(),
+		Metadata:   event.GetMetadata(),
+	}
+	if orgID := event.GetOrgID(); orgID != nil {
+		res.OrgID = orgID.String()
+	}
+	return res
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the audit API.
+const (
+	ResourceAudit = "audit"
+	ActionRead    = "read"
+)
+
+// exportFlushInterval is how many events are written between flushes of a
+// JSON Lines export.
+const exportFlushInterval = 500
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+	checker     middleware.PermissionCheckerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	List(ctx *gin.Context)
+	Export(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+		checker:     checker,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	read := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.checker, ResourceAudit, ActionRead)
+
+	audit := router.Group("/api/v1/audit", read)
+	{
+		audit.GET("", h.List)
+		audit.GET("/export", h.Export)
+	}
+}
+
+func (h *handler) List(ctx *gin.Context) {
+	var req ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.List(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Export streams the matching events as JSON Lines, oldest first. Once the
+// first line is out the status can no longer change, so a failure midway
+// only ends the stream early.
+func (h *handler) Export(ctx *gin.Context) {
+	var req ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(ctx.Writer)
+	written := 0
+	err := h.service.Export(ctx.Request.Context(), req, func(event EventResponse) error {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if written++; written%exportFlushInterval == 0 {
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("failed to export audit events", "error", err, slog.Int("written", written))
+	}
+	ctx.Writer.Flush()
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"context"
+
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewRecorder,
+		NewCheckpointer,
+		NewService,
+		httpserver.AsRouter(NewHandler),
+	),
+	fx.Invoke(func(lc fx.Lifecycle, checkpointer CheckpointerInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return checkpointer.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return checkpointer.Stop(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxUserAgentLength is the size of the user_agent column. The value is cut
+// before the event is hashed so the chain covers what is stored.
+const maxUserAgentLength = 512
+
+// Entry is what a caller knows about an action. The recorder adds who made
+// the request, where from, and the trace it belongs to.
+type Entry struct {
+	Action     string
+	Outcome    string
+	TargetType string
+	TargetID   string
+	// Actor overrides the request's actor, for actions such as a login,
+	// where the user is only known once the credentials check out.
+	Actor    *requestinfo.Actor
+	Metadata map[string]string
+}
+
+type recorder struct {
+	repository RepositoryInterface
+}
+
+// RecorderInterface appends entries to the audit log. Record writes through
+// the context's transaction, so an entry is committed or rolled back with
+// the change it describes; callers record inside db.WithTx and return its
+// error to undo a change that could not be audited.
+type RecorderInterface interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+func NewRecorder(repository RepositoryInterface) RecorderInterface {
+	return &recorder{
+		repository: repository,
+	}
+}
+
+func (r *recorder) Record(ctx context.Context, entry Entry) error {
+	info := requestinfo.FromContext(ctx)
+	params := domain.AuditEventParams{
+		Action:     entry.Action,
+		Outcome:    entry.Outcome,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IP:         info.IP,
+		UserAgent:  truncate(info.UserAgent, maxUserAgentLength),
+		Metadata:   entry.Metadata,
+	}
+
+	if entry.Actor != nil {
+		params.ActorType, params.ActorID = entry.Actor.Type, entry.Actor.ID
+	} else if actor, ok := requestinfo.ActorFromContext(ctx); ok {
+		params.ActorType, params.ActorID = actor.Type, actor.ID
+	}
+
+	if orgID, ok := database.TenantFromContext(ctx); ok {
+		params.OrgID = &orgID
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		params.TraceID = spanContext.TraceID().String()
+	}
+
+	return r.repository.Create(ctx, domain.NewAuditEvent(params))
+}
+
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	return strings.ToValidUTF8(value[:size], "")
+}
@@ -0,0 +1,371 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// chainLockID serializes appends across every replica sharing the database,
+// so each event links to the one committed right before it.
+//
+// The lock is global and held from the INSERT until the caller commits, so
+// audited writes never overlap anywhere in the deployment: the log takes at
+// most one event per round trip plus commit, a few hundred to a few
+// thousand a second in total however many replicas run. Failed logins are
+// audited too, so a credential-stuffing burst competes for it until the
+// login throttle locks the attacker out. Raising the ceiling means splitting
+// the log into several chains, each with its own lock key, head and
+// checkpoints, and verifying them separately.
+const chainLockID = 7_301_205
+
+var ErrCheckpointNotFound = errors.New("audit checkpoint not found")
+
+const selectEventColumns = `
+	SELECT seq, id, occurred_at, action, outcome, actor_type, actor_id, target_type, target_id,
+		org_id, ip, user_agent, trace_id, metadata, prev_hash, hash
+	FROM audit_events`
+
+const selectCheckpointColumns = `
+	SELECT seq, hash, key_id, public_key, signature, created_at
+	FROM audit_checkpoints`
+
+// Filter narrows a query over the log. Zero fields match everything;
+// AfterSeq and BeforeSeq bound the sequence exclusively.
+type Filter struct {
+	Action     string
+	Outcome    string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	OrgID      *uuid.UUID
+	Since      *time.Time
+	Until      *time.Time
+	AfterSeq   int64
+	BeforeSeq  int64
+}
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	// Create appends the event to the chain. The chain lock is held until
+	// the surrounding transaction ends, so callers record as the last step
+	// of a transaction.
+	Create(ctx context.Context, event domain.AuditEventInterface) error
+	// FindPage returns up to limit events, newest first.
+	FindPage(ctx context.Context, filter Filter, limit int) ([]domain.AuditEventInterface, error)
+	// Stream calls fn for every matching event, oldest first, stopping at
+	// the first error.
+	Stream(ctx context.Context, filter Filter, fn func(domain.AuditEventInterface) error) error
+	// Head returns the newest event's seq and hash; seq is zero when the
+	// log is empty and the hash is nil when the newest event is unchained.
+	Head(ctx context.Context) (int64, []byte, error)
+	// CreateCheckpoint stores a checkpoint unless one exists for its seq.
+	CreateCheckpoint(ctx context.Context, checkpoint domain.AuditCheckpointInterface) error
+	FindLastCheckpoint(ctx context.Context) (domain.AuditCheckpointInterface, error)
+	FindCheckpoints(ctx context.Context) ([]domain.AuditCheckpointInterface, error)
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, event domain.AuditEventInterface) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		querier := r.db.GetQuerier(ctx)
+
+		if _, err := querier.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+			return fmt.Errorf("failed to acquire audit chain lock: %w", err)
+		}
+
+		_, prevHash, err := r.Head(ctx)
+		if err != nil {
+			return err
+		}
+		if prevHash == nil {
+			prevHash = genesisHash
+		}
+
+		// The seq is drawn under the lock so it is part of the hash and
+		// follows the order of the chain.
+		var seq int64
+		if err := querier.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('audit_events', 'seq'))`).Scan(&seq); err != nil {
+			return fmt.Errorf("failed to allocate audit sequence: %w", err)
+		}
+
+		hash, err := chainHash(prevHash, seq, event)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO audit_events (
+				seq, id, occurred_at, action, outcome, actor_type, actor_id, target_type, target_id,
+				org_id, ip, user_agent, trace_id, metadata, prev_hash, hash
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+
+		_, err = querier.Exec(ctx, query,
+			seq,
+			event.GetID(),
+			event.GetOccurredAt(),
+			event.GetAction(),
+			event.GetOutcome(),
+			event.GetActorType(),
+			event.GetActorID(),
+			event.GetTargetType(),
+			event.GetTargetID(),
+			event.GetOrgID(),
+			event.GetIP(),
+			event.GetUserAgent(),
+			event.GetTraceID(),
+			event.GetMetadata(),
+			prevHash,
+			hash,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert audit event: %w", err)
+		}
+
+		event.Chain(seq, prevHash, hash)
+		return nil
+	})
+}
+
+func (r *repository) FindPage(ctx context.Context, filter Filter, limit int) ([]domain.AuditEventInterface, error) {
+	where, args := filter.build()
+	args = append(args, limit)
+	query := selectEventColumns + where + ` ORDER BY seq DESC LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.AuditEventInterface
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *repository) Stream(ctx context.Context, filter Filter, fn func(domain.AuditEventInterface) error) error {
+	where, args := filter.build()
+	query := selectEventColumns + where + ` ORDER BY seq`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate audit events: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) Head(ctx context.Context) (int64, []byte, error) {
+	var (
+		seq  int64
+		hash []byte
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, `SELECT seq, hash FROM audit_events ORDER BY seq DESC LIMIT 1`).Scan(&seq, &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil, nil
+		}
+		return 0, nil, fmt.Errorf("failed to find audit chain head: %w", err)
+	}
+
+	return seq, hash, nil
+}
+
+func (r *repository) CreateCheckpoint(ctx context.Context, checkpoint domain.AuditCheckpointInterface) error {
+	query := `
+		INSERT INTO audit_checkpoints (seq, hash, key_id, public_key, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (seq) DO NOTHING`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		checkpoint.GetSeq(),
+		checkpoint.GetHash(),
+		checkpoint.GetKeyID(),
+		checkpoint.GetPublicKey(),
+		checkpoint.GetSignature(),
+		checkpoint.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindLastCheckpoint(ctx context.Context) (domain.AuditCheckpointInterface, error) {
+	checkpoint, err := scanCheckpoint(r.db.GetQuerier(ctx).QueryRow(ctx, selectCheckpointColumns+` ORDER BY seq DESC LIMIT 1`))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCheckpointNotFound
+		}
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+func (r *repository) FindCheckpoints(ctx context.Context) ([]domain.AuditCheckpointInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, selectCheckpointColumns+` ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []domain.AuditCheckpointInterface
+	for rows.Next() {
+		checkpoint, err := scanCheckpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+func (f Filter) build() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Outcome != "" {
+		add("outcome = $%d", f.Outcome)
+	}
+	if f.ActorID != "" {
+		add("actor_id = $%d", f.ActorID)
+	}
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id = $%d", f.TargetID)
+	}
+	if f.OrgID != nil {
+		add("org_id = $%d", *f.OrgID)
+	}
+	if f.Since != nil {
+		add("occurred_at >= $%d", f.Since.UTC())
+	}
+	if f.Until != nil {
+		add("occurred_at < $%d", f.Until.UTC())
+	}
+	if f.AfterSeq > 0 {
+		add("seq > $%d", f.AfterSeq)
+	}
+	if f.BeforeSeq > 0 {
+		add("seq < $%d", f.BeforeSeq)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func scanEvent(row pgx.Row) (domain.AuditEventInterface, error) {
+	var (
+		seq        int64
+		id         uuid.UUID
+		occurredAt time.Time
+		params     domain.AuditEventParams
+		prevHash   []byte
+		hash       []byte
+	)
+
+	err := row.Scan(
+		&seq,
+		&id,
+		&occurredAt,
+		&params.Action,
+		&params.Outcome,
+		&params.ActorType,
+		&params.ActorID,
+		&params.TargetType,
+		&params.TargetID,
+		&params.OrgID,
+		&params.IP,
+		&params.UserAgent,
+		&params.TraceID,
+		&params.Metadata,
+		&prevHash,
+		&hash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit event: %w", err)
+	}
+
+	return domain.RestoreAuditEvent(id, seq, occurredAt, params, prevHash, hash), nil
+}
+
+func scanCheckpoint(row pgx.Row) (domain.AuditCheckpointInterface, error) {
+	var (
+		seq       int64
+		hash      []byte
+		keyID     string
+		publicKey string
+		signature string
+		createdAt time.Time
+	)
+
+	if err := row.Scan(&seq, &hash, &keyID, &publicKey, &signature, &createdAt); err != nil {
+		return nil, fmt.Errorf("failed to scan audit checkpoint: %w", err)
+	}
+
+	return domain.RestoreAuditCheckpoint(seq, hash, keyID, publicKey, signature, createdAt), nil
+}
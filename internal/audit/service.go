@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+const defaultPageSize = 100
+
+type service struct {
+	repository RepositoryInterface
+}
+
+type ServiceInterface interface {
+	List(ctx context.Context, req ListRequest) (*ListResponse, *httperr.HttpError)
+	// Export calls fn for every event matching the request, oldest first.
+	// The cursor and limit are ignored.
+	Export(ctx context.Context, req ListRequest, fn func(EventResponse) error) error
+}
+
+func NewService(repository RepositoryInterface) ServiceInterface {
+	return &service{
+		repository: repository,
+	}
+}
+
+func (s *service) List(ctx context.Context, req ListRequest) (*ListResponse, *httperr.HttpError) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	filter := newFilter(req)
+	filter.BeforeSeq = req.Cursor
+
+	// One extra row tells whether there is a next page.
+	events, err := s.repository.FindPage(ctx, filter, limit+1)
+	if err != nil {
+		slog.Error("failed to list audit events", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list audit events")
+	}
+
+	res := &ListResponse{Events: make([]EventResponse, 0, min(len(events), limit))}
+	if len(events) > limit {
+		events = events[:limit]
+		res.NextCursor = events[limit-1].GetSeq()
+	}
+	for _, event := range events {
+		res.Events = append(res.Events, NewEventResponse(event))
+	}
+
+	return res, nil
+}
+
+func (s *service) Export(ctx context.Context, req ListRequest, fn func(EventResponse) error) error {
+	return s.repository.Stream(ctx, newFilter(req), func(event domain.AuditEventInterface) error {
+		return fn(NewEventResponse(event))
+	})
+}
+
+func newFilter(req ListRequest) Filter {
+	filter := Filter{
+		Action:     req.Action,
+		Outcome:    req.Outcome,
+		ActorID:    req.ActorID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Since:      req.Since,
+		Until:      req.Until,
+	}
+	if orgID, err := uuid.Parse(req.OrgID); err == nil {
+		filter.OrgID = &orgID
+	}
+	return filter
+}
@@ -0,0 +1,223 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var errChainBroken = errors.New("audit chain broken")
+
+// Break is the first link of the chain that failed to verify.
+type Break struct {
+	Seq    int64
+	Reason string
+}
+
+// Report is the outcome of a walk over the audit chain.
+type Report struct {
+	// Unchained counts events written before the log was chained.
+	Unchained int64
+	// Verified counts chained events whose links checked out.
+	Verified int64
+	// Head is the seq of the last event visited.
+	Head int64
+	// Checkpoints counts signed checkpoints that matched the chain and
+	// whose key the keyring publishes or was pinned.
+	Checkpoints int
+	// Unverified counts checkpoints signed with a key that is neither: the
+	// signature only proves they match the public key stored next to them,
+	// which whoever rewrote the log could have replaced too.
+	Unverified int
+	// Broken is nil when the whole chain verified.
+	Broken *Break
+}
+
+type verifier struct {
+	repository RepositoryInterface
+	keyring    keys.KeyringInterface
+	pinned     map[string]crypto.PublicKey
+}
+
+// VerifierInterface walks the audit chain from the oldest event, recomputing
+// every hash and checking every signed checkpoint, and stops at the first
+// broken link.
+type VerifierInterface interface {
+	// Pin trusts the keys of a JWKS, typically an archived copy, for
+	// checkpoints signed with keys the keyring has since deleted.
+	Pin(set keys.JWKS) error
+	Verify(ctx context.Context) (*Report, error)
+}
+
+func NewVerifier(repository RepositoryInterface, keyring keys.KeyringInterface) VerifierInterface {
+	return &verifier{
+		repository: repository,
+		keyring:    keyring,
+		pinned:     map[string]crypto.PublicKey{},
+	}
+}
+
+func (v *verifier) Pin(set keys.JWKS) error {
+	for _, jwk := range set.Keys {
+		publicKey, _, err := jwk.PublicKey()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", jwk.KeyID, err)
+		}
+		v.pinned[jwk.KeyID] = publicKey
+	}
+	return nil
+}
+
+func (v *verifier) Verify(ctx context.Context) (*Report, error) {
+	checkpoints, err := v.repository.FindCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[int64]domain.AuditCheckpointInterface, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		pending[checkpoint.GetSeq()] = checkpoint
+	}
+
+	report := &Report{}
+	broken := func(seq int64, reason string) error {
+		report.Broken = &Break{Seq: seq, Reason: reason}
+		return errChainBroken
+	}
+
+	var prevHash []byte
+	err = v.repository.Stream(ctx, Filter{}, func(event domain.AuditEventInterface) error {
+		seq, hash := event.GetSeq(), event.GetHash()
+		report.Head = seq
+
+		if hash == nil {
+			if prevHash == nil {
+				report.Unchained++
+				return nil
+			}
+			return broken(seq, "event is missing its hash")
+		}
+
+		if prevHash == nil {
+			prevHash = genesisHash
+		}
+		if !bytes.Equal(event.GetPrevHash(), prevHash) {
+			return broken(seq, "previous hash does not match the preceding event")
+		}
+
+		computed, err := chainHash(prevHash, seq, event)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(computed, hash) {
+			return broken(seq, "hash does not match the event's contents")
+		}
+
+		if checkpoint, ok := pending[seq]; ok {
+			delete(pending, seq)
+			trusted, err := v.checkCheckpoint(ctx, checkpoint, hash)
+			if err != nil {
+				return broken(seq, "checkpoint: "+err.Error())
+			}
+			if trusted {
+				report.Checkpoints++
+			} else {
+				report.Unverified++
+			}
+		}
+
+		prevHash = hash
+		report.Verified++
+		return nil
+	})
+	if errors.Is(err, errChainBroken) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// A checkpoint without its event means events were removed, possibly
+	// from the end of the log where no later link would notice.
+	for _, checkpoint := range checkpoints {
+		if _, ok := pending[checkpoint.GetSeq()]; ok {
+			report.Broken = &Break{Seq: checkpoint.GetSeq(), Reason: "checkpointed event is missing"}
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// checkCheckpoint verifies the checkpoint's signature and that it commits to
+// hash. It reports whether the signing key is one the verifier trusts; when
+// it is not, the signature was only checked against the public key stored
+// with the checkpoint.
+func (v *verifier) checkCheckpoint(ctx context.Context, checkpoint domain.AuditCheckpointInterface, hash []byte) (bool, error) {
+	if !bytes.Equal(checkpoint.GetHash(), hash) {
+		return false, errors.New("hash does not match the event")
+	}
+
+	stored, algorithm, err := keys.ParsePublicKey([]byte(checkpoint.GetPublicKey()))
+	if err != nil {
+		return false, err
+	}
+
+	trustedKey, err := v.findKey(ctx, checkpoint.GetKeyID())
+	if err != nil {
+		return false, err
+	}
+	if trustedKey != nil {
+		trusted, err := keys.MarshalPublicKey(trustedKey)
+		if err != nil {
+			return false, err
+		}
+		if string(trusted) != checkpoint.GetPublicKey() {
+			return false, errors.New("stored public key does not match the signing key")
+		}
+	}
+
+	claims := &CheckpointClaims{}
+	_, err = jwt.ParseWithClaims(checkpoint.GetSignature(), claims, func(token *jwt.Token) (any, error) {
+		if kid, _ := token.Header["kid"].(string); kid != checkpoint.GetKeyID() {
+			return nil, fmt.Errorf("signed with key %q instead of %q", kid, checkpoint.GetKeyID())
+		}
+		if typ, _ := token.Header["typ"].(string); typ != checkpointType {
+			return nil, fmt.Errorf("unexpected type %q", typ)
+		}
+		return stored, nil
+	}, jwt.WithValidMethods([]string{algorithm}))
+	if err != nil {
+		return false, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	if claims.Seq != checkpoint.GetSeq() || claims.Hash != base64.RawURLEncoding.EncodeToString(hash) {
+		return false, errors.New("signed payload does not match the event")
+	}
+
+	return trustedKey != nil, nil
+}
+
+// findKey returns the public key the keyring publishes under keyID, or the
+// pinned one, or nil when neither knows it.
+func (v *verifier) findKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	key, err := v.keyring.FindKey(ctx, keyID)
+	switch {
+	case err == nil:
+		return key.GetPublicKey(), nil
+	case !errors.Is(err, keys.ErrKeyNotFound):
+		return nil, err
+	}
+
+	if pinned, ok := v.pinned[keyID]; ok {
+		return pinned, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/google/uuid"
+)
+
+func TestVerifyTrustsPublishedKeys(t *testing.T) {
+	ctx := context.Background()
+	repository := newChain(t, 3)
+	keyring := newKeyring(t, "k1")
+	checkpoint(t, repository, keyring)
+
+	report, err := NewVerifier(repository, keyring).Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if report.Broken != nil || report.Verified != 3 || report.Checkpoints != 1 || report.Unverified != 0 {
+		t.Fatalf("report = %+v, broken = %+v", report, report.Broken)
+	}
+}
+
+func TestVerifyDoesNotTrustDeletedKeys(t *testing.T) {
+	ctx := context.Background()
+	repository := newChain(t, 3)
+	keyring := newKeyring(t, "k1")
+	checkpoint(t, repository, keyring)
+	signingKey := keyring.keys["k1"]
+	delete(keyring.keys, "k1")
+
+	verifier := NewVerifier(repository, keyring)
+	report, err := verifier.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if report.Broken != nil || report.Checkpoints != 0 || report.Unverified != 1 {
+		t.Fatalf("without the key: report = %+v, broken = %+v", report, report.Broken)
+	}
+
+	jwk, err := keys.NewJWK("k1", signingKey.GetAlgorithm(), signingKey.GetPublicKey())
+	if err != nil {
+		t.Fatalf("NewJWK() error = %v", err)
+	}
+	if err := verifier.Pin(keys.JWKS{Keys: []keys.JWK{jwk}}); err != nil {
+		t.Fatalf("Pin() error = %v", err)
+	}
+	report, err = verifier.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if report.Broken != nil || report.Checkpoints != 1 || report.Unverified != 0 {
+		t.Fatalf("with the key pinned: report = %+v, broken = %+v", report, report.Broken)
+	}
+}
+
+// A rewritten log re-signed under a key id the verifier knows breaks the
+// chain; under one it does not know, the checkpoint stays unverified.
+func TestVerifyRejectsForgedCheckpoints(t *testing.T) {
+	ctx := context.Background()
+
+	repository := newChain(t, 3)
+	checkpoint(t, repository, newKeyring(t, "k1"))
+	report, err := NewVerifier(repository, newKeyring(t, "k1")).Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if report.Broken == nil || report.Broken.Seq != 3 || !strings.Contains(report.Broken.Reason, "does not match the signing key") {
+		t.Fatalf("known key id: broken = %+v", report.Broken)
+	}
+
+	repository = newChain(t, 3)
+	checkpoint(t, repository, newKeyring(t, "forged"))
+	report, err = NewVerifier(repository, newKeyring(t, "k1")).Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if report.Broken != nil || report.Checkpoints != 0 || report.Unverified != 1 {
+		t.Fatalf("unknown key id: report = %+v, broken = %+v", report, report.Broken)
+	}
+}
+
+func TestPinRejectsInvalidKeys(t *testing.T) {
+	verifier := NewVerifier(&fakeRepository{}, newKeyring(t, "k1"))
+	if err := verifier.Pin(keys.JWKS{Keys: []keys.JWK{{KeyID: "k1", KeyType: "EC", Curve: "P-256", X: "AA", Y: "AA"}}}); err == nil {
+		t.Fatal("Pin() with an invalid point: no error")
+	}
+}
+
+func newChain(t *testing.T, length int) *fakeRepository {
+	t.Helper()
+
+	repository := &fakeRepository{}
+	prevHash := genesisHash
+	occurredAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for seq := int64(1); seq <= int64(length); seq++ {
+		params := domain.AuditEventParams{Action: ActionLogin, Outcome: domain.AuditOutcomeSuccess, TargetID: "jane@example.com"}
+		event := domain.RestoreAuditEvent(uuid.New(), seq, occurredAt, params, nil, nil)
+		hash, err := chainHash(prevHash, seq, event)
+		if err != nil {
+			t.Fatalf("chainHash() error = %v", err)
+		}
+		repository.events = append(repository.events, domain.RestoreAuditEvent(event.GetID(), seq, occurredAt, params, prevHash, hash))
+		prevHash = hash
+	}
+	return repository
+}
+
+func newKeyring(t *testing.T, keyID string) *fakeKeyring {
+	t.Helper()
+
+	signer, err := keys.GenerateKey(keys.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return &fakeKeyring{
+		active: keyID,
+		keys:   map[string]domain.SigningKeyInterface{keyID: domain.NewSigningKey(keyID, keys.AlgorithmES256, signer)},
+	}
+}
+
+func checkpoint(t *testing.T, repository *fakeRepository, keyring *fakeKeyring) {
+	t.Helper()
+
+	if err := NewCheckpointer(config.AuditConfig{}, repository, keyring).Checkpoint(context.Background()); err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+}
+
+type fakeRepository struct {
+	RepositoryInterface
+	events      []domain.AuditEventInterface
+	checkpoints []domain.AuditCheckpointInterface
+}
+
+func (r *fakeRepository) Stream(_ context.Context, _ Filter, fn func(domain.AuditEventInterface) error) error {
+	for _, event := range r.events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) Head(context.Context) (int64, []byte, error) {
+	if len(r.events) == 0 {
+		return 0, nil, nil
+	}
+	last := r.events[len(r.events)-1]
+	return last.GetSeq(), last.GetHash(), nil
+}
+
+func (r *fakeRepository) CreateCheckpoint(_ context.Context, checkpoint domain.AuditCheckpointInterface) error {
+	r.checkpoints = append(r.checkpoints, checkpoint)
+	return nil
+}
+
+func (r *fakeRepository) FindLastCheckpoint(context.Context) (domain.AuditCheckpointInterface, error) {
+	if len(r.checkpoints) == 0 {
+		return nil, ErrCheckpointNotFound
+	}
+	return r.checkpoints[len(r.checkpoints)-1], nil
+}
+
+func (r *fakeRepository) FindCheckpoints(context.Context) ([]domain.AuditCheckpointInterface, error) {
+	return r.checkpoints, nil
+}
+
+type fakeKeyring struct {
+	keys.KeyringInterface
+	active string
+	keys   map[string]domain.SigningKeyInterface
+}
+
+func (k *fakeKeyring) ActiveKey(ctx context.Context) (domain.SigningKeyInterface, error) {
+	return k.FindKey(ctx, k.active)
+}
+
+func (k *fakeKeyring) FindKey(_ context.Context, keyID string) (domain.SigningKeyInterface, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, keys.ErrKeyNotFound
+	}
+	return key, nil
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"go.uber.org/fx"
+)
+
+// ErrInvalidCredentials is how a backend refuses a password.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// BackendInterface checks the password of a login. The password stored
+// with the account is checked first; directories contributed with
+// AsBackend are tried after it.
+type BackendInterface interface {
+	// Name is recorded as the method of the logins it accepts.
+	Name() string
+	// Authenticate returns the account the password opens. found is the
+	// account with the email, nil when there is none; a backend may open
+	// another one, or provision it. ErrInvalidCredentials refuses the
+	// password, other errors mean the backend could not tell.
+	Authenticate(ctx context.Context, email, password string, found domain.UserInterface) (domain.UserInterface, error)
+}
+
+// Backends are the backends contributed with AsBackend.
+type Backends []BackendInterface
+
+// AsBackend annotates the constructor of a backend so the logins try it.
+func AsBackend(constructor any) any {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(BackendInterface)),
+		fx.ResultTags(`group:"auth_backends"`),
+	)
+}
+
+// passwordBackend checks the password stored with the account. Accounts
+// without a password, and unknown emails, are compared against the dummy,
+// so timing does not tell them apart from a wrong password.
+type passwordBackend struct {
+	dummy domain.UserInterface
+}
+
+func (b *passwordBackend) Name() string {
+	return loginMethodPassword
+}
+
+func (b *passwordBackend) Authenticate(_ context.Context, _, password string, found domain.UserInterface) (domain.UserInterface, error) {
+	candidate := found
+	if candidate == nil || !candidate.HasPassword() {
+		candidate = b.dummy
+	}
+
+	if !candidate.ComparePassword(password) || found == nil || !found.HasPassword() {
+		return nil, ErrInvalidCredentials
+	}
+	return found, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import "github.com/felipeversiane/auth-service/internal/passkey"
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken     string                      `json:"mfa_token" binding:"required"`
+	Code         string                      `json:"code"`
+	RecoveryCode string                      `json:"recovery_code"`
+	Passkey      *passkey.FinishLoginRequest `json:"passkey"`
+}
+
+type MFAPasskeyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SwitchOrgRequest leaves every organization when OrgID is empty.
+type SwitchOrgRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	OrgID        string `json:"org_id" binding:"omitempty,uuid"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72,maxbytes=72"`
+}
+
+// PasswordlessStartRequest asks for a sign-in link to be mailed, or a code
+// when Method is "code".
+type PasswordlessStartRequest struct {
+	Email  string `json:"email" binding:"required,email"`
+	Method string `json:"method" binding:"omitempty,oneof=link code"`
+}
+
+// PasswordlessVerifyRequest carries the token of a mailed sign-in link, or
+// a mailed code with the email it was sent to.
+type PasswordlessVerifyRequest struct {
+	Token string `json:"token" binding:"required_without=Code,max=64"`
+	Email string `json:"email" binding:"required_with=Code,omitempty,email"`
+	Code  string `json:"code" binding:"omitempty,len=6,numeric"`
+}
+
+type PasswordlessStartResponse struct {
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// LoginResponse carries either the issued tokens or, for users with a second
+// factor, the challenge to complete at /api/v1/auth/mfa.
+type LoginResponse struct {
+	*TokenResponse
+	MFARequired       bool   `json:"mfa_required,omitempty"`
+	MFAToken          string `json:"mfa_token,omitempty"`
+	MFATokenExpiresIn int64  `json:"mfa_token_expires_in,omitempty"`
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// passwordlessNonceCookie binds a passwordless login to the browser that
+	// started it. It is only sent back to the passwordless endpoints.
+	passwordlessNonceCookie = "passwordless_nonce"
+	passwordlessCookiePath  = "/api/v1/auth/passwordless"
+)
+
+type handler struct {
+	service ServiceInterface
+	account config.AccountConfig
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Login(ctx *gin.Context)
+	VerifyMFA(ctx *gin.Context)
+	BeginMFAPasskey(ctx *gin.Context)
+	BeginPasskeyLogin(ctx *gin.Context)
+	FinishPasskeyLogin(ctx *gin.Context)
+	Refresh(ctx *gin.Context)
+	SwitchOrg(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
+	StartPasswordless(ctx *gin.Context)
+	VerifyPasswordless(ctx *gin.Context)
+}
+
+func NewHandler(service ServiceInterface, account config.AccountConfig) HandlerInterface {
+	return &handler{
+		service: service,
+		account: account,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	auth := router.Group("/api/v1/auth")
+	{
+		auth.POST("/login", h.Login)
+		auth.POST("/mfa", h.VerifyMFA)
+		auth.POST("/mfa/passkey", h.BeginMFAPasskey)
+		auth.POST("/passkey", h.BeginPasskeyLogin)
+		auth.POST("/passkey/finish", h.FinishPasskeyLogin)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/switch-org", h.SwitchOrg)
+		auth.POST("/password/forgot", h.ForgotPassword)
+		auth.POST("/password/reset", h.ResetPassword)
+		auth.POST("/passwordless/start", h.StartPasswordless)
+		auth.POST("/passwordless/verify", h.VerifyPasswordless)
+	}
+}
+
+func (h *handler) Login(ctx *gin.Context) {
+	var req LoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.Login(ctx.Request.Context(), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) VerifyMFA(ctx *gin.Context) {
+	var req MFAVerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.VerifyMFA(ctx.Request.Context(), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) BeginMFAPasskey(ctx *gin.Context) {
+	var req MFAPasskeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.BeginMFAPasskey(ctx.Request.Context(), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) BeginPasskeyLogin(ctx *gin.Context) {
+	res, restErr := h.service.BeginPasskeyLogin(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) FinishPasskeyLogin(ctx *gin.Context) {
+	var req passkey.FinishLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.FinishPasskeyLogin(ctx.Request.Context(), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Refresh(ctx *gin.Context) {
+	var req RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.Refresh(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) SwitchOrg(ctx *gin.Context) {
+	var req SwitchOrgRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.SwitchOrg(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ForgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	h.service.ForgotPassword(ctx.Request.Context(), req)
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (h *handler) ResetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	if restErr := h.service.ResetPassword(ctx.Request.Context(), req); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) StartPasswordless(ctx *gin.Context) {
+	var req PasswordlessStartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, nonce, restErr := h.service.StartPasswordless(ctx.Request.Context(), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	h.setNonceCookie(ctx, nonce, int(res.ExpiresIn))
+	ctx.JSON(http.StatusAccepted, res)
+}
+
+func (h *handler) VerifyPasswordless(ctx *gin.Context) {
+	var req PasswordlessVerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	nonce, _ := ctx.Cookie(passwordlessNonceCookie)
+	res, restErr := h.service.VerifyPasswordless(ctx.Request.Context(), nonce, req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	h.setNonceCookie(ctx, "", -1)
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+// setNonceCookie stores the nonce where scripts cannot read it; a negative
+// maxAge deletes it.
+func (h *handler) setNonceCookie(ctx *gin.Context, nonce string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(passwordlessNonceCookie, nonce, maxAge, passwordlessCookiePath, "", h.account.NonceCookieSecure, true)
+}
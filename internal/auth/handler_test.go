@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifyMFAThrottledSetsRetryAfter(t *testing.T) {
+	env := newTestEnv(t, true)
+	challenge, _, restErr := env.service.RequireSecondFactor(env.ctx, env.account)
+	if restErr != nil {
+		t.Fatalf("RequireSecondFactor() error = %v", restErr)
+	}
+	env.throttle.wait = 90 * time.Second
+
+	rec := env.serve(t, "/api/v1/auth/mfa", `{"mfa_token":"`+challenge+`","code":"`+testCode+`"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Errorf("Retry-After = %q, want 90", got)
+	}
+	if env.mfa.verified != 0 {
+		t.Errorf("challenge checked %d times while throttled, want 0", env.mfa.verified)
+	}
+}
+
+func TestFinishPasskeyLoginThrottledSetsRetryAfter(t *testing.T) {
+	env := newTestEnv(t, false)
+	env.throttle.wait = 30 * time.Second
+
+	rec := env.serve(t, "/api/v1/auth/passkey/finish", `{"session_token":"session","credential":{}}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+}
+
+// serve posts body to path through the handler's routes.
+func (env *testEnv) serve(t *testing.T, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewHandler(env.service, config.AccountConfig{}).RegisterRoutes(&router.RouterGroup)
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(env.ctx)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewService,
+		fx.Annotate(
+			func(backends []BackendInterface) Backends { return backends },
+			fx.ParamTags(`group:"auth_backends"`),
+		),
+		httpserver.AsRouter(NewHandler),
+	),
+)
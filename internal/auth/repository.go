@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, token domain.RefreshTokenInterface) error
+	FindByHash(ctx context.Context, tokenHash string) (domain.RefreshTokenInterface, error)
+	FindByHashForUpdate(ctx context.Context, tokenHash string) (domain.RefreshTokenInterface, error)
+	MarkRotated(ctx context.Context, token domain.RefreshTokenInterface) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, token domain.RefreshTokenInterface) error {
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, client_id, scope, org_id, parent_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		token.GetID(),
+		token.GetFamilyID(),
+		token.GetUserID(),
+		nullableString(token.GetClientID()),
+		token.GetScope(),
+		token.GetOrgID(),
+		token.GetParentID(),
+		token.GetTokenHash(),
+		token.GetExpiresAt(),
+		token.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindByHash(ctx context.Context, tokenHash string) (domain.RefreshTokenInterface, error) {
+	return r.findByHash(ctx, tokenHash, "")
+}
+
+func (r *repository) FindByHashForUpdate(ctx context.Context, tokenHash string) (domain.RefreshTokenInterface, error) {
+	return r.findByHash(ctx, tokenHash, "FOR UPDATE")
+}
+
+func (r *repository) findByHash(ctx context.Context, tokenHash, lock string) (domain.RefreshTokenInterface, error) {
+	query := `
+		SELECT id, family_id, user_id, client_id, scope, org_id, parent_id, token_hash, expires_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		` + lock
+
+	var (
+		id, familyID, userID uuid.UUID
+		clientID             *string
+		scope                string
+		orgID, parentID      *uuid.UUID
+		hash                 string
+		expiresAt, createdAt time.Time
+		rotatedAt, revokedAt *time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash).Scan(
+		&id, &familyID, &userID, &clientID, &scope, &orgID, &parentID, &hash, &expiresAt, &rotatedAt, &revokedAt, &createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+	}
+
+	return domain.RestoreRefreshToken(
+		id, familyID, userID, valueOrEmpty(clientID), scope, orgID, parentID, hash, expiresAt, rotatedAt, revokedAt, createdAt,
+	), nil
+}
+
+func (r *repository) MarkRotated(ctx context.Context, token domain.RefreshTokenInterface) error {
+	query := `UPDATE refresh_tokens SET rotated_at = $2 WHERE id = $1`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, token.GetID(), token.GetRotatedAt()); err != nil {
+		return fmt.Errorf("failed to mark refresh token as rotated: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, familyID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllForUser ends every session of the user, including the ones held
+// by OAuth clients.
+func (r *repository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, userID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens for user: %w", err)
+	}
+
+	return nil
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,1076 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/accounttoken"
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/infra/mailer"
+	"github.com/felipeversiane/auth-service/internal/mfa"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/throttle"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/google/uuid"
+)
+
+const (
+	invalidCredentialsMessage  = "invalid email or password"
+	invalidRefreshTokenMessage = "invalid refresh token"
+	invalidResetTokenMessage   = "invalid or expired reset token"
+	invalidLoginTokenMessage   = "invalid or expired sign-in link or code"
+	unverifiedEmailMessage     = "email address is not verified"
+	deactivatedAccountMessage  = "account is deactivated"
+	throttledLoginMessage      = "too many failed login attempts, try again later"
+
+	accountMailSendTimeout = 30 * time.Second
+
+	loginMethodPassword  = "password"
+	loginMethodPasskey   = "passkey"
+	loginMethodLoginLink = "login_link"
+	loginMethodLoginCode = "login_code"
+
+	passwordlessMethodCode = "code"
+
+	passwordChangeReset = "reset"
+)
+
+type service struct {
+	config        config.TokenConfig
+	account       config.AccountConfig
+	db            database.DatabaseInterface
+	repository    RepositoryInterface
+	users         user.RepositoryInterface
+	accountTokens accounttoken.RepositoryInterface
+	tokens        token.ManagerInterface
+	mfa           mfa.ServiceInterface
+	passkeys      passkey.ServiceInterface
+	rbac          rbac.ServiceInterface
+	orgs          org.ServiceInterface
+	throttle      throttle.TrackerInterface
+	mailer        mailer.MailerInterface
+	events        security.EmitterInterface
+	outbox        outbox.PublisherInterface
+	audit         audit.RecorderInterface
+	// backends check passwords, the one stored with the account first.
+	backends []BackendInterface
+}
+
+type ServiceInterface interface {
+	Login(ctx context.Context, req LoginRequest) (*LoginResponse, *httperr.HttpError)
+	VerifyMFA(ctx context.Context, req MFAVerifyRequest) (*TokenResponse, *httperr.HttpError)
+	BeginMFAPasskey(ctx context.Context, req MFAPasskeyRequest) (*passkey.BeginResponse, *httperr.HttpError)
+	BeginPasskeyLogin(ctx context.Context) (*passkey.BeginResponse, *httperr.HttpError)
+	FinishPasskeyLogin(ctx context.Context, req passkey.FinishLoginRequest) (*TokenResponse, *httperr.HttpError)
+	Refresh(ctx context.Context, req RefreshRequest) (*TokenResponse, *httperr.HttpError)
+	SwitchOrg(ctx context.Context, req SwitchOrgRequest) (*TokenResponse, *httperr.HttpError)
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest)
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) *httperr.HttpError
+	// StartPasswordless mails a sign-in link or code bound to the returned
+	// nonce, which the caller keeps in the requesting browser's cookie.
+	StartPasswordless(ctx context.Context, req PasswordlessStartRequest) (*PasswordlessStartResponse, string, *httperr.HttpError)
+	// VerifyPasswordless signs in with a mailed link or code presented along
+	// with the nonce it was bound to.
+	VerifyPasswordless(ctx context.Context, nonce string, req PasswordlessVerifyRequest) (*LoginResponse, *httperr.HttpError)
+	Authenticate(ctx context.Context, email, password string) (domain.UserInterface, *httperr.HttpError)
+	// RequireSecondFactor returns an MFA challenge token and its lifetime
+	// for a user whose first factor was accepted, or an empty token when
+	// they have no second factor and the login is complete.
+	RequireSecondFactor(ctx context.Context, found domain.UserInterface) (string, int64, *httperr.HttpError)
+	// VerifySecondFactor answers a challenge of RequireSecondFactor and
+	// returns the user it signs in.
+	VerifySecondFactor(ctx context.Context, rawToken string, proof mfa.Proof) (uuid.UUID, *httperr.HttpError)
+	IssueRefreshToken(ctx context.Context, userID uuid.UUID, clientID, scope string) (string, *httperr.HttpError)
+	RotateRefreshToken(ctx context.Context, rawToken, clientID string) (domain.RefreshTokenInterface, string, *httperr.HttpError)
+	FindRefreshToken(ctx context.Context, rawToken string) (domain.RefreshTokenInterface, *httperr.HttpError)
+	RevokeRefreshToken(ctx context.Context, rawToken, clientID string) *httperr.HttpError
+	// CompleteLogin finishes a login an external identity provider vouched
+	// for like a password login: it issues tokens acting in orgID when
+	// given, or the second factor challenge when the user has one. method
+	// is recorded with the login.
+	CompleteLogin(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, method string) (*LoginResponse, *httperr.HttpError)
+}
+
+func NewService(
+	config config.TokenConfig,
+	account config.AccountConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	accountTokens accounttoken.RepositoryInterface,
+	tokens token.ManagerInterface,
+	mfa mfa.ServiceInterface,
+	passkeys passkey.ServiceInterface,
+	rbac rbac.ServiceInterface,
+	orgs org.ServiceInterface,
+	throttle throttle.TrackerInterface,
+	mailer mailer.MailerInterface,
+	events security.EmitterInterface,
+	outbox outbox.PublisherInterface,
+	audit audit.RecorderInterface,
+	backends Backends,
+) (ServiceInterface, error) {
+	// The dummy is compared against when the email is unknown so that both
+	// failure paths pay the same bcrypt cost.
+	dummy, err := domain.New("", uuid.NewString(), "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	local := &passwordBackend{dummy: dummy}
+
+	return &service{
+		config:        config,
+		account:       account,
+		db:            db,
+		repository:    repository,
+		users:         users,
+		accountTokens: accountTokens,
+		tokens:        tokens,
+		mfa:           mfa,
+		passkeys:      passkeys,
+		rbac:          rbac,
+		orgs:          orgs,
+		throttle:      throttle,
+		mailer:        mailer,
+		events:        events,
+		outbox:        outbox,
+		audit:         audit,
+		backends:      append([]BackendInterface{local}, backends...),
+	}, nil
+}
+
+// Login checks the password and either issues tokens or, when the user has a
+// second factor, returns a challenge instead of a session.
+func (s *service) Login(ctx context.Context, req LoginRequest) (*LoginResponse, *httperr.HttpError) {
+	found, restErr := s.Authenticate(ctx, req.Email, req.Password)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return s.completeLogin(ctx, found, nil)
+}
+
+// completeLogin issues tokens acting in orgID to a user whose first factor
+// was accepted, or the second factor challenge when they have one.
+func (s *service) completeLogin(ctx context.Context, found domain.UserInterface, orgID *uuid.UUID) (*LoginResponse, *httperr.HttpError) {
+	challenge, expiresIn, restErr := s.requireSecondFactor(ctx, found, orgID)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if challenge != "" {
+		return &LoginResponse{MFARequired: true, MFAToken: challenge, MFATokenExpiresIn: expiresIn}, nil
+	}
+
+	res, restErr := s.startSession(ctx, found.GetID(), orgID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return &LoginResponse{TokenResponse: res}, nil
+}
+
+// VerifyMFA starts the session in the organization the login was for.
+func (s *service) VerifyMFA(ctx context.Context, req MFAVerifyRequest) (*TokenResponse, *httperr.HttpError) {
+	challenge, restErr := s.verifySecondFactor(ctx, req.MFAToken, mfa.Proof{
+		Code:         req.Code,
+		RecoveryCode: req.RecoveryCode,
+		Passkey:      req.Passkey,
+	})
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return s.startSession(ctx, challenge.GetUserID(), challenge.GetOrgID())
+}
+
+func (s *service) BeginMFAPasskey(ctx context.Context, req MFAPasskeyRequest) (*passkey.BeginResponse, *httperr.HttpError) {
+	return s.mfa.BeginPasskeyChallenge(ctx, req.MFAToken)
+}
+
+func (s *service) BeginPasskeyLogin(ctx context.Context) (*passkey.BeginResponse, *httperr.HttpError) {
+	return s.passkeys.BeginLogin(ctx, nil)
+}
+
+// FinishPasskeyLogin signs in without a password. The assertion required
+// user verification, so the passkey alone satisfies MFA. The login names no
+// account until the assertion is checked, so attempts are throttled by the
+// client address alone.
+func (s *service) FinishPasskeyLogin(ctx context.Context, req passkey.FinishLoginRequest) (*TokenResponse, *httperr.HttpError) {
+	ip := requestinfo.FromContext(ctx).IP
+	if restErr := s.checkThrottle(ctx, "", ip); restErr != nil {
+		return nil, restErr
+	}
+
+	userID, restErr := s.passkeys.FinishLogin(ctx, nil, req)
+	if restErr != nil {
+		if restErr.Code < http.StatusInternalServerError {
+			s.recordLoginFailure(ctx, "", ip, nil, loginMethodPasskey)
+		}
+		return nil, restErr
+	}
+
+	s.recordLogin(ctx, domain.AuditOutcomeSuccess, loginMethodPasskey, userID.String(), "")
+	s.resetThrottle(ctx, "")
+	return s.startSession(ctx, userID, nil)
+}
+
+func (s *service) Refresh(ctx context.Context, req RefreshRequest) (*TokenResponse, *httperr.HttpError) {
+	next, rawNext, restErr := s.RotateRefreshToken(ctx, req.RefreshToken, "")
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	found, restErr := s.sessionUser(ctx, next.GetUserID())
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return s.issueTokens(ctx, found, next.GetOrgID(), rawNext)
+}
+
+// SwitchOrg rotates a first-party refresh token into a session acting in
+// another organization the user belongs to, or in none when org_id is empty.
+// The old refresh token stops working like after any refresh.
+func (s *service) SwitchOrg(ctx context.Context, req SwitchOrgRequest) (*TokenResponse, *httperr.HttpError) {
+	var orgID *uuid.UUID
+	if req.OrgID != "" {
+		parsed, err := uuid.Parse(req.OrgID)
+		if err != nil {
+			return nil, httperr.NewBadRequestError("org_id must be a uuid")
+		}
+		orgID = &parsed
+	}
+
+	current, restErr := s.FindRefreshToken(ctx, req.RefreshToken)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	if orgID != nil {
+		member, restErr := s.orgs.IsMember(ctx, *orgID, current.GetUserID())
+		if restErr != nil {
+			return nil, restErr
+		}
+		if !member {
+			return nil, httperr.NewForbiddenError("not a member of the organization")
+		}
+	}
+
+	next, rawNext, restErr := s.rotateRefreshToken(ctx, req.RefreshToken, "", func(ctx context.Context, next domain.RefreshTokenInterface) error {
+		metadata := map[string]string{}
+		if from := next.GetOrgID(); from != nil {
+			metadata["from_org_id"] = from.String()
+		}
+		if orgID != nil {
+			metadata["to_org_id"] = orgID.String()
+		}
+		next.SwitchOrg(orgID)
+
+		userID := next.GetUserID().String()
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionSwitchOrg,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Actor:      &requestinfo.Actor{Type: requestinfo.ActorUser, ID: userID},
+			Metadata:   metadata,
+		})
+	})
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	found, restErr := s.sessionUser(ctx, next.GetUserID())
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return s.issueTokens(ctx, found, next.GetOrgID(), rawNext)
+}
+
+// ForgotPassword mails a reset link when the email belongs to an account.
+// The caller always gets the same answer, and the work happens in the
+// background so response times do not reveal which emails are registered.
+func (s *service) ForgotPassword(ctx context.Context, req ForgotPasswordRequest) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), accountMailSendTimeout)
+
+	go func() {
+		defer cancel()
+
+		found, err := s.users.FindByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, user.ErrUserNotFound) {
+				slog.Error("failed to look up user by email", "error", err)
+			}
+			return
+		}
+
+		if err := s.sendPasswordReset(ctx, found); err != nil {
+			slog.Error("failed to send password reset email", "error", err)
+		}
+	}()
+}
+
+// ResetPassword sets a new password with a mailed reset token and signs the
+// user out everywhere. Following the link also proves control of the email.
+func (s *service) ResetPassword(ctx context.Context, req ResetPasswordRequest) *httperr.HttpError {
+	var found domain.UserInterface
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.accountTokens.Consume(ctx, domain.HashOpaqueToken(req.Token), domain.AccountTokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		found, err = s.users.FindByID(ctx, token.GetUserID())
+		if err != nil {
+			return err
+		}
+
+		if err := found.ChangePassword(req.Password); err != nil {
+			return err
+		}
+		if err := s.users.UpdatePassword(ctx, found); err != nil {
+			return err
+		}
+
+		if !found.IsEmailVerified() {
+			found.VerifyEmail()
+			if err := s.users.MarkEmailVerified(ctx, found); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repository.RevokeAllForUser(ctx, found.GetID()); err != nil {
+			return err
+		}
+
+		err = s.outbox.Publish(ctx, outbox.PasswordChanged{UserID: found.GetID(), Method: passwordChangeReset})
+		if err != nil {
+			return err
+		}
+
+		userID := found.GetID().String()
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionPasswordReset,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetUser,
+			TargetID:   userID,
+			Actor:      &requestinfo.Actor{Type: requestinfo.ActorUser, ID: userID},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, accounttoken.ErrAccountTokenNotFound) || errors.Is(err, user.ErrUserNotFound) {
+			return httperr.NewBadRequestError(invalidResetTokenMessage)
+		}
+		// The token is left unused, so the user can retry with it.
+		if errors.Is(err, domain.ErrPasswordTooLong) {
+			return httperr.NewBadRequestError(domain.ErrPasswordTooLong.Error())
+		}
+		slog.Error("failed to reset password", "error", err)
+		return httperr.NewInternalServerError("failed to reset password")
+	}
+
+	s.events.Emit(ctx, security.Event{
+		Type:   security.EventPasswordReset,
+		UserID: found.GetID().String(),
+	})
+
+	return nil
+}
+
+// StartPasswordless mails a sign-in link, or a code, in place of a password.
+// Like ForgotPassword it answers the same whether the email is registered
+// and sends in the background. The token is bound to a fresh nonce, so it
+// only works from the browser that asked for it; a newer request replaces
+// the previous one.
+func (s *service) StartPasswordless(
+	ctx context.Context,
+	req PasswordlessStartRequest,
+) (*PasswordlessStartResponse, string, *httperr.HttpError) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if restErr := s.checkThrottle(ctx, email, requestinfo.FromContext(ctx).IP); restErr != nil {
+		return nil, "", restErr
+	}
+
+	purpose, ttl := domain.AccountTokenPurposeLoginLink, time.Duration(s.account.LoginLinkTTL)*time.Second
+	if req.Method == passwordlessMethodCode {
+		purpose, ttl = domain.AccountTokenPurposeLoginCode, time.Duration(s.account.LoginCodeTTL)*time.Second
+	}
+	nonce := domain.NewLoginNonce()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), accountMailSendTimeout)
+	go func() {
+		defer cancel()
+
+		found, err := s.users.FindByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, user.ErrUserNotFound) {
+				slog.Error("failed to look up user by email", "error", err)
+			}
+			return
+		}
+		if found.IsDeactivated() {
+			return
+		}
+
+		if err := s.sendLoginToken(ctx, found, purpose, nonce, ttl); err != nil {
+			slog.Error("failed to send sign-in email", "error", err)
+		}
+	}()
+
+	return &PasswordlessStartResponse{ExpiresIn: int64(ttl.Seconds())}, nonce, nil
+}
+
+// VerifyPasswordless signs in with a mailed link or code and issues the same
+// tokens as a password login, second factor included. Attempts share the
+// password throttle: codes are counted against the email they were sent
+// to, links, which name no account, against the client address. Using
+// either proves control of the email.
+func (s *service) VerifyPasswordless(
+	ctx context.Context,
+	nonce string,
+	req PasswordlessVerifyRequest,
+) (*LoginResponse, *httperr.HttpError) {
+	ip := requestinfo.FromContext(ctx).IP
+	method, purpose, secret, email := loginMethodLoginLink, domain.AccountTokenPurposeLoginLink, req.Token, ""
+	if req.Code != "" {
+		method, purpose, secret = loginMethodLoginCode, domain.AccountTokenPurposeLoginCode, req.Code
+		email = strings.ToLower(strings.TrimSpace(req.Email))
+	}
+
+	if restErr := s.checkThrottle(ctx, email, ip); restErr != nil {
+		return nil, restErr
+	}
+
+	var found domain.UserInterface
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if nonce == "" {
+			return accounttoken.ErrAccountTokenNotFound
+		}
+
+		loginToken, err := s.accountTokens.Consume(ctx, domain.HashLoginToken(nonce, secret), purpose)
+		if err != nil {
+			return err
+		}
+
+		found, err = s.users.FindByID(ctx, loginToken.GetUserID())
+		if err != nil {
+			return err
+		}
+		// Rolling back leaves the code to the account it was sent to.
+		if email != "" && !strings.EqualFold(found.GetEmail(), email) {
+			return accounttoken.ErrAccountTokenNotFound
+		}
+
+		if found.IsEmailVerified() {
+			return nil
+		}
+		found.VerifyEmail()
+		return s.users.MarkEmailVerified(ctx, found)
+	})
+	if err != nil {
+		if errors.Is(err, accounttoken.ErrAccountTokenNotFound) || errors.Is(err, user.ErrUserNotFound) {
+			s.recordLoginFailure(ctx, email, ip, nil, method)
+			return nil, httperr.NewUnauthorizedRequestError(invalidLoginTokenMessage)
+		}
+		slog.Error("failed to verify sign-in token", "error", err)
+		return nil, httperr.NewInternalServerError("failed to authenticate")
+	}
+
+	s.recordLogin(ctx, domain.AuditOutcomeSuccess, method, found.GetID().String(), found.GetEmail())
+
+	if found.IsDeactivated() {
+		return nil, httperr.NewForbiddenError(deactivatedAccountMessage)
+	}
+
+	return s.completeLogin(ctx, found, nil)
+}
+
+// Authenticate checks a password login against the backends. The stored
+// password is checked in constant time with respect to whether the email
+// exists. Attempts are throttled per account, per client address and per
+// pair of both before the password is looked at. A right password does not
+// reset the throttle: the login is not over until RequireSecondFactor or
+// VerifySecondFactor says so.
+func (s *service) Authenticate(ctx context.Context, email, password string) (domain.UserInterface, *httperr.HttpError) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	ip := requestinfo.FromContext(ctx).IP
+
+	if restErr := s.checkThrottle(ctx, email, ip); restErr != nil {
+		return nil, restErr
+	}
+
+	found, err := s.users.FindByEmail(ctx, email)
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		slog.Error("failed to look up user by email", "error", err)
+		return nil, httperr.NewInternalServerError("failed to authenticate")
+	}
+
+	account, method, failed := s.checkPassword(ctx, email, password, found)
+	if account == nil {
+		s.recordLoginFailure(ctx, email, ip, found, loginMethodPassword)
+		if failed {
+			return nil, httperr.NewInternalServerError("failed to authenticate")
+		}
+		return nil, httperr.NewUnauthorizedRequestError(invalidCredentialsMessage)
+	}
+	found = account
+
+	s.recordLogin(ctx, domain.AuditOutcomeSuccess, method, found.GetID().String(), email)
+
+	if restErr := s.checkAccountPolicy(found); restErr != nil {
+		return nil, restErr
+	}
+
+	return found, nil
+}
+
+func (s *service) RequireSecondFactor(ctx context.Context, found domain.UserInterface) (string, int64, *httperr.HttpError) {
+	return s.requireSecondFactor(ctx, found, nil)
+}
+
+func (s *service) requireSecondFactor(ctx context.Context, found domain.UserInterface, orgID *uuid.UUID) (string, int64, *httperr.HttpError) {
+	enrolled, restErr := s.mfa.IsEnrolled(ctx, found.GetID())
+	if restErr != nil {
+		return "", 0, restErr
+	}
+
+	if enrolled {
+		return s.mfa.CreateChallenge(ctx, found.GetID(), orgID)
+	}
+
+	s.resetThrottle(ctx, found.GetEmail())
+	return "", 0, nil
+}
+
+func (s *service) VerifySecondFactor(ctx context.Context, rawToken string, proof mfa.Proof) (uuid.UUID, *httperr.HttpError) {
+	challenge, restErr := s.verifySecondFactor(ctx, rawToken, proof)
+	if restErr != nil {
+		return uuid.Nil, restErr
+	}
+	return challenge.GetUserID(), nil
+}
+
+// verifySecondFactor answers a challenge and returns it. Wrong answers
+// count against the same throttle as wrong passwords, for the account the
+// challenge belongs to and for the client address, so a stolen password
+// does not buy unlimited guesses at the code across challenges.
+func (s *service) verifySecondFactor(ctx context.Context, rawToken string, proof mfa.Proof) (domain.MFAChallengeInterface, *httperr.HttpError) {
+	ip := requestinfo.FromContext(ctx).IP
+
+	var found domain.UserInterface
+	challenge, restErr := s.mfa.FindChallenge(ctx, rawToken)
+	switch {
+	case restErr == nil:
+		var err error
+		found, err = s.users.FindByID(ctx, challenge.GetUserID())
+		if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+			slog.Error("failed to find user", "error", err)
+			return nil, httperr.NewInternalServerError("failed to verify mfa challenge")
+		}
+	case restErr.Code >= http.StatusInternalServerError:
+		return nil, restErr
+	}
+
+	var email string
+	if found != nil {
+		email = found.GetEmail()
+	}
+
+	if restErr := s.checkThrottle(ctx, email, ip); restErr != nil {
+		return nil, restErr
+	}
+
+	if _, restErr := s.mfa.VerifyChallenge(ctx, rawToken, proof); restErr != nil {
+		if restErr.Code == http.StatusUnauthorized {
+			// The mfa service audits the attempt itself.
+			s.reportLockouts(ctx, s.countLoginFailure(ctx, email, ip), found, email, ip)
+		}
+		return nil, restErr
+	}
+
+	s.resetThrottle(ctx, email)
+	return challenge, nil
+}
+
+// resetThrottle forgets the failed attempts of a login that went through.
+func (s *service) resetThrottle(ctx context.Context, email string) {
+	if err := s.throttle.RecordSuccess(ctx, email, requestinfo.FromContext(ctx).IP); err != nil {
+		slog.Warn("failed to reset login throttle", "error", err)
+	}
+}
+
+// checkThrottle refuses a login attempt while the email, the client address
+// or the pair of both are throttled.
+func (s *service) checkThrottle(ctx context.Context, email, ip string) *httperr.HttpError {
+	wait, err := s.throttle.Check(ctx, email, ip)
+	if err != nil {
+		slog.Error("failed to check login throttle", "error", err)
+		return httperr.NewInternalServerError("failed to authenticate")
+	}
+	if wait > 0 {
+		return httperr.NewTooManyRequestsError(throttledLoginMessage, wait)
+	}
+	return nil
+}
+
+// checkPassword tries the backends in turn and returns the account the
+// first to accept the password opens, with its name. failed tells that a
+// backend could not check it, so a refusal may be wrong.
+func (s *service) checkPassword(
+	ctx context.Context,
+	email, password string,
+	found domain.UserInterface,
+) (domain.UserInterface, string, bool) {
+	failed := false
+	for _, backend := range s.backends {
+		account, err := backend.Authenticate(ctx, email, password, found)
+		if err == nil {
+			return account, backend.Name(), false
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			slog.Error("failed to check password", "error", err, "backend", backend.Name())
+			failed = true
+		}
+	}
+	return nil, "", failed
+}
+
+func (s *service) IssueRefreshToken(ctx context.Context, userID uuid.UUID, clientID, scope string) (string, *httperr.HttpError) {
+	refreshToken, raw := domain.NewRefreshToken(userID, clientID, scope, s.refreshTokenTTL())
+	if err := s.repository.Create(ctx, refreshToken); err != nil {
+		slog.Error("failed to store refresh token", "error", err)
+		return "", httperr.NewInternalServerError("failed to issue refresh token")
+	}
+
+	return raw, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for its successor. Presenting
+// a token that was already rotated revokes its whole family.
+func (s *service) RotateRefreshToken(
+	ctx context.Context,
+	rawToken, clientID string,
+) (domain.RefreshTokenInterface, string, *httperr.HttpError) {
+	return s.rotateRefreshToken(ctx, rawToken, clientID, nil)
+}
+
+// rotateRefreshToken is RotateRefreshToken with a hook that may adjust the
+// successor before it is stored. The hook runs in the rotation's
+// transaction and an error from it aborts the rotation.
+func (s *service) rotateRefreshToken(
+	ctx context.Context,
+	rawToken, clientID string,
+	prepare func(ctx context.Context, next domain.RefreshTokenInterface) error,
+) (domain.RefreshTokenInterface, string, *httperr.HttpError) {
+	var (
+		current     domain.RefreshTokenInterface
+		next        domain.RefreshTokenInterface
+		rawNext     string
+		reuseFamily *uuid.UUID
+	)
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.repository.FindByHashForUpdate(ctx, domain.HashOpaqueToken(rawToken))
+		if err != nil {
+			return err
+		}
+		current = found
+
+		if found.GetClientID() != clientID || found.IsRevoked() || found.IsExpired(time.Now().UTC()) {
+			return nil
+		}
+
+		if found.IsRotated() {
+			familyID := found.GetFamilyID()
+			reuseFamily = &familyID
+			if err := s.repository.RevokeFamily(ctx, familyID); err != nil {
+				return err
+			}
+			return s.audit.Record(ctx, audit.Entry{
+				Action:     audit.ActionTokenReuse,
+				Outcome:    domain.AuditOutcomeFailure,
+				TargetType: audit.TargetToken,
+				TargetID:   found.GetID().String(),
+				Metadata: map[string]string{
+					"family_id": familyID.String(),
+					"user_id":   found.GetUserID().String(),
+				},
+			})
+		}
+
+		next, rawNext = found.Rotate(s.refreshTokenTTL())
+		if prepare != nil {
+			if err := prepare(ctx, next); err != nil {
+				return err
+			}
+		}
+		if err := s.repository.MarkRotated(ctx, found); err != nil {
+			return err
+		}
+
+		return s.repository.Create(ctx, next)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return nil, "", httperr.NewUnauthorizedRequestError(invalidRefreshTokenMessage)
+		}
+		slog.Error("failed to rotate refresh token", "error", err)
+		return nil, "", httperr.NewInternalServerError("failed to refresh token")
+	}
+
+	if reuseFamily != nil {
+		s.events.Emit(ctx, security.Event{
+			Type:   security.EventRefreshTokenReuse,
+			UserID: current.GetUserID().String(),
+			Attributes: map[string]string{
+				"family_id": reuseFamily.String(),
+				"token_id":  current.GetID().String(),
+			},
+		})
+		return nil, "", httperr.NewUnauthorizedRequestError(invalidRefreshTokenMessage)
+	}
+
+	if next == nil {
+		return nil, "", httperr.NewUnauthorizedRequestError(invalidRefreshTokenMessage)
+	}
+
+	return next, rawNext, nil
+}
+
+func (s *service) FindRefreshToken(ctx context.Context, rawToken string) (domain.RefreshTokenInterface, *httperr.HttpError) {
+	found, err := s.repository.FindByHash(ctx, domain.HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return nil, httperr.NewUnauthorizedRequestError(invalidRefreshTokenMessage)
+		}
+		slog.Error("failed to find refresh token", "error", err)
+		return nil, httperr.NewInternalServerError("failed to find refresh token")
+	}
+
+	return found, nil
+}
+
+// RevokeRefreshToken revokes the token's whole family. Unknown tokens and
+// tokens issued to another client are ignored, as RFC 7009 requires the
+// endpoint to answer the same way for both.
+func (s *service) RevokeRefreshToken(ctx context.Context, rawToken, clientID string) *httperr.HttpError {
+	found, err := s.repository.FindByHash(ctx, domain.HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenNotFound) {
+			return nil
+		}
+		slog.Error("failed to find refresh token", "error", err)
+		return httperr.NewInternalServerError("failed to revoke token")
+	}
+
+	if found.GetClientID() != clientID || found.IsRevoked() {
+		return nil
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.RevokeFamily(ctx, found.GetFamilyID()); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionTokenRevoke,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetToken,
+			TargetID:   found.GetID().String(),
+			Actor:      &requestinfo.Actor{Type: requestinfo.ActorClient, ID: clientID},
+			Metadata: map[string]string{
+				"token_type": "refresh_token",
+				"family_id":  found.GetFamilyID().String(),
+				"subject":    found.GetUserID().String(),
+			},
+		})
+	})
+	if err != nil {
+		slog.Error("failed to revoke refresh token", "error", err)
+		return httperr.NewInternalServerError("failed to revoke token")
+	}
+
+	return nil
+}
+
+func (s *service) CompleteLogin(
+	ctx context.Context,
+	userID uuid.UUID,
+	orgID *uuid.UUID,
+	method string,
+) (*LoginResponse, *httperr.HttpError) {
+	found, restErr := s.sessionUser(ctx, userID)
+	if restErr != nil {
+		s.recordLogin(ctx, domain.AuditOutcomeFailure, method, userID.String(), "")
+		return nil, restErr
+	}
+
+	s.recordLogin(ctx, domain.AuditOutcomeSuccess, method, userID.String(), "")
+	return s.completeLogin(ctx, found, orgID)
+}
+
+// startSession issues a first-party session acting in orgID, nil for none.
+func (s *service) startSession(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (*TokenResponse, *httperr.HttpError) {
+	found, restErr := s.sessionUser(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	refreshToken, raw := domain.NewRefreshToken(userID, "", "", s.refreshTokenTTL())
+	refreshToken.SwitchOrg(orgID)
+	if err := s.repository.Create(ctx, refreshToken); err != nil {
+		slog.Error("failed to store refresh token", "error", err)
+		return nil, httperr.NewInternalServerError("failed to issue refresh token")
+	}
+
+	return s.issueTokens(ctx, found, orgID, raw)
+}
+
+// sessionUser loads the user a first-party session is issued for, so the
+// access token reflects the current verification state, and refuses
+// deactivated accounts whichever way they signed in.
+func (s *service) sessionUser(ctx context.Context, userID uuid.UUID) (domain.UserInterface, *httperr.HttpError) {
+	found, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, httperr.NewUnauthorizedRequestError("user no longer exists")
+		}
+		slog.Error("failed to find user", "error", err)
+		return nil, httperr.NewInternalServerError("failed to issue tokens")
+	}
+
+	if restErr := s.checkAccountPolicy(found); restErr != nil {
+		return nil, restErr
+	}
+
+	return found, nil
+}
+
+// recordLoginFailure counts a failed login and reports any lockout it
+// caused. Unknown emails are counted too, so throttling does not reveal
+// which accounts exist.
+func (s *service) recordLoginFailure(ctx context.Context, email, ip string, found domain.UserInterface, method string) {
+	locked := s.countLoginFailure(ctx, email, ip)
+
+	var userID string
+	if found != nil {
+		userID = found.GetID().String()
+	}
+	s.recordLogin(ctx, domain.AuditOutcomeFailure, method, userID, email)
+
+	s.reportLockouts(ctx, locked, found, email, ip)
+}
+
+// countLoginFailure adds a failure to the throttle and returns the scopes
+// it locked.
+func (s *service) countLoginFailure(ctx context.Context, email, ip string) []string {
+	locked, err := s.throttle.RecordFailure(ctx, email, ip)
+	if err != nil {
+		slog.Error("failed to record login failure", "error", err)
+	}
+	return locked
+}
+
+// reportLockouts emits and audits the lockouts a failed attempt caused.
+func (s *service) reportLockouts(ctx context.Context, locked []string, found domain.UserInterface, email, ip string) {
+	var userID string
+	if found != nil {
+		userID = found.GetID().String()
+	}
+
+	for _, scope := range locked {
+		s.events.Emit(ctx, security.Event{
+			Type:   security.EventLoginLockout,
+			UserID: userID,
+			Attributes: map[string]string{
+				"scope": scope,
+				"ip":    ip,
+			},
+		})
+
+		err := s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionLoginLockout,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetLogin,
+			TargetID:   email,
+			Metadata:   map[string]string{"scope": scope},
+		})
+		if err != nil {
+			slog.Error("failed to audit login lockout", "error", err)
+		}
+	}
+}
+
+// recordLogin audits a credential check. A login changes nothing on its
+// own, so a failure to audit it is logged rather than refused. userID is
+// empty when the credentials matched no account; the attempt is then filed
+// under the email that was tried, if any.
+func (s *service) recordLogin(ctx context.Context, outcome, method, userID, email string) {
+	entry := audit.Entry{
+		Action:   audit.ActionLogin,
+		Outcome:  outcome,
+		Metadata: map[string]string{"method": method},
+	}
+	switch {
+	case userID != "":
+		entry.TargetType, entry.TargetID = audit.TargetUser, userID
+		entry.Actor = &requestinfo.Actor{Type: requestinfo.ActorUser, ID: userID}
+	case email != "":
+		entry.TargetType, entry.TargetID = audit.TargetLogin, email
+	}
+
+	if err := s.audit.Record(ctx, entry); err != nil {
+		slog.Error("failed to audit login", "error", err)
+	}
+}
+
+// checkAccountPolicy refuses to sign in deactivated users, and users who
+// have not verified their email when the policy blocks them. It runs only
+// after the credentials were accepted, so it does not reveal whether an
+// account exists.
+func (s *service) checkAccountPolicy(found domain.UserInterface) *httperr.HttpError {
+	if found.IsDeactivated() {
+		return httperr.NewForbiddenError(deactivatedAccountMessage)
+	}
+	if s.account.UnverifiedPolicy == config.UnverifiedPolicyBlock && !found.IsEmailVerified() {
+		return httperr.NewForbiddenError(unverifiedEmailMessage)
+	}
+	return nil
+}
+
+func (s *service) sendPasswordReset(ctx context.Context, found domain.UserInterface) error {
+	ttl := time.Duration(s.account.PasswordResetTTL) * time.Second
+	resetToken, raw := domain.NewAccountToken(found.GetID(), domain.AccountTokenPurposePasswordReset, ttl)
+
+	if err := s.accountTokens.Create(ctx, resetToken); err != nil {
+		return err
+	}
+
+	if err := s.accountTokens.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up account tokens", "error", err)
+	}
+
+	link, err := mailer.LinkWithToken(s.account.ResetPasswordURL, raw)
+	if err != nil {
+		return err
+	}
+
+	message, err := mailer.Compose(found.GetEmail(), mailer.TemplateResetPassword, mailer.LinkData{
+		FirstName: found.GetFirstName(),
+		Link:      link,
+		ExpiresIn: ttl,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, message)
+}
+
+func (s *service) sendLoginToken(
+	ctx context.Context,
+	found domain.UserInterface,
+	purpose, nonce string,
+	ttl time.Duration,
+) error {
+	loginToken, raw := domain.NewLoginToken(found.GetID(), purpose, nonce, ttl)
+
+	if err := s.accountTokens.Create(ctx, loginToken); err != nil {
+		return err
+	}
+
+	if err := s.accountTokens.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up account tokens", "error", err)
+	}
+
+	name, data := mailer.TemplateLoginCode, any(mailer.CodeData{
+		FirstName: found.GetFirstName(),
+		Code:      raw,
+		ExpiresIn: ttl,
+	})
+	if purpose == domain.AccountTokenPurposeLoginLink {
+		link, err := mailer.LinkWithToken(s.account.LoginLinkURL, raw)
+		if err != nil {
+			return err
+		}
+		name, data = mailer.TemplateLoginLink, mailer.LinkData{
+			FirstName: found.GetFirstName(),
+			Link:      link,
+			ExpiresIn: ttl,
+		}
+	}
+
+	message, err := mailer.Compose(found.GetEmail(), name, data)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, message)
+}
+
+// issueTokens signs an access token for the session. When an organization
+// is active, the roles granted there are added and its id becomes the
+// org_id claim; a user who has since left the organization gets a token
+// without one instead.
+func (s *service) issueTokens(
+	ctx context.Context,
+	found domain.UserInterface,
+	orgID *uuid.UUID,
+	rawRefreshToken string,
+) (*TokenResponse, *httperr.HttpError) {
+	var activeOrg string
+	if orgID != nil {
+		member, restErr := s.orgs.IsMember(ctx, *orgID, found.GetID())
+		if restErr != nil {
+			return nil, restErr
+		}
+		if member {
+			ctx = database.WithTenant(ctx, *orgID)
+			activeOrg = orgID.String()
+		}
+	}
+
+	roles, restErr := s.rbac.UserRoles(ctx, found.GetID())
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	emailVerified := found.IsEmailVerified()
+	accessToken, claims, err := s.tokens.IssueAccessToken(ctx, token.AccessTokenParams{
+		Subject:       found.GetID().String(),
+		EmailVerified: &emailVerified,
+		Roles:         roles,
+		OrgID:         activeOrg,
+	})
+	if err != nil {
+		slog.Error("failed to issue access token", "error", err)
+		return nil, httperr.NewInternalServerError("failed to issue tokens")
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(claims.ExpiresAt.Time).Seconds()),
+		RefreshToken: rawRefreshToken,
+	}, nil
+}
+
+func (s *service) refreshTokenTTL() time.Duration {
+	return time.Duration(s.config.RefreshTokenTTL) * time.Second
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/accounttoken"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/mfa"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/internal/throttle"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/google/uuid"
+)
+
+const (
+	testEmail    = "jane@example.com"
+	testPassword = "correct horse battery"
+	testIP       = "203.0.113.7"
+	testCode     = "123456"
+)
+
+func TestLoginWithSecondFactorResetsThrottleOnlyAfterIt(t *testing.T) {
+	env := newTestEnv(t, true)
+
+	res, restErr := env.service.Login(env.ctx, LoginRequest{Email: testEmail, Password: testPassword})
+	if restErr != nil {
+		t.Fatalf("Login() error = %v", restErr)
+	}
+	if !res.MFARequired || res.MFAToken == "" {
+		t.Fatalf("Login() = %+v, want an mfa challenge", res)
+	}
+	if len(env.throttle.successes) != 0 {
+		t.Fatalf("throttle reset after the password alone: %v", env.throttle.successes)
+	}
+
+	_, restErr = env.service.VerifySecondFactor(env.ctx, res.MFAToken, mfa.Proof{Code: "000000"})
+	if restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("VerifySecondFactor(wrong code) error = %v, want 401", restErr)
+	}
+	if want := []string{testEmail + " " + testIP}; !slices.Equal(env.throttle.failures, want) {
+		t.Fatalf("failures = %v, want %v", env.throttle.failures, want)
+	}
+	if len(env.throttle.successes) != 0 {
+		t.Fatalf("throttle reset after a wrong code: %v", env.throttle.successes)
+	}
+
+	userID, restErr := env.service.VerifySecondFactor(env.ctx, res.MFAToken, mfa.Proof{Code: testCode})
+	if restErr != nil || userID != env.userID {
+		t.Fatalf("VerifySecondFactor() = %s, %v", userID, restErr)
+	}
+	if want := []string{testEmail + " " + testIP}; !slices.Equal(env.throttle.successes, want) {
+		t.Fatalf("successes = %v, want %v", env.throttle.successes, want)
+	}
+}
+
+func TestRequireSecondFactorWithoutFactorResetsThrottle(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	found, restErr := env.service.Authenticate(env.ctx, testEmail, testPassword)
+	if restErr != nil {
+		t.Fatalf("Authenticate() error = %v", restErr)
+	}
+	if len(env.throttle.successes) != 0 {
+		t.Fatalf("Authenticate() reset the throttle: %v", env.throttle.successes)
+	}
+
+	challenge, _, restErr := env.service.RequireSecondFactor(env.ctx, found)
+	if restErr != nil || challenge != "" {
+		t.Fatalf("RequireSecondFactor() = %q, %v; want no challenge", challenge, restErr)
+	}
+	if want := []string{testEmail + " " + testIP}; !slices.Equal(env.throttle.successes, want) {
+		t.Fatalf("successes = %v, want %v", env.throttle.successes, want)
+	}
+}
+
+func TestVerifySecondFactorLocksOut(t *testing.T) {
+	env := newTestEnv(t, true)
+	challenge, _, restErr := env.service.RequireSecondFactor(env.ctx, env.account)
+	if restErr != nil {
+		t.Fatalf("RequireSecondFactor() error = %v", restErr)
+	}
+
+	env.throttle.lock = []string{throttle.ScopeAccount}
+	if _, restErr := env.service.VerifySecondFactor(env.ctx, challenge, mfa.Proof{Code: "000000"}); restErr == nil {
+		t.Fatal("VerifySecondFactor(wrong code) succeeded")
+	}
+	if len(env.events.Events) != 1 || env.events.Events[0].Type != security.EventLoginLockout {
+		t.Fatalf("events = %+v, want a lockout", env.events.Events)
+	}
+
+	env.throttle.wait = time.Minute
+	_, restErr = env.service.VerifySecondFactor(env.ctx, challenge, mfa.Proof{Code: testCode})
+	if restErr == nil || restErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("VerifySecondFactor() while locked error = %v, want 429", restErr)
+	}
+	if env.mfa.verified != 1 {
+		t.Fatalf("challenge checked %d times, want 1", env.mfa.verified)
+	}
+}
+
+func TestVerifySecondFactorUnknownChallengeCountsAgainstAddress(t *testing.T) {
+	env := newTestEnv(t, true)
+
+	if _, restErr := env.service.VerifySecondFactor(env.ctx, "unknown", mfa.Proof{Code: testCode}); restErr == nil {
+		t.Fatal("VerifySecondFactor(unknown challenge) succeeded")
+	}
+	if want := []string{" " + testIP}; !slices.Equal(env.throttle.failures, want) {
+		t.Fatalf("failures = %v, want %v", env.throttle.failures, want)
+	}
+}
+
+func TestFinishPasskeyLoginIsThrottledByAddress(t *testing.T) {
+	env := newTestEnv(t, false)
+	req := passkey.FinishLoginRequest{SessionToken: "session", Credential: []byte(`{}`)}
+
+	if _, restErr := env.service.FinishPasskeyLogin(env.ctx, req); restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FinishPasskeyLogin(bad assertion) error = %v, want 401", restErr)
+	}
+	if want := []string{" " + testIP}; !slices.Equal(env.throttle.failures, want) {
+		t.Fatalf("failures = %v, want %v", env.throttle.failures, want)
+	}
+
+	env.throttle.wait = time.Minute
+	_, restErr := env.service.FinishPasskeyLogin(env.ctx, req)
+	if restErr == nil || restErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("FinishPasskeyLogin() while throttled error = %v, want 429", restErr)
+	}
+	if env.passkeys.finished != 1 {
+		t.Fatalf("assertion checked %d times, want 1", env.passkeys.finished)
+	}
+}
+
+func TestSwitchOrgRejectsMalformedOrg(t *testing.T) {
+	env := newTestEnv(t, false)
+
+	_, restErr := env.service.SwitchOrg(env.ctx, SwitchOrgRequest{RefreshToken: "token", OrgID: "not-a-uuid"})
+	if restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("SwitchOrg(malformed org_id) error = %v, want 400", restErr)
+	}
+}
+
+func TestDeactivatedAccountCannotSignIn(t *testing.T) {
+	env := newTestEnv(t, false)
+	env.account.SetDeactivated(true)
+
+	if _, restErr := env.service.Authenticate(env.ctx, testEmail, testPassword); restErr == nil || restErr.Code != http.StatusForbidden {
+		t.Fatalf("Authenticate() error = %v, want 403", restErr)
+	}
+
+	if _, restErr := env.service.CompleteLogin(env.ctx, env.userID, nil, "oidc"); restErr == nil || restErr.Code != http.StatusForbidden {
+		t.Fatalf("CompleteLogin() error = %v, want 403", restErr)
+	}
+
+	_, restErr := env.service.VerifyPasswordless(env.ctx, domain.NewLoginNonce(), PasswordlessVerifyRequest{Token: "mailed"})
+	if restErr == nil || restErr.Code != http.StatusForbidden {
+		t.Fatalf("VerifyPasswordless() error = %v, want 403", restErr)
+	}
+
+	if len(env.throttle.successes) != 0 || env.mfa.created != 0 {
+		t.Fatalf("a deactivated account got past sign-in: successes = %v, challenges = %d", env.throttle.successes, env.mfa.created)
+	}
+}
+
+func TestCompleteLoginCarriesOrgThroughSecondFactor(t *testing.T) {
+	env := newTestEnv(t, true)
+	orgID := uuid.New()
+
+	res, restErr := env.service.CompleteLogin(env.ctx, env.userID, &orgID, "saml")
+	if restErr != nil {
+		t.Fatalf("CompleteLogin() error = %v", restErr)
+	}
+	if !res.MFARequired || res.TokenResponse != nil {
+		t.Fatalf("CompleteLogin() = %+v, want an mfa challenge", res)
+	}
+	if challenge := env.mfa.challenges[res.MFAToken]; challenge == nil || challenge.GetOrgID() == nil || *challenge.GetOrgID() != orgID {
+		t.Fatalf("challenge = %+v, want one for org %s", challenge, orgID)
+	}
+	if len(env.throttle.successes) != 0 {
+		t.Fatalf("throttle reset before the second factor: %v", env.throttle.successes)
+	}
+}
+
+type testEnv struct {
+	ctx      context.Context
+	service  ServiceInterface
+	account  domain.UserInterface
+	userID   uuid.UUID
+	mfa      *fakeMFA
+	passkeys *fakePasskeys
+	throttle *fakeTracker
+	events   *testutil.Emitter
+}
+
+func newTestEnv(t *testing.T, enrolled bool) *testEnv {
+	t.Helper()
+
+	account, err := domain.New(testEmail, testPassword, "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+
+	env := &testEnv{
+		ctx:      requestinfo.NewContext(context.Background(), requestinfo.Info{IP: testIP}),
+		account:  account,
+		userID:   account.GetID(),
+		mfa:      &fakeMFA{enrolled: enrolled, challenges: map[string]domain.MFAChallengeInterface{}},
+		passkeys: &fakePasskeys{},
+		throttle: &fakeTracker{},
+		events:   &testutil.Emitter{},
+	}
+
+	env.service, err = NewService(
+		config.TokenConfig{},
+		config.AccountConfig{},
+		testutil.DB{},
+		nil,
+		testutil.NewUsers(account),
+		fakeAccountTokens{userID: account.GetID()},
+		nil,
+		env.mfa,
+		env.passkeys,
+		nil,
+		nil,
+		env.throttle,
+		nil,
+		env.events,
+		nil,
+		&testutil.Recorder{},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return env
+}
+
+type fakeMFA struct {
+	mfa.ServiceInterface
+	enrolled   bool
+	challenges map[string]domain.MFAChallengeInterface
+	created    int
+	verified   int
+}
+
+func (m *fakeMFA) IsEnrolled(context.Context, uuid.UUID) (bool, *httperr.HttpError) {
+	return m.enrolled, nil
+}
+
+func (m *fakeMFA) CreateChallenge(_ context.Context, userID uuid.UUID, orgID *uuid.UUID) (string, int64, *httperr.HttpError) {
+	m.created++
+	challenge, raw := domain.NewMFAChallenge(userID, orgID, 5*time.Minute)
+	m.challenges[raw] = challenge
+	return raw, 300, nil
+}
+
+func (m *fakeMFA) FindChallenge(_ context.Context, rawToken string) (domain.MFAChallengeInterface, *httperr.HttpError) {
+	challenge, ok := m.challenges[rawToken]
+	if !ok {
+		return nil, httperr.NewUnauthorizedRequestError("invalid or expired mfa challenge")
+	}
+	return challenge, nil
+}
+
+func (m *fakeMFA) VerifyChallenge(ctx context.Context, rawToken string, proof mfa.Proof) (uuid.UUID, *httperr.HttpError) {
+	m.verified++
+	challenge, restErr := m.FindChallenge(ctx, rawToken)
+	if restErr != nil {
+		return uuid.Nil, restErr
+	}
+	if proof.Code != testCode {
+		return uuid.Nil, httperr.NewUnauthorizedRequestError("invalid code")
+	}
+	delete(m.challenges, rawToken)
+	return challenge.GetUserID(), nil
+}
+
+// fakePasskeys refuses every assertion.
+type fakePasskeys struct {
+	passkey.ServiceInterface
+	finished int
+}
+
+func (p *fakePasskeys) FinishLogin(context.Context, *uuid.UUID, passkey.FinishLoginRequest) (uuid.UUID, *httperr.HttpError) {
+	p.finished++
+	return uuid.Nil, httperr.NewUnauthorizedRequestError("invalid passkey assertion")
+}
+
+// fakeTracker records attempts as "email ip".
+type fakeTracker struct {
+	throttle.TrackerInterface
+	wait      time.Duration
+	lock      []string
+	failures  []string
+	successes []string
+}
+
+func (f *fakeTracker) Check(context.Context, string, string) (time.Duration, error) {
+	return f.wait, nil
+}
+
+func (f *fakeTracker) RecordFailure(_ context.Context, email, ip string) ([]string, error) {
+	f.failures = append(f.failures, email+" "+ip)
+	return f.lock, nil
+}
+
+func (f *fakeTracker) RecordSuccess(_ context.Context, email, ip string) error {
+	f.successes = append(f.successes, email+" "+ip)
+	return nil
+}
+
+// fakeAccountTokens accepts any token as one sent to userID.
+type fakeAccountTokens struct {
+	accounttoken.RepositoryInterface
+	userID uuid.UUID
+}
+
+func (a fakeAccountTokens) Consume(_ context.Context, _, purpose string) (domain.AccountTokenInterface, error) {
+	token, _ := domain.NewAccountToken(a.userID, purpose, time.Minute)
+	return token, nil
+}
@@ -0,0 +1,76 @@
+package domain
+
+import "time"
+
+type auditCheckpoint struct {
+	seq       int64
+	hash      []byte
+	keyID     string
+	publicKey string
+	signature string
+	createdAt time.Time
+}
+
+// AuditCheckpointInterface is a signed statement that the audit chain ended
+// at Seq with Hash. Signature is a JWS made with the service signing key
+// KeyID; PublicKey is the PEM encoded key, kept so checkpoints stay
+// checkable after the key has been rotated out of the JWKS.
+type AuditCheckpointInterface interface {
+	GetSeq() int64
+	GetHash() []byte
+	GetKeyID() string
+	GetPublicKey() string
+	GetSignature() string
+	GetCreatedAt() time.Time
+}
+
+func NewAuditCheckpoint(seq int64, hash []byte, keyID, publicKey, signature string) AuditCheckpointInterface {
+	return &auditCheckpoint{
+		seq:       seq,
+		hash:      hash,
+		keyID:     keyID,
+		publicKey: publicKey,
+		signature: signature,
+		createdAt: time.Now().UTC(),
+	}
+}
+
+func RestoreAuditCheckpoint(
+	seq int64,
+	hash []byte,
+	keyID, publicKey, signature string,
+	createdAt time.Time,
+) AuditCheckpointInterface {
+	return &auditCheckpoint{
+		seq:       seq,
+		hash:      hash,
+		keyID:     keyID,
+		publicKey: publicKey,
+		signature: signature,
+		createdAt: createdAt,
+	}
+}
+
+func (c *auditCheckpoint) GetSeq() int64 {
+	return c.seq
+}
+
+func (c *auditCheckpoint) GetHash() []byte {
+	return c.hash
+}
+
+func (c *auditCheckpoint) GetKeyID() string {
+	return c.keyID
+}
+
+func (c *auditCheckpoint) GetPublicKey() string {
+	return c.publicKey
+}
+
+func (c *auditCheckpoint) GetSignature() string {
+	return c.signature
+}
+
+func (c *auditCheckpoint) GetCreatedAt() time.Time {
+	return c.createdAt
+}
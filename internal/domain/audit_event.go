@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of an audited action.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEventParams are the facts an audit event records.
+type AuditEventParams struct {
+	Action     string
+	Outcome    string
+	ActorType  string
+	ActorID    string
+	TargetType string
+	TargetID   string
+	OrgID      *uuid.UUID
+	IP         string
+	UserAgent  string
+	TraceID    string
+	Metadata   map[string]string
+}
+
+type auditEvent struct {
+	id         uuid.UUID
+	seq        int64
+	occurredAt time.Time
+	params     AuditEventParams
+	prevHash   []byte
+	hash       []byte
+}
+
+// AuditEventInterface is one entry of the append-only audit log. Seq orders
+// the log; it and the hashes linking the event to its predecessor are set
+// when the event is appended.
+type AuditEventInterface interface {
+	GetID() uuid.UUID
+	GetSeq() int64
+	GetPrevHash() []byte
+	GetHash() []byte
+	GetOccurredAt() time.Time
+	GetAction() string
+	GetOutcome() string
+	GetActorType() string
+	GetActorID() string
+	GetTargetType() string
+	GetTargetID() string
+	GetOrgID() *uuid.UUID
+	GetIP() string
+	GetUserAgent() string
+	GetTraceID() string
+	GetMetadata() map[string]string
+	Chain(seq int64, prevHash, hash []byte)
+}
+
+func NewAuditEvent(params AuditEventParams) AuditEventInterface {
+	if params.Metadata == nil {
+		params.Metadata = map[string]string{}
+	}
+
+	// The database keeps microseconds; truncating here keeps the hashed
+	// time identical to the stored one.
+	return &auditEvent{
+		id:         uuid.New(),
+		occurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		params:     params,
+	}
+}
+
+func RestoreAuditEvent(
+	id uuid.UUID,
+	seq int64,
+	occurredAt time.Time,
+	params AuditEventParams,
+	prevHash, hash []byte,
+) AuditEventInterface {
+	return &auditEvent{
+		id:         id,
+		seq:        seq,
+		occurredAt: occurredAt,
+		params:     params,
+		prevHash:   prevHash,
+		hash:       hash,
+	}
+}
+
+func (e *auditEvent) GetID() uuid.UUID {
+	return e.id
+}
+
+func (e *auditEvent) GetSeq() int64 {
+	return e.seq
+}
+
+func (e *auditEvent) GetPrevHash() []byte {
+	return e.prevHash
+}
+
+func (e *auditEvent) GetHash() []byte {
+	return e.hash
+}
+
+func (e *auditEvent) GetOccurredAt() time.Time {
+	return e.occurredAt
+}
+
+func (e *auditEvent) GetAction() string {
+	return e.params.Action
+}
+
+func (e *auditEvent) GetOutcome() string {
+	return e.params.Outcome
+}
+
+func (e *auditEvent) GetActorType() string {
+	return e.params.ActorType
+}
+
+func (e *auditEvent) GetActorID() string {
+	return e.params.ActorID
+}
+
+func (e *auditEvent) GetTargetType() string {
+	return e.params.TargetType
+}
+
+func (e *auditEvent) GetTargetID() string {
+	return e.params.TargetID
+}
+
+func (e *auditEvent) GetOrgID() *uuid.UUID {
+	return e.params.OrgID
+}
+
+func (e *auditEvent) GetIP() string {
+	return e.params.IP
+}
+
+func (e *auditEvent) GetUserAgent() string {
+	return e.params.UserAgent
+}
+
+func (e *auditEvent) GetTraceID() string {
+	return e.params.TraceID
+}
+
+func (e *auditEvent) GetMetadata() map[string]string {
+	return e.params.Metadata
+}
+
+func (e *auditEvent) Chain(seq int64, prevHash, hash []byte) {
+	e.seq = seq
+	e.prevHash = prevHash
+	e.hash = hash
+}
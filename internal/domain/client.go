@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type client struct {
+	id           uuid.UUID
+	clientID     string
+	secretHash   string
+	name         string
+	redirectURIs []string
+	public       bool
+	createdAt    time.Time
+	updatedAt    time.Time
+}
+
+type ClientInterface interface {
+	GetID() uuid.UUID
+	GetClientID() string
+	GetSecretHash() string
+	GetName() string
+	GetRedirectURIs() []string
+	IsPublic() bool
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+	HasRedirectURI(redirectURI string) bool
+	CompareSecret(secret string) bool
+}
+
+// NewClient registers an OAuth client. Confidential clients get a generated
+// secret which is returned once and only stored hashed.
+func NewClient(name string, redirectURIs []string, public bool) (ClientInterface, string) {
+	var secret, secretHash string
+	if !public {
+		secret = generateOpaqueToken()
+		secretHash = HashOpaqueToken(secret)
+	}
+
+	now := time.Now().UTC()
+	return &client{
+		id:           uuid.Must(uuid.NewRandom()),
+		clientID:     uuid.NewString(),
+		secretHash:   secretHash,
+		name:         name,
+		redirectURIs: redirectURIs,
+		public:       public,
+		createdAt:    now,
+		updatedAt:    now,
+	}, secret
+}
+
+func RestoreClient(
+	id uuid.UUID,
+	clientID, secretHash, name string,
+	redirectURIs []string,
+	public bool,
+	createdAt, updatedAt time.Time,
+) ClientInterface {
+	return &client{
+		id:           id,
+		clientID:     clientID,
+		secretHash:   secretHash,
+		name:         name,
+		redirectURIs: redirectURIs,
+		public:       public,
+		createdAt:    createdAt,
+		updatedAt:    updatedAt,
+	}
+}
+
+func (c *client) GetID() uuid.UUID {
+	return c.id
+}
+
+func (c *client) GetClientID() string {
+	return c.clientID
+}
+
+func (c *client) GetSecretHash() string {
+	return c.secretHash
+}
+
+func (c *client) GetName() string {
+	return c.name
+}
+
+func (c *client) GetRedirectURIs() []string {
+	return c.redirectURIs
+}
+
+func (c *client) IsPublic() bool {
+	return c.public
+}
+
+func (c *client) GetCreatedAt() time.Time {
+	return c.createdAt
+}
+
+func (c *client) GetUpdatedAt() time.Time {
+	return c.updatedAt
+}
+
+// HasRedirectURI performs the exact string match required by OAuth 2.1.
+func (c *client) HasRedirectURI(redirectURI string) bool {
+	return slices.Contains(c.redirectURIs, redirectURI)
+}
+
+func (c *client) CompareSecret(secret string) bool {
+	if c.public {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.secretHash), []byte(HashOpaqueToken(secret))) == 1
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type invitation struct {
+	id         uuid.UUID
+	orgID      uuid.UUID
+	email      string
+	roleID     *uuid.UUID
+	invitedBy  *uuid.UUID
+	tokenHash  string
+	expiresAt  time.Time
+	acceptedAt *time.Time
+	createdAt  time.Time
+}
+
+type InvitationInterface interface {
+	GetID() uuid.UUID
+	GetOrgID() uuid.UUID
+	GetEmail() string
+	GetRoleID() *uuid.UUID
+	GetInvitedBy() *uuid.UUID
+	GetTokenHash() string
+	GetExpiresAt() time.Time
+	GetAcceptedAt() *time.Time
+	GetCreatedAt() time.Time
+	IsExpired(now time.Time) bool
+	IsAccepted() bool
+	Accept()
+}
+
+// NewInvitation asks the owner of email to join an organization with the
+// given role. Only the hash of the returned raw token is stored; the raw
+// value is mailed to the invitee.
+func NewInvitation(orgID uuid.UUID, email string, roleID, invitedBy *uuid.UUID, ttl time.Duration) (InvitationInterface, string) {
+	raw := generateOpaqueToken()
+	now := time.Now().UTC()
+
+	return &invitation{
+		id:        uuid.Must(uuid.NewRandom()),
+		orgID:     orgID,
+		email:     email,
+		roleID:    roleID,
+		invitedBy: invitedBy,
+		tokenHash: HashOpaqueToken(raw),
+		expiresAt: now.Add(ttl),
+		createdAt: now,
+	}, raw
+}
+
+func RestoreInvitation(
+	id, orgID uuid.UUID,
+	email string,
+	roleID, invitedBy *uuid.UUID,
+	tokenHash string,
+	expiresAt time.Time,
+	acceptedAt *time.Time,
+	createdAt time.Time,
+) InvitationInterface {
+	return &invitation{
+		id:         id,
+		orgID:      orgID,
+		email:      email,
+		roleID:     roleID,
+		invitedBy:  invitedBy,
+		tokenHash:  tokenHash,
+		expiresAt:  expiresAt,
+		acceptedAt: acceptedAt,
+		createdAt:  createdAt,
+	}
+}
+
+func (i *invitation) GetID() uuid.UUID {
+	return i.id
+}
+
+func (i *invitation) GetOrgID() uuid.UUID {
+	return i.orgID
+}
+
+func (i *invitation) GetEmail() string {
+	return i.email
+}
+
+func (i *invitation) GetRoleID() *uuid.UUID {
+	return i.roleID
+}
+
+func (i *invitation) GetInvitedBy() *uuid.UUID {
+	return i.invitedBy
+}
+
+func (i *invitation) GetTokenHash() string {
+	return i.tokenHash
+}
+
+func (i *invitation) GetExpiresAt() time.Time {
+	return i.expiresAt
+}
+
+func (i *invitation) GetAcceptedAt() *time.Time {
+	return i.acceptedAt
+}
+
+func (i *invitation) GetCreatedAt() time.Time {
+	return i.createdAt
+}
+
+func (i *invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.expiresAt)
+}
+
+func (i *invitation) IsAccepted() bool {
+	return i.acceptedAt != nil
+}
+
+func (i *invitation) Accept() {
+	now := time.Now().UTC()
+	i.acceptedAt = &now
+}
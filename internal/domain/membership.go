@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type membership struct {
+	orgID     uuid.UUID
+	userID    uuid.UUID
+	createdAt time.Time
+}
+
+// MembershipInterface links a user to an organization. Roles granted through
+// a membership only apply while that organization is active.
+type MembershipInterface interface {
+	GetOrgID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetCreatedAt() time.Time
+}
+
+func NewMembership(orgID, userID uuid.UUID) MembershipInterface {
+	return &membership{
+		orgID:     orgID,
+		userID:    userID,
+		createdAt: time.Now().UTC(),
+	}
+}
+
+func RestoreMembership(orgID, userID uuid.UUID, createdAt time.Time) MembershipInterface {
+	return &membership{
+		orgID:     orgID,
+		userID:    userID,
+		createdAt: createdAt,
+	}
+}
+
+func (m *membership) GetOrgID() uuid.UUID {
+	return m.orgID
+}
+
+func (m *membership) GetUserID() uuid.UUID {
+	return m.userID
+}
+
+func (m *membership) GetCreatedAt() time.Time {
+	return m.createdAt
+}
@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type mfaChallenge struct {
+	id         uuid.UUID
+	userID     uuid.UUID
+	orgID      *uuid.UUID
+	tokenHash  string
+	attempts   int
+	expiresAt  time.Time
+	consumedAt *time.Time
+	createdAt  time.Time
+}
+
+type MFAChallengeInterface interface {
+	GetID() uuid.UUID
+	GetUserID() uuid.UUID
+	// GetOrgID is the organization the session starts in, nil for none.
+	GetOrgID() *uuid.UUID
+	GetTokenHash() string
+	GetAttempts() int
+	GetExpiresAt() time.Time
+	GetConsumedAt() *time.Time
+	GetCreatedAt() time.Time
+	IsExpired(now time.Time) bool
+	IsConsumed() bool
+}
+
+// NewMFAChallenge is issued after a correct password for a user with a second
+// factor. The raw token is exchanged for a session once the factor is checked.
+func NewMFAChallenge(userID uuid.UUID, orgID *uuid.UUID, ttl time.Duration) (MFAChallengeInterface, string) {
+	raw := generateOpaqueToken()
+	now := time.Now().UTC()
+
+	return &mfaChallenge{
+		id:        uuid.Must(uuid.NewRandom()),
+		userID:    userID,
+		orgID:     orgID,
+		tokenHash: HashOpaqueToken(raw),
+		expiresAt: now.Add(ttl),
+		createdAt: now,
+	}, raw
+}
+
+func RestoreMFAChallenge(
+	id, userID uuid.UUID,
+	orgID *uuid.UUID,
+	tokenHash string,
+	attempts int,
+	expiresAt time.Time,
+	consumedAt *time.Time,
+	createdAt time.Time,
+) MFAChallengeInterface {
+	return &mfaChallenge{
+		id:         id,
+		userID:     userID,
+		orgID:      orgID,
+		tokenHash:  tokenHash,
+		attempts:   attempts,
+		expiresAt:  expiresAt,
+		consumedAt: consumedAt,
+		createdAt:  createdAt,
+	}
+}
+
+func (c *mfaChallenge) GetID() uuid.UUID {
+	return c.id
+}
+
+func (c *mfaChallenge) GetUserID() uuid.UUID {
+	return c.userID
+}
+
+func (c *mfaChallenge) GetOrgID() *uuid.UUID {
+	return c.orgID
+}
+
+func (c *mfaChallenge) GetTokenHash() string {
+	return c.tokenHash
+}
+
+func (c *mfaChallenge) GetAttempts() int {
+	return c.attempts
+}
+
+func (c *mfaChallenge) GetExpiresAt() time.Time {
+	return c.expiresAt
+}
+
+func (c *mfaChallenge) GetConsumedAt() *time.Time {
+	return c.consumedAt
+}
+
+func (c *mfaChallenge) GetCreatedAt() time.Time {
+	return c.createdAt
+}
+
+func (c *mfaChallenge) IsExpired(now time.Time) bool {
+	return !now.Before(c.expiresAt)
+}
+
+func (c *mfaChallenge) IsConsumed() bool {
+	return c.consumedAt != nil
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type organization struct {
+	id        uuid.UUID
+	name      string
+	slug      string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// OrganizationInterface is a tenant. Users stay global and join
+// organizations through memberships.
+type OrganizationInterface interface {
+	GetID() uuid.UUID
+	GetName() string
+	GetSlug() string
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+}
+
+func NewOrganization(name, slug string) OrganizationInterface {
+	now := time.Now().UTC()
+	return &organization{
+		id:        uuid.Must(uuid.NewRandom()),
+		name:      name,
+		slug:      slug,
+		createdAt: now,
+		updatedAt: now,
+	}
+}
+
+func RestoreOrganization(id uuid.UUID, name, slug string, createdAt, updatedAt time.Time) OrganizationInterface {
+	return &organization{
+		id:        id,
+		name:      name,
+		slug:      slug,
+		createdAt: createdAt,
+		updatedAt: updatedAt,
+	}
+}
+
+func (o *organization) GetID() uuid.UUID {
+	return o.id
+}
+
+func (o *organization) GetName() string {
+	return o.name
+}
+
+func (o *organization) GetSlug() string {
+	return o.slug
+}
+
+func (o *organization) GetCreatedAt() time.Time {
+	return o.createdAt
+}
+
+func (o *organization) GetUpdatedAt() time.Time {
+	return o.updatedAt
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type outboxEvent struct {
+	id            uuid.UUID
+	seq           int64
+	eventType     string
+	subject       string
+	payload       []byte
+	occurredAt    time.Time
+	attempts      int
+	lastError     string
+	nextAttemptAt time.Time
+	publishedAt   *time.Time
+}
+
+// OutboxEventInterface is a domain event waiting to be relayed to the
+// configured sinks. It is written in the transaction of the change it
+// describes, so it exists if and only if that change committed. Subject is
+// the ID of the entity the event is about and Payload its JSON encoded data.
+type OutboxEventInterface interface {
+	GetID() uuid.UUID
+	GetSeq() int64
+	GetType() string
+	GetSubject() string
+	GetPayload() []byte
+	GetOccurredAt() time.Time
+	GetAttempts() int
+	GetLastError() string
+	GetNextAttemptAt() time.Time
+	GetPublishedAt() *time.Time
+	IsPublished() bool
+	MarkPublished(now time.Time)
+	// Fail records a failed delivery attempt and when to try again.
+	Fail(reason string, nextAttemptAt time.Time)
+}
+
+func NewOutboxEvent(eventType, subject string, payload []byte) OutboxEventInterface {
+	now := time.Now().UTC()
+
+	return &outboxEvent{
+		id:            uuid.New(),
+		eventType:     eventType,
+		subject:       subject,
+		payload:       payload,
+		occurredAt:    now,
+		nextAttemptAt: now,
+	}
+}
+
+func RestoreOutboxEvent(
+	id uuid.UUID,
+	seq int64,
+	eventType, subject string,
+	payload []byte,
+	occurredAt time.Time,
+	attempts int,
+	lastError string,
+	nextAttemptAt time.Time,
+	publishedAt *time.Time,
+) OutboxEventInterface {
+	return &outboxEvent{
+		id:            id,
+		seq:           seq,
+		eventType:     eventType,
+		subject:       subject,
+		payload:       payload,
+		occurredAt:    occurredAt,
+		attempts:      attempts,
+		lastError:     lastError,
+		nextAttemptAt: nextAttemptAt,
+		publishedAt:   publishedAt,
+	}
+}
+
+func (e *outboxEvent) GetID() uuid.UUID {
+	return e.id
+}
+
+func (e *outboxEvent) GetSeq() int64 {
+	return e.seq
+}
+
+func (e *outboxEvent) GetType() string {
+	return e.eventType
+}
+
+func (e *outboxEvent) GetSubject() string {
+	return e.subject
+}
+
+func (e *outboxEvent) GetPayload() []byte {
+	return e.payload
+}
+
+func (e *outboxEvent) GetOccurredAt() time.Time {
+	return e.occurredAt
+}
+
+func (e *outboxEvent) GetAttempts() int {
+	return e.attempts
+}
+
+func (e *outboxEvent) GetLastError() string {
+	return e.lastError
+}
+
+func (e *outboxEvent) GetNextAttemptAt() time.Time {
+	return e.nextAttemptAt
+}
+
+func (e *outboxEvent) GetPublishedAt() *time.Time {
+	return e.publishedAt
+}
+
+func (e *outboxEvent) IsPublished() bool {
+	return e.publishedAt != nil
+}
+
+func (e *outboxEvent) MarkPublished(now time.Time) {
+	e.attempts++
+	e.lastError = ""
+	e.publishedAt = &now
+}
+
+func (e *outboxEvent) Fail(reason string, nextAttemptAt time.Time) {
+	e.attempts++
+	e.lastError = reason
+	e.nextAttemptAt = nextAttemptAt
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type passkey struct {
+	id              uuid.UUID
+	userID          uuid.UUID
+	name            string
+	credentialID    []byte
+	publicKey       []byte
+	attestationType string
+	transports      []string
+	aaguid          []byte
+	signCount       uint32
+	backupEligible  bool
+	backupState     bool
+	cloneWarning    bool
+	lastUsedAt      *time.Time
+	createdAt       time.Time
+}
+
+type PasskeyInterface interface {
+	GetID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetName() string
+	GetCredentialID() []byte
+	GetPublicKey() []byte
+	GetAttestationType() string
+	GetTransports() []string
+	GetAAGUID() []byte
+	GetSignCount() uint32
+	IsBackupEligible() bool
+	IsBackedUp() bool
+	HasCloneWarning() bool
+	GetLastUsedAt() *time.Time
+	GetCreatedAt() time.Time
+	RecordUse(signCount uint32, backupState bool, now time.Time)
+	MarkCloned()
+}
+
+func NewPasskey(
+	userID uuid.UUID,
+	name string,
+	credentialID, publicKey []byte,
+	attestationType string,
+	transports []string,
+	aaguid []byte,
+	signCount uint32,
+	backupEligible, backupState bool,
+) PasskeyInterface {
+	return &passkey{
+		id:              uuid.Must(uuid.NewRandom()),
+		userID:          userID,
+		name:            name,
+		credentialID:    credentialID,
+		publicKey:       publicKey,
+		attestationType: attestationType,
+		transports:      transports,
+		aaguid:          aaguid,
+		signCount:       signCount,
+		backupEligible:  backupEligible,
+		backupState:     backupState,
+		createdAt:       time.Now().UTC(),
+	}
+}
+
+func RestorePasskey(
+	id, userID uuid.UUID,
+	name string,
+	credentialID, publicKey []byte,
+	attestationType string,
+	transports []string,
+	aaguid []byte,
+	signCount uint32,
+	backupEligible, backupState, cloneWarning bool,
+	lastUsedAt *time.Time,
+	createdAt time.Time,
+) PasskeyInterface {
+	return &passkey{
+		id:              id,
+		userID:          userID,
+		name:            name,
+		credentialID:    credentialID,
+		publicKey:       publicKey,
+		attestationType: attestationType,
+		transports:      transports,
+		aaguid:          aaguid,
+		signCount:       signCount,
+		backupEligible:  backupEligible,
+		backupState:     backupState,
+		cloneWarning:    cloneWarning,
+		lastUsedAt:      lastUsedAt,
+		createdAt:       createdAt,
+	}
+}
+
+func (p *passkey) GetID() uuid.UUID {
+	return p.id
+}
+
+func (p *passkey) GetUserID() uuid.UUID {
+	return p.userID
+}
+
+func (p *passkey) GetName() string {
+	return p.name
+}
+
+func (p *passkey) GetCredentialID() []byte {
+	return p.credentialID
+}
+
+func (p *passkey) GetPublicKey() []byte {
+	return p.publicKey
+}
+
+func (p *passkey) GetAttestationType() string {
+	return p.attestationType
+}
+
+func (p *passkey) GetTransports() []string {
+	return p.transports
+}
+
+func (p *passkey) GetAAGUID() []byte {
+	return p.aaguid
+}
+
+func (p *passkey) GetSignCount() uint32 {
+	return p.signCount
+}
+
+func (p *passkey) IsBackupEligible() bool {
+	return p.backupEligible
+}
+
+func (p *passkey) IsBackedUp() bool {
+	return p.backupState
+}
+
+func (p *passkey) HasCloneWarning() bool {
+	return p.cloneWarning
+}
+
+func (p *passkey) GetLastUsedAt() *time.Time {
+	return p.lastUsedAt
+}
+
+func (p *passkey) GetCreatedAt() time.Time {
+	return p.createdAt
+}
+
+func (p *passkey) RecordUse(signCount uint32, backupState bool, now time.Time) {
+	p.signCount = signCount
+	p.backupState = backupState
+	p.lastUsedAt = &now
+}
+
+// MarkCloned flags a credential whose signature counter went backwards; it
+// can no longer be used to sign in.
+func (p *passkey) MarkCloned() {
+	p.cloneWarning = true
+}
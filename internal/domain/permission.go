@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PermissionWildcard matches any action, any resource, or, as the last
+// character of a resource, any resource with that prefix.
+const PermissionWildcard = "*"
+
+type permission struct {
+	id          uuid.UUID
+	resource    string
+	action      string
+	description string
+	createdAt   time.Time
+}
+
+// PermissionInterface allows one action on a resource, written as
+// "resource:action", e.g. "users:read" or "orders/*:*".
+type PermissionInterface interface {
+	GetID() uuid.UUID
+	GetResource() string
+	GetAction() string
+	GetDescription() string
+	GetCreatedAt() time.Time
+	String() string
+	Allows(resource, action string) bool
+}
+
+func NewPermission(resource, action, description string) PermissionInterface {
+	return &permission{
+		id:          uuid.Must(uuid.NewRandom()),
+		resource:    resource,
+		action:      action,
+		description: description,
+		createdAt:   time.Now().UTC(),
+	}
+}
+
+func RestorePermission(id uuid.UUID, resource, action, description string, createdAt time.Time) PermissionInterface {
+	return &permission{
+		id:          id,
+		resource:    resource,
+		action:      action,
+		description: description,
+		createdAt:   createdAt,
+	}
+}
+
+func (p *permission) GetID() uuid.UUID {
+	return p.id
+}
+
+func (p *permission) GetResource() string {
+	return p.resource
+}
+
+func (p *permission) GetAction() string {
+	return p.action
+}
+
+func (p *permission) GetDescription() string {
+	return p.description
+}
+
+func (p *permission) GetCreatedAt() time.Time {
+	return p.createdAt
+}
+
+func (p *permission) String() string {
+	return p.resource + ":" + p.action
+}
+
+func (p *permission) Allows(resource, action string) bool {
+	return matchesPattern(p.resource, resource) && matchesPattern(p.action, action)
+}
+
+func matchesPattern(pattern, value string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, PermissionWildcard); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type refreshToken struct {
+	id        uuid.UUID
+	familyID  uuid.UUID
+	userID    uuid.UUID
+	clientID  string
+	scope     string
+	orgID     *uuid.UUID
+	parentID  *uuid.UUID
+	tokenHash string
+	expiresAt time.Time
+	rotatedAt *time.Time
+	revokedAt *time.Time
+	createdAt time.Time
+}
+
+type RefreshTokenInterface interface {
+	GetID() uuid.UUID
+	GetFamilyID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetClientID() string
+	GetScope() string
+	GetOrgID() *uuid.UUID
+	GetParentID() *uuid.UUID
+	GetTokenHash() string
+	GetExpiresAt() time.Time
+	GetRotatedAt() *time.Time
+	GetRevokedAt() *time.Time
+	GetCreatedAt() time.Time
+	IsExpired(now time.Time) bool
+	IsRotated() bool
+	IsRevoked() bool
+	Rotate(ttl time.Duration) (RefreshTokenInterface, string)
+	SwitchOrg(orgID *uuid.UUID)
+}
+
+// NewRefreshToken starts a new token family and returns the token together
+// with its raw value, which is only ever handed to the client. First-party
+// logins leave clientID and scope empty.
+func NewRefreshToken(userID uuid.UUID, clientID, scope string, ttl time.Duration) (RefreshTokenInterface, string) {
+	return newRefreshToken(uuid.Must(uuid.NewRandom()), userID, clientID, scope, nil, nil, ttl)
+}
+
+func RestoreRefreshToken(
+	id, familyID, userID uuid.UUID,
+	clientID, scope string,
+	orgID, parentID *uuid.UUID,
+	tokenHash string,
+	expiresAt time.Time,
+	rotatedAt, revokedAt *time.Time,
+	createdAt time.Time,
+) RefreshTokenInterface {
+	return &refreshToken{
+		id:        id,
+		familyID:  familyID,
+		userID:    userID,
+		clientID:  clientID,
+		scope:     scope,
+		orgID:     orgID,
+		parentID:  parentID,
+		tokenHash: tokenHash,
+		expiresAt: expiresAt,
+		rotatedAt: rotatedAt,
+		revokedAt: revokedAt,
+		createdAt: createdAt,
+	}
+}
+
+func newRefreshToken(
+	familyID, userID uuid.UUID,
+	clientID, scope string,
+	orgID, parentID *uuid.UUID,
+	ttl time.Duration,
+) (RefreshTokenInterface, string) {
+	raw := generateOpaqueToken()
+	now := time.Now().UTC()
+
+	return &refreshToken{
+		id:        uuid.Must(uuid.NewRandom()),
+		familyID:  familyID,
+		userID:    userID,
+		clientID:  clientID,
+		scope:     scope,
+		orgID:     orgID,
+		parentID:  parentID,
+		tokenHash: HashOpaqueToken(raw),
+		expiresAt: now.Add(ttl),
+		createdAt: now,
+	}, raw
+}
+
+func (t *refreshToken) GetID() uuid.UUID {
+	return t.id
+}
+
+func (t *refreshToken) GetFamilyID() uuid.UUID {
+	return t.familyID
+}
+
+func (t *refreshToken) GetUserID() uuid.UUID {
+	return t.userID
+}
+
+func (t *refreshToken) GetClientID() string {
+	return t.clientID
+}
+
+func (t *refreshToken) GetScope() string {
+	return t.scope
+}
+
+// GetOrgID is the organization the session is acting in, or nil when no
+// organization is active.
+func (t *refreshToken) GetOrgID() *uuid.UUID {
+	return t.orgID
+}
+
+func (t *refreshToken) GetParentID() *uuid.UUID {
+	return t.parentID
+}
+
+func (t *refreshToken) GetTokenHash() string {
+	return t.tokenHash
+}
+
+func (t *refreshToken) GetExpiresAt() time.Time {
+	return t.expiresAt
+}
+
+func (t *refreshToken) GetRotatedAt() *time.Time {
+	return t.rotatedAt
+}
+
+func (t *refreshToken) GetRevokedAt() *time.Time {
+	return t.revokedAt
+}
+
+func (t *refreshToken) GetCreatedAt() time.Time {
+	return t.createdAt
+}
+
+func (t *refreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.expiresAt)
+}
+
+func (t *refreshToken) IsRotated() bool {
+	return t.rotatedAt != nil
+}
+
+func (t *refreshToken) IsRevoked() bool {
+	return t.revokedAt != nil
+}
+
+// Rotate marks the token as used and returns its successor in the same family.
+func (t *refreshToken) Rotate(ttl time.Duration) (RefreshTokenInterface, string) {
+	now := time.Now().UTC()
+	t.rotatedAt = &now
+
+	parentID := t.id
+	return newRefreshToken(t.familyID, t.userID, t.clientID, t.scope, t.orgID, &parentID, ttl)
+}
+
+// SwitchOrg changes the active organization of a token that has not been
+// stored yet, typically the successor returned by Rotate.
+func (t *refreshToken) SwitchOrg(orgID *uuid.UUID) {
+	t.orgID = orgID
+}
+
+func HashOpaqueToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateOpaqueToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
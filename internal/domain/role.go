@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type role struct {
+	id          uuid.UUID
+	name        string
+	description string
+	createdAt   time.Time
+	updatedAt   time.Time
+}
+
+// RoleInterface groups permissions. A role inherits every permission of the
+// roles it extends, and users are granted roles rather than permissions.
+type RoleInterface interface {
+	GetID() uuid.UUID
+	GetName() string
+	GetDescription() string
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+}
+
+func NewRole(name, description string) RoleInterface {
+	now := time.Now().UTC()
+	return &role{
+		id:          uuid.Must(uuid.NewRandom()),
+		name:        name,
+		description: description,
+		createdAt:   now,
+		updatedAt:   now,
+	}
+}
+
+func RestoreRole(id uuid.UUID, name, description string, createdAt, updatedAt time.Time) RoleInterface {
+	return &role{
+		id:          id,
+		name:        name,
+		description: description,
+		createdAt:   createdAt,
+		updatedAt:   updatedAt,
+	}
+}
+
+func (r *role) GetID() uuid.UUID {
+	return r.id
+}
+
+func (r *role) GetName() string {
+	return r.name
+}
+
+func (r *role) GetDescription() string {
+	return r.description
+}
+
+func (r *role) GetCreatedAt() time.Time {
+	return r.createdAt
+}
+
+func (r *role) GetUpdatedAt() time.Time {
+	return r.updatedAt
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type scimGroup struct {
+	id          uuid.UUID
+	orgID       uuid.UUID
+	displayName string
+	externalID  string
+	version     int64
+	createdAt   time.Time
+	updatedAt   time.Time
+}
+
+// SCIMGroupInterface is a group an organization's identity provider pushes
+// along with its users. Members are stored apart, since groups can be
+// large and most changes only touch a few of them.
+type SCIMGroupInterface interface {
+	GetID() uuid.UUID
+	GetOrgID() uuid.UUID
+	GetDisplayName() string
+	GetExternalID() string
+	GetVersion() int64
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+	// Update also stands for a change of members, so the version moves
+	// with those too.
+	Update(displayName, externalID string)
+}
+
+func NewSCIMGroup(orgID uuid.UUID, displayName, externalID string) SCIMGroupInterface {
+	now := time.Now().UTC()
+
+	return &scimGroup{
+		id:          uuid.Must(uuid.NewRandom()),
+		orgID:       orgID,
+		displayName: displayName,
+		externalID:  externalID,
+		version:     1,
+		createdAt:   now,
+		updatedAt:   now,
+	}
+}
+
+func RestoreSCIMGroup(
+	id, orgID uuid.UUID,
+	displayName, externalID string,
+	version int64,
+	createdAt, updatedAt time.Time,
+) SCIMGroupInterface {
+	return &scimGroup{
+		id:          id,
+		orgID:       orgID,
+		displayName: displayName,
+		externalID:  externalID,
+		version:     version,
+		createdAt:   createdAt,
+		updatedAt:   updatedAt,
+	}
+}
+
+func (g *scimGroup) GetID() uuid.UUID {
+	return g.id
+}
+
+func (g *scimGroup) GetOrgID() uuid.UUID {
+	return g.orgID
+}
+
+func (g *scimGroup) GetDisplayName() string {
+	return g.displayName
+}
+
+func (g *scimGroup) GetExternalID() string {
+	return g.externalID
+}
+
+func (g *scimGroup) GetVersion() int64 {
+	return g.version
+}
+
+func (g *scimGroup) GetCreatedAt() time.Time {
+	return g.createdAt
+}
+
+func (g *scimGroup) GetUpdatedAt() time.Time {
+	return g.updatedAt
+}
+
+func (g *scimGroup) Update(displayName, externalID string) {
+	g.displayName = displayName
+	g.externalID = externalID
+	g.version++
+	g.updatedAt = time.Now().UTC()
+}
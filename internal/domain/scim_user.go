@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type scimUser struct {
+	orgID      uuid.UUID
+	userID     uuid.UUID
+	userName   string
+	externalID string
+	active     bool
+	extension  map[string]any
+	version    int64
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+// SCIMUserInterface is what an organization's identity provider knows of a
+// user it provisioned, beyond the account itself: the identifiers it goes
+// by, whether it is active, and the attributes of the enterprise extension.
+// Version changes with every write and backs the resource's ETag.
+type SCIMUserInterface interface {
+	GetOrgID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetUserName() string
+	GetExternalID() string
+	IsActive() bool
+	GetExtension() map[string]any
+	GetVersion() int64
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+	Update(userName, externalID string, active bool, extension map[string]any)
+}
+
+func NewSCIMUser(orgID, userID uuid.UUID, userName, externalID string, active bool, extension map[string]any) SCIMUserInterface {
+	now := time.Now().UTC()
+
+	return &scimUser{
+		orgID:      orgID,
+		userID:     userID,
+		userName:   userName,
+		externalID: externalID,
+		active:     active,
+		extension:  extension,
+		version:    1,
+		createdAt:  now,
+		updatedAt:  now,
+	}
+}
+
+func RestoreSCIMUser(
+	orgID, userID uuid.UUID,
+	userName, externalID string,
+	active bool,
+	extension map[string]any,
+	version int64,
+	createdAt, updatedAt time.Time,
+) SCIMUserInterface {
+	return &scimUser{
+		orgID:      orgID,
+		userID:     userID,
+		userName:   userName,
+		externalID: externalID,
+		active:     active,
+		extension:  extension,
+		version:    version,
+		createdAt:  createdAt,
+		updatedAt:  updatedAt,
+	}
+}
+
+func (u *scimUser) GetOrgID() uuid.UUID {
+	return u.orgID
+}
+
+func (u *scimUser) GetUserID() uuid.UUID {
+	return u.userID
+}
+
+func (u *scimUser) GetUserName() string {
+	return u.userName
+}
+
+func (u *scimUser) GetExternalID() string {
+	return u.externalID
+}
+
+func (u *scimUser) IsActive() bool {
+	return u.active
+}
+
+func (u *scimUser) GetExtension() map[string]any {
+	return u.extension
+}
+
+func (u *scimUser) GetVersion() int64 {
+	return u.version
+}
+
+func (u *scimUser) GetCreatedAt() time.Time {
+	return u.createdAt
+}
+
+func (u *scimUser) GetUpdatedAt() time.Time {
+	return u.updatedAt
+}
+
+func (u *scimUser) Update(userName, externalID string, active bool, extension map[string]any) {
+	u.userName = userName
+	u.externalID = externalID
+	u.active = active
+	u.extension = extension
+	u.version++
+	u.updatedAt = time.Now().UTC()
+}
@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type serviceAccount struct {
+	id         uuid.UUID
+	name       string
+	clientID   string
+	secretHash string
+	publicKey  string
+	audiences  []string
+	scopes     []string
+	disabledAt *time.Time
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+// ServiceAccountInterface is a machine principal. It authenticates with a
+// client secret or a registered public key and never has a password.
+type ServiceAccountInterface interface {
+	GetID() uuid.UUID
+	GetName() string
+	GetClientID() string
+	GetSecretHash() string
+	GetPublicKey() string
+	GetAudiences() []string
+	GetScopes() []string
+	GetDisabledAt() *time.Time
+	GetCreatedAt() time.Time
+	GetUpdatedAt() time.Time
+	IsDisabled() bool
+	HasSecret() bool
+	CompareSecret(secret string) bool
+	AllowsAudience(audience string) bool
+	AllowsScope(scope string) bool
+	RotateSecret() string
+	SetPublicKey(publicKey string)
+	Disable()
+}
+
+func NewServiceAccount(name string, audiences, scopes []string, publicKey string) (ServiceAccountInterface, string) {
+	now := time.Now().UTC()
+	account := &serviceAccount{
+		id:        uuid.Must(uuid.NewRandom()),
+		name:      name,
+		clientID:  "sa-" + uuid.NewString(),
+		publicKey: publicKey,
+		audiences: audiences,
+		scopes:    scopes,
+		createdAt: now,
+		updatedAt: now,
+	}
+
+	var secret string
+	if publicKey == "" {
+		secret = account.RotateSecret()
+	}
+
+	return account, secret
+}
+
+func RestoreServiceAccount(
+	id uuid.UUID,
+	name, clientID, secretHash, publicKey string,
+	audiences, scopes []string,
+	disabledAt *time.Time,
+	createdAt, updatedAt time.Time,
+) ServiceAccountInterface {
+	return &serviceAccount{
+		id:         id,
+		name:       name,
+		clientID:   clientID,
+		secretHash: secretHash,
+		publicKey:  publicKey,
+		audiences:  audiences,
+		scopes:     scopes,
+		disabledAt: disabledAt,
+		createdAt:  createdAt,
+		updatedAt:  updatedAt,
+	}
+}
+
+func (a *serviceAccount) GetID() uuid.UUID {
+	return a.id
+}
+
+func (a *serviceAccount) GetName() string {
+	return a.name
+}
+
+func (a *serviceAccount) GetClientID() string {
+	return a.clientID
+}
+
+func (a *serviceAccount) GetSecretHash() string {
+	return a.secretHash
+}
+
+func (a *serviceAccount) GetPublicKey() string {
+	return a.publicKey
+}
+
+func (a *serviceAccount) GetAudiences() []string {
+	return a.audiences
+}
+
+func (a *serviceAccount) GetScopes() []string {
+	return a.scopes
+}
+
+func (a *serviceAccount) GetDisabledAt() *time.Time {
+	return a.disabledAt
+}
+
+func (a *serviceAccount) GetCreatedAt() time.Time {
+	return a.createdAt
+}
+
+func (a *serviceAccount) GetUpdatedAt() time.Time {
+	return a.updatedAt
+}
+
+func (a *serviceAccount) IsDisabled() bool {
+	return a.disabledAt != nil
+}
+
+func (a *serviceAccount) HasSecret() bool {
+	return a.secretHash != ""
+}
+
+func (a *serviceAccount) CompareSecret(secret string) bool {
+	if !a.HasSecret() {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(a.secretHash), []byte(HashOpaqueToken(secret))) == 1
+}
+
+func (a *serviceAccount) AllowsAudience(audience string) bool {
+	return slices.Contains(a.audiences, audience)
+}
+
+func (a *serviceAccount) AllowsScope(scope string) bool {
+	return slices.Contains(a.scopes, scope)
+}
+
+// RotateSecret replaces the client secret and returns the new raw value.
+func (a *serviceAccount) RotateSecret() string {
+	secret := generateOpaqueToken()
+	a.secretHash = HashOpaqueToken(secret)
+	a.updatedAt = time.Now().UTC()
+	return secret
+}
+
+func (a *serviceAccount) SetPublicKey(publicKey string) {
+	a.publicKey = publicKey
+	a.updatedAt = time.Now().UTC()
+}
+
+func (a *serviceAccount) Disable() {
+	now := time.Now().UTC()
+	a.disabledAt = &now
+	a.updatedAt = now
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"crypto"
+	"time"
+)
+
+const (
+	SigningKeyStateNext    = "next"
+	SigningKeyStateActive  = "active"
+	SigningKeyStateRetired = "retired"
+)
+
+type signingKey struct {
+	id          string
+	algorithm   string
+	state       string
+	privateKey  crypto.Signer
+	createdAt   time.Time
+	activatedAt *time.Time
+	retiredAt   *time.Time
+	expiresAt   *time.Time
+}
+
+type SigningKeyInterface interface {
+	GetID() string
+	GetAlgorithm() string
+	GetState() string
+	GetPrivateKey() crypto.Signer
+	GetPublicKey() crypto.PublicKey
+	GetCreatedAt() time.Time
+	GetActivatedAt() *time.Time
+	GetRetiredAt() *time.Time
+	GetExpiresAt() *time.Time
+	IsPublished(now time.Time) bool
+	Activate(now time.Time)
+	Retire(now time.Time, retention time.Duration)
+}
+
+func NewSigningKey(id, algorithm string, privateKey crypto.Signer) SigningKeyInterface {
+	return &signingKey{
+		id:         id,
+		algorithm:  algorithm,
+		state:      SigningKeyStateNext,
+		privateKey: privateKey,
+		createdAt:  time.Now().UTC(),
+	}
+}
+
+func RestoreSigningKey(
+	id, algorithm, state string,
+	privateKey crypto.Signer,
+	createdAt time.Time,
+	activatedAt, retiredAt, expiresAt *time.Time,
+) SigningKeyInterface {
+	return &signingKey{
+		id:          id,
+		algorithm:   algorithm,
+		state:       state,
+		privateKey:  privateKey,
+		createdAt:   createdAt,
+		activatedAt: activatedAt,
+		retiredAt:   retiredAt,
+		expiresAt:   expiresAt,
+	}
+}
+
+func (k *signingKey) GetID() string {
+	return k.id
+}
+
+func (k *signingKey) GetAlgorithm() string {
+	return k.algorithm
+}
+
+func (k *signingKey) GetState() string {
+	return k.state
+}
+
+func (k *signingKey) GetPrivateKey() crypto.Signer {
+	return k.privateKey
+}
+
+func (k *signingKey) GetPublicKey() crypto.PublicKey {
+	return k.privateKey.Public()
+}
+
+func (k *signingKey) GetCreatedAt() time.Time {
+	return k.createdAt
+}
+
+func (k *signingKey) GetActivatedAt() *time.Time {
+	return k.activatedAt
+}
+
+func (k *signingKey) GetRetiredAt() *time.Time {
+	return k.retiredAt
+}
+
+func (k *signingKey) GetExpiresAt() *time.Time {
+	return k.expiresAt
+}
+
+// IsPublished reports whether the public key still has to be served so that
+// tokens it signed can be verified.
+func (k *signingKey) IsPublished(now time.Time) bool {
+	return k.expiresAt == nil || now.Before(*k.expiresAt)
+}
+
+func (k *signingKey) Activate(now time.Time) {
+	k.state = SigningKeyStateActive
+	k.activatedAt = &now
+}
+
+// Retire stops the key from signing and keeps it published for retention,
+// which must cover the lifetime of the longest token it may have signed.
+func (k *signingKey) Retire(now time.Time, retention time.Duration) {
+	expiresAt := now.Add(retention)
+	k.state = SigningKeyStateRetired
+	k.retiredAt = &now
+	k.expiresAt = &expiresAt
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/pkg/totp"
+	"github.com/google/uuid"
+)
+
+type totpFactor struct {
+	id           uuid.UUID
+	userID       uuid.UUID
+	secret       []byte
+	lastUsedStep int64
+	confirmedAt  *time.Time
+	createdAt    time.Time
+}
+
+type TOTPFactorInterface interface {
+	GetID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetSecret() []byte
+	GetLastUsedStep() int64
+	GetConfirmedAt() *time.Time
+	GetCreatedAt() time.Time
+	IsConfirmed() bool
+	Confirm(now time.Time)
+	Verify(code string, now time.Time, skew int) bool
+}
+
+// NewTOTPFactor starts an unconfirmed enrollment; it only protects logins
+// once the user proves possession with a first valid code.
+func NewTOTPFactor(userID uuid.UUID) (TOTPFactorInterface, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return &totpFactor{
+		id:        uuid.Must(uuid.NewRandom()),
+		userID:    userID,
+		secret:    secret,
+		createdAt: time.Now().UTC(),
+	}, nil
+}
+
+func RestoreTOTPFactor(
+	id, userID uuid.UUID,
+	secret []byte,
+	lastUsedStep int64,
+	confirmedAt *time.Time,
+	createdAt time.Time,
+) TOTPFactorInterface {
+	return &totpFactor{
+		id:           id,
+		userID:       userID,
+		secret:       secret,
+		lastUsedStep: lastUsedStep,
+		confirmedAt:  confirmedAt,
+		createdAt:    createdAt,
+	}
+}
+
+func (f *totpFactor) GetID() uuid.UUID {
+	return f.id
+}
+
+func (f *totpFactor) GetUserID() uuid.UUID {
+	return f.userID
+}
+
+func (f *totpFactor) GetSecret() []byte {
+	return f.secret
+}
+
+func (f *totpFactor) GetLastUsedStep() int64 {
+	return f.lastUsedStep
+}
+
+func (f *totpFactor) GetConfirmedAt() *time.Time {
+	return f.confirmedAt
+}
+
+func (f *totpFactor) GetCreatedAt() time.Time {
+	return f.createdAt
+}
+
+func (f *totpFactor) IsConfirmed() bool {
+	return f.confirmedAt != nil
+}
+
+func (f *totpFactor) Confirm(now time.Time) {
+	f.confirmedAt = &now
+}
+
+// Verify accepts a code within skew steps of now, rejecting any step at or
+// before the last one used so an observed code cannot be replayed.
+func (f *totpFactor) Verify(code string, now time.Time, skew int) bool {
+	step, ok := totp.Validate(f.secret, code, now, skew)
+	if !ok || step <= f.lastUsedStep {
+		return false
+	}
+
+	f.lastUsedStep = step
+	return true
+}
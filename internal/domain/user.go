@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -8,15 +10,29 @@ import (
 	"github.com/google/uuid"
 )
 
+// MaxPasswordBytes is the longest password bcrypt accepts. Its limit is in
+// bytes, so a password of fewer characters may still exceed it.
+const MaxPasswordBytes = 72
+
+// ErrPasswordTooLong refuses a password bcrypt cannot hash in full.
+var ErrPasswordTooLong = errors.New("password is longer than 72 bytes")
+
 type user struct {
-	id        uuid.UUID
-	email     string
+	id    uuid.UUID
+	email string
+	// password is the bcrypt hash, empty for accounts that only sign in
+	// through an external identity provider.
 	password  string
 	phone     string
 	firstName string
 	lastName  string
-	createdAt time.Time
-	updatedAt time.Time
+	// emailVerifiedAt is nil until the user follows the link sent to email.
+	emailVerifiedAt *time.Time
+	// deactivatedAt is set while the identity provider that provisioned the
+	// account has it turned off.
+	deactivatedAt *time.Time
+	createdAt     time.Time
+	updatedAt     time.Time
 }
 
 type UserInterface interface {
@@ -26,23 +42,66 @@ type UserInterface interface {
 	GetPhone() string
 	GetFirstName() string
 	GetLastName() string
+	GetEmailVerifiedAt() *time.Time
+	GetDeactivatedAt() *time.Time
 	GetCreatedAt() time.Time
 	GetUpdatedAt() time.Time
+	IsEmailVerified() bool
+	// IsDeactivated reports whether the account is barred from signing in.
+	IsDeactivated() bool
+	HasPassword() bool
+	// ComparePassword never matches for an account without a password.
 	ComparePassword(password string) bool
+	ChangePassword(password string) error
+	VerifyEmail()
+	// UpdateProfile replaces the contact details and name. A new email
+	// starts out unverified.
+	UpdateProfile(email, phone, firstName, lastName string)
+	// SetDeactivated turns the account off or back on; the first
+	// deactivation time is kept.
+	SetDeactivated(deactivated bool)
 }
 
-func New(email, password, phone, firstName, lastName string) UserInterface {
+// New creates a user. An empty password creates an account without one,
+// which signs in through an external identity provider until a password
+// is set.
+func New(email, password, phone, firstName, lastName string) (UserInterface, error) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
 	user := &user{
 		id:        uuid.Must(uuid.NewRandom()),
 		email:     email,
-		password:  hashPassword(password),
+		password:  hashed,
 		phone:     phone,
 		firstName: firstName,
 		lastName:  lastName,
 		createdAt: time.Now(),
 		updatedAt: time.Now(),
 	}
-	return user
+	return user, nil
+}
+
+func Restore(
+	id uuid.UUID,
+	email, password, phone, firstName, lastName string,
+	emailVerifiedAt, deactivatedAt *time.Time,
+	createdAt, updatedAt time.Time,
+) UserInterface {
+	return &user{
+		id:              id,
+		email:           email,
+		password:        password,
+		phone:           phone,
+		firstName:       firstName,
+		lastName:        lastName,
+		emailVerifiedAt: emailVerifiedAt,
+		deactivatedAt:   deactivatedAt,
+		createdAt:       createdAt,
+		updatedAt:       updatedAt,
+	}
 }
 
 func (u *user) GetID() uuid.UUID {
@@ -69,6 +128,14 @@ func (u *user) GetLastName() string {
 	return u.lastName
 }
 
+func (u *user) GetEmailVerifiedAt() *time.Time {
+	return u.emailVerifiedAt
+}
+
+func (u *user) GetDeactivatedAt() *time.Time {
+	return u.deactivatedAt
+}
+
 func (u *user) GetCreatedAt() time.Time {
 	return u.createdAt
 }
@@ -77,12 +144,79 @@ func (u *user) GetUpdatedAt() time.Time {
 	return u.updatedAt
 }
 
+func (u *user) IsEmailVerified() bool {
+	return u.emailVerifiedAt != nil
+}
+
+func (u *user) IsDeactivated() bool {
+	return u.deactivatedAt != nil
+}
+
+func (u *user) HasPassword() bool {
+	return u.password != ""
+}
+
 func (u *user) ComparePassword(password string) bool {
+	if u.password == "" {
+		return false
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(u.password), []byte(password))
 	return err == nil
 }
 
-func hashPassword(password string) string {
-	hashed, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashed)
+func (u *user) ChangePassword(password string) error {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.password = hashed
+	u.updatedAt = time.Now()
+	return nil
+}
+
+// VerifyEmail is idempotent; the first verification time is kept.
+func (u *user) VerifyEmail() {
+	if u.emailVerifiedAt != nil {
+		return
+	}
+	now := time.Now()
+	u.emailVerifiedAt = &now
+	u.updatedAt = now
+}
+
+func (u *user) UpdateProfile(email, phone, firstName, lastName string) {
+	if email != u.email {
+		u.email = email
+		u.emailVerifiedAt = nil
+	}
+	u.phone = phone
+	u.firstName = firstName
+	u.lastName = lastName
+	u.updatedAt = time.Now()
+}
+
+func (u *user) SetDeactivated(deactivated bool) {
+	if deactivated == u.IsDeactivated() {
+		return
+	}
+	now := time.Now()
+	u.deactivatedAt = nil
+	if deactivated {
+		u.deactivatedAt = &now
+	}
+	u.updatedAt = now
+}
+
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordBytes {
+		return "", ErrPasswordTooLong
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashed), nil
 }
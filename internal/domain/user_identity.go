@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userIdentity links the subject an external identity provider knows a
+// user by to their account. Email is what the provider last said, kept for
+// display only; the subject is what signs the user in.
+type userIdentity struct {
+	id          uuid.UUID
+	userID      uuid.UUID
+	provider    string
+	subject     string
+	email       string
+	createdAt   time.Time
+	lastLoginAt time.Time
+}
+
+type UserIdentityInterface interface {
+	GetID() uuid.UUID
+	GetUserID() uuid.UUID
+	GetProvider() string
+	GetSubject() string
+	GetEmail() string
+	GetCreatedAt() time.Time
+	GetLastLoginAt() time.Time
+	// Touch records a sign-in and the email the provider sent with it.
+	Touch(email string)
+}
+
+func NewUserIdentity(userID uuid.UUID, provider, subject, email string) UserIdentityInterface {
+	now := time.Now()
+	return &userIdentity{
+		id:          uuid.Must(uuid.NewRandom()),
+		userID:      userID,
+		provider:    provider,
+		subject:     subject,
+		email:       email,
+		createdAt:   now,
+		lastLoginAt: now,
+	}
+}
+
+func RestoreUserIdentity(
+	id, userID uuid.UUID,
+	provider, subject, email string,
+	createdAt, lastLoginAt time.Time,
+) UserIdentityInterface {
+	return &userIdentity{
+		id:          id,
+		userID:      userID,
+		provider:    provider,
+		subject:     subject,
+		email:       email,
+		createdAt:   createdAt,
+		lastLoginAt: lastLoginAt,
+	}
+}
+
+func (i *userIdentity) GetID() uuid.UUID {
+	return i.id
+}
+
+func (i *userIdentity) GetUserID() uuid.UUID {
+	return i.userID
+}
+
+func (i *userIdentity) GetProvider() string {
+	return i.provider
+}
+
+func (i *userIdentity) GetSubject() string {
+	return i.subject
+}
+
+func (i *userIdentity) GetEmail() string {
+	return i.email
+}
+
+func (i *userIdentity) GetCreatedAt() time.Time {
+	return i.createdAt
+}
+
+func (i *userIdentity) GetLastLoginAt() time.Time {
+	return i.lastLoginAt
+}
+
+func (i *userIdentity) Touch(email string) {
+	i.email = email
+	i.lastLoginAt = time.Now()
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewRefusesPasswordOverBcryptLimit(t *testing.T) {
+	// 40 characters, 80 bytes: short enough for a character count.
+	password := strings.Repeat("é", 40)
+
+	if _, err := New("a@example.com", password, "", "A", "B"); !errors.Is(err, ErrPasswordTooLong) {
+		t.Fatalf("New() error = %v, want ErrPasswordTooLong", err)
+	}
+}
+
+func TestChangePasswordKeepsOldPasswordOnError(t *testing.T) {
+	u, err := New("a@example.com", "old-password", "", "A", "B")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := u.ChangePassword(strings.Repeat("é", 40)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Fatalf("ChangePassword() error = %v, want ErrPasswordTooLong", err)
+	}
+	if !u.HasPassword() || !u.ComparePassword("old-password") {
+		t.Fatal("ChangePassword() dropped the old password on error")
+	}
+}
+
+func TestChangePasswordAcceptsLimit(t *testing.T) {
+	u, err := New("a@example.com", "", "", "A", "B")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	password := strings.Repeat("é", MaxPasswordBytes/2)
+	if err := u.ChangePassword(password); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if !u.ComparePassword(password) {
+		t.Fatal("ComparePassword() = false for the password just set")
+	}
+}
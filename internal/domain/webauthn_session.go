@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+)
+
+type webAuthnSession struct {
+	id        uuid.UUID
+	tokenHash string
+	userID    *uuid.UUID
+	ceremony  string
+	data      []byte
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+type WebAuthnSessionInterface interface {
+	GetID() uuid.UUID
+	GetTokenHash() string
+	GetUserID() *uuid.UUID
+	GetCeremony() string
+	GetData() []byte
+	GetExpiresAt() time.Time
+	GetCreatedAt() time.Time
+	IsExpired(now time.Time) bool
+}
+
+// NewWebAuthnSession keeps the server side state of a registration or login
+// ceremony between its two round trips. Discoverable logins have no user yet.
+func NewWebAuthnSession(userID *uuid.UUID, ceremony string, data []byte, ttl time.Duration) (WebAuthnSessionInterface, string) {
+	raw := generateOpaqueToken()
+	now := time.Now().UTC()
+
+	return &webAuthnSession{
+		id:        uuid.Must(uuid.NewRandom()),
+		tokenHash: HashOpaqueToken(raw),
+		userID:    userID,
+		ceremony:  ceremony,
+		data:      data,
+		expiresAt: now.Add(ttl),
+		createdAt: now,
+	}, raw
+}
+
+func RestoreWebAuthnSession(
+	id uuid.UUID,
+	tokenHash string,
+	userID *uuid.UUID,
+	ceremony string,
+	data []byte,
+	expiresAt, createdAt time.Time,
+) WebAuthnSessionInterface {
+	return &webAuthnSession{
+		id:        id,
+		tokenHash: tokenHash,
+		userID:    userID,
+		ceremony:  ceremony,
+		data:      data,
+		expiresAt: expiresAt,
+		createdAt: createdAt,
+	}
+}
+
+func (s *webAuthnSession) GetID() uuid.UUID {
+	return s.id
+}
+
+func (s *webAuthnSession) GetTokenHash() string {
+	return s.tokenHash
+}
+
+func (s *webAuthnSession) GetUserID() *uuid.UUID {
+	return s.userID
+}
+
+func (s *webAuthnSession) GetCeremony() string {
+	return s.ceremony
+}
+
+func (s *webAuthnSession) GetData() []byte {
+	return s.data
+}
+
+func (s *webAuthnSession) GetExpiresAt() time.Time {
+	return s.expiresAt
+}
+
+func (s *webAuthnSession) GetCreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *webAuthnSession) IsExpired(now time.Time) bool {
+	return !now.Before(s.expiresAt)
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a webhook delivery. A pending delivery is retried until it
+// succeeds or runs out of attempts, which leaves it dead until an admin
+// redelivers it.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+type webhookDelivery struct {
+	id             uuid.UUID
+	seq            int64
+	endpointID     uuid.UUID
+	eventID        uuid.UUID
+	eventType      string
+	payload        []byte
+	status         string
+	attempts       int
+	nextAttemptAt  time.Time
+	lastAttemptAt  *time.Time
+	responseStatus int
+	lastError      string
+	createdAt      time.Time
+	deliveredAt    *time.Time
+}
+
+// WebhookDeliveryInterface is one event on its way to one endpoint, and its
+// entry in the delivery log. Payload is the body posted on every attempt.
+type WebhookDeliveryInterface interface {
+	GetID() uuid.UUID
+	GetSeq() int64
+	GetEndpointID() uuid.UUID
+	GetEventID() uuid.UUID
+	GetEventType() string
+	GetPayload() []byte
+	GetStatus() string
+	GetAttempts() int
+	GetNextAttemptAt() time.Time
+	GetLastAttemptAt() *time.Time
+	// GetResponseStatus is the HTTP status of the last attempt, zero when
+	// no response came back.
+	GetResponseStatus() int
+	GetLastError() string
+	GetCreatedAt() time.Time
+	GetDeliveredAt() *time.Time
+	Succeed(now time.Time, responseStatus int)
+	// Fail records a failed attempt. A nil retryAt gives up on the
+	// delivery.
+	Fail(now time.Time, responseStatus int, reason string, retryAt *time.Time)
+	// Redeliver queues the delivery again with a fresh round of attempts.
+	Redeliver(now time.Time)
+}
+
+func NewWebhookDelivery(endpointID, eventID uuid.UUID, eventType string, payload []byte) WebhookDeliveryInterface {
+	now := time.Now().UTC()
+
+	return &webhookDelivery{
+		id:            uuid.Must(uuid.NewRandom()),
+		endpointID:    endpointID,
+		eventID:       eventID,
+		eventType:     eventType,
+		payload:       payload,
+		status:        WebhookDeliveryPending,
+		nextAttemptAt: now,
+		createdAt:     now,
+	}
+}
+
+func RestoreWebhookDelivery(
+	id uuid.UUID,
+	seq int64,
+	endpointID, eventID uuid.UUID,
+	eventType string,
+	payload []byte,
+	status string,
+	attempts int,
+	nextAttemptAt time.Time,
+	lastAttemptAt *time.Time,
+	responseStatus int,
+	lastError string,
+	createdAt time.Time,
+	deliveredAt *time.Time,
+) WebhookDeliveryInterface {
+	return &webhookDelivery{
+		id:             id,
+		seq:            seq,
+		endpointID:     endpointID,
+		eventID:        eventID,
+		eventType:      eventType,
+		payload:        payload,
+		status:         status,
+		attempts:       attempts,
+		nextAttemptAt:  nextAttemptAt,
+		lastAttemptAt:  lastAttemptAt,
+		responseStatus: responseStatus,
+		lastError:      lastError,
+		createdAt:      createdAt,
+		deliveredAt:    deliveredAt,
+	}
+}
+
+func (d *webhookDelivery) GetID() uuid.UUID {
+	return d.id
+}
+
+func (d *webhookDelivery) GetSeq() int64 {
+	return d.seq
+}
+
+func (d *webhookDelivery) GetEndpointID() uuid.UUID {
+	return d.endpointID
+}
+
+func (d *webhookDelivery) GetEventID() uuid.UUID {
+	return d.eventID
+}
+
+func (d *webhookDelivery) GetEventType() string {
+	return d.eventType
+}
+
+func (d *webhookDelivery) GetPayload() []byte {
+	return d.payload
+}
+
+func (d *webhookDelivery) GetStatus() string {
+	return d.status
+}
+
+func (d *webhookDelivery) GetAttempts() int {
+	return d.attempts
+}
+
+func (d *webhookDelivery) GetNextAttemptAt() time.Time {
+	return d.nextAttemptAt
+}
+
+func (d *webhookDelivery) GetLastAttemptAt() *time.Time {
+	return d.lastAttemptAt
+}
+
+func (d *webhookDelivery) GetResponseStatus() int {
+	return d.responseStatus
+}
+
+func (d *webhookDelivery) GetLastError() string {
+	return d.lastError
+}
+
+func (d *webhookDelivery) GetCreatedAt() time.Time {
+	return d.createdAt
+}
+
+func (d *webhookDelivery) GetDeliveredAt() *time.Time {
+	return d.deliveredAt
+}
+
+func (d *webhookDelivery) Succeed(now time.Time, responseStatus int) {
+	d.attempts++
+	d.status = WebhookDeliverySucceeded
+	d.lastAttemptAt = &now
+	d.responseStatus = responseStatus
+	d.lastError = ""
+	d.deliveredAt = &now
+}
+
+func (d *webhookDelivery) Fail(now time.Time, responseStatus int, reason string, retryAt *time.Time) {
+	d.attempts++
+	d.lastAttemptAt = &now
+	d.responseStatus = responseStatus
+	d.lastError = reason
+
+	if retryAt == nil {
+		d.status = WebhookDeliveryDead
+		return
+	}
+	d.nextAttemptAt = *retryAt
+}
+
+func (d *webhookDelivery) Redeliver(now time.Time) {
+	d.status = WebhookDeliveryPending
+	d.attempts = 0
+	d.nextAttemptAt = now
+	d.deliveredAt = nil
+}
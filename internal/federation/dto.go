@@ -0,0 +1,61 @@
+package federation
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type ProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// LoginQuery carries where the client wants to land after the login; it is
+// handed back by the callback.
+type LoginQuery struct {
+	ReturnTo string `form:"return_to" binding:"max=1024"`
+}
+
+// CallbackRequest is what the provider sends the browser back with: a code
+// on success, an error otherwise.
+type CallbackRequest struct {
+	Code             string `form:"code" binding:"max=2048"`
+	State            string `form:"state" binding:"required,max=64"`
+	Error            string `form:"error" binding:"max=255"`
+	ErrorDescription string `form:"error_description" binding:"max=1024"`
+}
+
+// CallbackResponse carries the session, or the second factor challenge, of
+// a login along with the return_to it was started with. A callback that
+// links a provider to a signed-in user carries neither and sets Linked.
+type CallbackResponse struct {
+	*auth.LoginResponse
+	Linked   bool   `json:"linked,omitempty"`
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// LinkResponse tells a signed-in user where to go to link a provider.
+type LinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type IdentityResponse struct {
+	ID          string    `json:"id"`
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+func NewIdentityResponse(identity domain.UserIdentityInterface) IdentityResponse {
+	return IdentityResponse{
+		ID:          identity.GetID().String(),
+		Provider:    identity.GetProvider(),
+		Email:       identity.GetEmail(),
+		CreatedAt:   identity.GetCreatedAt(),
+		LastLoginAt: identity.GetLastLoginAt(),
+	}
+}
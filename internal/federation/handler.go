@@ -0,0 +1,125 @@
+package federation
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service ServiceInterface
+	tokens  token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	ListProviders(ctx *gin.Context)
+	Login(ctx *gin.Context)
+	Callback(ctx *gin.Context)
+	Link(ctx *gin.Context)
+	ListIdentities(ctx *gin.Context)
+	Unlink(ctx *gin.Context)
+}
+
+func NewHandler(service ServiceInterface, tokens token.ManagerInterface) HandlerInterface {
+	return &handler{
+		service: service,
+		tokens:  tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	// The browser reaches these on its way to and back from the provider.
+	federation := router.Group("/api/v1/auth/federation")
+	{
+		federation.GET("/providers", h.ListProviders)
+		federation.GET("/:provider/login", h.Login)
+		federation.GET("/:provider/callback", h.Callback)
+	}
+
+	account := router.Group("/api/v1/auth/federation", middleware.Authenticate(h.tokens), middleware.RequireUser())
+	{
+		account.POST("/:provider/link", h.Link)
+		account.GET("/identities", h.ListIdentities)
+		account.DELETE("/identities/:id", h.Unlink)
+	}
+}
+
+func (h *handler) ListProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.service.ListProviders(ctx.Request.Context()))
+}
+
+func (h *handler) Login(ctx *gin.Context) {
+	var query LoginQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	location, restErr := h.service.BeginLogin(ctx.Request.Context(), ctx.Param("provider"), query.ReturnTo)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, location)
+}
+
+func (h *handler) Callback(ctx *gin.Context) {
+	var req CallbackRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.Callback(ctx.Request.Context(), ctx.Param("provider"), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Link(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.BeginLink(ctx.Request.Context(), userID, ctx.Param("provider"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListIdentities(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.ListIdentities(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Unlink(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	if restErr := h.service.Unlink(ctx.Request.Context(), userID, ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
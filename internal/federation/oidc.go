@@ -0,0 +1,300 @@
+package federation
+
+import (
+	"context"
+	"crypto"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// discoveryTTL is how long the discovery document and the keys of a
+	// provider are trusted before being fetched again.
+	discoveryTTL = time.Hour
+	// keysRefreshInterval bounds how often an unknown key ID makes the keys
+	// be fetched again, so forged tokens cannot hammer the provider.
+	keysRefreshInterval = time.Minute
+	// idTokenLeeway is the clock skew granted to the ID token.
+	idTokenLeeway = time.Minute
+)
+
+// discovery is the part of an OpenID Provider configuration the login
+// needs.
+type discovery struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	UserInfoEndpoint         string   `json:"userinfo_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// verificationKey is a key of the provider with the algorithm it signs
+// with.
+type verificationKey struct {
+	publicKey crypto.PublicKey
+	algorithm string
+}
+
+// idTokenClaims are the ID token claims the login reads. email_verified is
+// a string at some providers.
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	AuthorizedBy  string `json:"azp"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Name          string `json:"name"`
+}
+
+// oidcProvider signs users in through an OpenID Connect provider found
+// through its discovery document.
+type oidcProvider struct {
+	name   string
+	config config.FederationProviderConfig
+	client *http.Client
+	// issuers are the values the iss claim may take.
+	issuers []string
+
+	mu            sync.Mutex
+	discovery     *discovery
+	discoveredAt  time.Time
+	keys          map[string]verificationKey
+	keysFetchedAt time.Time
+}
+
+func newOIDCProvider(name string, cfg config.FederationProviderConfig, client *http.Client, aliases ...string) *oidcProvider {
+	return &oidcProvider{
+		name:    name,
+		config:  cfg,
+		client:  client,
+		issuers: append([]string{strings.TrimSuffix(cfg.Issuer, "/")}, aliases...),
+	}
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) DisplayName() string {
+	return p.config.Name
+}
+
+func (p *oidcProvider) AuthorizationURL(ctx context.Context, a authorization) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return withQuery(d.AuthorizationEndpoint, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {a.RedirectURI},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {a.State},
+		"nonce":                 {a.Nonce},
+		"code_challenge":        {a.CodeChallenge},
+		"code_challenge_method": {"S256"},
+	})
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier, nonce, redirectURI string) (*identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+	// Basic is the default of the specification; a provider that lists
+	// only client_secret_post gets the secret in the form.
+	basic := len(d.TokenEndpointAuthMethods) == 0 || slices.Contains(d.TokenEndpointAuthMethods, "client_secret_basic")
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := postForm(ctx, p.client, d.TokenEndpoint, form, p.config, basic, &tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, providerError("token response carries no id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	found := &identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}
+
+	// Some providers keep the profile out of the ID token.
+	if found.Email == "" && d.UserInfoEndpoint != "" && tokens.AccessToken != "" {
+		var info idTokenClaims
+		if err := getJSON(ctx, p.client, d.UserInfoEndpoint, tokens.AccessToken, &info); err != nil {
+			return nil, err
+		}
+		if info.Subject != claims.Subject {
+			return nil, providerError("userinfo is about another subject")
+		}
+		found.Email, found.EmailVerified = info.Email, isTrue(info.EmailVerified)
+		found.FirstName, found.LastName = info.GivenName, info.FamilyName
+		claims.Name = info.Name
+	}
+	if found.FirstName == "" && found.LastName == "" {
+		found.FirstName, found.LastName = splitName(claims.Name)
+	}
+
+	return found, nil
+}
+
+// verifyIDToken checks the ID token was issued by the provider to this
+// client for the authorization request that carried nonce.
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.algorithm {
+			return nil, providerError("id_token is signed with %s by a %s key", token.Method.Alg(), key.algorithm)
+		}
+		return key.publicKey, nil
+	},
+		jwt.WithValidMethods([]string{keys.AlgorithmRS256, keys.AlgorithmES256, keys.AlgorithmEdDSA}),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, providerError("invalid id_token: %v", err)
+	}
+
+	switch {
+	case !slices.Contains(p.issuers, strings.TrimSuffix(claims.Issuer, "/")):
+		return nil, providerError("id_token is issued by %q", claims.Issuer)
+	case claims.AuthorizedBy != "" && claims.AuthorizedBy != p.config.ClientID:
+		return nil, providerError("id_token is authorized for another client")
+	case claims.Nonce != nonce:
+		return nil, providerError("id_token answers another request")
+	case claims.Subject == "":
+		return nil, providerError("id_token has no subject")
+	}
+
+	return claims, nil
+}
+
+// discover returns the provider configuration, fetched at most once per
+// discoveryTTL.
+func (p *oidcProvider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	var d discovery
+	if err := getJSON(ctx, p.client, p.issuers[0]+"/.well-known/openid-configuration", "", &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.issuers[0] {
+		return nil, providerError("discovery document is for issuer %q", d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, providerError("discovery document lacks an endpoint")
+	}
+
+	p.discovery, p.discoveredAt = &d, time.Now()
+	return p.discovery, nil
+}
+
+// key returns the key an ID token names, fetching the keys again when it
+// is unknown, as after a rotation at the provider. A token without a key
+// ID is accepted when the provider has a single key.
+func (p *oidcProvider) key(ctx context.Context, kid string) (verificationKey, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return verificationKey{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := time.Since(p.keysFetchedAt) >= discoveryTTL
+	if key, ok := p.lookup(kid); ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return verificationKey{}, providerError("id_token is signed with unknown key %q", kid)
+	}
+
+	var set keys.JWKS
+	if err := getJSON(ctx, p.client, d.JWKSURI, "", &set); err != nil {
+		return verificationKey{}, err
+	}
+
+	fetched := map[string]verificationKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, algorithm, err := jwk.PublicKey()
+		if err != nil || (jwk.Algorithm != "" && jwk.Algorithm != algorithm) {
+			// Keys this service cannot use are skipped rather than failing
+			// the ones it can.
+			continue
+		}
+		fetched[jwk.KeyID] = verificationKey{publicKey: publicKey, algorithm: algorithm}
+	}
+	p.keys, p.keysFetchedAt = fetched, time.Now()
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return verificationKey{}, providerError("id_token is signed with unknown key %q", kid)
+}
+
+func (p *oidcProvider) lookup(kid string) (verificationKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// isTrue reads a boolean claim, which some providers send as a string.
+func isTrue(claim any) bool {
+	switch value := claim.(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+)
+
+// Names of the providers, as they appear in URLs and on linked identities.
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+	ProviderOIDC   = "oidc"
+)
+
+// maxResponseSize bounds what is read from a provider.
+const maxResponseSize = 1 << 20
+
+// ErrProvider is wrapped by every failure to complete a login with a
+// provider, whether it refused or answered something invalid.
+var ErrProvider = errors.New("identity provider error")
+
+// identity is what a provider tells about the user who signed in. Subject
+// is stable at the provider; the rest may change between logins.
+type identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// authorization is what an authorization request is bound to: the state
+// that comes back with the callback, the nonce that comes back in the ID
+// token and the PKCE challenge of the verifier the code is redeemed with.
+type authorization struct {
+	State         string
+	Nonce         string
+	CodeChallenge string
+	RedirectURI   string
+}
+
+// provider runs the authorization code flow against one identity
+// provider.
+type provider interface {
+	Name() string
+	DisplayName() string
+	// AuthorizationURL is where to send the browser to sign in.
+	AuthorizationURL(ctx context.Context, a authorization) (string, error)
+	// Exchange redeems the code the callback brought and returns who
+	// signed in.
+	Exchange(ctx context.Context, code, codeVerifier, nonce, redirectURI string) (*identity, error)
+}
+
+// newProviders returns the providers configured with a client ID, in the
+// order they are offered to users.
+func newProviders(cfg config.FederationConfig, client *http.Client) []provider {
+	var providers []provider
+	if cfg.Google.ClientID != "" {
+		// Google has issued ID tokens with its issuer both with and without
+		// the scheme.
+		providers = append(providers, newOIDCProvider(ProviderGoogle, cfg.Google, client, "accounts.google.com"))
+	}
+	if cfg.GitHub.ClientID != "" {
+		providers = append(providers, newGitHubProvider(cfg.GitHub, client))
+	}
+	if cfg.OIDC.ClientID != "" && cfg.OIDC.Issuer != "" {
+		providers = append(providers, newOIDCProvider(ProviderOIDC, cfg.OIDC, client))
+	}
+	return providers
+}
+
+func providerError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrProvider, fmt.Sprintf(format, args...))
+}
+
+// getJSON reads a JSON document, with the access token when given.
+func getJSON(ctx context.Context, client *http.Client, endpoint, accessToken string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return do(client, req, out)
+}
+
+// postForm posts a form, authenticating as the client with HTTP Basic
+// when basic is set and in the form otherwise.
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, cfg config.FederationProviderConfig, basic bool, out any) error {
+	if !basic {
+		form.Set("client_id", cfg.ClientID)
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		// RFC 6749 section 2.3.1 form encodes the credentials first.
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	return do(client, req, out)
+}
+
+// tokenError is how an OAuth 2.0 endpoint explains a refusal.
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (e tokenError) String() string {
+	return strings.TrimSpace(e.Error + " " + e.ErrorDescription)
+}
+
+func do(client *http.Client, req *http.Request, out any) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return providerError("%s %s: %v", req.Method, req.URL.Redacted(), err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return providerError("reading %s: %v", req.URL.Redacted(), err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var refusal tokenError
+		if json.Unmarshal(body, &refusal) == nil && refusal.Error != "" {
+			return providerError("%s answered %d: %s", req.URL.Redacted(), res.StatusCode, refusal)
+		}
+		return providerError("%s answered %d", req.URL.Redacted(), res.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return providerError("decoding %s: %v", req.URL.Redacted(), err)
+	}
+	return nil
+}
+
+// withQuery appends parameters to an endpoint that may already carry a
+// query.
+func withQuery(endpoint string, query url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	values := u.Query()
+	for name, value := range query {
+		values[name] = value
+	}
+	u.RawQuery = values.Encode()
+
+	return u.String(), nil
+}
+
+// splitName splits a display name into a first name and the rest.
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
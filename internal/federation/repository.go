@@ -0,0 +1,224 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityExists   = errors.New("identity already linked")
+	ErrRequestNotFound  = errors.New("federation request not found")
+)
+
+// Request is an authorization request awaiting its callback. UserID is set
+// when a signed-in user links the provider instead of signing in with it.
+type Request struct {
+	State        string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	UserID       *uuid.UUID
+	ReturnTo     string
+	ExpiresAt    time.Time
+}
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	CreateIdentity(ctx context.Context, identity domain.UserIdentityInterface) error
+	FindIdentity(ctx context.Context, provider, subject string) (domain.UserIdentityInterface, error)
+	FindIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentityInterface, error)
+	UpdateIdentity(ctx context.Context, identity domain.UserIdentityInterface) error
+	DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error
+
+	CreateRequest(ctx context.Context, request Request) error
+	// ConsumeRequest deletes and returns an unexpired request of provider,
+	// so a callback is answered once.
+	ConsumeRequest(ctx context.Context, provider, state string, now time.Time) (*Request, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateIdentity(ctx context.Context, identity domain.UserIdentityInterface) error {
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		identity.GetID(),
+		identity.GetUserID(),
+		identity.GetProvider(),
+		identity.GetSubject(),
+		identity.GetEmail(),
+		identity.GetCreatedAt(),
+		identity.GetLastLoginAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrIdentityExists
+		}
+		return fmt.Errorf("failed to insert identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindIdentity(ctx context.Context, provider, subject string) (domain.UserIdentityInterface, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	return scanIdentity(r.db.GetQuerier(ctx).QueryRow(ctx, query, provider, subject))
+}
+
+func (r *repository) FindIdentities(ctx context.Context, userID uuid.UUID) ([]domain.UserIdentityInterface, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []domain.UserIdentityInterface
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (r *repository) UpdateIdentity(ctx context.Context, identity domain.UserIdentityInterface) error {
+	query := `UPDATE user_identities SET email = $2, last_login_at = $3 WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, identity.GetID(), identity.GetEmail(), identity.GetLastLoginAt())
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) CreateRequest(ctx context.Context, request Request) error {
+	query := `
+		INSERT INTO federation_requests (state, provider, code_verifier, nonce, user_id, return_to, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		request.State,
+		request.Provider,
+		request.CodeVerifier,
+		request.Nonce,
+		request.UserID,
+		request.ReturnTo,
+		request.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert federation request: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) ConsumeRequest(ctx context.Context, provider, state string, now time.Time) (*Request, error) {
+	query := `
+		DELETE FROM federation_requests
+		WHERE state = $1 AND provider = $2 AND expires_at > $3
+		RETURNING state, provider, code_verifier, nonce, user_id, return_to, expires_at`
+
+	var request Request
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, state, provider, now).Scan(
+		&request.State,
+		&request.Provider,
+		&request.CodeVerifier,
+		&request.Nonce,
+		&request.UserID,
+		&request.ReturnTo,
+		&request.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to consume federation request: %w", err)
+	}
+
+	return &request, nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM federation_requests WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired federation requests: %w", err)
+	}
+
+	return nil
+}
+
+func scanIdentity(row pgx.Row) (domain.UserIdentityInterface, error) {
+	var (
+		id          uuid.UUID
+		userID      uuid.UUID
+		provider    string
+		subject     string
+		email       string
+		createdAt   time.Time
+		lastLoginAt time.Time
+	)
+
+	err := row.Scan(&id, &userID, &provider, &subject, &email, &createdAt, &lastLoginAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, fmt.Errorf("failed to scan identity: %w", err)
+	}
+
+	return domain.RestoreUserIdentity(id, userID, provider, subject, email, createdAt, lastLoginAt), nil
+}
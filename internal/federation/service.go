@@ -0,0 +1,481 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+const loginRefusedMessage = "login with the identity provider was refused"
+
+type service struct {
+	config     config.FederationConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	accounts   user.ServiceInterface
+	sessions   auth.ServiceInterface
+	audit      audit.RecorderInterface
+	providers  []provider
+}
+
+// ServiceInterface signs users in with external identity providers and
+// manages the identities linked to their accounts.
+type ServiceInterface interface {
+	ListProviders(ctx context.Context) []ProviderResponse
+	// BeginLogin returns where to send the browser to sign in with a
+	// provider. returnTo is handed back by the callback.
+	BeginLogin(ctx context.Context, providerName, returnTo string) (string, *httperr.HttpError)
+	// BeginLink is BeginLogin for a signed-in user linking a provider to
+	// their account.
+	BeginLink(ctx context.Context, userID uuid.UUID, providerName string) (*LinkResponse, *httperr.HttpError)
+	// Callback redeems the code a provider sent the browser back with and
+	// either signs the user in, linking or provisioning an account on first
+	// sight, or completes a link.
+	Callback(ctx context.Context, providerName string, req CallbackRequest) (*CallbackResponse, *httperr.HttpError)
+
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]IdentityResponse, *httperr.HttpError)
+	Unlink(ctx context.Context, userID uuid.UUID, identityID string) *httperr.HttpError
+}
+
+func NewService(
+	config config.FederationConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	accounts user.ServiceInterface,
+	sessions auth.ServiceInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		users:      users,
+		accounts:   accounts,
+		sessions:   sessions,
+		audit:      audit,
+		providers:  newProviders(config, client),
+	}
+}
+
+func (s *service) ListProviders(_ context.Context) []ProviderResponse {
+	res := make([]ProviderResponse, 0, len(s.providers))
+	for _, p := range s.providers {
+		res = append(res, ProviderResponse{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			LoginURL:    s.endpoint(p.Name()) + "/login",
+		})
+	}
+	return res
+}
+
+func (s *service) BeginLogin(ctx context.Context, providerName, returnTo string) (string, *httperr.HttpError) {
+	p, restErr := s.provider(providerName)
+	if restErr != nil {
+		return "", restErr
+	}
+
+	return s.begin(ctx, p, nil, returnTo)
+}
+
+func (s *service) BeginLink(ctx context.Context, userID uuid.UUID, providerName string) (*LinkResponse, *httperr.HttpError) {
+	p, restErr := s.provider(providerName)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	location, restErr := s.begin(ctx, p, &userID, "")
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return &LinkResponse{AuthorizationURL: location}, nil
+}
+
+// begin stores an authorization request, so its callback can be answered
+// once, and returns where to send the browser.
+func (s *service) begin(ctx context.Context, p provider, userID *uuid.UUID, returnTo string) (string, *httperr.HttpError) {
+	now := time.Now().UTC()
+	request := Request{
+		State:        randomString(),
+		Provider:     p.Name(),
+		CodeVerifier: randomString(),
+		Nonce:        randomString(),
+		UserID:       userID,
+		ReturnTo:     returnTo,
+		ExpiresAt:    now.Add(time.Duration(s.config.RequestTTL) * time.Second),
+	}
+
+	challenge := sha256.Sum256([]byte(request.CodeVerifier))
+	location, err := p.AuthorizationURL(ctx, authorization{
+		State:         request.State,
+		Nonce:         request.Nonce,
+		CodeChallenge: base64.RawURLEncoding.EncodeToString(challenge[:]),
+		RedirectURI:   s.redirectURI(p.Name()),
+	})
+	if err != nil {
+		slog.Error("failed to build authorization url", "error", err, "provider", p.Name())
+		return "", httperr.NewInternalServerError("identity provider is unavailable")
+	}
+
+	if err := s.repository.DeleteExpired(ctx, now); err != nil {
+		slog.Warn("failed to clean up federation requests", "error", err)
+	}
+	if err := s.repository.CreateRequest(ctx, request); err != nil {
+		slog.Error("failed to store federation request", "error", err)
+		return "", httperr.NewInternalServerError("failed to start login")
+	}
+
+	return location, nil
+}
+
+func (s *service) Callback(ctx context.Context, providerName string, req CallbackRequest) (*CallbackResponse, *httperr.HttpError) {
+	p, restErr := s.provider(providerName)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	request, err := s.repository.ConsumeRequest(ctx, p.Name(), req.State, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, ErrRequestNotFound) {
+			return nil, httperr.NewBadRequestError("login request is invalid or expired")
+		}
+		slog.Error("failed to consume federation request", "error", err)
+		return nil, httperr.NewInternalServerError("failed to complete login")
+	}
+
+	if req.Error != "" {
+		s.recordFailure(ctx, p.Name(), "provider answered "+req.Error)
+		return nil, httperr.NewUnauthorizedRequestError(loginRefusedMessage)
+	}
+	if req.Code == "" {
+		return nil, httperr.NewBadRequestError("code is required")
+	}
+
+	found, err := p.Exchange(ctx, req.Code, request.CodeVerifier, request.Nonce, s.redirectURI(p.Name()))
+	if err != nil {
+		slog.Info("federated login refused", "error", err, "provider", p.Name())
+		s.recordFailure(ctx, p.Name(), err.Error())
+		return nil, httperr.NewUnauthorizedRequestError(loginRefusedMessage)
+	}
+	if len(found.Subject) > 255 {
+		s.recordFailure(ctx, p.Name(), "subject is too long")
+		return nil, httperr.NewUnauthorizedRequestError(loginRefusedMessage)
+	}
+	found.normalize()
+
+	if request.UserID != nil {
+		if restErr := s.complete(ctx, p.Name(), *request.UserID, found); restErr != nil {
+			return nil, restErr
+		}
+		return &CallbackResponse{Linked: true, ReturnTo: request.ReturnTo}, nil
+	}
+
+	var userID uuid.UUID
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		resolved, err := s.resolveUser(ctx, p.Name(), found)
+		if err != nil {
+			return err
+		}
+		userID = resolved
+		return nil
+	})
+	if err != nil {
+		var refused *refusal
+		if errors.As(err, &refused) {
+			s.recordFailure(ctx, p.Name(), refused.reason)
+			return nil, refused.restErr
+		}
+		var restErr *httperr.HttpError
+		if errors.As(err, &restErr) {
+			return nil, restErr
+		}
+		slog.Error("failed to sign in with identity provider", "error", err, "provider", p.Name())
+		return nil, httperr.NewInternalServerError("failed to complete login")
+	}
+
+	// Unlike an enterprise identity provider, a public one says nothing of
+	// the second factor the account has here, so a user enrolled in one
+	// still has to pass it.
+	res, restErr := s.sessions.CompleteLogin(ctx, userID, nil, p.Name())
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return &CallbackResponse{LoginResponse: res, ReturnTo: request.ReturnTo}, nil
+}
+
+// resolveUser returns the account the identity signs in to. An identity
+// seen before keeps its account. Otherwise it is linked to the account with
+// its email only when both the provider and this service have verified that
+// email, so nobody can register an address they do not own on either side
+// and take over the account. Failing that, an account is provisioned when
+// signups are allowed.
+func (s *service) resolveUser(ctx context.Context, providerName string, found *identity) (uuid.UUID, error) {
+	linked, err := s.repository.FindIdentity(ctx, providerName, found.Subject)
+	switch {
+	case err == nil:
+		linked.Touch(found.Email)
+		if err := s.repository.UpdateIdentity(ctx, linked); err != nil {
+			return uuid.Nil, err
+		}
+		return linked.GetUserID(), nil
+	case !errors.Is(err, ErrIdentityNotFound):
+		return uuid.Nil, err
+	}
+
+	if found.Email == "" || !found.EmailVerified {
+		return uuid.Nil, rejection("provider did not verify an email")
+	}
+
+	account, err := s.users.FindByEmail(ctx, found.Email)
+	switch {
+	case err == nil:
+		if !account.IsEmailVerified() {
+			return uuid.Nil, &refusal{
+				reason:  "an account with an unverified email has this email",
+				restErr: httperr.NewForbiddenError("an account with this email exists; sign in to it and link the provider from there"),
+			}
+		}
+	case errors.Is(err, user.ErrUserNotFound):
+		if !s.config.AllowSignup {
+			return uuid.Nil, rejection("no account matches the identity")
+		}
+		provisioned, restErr := s.accounts.Provision(ctx, user.ProvisionRequest{
+			Email:     found.Email,
+			FirstName: found.FirstName,
+			LastName:  found.LastName,
+			Method:    providerName,
+		})
+		if restErr != nil {
+			return uuid.Nil, restErr
+		}
+		account = provisioned
+	default:
+		return uuid.Nil, err
+	}
+
+	if err := s.link(ctx, providerName, account.GetID(), found); err != nil {
+		if errors.Is(err, ErrIdentityExists) {
+			return uuid.Nil, rejection("account is linked to another identity of the provider")
+		}
+		return uuid.Nil, err
+	}
+
+	return account.GetID(), nil
+}
+
+// complete links the identity to the user who started the link.
+func (s *service) complete(ctx context.Context, providerName string, userID uuid.UUID, found *identity) *httperr.HttpError {
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		linked, err := s.repository.FindIdentity(ctx, providerName, found.Subject)
+		switch {
+		case err == nil:
+			if linked.GetUserID() != userID {
+				return httperr.NewBadRequestError("this identity is linked to another account")
+			}
+			linked.Touch(found.Email)
+			return s.repository.UpdateIdentity(ctx, linked)
+		case !errors.Is(err, ErrIdentityNotFound):
+			return err
+		}
+
+		return s.link(ctx, providerName, userID, found)
+	})
+	if err != nil {
+		var restErr *httperr.HttpError
+		switch {
+		case errors.As(err, &restErr):
+			return restErr
+		case errors.Is(err, ErrIdentityExists):
+			return httperr.NewBadRequestError("another identity of this provider is linked to your account")
+		}
+		slog.Error("failed to link identity", "error", err, "provider", providerName)
+		return httperr.NewInternalServerError("failed to link identity")
+	}
+
+	return nil
+}
+
+func (s *service) link(ctx context.Context, providerName string, userID uuid.UUID, found *identity) error {
+	linked := domain.NewUserIdentity(userID, providerName, found.Subject, found.Email)
+	if err := s.repository.CreateIdentity(ctx, linked); err != nil {
+		return err
+	}
+
+	metadata := map[string]string{"provider": providerName, "identity_id": linked.GetID().String()}
+	return s.record(ctx, audit.ActionIdentityLink, audit.TargetUser, userID, metadata)
+}
+
+func (s *service) ListIdentities(ctx context.Context, userID uuid.UUID) ([]IdentityResponse, *httperr.HttpError) {
+	identities, err := s.repository.FindIdentities(ctx, userID)
+	if err != nil {
+		slog.Error("failed to list identities", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list identities")
+	}
+
+	res := make([]IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		res = append(res, NewIdentityResponse(identity))
+	}
+	return res, nil
+}
+
+// Unlink refuses to remove the last identity of an account without a
+// password, which would lock its owner out.
+func (s *service) Unlink(ctx context.Context, userID uuid.UUID, identityID string) *httperr.HttpError {
+	id, err := uuid.Parse(identityID)
+	if err != nil {
+		return httperr.NewNotFoundError("identity not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		identities, err := s.repository.FindIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		var unlinked domain.UserIdentityInterface
+		for _, identity := range identities {
+			if identity.GetID() == id {
+				unlinked = identity
+			}
+		}
+		if unlinked == nil {
+			return ErrIdentityNotFound
+		}
+		if !found.HasPassword() && len(identities) == 1 {
+			return httperr.NewBadRequestError("set a password before unlinking your last identity provider")
+		}
+
+		if err := s.repository.DeleteIdentity(ctx, userID, id); err != nil {
+			return err
+		}
+
+		metadata := map[string]string{"provider": unlinked.GetProvider(), "identity_id": id.String()}
+		return s.record(ctx, audit.ActionIdentityUnlink, audit.TargetUser, userID, metadata)
+	})
+	if err != nil {
+		var restErr *httperr.HttpError
+		switch {
+		case errors.As(err, &restErr):
+			return restErr
+		case errors.Is(err, ErrIdentityNotFound):
+			return httperr.NewNotFoundError("identity not found")
+		case errors.Is(err, user.ErrUserNotFound):
+			return httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to unlink identity", "error", err)
+		return httperr.NewInternalServerError("failed to unlink identity")
+	}
+
+	return nil
+}
+
+func (s *service) provider(name string) (provider, *httperr.HttpError) {
+	for _, p := range s.providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, httperr.NewNotFoundError("identity provider not found")
+}
+
+func (s *service) endpoint(providerName string) string {
+	return strings.TrimRight(s.config.BaseURL, "/") + "/" + providerName
+}
+
+func (s *service) redirectURI(providerName string) string {
+	return s.endpoint(providerName) + "/callback"
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+// recordFailure audits a refused login. Like the other logins, a failure
+// to audit it is logged rather than refused.
+func (s *service) recordFailure(ctx context.Context, providerName, reason string) {
+	err := s.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionLogin,
+		Outcome:    domain.AuditOutcomeFailure,
+		TargetType: audit.TargetIdentityProvider,
+		TargetID:   providerName,
+		Metadata:   map[string]string{"method": providerName, "reason": reason},
+	})
+	if err != nil {
+		slog.Error("failed to audit login", "error", err)
+	}
+}
+
+// refusal aborts a login for a reason that is audited but not told to the
+// caller.
+type refusal struct {
+	reason  string
+	restErr *httperr.HttpError
+}
+
+func (r *refusal) Error() string {
+	return r.reason
+}
+
+func rejection(reason string) error {
+	return &refusal{reason: reason, restErr: httperr.NewForbiddenError(loginRefusedMessage)}
+}
+
+// normalize drops an email that is not a plain address and trims what
+// would not fit the account.
+func (i *identity) normalize() {
+	i.Email = strings.ToLower(strings.TrimSpace(i.Email))
+	if address, err := mail.ParseAddress(i.Email); err != nil || address.Address != i.Email || len(i.Email) > 255 {
+		i.Email, i.EmailVerified = "", false
+	}
+	i.FirstName = truncate(strings.TrimSpace(i.FirstName), 255)
+	i.LastName = truncate(strings.TrimSpace(i.LastName), 255)
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// randomString returns 32 random bytes, base64url encoded: the 43
+// characters PKCE allows at least.
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,432 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testClientID     = "auth-service"
+	testClientSecret = "client secret"
+	testSubject      = "248289761001"
+	testEmail        = "jane@example.com"
+)
+
+func TestCallbackCompletesLoginWithSecondFactor(t *testing.T) {
+	env := newTestEnv(t)
+	account := env.addAccount(t, testEmail)
+
+	res, restErr := env.login(t, nil)
+	if restErr != nil {
+		t.Fatalf("Callback() error = %v", restErr)
+	}
+
+	if len(env.sessions.logins) != 1 {
+		t.Fatalf("CompleteLogin() called %d times, want 1", len(env.sessions.logins))
+	}
+	login := env.sessions.logins[0]
+	if login.userID != account.GetID() || login.orgID != nil || login.method != ProviderOIDC {
+		t.Fatalf("CompleteLogin(%s, %v, %q), want (%s, nil, %q)", login.userID, login.orgID, login.method, account.GetID(), ProviderOIDC)
+	}
+	if !res.MFARequired || res.MFAToken != "challenge" || res.TokenResponse != nil || res.ReturnTo != "/home" {
+		t.Fatalf("Callback() = %+v, want the second factor challenge", res)
+	}
+
+	linked, err := env.repository.FindIdentity(context.Background(), ProviderOIDC, testSubject)
+	if err != nil || linked.GetUserID() != account.GetID() {
+		t.Fatalf("identity linked to %v, %v; want %s", linked, err, account.GetID())
+	}
+}
+
+func TestCallbackReadsProfileFromUserInfo(t *testing.T) {
+	env := newTestEnv(t)
+	account := env.addAccount(t, testEmail)
+	env.idp.userInfo = map[string]any{"sub": testSubject, "email": testEmail, "email_verified": "true", "name": "Jane Doe"}
+
+	if _, restErr := env.login(t, func(claims jwt.MapClaims) {
+		delete(claims, "email")
+		delete(claims, "email_verified")
+	}); restErr != nil {
+		t.Fatalf("Callback() error = %v", restErr)
+	}
+	if len(env.sessions.logins) != 1 || env.sessions.logins[0].userID != account.GetID() {
+		t.Fatalf("logins = %+v, want one for %s", env.sessions.logins, account.GetID())
+	}
+}
+
+func TestCallbackRefusesInvalidIDToken(t *testing.T) {
+	foreignKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+		setup  func(idp *mockOIDC)
+	}{
+		{name: "another request", tamper: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+		{name: "another audience", tamper: func(claims jwt.MapClaims) { claims["aud"] = "another-client" }},
+		{name: "another issuer", tamper: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{name: "authorized for another client", tamper: func(claims jwt.MapClaims) { claims["azp"] = "another-client" }},
+		{name: "expired", tamper: func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "no expiry", tamper: func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{name: "no subject", tamper: func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{name: "signed by a foreign key", setup: func(idp *mockOIDC) { idp.signingKey = foreignKey }},
+		{name: "unsigned", setup: func(idp *mockOIDC) { idp.unsigned = true }},
+		{name: "unverified email", tamper: func(claims jwt.MapClaims) { claims["email_verified"] = false }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.addAccount(t, testEmail)
+			if tt.setup != nil {
+				tt.setup(env.idp)
+			}
+
+			_, restErr := env.login(t, tt.tamper)
+			if restErr == nil || (restErr.Code != http.StatusUnauthorized && restErr.Code != http.StatusForbidden) {
+				t.Fatalf("Callback() error = %v, want a refusal", restErr)
+			}
+			if len(env.sessions.logins) != 0 {
+				t.Fatalf("CompleteLogin() called for a refused token: %+v", env.sessions.logins)
+			}
+			if env.audit.Failures() != 1 {
+				t.Fatalf("failures audited = %d, want 1", env.audit.Failures())
+			}
+		})
+	}
+}
+
+func TestCallbackIsAnsweredOnce(t *testing.T) {
+	env := newTestEnv(t)
+	env.addAccount(t, testEmail)
+
+	state, code := env.authorize(t, nil)
+	if _, restErr := env.service.Callback(context.Background(), ProviderOIDC, CallbackRequest{State: state, Code: code}); restErr != nil {
+		t.Fatalf("Callback() error = %v", restErr)
+	}
+	if _, restErr := env.service.Callback(context.Background(), ProviderOIDC, CallbackRequest{State: state, Code: code}); restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("replayed Callback() error = %v, want 400", restErr)
+	}
+}
+
+type testEnv struct {
+	service    ServiceInterface
+	idp        *mockOIDC
+	repository *fakeRepository
+	users      *testutil.Users
+	sessions   *fakeSessions
+	audit      *testutil.Recorder
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	idp := newMockOIDC(t)
+	env := &testEnv{
+		idp:        idp,
+		repository: &fakeRepository{requests: map[string]Request{}, identities: map[string]domain.UserIdentityInterface{}},
+		users:      testutil.NewUsers(),
+		sessions:   &fakeSessions{},
+		audit:      &testutil.Recorder{},
+	}
+	env.service = NewService(
+		config.FederationConfig{
+			BaseURL:    "https://auth.example.com/federation",
+			RequestTTL: 600,
+			Timeout:    5,
+			OIDC: config.FederationProviderConfig{
+				Name:         "Example",
+				ClientID:     testClientID,
+				ClientSecret: testClientSecret,
+				Issuer:       idp.server.URL,
+				Scopes:       []string{"openid", "email", "profile"},
+			},
+		},
+		testutil.DB{},
+		env.repository,
+		env.users,
+		fakeAccounts{},
+		env.sessions,
+		env.audit,
+	)
+	return env
+}
+
+func (env *testEnv) addAccount(t *testing.T, email string) domain.UserInterface {
+	t.Helper()
+
+	account, err := domain.New(email, "", "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+	account.VerifyEmail()
+	env.users.Add(account)
+	return account
+}
+
+// login runs the whole authorization code flow against the mock provider,
+// which issues an ID token with the claims tamper leaves.
+func (env *testEnv) login(t *testing.T, tamper func(jwt.MapClaims)) (*CallbackResponse, *httperr.HttpError) {
+	t.Helper()
+
+	state, code := env.authorize(t, tamper)
+	return env.service.Callback(context.Background(), ProviderOIDC, CallbackRequest{State: state, Code: code})
+}
+
+// authorize begins a login and follows the authorization URL to the mock
+// provider, as a browser would, returning what it sends back.
+func (env *testEnv) authorize(t *testing.T, tamper func(jwt.MapClaims)) (string, string) {
+	t.Helper()
+
+	location, restErr := env.service.BeginLogin(context.Background(), ProviderOIDC, "/home")
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	authorization, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", location, err)
+	}
+	if authorization.Host != env.idp.host() || authorization.Path != "/authorize" {
+		t.Fatalf("authorization URL = %s, want the provider's endpoint", location)
+	}
+
+	query := authorization.Query()
+	if query.Get("client_id") != testClientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization query = %v", query)
+	}
+	return query.Get("state"), env.idp.authorize(query, tamper)
+}
+
+// mockOIDC is an OpenID provider serving discovery, keys, a token endpoint
+// that checks the client and the PKCE verifier, and userinfo.
+type mockOIDC struct {
+	t      *testing.T
+	server *httptest.Server
+
+	key        *rsa.PrivateKey
+	signingKey *rsa.PrivateKey
+	unsigned   bool
+	userInfo   map[string]any
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+type issuedCode struct {
+	challenge   string
+	redirectURI string
+	claims      jwt.MapClaims
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	idp := &mockOIDC{t: t, key: key, signingKey: key, codes: map[string]issuedCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("GET /jwks", idp.jwks)
+	mux.HandleFunc("POST /token", idp.token)
+	mux.HandleFunc("GET /userinfo", idp.userinfo)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *mockOIDC) host() string {
+	u, _ := url.Parse(idp.server.URL)
+	return u.Host
+}
+
+// authorize issues a code for an authorization request, as the provider
+// would once the user signed in.
+func (idp *mockOIDC) authorize(query url.Values, tamper func(jwt.MapClaims)) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            testSubject,
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          query.Get("nonce"),
+		"email":          testEmail,
+		"email_verified": true,
+		"given_name":     "Jane",
+		"family_name":    "Doe",
+	}
+	if tamper != nil {
+		tamper(claims)
+	}
+
+	code := uuid.NewString()
+	idp.mu.Lock()
+	idp.codes[code] = issuedCode{challenge: query.Get("code_challenge"), redirectURI: query.Get("redirect_uri"), claims: claims}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockOIDC) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                idp.server.URL,
+		"authorization_endpoint":                idp.server.URL + "/authorize",
+		"token_endpoint":                        idp.server.URL + "/token",
+		"userinfo_endpoint":                     idp.server.URL + "/userinfo",
+		"jwks_uri":                              idp.server.URL + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (idp *mockOIDC) jwks(w http.ResponseWriter, _ *http.Request) {
+	jwk, err := keys.NewJWK("key-1", keys.AlgorithmRS256, &idp.key.PublicKey)
+	if err != nil {
+		idp.t.Errorf("keys.NewJWK() error = %v", err)
+	}
+	writeJSON(w, http.StatusOK, keys.JWKS{Keys: []keys.JWK{jwk}})
+}
+
+func (idp *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != url.QueryEscape(testClientSecret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	idp.mu.Lock()
+	issued, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || issued.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != issued.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	var idToken string
+	if idp.unsigned {
+		idToken, _ = jwt.NewWithClaims(jwt.SigningMethodNone, issued.claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	} else {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, issued.claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(idp.signingKey)
+		if err != nil {
+			idp.t.Errorf("SignedString() error = %v", err)
+		}
+		idToken = signed
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (idp *mockOIDC) userinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer access-token" || idp.userInfo == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, idp.userInfo)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type fakeRepository struct {
+	RepositoryInterface
+	requests   map[string]Request
+	identities map[string]domain.UserIdentityInterface
+}
+
+func (r *fakeRepository) CreateRequest(_ context.Context, request Request) error {
+	r.requests[request.State] = request
+	return nil
+}
+
+func (r *fakeRepository) ConsumeRequest(_ context.Context, provider, state string, now time.Time) (*Request, error) {
+	request, ok := r.requests[state]
+	delete(r.requests, state)
+	if !ok || request.Provider != provider || !now.Before(request.ExpiresAt) {
+		return nil, ErrRequestNotFound
+	}
+	return &request, nil
+}
+
+func (r *fakeRepository) DeleteExpired(context.Context, time.Time) error {
+	return nil
+}
+
+func (r *fakeRepository) FindIdentity(_ context.Context, provider, subject string) (domain.UserIdentityInterface, error) {
+	if identity, ok := r.identities[provider+"/"+subject]; ok {
+		return identity, nil
+	}
+	return nil, ErrIdentityNotFound
+}
+
+func (r *fakeRepository) CreateIdentity(_ context.Context, identity domain.UserIdentityInterface) error {
+	r.identities[identity.GetProvider()+"/"+identity.GetSubject()] = identity
+	return nil
+}
+
+func (r *fakeRepository) UpdateIdentity(context.Context, domain.UserIdentityInterface) error {
+	return nil
+}
+
+type fakeAccounts struct {
+	user.ServiceInterface
+}
+
+type completedLogin struct {
+	userID uuid.UUID
+	orgID  *uuid.UUID
+	method string
+}
+
+// fakeSessions answers every login with a second factor challenge, as for
+// a user enrolled in one.
+type fakeSessions struct {
+	auth.ServiceInterface
+	logins []completedLogin
+}
+
+func (s *fakeSessions) CompleteLogin(_ context.Context, userID uuid.UUID, orgID *uuid.UUID, method string) (*auth.LoginResponse, *httperr.HttpError) {
+	s.logins = append(s.logins, completedLogin{userID: userID, orgID: orgID, method: method})
+	return &auth.LoginResponse{MFARequired: true, MFAToken: "challenge", MFATokenExpiresIn: 300}, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/joho/godotenv"
@@ -17,6 +18,25 @@ type config struct {
 	HttpServer HttpServerConfig
 	Log        LogConfig
 	Telemetry  TelemetryConfig
+	Token      TokenConfig
+	Keys       KeysConfig
+	OAuth      OAuthConfig
+	Admin      AdminConfig
+	MFA        MFAConfig
+	WebAuthn   WebAuthnConfig
+	Mailer     MailerConfig
+	Account    AccountConfig
+	RBAC       RBACConfig
+	Org        OrgConfig
+	Throttle   LoginThrottleConfig
+	RateLimit  RateLimitConfig
+	Audit      AuditConfig
+	Outbox     OutboxConfig
+	Webhook    WebhookConfig
+	SCIM       SCIMConfig
+	SAML       SAMLConfig
+	Federation FederationConfig
+	LDAP       LDAPConfig
 }
 
 type ConfigInterface interface {
@@ -24,6 +44,25 @@ type ConfigInterface interface {
 	GetHttpServerConfig() HttpServerConfig
 	GetLogConfig() LogConfig
 	GetTelemetryConfig() TelemetryConfig
+	GetTokenConfig() TokenConfig
+	GetKeysConfig() KeysConfig
+	GetOAuthConfig() OAuthConfig
+	GetAdminConfig() AdminConfig
+	GetMFAConfig() MFAConfig
+	GetWebAuthnConfig() WebAuthnConfig
+	GetMailerConfig() MailerConfig
+	GetAccountConfig() AccountConfig
+	GetRBACConfig() RBACConfig
+	GetOrgConfig() OrgConfig
+	GetLoginThrottleConfig() LoginThrottleConfig
+	GetRateLimitConfig() RateLimitConfig
+	GetAuditConfig() AuditConfig
+	GetOutboxConfig() OutboxConfig
+	GetWebhookConfig() WebhookConfig
+	GetSCIMConfig() SCIMConfig
+	GetSAMLConfig() SAMLConfig
+	GetFederationConfig() FederationConfig
+	GetLDAPConfig() LDAPConfig
 }
 
 type DatabaseConfig struct {
@@ -36,14 +75,18 @@ type DatabaseConfig struct {
 	MaxConnections  int
 	MinConnections  int
 	ConnMaxLifetime int
+	// RequireRowSecurity refuses to start with a database role that
+	// bypasses row-level security, such as a superuser.
+	RequireRowSecurity bool
 }
 
 type HttpServerConfig struct {
-	Port         string
-	ReadTimeout  int
-	WriteTimeout int
-	IdleTimeout  int
-	Environment  string
+	Port           string
+	ReadTimeout    int
+	WriteTimeout   int
+	IdleTimeout    int
+	Environment    string
+	TrustedProxies []string
 }
 
 type LogConfig struct {
@@ -60,6 +103,269 @@ type TelemetryConfig struct {
 	OtelExporterOtlpInsecure bool
 }
 
+type TokenConfig struct {
+	Issuer          string
+	Audience        string
+	AccessTokenTTL  int
+	RefreshTokenTTL int
+	IDTokenTTL      int
+}
+
+type KeysConfig struct {
+	Algorithm             string
+	MasterKey             string
+	RotationInterval      int
+	RotationCheckInterval int
+	CacheTTL              int
+}
+
+type OAuthConfig struct {
+	Issuer               string
+	AuthorizationCodeTTL int
+	RegistrationToken    string
+}
+
+type AdminConfig struct {
+	APIToken string
+}
+
+type MFAConfig struct {
+	Issuer               string
+	Skew                 int
+	ChallengeTTL         int
+	ChallengeMaxAttempts int
+	RecoveryCodeCount    int
+}
+
+type WebAuthnConfig struct {
+	RPID          string
+	RPDisplayName string
+	RPOrigins     []string
+	Attestation   string
+	SessionTTL    int
+}
+
+type MailerConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTLS      string
+	FilePath     string
+	Timeout      int
+}
+
+// Unverified policies decide what a user who has not verified their email
+// may do: anything, only sign in and manage the basics, or nothing at all.
+const (
+	UnverifiedPolicyAllow    = "allow"
+	UnverifiedPolicyRestrict = "restrict"
+	UnverifiedPolicyBlock    = "block"
+)
+
+type AccountConfig struct {
+	VerifyEmailURL       string
+	ResetPasswordURL     string
+	EmailVerificationTTL int
+	PasswordResetTTL     int
+	UnverifiedPolicy     string
+	// LoginLinkURL is the page a mailed sign-in link opens; it posts the
+	// token back to /api/v1/auth/passwordless/verify.
+	LoginLinkURL string
+	LoginLinkTTL int
+	LoginCodeTTL int
+	// NonceCookieSecure restricts the cookie binding a passwordless login to
+	// its browser to HTTPS. Only local development over HTTP turns it off.
+	NonceCookieSecure bool
+}
+
+type RBACConfig struct {
+	CacheTTL int
+}
+
+type OrgConfig struct {
+	InvitationURL string
+	InvitationTTL int
+	OwnerRole     string
+	MemberRole    string
+}
+
+type LoginThrottleConfig struct {
+	Backend      string
+	Window       int
+	FreeAttempts int
+	BaseDelay    int
+	MaxDelay     int
+	PairLimit    int
+	AccountLimit int
+	IPLimit      int
+	Lockout      int
+}
+
+type RateLimitConfig struct {
+	Enabled  bool
+	Store    string
+	Policies []string
+	// FailOpen lets requests through when the store cannot be reached.
+	FailOpen      bool
+	RedisAddr     string
+	RedisUsername string
+	RedisPassword string
+	RedisDB       int
+	RedisTLS      bool
+	RedisTimeout  int
+}
+
+type AuditConfig struct {
+	// CheckpointInterval is how often, in seconds, the head of the audit
+	// chain is signed. Zero disables checkpoints.
+	CheckpointInterval int
+}
+
+type OutboxConfig struct {
+	Sink         string
+	PollInterval int
+	BatchSize    int
+	// MaxAttempts bounds the deliveries of one event; after that it stays
+	// in the table undelivered. Zero retries forever.
+	MaxAttempts     int
+	RetryBackoff    int
+	MaxRetryBackoff int
+	Retention       int
+	WebhookURL      string
+	WebhookSecret   string
+	WebhookTimeout  int
+	NATSURL         string
+	NATSSubject     string
+	NATSTimeout     int
+	KafkaBrokers    []string
+	KafkaTopic      string
+	KafkaTLS        bool
+	KafkaTimeout    int
+	NotifyChannel   string
+}
+
+type WebhookConfig struct {
+	PollInterval    int
+	BatchSize       int
+	Timeout         int
+	MaxAttempts     int
+	RetryBackoff    int
+	MaxRetryBackoff int
+	// Retention is how long, in seconds, finished deliveries stay in the
+	// delivery log.
+	Retention int
+	// AllowPrivateNetworks lets endpoints resolve to loopback and private
+	// addresses, which is otherwise refused to keep admins from reaching
+	// internal services through webhooks.
+	AllowPrivateNetworks bool
+}
+
+type SCIMConfig struct {
+	// BaseURL is where the SCIM API is reached from outside, used in the
+	// location of every resource.
+	BaseURL string
+	// MaxResults caps the page size of a list, which is also the page size
+	// when the client does not ask for one.
+	MaxResults int
+}
+
+type SAMLConfig struct {
+	// BaseURL is where the SAML endpoints are reached from outside; the
+	// entity ID and the assertion consumer service URL of each connection
+	// are derived from it.
+	BaseURL string
+	// RequestTTL bounds, in seconds, how long an identity provider has to
+	// answer an authentication request.
+	RequestTTL int
+	// ClockSkew is the leeway, in seconds, granted to the validity window
+	// of an assertion.
+	ClockSkew int
+	// AssertionTTL is how long, in seconds, an assertion issued to a
+	// service provider may be presented.
+	AssertionTTL int
+	// SessionTTL is how long, in seconds, service providers may keep a
+	// session they got from an assertion before signing the user in again.
+	SessionTTL int
+}
+
+type FederationConfig struct {
+	// BaseURL is where the federation endpoints are reached from outside;
+	// the redirect URI to register at a provider is BaseURL/<provider>/callback.
+	BaseURL string
+	// RequestTTL bounds, in seconds, how long a user has to come back from
+	// the provider.
+	RequestTTL int
+	// Timeout bounds, in seconds, each call to a provider.
+	Timeout int
+	// AllowSignup provisions an account, without a password, for a user a
+	// provider vouches for whose email matches no account.
+	AllowSignup bool
+	Google      FederationProviderConfig
+	GitHub      FederationProviderConfig
+	OIDC        FederationProviderConfig
+}
+
+// FederationProviderConfig configures one provider, which is enabled when
+// it has a client ID. OpenID Connect providers are found through the
+// discovery document of Issuer; GitHub, which is plain OAuth 2.0, is
+// reached at AuthURL, TokenURL and APIURL instead.
+type FederationProviderConfig struct {
+	// Name is shown to users choosing a provider.
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Issuer       string
+	Scopes       []string
+	AuthURL      string
+	TokenURL     string
+	APIURL       string
+}
+
+// LDAPConfig configures password logins checked against a directory, which
+// are enabled when URL is set. The user entry is searched for with the
+// service account, then bound to with the password.
+type LDAPConfig struct {
+	// URL is ldap://host[:port] or ldaps://host[:port].
+	URL string
+	// StartTLS upgrades an ldap:// connection before anything is sent.
+	StartTLS bool
+	// CAFile is a PEM bundle of the CAs trusted for the server certificate,
+	// the system pool when empty.
+	CAFile             string
+	InsecureSkipVerify bool
+	// Timeout bounds, in seconds, a whole login against the directory.
+	Timeout      int
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserFilter finds the entry of a login; {login} is replaced with the
+	// escaped email that was entered.
+	UserFilter string
+	// UIDAttribute holds the stable identifier of an entry, such as
+	// entryUUID or, in Active Directory, objectGUID.
+	UIDAttribute       string
+	EmailAttribute     string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	PhoneAttribute     string
+	// GroupBaseDN is searched with GroupFilter, where {dn} is replaced with
+	// the escaped DN of the user, for the groups of a user. When empty, the
+	// groups are read from the memberOf attribute of the user entry.
+	GroupBaseDN string
+	GroupFilter string
+	// GroupRoles maps group DNs to the roles their members are given.
+	GroupRoles map[string]string
+	// SyncAttributes copies the names and phone of the entry to the account
+	// on every login.
+	SyncAttributes bool
+	// AllowSignup provisions an account, without a password, for an entry
+	// whose email matches no account.
+	AllowSignup bool
+}
+
 func New() ConfigInterface {
 	var cfg *config
 	once.Do(func() {
@@ -67,22 +373,24 @@ func New() ConfigInterface {
 
 		cfg = &config{
 			Database: DatabaseConfig{
-				Host:            getEnv("DB_HOST", "localhost"),
-				Port:            getEnv("DB_PORT", "5432"),
-				User:            getEnv("DB_USER", "user"),
-				Password:        getEnv("DB_PASSWORD", ""),
-				Name:            getEnv("DB_NAME", "db"),
-				SslMode:         getEnv("DB_SSL", "disable"),
-				MaxConnections:  getEnvInt("DB_MAX_CONNECTIONS", 20),
-				MinConnections:  getEnvInt("DB_MIN_CONNECTIONS", 1),
-				ConnMaxLifetime: getEnvInt("DB_CONN_MAX_LIFETIME", 300),
+				Host:               getEnv("DB_HOST", "localhost"),
+				Port:               getEnv("DB_PORT", "5432"),
+				User:               getEnv("DB_USER", "user"),
+				Password:           getEnv("DB_PASSWORD", ""),
+				Name:               getEnv("DB_NAME", "db"),
+				SslMode:            getEnv("DB_SSL", "disable"),
+				MaxConnections:     getEnvInt("DB_MAX_CONNECTIONS", 20),
+				MinConnections:     getEnvInt("DB_MIN_CONNECTIONS", 1),
+				ConnMaxLifetime:    getEnvInt("DB_CONN_MAX_LIFETIME", 300),
+				RequireRowSecurity: getEnvBool("DB_REQUIRE_ROW_SECURITY", true),
 			},
 			HttpServer: HttpServerConfig{
-				Port:         getEnv("HTTP_SERVER_PORT", "8000"),
-				ReadTimeout:  getEnvInt("HTTP_SERVER_READ_TIMEOUT", 15),
-				WriteTimeout: getEnvInt("HTTP_SERVER_WRITE_TIMEOUT", 15),
-				IdleTimeout:  getEnvInt("HTTP_SERVER_IDLE_TIMEOUT", 60),
-				Environment:  getEnv("ENVIRONMENT", "development"),
+				Port:           getEnv("HTTP_SERVER_PORT", "8000"),
+				ReadTimeout:    getEnvInt("HTTP_SERVER_READ_TIMEOUT", 15),
+				WriteTimeout:   getEnvInt("HTTP_SERVER_WRITE_TIMEOUT", 15),
+				IdleTimeout:    getEnvInt("HTTP_SERVER_IDLE_TIMEOUT", 60),
+				Environment:    getEnv("ENVIRONMENT", "development"),
+				TrustedProxies: getEnvList("HTTP_SERVER_TRUSTED_PROXIES", ""),
 			},
 			Log: LogConfig{
 				Level:       getEnv("LOG_LEVEL", "INFO"),
@@ -96,6 +404,190 @@ func New() ConfigInterface {
 				OtelExporterOtlpEndpoint: getEnv("OTEL_EXPORTER_ENDPOINT", "http://collector:4317"),
 				OtelExporterOtlpInsecure: true,
 			},
+			Token: TokenConfig{
+				Issuer:          getEnv("TOKEN_ISSUER", "http://localhost:8000"),
+				Audience:        getEnv("TOKEN_AUDIENCE", "auth-service"),
+				AccessTokenTTL:  getEnvInt("TOKEN_ACCESS_TTL", 900),
+				RefreshTokenTTL: getEnvInt("TOKEN_REFRESH_TTL", 2592000),
+				IDTokenTTL:      getEnvInt("TOKEN_ID_TTL", 3600),
+			},
+			Keys: KeysConfig{
+				Algorithm:             getEnv("KEYS_SIGNING_ALGORITHM", "EdDSA"),
+				MasterKey:             getEnv("KEYS_MASTER_KEY", ""),
+				RotationInterval:      getEnvInt("KEYS_ROTATION_INTERVAL", 2592000),
+				RotationCheckInterval: getEnvInt("KEYS_ROTATION_CHECK_INTERVAL", 3600),
+				CacheTTL:              getEnvInt("KEYS_CACHE_TTL", 60),
+			},
+			OAuth: OAuthConfig{
+				Issuer:               getEnv("TOKEN_ISSUER", "http://localhost:8000"),
+				AuthorizationCodeTTL: getEnvInt("OAUTH_AUTHORIZATION_CODE_TTL", 60),
+				RegistrationToken:    getEnv("OAUTH_REGISTRATION_TOKEN", ""),
+			},
+			Admin: AdminConfig{
+				APIToken: getEnv("ADMIN_API_TOKEN", ""),
+			},
+			MFA: MFAConfig{
+				Issuer:               getEnv("MFA_TOTP_ISSUER", "auth-service"),
+				Skew:                 getEnvInt("MFA_TOTP_SKEW", 1),
+				ChallengeTTL:         getEnvInt("MFA_CHALLENGE_TTL", 300),
+				ChallengeMaxAttempts: getEnvInt("MFA_CHALLENGE_MAX_ATTEMPTS", 5),
+				RecoveryCodeCount:    getEnvInt("MFA_RECOVERY_CODE_COUNT", 10),
+			},
+			WebAuthn: WebAuthnConfig{
+				RPID:          getEnv("WEBAUTHN_RP_ID", "localhost"),
+				RPDisplayName: getEnv("WEBAUTHN_RP_NAME", "auth-service"),
+				RPOrigins:     getEnvList("WEBAUTHN_RP_ORIGINS", "http://localhost:8000"),
+				Attestation:   getEnv("WEBAUTHN_ATTESTATION", "none"),
+				SessionTTL:    getEnvInt("WEBAUTHN_SESSION_TTL", 300),
+			},
+			Mailer: MailerConfig{
+				Driver:       getEnv("MAIL_DRIVER", "stdout"),
+				From:         getEnv("MAIL_FROM", "auth-service <no-reply@localhost>"),
+				SMTPHost:     getEnv("MAIL_SMTP_HOST", "localhost"),
+				SMTPPort:     getEnv("MAIL_SMTP_PORT", "587"),
+				SMTPUsername: getEnv("MAIL_SMTP_USERNAME", ""),
+				SMTPPassword: getEnv("MAIL_SMTP_PASSWORD", ""),
+				SMTPTLS:      getEnv("MAIL_SMTP_TLS", "starttls"),
+				FilePath:     getEnv("MAIL_FILE_PATH", "./logs/mail.log"),
+				Timeout:      getEnvInt("MAIL_TIMEOUT", 10),
+			},
+			Account: AccountConfig{
+				VerifyEmailURL:       getEnv("ACCOUNT_VERIFY_EMAIL_URL", "http://localhost:3000/verify-email"),
+				ResetPasswordURL:     getEnv("ACCOUNT_RESET_PASSWORD_URL", "http://localhost:3000/reset-password"),
+				EmailVerificationTTL: getEnvInt("ACCOUNT_EMAIL_VERIFICATION_TTL", 86400),
+				PasswordResetTTL:     getEnvInt("ACCOUNT_PASSWORD_RESET_TTL", 3600),
+				UnverifiedPolicy:     getEnv("ACCOUNT_UNVERIFIED_POLICY", "restrict"),
+				LoginLinkURL:         getEnv("ACCOUNT_LOGIN_LINK_URL", "http://localhost:3000/login-link"),
+				LoginLinkTTL:         getEnvInt("ACCOUNT_LOGIN_LINK_TTL", 900),
+				LoginCodeTTL:         getEnvInt("ACCOUNT_LOGIN_CODE_TTL", 600),
+				NonceCookieSecure:    getEnvBool("ACCOUNT_NONCE_COOKIE_SECURE", true),
+			},
+			RBAC: RBACConfig{
+				CacheTTL: getEnvInt("RBAC_CACHE_TTL", 30),
+			},
+			Org: OrgConfig{
+				InvitationURL: getEnv("ORG_INVITATION_URL", "http://localhost:3000/invitations/accept"),
+				InvitationTTL: getEnvInt("ORG_INVITATION_TTL", 604800),
+				OwnerRole:     getEnv("ORG_OWNER_ROLE", "org_owner"),
+				MemberRole:    getEnv("ORG_MEMBER_ROLE", "org_member"),
+			},
+			Throttle: LoginThrottleConfig{
+				Backend:      getEnv("LOGIN_THROTTLE_BACKEND", "memory"),
+				Window:       getEnvInt("LOGIN_THROTTLE_WINDOW", 900),
+				FreeAttempts: getEnvInt("LOGIN_THROTTLE_FREE_ATTEMPTS", 3),
+				BaseDelay:    getEnvInt("LOGIN_THROTTLE_BASE_DELAY", 1),
+				MaxDelay:     getEnvInt("LOGIN_THROTTLE_MAX_DELAY", 30),
+				PairLimit:    getEnvInt("LOGIN_THROTTLE_PAIR_LIMIT", 10),
+				AccountLimit: getEnvInt("LOGIN_THROTTLE_ACCOUNT_LIMIT", 50),
+				IPLimit:      getEnvInt("LOGIN_THROTTLE_IP_LIMIT", 100),
+				Lockout:      getEnvInt("LOGIN_THROTTLE_LOCKOUT", 900),
+			},
+			RateLimit: RateLimitConfig{
+				Enabled:       getEnvBool("RATE_LIMIT_ENABLED", true),
+				Store:         getEnv("RATE_LIMIT_STORE", "memory"),
+				Policies:      getEnvList("RATE_LIMIT_POLICIES", "* * ip 600/60,POST /api/v1/auth/login ip 30/60,POST /api/v1/auth/passwordless/start ip 10/60,POST /token ip 120/60,* * client 1200/60"),
+				FailOpen:      getEnvBool("RATE_LIMIT_FAIL_OPEN", true),
+				RedisAddr:     getEnv("RATE_LIMIT_REDIS_ADDR", "localhost:6379"),
+				RedisUsername: getEnv("RATE_LIMIT_REDIS_USERNAME", ""),
+				RedisPassword: getEnv("RATE_LIMIT_REDIS_PASSWORD", ""),
+				RedisDB:       getEnvInt("RATE_LIMIT_REDIS_DB", 0),
+				RedisTLS:      getEnvBool("RATE_LIMIT_REDIS_TLS", false),
+				RedisTimeout:  getEnvInt("RATE_LIMIT_REDIS_TIMEOUT", 2),
+			},
+			Audit: AuditConfig{
+				CheckpointInterval: getEnvInt("AUDIT_CHECKPOINT_INTERVAL", 900),
+			},
+			Outbox: OutboxConfig{
+				Sink:            getEnv("OUTBOX_SINK", "none"),
+				PollInterval:    getEnvInt("OUTBOX_POLL_INTERVAL", 1),
+				BatchSize:       getEnvInt("OUTBOX_BATCH_SIZE", 100),
+				MaxAttempts:     getEnvInt("OUTBOX_MAX_ATTEMPTS", 0),
+				RetryBackoff:    getEnvInt("OUTBOX_RETRY_BACKOFF", 5),
+				MaxRetryBackoff: getEnvInt("OUTBOX_MAX_RETRY_BACKOFF", 600),
+				Retention:       getEnvInt("OUTBOX_RETENTION", 604800),
+				WebhookURL:      getEnv("OUTBOX_WEBHOOK_URL", ""),
+				WebhookSecret:   getEnv("OUTBOX_WEBHOOK_SECRET", ""),
+				WebhookTimeout:  getEnvInt("OUTBOX_WEBHOOK_TIMEOUT", 10),
+				NATSURL:         getEnv("OUTBOX_NATS_URL", "nats://localhost:4222"),
+				NATSSubject:     getEnv("OUTBOX_NATS_SUBJECT", "auth"),
+				NATSTimeout:     getEnvInt("OUTBOX_NATS_TIMEOUT", 5),
+				KafkaBrokers:    getEnvList("OUTBOX_KAFKA_BROKERS", "localhost:9092"),
+				KafkaTopic:      getEnv("OUTBOX_KAFKA_TOPIC", "auth.events"),
+				KafkaTLS:        getEnvBool("OUTBOX_KAFKA_TLS", false),
+				KafkaTimeout:    getEnvInt("OUTBOX_KAFKA_TIMEOUT", 10),
+				NotifyChannel:   getEnv("OUTBOX_NOTIFY_CHANNEL", "auth_events"),
+			},
+			Webhook: WebhookConfig{
+				PollInterval:         getEnvInt("WEBHOOK_POLL_INTERVAL", 1),
+				BatchSize:            getEnvInt("WEBHOOK_BATCH_SIZE", 50),
+				Timeout:              getEnvInt("WEBHOOK_TIMEOUT", 10),
+				MaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 12),
+				RetryBackoff:         getEnvInt("WEBHOOK_RETRY_BACKOFF", 10),
+				MaxRetryBackoff:      getEnvInt("WEBHOOK_MAX_RETRY_BACKOFF", 21600),
+				Retention:            getEnvInt("WEBHOOK_RETENTION", 2592000),
+				AllowPrivateNetworks: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+			},
+			SCIM: SCIMConfig{
+				BaseURL:    getEnv("SCIM_BASE_URL", "http://localhost:8000/scim/v2"),
+				MaxResults: getEnvInt("SCIM_MAX_RESULTS", 200),
+			},
+			SAML: SAMLConfig{
+				BaseURL:      getEnv("SAML_BASE_URL", "http://localhost:8000/api/v1/saml"),
+				RequestTTL:   getEnvInt("SAML_REQUEST_TTL", 600),
+				ClockSkew:    getEnvInt("SAML_CLOCK_SKEW", 180),
+				AssertionTTL: getEnvInt("SAML_ASSERTION_TTL", 300),
+				SessionTTL:   getEnvInt("SAML_SESSION_TTL", 28800),
+			},
+			Federation: FederationConfig{
+				BaseURL:     getEnv("FEDERATION_BASE_URL", "http://localhost:8000/api/v1/auth/federation"),
+				RequestTTL:  getEnvInt("FEDERATION_REQUEST_TTL", 600),
+				Timeout:     getEnvInt("FEDERATION_TIMEOUT", 10),
+				AllowSignup: getEnvBool("FEDERATION_ALLOW_SIGNUP", true),
+				Google: FederationProviderConfig{
+					Name:         getEnv("FEDERATION_GOOGLE_NAME", "Google"),
+					ClientID:     getEnv("FEDERATION_GOOGLE_CLIENT_ID", ""),
+					ClientSecret: getEnv("FEDERATION_GOOGLE_CLIENT_SECRET", ""),
+					Issuer:       getEnv("FEDERATION_GOOGLE_ISSUER", "https://accounts.google.com"),
+					Scopes:       getEnvList("FEDERATION_GOOGLE_SCOPES", "openid,email,profile"),
+				},
+				GitHub: FederationProviderConfig{
+					Name:         getEnv("FEDERATION_GITHUB_NAME", "GitHub"),
+					ClientID:     getEnv("FEDERATION_GITHUB_CLIENT_ID", ""),
+					ClientSecret: getEnv("FEDERATION_GITHUB_CLIENT_SECRET", ""),
+					Scopes:       getEnvList("FEDERATION_GITHUB_SCOPES", "read:user,user:email"),
+					AuthURL:      getEnv("FEDERATION_GITHUB_AUTH_URL", "https://github.com/login/oauth/authorize"),
+					TokenURL:     getEnv("FEDERATION_GITHUB_TOKEN_URL", "https://github.com/login/oauth/access_token"),
+					APIURL:       getEnv("FEDERATION_GITHUB_API_URL", "https://api.github.com"),
+				},
+				OIDC: FederationProviderConfig{
+					Name:         getEnv("FEDERATION_OIDC_NAME", "Single sign-on"),
+					ClientID:     getEnv("FEDERATION_OIDC_CLIENT_ID", ""),
+					ClientSecret: getEnv("FEDERATION_OIDC_CLIENT_SECRET", ""),
+					Issuer:       getEnv("FEDERATION_OIDC_ISSUER", ""),
+					Scopes:       getEnvList("FEDERATION_OIDC_SCOPES", "openid,email,profile"),
+				},
+			},
+			LDAP: LDAPConfig{
+				URL:                getEnv("LDAP_URL", ""),
+				StartTLS:           getEnvBool("LDAP_START_TLS", false),
+				CAFile:             getEnv("LDAP_CA_FILE", ""),
+				InsecureSkipVerify: getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+				Timeout:            getEnvInt("LDAP_TIMEOUT", 10),
+				BindDN:             getEnv("LDAP_BIND_DN", ""),
+				BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+				BaseDN:             getEnv("LDAP_BASE_DN", ""),
+				UserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(mail={login}))"),
+				UIDAttribute:       getEnv("LDAP_UID_ATTRIBUTE", "entryUUID"),
+				EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+				FirstNameAttribute: getEnv("LDAP_FIRST_NAME_ATTRIBUTE", "givenName"),
+				LastNameAttribute:  getEnv("LDAP_LAST_NAME_ATTRIBUTE", "sn"),
+				PhoneAttribute:     getEnv("LDAP_PHONE_ATTRIBUTE", "telephoneNumber"),
+				GroupBaseDN:        getEnv("LDAP_GROUP_BASE_DN", ""),
+				GroupFilter:        getEnv("LDAP_GROUP_FILTER", "(|(member={dn})(uniqueMember={dn}))"),
+				GroupRoles:         getEnvMapping("LDAP_GROUP_ROLES"),
+				SyncAttributes:     getEnvBool("LDAP_SYNC_ATTRIBUTES", true),
+				AllowSignup:        getEnvBool("LDAP_ALLOW_SIGNUP", true),
+			},
 		}
 	})
 
@@ -118,6 +610,82 @@ func (c *config) GetTelemetryConfig() TelemetryConfig {
 	return c.Telemetry
 }
 
+func (c *config) GetTokenConfig() TokenConfig {
+	return c.Token
+}
+
+func (c *config) GetKeysConfig() KeysConfig {
+	return c.Keys
+}
+
+func (c *config) GetOAuthConfig() OAuthConfig {
+	return c.OAuth
+}
+
+func (c *config) GetAdminConfig() AdminConfig {
+	return c.Admin
+}
+
+func (c *config) GetMFAConfig() MFAConfig {
+	return c.MFA
+}
+
+func (c *config) GetWebAuthnConfig() WebAuthnConfig {
+	return c.WebAuthn
+}
+
+func (c *config) GetMailerConfig() MailerConfig {
+	return c.Mailer
+}
+
+func (c *config) GetAccountConfig() AccountConfig {
+	return c.Account
+}
+
+func (c *config) GetRBACConfig() RBACConfig {
+	return c.RBAC
+}
+
+func (c *config) GetOrgConfig() OrgConfig {
+	return c.Org
+}
+
+func (c *config) GetLoginThrottleConfig() LoginThrottleConfig {
+	return c.Throttle
+}
+
+func (c *config) GetRateLimitConfig() RateLimitConfig {
+	return c.RateLimit
+}
+
+func (c *config) GetAuditConfig() AuditConfig {
+	return c.Audit
+}
+
+func (c *config) GetOutboxConfig() OutboxConfig {
+	return c.Outbox
+}
+
+func (c *config) GetWebhookConfig() WebhookConfig {
+	return c.Webhook
+}
+
+func (c *config) GetSCIMConfig() SCIMConfig {
+	return c.SCIM
+}
+
+func (c *config) GetSAMLConfig() SAMLConfig {
+	return c.SAML
+}
+
+func (c *config) GetFederationConfig() FederationConfig {
+	return c.Federation
+}
+
+func (c *config) GetLDAPConfig() LDAPConfig {
+	return c.LDAP
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -128,6 +696,45 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvMapping reads key:value pairs separated by semicolons. Keys may
+// hold colons, as DNs do; the value is what follows the last one.
+func getEnvMapping(key string) map[string]string {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			continue
+		}
+		name, value := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if name != "" && value != "" {
+			mapping[name] = value
+		}
+	}
+	return mapping
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsedValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsedValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	value := os.Getenv(key)
 	if value == "" {
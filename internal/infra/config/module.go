@@ -17,5 +17,62 @@ var Module = fx.Options(
 		func(cfg ConfigInterface) TelemetryConfig {
 			return cfg.GetTelemetryConfig()
 		},
+		func(cfg ConfigInterface) TokenConfig {
+			return cfg.GetTokenConfig()
+		},
+		func(cfg ConfigInterface) KeysConfig {
+			return cfg.GetKeysConfig()
+		},
+		func(cfg ConfigInterface) OAuthConfig {
+			return cfg.GetOAuthConfig()
+		},
+		func(cfg ConfigInterface) AdminConfig {
+			return cfg.GetAdminConfig()
+		},
+		func(cfg ConfigInterface) MFAConfig {
+			return cfg.GetMFAConfig()
+		},
+		func(cfg ConfigInterface) WebAuthnConfig {
+			return cfg.GetWebAuthnConfig()
+		},
+		func(cfg ConfigInterface) MailerConfig {
+			return cfg.GetMailerConfig()
+		},
+		func(cfg ConfigInterface) AccountConfig {
+			return cfg.GetAccountConfig()
+		},
+		func(cfg ConfigInterface) RBACConfig {
+			return cfg.GetRBACConfig()
+		},
+		func(cfg ConfigInterface) OrgConfig {
+			return cfg.GetOrgConfig()
+		},
+		func(cfg ConfigInterface) LoginThrottleConfig {
+			return cfg.GetLoginThrottleConfig()
+		},
+		func(cfg ConfigInterface) RateLimitConfig {
+			return cfg.GetRateLimitConfig()
+		},
+		func(cfg ConfigInterface) AuditConfig {
+			return cfg.GetAuditConfig()
+		},
+		func(cfg ConfigInterface) OutboxConfig {
+			return cfg.GetOutboxConfig()
+		},
+		func(cfg ConfigInterface) WebhookConfig {
+			return cfg.GetWebhookConfig()
+		},
+		func(cfg ConfigInterface) SCIMConfig {
+			return cfg.GetSCIMConfig()
+		},
+		func(cfg ConfigInterface) SAMLConfig {
+			return cfg.GetSAMLConfig()
+		},
+		func(cfg ConfigInterface) FederationConfig {
+			return cfg.GetFederationConfig()
+		},
+		func(cfg ConfigInterface) LDAPConfig {
+			return cfg.GetLDAPConfig()
+		},
 	),
 )
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/exaring/otelpgx"
 	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -22,8 +25,18 @@ type database struct {
 	config config.DatabaseConfig
 }
 
+type txKey struct{}
+
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type DatabaseInterface interface {
 	GetDB() *pgxpool.Pool
+	GetQuerier(ctx context.Context) Querier
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 	Ping(ctx context.Context) error
 	Close()
 }
@@ -63,12 +76,19 @@ func New(config config.DatabaseConfig) (DatabaseInterface, error) {
 
 		slog.Info("attempting to ping database")
 
-		if err := instance.Ping(context.Background()); err != nil {
+		if pingErr := instance.Ping(context.Background()); pingErr != nil {
 			instance.Close()
-			err = fmt.Errorf("failed to connect to database: %w", err)
+			err = fmt.Errorf("failed to connect to database: %w", pingErr)
 			slog.Error("error connecting to database", "error", err)
-		} else {
-			slog.Info("database connection established successfully")
+			return
+		}
+
+		slog.Info("database connection established successfully")
+
+		if rlsErr := instance.checkRowSecurity(context.Background()); rlsErr != nil {
+			instance.Close()
+			err = rlsErr
+			slog.Error("error checking row-level security", "error", err)
 		}
 	})
 
@@ -98,6 +118,63 @@ func (d *database) GetDB() *pgxpool.Pool {
 	return d.db
 }
 
+// GetQuerier returns the current transaction, or the pool when there is
+// none. Contexts carrying a tenant or an actor are never served by the bare
+// pool: each statement is batched behind one that sets the row-level
+// security variables.
+func (d *database) GetQuerier(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*scopedTx); ok {
+		return tx
+	}
+	if !scopeFromContext(ctx).isEmpty() {
+		return scopedPool{db: d}
+	}
+	return d.db
+}
+
+func (d *database) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*scopedTx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, &scopedTx{Tx: tx})); err != nil {
+		rollback(ctx, tx)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// checkRowSecurity looks for a role that bypasses row-level security, as
+// superusers do. Tenant isolation then rests on the query filters alone,
+// which is refused when the configuration requires row security.
+func (d *database) checkRowSecurity(ctx context.Context) error {
+	var bypass bool
+	query := `SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`
+	if err := d.db.QueryRow(ctx, query).Scan(&bypass); err != nil {
+		return fmt.Errorf("failed to check database role: %w", err)
+	}
+
+	if !bypass {
+		return nil
+	}
+	if d.config.RequireRowSecurity {
+		return errors.New("database role bypasses row-level security")
+	}
+
+	slog.Warn("database role bypasses row-level security, tenant isolation relies on query filters only")
+	return nil
+}
+
 func getConnectionString(config config.DatabaseConfig) string {
 	return fmt.Sprintf("user=%s password=%s dbname=%s port=%s host=%s sslmode=%s",
 		config.User,
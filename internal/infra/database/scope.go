@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Session variables read by the row-level security policies. They are only
+// ever set with is_local, so they end with the transaction and never leak
+// to the next user of a pooled connection.
+const (
+	tenantSetting = "app.current_org_id"
+	actorSetting  = "app.current_user_id"
+)
+
+// scope is the pair of session variables a context asks for.
+type scope struct {
+	tenant string
+	actor  string
+}
+
+func scopeFromContext(ctx context.Context) scope {
+	var s scope
+	if orgID, ok := TenantFromContext(ctx); ok {
+		s.tenant = orgID.String()
+	}
+	if userID, ok := ActorFromContext(ctx); ok {
+		s.actor = userID.String()
+	}
+	return s
+}
+
+func (s scope) isEmpty() bool {
+	return s == scope{}
+}
+
+const setScopeQuery = `SELECT set_config($1, $2, true), set_config($3, $4, true)`
+
+func applyScope(ctx context.Context, tx pgx.Tx, s scope) error {
+	if _, err := tx.Exec(ctx, setScopeQuery, tenantSetting, s.tenant, actorSetting, s.actor); err != nil {
+		return fmt.Errorf("failed to set row-level security scope: %w", err)
+	}
+	return nil
+}
+
+// scopedTx is the transaction WithTx hands to repositories. Before every
+// statement it makes the session variables match the context the statement
+// runs with, so switching tenants inside one transaction is honored.
+type scopedTx struct {
+	pgx.Tx
+	applied scope
+}
+
+func (t *scopedTx) sync(ctx context.Context) error {
+	s := scopeFromContext(ctx)
+	if s == t.applied {
+		return nil
+	}
+	if err := applyScope(ctx, t.Tx, s); err != nil {
+		return err
+	}
+	t.applied = s
+	return nil
+}
+
+func (t *scopedTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	if err := t.sync(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return t.Tx.Exec(ctx, sql, arguments...)
+}
+
+func (t *scopedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := t.sync(ctx); err != nil {
+		return nil, err
+	}
+	return t.Tx.Query(ctx, sql, args...)
+}
+
+func (t *scopedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := t.sync(ctx); err != nil {
+		return errRow{err: err}
+	}
+	return t.Tx.QueryRow(ctx, sql, args...)
+}
+
+// scopedPool runs each statement made outside WithTx in a batch of its own,
+// queued behind the statement that sets the session variables. A batch is a
+// single implicit transaction, so the variables end with the statement, and
+// it costs one round trip rather than a begin and a commit around it.
+type scopedPool struct {
+	db *database
+}
+
+func (p scopedPool) send(ctx context.Context, sql string, args []any) (pgx.BatchResults, error) {
+	s := scopeFromContext(ctx)
+
+	batch := &pgx.Batch{}
+	batch.Queue(setScopeQuery, tenantSetting, s.tenant, actorSetting, s.actor)
+	batch.Queue(sql, args...)
+
+	results := p.db.db.SendBatch(ctx, batch)
+	// A statement that fails to prepare fails the whole batch, so the error
+	// here may belong to either statement.
+	if _, err := results.Exec(); err != nil {
+		_ = results.Close()
+		return nil, err
+	}
+	return results, nil
+}
+
+func (p scopedPool) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	results, err := p.send(ctx, sql, arguments)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	tag, err := results.Exec()
+	if closeErr := results.Close(); err == nil {
+		err = closeErr
+	}
+	return tag, err
+}
+
+func (p scopedPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	results, err := p.send(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := results.Query()
+	if err != nil {
+		_ = results.Close()
+		return nil, err
+	}
+
+	return &scopedRows{Rows: rows, results: results}, nil
+}
+
+func (p scopedPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	results, err := p.send(ctx, sql, args)
+	if err != nil {
+		return errRow{err: err}
+	}
+
+	return &scopedRow{row: results.QueryRow(), results: results}
+}
+
+// scopedRows releases the batch, and with it the connection, once the rows
+// are exhausted or closed. An error ending the implicit transaction is
+// reported by Err.
+type scopedRows struct {
+	pgx.Rows
+	results  pgx.BatchResults
+	closed   bool
+	closeErr error
+}
+
+func (r *scopedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *scopedRows) Close() {
+	r.Rows.Close()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	r.closeErr = r.results.Close()
+}
+
+func (r *scopedRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+	return r.closeErr
+}
+
+// scopedRow releases the batch once the row has been scanned.
+type scopedRow struct {
+	row     pgx.Row
+	results pgx.BatchResults
+}
+
+func (r *scopedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if closeErr := r.results.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
+
+func rollback(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		slog.Error("failed to rollback transaction", "error", err)
+	}
+}
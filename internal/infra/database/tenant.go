@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrTenantRequired is returned by tenant-owned repositories when the
+// context does not name the organization the query belongs to.
+var ErrTenantRequired = errors.New("tenant is required")
+
+type (
+	tenantKey struct{}
+	actorKey  struct{}
+)
+
+// WithTenant scopes every repository call made with the returned context to
+// the given organization.
+func WithTenant(ctx context.Context, orgID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// TenantFromContext returns the organization the context is scoped to, if any.
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	orgID, ok := ctx.Value(tenantKey{}).(uuid.UUID)
+	return orgID, ok
+}
+
+// RequireTenant is TenantFromContext for queries that must never run
+// unscoped.
+func RequireTenant(ctx context.Context) (uuid.UUID, error) {
+	orgID, ok := TenantFromContext(ctx)
+	if !ok {
+		return uuid.Nil, ErrTenantRequired
+	}
+	return orgID, nil
+}
+
+// WithActor names the user a query runs for. Row-level security lets an
+// actor see their own memberships and the invitations addressed to them
+// across tenants, which is how a user lists their organizations or accepts
+// an invitation before any tenant is active.
+func WithActor(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFromContext returns the user the context acts for, if any.
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	return userID, ok
+}
@@ -9,14 +9,18 @@ import (
 
 	"github.com/felipeversiane/auth-service/internal/infra/config"
 	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/infra/ratelimit"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/felipeversiane/auth-service/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
 type httpServer struct {
-	router *gin.Engine
-	srv    *http.Server
-	config config.HttpServerConfig
-	db     database.DatabaseInterface
+	router  *gin.Engine
+	srv     *http.Server
+	config  config.HttpServerConfig
+	db      database.DatabaseInterface
+	routers []RouterInterface
 }
 
 type HttpServerInterface interface {
@@ -25,17 +29,35 @@ type HttpServerInterface interface {
 	InitRoutes()
 }
 
-func New(config config.HttpServerConfig, db database.DatabaseInterface) HttpServerInterface {
+type RouterInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+}
+
+func New(
+	config config.HttpServerConfig,
+	db database.DatabaseInterface,
+	limiter ratelimit.LimiterInterface,
+	routers []RouterInterface,
+) (HttpServerInterface, error) {
 	if config.Environment == "development" {
 		gin.SetMode(gin.DebugMode)
 	} else {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	validation.Setup()
+
 	router := gin.New()
+	// Only these proxies may set X-Forwarded-For; with none configured the
+	// client IP is the peer address.
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid HTTP_SERVER_TRUSTED_PROXIES: %w", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
+	router.Use(requestInfoMiddleware())
+	router.Use(limiter.Middleware())
 
 	server := &httpServer{
 		router: router,
@@ -46,11 +68,12 @@ func New(config config.HttpServerConfig, db database.DatabaseInterface) HttpServ
 			WriteTimeout: time.Duration(config.WriteTimeout) * time.Second,
 			IdleTimeout:  time.Duration(config.IdleTimeout) * time.Second,
 		},
-		config: config,
-		db:     db,
+		config:  config,
+		db:      db,
+		routers: routers,
 	}
 
-	return server
+	return server, nil
 }
 
 func (s *httpServer) InitRoutes() {
@@ -63,6 +86,10 @@ func (s *httpServer) InitRoutes() {
 			})
 		})
 	}
+
+	for _, router := range s.routers {
+		router.RegisterRoutes(&s.router.RouterGroup)
+	}
 }
 
 func (s *httpServer) Start() error {
@@ -107,3 +134,11 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func requestInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := requestinfo.Info{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		c.Request = c.Request.WithContext(requestinfo.NewContext(c.Request.Context(), info))
+		c.Next()
+	}
+}
@@ -5,14 +5,23 @@ import (
 
 	"github.com/felipeversiane/auth-service/internal/infra/config"
 	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/infra/ratelimit"
 	"go.uber.org/fx"
 )
 
 var Module = fx.Options(
 	fx.Provide(
-		func(config config.HttpServerConfig, db database.DatabaseInterface) HttpServerInterface {
-			return New(config, db)
-		},
+		fx.Annotate(
+			func(
+				config config.HttpServerConfig,
+				db database.DatabaseInterface,
+				limiter ratelimit.LimiterInterface,
+				routers []RouterInterface,
+			) (HttpServerInterface, error) {
+				return New(config, db, limiter, routers)
+			},
+			fx.ParamTags(``, ``, ``, `group:"routers"`),
+		),
 	),
 	fx.Invoke(func(lc fx.Lifecycle, server HttpServerInterface) {
 		lc.Append(fx.Hook{
@@ -31,3 +40,11 @@ var Module = fx.Options(
 		})
 	}),
 )
+
+func AsRouter(constructor any) any {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(RouterInterface)),
+		fx.ResultTags(`group:"routers"`),
+	)
+}
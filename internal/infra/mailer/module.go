@@ -0,0 +1,14 @@
+package mailer
+
+import (
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		func(config config.MailerConfig) (MailerInterface, error) {
+			return New(config)
+		},
+	),
+)
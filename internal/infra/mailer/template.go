@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+	TemplateInvitation    = "invitation"
+	TemplateLoginLink     = "login_link"
+	TemplateLoginCode     = "login_code"
+)
+
+//go:embed templates/*
+var templatesFS embed.FS
+
+var (
+	funcs         = map[string]any{"duration": formatDuration}
+	textTemplates = texttemplate.Must(texttemplate.New("").Funcs(funcs).ParseFS(templatesFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(funcs).ParseFS(templatesFS, "templates/*.html"))
+)
+
+// LinkData feeds the emails that carry a single-use link.
+type LinkData struct {
+	FirstName string
+	Link      string
+	ExpiresIn time.Duration
+}
+
+// CodeData feeds the emails that carry a single-use code to type in.
+type CodeData struct {
+	FirstName string
+	Code      string
+	ExpiresIn time.Duration
+}
+
+// InvitationData feeds the email inviting someone to an organization.
+type InvitationData struct {
+	OrgName     string
+	InviterName string
+	Link        string
+	ExpiresIn   time.Duration
+}
+
+// Compose renders the text and HTML bodies of the named email. The subject
+// is defined as "<name>.subject" inside the text template.
+func Compose(to, name string, data any) (Message, error) {
+	var subject, text, html bytes.Buffer
+
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s text body: %w", name, err)
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s html body: %w", name, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// LinkWithToken appends the raw token to a configured page URL as the
+// "token" query parameter.
+func LinkWithToken(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid link base url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// formatDuration renders a TTL the way a person would say it, e.g. "1 hour"
+// or "30 minutes".
+func formatDuration(d time.Duration) string {
+	unit, amount := "minute", int(d.Round(time.Minute)/time.Minute)
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		unit, amount = "day", int(d/(24*time.Hour))
+	case d >= time.Hour && d%time.Hour == 0:
+		unit, amount = "hour", int(d/time.Hour)
+	}
+
+	if amount == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(amount) + " " + unit + "s"
+}
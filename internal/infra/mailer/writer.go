@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"sync"
+)
+
+// writerMailer writes each encoded message to a file, or to stdout when no
+// path is set, instead of delivering it.
+type writerMailer struct {
+	mu   sync.Mutex
+	path string
+	from *mail.Address
+}
+
+func newWriterMailer(path string, from *mail.Address) MailerInterface {
+	return &writerMailer{
+		path: path,
+		from: from,
+	}
+}
+
+func (m *writerMailer) Send(_ context.Context, message Message) error {
+	raw, err := encode(m.from, message)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var w io.Writer = os.Stdout
+	if m.path != "" {
+		file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open mail file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\r\n\r\n", raw); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Result is the outcome of taking one request from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed; zero
+	// when this one was.
+	RetryAfter time.Duration
+}
+
+// bucket is the state every store keeps per key: the tokens left and when
+// they were counted.
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take refills the bucket for the time elapsed since it was last counted
+// and spends one token if there is one. It returns the new state, which
+// stores write back whether or not the request was allowed.
+func take(policy Policy, current *bucket, now time.Time) (bucket, Result) {
+	burst := float64(policy.Burst)
+	rate := policy.Rate()
+
+	tokens := burst
+	if current != nil {
+		elapsed := max(now.Sub(current.updatedAt).Seconds(), 0)
+		tokens = min(burst, current.tokens+elapsed*rate)
+	}
+
+	var result Result
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((burst - tokens) / rate)
+
+	return bucket{tokens: tokens, updatedAt: now}, result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(math.Ceil(value * float64(time.Second)))
+}
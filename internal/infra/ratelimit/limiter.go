@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin"
+)
+
+const purgeInterval = time.Minute
+
+// ClientResolverInterface names the OAuth client a request authenticates
+// as, or returns an empty string for anonymous and first-party requests.
+// It lives outside this package so the limiter does not depend on token
+// verification.
+type ClientResolverInterface interface {
+	ClientID(ctx *gin.Context) string
+}
+
+type limiter struct {
+	config   config.RateLimitConfig
+	policies []Policy
+	store    StoreInterface
+	clients  ClientResolverInterface
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// LimiterInterface applies the configured policies to every request. Each
+// matching policy has its own bucket and the request is rejected when any
+// of them is empty. The RateLimit headers describe the policy closest to
+// running out.
+type LimiterInterface interface {
+	Middleware() gin.HandlerFunc
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewLimiter(config config.RateLimitConfig, store StoreInterface, clients ClientResolverInterface) (LimiterInterface, error) {
+	policies, err := ParsePolicies(config.Policies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limiter{
+		config:   config,
+		policies: policies,
+		store:    store,
+		clients:  clients,
+	}, nil
+}
+
+func (l *limiter) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !l.config.Enabled {
+			ctx.Next()
+			return
+		}
+
+		var (
+			policies []string
+			tightest *Result
+			limited  *Result
+			clientID string
+			resolved bool
+		)
+		now := time.Now().UTC()
+		method, route := ctx.Request.Method, ctx.FullPath()
+
+		for _, policy := range l.policies {
+			if !policy.Matches(method, route) {
+				continue
+			}
+
+			var subject string
+			switch policy.Key {
+			case KeyIP:
+				subject = ctx.ClientIP()
+			case KeyClient:
+				if !resolved {
+					clientID, resolved = l.clients.ClientID(ctx), true
+				}
+				if clientID == "" {
+					continue
+				}
+				subject = clientID
+			case KeyRoute:
+				subject = method + " " + route
+			}
+
+			result, err := l.store.Take(ctx.Request.Context(), policy.String()+":"+subject, policy, now)
+			if err != nil {
+				slog.Error("failed to apply rate limit", "error", err, slog.String("policy", policy.String()))
+				if l.config.FailOpen {
+					continue
+				}
+				restErr := httperr.NewInternalServerError("failed to apply rate limit")
+				ctx.AbortWithStatusJSON(restErr.Code, restErr)
+				return
+			}
+
+			policies = append(policies, policy.Header())
+			if !result.Allowed && (limited == nil || result.RetryAfter > limited.RetryAfter) {
+				limited = &result
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+				ctx.Header("RateLimit-Limit", strconv.Itoa(policy.Burst))
+				ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+				ctx.Header("RateLimit-Reset", strconv.Itoa(wholeSeconds(result.Reset)))
+			}
+		}
+
+		if len(policies) > 0 {
+			ctx.Header("RateLimit-Policy", strings.Join(policies, ", "))
+		}
+
+		if limited != nil {
+			restErr := httperr.NewTooManyRequestsError("rate limit exceeded, try again later", limited.RetryAfter)
+			restErr.WriteHeaders(ctx.Writer.Header())
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func (l *limiter) Start(ctx context.Context) error {
+	if !l.config.Enabled {
+		return nil
+	}
+
+	slog.Info("starting rate limiter", slog.String("store", l.config.Store), slog.Int("policies", len(l.policies)))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+
+	l.done.Add(1)
+	go l.purge(runCtx)
+
+	return nil
+}
+
+func (l *limiter) Stop(ctx context.Context) error {
+	if l.cancel != nil {
+		l.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		l.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		slog.Info("rate limiter stopped")
+		return l.store.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) purge(ctx context.Context) {
+	defer l.done.Done()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := l.store.Purge(ctx, time.Now().UTC()); err != nil {
+				slog.Warn("failed to purge rate limit buckets", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func wholeSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
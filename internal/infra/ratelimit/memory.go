@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	bucket    bucket
+	expiresAt time.Time
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryEntry
+}
+
+func newMemoryStore() StoreInterface {
+	return &memoryStore{
+		buckets: make(map[string]memoryEntry),
+	}
+}
+
+func (s *memoryStore) Take(_ context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current *bucket
+	if entry, ok := s.buckets[key]; ok {
+		current = &entry.bucket
+	}
+
+	next, result := take(policy, current, now)
+	s.buckets[key] = memoryEntry{bucket: next, expiresAt: now.Add(result.Reset)}
+
+	return result, nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.buckets {
+		if !now.Before(entry.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package ratelimit
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewStore,
+		NewLimiter,
+	),
+	fx.Invoke(func(lc fx.Lifecycle, limiter LimiterInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return limiter.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return limiter.Stop(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys a policy can count requests by.
+const (
+	KeyIP     = "ip"
+	KeyClient = "client"
+	KeyRoute  = "route"
+)
+
+// Policy limits the requests matching a method and route. Requests are
+// counted per client address, per OAuth client_id, or all together per
+// route, with a token bucket that holds Burst requests and refills at
+// Requests per Period.
+type Policy struct {
+	Method   string
+	Route    string
+	Key      string
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParsePolicies reads RATE_LIMIT_POLICIES entries of the form
+//
+//	<method> <route> <key> <requests>/<seconds> [burst]
+//
+// where method and route may be "*", a route ending in "/*" matches by
+// prefix, routes use gin patterns such as /api/v1/users/:id, and key is ip,
+// client or route.
+func ParsePolicies(entries []string) ([]Policy, error) {
+	policies := make([]Policy, 0, len(entries))
+	for _, entry := range entries {
+		policy, err := parsePolicy(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit policy %q: %w", entry, err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func parsePolicy(entry string) (Policy, error) {
+	fields := strings.Fields(entry)
+	if len(fields) != 4 && len(fields) != 5 {
+		return Policy{}, fmt.Errorf("expected <method> <route> <key> <requests>/<seconds> [burst]")
+	}
+
+	policy := Policy{
+		Method: strings.ToUpper(fields[0]),
+		Route:  fields[1],
+		Key:    strings.ToLower(fields[2]),
+	}
+
+	switch policy.Key {
+	case KeyIP, KeyClient, KeyRoute:
+	default:
+		return Policy{}, fmt.Errorf("unsupported key %q", policy.Key)
+	}
+
+	requests, seconds, found := strings.Cut(fields[3], "/")
+	if !found {
+		return Policy{}, fmt.Errorf("rate must be <requests>/<seconds>")
+	}
+
+	var err error
+	if policy.Requests, err = strconv.Atoi(requests); err != nil || policy.Requests <= 0 {
+		return Policy{}, fmt.Errorf("requests must be a positive integer")
+	}
+
+	period, err := strconv.Atoi(seconds)
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("seconds must be a positive integer")
+	}
+	policy.Period = time.Duration(period) * time.Second
+
+	policy.Burst = policy.Requests
+	if len(fields) == 5 {
+		if policy.Burst, err = strconv.Atoi(fields[4]); err != nil || policy.Burst <= 0 {
+			return Policy{}, fmt.Errorf("burst must be a positive integer")
+		}
+	}
+
+	return policy, nil
+}
+
+// Matches reports whether the policy applies to a request for the given
+// method and gin route pattern.
+func (p Policy) Matches(method, route string) bool {
+	if p.Method != "*" && p.Method != method {
+		return false
+	}
+
+	switch {
+	case p.Route == "*":
+		return true
+	case strings.HasSuffix(p.Route, "/*"):
+		return strings.HasPrefix(route, strings.TrimSuffix(p.Route, "*"))
+	default:
+		return p.Route == route
+	}
+}
+
+// Rate is the number of requests the bucket refills per second.
+func (p Policy) Rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// Header renders the policy for the RateLimit-Policy header.
+func (p Policy) Header() string {
+	return fmt.Sprintf("%d;w=%d;burst=%d", p.Requests, int(p.Period.Seconds()), p.Burst)
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%s %s %s", p.Method, p.Route, p.Key)
+}
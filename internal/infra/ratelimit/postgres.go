@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+type postgresStore struct {
+	db database.DatabaseInterface
+}
+
+func newPostgresStore(db database.DatabaseInterface) StoreInterface {
+	return &postgresStore{
+		db: db,
+	}
+}
+
+// Take locks the bucket's row for the rest of the transaction. The insert
+// creates a full bucket the first time a key is seen; on conflict the no-op
+// update still locks the row and returns what it holds.
+func (s *postgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO rate_limit_buckets (key, tokens, updated_at, expires_at)
+			VALUES ($1, $2, $3, $3)
+			ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+			RETURNING tokens, updated_at`
+
+		var current bucket
+		err := s.db.GetQuerier(ctx).QueryRow(ctx, query, key, float64(policy.Burst), now).
+			Scan(&current.tokens, &current.updatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to lock rate limit bucket: %w", err)
+		}
+
+		var next bucket
+		next, result = take(policy, &current, now)
+
+		query = `UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, expires_at = $4 WHERE key = $1`
+		if _, err := s.db.GetQuerier(ctx).Exec(ctx, query, key, next.tokens, next.updatedAt, now.Add(result.Reset)); err != nil {
+			return fmt.Errorf("failed to update rate limit bucket: %w", err)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+func (s *postgresStore) Purge(ctx context.Context, now time.Time) error {
+	if _, err := s.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM rate_limit_buckets WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to purge rate limit buckets: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Close() error {
+	return nil
+}
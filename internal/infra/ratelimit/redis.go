@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix    = "ratelimit:"
+	redisMaxIdleConns = 16
+)
+
+// takeScript applies the same refill as take atomically on the server and
+// returns the state it found, from which the caller derives the result. The
+// key expires once the bucket would be full again.
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+local left = burst
+if tokens and ts then
+	left = math.min(burst, tokens + math.max(now - ts, 0) / 1000 * rate)
+end
+if left >= 1 then
+	left = left - 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(left), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - left) / rate * 1000) + 1000)
+if tokens and ts then
+	return {tostring(tokens), tostring(ts)}
+end
+return {}
+`
+
+// takeRedisScript runs takeScript by its digest and loads it on a NOSCRIPT
+// reply.
+var takeRedisScript = redis.NewScript(takeScript)
+
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(client *redis.Client) StoreInterface {
+	return &redisStore{
+		client: client,
+	}
+}
+
+// newRedisClient connects to Redis or any server compatible with its
+// protocol, such as Valkey or KeyDB. RESP2 is spoken, since not all of
+// them know RESP3.
+func newRedisClient(config config.RateLimitConfig) *redis.Client {
+	timeout := time.Duration(config.RedisTimeout) * time.Second
+	options := &redis.Options{
+		Addr:         config.RedisAddr,
+		Username:     config.RedisUsername,
+		Password:     config.RedisPassword,
+		DB:           config.RedisDB,
+		Protocol:     2,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		MaxIdleConns: redisMaxIdleConns,
+	}
+	if config.RedisTLS {
+		host, _, err := net.SplitHostPort(config.RedisAddr)
+		if err != nil {
+			host = config.RedisAddr
+		}
+		options.TLSConfig = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	}
+	return redis.NewClient(options)
+}
+
+func (s *redisStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	// The script works in milliseconds; truncating here keeps both sides of
+	// the computation identical.
+	now = now.Truncate(time.Millisecond)
+	reply, err := takeRedisScript.Run(ctx, s.client,
+		[]string{redisKeyPrefix + key},
+		strconv.FormatFloat(policy.Rate(), 'g', -1, 64),
+		strconv.Itoa(policy.Burst),
+		strconv.FormatInt(now.UnixMilli(), 10),
+	).Result()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take from rate limit bucket: %w", err)
+	}
+
+	current, err := parseRedisBucket(reply)
+	if err != nil {
+		return Result{}, err
+	}
+
+	_, result := take(policy, current, now)
+	return result, nil
+}
+
+// Purge is a no-op: Redis expires buckets on its own.
+func (s *redisStore) Purge(context.Context, time.Time) error {
+	return nil
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
+
+func parseRedisBucket(reply any) (*bucket, error) {
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rate limit script reply %T", reply)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if len(items) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit script reply of %d items", len(items))
+	}
+
+	values := make([]float64, 0, 2)
+	for _, item := range items {
+		raw, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected rate limit script item %T", item)
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed rate limit bucket: %w", err)
+		}
+		values = append(values, value)
+	}
+
+	return &bucket{tokens: values[0], updatedAt: time.UnixMilli(int64(values[1])).UTC()}, nil
+}
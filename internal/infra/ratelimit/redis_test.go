@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+)
+
+// TestRedisStoreMatchesMemoryStore runs the token bucket script against an
+// in-process Redis and checks that every result matches the memory store's,
+// whose arithmetic is the reference.
+func TestRedisStoreMatchesMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	store := newRedisStore(newRedisClient(config.RateLimitConfig{RedisAddr: server.Addr(), RedisTimeout: 2}))
+	t.Cleanup(func() { _ = store.Close() })
+	reference := newMemoryStore()
+
+	policy := Policy{Requests: 2, Period: time.Second, Burst: 3}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	offsets := []time.Duration{0, 0, 0, 0, 100 * time.Millisecond, 600 * time.Millisecond, 601 * time.Millisecond, 5 * time.Second, 4 * time.Second}
+
+	for i, offset := range offsets {
+		now := start.Add(offset)
+		if i == 5 {
+			// A restarted server has forgotten the script; the store must
+			// load it again.
+			if err := store.(*redisStore).client.ScriptFlush(ctx).Err(); err != nil {
+				t.Fatalf("SCRIPT FLUSH: %v", err)
+			}
+		}
+
+		got, err := store.Take(ctx, "ip:203.0.113.7", policy, now)
+		if err != nil {
+			t.Fatalf("take %d: %v", i, err)
+		}
+		want, err := reference.Take(ctx, "ip:203.0.113.7", policy, now)
+		if err != nil {
+			t.Fatalf("take %d from memory: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("take %d at +%v = %+v, want %+v", i, offset, got, want)
+		}
+	}
+
+	if ttl := server.TTL(redisKeyPrefix + "ip:203.0.113.7"); ttl <= 0 {
+		t.Errorf("bucket TTL = %v, want the bucket to expire", ttl)
+	}
+}
+
+func TestRedisStoreReportsServerErrors(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	store := newRedisStore(newRedisClient(config.RateLimitConfig{RedisAddr: server.Addr(), RedisTimeout: 2}))
+	t.Cleanup(func() { _ = store.Close() })
+
+	if _, err := store.Take(context.Background(), "ip:203.0.113.7", Policy{Requests: 1, Period: time.Second, Burst: 1}, time.Now()); err == nil {
+		t.Fatal("Take() without credentials: no error")
+	}
+}
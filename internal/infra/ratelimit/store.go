@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreRedis    = "redis"
+)
+
+// StoreInterface keeps one token bucket per key. Take must be atomic per
+// key, since replicas sharing a store race for the same bucket.
+type StoreInterface interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+	// Purge drops buckets that have refilled completely, which are
+	// indistinguishable from missing ones.
+	Purge(ctx context.Context, now time.Time) error
+	Close() error
+}
+
+// NewStore picks the backend from RATE_LIMIT_STORE. Limits kept in memory
+// are per instance; postgres and redis share them across replicas.
+func NewStore(config config.RateLimitConfig, db database.DatabaseInterface) (StoreInterface, error) {
+	switch config.Store {
+	case StoreMemory:
+		return newMemoryStore(), nil
+	case StorePostgres:
+		return newPostgresStore(db), nil
+	case StoreRedis:
+		return newRedisStore(newRedisClient(config)), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store %q", config.Store)
+	}
+}
@@ -0,0 +1,51 @@
+package keys
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	keyring KeyringInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	GetJWKS(ctx *gin.Context)
+}
+
+func NewHandler(keyring KeyringInterface) HandlerInterface {
+	return &handler{
+		keyring: keyring,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/jwks.json", h.GetJWKS)
+}
+
+func (h *handler) GetJWKS(ctx *gin.Context) {
+	keys, err := h.keyring.PublishedKeys(ctx.Request.Context())
+	if err != nil {
+		slog.Error("failed to load published signing keys", "error", err)
+		restErr := httperr.NewInternalServerError("failed to load signing keys")
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	jwks := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := NewJWK(key.GetID(), key.GetAlgorithm(), key.GetPublicKey())
+		if err != nil {
+			slog.Error("failed to encode signing key", "kid", key.GetID(), "error", err)
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwks)
+}
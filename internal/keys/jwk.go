@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type JWK struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWK(keyID, algorithm string, publicKey crypto.PublicKey) (JWK, error) {
+	jwk := JWK{
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		Use:       "sig",
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(key.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		ecdhKey, err := key.ECDH()
+		if err != nil {
+			return JWK{}, fmt.Errorf("failed to convert EC public key: %w", err)
+		}
+		// Uncompressed point encoding: 0x04 || X || Y.
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = encode(point[1 : 1+size])
+		jwk.Y = encode(point[1+size:])
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(key)
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey decodes the key a JWK carries and returns it with the JWS
+// algorithm it is used with, accepting the same keys as ParsePublicKey.
+func (j JWK) PublicKey() (crypto.PublicKey, string, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, "", err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, "", err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n)*8 < 2048 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, "", errors.New("unsupported RSA key size or exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, AlgorithmRS256, nil
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, "", fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, "", err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, "", errors.New("invalid P-256 coordinates")
+		}
+		// Decoding the point through ecdh checks it is on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, "", fmt.Errorf("invalid P-256 point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, AlgorithmES256, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, "", errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), AlgorithmEdDSA, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported key type %q", j.KeyType)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(value string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url key parameter")
+	}
+	return data, nil
+}
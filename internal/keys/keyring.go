@@ -0,0 +1,318 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const minReloadInterval = 5 * time.Second
+
+var (
+	ErrNoActiveKey = errors.New("no active signing key")
+	ErrKeyNotFound = errors.New("signing key not found")
+)
+
+type keyring struct {
+	config     config.KeysConfig
+	retention  time.Duration
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+
+	mu       sync.RWMutex
+	keys     []domain.SigningKeyInterface
+	loadedAt time.Time
+
+	rotations metric.Int64Counter
+	failures  metric.Int64Counter
+}
+
+type KeyringInterface interface {
+	ActiveKey(ctx context.Context) (domain.SigningKeyInterface, error)
+	FindKey(ctx context.Context, keyID string) (domain.SigningKeyInterface, error)
+	PublishedKeys(ctx context.Context) ([]domain.SigningKeyInterface, error)
+	Rotate(ctx context.Context) error
+}
+
+func NewKeyring(
+	config config.KeysConfig,
+	tokenConfig config.TokenConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+) (KeyringInterface, error) {
+	if _, err := GenerateKey(config.Algorithm); err != nil {
+		return nil, err
+	}
+
+	k := &keyring{
+		config:     config,
+		retention:  time.Duration(max(tokenConfig.AccessTokenTTL, tokenConfig.IDTokenTTL)) * time.Second,
+		db:         db,
+		repository: repository,
+	}
+
+	if err := k.registerMetrics(); err != nil {
+		return nil, fmt.Errorf("failed to register key rotation metrics: %w", err)
+	}
+
+	return k, nil
+}
+
+func (k *keyring) ActiveKey(ctx context.Context) (domain.SigningKeyInterface, error) {
+	keys, err := k.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.GetState() == domain.SigningKeyStateActive {
+			return key, nil
+		}
+	}
+
+	return nil, ErrNoActiveKey
+}
+
+func (k *keyring) FindKey(ctx context.Context, keyID string) (domain.SigningKeyInterface, error) {
+	keys, err := k.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if key := findPublished(keys, keyID); key != nil {
+		return key, nil
+	}
+
+	// Another replica may have rotated since the cache was filled, but do not
+	// let unknown key IDs turn every request into a database round trip.
+	keys, err = k.load(ctx, k.stale(minReloadInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	if key := findPublished(keys, keyID); key != nil {
+		return key, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+func (k *keyring) PublishedKeys(ctx context.Context) ([]domain.SigningKeyInterface, error) {
+	keys, err := k.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	published := make([]domain.SigningKeyInterface, 0, len(keys))
+	for _, key := range keys {
+		if key.IsPublished(now) {
+			published = append(published, key)
+		}
+	}
+
+	return published, nil
+}
+
+// Rotate makes sure there is an active key and a pre-published next key,
+// promotes next to active once the active key is older than the rotation
+// interval, and drops retired keys whose tokens have all expired.
+func (k *keyring) Rotate(ctx context.Context) error {
+	rotated := false
+
+	err := k.db.WithTx(ctx, func(ctx context.Context) error {
+		locked, err := k.repository.LockForRotation(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		now := time.Now().UTC()
+
+		deleted, err := k.repository.DeleteExpired(ctx, now)
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			slog.Info("removed expired signing keys", slog.Int64("count", deleted))
+		}
+
+		keys, err := k.repository.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		var active, next domain.SigningKeyInterface
+		for _, key := range keys {
+			switch key.GetState() {
+			case domain.SigningKeyStateActive:
+				active = key
+			case domain.SigningKeyStateNext:
+				next = key
+			}
+		}
+
+		due := active == nil || now.Sub(*active.GetActivatedAt()) >= k.rotationInterval()
+		if !due && next != nil {
+			return nil
+		}
+
+		if next == nil {
+			if next, err = k.createKey(ctx); err != nil {
+				return err
+			}
+		}
+
+		if due {
+			if active != nil {
+				active.Retire(now, k.retention)
+				if err := k.repository.UpdateState(ctx, active); err != nil {
+					return err
+				}
+			}
+
+			next.Activate(now)
+			if err := k.repository.UpdateState(ctx, next); err != nil {
+				return err
+			}
+			slog.Info("activated signing key", slog.String("kid", next.GetID()))
+			rotated = true
+
+			if _, err := k.createKey(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		k.failures.Add(ctx, 1)
+		return fmt.Errorf("failed to rotate signing keys: %w", err)
+	}
+
+	if rotated {
+		k.rotations.Add(ctx, 1, metric.WithAttributes(attribute.String("algorithm", k.config.Algorithm)))
+	}
+
+	_, err = k.load(ctx, true)
+	return err
+}
+
+func (k *keyring) createKey(ctx context.Context) (domain.SigningKeyInterface, error) {
+	privateKey, err := GenerateKey(k.config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := Thumbprint(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	key := domain.NewSigningKey(keyID, k.config.Algorithm, privateKey)
+	if err := k.repository.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	slog.Info("generated next signing key", slog.String("kid", keyID))
+	return key, nil
+}
+
+func (k *keyring) load(ctx context.Context, force bool) ([]domain.SigningKeyInterface, error) {
+	k.mu.RLock()
+	keys, loadedAt := k.keys, k.loadedAt
+	k.mu.RUnlock()
+
+	if !force && keys != nil && time.Since(loadedAt) < k.cacheTTL() {
+		return keys, nil
+	}
+
+	keys, err := k.repository.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+
+	return keys, nil
+}
+
+func (k *keyring) stale(age time.Duration) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return time.Since(k.loadedAt) >= age
+}
+
+func (k *keyring) registerMetrics() error {
+	meter := otel.Meter("auth-service/keys")
+
+	var err error
+	k.rotations, err = meter.Int64Counter(
+		"signing_keys.rotations",
+		metric.WithDescription("Number of signing key promotions"),
+	)
+	if err != nil {
+		return err
+	}
+
+	k.failures, err = meter.Int64Counter(
+		"signing_keys.rotation_failures",
+		metric.WithDescription("Number of failed signing key rotation runs"),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.Int64ObservableGauge(
+		"signing_keys.published",
+		metric.WithDescription("Number of cached signing keys by state"),
+		metric.WithInt64Callback(func(_ context.Context, observer metric.Int64Observer) error {
+			k.mu.RLock()
+			defer k.mu.RUnlock()
+
+			counts := map[string]int64{
+				domain.SigningKeyStateNext:    0,
+				domain.SigningKeyStateActive:  0,
+				domain.SigningKeyStateRetired: 0,
+			}
+			for _, key := range k.keys {
+				counts[key.GetState()]++
+			}
+			for state, count := range counts {
+				observer.Observe(count, metric.WithAttributes(attribute.String("state", state)))
+			}
+			return nil
+		}),
+	)
+	return err
+}
+
+func (k *keyring) rotationInterval() time.Duration {
+	return time.Duration(k.config.RotationInterval) * time.Second
+}
+
+func (k *keyring) cacheTTL() time.Duration {
+	return time.Duration(k.config.CacheTTL) * time.Second
+}
+
+func findPublished(keys []domain.SigningKeyInterface, keyID string) domain.SigningKeyInterface {
+	now := time.Now().UTC()
+	for _, key := range keys {
+		if key.GetID() == keyID && key.IsPublished(now) {
+			return key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const (
+	testRotationInterval = 86400
+	testRetention        = 900
+)
+
+// fakeDB runs each transaction directly in the calling context. The shared
+// one in testutil cannot be used here because testutil depends on keys.
+type fakeDB struct {
+	database.DatabaseInterface
+}
+
+func (fakeDB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeRepository struct {
+	keys     []domain.SigningKeyInterface
+	unlocked bool
+	loads    int
+}
+
+func (r *fakeRepository) LockForRotation(context.Context) (bool, error) {
+	return !r.unlocked, nil
+}
+
+func (r *fakeRepository) FindAll(context.Context) ([]domain.SigningKeyInterface, error) {
+	r.loads++
+	return append([]domain.SigningKeyInterface(nil), r.keys...), nil
+}
+
+func (r *fakeRepository) Create(_ context.Context, key domain.SigningKeyInterface) error {
+	r.keys = append(r.keys, key)
+	return nil
+}
+
+func (r *fakeRepository) UpdateState(context.Context, domain.SigningKeyInterface) error {
+	return nil
+}
+
+func (r *fakeRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	kept := r.keys[:0]
+	for _, key := range r.keys {
+		if expiresAt := key.GetExpiresAt(); expiresAt == nil || expiresAt.After(now) {
+			kept = append(kept, key)
+		}
+	}
+	deleted := int64(len(r.keys) - len(kept))
+	r.keys = kept
+	return deleted, nil
+}
+
+func (r *fakeRepository) inState(state string) []domain.SigningKeyInterface {
+	var found []domain.SigningKeyInterface
+	for _, key := range r.keys {
+		if key.GetState() == state {
+			found = append(found, key)
+		}
+	}
+	return found
+}
+
+func newTestKeyring(t *testing.T, repository *fakeRepository) KeyringInterface {
+	t.Helper()
+
+	keyring, err := NewKeyring(
+		config.KeysConfig{Algorithm: AlgorithmES256, RotationInterval: testRotationInterval, CacheTTL: 60},
+		config.TokenConfig{AccessTokenTTL: testRetention, IDTokenTTL: testRetention / 3},
+		fakeDB{},
+		repository,
+	)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+// storedKey returns a key in state that became active activeFor ago.
+func storedKey(t *testing.T, state string, activeFor time.Duration, expiresAt *time.Time) domain.SigningKeyInterface {
+	t.Helper()
+
+	signer, err := GenerateKey(AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	keyID, err := Thumbprint(signer.Public())
+	if err != nil {
+		t.Fatalf("Thumbprint() error = %v", err)
+	}
+
+	now := time.Now().UTC()
+	var activatedAt, retiredAt *time.Time
+	if state != domain.SigningKeyStateNext {
+		at := now.Add(-activeFor)
+		activatedAt = &at
+	}
+	if state == domain.SigningKeyStateRetired {
+		retiredAt = &now
+	}
+	return domain.RestoreSigningKey(keyID, AlgorithmES256, state, signer, now.Add(-activeFor), activatedAt, retiredAt, expiresAt)
+}
+
+func TestRotate(t *testing.T) {
+	interval := time.Duration(testRotationInterval) * time.Second
+
+	tests := []struct {
+		name string
+		// activeFor is how long the stored active key has been active; zero
+		// means there is none.
+		activeFor time.Duration
+		withNext  bool
+		promoted  bool
+	}{
+		{name: "first run", promoted: true},
+		{name: "active key due", activeFor: interval + time.Minute, withNext: true, promoted: true},
+		{name: "active key due without a next key", activeFor: interval + time.Minute, promoted: true},
+		{name: "active key fresh", activeFor: time.Hour, withNext: true},
+		{name: "active key fresh without a next key", activeFor: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeRepository{}
+			var active, next domain.SigningKeyInterface
+			if tt.activeFor > 0 {
+				active = storedKey(t, domain.SigningKeyStateActive, tt.activeFor, nil)
+				repository.keys = append(repository.keys, active)
+			}
+			if tt.withNext {
+				next = storedKey(t, domain.SigningKeyStateNext, 0, nil)
+				repository.keys = append(repository.keys, next)
+			}
+
+			keyring := newTestKeyring(t, repository)
+			if err := keyring.Rotate(context.Background()); err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+
+			actives := repository.inState(domain.SigningKeyStateActive)
+			nexts := repository.inState(domain.SigningKeyStateNext)
+			if len(actives) != 1 || len(nexts) != 1 {
+				t.Fatalf("after Rotate() %d active and %d next keys, want 1 and 1", len(actives), len(nexts))
+			}
+
+			switch {
+			case !tt.promoted && actives[0] != active:
+				t.Fatal("Rotate() replaced an active key that was not due")
+			case tt.promoted && actives[0] == active:
+				t.Fatal("Rotate() kept an active key that was due")
+			case tt.promoted && next != nil && actives[0] != next:
+				t.Fatal("Rotate() did not promote the pre-published next key")
+			case !tt.promoted && next != nil && nexts[0] != next:
+				t.Fatal("Rotate() replaced a next key that was still waiting")
+			}
+
+			if tt.promoted && active != nil {
+				if active.GetState() != domain.SigningKeyStateRetired {
+					t.Fatalf("the old active key is %s, want retired", active.GetState())
+				}
+				// Tokens it signed stay verifiable for their whole lifetime.
+				retention := time.Duration(testRetention) * time.Second
+				if expiresAt := active.GetExpiresAt(); expiresAt == nil || expiresAt.Sub(*active.GetRetiredAt()) != retention {
+					t.Fatalf("the retired key expires at %v, want %s after retirement", expiresAt, retention)
+				}
+			}
+
+			// The keyring reloads after rotating, so it signs with the new key.
+			signing, err := keyring.ActiveKey(context.Background())
+			if err != nil {
+				t.Fatalf("ActiveKey() error = %v", err)
+			}
+			if signing != actives[0] {
+				t.Error("ActiveKey() is not the key Rotate() activated")
+			}
+		})
+	}
+}
+
+func TestRotateDropsExpiredKeys(t *testing.T) {
+	past := time.Now().UTC().Add(-time.Minute)
+	future := time.Now().UTC().Add(time.Minute)
+
+	expired := storedKey(t, domain.SigningKeyStateRetired, 48*time.Hour, &past)
+	retained := storedKey(t, domain.SigningKeyStateRetired, 48*time.Hour, &future)
+	repository := &fakeRepository{keys: []domain.SigningKeyInterface{
+		expired,
+		retained,
+		storedKey(t, domain.SigningKeyStateActive, time.Hour, nil),
+		storedKey(t, domain.SigningKeyStateNext, 0, nil),
+	}}
+
+	keyring := newTestKeyring(t, repository)
+	if err := keyring.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	if _, err := keyring.FindKey(context.Background(), expired.GetID()); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("FindKey(expired) error = %v, want ErrKeyNotFound", err)
+	}
+	if key, err := keyring.FindKey(context.Background(), retained.GetID()); err != nil || key != retained {
+		t.Errorf("FindKey(retained) = %v, %v", key, err)
+	}
+	if len(repository.keys) != 3 {
+		t.Errorf("%d keys left, want 3", len(repository.keys))
+	}
+}
+
+func TestRotateLeavesKeysToTheLockHolder(t *testing.T) {
+	repository := &fakeRepository{unlocked: true}
+
+	keyring := newTestKeyring(t, repository)
+	if err := keyring.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if len(repository.keys) != 0 {
+		t.Fatalf("Rotate() created %d keys without the lock", len(repository.keys))
+	}
+	if _, err := keyring.ActiveKey(context.Background()); !errors.Is(err, ErrNoActiveKey) {
+		t.Fatalf("ActiveKey() error = %v, want ErrNoActiveKey", err)
+	}
+}
+
+func TestActiveKey(t *testing.T) {
+	future := time.Now().UTC().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		states []string
+		want   int
+	}{
+		{"active among the others", []string{domain.SigningKeyStateRetired, domain.SigningKeyStateNext, domain.SigningKeyStateActive}, 2},
+		{"active first", []string{domain.SigningKeyStateActive, domain.SigningKeyStateNext}, 0},
+		{"no active key", []string{domain.SigningKeyStateRetired, domain.SigningKeyStateNext}, -1},
+		{"no keys", nil, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeRepository{}
+			for _, state := range tt.states {
+				var expiresAt *time.Time
+				if state == domain.SigningKeyStateRetired {
+					expiresAt = &future
+				}
+				repository.keys = append(repository.keys, storedKey(t, state, time.Hour, expiresAt))
+			}
+
+			key, err := newTestKeyring(t, repository).ActiveKey(context.Background())
+			if tt.want < 0 {
+				if !errors.Is(err, ErrNoActiveKey) {
+					t.Fatalf("ActiveKey() error = %v, want ErrNoActiveKey", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ActiveKey() error = %v", err)
+			}
+			if key != repository.keys[tt.want] {
+				t.Fatalf("ActiveKey() = %s, want %s", key.GetID(), repository.keys[tt.want].GetID())
+			}
+		})
+	}
+}
+
+func TestActiveKeyIsCached(t *testing.T) {
+	active := storedKey(t, domain.SigningKeyStateActive, time.Hour, nil)
+	repository := &fakeRepository{keys: []domain.SigningKeyInterface{active}}
+	keys := newTestKeyring(t, repository)
+
+	for i := 0; i < 3; i++ {
+		if _, err := keys.ActiveKey(context.Background()); err != nil {
+			t.Fatalf("ActiveKey() error = %v", err)
+		}
+	}
+	if repository.loads != 1 {
+		t.Fatalf("loaded keys %d times, want 1", repository.loads)
+	}
+
+	// A key from a fresh rotation elsewhere is found without waiting for the
+	// cache to expire, as long as the last reload is old enough.
+	other := storedKey(t, domain.SigningKeyStateNext, 0, nil)
+	repository.keys = append(repository.keys, other)
+	keys.(*keyring).loadedAt = time.Now().Add(-minReloadInterval)
+
+	key, err := keys.FindKey(context.Background(), other.GetID())
+	if err != nil || key != other {
+		t.Fatalf("FindKey(new key) = %v, %v", key, err)
+	}
+
+	// Unknown key IDs do not reload again right away.
+	if _, err := keys.FindKey(context.Background(), "unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("FindKey(unknown) error = %v, want ErrKeyNotFound", err)
+	}
+	if repository.loads != 2 {
+		t.Fatalf("loaded keys %d times, want 2", repository.loads)
+	}
+}
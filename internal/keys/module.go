@@ -0,0 +1,40 @@
+package keys
+
+import (
+	"context"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"github.com/felipeversiane/auth-service/pkg/secretbox"
+	"go.uber.org/fx"
+)
+
+// KeyringModule provides the keyring alone, without rotation or the JWKS
+// endpoint, for tools that only need to read the signing keys.
+var KeyringModule = fx.Options(
+	fx.Provide(
+		func(config config.KeysConfig) (secretbox.BoxInterface, error) {
+			return secretbox.New(config.MasterKey)
+		},
+		NewRepository,
+		NewKeyring,
+	),
+)
+
+var Module = fx.Options(
+	KeyringModule,
+	fx.Provide(
+		NewRotator,
+		httpserver.AsRouter(NewHandler),
+	),
+	fx.Invoke(func(lc fx.Lifecycle, rotator RotatorInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return rotator.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return rotator.Stop(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,148 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/secretbox"
+)
+
+// rotationLockID serializes rotations across every replica sharing the database.
+const rotationLockID = 7_301_204
+
+type repository struct {
+	db  database.DatabaseInterface
+	box secretbox.BoxInterface
+}
+
+type RepositoryInterface interface {
+	LockForRotation(ctx context.Context) (bool, error)
+	FindAll(ctx context.Context) ([]domain.SigningKeyInterface, error)
+	Create(ctx context.Context, key domain.SigningKeyInterface) error
+	UpdateState(ctx context.Context, key domain.SigningKeyInterface) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+func NewRepository(db database.DatabaseInterface, box secretbox.BoxInterface) RepositoryInterface {
+	return &repository{
+		db:  db,
+		box: box,
+	}
+}
+
+// LockForRotation takes a transaction scoped advisory lock and reports whether
+// this instance won it. It must be called inside database.WithTx.
+func (r *repository) LockForRotation(ctx context.Context) (bool, error) {
+	var locked bool
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, rotationLockID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to acquire rotation lock: %w", err)
+	}
+	return locked, nil
+}
+
+func (r *repository) FindAll(ctx context.Context) ([]domain.SigningKeyInterface, error) {
+	query := `
+		SELECT id, algorithm, state, private_key, created_at, activated_at, retired_at, expires_at
+		FROM signing_keys
+		ORDER BY created_at`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.SigningKeyInterface
+	for rows.Next() {
+		var (
+			id, algorithm, state              string
+			sealed                            []byte
+			createdAt                         time.Time
+			activatedAt, retiredAt, expiresAt *time.Time
+		)
+		if err := rows.Scan(&id, &algorithm, &state, &sealed, &createdAt, &activatedAt, &retiredAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+
+		der, err := r.box.Open(sealed, []byte(id))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", id, err)
+		}
+
+		privateKey, err := ParsePrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, domain.RestoreSigningKey(id, algorithm, state, privateKey, createdAt, activatedAt, retiredAt, expiresAt))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *repository) Create(ctx context.Context, key domain.SigningKeyInterface) error {
+	der, err := MarshalPrivateKey(key.GetPrivateKey())
+	if err != nil {
+		return err
+	}
+
+	sealed, err := r.box.Seal(der, []byte(key.GetID()))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	query := `
+		INSERT INTO signing_keys (id, algorithm, state, private_key, created_at, activated_at, retired_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		key.GetID(),
+		key.GetAlgorithm(),
+		key.GetState(),
+		sealed,
+		key.GetCreatedAt(),
+		key.GetActivatedAt(),
+		key.GetRetiredAt(),
+		key.GetExpiresAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateState(ctx context.Context, key domain.SigningKeyInterface) error {
+	query := `
+		UPDATE signing_keys
+		SET state = $2, activated_at = $3, retired_at = $4, expires_at = $5
+		WHERE id = $1`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		key.GetID(),
+		key.GetState(),
+		key.GetActivatedAt(),
+		key.GetRetiredAt(),
+		key.GetExpiresAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM signing_keys WHERE expires_at IS NOT NULL AND expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired signing keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
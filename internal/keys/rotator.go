@@ -0,0 +1,75 @@
+package keys
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+)
+
+type rotator struct {
+	config  config.KeysConfig
+	keyring KeyringInterface
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type RotatorInterface interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewRotator(config config.KeysConfig, keyring KeyringInterface) RotatorInterface {
+	return &rotator{
+		config:  config,
+		keyring: keyring,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start runs a first rotation synchronously so the service never starts
+// without an active signing key, then keeps checking on a schedule.
+func (r *rotator) Start(ctx context.Context) error {
+	slog.Info("starting signing key rotator", slog.Int("check_interval", r.config.RotationCheckInterval))
+
+	if err := r.keyring.Rotate(ctx); err != nil {
+		slog.Error("initial signing key rotation failed", "error", err)
+		return err
+	}
+
+	go r.run()
+	return nil
+}
+
+func (r *rotator) Stop(ctx context.Context) error {
+	close(r.stop)
+
+	select {
+	case <-r.done:
+		slog.Info("signing key rotator stopped")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+func (r *rotator) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(time.Duration(r.config.RotationCheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.keyring.Rotate(context.Background()); err != nil {
+				slog.Error("signing key rotation failed", "error", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
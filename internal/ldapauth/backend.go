@@ -0,0 +1,497 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/federation"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+)
+
+// ProviderLDAP names the directory on linked identities and is recorded as
+// the method of its logins.
+const ProviderLDAP = "ldap"
+
+// noAttributes asks a search for the DNs of the entries alone.
+const noAttributes = "1.1"
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// entry is what the directory tells about the user who signed in.
+type entry struct {
+	UID       string
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+	Groups    []string
+}
+
+type backend struct {
+	config     config.LDAPConfig
+	db         database.DatabaseInterface
+	identities federation.RepositoryInterface
+	users      user.RepositoryInterface
+	accounts   user.ServiceInterface
+	roles      rbac.ServiceInterface
+	audit      audit.RecorderInterface
+	tls        *tls.Config
+	// groupRoles is GroupRoles keyed by normalized DN.
+	groupRoles map[string]string
+}
+
+// NewBackend returns the LDAP backend of the logins. It refuses every
+// password while no directory is configured.
+func NewBackend(
+	config config.LDAPConfig,
+	db database.DatabaseInterface,
+	identities federation.RepositoryInterface,
+	users user.RepositoryInterface,
+	accounts user.ServiceInterface,
+	roles rbac.ServiceInterface,
+	audit audit.RecorderInterface,
+) (auth.BackendInterface, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	groupRoles := make(map[string]string, len(config.GroupRoles))
+	for group, role := range config.GroupRoles {
+		groupRoles[normalizeDN(group)] = role
+	}
+
+	return &backend{
+		config:     config,
+		db:         db,
+		identities: identities,
+		users:      users,
+		accounts:   accounts,
+		roles:      roles,
+		audit:      audit,
+		tls:        tlsConfig,
+		groupRoles: groupRoles,
+	}, nil
+}
+
+func (b *backend) Name() string {
+	return ProviderLDAP
+}
+
+// Authenticate searches the entry of the login with the service account
+// and binds to it with the password. The account of an entry seen before
+// is the one linked to it; otherwise the entry is linked to the account
+// with its email when that email is verified, or one is provisioned when
+// signups are allowed.
+func (b *backend) Authenticate(ctx context.Context, email, password string, _ domain.UserInterface) (domain.UserInterface, error) {
+	if b.config.URL == "" || password == "" {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	found, err := b.lookup(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	var account domain.UserInterface
+	err = b.db.WithTx(ctx, func(ctx context.Context) error {
+		resolved, err := b.resolveUser(ctx, found)
+		if err != nil {
+			return err
+		}
+
+		if b.config.SyncAttributes && found.changes(resolved) {
+			resolved.UpdateProfile(resolved.GetEmail(), found.phoneOr(resolved), found.firstNameOr(resolved), found.lastNameOr(resolved))
+			if err := b.users.UpdateProfile(ctx, resolved); err != nil {
+				return err
+			}
+		}
+
+		if err := b.syncRoles(ctx, resolved.GetID(), found.Groups); err != nil {
+			return err
+		}
+
+		account = resolved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// lookup checks the password against the directory and returns the entry
+// it opens.
+func (b *backend) lookup(ctx context.Context, login, password string) (*entry, error) {
+	timeout := time.Duration(b.config.Timeout) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := ldap.DialURL(b.config.URL, ldap.DialWithTLSConfig(b.tls), ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+	// The client takes no context: closing the connection is what ends the
+	// request in flight once the login is abandoned or runs out of time.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	found, err := b.lookupEntry(conn, login, password)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return found, err
+}
+
+func (b *backend) lookupEntry(conn *ldap.Conn, login, password string) (*entry, error) {
+	if b.config.StartTLS {
+		if err := conn.StartTLS(b.tls); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		b.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		strings.ReplaceAll(b.config.UserFilter, "{login}", ldap.EscapeFilter(login)),
+		b.attributes(), nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, auth.ErrInvalidCredentials
+	case 1:
+	default:
+		slog.Warn("ldap login matches several entries", "filter", b.config.UserFilter)
+		return nil, auth.ErrInvalidCredentials
+	}
+	matched := result.Entries[0]
+
+	if err := conn.Bind(matched.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, auth.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	found := b.parseEntry(matched)
+	if found.UID == "" {
+		return nil, fmt.Errorf("entry %s has no usable %s", matched.DN, b.config.UIDAttribute)
+	}
+
+	if b.config.GroupBaseDN != "" {
+		// The user may not be allowed to read the groups.
+		if err := b.bindService(conn); err != nil {
+			return nil, err
+		}
+		groups, err := conn.Search(ldap.NewSearchRequest(
+			b.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			strings.ReplaceAll(b.config.GroupFilter, "{dn}", ldap.EscapeFilter(matched.DN)),
+			[]string{noAttributes}, nil,
+		))
+		if err != nil {
+			return nil, err
+		}
+		found.Groups = found.Groups[:0]
+		for _, group := range groups.Entries {
+			found.Groups = append(found.Groups, group.DN)
+		}
+	}
+
+	return found, nil
+}
+
+// bindService authenticates as the service account, when there is one;
+// otherwise searches run anonymously.
+func (b *backend) bindService(conn *ldap.Conn) error {
+	if b.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(b.config.BindDN, b.config.BindPassword); err != nil {
+		return fmt.Errorf("failed to bind the service account: %w", err)
+	}
+	return nil
+}
+
+func (b *backend) resolveUser(ctx context.Context, found *entry) (domain.UserInterface, error) {
+	linked, err := b.identities.FindIdentity(ctx, ProviderLDAP, found.UID)
+	switch {
+	case err == nil:
+		linked.Touch(found.Email)
+		if err := b.identities.UpdateIdentity(ctx, linked); err != nil {
+			return nil, err
+		}
+		return b.users.FindByID(ctx, linked.GetUserID())
+	case !errors.Is(err, federation.ErrIdentityNotFound):
+		return nil, err
+	}
+
+	if found.Email == "" {
+		return nil, refuse("entry has no email")
+	}
+
+	account, err := b.users.FindByEmail(ctx, found.Email)
+	switch {
+	case err == nil:
+		// An unverified email may have been registered by anyone.
+		if !account.IsEmailVerified() {
+			return nil, refuse("an account with an unverified email has the email of the entry")
+		}
+	case errors.Is(err, user.ErrUserNotFound):
+		if !b.config.AllowSignup {
+			return nil, refuse("no account matches the entry")
+		}
+		provisioned, restErr := b.accounts.Provision(ctx, user.ProvisionRequest{
+			Email:     found.Email,
+			Phone:     found.Phone,
+			FirstName: found.FirstName,
+			LastName:  found.LastName,
+			Method:    ProviderLDAP,
+		})
+		if restErr != nil {
+			return nil, restErr
+		}
+		account = provisioned
+	default:
+		return nil, err
+	}
+
+	linked = domain.NewUserIdentity(account.GetID(), ProviderLDAP, found.UID, found.Email)
+	if err := b.identities.CreateIdentity(ctx, linked); err != nil {
+		if errors.Is(err, federation.ErrIdentityExists) {
+			return nil, refuse("account is linked to another entry")
+		}
+		return nil, err
+	}
+
+	err = b.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionIdentityLink,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: audit.TargetUser,
+		TargetID:   account.GetID().String(),
+		Metadata:   map[string]string{"provider": ProviderLDAP, "identity_id": linked.GetID().String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// syncRoles gives the user the roles of their groups, and takes back the
+// roles the mapping hands out from those who left the group. Roles the
+// mapping does not mention are left alone.
+func (b *backend) syncRoles(ctx context.Context, userID uuid.UUID, groups []string) error {
+	if len(b.groupRoles) == 0 {
+		return nil
+	}
+
+	wanted := map[string]bool{}
+	for _, group := range groups {
+		if role, ok := b.groupRoles[normalizeDN(group)]; ok {
+			wanted[role] = true
+		}
+	}
+	managed := map[string]bool{}
+	for _, role := range b.groupRoles {
+		managed[role] = true
+	}
+
+	held, restErr := b.roles.ListUserRoles(ctx, userID.String())
+	if restErr != nil {
+		return restErr
+	}
+	for _, role := range held {
+		if wanted[role.Name] {
+			delete(wanted, role.Name)
+			continue
+		}
+		if managed[role.Name] {
+			if restErr := b.roles.UnassignRole(ctx, userID.String(), role.ID); restErr != nil {
+				return restErr
+			}
+		}
+	}
+
+	for role := range wanted {
+		restErr := b.roles.AssignRole(ctx, userID.String(), rbac.RoleNameRequest{Role: role})
+		if restErr != nil {
+			if restErr.Code == http.StatusBadRequest {
+				slog.Warn("ldap group maps to a role that does not exist", "role", role)
+				continue
+			}
+			return restErr
+		}
+	}
+
+	return nil
+}
+
+func (b *backend) attributes() []string {
+	attributes := []string{b.config.UIDAttribute, b.config.EmailAttribute, "memberOf"}
+	for _, attribute := range []string{b.config.FirstNameAttribute, b.config.LastNameAttribute, b.config.PhoneAttribute} {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
+
+// parseEntry reads the entry, dropping values that would not fit the
+// account.
+func (b *backend) parseEntry(matched *ldap.Entry) *entry {
+	found := &entry{Groups: matched.GetEqualFoldAttributeValues("memberOf")}
+
+	// Binary identifiers, such as objectGUID, are stored hex encoded.
+	if raw := matched.GetEqualFoldRawAttributeValues(b.config.UIDAttribute); len(raw) > 0 {
+		uid := string(raw[0])
+		if !printable(uid) {
+			uid = hex.EncodeToString(raw[0])
+		}
+		if len(uid) <= 255 {
+			found.UID = uid
+		}
+	}
+
+	email := strings.ToLower(strings.TrimSpace(matched.GetEqualFoldAttributeValue(b.config.EmailAttribute)))
+	if address, err := mail.ParseAddress(email); err == nil && address.Address == email && len(email) <= 255 {
+		found.Email = email
+	}
+
+	if b.config.FirstNameAttribute != "" {
+		found.FirstName = truncate(strings.TrimSpace(matched.GetEqualFoldAttributeValue(b.config.FirstNameAttribute)), 255)
+	}
+	if b.config.LastNameAttribute != "" {
+		found.LastName = truncate(strings.TrimSpace(matched.GetEqualFoldAttributeValue(b.config.LastNameAttribute)), 255)
+	}
+	if b.config.PhoneAttribute != "" {
+		if phone := strings.TrimSpace(matched.GetEqualFoldAttributeValue(b.config.PhoneAttribute)); e164.MatchString(phone) {
+			found.Phone = phone
+		}
+	}
+
+	return found
+}
+
+// changes tells whether the synchronized columns differ from the account.
+// The email is not synchronized: it is what logins find the account by.
+func (e *entry) changes(found domain.UserInterface) bool {
+	return e.firstNameOr(found) != found.GetFirstName() ||
+		e.lastNameOr(found) != found.GetLastName() ||
+		e.phoneOr(found) != found.GetPhone()
+}
+
+func (e *entry) firstNameOr(found domain.UserInterface) string {
+	if e.FirstName == "" {
+		return found.GetFirstName()
+	}
+	return e.FirstName
+}
+
+func (e *entry) lastNameOr(found domain.UserInterface) string {
+	if e.LastName == "" {
+		return found.GetLastName()
+	}
+	return e.LastName
+}
+
+func (e *entry) phoneOr(found domain.UserInterface) string {
+	if e.Phone == "" {
+		return found.GetPhone()
+	}
+	return e.Phone
+}
+
+// refuse turns down a password the directory accepted, for a reason that is
+// logged but not told to the caller.
+func refuse(reason string) error {
+	slog.Info("ldap login refused", "reason", reason)
+	return auth.ErrInvalidCredentials
+}
+
+func newTLSConfig(cfg config.LDAPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	// StartTLS upgrades a connection that does not know the name it was
+	// dialed by.
+	if parsed, err := url.Parse(cfg.URL); err == nil {
+		tlsConfig.ServerName = parsed.Hostname()
+	}
+	if cfg.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ldap ca file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("ldap ca file holds no certificate")
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
+
+// normalizeDN lowercases a DN and drops the spaces around its separators,
+// which is enough to compare the DNs a directory spells alike.
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		name, value, _ := strings.Cut(part, "=")
+		parts[i] = strings.TrimSpace(name) + "=" + strings.TrimSpace(value)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
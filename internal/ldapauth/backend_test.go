@@ -0,0 +1,666 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/federation"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/hashicorp/go-hclog"
+	"github.com/jimlambrt/gldap"
+)
+
+const (
+	testBaseDN    = "ou=people,dc=example,dc=com"
+	testGroupDN   = "ou=groups,dc=example,dc=com"
+	testServiceDN = "cn=service,dc=example,dc=com"
+	testJaneDN    = "uid=jane,ou=people,dc=example,dc=com"
+	testEmail     = "jane@example.com"
+	testPassword  = "correct horse"
+)
+
+func TestAuthenticateLinksVerifiedAccount(t *testing.T) {
+	directory := newTestDirectory(t, nil)
+	directory.add(testJaneDN, testPassword, map[string][]string{
+		"entryUUID":       {"5f3c0a1e-7d4b-4c6e-9a2f-0b8e1d7c6a53"},
+		"mail":            {"Jane@Example.com"},
+		"givenName":       {"Janet"},
+		"sn":              {"Doe"},
+		"telephoneNumber": {"+5511999990000"},
+		"memberOf":        {"CN=Admins, OU=Groups,DC=example,DC=com"},
+	})
+	env := newTestEnv(t, directory.config())
+	account := env.addAccount(t, testEmail, true)
+
+	found, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if found.GetID() != account.GetID() {
+		t.Fatalf("Authenticate() = %s, want the account with the entry's email", found.GetID())
+	}
+	if found.GetFirstName() != "Janet" || found.GetPhone() != "+5511999990000" {
+		t.Errorf("profile = %q %q, want the entry's attributes", found.GetFirstName(), found.GetPhone())
+	}
+	if roles := env.roles.held[account.GetID().String()]; len(roles) != 1 || !roles["admin"] {
+		t.Errorf("roles = %v, want admin from the group", roles)
+	}
+
+	identity, err := env.identities.FindIdentity(context.Background(), ProviderLDAP, "5f3c0a1e-7d4b-4c6e-9a2f-0b8e1d7c6a53")
+	if err != nil {
+		t.Fatalf("FindIdentity() error = %v", err)
+	}
+	if identity.GetUserID() != account.GetID() {
+		t.Errorf("identity links %s, want %s", identity.GetUserID(), account.GetID())
+	}
+	if links := env.audit.Count(audit.ActionIdentityLink); links != 1 {
+		t.Errorf("audited links = %d, want 1", links)
+	}
+
+	// The entry is found by its identifier from then on.
+	found, err = env.backend.Authenticate(context.Background(), testEmail, testPassword, nil)
+	if err != nil {
+		t.Fatalf("second Authenticate() error = %v", err)
+	}
+	if links := env.audit.Count(audit.ActionIdentityLink); found.GetID() != account.GetID() || links != 1 {
+		t.Errorf("second Authenticate() = %s with %d links, want the linked account", found.GetID(), links)
+	}
+
+	if got := directory.binds(); strings.Join(got, " ") != strings.Join([]string{testServiceDN, testJaneDN, testServiceDN, testJaneDN}, " ") {
+		t.Errorf("binds = %v, want the service account then the entry", got)
+	}
+}
+
+func TestAuthenticateEncodesBinaryUID(t *testing.T) {
+	directory := newTestDirectory(t, nil)
+	directory.add(testJaneDN, testPassword, map[string][]string{
+		"objectGUID": {"\x00\x01\xfe\xff"},
+		"mail":       {testEmail},
+	})
+	cfg := directory.config()
+	cfg.UIDAttribute = "objectGUID"
+	env := newTestEnv(t, cfg)
+	env.addAccount(t, testEmail, true)
+
+	if _, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if _, err := env.identities.FindIdentity(context.Background(), ProviderLDAP, hex.EncodeToString([]byte("\x00\x01\xfe\xff"))); err != nil {
+		t.Errorf("FindIdentity() error = %v, want the identifier hex encoded", err)
+	}
+}
+
+func TestAuthenticateSyncsRolesFromGroupSearch(t *testing.T) {
+	directory := newTestDirectory(t, nil)
+	directory.add(testJaneDN, testPassword, map[string][]string{
+		"entryUUID": {"jane"},
+		"mail":      {testEmail},
+	})
+	directory.add("cn=developers,ou=groups,dc=example,dc=com", "", map[string][]string{
+		"member": {testJaneDN},
+	})
+	directory.add("cn=admins,ou=groups,dc=example,dc=com", "", map[string][]string{
+		"member": {"uid=john,ou=people,dc=example,dc=com"},
+	})
+	cfg := directory.config()
+	cfg.GroupBaseDN = testGroupDN
+	cfg.GroupFilter = "(member={dn})"
+	cfg.GroupRoles["cn=developers,ou=groups,dc=example,dc=com"] = "developer"
+	env := newTestEnv(t, cfg)
+	account := env.addAccount(t, testEmail, true)
+	env.roles.held[account.GetID().String()] = map[string]bool{"admin": true, "auditor": true}
+
+	if _, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	// admin is handed out by the mapping and taken back; auditor is not.
+	roles := env.roles.held[account.GetID().String()]
+	if len(roles) != 2 || !roles["developer"] || !roles["auditor"] {
+		t.Errorf("roles = %v, want developer and auditor", roles)
+	}
+	if got := directory.filters(); got[len(got)-1] != "(member="+testJaneDN+")" {
+		t.Errorf("group filter = %q, want the member filter of the entry", got[len(got)-1])
+	}
+}
+
+func TestAuthenticateProvisionsWhenSignupIsAllowed(t *testing.T) {
+	directory := newTestDirectory(t, nil)
+	directory.add(testJaneDN, testPassword, map[string][]string{
+		"entryUUID": {"jane"},
+		"mail":      {testEmail},
+		"givenName": {"Jane"},
+		"sn":        {"Doe"},
+	})
+	cfg := directory.config()
+	cfg.AllowSignup = true
+	env := newTestEnv(t, cfg)
+
+	found, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if len(env.accounts.provisioned) != 1 {
+		t.Fatalf("provisioned = %v, want one account", env.accounts.provisioned)
+	}
+	request := env.accounts.provisioned[0]
+	if request.Email != testEmail || request.FirstName != "Jane" || request.Method != ProviderLDAP {
+		t.Errorf("Provision() request = %+v", request)
+	}
+	if found.GetEmail() != testEmail {
+		t.Errorf("Authenticate() = %s, want the provisioned account", found.GetEmail())
+	}
+}
+
+func TestAuthenticateOverLDAPS(t *testing.T) {
+	serverTLS, caFile := newTestTLS(t)
+	directory := newTestDirectory(t, serverTLS)
+	directory.add(testJaneDN, testPassword, map[string][]string{
+		"entryUUID": {"jane"},
+		"mail":      {testEmail},
+	})
+	cfg := directory.config()
+	cfg.CAFile = caFile
+	env := newTestEnv(t, cfg)
+	env.addAccount(t, testEmail, true)
+
+	if _, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	cfg.CAFile = ""
+	env = newTestEnv(t, cfg)
+	env.addAccount(t, testEmail, true)
+	if _, err := env.backend.Authenticate(context.Background(), testEmail, testPassword, nil); err == nil || errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("Authenticate() with an untrusted certificate error = %v, want a TLS failure", err)
+	}
+}
+
+func TestAuthenticateRefuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		setup    func(directory *testDirectory, env *testEnv)
+	}{
+		{
+			name:     "wrong password",
+			login:    testEmail,
+			password: "wrong",
+			setup: func(_ *testDirectory, env *testEnv) {
+				env.addAccount(t, testEmail, true)
+			},
+		},
+		{
+			name:     "empty password",
+			login:    testEmail,
+			password: "",
+			setup: func(_ *testDirectory, env *testEnv) {
+				env.addAccount(t, testEmail, true)
+			},
+		},
+		{
+			name:     "unknown login",
+			login:    "john@example.com",
+			password: testPassword,
+		},
+		{
+			name:     "several entries",
+			login:    testEmail,
+			password: testPassword,
+			setup: func(directory *testDirectory, env *testEnv) {
+				directory.add("uid=jane2,ou=people,dc=example,dc=com", testPassword, map[string][]string{
+					"entryUUID": {"jane2"},
+					"mail":      {testEmail},
+				})
+				env.addAccount(t, testEmail, true)
+			},
+		},
+		{
+			name:     "unverified account",
+			login:    testEmail,
+			password: testPassword,
+			setup: func(_ *testDirectory, env *testEnv) {
+				env.addAccount(t, testEmail, false)
+			},
+		},
+		{
+			name:     "no account and no signup",
+			login:    testEmail,
+			password: testPassword,
+		},
+		{
+			name:     "filter injection",
+			login:    "*",
+			password: testPassword,
+			setup: func(_ *testDirectory, env *testEnv) {
+				env.addAccount(t, testEmail, true)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newTestDirectory(t, nil)
+			directory.add(testJaneDN, testPassword, map[string][]string{
+				"entryUUID": {"jane"},
+				"mail":      {testEmail},
+			})
+			env := newTestEnv(t, directory.config())
+			if tt.setup != nil {
+				tt.setup(directory, env)
+			}
+
+			if _, err := env.backend.Authenticate(context.Background(), tt.login, tt.password, nil); !errors.Is(err, auth.ErrInvalidCredentials) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, auth.ErrInvalidCredentials)
+			}
+			if len(env.identities.identities) != 0 {
+				t.Errorf("identities = %v, want none linked", env.identities.identities)
+			}
+		})
+	}
+}
+
+func TestAuthenticateEscapesLogin(t *testing.T) {
+	directory := newTestDirectory(t, nil)
+	env := newTestEnv(t, directory.config())
+
+	login := `jane*)(uid=\`
+	if _, err := env.backend.Authenticate(context.Background(), login, testPassword, nil); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, auth.ErrInvalidCredentials)
+	}
+
+	want := `(mail=jane\2a\29\28uid=\5c)`
+	if got := directory.filters(); len(got) != 1 || got[0] != want {
+		t.Errorf("filters = %q, want %q", got, want)
+	}
+}
+
+// testDirectory is an LDAP server holding entries in memory. Binds check
+// the password of an entry; searches take a single equality filter.
+type testDirectory struct {
+	t      *testing.T
+	server *gldap.Server
+	addr   string
+	tls    bool
+
+	mu       sync.Mutex
+	entries  []testEntry
+	bound    []string
+	searched []string
+}
+
+type testEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+func newTestDirectory(t *testing.T, tlsConfig *tls.Config) *testDirectory {
+	t.Helper()
+
+	server, err := gldap.NewServer(gldap.WithLogger(hclog.NewNullLogger()))
+	if err != nil {
+		t.Fatalf("gldap.NewServer() error = %v", err)
+	}
+	directory := &testDirectory{t: t, server: server, addr: freeAddr(t), tls: tlsConfig != nil}
+	directory.add(testServiceDN, "service secret", nil)
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		t.Fatalf("gldap.NewMux() error = %v", err)
+	}
+	if err := mux.Bind(directory.bind); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if err := mux.Search(directory.search); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if err := server.Router(mux); err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+
+	var options []gldap.Option
+	if tlsConfig != nil {
+		options = append(options, gldap.WithTLSConfig(tlsConfig))
+	}
+	go func() {
+		_ = server.Run(directory.addr, options...)
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !server.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("ldap server is not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return directory
+}
+
+func (d *testDirectory) config() config.LDAPConfig {
+	scheme := "ldap"
+	if d.tls {
+		scheme = "ldaps"
+	}
+	return config.LDAPConfig{
+		URL:                scheme + "://" + d.addr,
+		Timeout:            5,
+		BindDN:             testServiceDN,
+		BindPassword:       "service secret",
+		BaseDN:             testBaseDN,
+		UserFilter:         "(mail={login})",
+		UIDAttribute:       "entryUUID",
+		EmailAttribute:     "mail",
+		FirstNameAttribute: "givenName",
+		LastNameAttribute:  "sn",
+		PhoneAttribute:     "telephoneNumber",
+		GroupRoles:         map[string]string{"cn=admins,ou=groups,dc=example,dc=com": "admin"},
+		SyncAttributes:     true,
+	}
+}
+
+func (d *testDirectory) add(dn, password string, attributes map[string][]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = append(d.entries, testEntry{dn: dn, password: password, attributes: attributes})
+}
+
+func (d *testDirectory) binds() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.bound...)
+}
+
+func (d *testDirectory) filters() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.searched...)
+}
+
+func (d *testDirectory) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer func() {
+		_ = w.Write(resp)
+	}()
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range d.entries {
+		if strings.EqualFold(e.dn, m.UserName) && e.password != "" && e.password == string(m.Password) {
+			d.bound = append(d.bound, e.dn)
+			resp.SetResultCode(gldap.ResultSuccess)
+			return
+		}
+	}
+}
+
+func (d *testDirectory) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+	defer func() {
+		_ = w.Write(resp)
+	}()
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		resp.SetResultCode(gldap.ResultOperationsError)
+		return
+	}
+	attribute, value, ok := parseEquality(m.Filter)
+	if !ok {
+		d.t.Errorf("search filter %q is not an equality", m.Filter)
+		resp.SetResultCode(gldap.ResultUnwillingToPerform)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.searched = append(d.searched, m.Filter)
+
+	sent := 0
+	for _, e := range d.entries {
+		if !strings.HasSuffix(strings.ToLower(e.dn), ","+strings.ToLower(m.BaseDN)) || !e.has(attribute, value) {
+			continue
+		}
+		if m.SizeLimit > 0 && int64(sent) == m.SizeLimit {
+			resp.SetResultCode(gldap.ResultSizeLimitExceeded)
+			return
+		}
+		found := r.NewSearchResponseEntry(e.dn)
+		for name, values := range e.attributes {
+			found.AddAttribute(name, values)
+		}
+		_ = w.Write(found)
+		sent++
+	}
+}
+
+func (e testEntry) has(attribute, value string) bool {
+	for name, values := range e.attributes {
+		if !strings.EqualFold(name, attribute) {
+			continue
+		}
+		for _, v := range values {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseEquality reads a filter such as (mail=jane@example.com), undoing
+// the escapes of its value.
+func parseEquality(filter string) (string, string, bool) {
+	if !strings.HasPrefix(filter, "(") || !strings.HasSuffix(filter, ")") {
+		return "", "", false
+	}
+	attribute, escaped, ok := strings.Cut(filter[1:len(filter)-1], "=")
+	if !ok || strings.ContainsAny(attribute, "()&|!*") || strings.ContainsAny(escaped, "()*") {
+		return "", "", false
+	}
+
+	var value strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '\\' {
+			value.WriteByte(escaped[i])
+			continue
+		}
+		if i+3 > len(escaped) {
+			return "", "", false
+		}
+		b, err := hex.DecodeString(escaped[i+1 : i+3])
+		if err != nil {
+			return "", "", false
+		}
+		value.Write(b)
+		i += 2
+	}
+	return attribute, value.String(), true
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// newTestTLS returns the TLS configuration of a server for 127.0.0.1 and
+// the file of the CA that signed it.
+func newTestTLS(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ldap.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, caFile
+}
+
+type testEnv struct {
+	backend    auth.BackendInterface
+	identities *fakeIdentities
+	users      *testutil.Users
+	accounts   *fakeAccounts
+	roles      *fakeRoles
+	audit      *testutil.Recorder
+}
+
+func newTestEnv(t *testing.T, cfg config.LDAPConfig) *testEnv {
+	t.Helper()
+
+	env := &testEnv{
+		identities: &fakeIdentities{identities: map[string]domain.UserIdentityInterface{}},
+		users:      testutil.NewUsers(),
+		roles:      &fakeRoles{held: map[string]map[string]bool{}, exists: map[string]bool{"admin": true, "developer": true, "auditor": true}},
+		audit:      &testutil.Recorder{},
+	}
+	env.accounts = &fakeAccounts{users: env.users}
+
+	backend, err := NewBackend(cfg, testutil.DB{}, env.identities, env.users, env.accounts, env.roles, env.audit)
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	env.backend = backend
+	return env
+}
+
+func (env *testEnv) addAccount(t *testing.T, email string, verified bool) domain.UserInterface {
+	t.Helper()
+
+	account, err := domain.New(email, "", "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+	if verified {
+		account.VerifyEmail()
+	}
+	env.users.Add(account)
+	return account
+}
+
+type fakeIdentities struct {
+	federation.RepositoryInterface
+	identities map[string]domain.UserIdentityInterface
+}
+
+func (r *fakeIdentities) FindIdentity(_ context.Context, provider, subject string) (domain.UserIdentityInterface, error) {
+	if identity, ok := r.identities[provider+"/"+subject]; ok {
+		return identity, nil
+	}
+	return nil, federation.ErrIdentityNotFound
+}
+
+func (r *fakeIdentities) CreateIdentity(_ context.Context, identity domain.UserIdentityInterface) error {
+	r.identities[identity.GetProvider()+"/"+identity.GetSubject()] = identity
+	return nil
+}
+
+func (r *fakeIdentities) UpdateIdentity(context.Context, domain.UserIdentityInterface) error {
+	return nil
+}
+
+type fakeAccounts struct {
+	user.ServiceInterface
+	users       *testutil.Users
+	provisioned []user.ProvisionRequest
+}
+
+func (a *fakeAccounts) Provision(_ context.Context, req user.ProvisionRequest) (domain.UserInterface, *httperr.HttpError) {
+	account, err := domain.New(req.Email, "", req.Phone, req.FirstName, req.LastName)
+	if err != nil {
+		return nil, httperr.NewBadRequestError(err.Error())
+	}
+	a.provisioned = append(a.provisioned, req)
+	a.users.Add(account)
+	return account, nil
+}
+
+// fakeRoles keeps the roles of each user by name; a role's ID is its name.
+type fakeRoles struct {
+	rbac.ServiceInterface
+	held   map[string]map[string]bool
+	exists map[string]bool
+}
+
+func (r *fakeRoles) ListUserRoles(_ context.Context, userID string) ([]rbac.RoleResponse, *httperr.HttpError) {
+	var roles []rbac.RoleResponse
+	for name := range r.held[userID] {
+		roles = append(roles, rbac.RoleResponse{ID: name, Name: name})
+	}
+	return roles, nil
+}
+
+func (r *fakeRoles) AssignRole(_ context.Context, userID string, req rbac.RoleNameRequest) *httperr.HttpError {
+	if !r.exists[req.Role] {
+		return httperr.NewBadRequestError("role not found")
+	}
+	if r.held[userID] == nil {
+		r.held[userID] = map[string]bool{}
+	}
+	r.held[userID][req.Role] = true
+	return nil
+}
+
+func (r *fakeRoles) UnassignRole(_ context.Context, userID, roleID string) *httperr.HttpError {
+	delete(r.held[userID], roleID)
+	return nil
+}
@@ -0,0 +1,12 @@
+package ldapauth
+
+import (
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		auth.AsBackend(NewBackend),
+	),
+)
@@ -0,0 +1,31 @@
+package mfa
+
+import "github.com/felipeversiane/auth-service/internal/passkey"
+
+// Proof is the second factor presented to complete a login challenge.
+// Exactly one of its fields must be set.
+type Proof struct {
+	Code         string
+	RecoveryCode string
+	Passkey      *passkey.FinishLoginRequest
+}
+
+type EnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+type CodeRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type StatusResponse struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	PasskeyEnabled         bool `json:"passkey_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
@@ -0,0 +1,133 @@
+package mfa
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service ServiceInterface
+	tokens  token.ManagerInterface
+	account config.AccountConfig
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Status(ctx *gin.Context)
+	Enroll(ctx *gin.Context)
+	Confirm(ctx *gin.Context)
+	RegenerateRecoveryCodes(ctx *gin.Context)
+	Disable(ctx *gin.Context)
+}
+
+func NewHandler(service ServiceInterface, tokens token.ManagerInterface, account config.AccountConfig) HandlerInterface {
+	return &handler{
+		service: service,
+		tokens:  tokens,
+		account: account,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	mfa := router.Group(
+		"/api/v1/mfa",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+		middleware.RequireVerifiedEmail(h.account),
+	)
+	{
+		mfa.GET("", h.Status)
+		mfa.POST("/totp", h.Enroll)
+		mfa.POST("/totp/confirm", h.Confirm)
+		mfa.POST("/totp/disable", h.Disable)
+		mfa.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+	}
+}
+
+func (h *handler) Status(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.Status(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Enroll(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.Enroll(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) Confirm(ctx *gin.Context) {
+	var req CodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.Confirm(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req CodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.RegenerateRecoveryCodes(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Disable(ctx *gin.Context) {
+	var req CodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+
+	if restErr := h.service.Disable(ctx.Request.Context(), userID, req); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+const recoveryCodeLength = 10
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns codes formatted for display (xxxxx-xxxxx) and
+// the hashes to store; the plain codes are shown to the user exactly once.
+func generateRecoveryCodes(count int) ([]string, []string) {
+	codes := make([]string, count)
+	hashes := make([]string, count)
+
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(buf))[:recoveryCodeLength]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	return domain.HashOpaqueToken(normalizeRecoveryCode(code))
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
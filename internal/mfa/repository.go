@@ -0,0 +1,266 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/secretbox"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrFactorNotFound         = errors.New("totp factor not found")
+	ErrFactorAlreadyConfirmed = errors.New("totp factor already confirmed")
+	ErrChallengeNotFound      = errors.New("mfa challenge not found")
+)
+
+type repository struct {
+	db  database.DatabaseInterface
+	box secretbox.BoxInterface
+}
+
+type RepositoryInterface interface {
+	SaveFactor(ctx context.Context, factor domain.TOTPFactorInterface) error
+	FindFactorByUserID(ctx context.Context, userID uuid.UUID) (domain.TOTPFactorInterface, error)
+	ConfirmFactor(ctx context.Context, factor domain.TOTPFactorInterface) error
+	UseStep(ctx context.Context, factor domain.TOTPFactorInterface) (bool, error)
+	DeleteFactor(ctx context.Context, userID uuid.UUID) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	CreateChallenge(ctx context.Context, challenge domain.MFAChallengeInterface) error
+	FindChallenge(ctx context.Context, tokenHash string) (domain.MFAChallengeInterface, error)
+	FindChallengeForUpdate(ctx context.Context, tokenHash string) (domain.MFAChallengeInterface, error)
+	IncrementChallengeAttempts(ctx context.Context, id uuid.UUID) error
+	ConsumeChallenge(ctx context.Context, id uuid.UUID) error
+	DeleteExpiredChallenges(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface, box secretbox.BoxInterface) RepositoryInterface {
+	return &repository{
+		db:  db,
+		box: box,
+	}
+}
+
+// SaveFactor stores a pending enrollment, replacing any earlier unconfirmed
+// one. A confirmed factor is never overwritten; it must be disabled first.
+func (r *repository) SaveFactor(ctx context.Context, factor domain.TOTPFactorInterface) error {
+	sealed, err := r.box.Seal(factor.GetSecret(), secretAD(factor.GetUserID()))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO totp_factors (id, user_id, secret, last_used_step, confirmed_at, created_at)
+		VALUES ($1, $2, $3, 0, NULL, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET id = EXCLUDED.id, secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE totp_factors.confirmed_at IS NULL`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, factor.GetID(), factor.GetUserID(), sealed, factor.GetCreatedAt())
+	if err != nil {
+		return fmt.Errorf("failed to save totp factor: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrFactorAlreadyConfirmed
+	}
+
+	return nil
+}
+
+func (r *repository) FindFactorByUserID(ctx context.Context, userID uuid.UUID) (domain.TOTPFactorInterface, error) {
+	query := `
+		SELECT id, secret, last_used_step, confirmed_at, created_at
+		FROM totp_factors
+		WHERE user_id = $1`
+
+	var (
+		id           uuid.UUID
+		sealed       []byte
+		lastUsedStep int64
+		confirmedAt  *time.Time
+		createdAt    time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, userID).Scan(&id, &sealed, &lastUsedStep, &confirmedAt, &createdAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFactorNotFound
+		}
+		return nil, fmt.Errorf("failed to scan totp factor: %w", err)
+	}
+
+	secret, err := r.box.Open(sealed, secretAD(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	return domain.RestoreTOTPFactor(id, userID, secret, lastUsedStep, confirmedAt, createdAt), nil
+}
+
+func (r *repository) ConfirmFactor(ctx context.Context, factor domain.TOTPFactorInterface) error {
+	query := `UPDATE totp_factors SET confirmed_at = $2, last_used_step = $3 WHERE id = $1`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, factor.GetID(), factor.GetConfirmedAt(), factor.GetLastUsedStep()); err != nil {
+		return fmt.Errorf("failed to confirm totp factor: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep records the step a code was accepted for. It reports false when a
+// concurrent request already used the same or a later step.
+func (r *repository) UseStep(ctx context.Context, factor domain.TOTPFactorInterface) (bool, error) {
+	query := `UPDATE totp_factors SET last_used_step = $2 WHERE id = $1 AND last_used_step < $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, factor.GetID(), factor.GetLastUsedStep())
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *repository) DeleteFactor(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM totp_factors WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete totp factor: %w", err)
+		}
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		query := `INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`
+		now := time.Now().UTC()
+		for _, codeHash := range codeHashes {
+			if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, uuid.Must(uuid.NewRandom()), userID, codeHash, now); err != nil {
+				return fmt.Errorf("failed to insert recovery code: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *repository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, userID, codeHash, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *repository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *repository) CreateChallenge(ctx context.Context, challenge domain.MFAChallengeInterface) error {
+	query := `
+		INSERT INTO mfa_challenges (id, user_id, org_id, token_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		challenge.GetID(),
+		challenge.GetUserID(),
+		challenge.GetOrgID(),
+		challenge.GetTokenHash(),
+		challenge.GetAttempts(),
+		challenge.GetExpiresAt(),
+		challenge.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert mfa challenge: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindChallenge(ctx context.Context, tokenHash string) (domain.MFAChallengeInterface, error) {
+	return r.findChallenge(ctx, tokenHash, "")
+}
+
+func (r *repository) FindChallengeForUpdate(ctx context.Context, tokenHash string) (domain.MFAChallengeInterface, error) {
+	return r.findChallenge(ctx, tokenHash, "FOR UPDATE")
+}
+
+func (r *repository) findChallenge(ctx context.Context, tokenHash, lock string) (domain.MFAChallengeInterface, error) {
+	query := `
+		SELECT id, user_id, org_id, token_hash, attempts, expires_at, consumed_at, created_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+		` + lock
+
+	var (
+		id, userID           uuid.UUID
+		orgID                *uuid.UUID
+		hash                 string
+		attempts             int
+		expiresAt, createdAt time.Time
+		consumedAt           *time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash).Scan(&id, &userID, &orgID, &hash, &attempts, &expiresAt, &consumedAt, &createdAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, fmt.Errorf("failed to scan mfa challenge: %w", err)
+	}
+
+	return domain.RestoreMFAChallenge(id, userID, orgID, hash, attempts, expiresAt, consumedAt, createdAt), nil
+}
+
+func (r *repository) IncrementChallengeAttempts(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to increment mfa challenge attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ConsumeChallenge(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `UPDATE mfa_challenges SET consumed_at = $2 WHERE id = $1`, id, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to consume mfa challenge: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) DeleteExpiredChallenges(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired mfa challenges: %w", err)
+	}
+	return nil
+}
+
+// secretAD binds a sealed secret to its owner so rows cannot be swapped
+// between users.
+func secretAD(userID uuid.UUID) []byte {
+	return []byte("totp:" + userID.String())
+}
@@ -0,0 +1,486 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/felipeversiane/auth-service/pkg/totp"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	qrCodeSize = 256
+
+	invalidCodeMessage      = "invalid verification code"
+	invalidChallengeMessage = "invalid or expired mfa challenge"
+)
+
+type service struct {
+	config     config.MFAConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	passkeys   passkey.ServiceInterface
+	events     security.EmitterInterface
+	audit      audit.RecorderInterface
+}
+
+type ServiceInterface interface {
+	Status(ctx context.Context, userID uuid.UUID) (*StatusResponse, *httperr.HttpError)
+	Enroll(ctx context.Context, userID uuid.UUID) (*EnrollResponse, *httperr.HttpError)
+	Confirm(ctx context.Context, userID uuid.UUID, req CodeRequest) (*RecoveryCodesResponse, *httperr.HttpError)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req CodeRequest) (*RecoveryCodesResponse, *httperr.HttpError)
+	Disable(ctx context.Context, userID uuid.UUID, req CodeRequest) *httperr.HttpError
+	IsEnrolled(ctx context.Context, userID uuid.UUID) (bool, *httperr.HttpError)
+	// CreateChallenge starts a login challenge for the user; orgID is the
+	// organization the session will act in, nil for none.
+	CreateChallenge(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (string, int64, *httperr.HttpError)
+	// FindChallenge returns a pending login challenge.
+	FindChallenge(ctx context.Context, rawToken string) (domain.MFAChallengeInterface, *httperr.HttpError)
+	BeginPasskeyChallenge(ctx context.Context, rawToken string) (*passkey.BeginResponse, *httperr.HttpError)
+	VerifyChallenge(ctx context.Context, rawToken string, proof Proof) (uuid.UUID, *httperr.HttpError)
+}
+
+func NewService(
+	config config.MFAConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	passkeys passkey.ServiceInterface,
+	events security.EmitterInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		users:      users,
+		passkeys:   passkeys,
+		events:     events,
+		audit:      audit,
+	}
+}
+
+func (s *service) Status(ctx context.Context, userID uuid.UUID) (*StatusResponse, *httperr.HttpError) {
+	totpEnabled, restErr := s.hasTOTP(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	passkeyEnabled, restErr := s.passkeys.HasPasskeys(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	remaining, err := s.repository.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		slog.Error("failed to count recovery codes", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load mfa status")
+	}
+
+	return &StatusResponse{
+		TOTPEnabled:            totpEnabled,
+		PasskeyEnabled:         passkeyEnabled,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// Enroll creates a pending TOTP factor and returns what the user needs to add
+// it to an authenticator app. Calling it again before confirming starts over.
+func (s *service) Enroll(ctx context.Context, userID uuid.UUID) (*EnrollResponse, *httperr.HttpError) {
+	found, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to load user for mfa enrollment", "error", err)
+		return nil, httperr.NewInternalServerError("failed to enroll mfa")
+	}
+
+	factor, err := domain.NewTOTPFactor(userID)
+	if err != nil {
+		slog.Error("failed to create totp factor", "error", err)
+		return nil, httperr.NewInternalServerError("failed to enroll mfa")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.SaveFactor(ctx, factor); err != nil {
+			return err
+		}
+		return s.recordFactorChange(ctx, audit.ActionMFAEnroll, userID)
+	})
+	if err != nil {
+		if errors.Is(err, ErrFactorAlreadyConfirmed) {
+			return nil, httperr.NewBadRequestError("mfa is already enabled")
+		}
+		slog.Error("failed to save totp factor", "error", err)
+		return nil, httperr.NewInternalServerError("failed to enroll mfa")
+	}
+
+	uri := totp.URI(s.config.Issuer, found.GetEmail(), factor.GetSecret())
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		slog.Error("failed to render totp qr code", "error", err)
+		return nil, httperr.NewInternalServerError("failed to enroll mfa")
+	}
+
+	return &EnrollResponse{
+		Secret:     totp.Encode(factor.GetSecret()),
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm activates a pending factor with its first code and issues the
+// initial set of recovery codes.
+func (s *service) Confirm(ctx context.Context, userID uuid.UUID, req CodeRequest) (*RecoveryCodesResponse, *httperr.HttpError) {
+	factor, restErr := s.findFactor(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	if factor.IsConfirmed() {
+		return nil, httperr.NewBadRequestError("mfa is already enabled")
+	}
+
+	now := time.Now().UTC()
+	if !factor.Verify(req.Code, now, s.config.Skew) {
+		return nil, httperr.NewBadRequestError(invalidCodeMessage)
+	}
+	factor.Confirm(now)
+
+	codes, hashes := generateRecoveryCodes(s.config.RecoveryCodeCount)
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.ConfirmFactor(ctx, factor); err != nil {
+			return err
+		}
+		if err := s.repository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+			return err
+		}
+		return s.recordFactorChange(ctx, audit.ActionMFAConfirm, userID)
+	})
+	if err != nil {
+		slog.Error("failed to confirm totp factor", "error", err)
+		return nil, httperr.NewInternalServerError("failed to enable mfa")
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req CodeRequest) (*RecoveryCodesResponse, *httperr.HttpError) {
+	if restErr := s.verifyConfirmedCode(ctx, userID, req.Code); restErr != nil {
+		return nil, restErr
+	}
+
+	codes, hashes := generateRecoveryCodes(s.config.RecoveryCodeCount)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+			return err
+		}
+		return s.recordFactorChange(ctx, audit.ActionMFARecovery, userID)
+	})
+	if err != nil {
+		slog.Error("failed to replace recovery codes", "error", err)
+		return nil, httperr.NewInternalServerError("failed to regenerate recovery codes")
+	}
+
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *service) Disable(ctx context.Context, userID uuid.UUID, req CodeRequest) *httperr.HttpError {
+	if restErr := s.verifyConfirmedCode(ctx, userID, req.Code); restErr != nil {
+		return restErr
+	}
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteFactor(ctx, userID); err != nil {
+			return err
+		}
+		return s.recordFactorChange(ctx, audit.ActionMFADisable, userID)
+	})
+	if err != nil {
+		slog.Error("failed to delete totp factor", "error", err)
+		return httperr.NewInternalServerError("failed to disable mfa")
+	}
+
+	s.events.Emit(ctx, security.Event{
+		Type:   security.EventMFADisabled,
+		UserID: userID.String(),
+	})
+
+	return nil
+}
+
+// IsEnrolled reports whether logins for the user need a second factor: a
+// confirmed TOTP factor or a registered passkey.
+func (s *service) IsEnrolled(ctx context.Context, userID uuid.UUID) (bool, *httperr.HttpError) {
+	enrolled, restErr := s.hasTOTP(ctx, userID)
+	if restErr != nil || enrolled {
+		return enrolled, restErr
+	}
+
+	return s.passkeys.HasPasskeys(ctx, userID)
+}
+
+func (s *service) hasTOTP(ctx context.Context, userID uuid.UUID) (bool, *httperr.HttpError) {
+	factor, err := s.repository.FindFactorByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrFactorNotFound) {
+			return false, nil
+		}
+		slog.Error("failed to load totp factor", "error", err)
+		return false, httperr.NewInternalServerError("failed to check mfa enrollment")
+	}
+
+	return factor.IsConfirmed(), nil
+}
+
+// CreateChallenge returns the raw challenge token handed out in place of a
+// session and its lifetime in seconds.
+func (s *service) CreateChallenge(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID) (string, int64, *httperr.HttpError) {
+	ttl := time.Duration(s.config.ChallengeTTL) * time.Second
+
+	challenge, raw := domain.NewMFAChallenge(userID, orgID, ttl)
+	if err := s.repository.CreateChallenge(ctx, challenge); err != nil {
+		slog.Error("failed to store mfa challenge", "error", err)
+		return "", 0, httperr.NewInternalServerError("failed to start mfa challenge")
+	}
+
+	if err := s.repository.DeleteExpiredChallenges(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up mfa challenges", "error", err)
+	}
+
+	return raw, int64(ttl.Seconds()), nil
+}
+
+// BeginPasskeyChallenge starts a WebAuthn assertion for the user behind a
+// pending login challenge.
+func (s *service) BeginPasskeyChallenge(ctx context.Context, rawToken string) (*passkey.BeginResponse, *httperr.HttpError) {
+	challenge, restErr := s.FindChallenge(ctx, rawToken)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	userID := challenge.GetUserID()
+	return s.passkeys.BeginLogin(ctx, &userID)
+}
+
+func (s *service) FindChallenge(ctx context.Context, rawToken string) (domain.MFAChallengeInterface, *httperr.HttpError) {
+	challenge, err := s.repository.FindChallenge(ctx, domain.HashOpaqueToken(rawToken))
+	if err != nil {
+		if errors.Is(err, ErrChallengeNotFound) {
+			return nil, httperr.NewUnauthorizedRequestError(invalidChallengeMessage)
+		}
+		slog.Error("failed to load mfa challenge", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load mfa challenge")
+	}
+
+	if !s.isOpen(challenge) {
+		return nil, httperr.NewUnauthorizedRequestError(invalidChallengeMessage)
+	}
+
+	return challenge, nil
+}
+
+// VerifyChallenge completes a login challenge with a TOTP code, a recovery
+// code or a passkey assertion and returns the authenticated user. Each
+// challenge allows a limited number of wrong guesses before it is burned.
+func (s *service) VerifyChallenge(ctx context.Context, rawToken string, proof Proof) (uuid.UUID, *httperr.HttpError) {
+	provided := 0
+	for _, set := range []bool{proof.Code != "", proof.RecoveryCode != "", proof.Passkey != nil} {
+		if set {
+			provided++
+		}
+	}
+	if provided != 1 {
+		return uuid.Nil, httperr.NewBadRequestError("provide exactly one of code, recovery_code or passkey")
+	}
+
+	var (
+		userID       uuid.UUID
+		verified     bool
+		locked       bool
+		usedRecovery bool
+	)
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		challenge, err := s.repository.FindChallengeForUpdate(ctx, domain.HashOpaqueToken(rawToken))
+		if err != nil {
+			return err
+		}
+
+		if !s.isOpen(challenge) {
+			return ErrChallengeNotFound
+		}
+		userID = challenge.GetUserID()
+
+		var method string
+		switch {
+		case proof.Code != "":
+			method = "totp"
+			verified, err = s.verifyCode(ctx, userID, proof.Code)
+		case proof.RecoveryCode != "":
+			method = "recovery_code"
+			verified, err = s.repository.UseRecoveryCode(ctx, userID, hashRecoveryCode(proof.RecoveryCode))
+			usedRecovery = verified
+		default:
+			method = "passkey"
+			verified, err = s.verifyPasskey(ctx, userID, *proof.Passkey)
+		}
+		if errors.Is(err, ErrFactorNotFound) {
+			return ErrChallengeNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.recordLogin(ctx, userID, method, verified); err != nil {
+			return err
+		}
+
+		if !verified {
+			locked = challenge.GetAttempts()+1 >= s.config.ChallengeMaxAttempts
+			// The failed attempt must be committed, so this is not an error.
+			return s.repository.IncrementChallengeAttempts(ctx, challenge.GetID())
+		}
+
+		return s.repository.ConsumeChallenge(ctx, challenge.GetID())
+	})
+	if err != nil {
+		if errors.Is(err, ErrChallengeNotFound) {
+			return uuid.Nil, httperr.NewUnauthorizedRequestError(invalidChallengeMessage)
+		}
+		slog.Error("failed to verify mfa challenge", "error", err)
+		return uuid.Nil, httperr.NewInternalServerError("failed to verify mfa challenge")
+	}
+
+	if locked {
+		s.events.Emit(ctx, security.Event{
+			Type:   security.EventMFAChallengeLocked,
+			UserID: userID.String(),
+		})
+	}
+
+	if !verified {
+		return uuid.Nil, httperr.NewUnauthorizedRequestError(invalidCodeMessage)
+	}
+
+	if usedRecovery {
+		s.events.Emit(ctx, security.Event{
+			Type:   security.EventMFARecoveryCodeUsed,
+			UserID: userID.String(),
+		})
+	}
+
+	return userID, nil
+}
+
+// recordLogin audits the second step of a login. The user is the actor:
+// the request carries no credentials of its own.
+func (s *service) recordLogin(ctx context.Context, userID uuid.UUID, method string, verified bool) error {
+	outcome := domain.AuditOutcomeSuccess
+	if !verified {
+		outcome = domain.AuditOutcomeFailure
+	}
+
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionLogin,
+		Outcome:    outcome,
+		TargetType: audit.TargetUser,
+		TargetID:   userID.String(),
+		Actor:      &requestinfo.Actor{Type: requestinfo.ActorUser, ID: userID.String()},
+		Metadata:   map[string]string{"method": "mfa", "factor": method},
+	})
+}
+
+func (s *service) recordFactorChange(ctx context.Context, action string, userID uuid.UUID) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: audit.TargetUser,
+		TargetID:   userID.String(),
+	})
+}
+
+func (s *service) isOpen(challenge domain.MFAChallengeInterface) bool {
+	return !challenge.IsConsumed() &&
+		!challenge.IsExpired(time.Now().UTC()) &&
+		challenge.GetAttempts() < s.config.ChallengeMaxAttempts
+}
+
+// verifyPasskey treats a rejected assertion as a failed attempt; only
+// unexpected failures are returned as errors.
+func (s *service) verifyPasskey(ctx context.Context, userID uuid.UUID, req passkey.FinishLoginRequest) (bool, error) {
+	if _, restErr := s.passkeys.FinishLogin(ctx, &userID, req); restErr != nil {
+		if restErr.Code >= http.StatusInternalServerError {
+			return false, errors.New(restErr.Message)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *service) findFactor(ctx context.Context, userID uuid.UUID) (domain.TOTPFactorInterface, *httperr.HttpError) {
+	factor, err := s.repository.FindFactorByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrFactorNotFound) {
+			return nil, httperr.NewBadRequestError("mfa is not enrolled")
+		}
+		slog.Error("failed to load totp factor", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load mfa factor")
+	}
+
+	return factor, nil
+}
+
+func (s *service) verifyConfirmedCode(ctx context.Context, userID uuid.UUID, code string) *httperr.HttpError {
+	verified, err := s.verifyCode(ctx, userID, code)
+	if err != nil {
+		if errors.Is(err, ErrFactorNotFound) {
+			return httperr.NewBadRequestError("mfa is not enabled")
+		}
+		slog.Error("failed to verify totp code", "error", err)
+		return httperr.NewInternalServerError("failed to verify code")
+	}
+
+	if !verified {
+		return httperr.NewBadRequestError(invalidCodeMessage)
+	}
+
+	return nil
+}
+
+// verifyCode checks a code against the user's confirmed factor and records
+// the accepted step so the same code cannot be used twice.
+func (s *service) verifyCode(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	factor, err := s.repository.FindFactorByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if !factor.IsConfirmed() {
+		return false, ErrFactorNotFound
+	}
+
+	if !factor.Verify(code, time.Now().UTC(), s.config.Skew) {
+		return false, nil
+	}
+
+	return s.repository.UseStep(ctx, factor)
+}
@@ -0,0 +1,427 @@
+package mfa
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/totp"
+	"github.com/google/uuid"
+)
+
+var testConfig = config.MFAConfig{
+	Issuer:               "Example",
+	Skew:                 1,
+	ChallengeTTL:         300,
+	ChallengeMaxAttempts: 3,
+	RecoveryCodeCount:    4,
+}
+
+func TestVerifyChallengeTOTP(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps are the offsets from now of the codes sent, in order; want
+		// tells whether each is accepted.
+		steps []int64
+		want  []bool
+	}{
+		{"current step", []int64{0}, []bool{true}},
+		{"previous step within skew", []int64{-1}, []bool{true}},
+		{"next step within skew", []int64{1}, []bool{true}},
+		{"two steps behind", []int64{-2}, []bool{false}},
+		{"two steps ahead", []int64{2}, []bool{false}},
+		{"replayed code", []int64{0, 0}, []bool{true, false}},
+		{"older code after a newer one", []int64{1, 0}, []bool{true, false}},
+		{"newer code after an older one", []int64{-1, 0}, []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			secret := env.confirmedFactor(t)
+
+			for i, offset := range tt.steps {
+				code := totp.Code(secret, totp.Step(time.Now())+offset)
+				userID, restErr := env.service.VerifyChallenge(context.Background(), env.challenge(t), Proof{Code: code})
+				if got := restErr == nil; got != tt.want[i] {
+					t.Fatalf("code %d accepted = %t, want %t (error %v)", i, got, tt.want[i], restErr)
+				}
+				if restErr == nil && userID != env.userID {
+					t.Fatalf("VerifyChallenge() = %s, want %s", userID, env.userID)
+				}
+				if restErr != nil && restErr.Code != http.StatusUnauthorized {
+					t.Fatalf("VerifyChallenge() error = %v, want 401", restErr)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyChallengeRefuses(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, env *testEnv) (string, Proof)
+	}{
+		{
+			name: "wrong code",
+			setup: func(t *testing.T, env *testEnv) (string, Proof) {
+				secret := env.confirmedFactor(t)
+				code := totp.Code(secret, totp.Step(time.Now()))
+				wrong := []byte(code)
+				wrong[0] = '0' + (wrong[0]-'0'+1)%10
+				return env.challenge(t), Proof{Code: string(wrong)}
+			},
+		},
+		{
+			name: "unconfirmed factor",
+			setup: func(t *testing.T, env *testEnv) (string, Proof) {
+				secret := env.enroll(t)
+				return env.challenge(t), Proof{Code: totp.Code(secret, totp.Step(time.Now()))}
+			},
+		},
+		{
+			name: "unknown challenge",
+			setup: func(t *testing.T, env *testEnv) (string, Proof) {
+				secret := env.confirmedFactor(t)
+				return "unknown", Proof{Code: totp.Code(secret, totp.Step(time.Now()))}
+			},
+		},
+		{
+			name: "challenge out of attempts",
+			setup: func(t *testing.T, env *testEnv) (string, Proof) {
+				secret := env.confirmedFactor(t)
+				challenge := env.challenge(t)
+				for i := 0; i < testConfig.ChallengeMaxAttempts; i++ {
+					_, _ = env.service.VerifyChallenge(context.Background(), challenge, Proof{Code: "000000"})
+				}
+				return challenge, Proof{Code: totp.Code(secret, totp.Step(time.Now()))}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			challenge, proof := tt.setup(t, env)
+
+			_, restErr := env.service.VerifyChallenge(context.Background(), challenge, proof)
+			if restErr == nil || restErr.Code != http.StatusUnauthorized {
+				t.Fatalf("VerifyChallenge() error = %v, want 401", restErr)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	codes := env.confirm(t, env.enroll(t))
+	if len(codes) != testConfig.RecoveryCodeCount {
+		t.Fatalf("Confirm() issued %d recovery codes, want %d", len(codes), testConfig.RecoveryCodeCount)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"as shown", codes[0], true},
+		{"used again", codes[0], false},
+		{"typed without the dash and in capitals", strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")), true},
+		{"unknown", "aaaaa-aaaaa", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, restErr := env.service.VerifyChallenge(context.Background(), env.challenge(t), Proof{RecoveryCode: tt.code})
+			if got := restErr == nil; got != tt.want {
+				t.Fatalf("recovery code accepted = %t, want %t (error %v)", got, tt.want, restErr)
+			}
+		})
+	}
+
+	if remaining := len(env.repository.recoveryCodes); remaining != testConfig.RecoveryCodeCount-2 {
+		t.Errorf("%d recovery codes left, want %d", remaining, testConfig.RecoveryCodeCount-2)
+	}
+	if len(env.events.Events) != 2 {
+		t.Errorf("emitted %d events, want one per recovery code used", len(env.events.Events))
+	}
+}
+
+func TestConfirmEnrollment(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       func(secret []byte) string
+		wantStatus int
+	}{
+		{"current code", func(secret []byte) string { return totp.Code(secret, totp.Step(time.Now())) }, 0},
+		{"code within skew", func(secret []byte) string { return totp.Code(secret, totp.Step(time.Now())-1) }, 0},
+		{"code out of skew", func(secret []byte) string { return totp.Code(secret, totp.Step(time.Now())-2) }, http.StatusBadRequest},
+		{"malformed code", func([]byte) string { return "12345" }, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			secret := env.enroll(t)
+
+			res, restErr := env.service.Confirm(context.Background(), env.userID, CodeRequest{Code: tt.code(secret)})
+			enrolled, _ := env.service.IsEnrolled(context.Background(), env.userID)
+
+			if tt.wantStatus != 0 {
+				if restErr == nil || restErr.Code != tt.wantStatus {
+					t.Fatalf("Confirm() error = %v, want %d", restErr, tt.wantStatus)
+				}
+				if enrolled || env.repository.factor.confirmedAt != nil {
+					t.Fatal("a refused code confirmed the factor")
+				}
+				return
+			}
+
+			if restErr != nil {
+				t.Fatalf("Confirm() error = %v", restErr)
+			}
+			if len(res.RecoveryCodes) != testConfig.RecoveryCodeCount || len(env.repository.recoveryCodes) != testConfig.RecoveryCodeCount {
+				t.Errorf("Confirm() issued %d recovery codes and stored %d, want %d",
+					len(res.RecoveryCodes), len(env.repository.recoveryCodes), testConfig.RecoveryCodeCount)
+			}
+			if !enrolled {
+				t.Error("IsEnrolled() = false after confirmation")
+			}
+
+			// The code that confirmed the factor cannot sign in.
+			_, restErr = env.service.VerifyChallenge(context.Background(), env.challenge(t), Proof{Code: tt.code(secret)})
+			if restErr == nil {
+				t.Error("the confirmation code was accepted again")
+			}
+		})
+	}
+}
+
+func TestConfirmRefuses(t *testing.T) {
+	t.Run("without enrollment", func(t *testing.T) {
+		env := newTestEnv(t)
+		_, restErr := env.service.Confirm(context.Background(), env.userID, CodeRequest{Code: "123456"})
+		if restErr == nil || restErr.Code != http.StatusBadRequest {
+			t.Fatalf("Confirm() error = %v, want 400", restErr)
+		}
+	})
+
+	t.Run("already confirmed", func(t *testing.T) {
+		env := newTestEnv(t)
+		secret := env.enroll(t)
+		env.confirm(t, secret)
+
+		_, restErr := env.service.Confirm(context.Background(), env.userID, CodeRequest{Code: totp.Code(secret, totp.Step(time.Now())+1)})
+		if restErr == nil || restErr.Code != http.StatusBadRequest {
+			t.Fatalf("Confirm() error = %v, want 400", restErr)
+		}
+		if _, restErr := env.service.Enroll(context.Background(), env.userID); restErr == nil {
+			t.Fatal("Enroll() replaced a confirmed factor")
+		}
+	})
+}
+
+type testEnv struct {
+	service    ServiceInterface
+	repository *fakeRepository
+	events     *testutil.Emitter
+	userID     uuid.UUID
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	account, err := domain.New("jane@example.com", "", "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+
+	env := &testEnv{
+		repository: &fakeRepository{recoveryCodes: map[string]bool{}, challenges: map[string]*challengeRow{}},
+		events:     &testutil.Emitter{},
+		userID:     account.GetID(),
+	}
+	env.service = NewService(
+		testConfig,
+		testutil.DB{},
+		env.repository,
+		testutil.NewUsers(account),
+		fakePasskeys{},
+		env.events,
+		&testutil.Recorder{},
+	)
+	return env
+}
+
+// enroll starts an enrollment and returns its secret.
+func (env *testEnv) enroll(t *testing.T) []byte {
+	t.Helper()
+
+	if _, restErr := env.service.Enroll(context.Background(), env.userID); restErr != nil {
+		t.Fatalf("Enroll() error = %v", restErr)
+	}
+	return env.repository.factor.secret
+}
+
+// confirm confirms the enrollment with the current code and returns the
+// recovery codes.
+func (env *testEnv) confirm(t *testing.T, secret []byte) []string {
+	t.Helper()
+
+	res, restErr := env.service.Confirm(context.Background(), env.userID, CodeRequest{Code: totp.Code(secret, totp.Step(time.Now()))})
+	if restErr != nil {
+		t.Fatalf("Confirm() error = %v", restErr)
+	}
+	return res.RecoveryCodes
+}
+
+// confirmedFactor stores a confirmed factor no code was used for yet and
+// returns its secret.
+func (env *testEnv) confirmedFactor(t *testing.T) []byte {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("totp.GenerateSecret() error = %v", err)
+	}
+	confirmedAt := time.Now().UTC()
+	env.repository.factor = &factorRow{id: uuid.New(), userID: env.userID, secret: secret, confirmedAt: &confirmedAt}
+	return secret
+}
+
+func (env *testEnv) challenge(t *testing.T) string {
+	t.Helper()
+
+	raw, _, restErr := env.service.CreateChallenge(context.Background(), env.userID, nil)
+	if restErr != nil {
+		t.Fatalf("CreateChallenge() error = %v", restErr)
+	}
+	return raw
+}
+
+type factorRow struct {
+	id           uuid.UUID
+	userID       uuid.UUID
+	secret       []byte
+	lastUsedStep int64
+	confirmedAt  *time.Time
+}
+
+type challengeRow struct {
+	challenge domain.MFAChallengeInterface
+	attempts  int
+	consumed  *time.Time
+}
+
+// fakeRepository keeps rows rather than the domain objects it is given, so
+// what the service reads back is what a database would return.
+type fakeRepository struct {
+	RepositoryInterface
+	factor        *factorRow
+	recoveryCodes map[string]bool
+	challenges    map[string]*challengeRow
+}
+
+func (r *fakeRepository) SaveFactor(_ context.Context, factor domain.TOTPFactorInterface) error {
+	if r.factor != nil && r.factor.confirmedAt != nil {
+		return ErrFactorAlreadyConfirmed
+	}
+	r.factor = &factorRow{id: factor.GetID(), userID: factor.GetUserID(), secret: factor.GetSecret()}
+	return nil
+}
+
+func (r *fakeRepository) FindFactorByUserID(_ context.Context, userID uuid.UUID) (domain.TOTPFactorInterface, error) {
+	if r.factor == nil || r.factor.userID != userID {
+		return nil, ErrFactorNotFound
+	}
+	f := r.factor
+	return domain.RestoreTOTPFactor(f.id, f.userID, f.secret, f.lastUsedStep, f.confirmedAt, time.Now()), nil
+}
+
+func (r *fakeRepository) ConfirmFactor(_ context.Context, factor domain.TOTPFactorInterface) error {
+	r.factor.confirmedAt = factor.GetConfirmedAt()
+	r.factor.lastUsedStep = factor.GetLastUsedStep()
+	return nil
+}
+
+func (r *fakeRepository) UseStep(_ context.Context, factor domain.TOTPFactorInterface) (bool, error) {
+	if r.factor.lastUsedStep >= factor.GetLastUsedStep() {
+		return false, nil
+	}
+	r.factor.lastUsedStep = factor.GetLastUsedStep()
+	return true, nil
+}
+
+func (r *fakeRepository) ReplaceRecoveryCodes(_ context.Context, _ uuid.UUID, codeHashes []string) error {
+	r.recoveryCodes = map[string]bool{}
+	for _, hash := range codeHashes {
+		r.recoveryCodes[hash] = true
+	}
+	return nil
+}
+
+func (r *fakeRepository) UseRecoveryCode(_ context.Context, _ uuid.UUID, codeHash string) (bool, error) {
+	if !r.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(r.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func (r *fakeRepository) CreateChallenge(_ context.Context, challenge domain.MFAChallengeInterface) error {
+	r.challenges[challenge.GetTokenHash()] = &challengeRow{challenge: challenge}
+	return nil
+}
+
+func (r *fakeRepository) FindChallenge(_ context.Context, tokenHash string) (domain.MFAChallengeInterface, error) {
+	row, ok := r.challenges[tokenHash]
+	if !ok {
+		return nil, ErrChallengeNotFound
+	}
+	c := row.challenge
+	return domain.RestoreMFAChallenge(c.GetID(), c.GetUserID(), c.GetOrgID(), c.GetTokenHash(), row.attempts, c.GetExpiresAt(), row.consumed, c.GetCreatedAt()), nil
+}
+
+func (r *fakeRepository) FindChallengeForUpdate(ctx context.Context, tokenHash string) (domain.MFAChallengeInterface, error) {
+	return r.FindChallenge(ctx, tokenHash)
+}
+
+func (r *fakeRepository) IncrementChallengeAttempts(_ context.Context, id uuid.UUID) error {
+	r.challengeByID(id).attempts++
+	return nil
+}
+
+func (r *fakeRepository) ConsumeChallenge(_ context.Context, id uuid.UUID) error {
+	now := time.Now().UTC()
+	r.challengeByID(id).consumed = &now
+	return nil
+}
+
+func (r *fakeRepository) DeleteExpiredChallenges(context.Context, time.Time) error {
+	return nil
+}
+
+func (r *fakeRepository) challengeByID(id uuid.UUID) *challengeRow {
+	for _, row := range r.challenges {
+		if row.challenge.GetID() == id {
+			return row
+		}
+	}
+	return &challengeRow{}
+}
+
+// fakePasskeys has no passkeys for anyone.
+type fakePasskeys struct {
+	passkey.ServiceInterface
+}
+
+func (fakePasskeys) HasPasskeys(context.Context, uuid.UUID) (bool, *httperr.HttpError) {
+	return false, nil
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin guards administrative endpoints with the static admin API
+// token. Leaving the token unset disables those endpoints.
+func RequireAdmin(config config.AdminConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if config.APIToken == "" {
+			restErr := httperr.NewForbiddenError("admin API is disabled")
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return
+		}
+
+		if !isAdminToken(ctx, config) {
+			abortUnauthorized(ctx, "invalid admin token")
+			return
+		}
+
+		actAsAdmin(ctx)
+		ctx.Next()
+	}
+}
+
+func isAdminToken(ctx *gin.Context, config config.AdminConfig) bool {
+	raw, ok := BearerToken(ctx)
+	return ok && config.APIToken != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(config.APIToken)) == 1
+}
+
+// actAsAdmin records the admin API token as the request's actor.
+func actAsAdmin(ctx *gin.Context) {
+	actor := requestinfo.Actor{Type: requestinfo.ActorAdmin, ID: "admin"}
+	ctx.Request = ctx.Request.WithContext(requestinfo.WithActor(ctx.Request.Context(), actor))
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const claimsKey = "token_claims"
+
+// Authenticate requires a valid bearer access token and stores its claims
+// on the gin context.
+func Authenticate(tokens token.ManagerInterface) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !authenticate(ctx, tokens) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func Claims(ctx *gin.Context) *token.Claims {
+	claims, _ := ctx.MustGet(claimsKey).(*token.Claims)
+	return claims
+}
+
+// CurrentUser returns the user behind a first-party access token. Tokens
+// issued to OAuth clients or service accounts act on the user's behalf only
+// within their scopes and cannot manage the account itself.
+func CurrentUser(ctx *gin.Context) (uuid.UUID, bool) {
+	claims := Claims(ctx)
+	if claims == nil || claims.ClientID != "" {
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// RequireUser must follow Authenticate and rejects tokens that CurrentUser
+// does not accept.
+func RequireUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := CurrentUser(ctx); !ok {
+			restErr := httperr.NewForbiddenError("a user access token is required")
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func BearerToken(ctx *gin.Context) (string, bool) {
+	header := ctx.GetHeader("Authorization")
+	scheme, value, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(value) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(value), true
+}
+
+// authenticate stores the bearer token's claims, or aborts and returns false.
+func authenticate(ctx *gin.Context, tokens token.ManagerInterface) bool {
+	raw, ok := BearerToken(ctx)
+	if !ok {
+		abortUnauthorized(ctx, "missing bearer token")
+		return false
+	}
+
+	claims, err := tokens.ParseAccessToken(ctx.Request.Context(), raw)
+	if err != nil {
+		abortUnauthorized(ctx, "invalid bearer token")
+		return false
+	}
+
+	ctx.Set(claimsKey, claims)
+
+	actor := requestinfo.Actor{Type: requestinfo.ActorUser, ID: claims.Subject}
+	if claims.ClientID != "" {
+		actor = requestinfo.Actor{Type: requestinfo.ActorClient, ID: claims.ClientID}
+	}
+	ctx.Request = ctx.Request.WithContext(requestinfo.WithActor(ctx.Request.Context(), actor))
+
+	return true
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	restErr := httperr.NewUnauthorizedRequestError(message)
+	ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	ctx.AbortWithStatusJSON(restErr.Code, restErr)
+}
@@ -0,0 +1,9 @@
+package middleware
+
+import "go.uber.org/fx"
+
+var Module = fx.Options(
+	fx.Provide(
+		NewClientResolver,
+	),
+)
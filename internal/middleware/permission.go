@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionCheckerInterface resolves role names to permissions; the RBAC
+// authorizer implements it.
+type PermissionCheckerInterface interface {
+	RolesAllow(ctx context.Context, roles []string, resource, action string) (bool, error)
+}
+
+// RequirePermission must follow Authenticate. User tokens are checked against
+// the roles they carry. Tokens issued to clients and service accounts are
+// checked against their scopes instead, which must include
+// "resource:action" literally.
+func RequirePermission(checker PermissionCheckerInterface, resource, action string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !authorize(ctx, checker, resource, action) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireAdminOrPermission accepts the static admin API token as a
+// superuser, which is how the first roles get assigned, and otherwise
+// authenticates the bearer token and requires the permission.
+func RequireAdminOrPermission(
+	admin config.AdminConfig,
+	tokens token.ManagerInterface,
+	checker PermissionCheckerInterface,
+	resource, action string,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if isAdminToken(ctx, admin) {
+			actAsAdmin(ctx)
+			ctx.Next()
+			return
+		}
+
+		if !authenticate(ctx, tokens) || !authorize(ctx, checker, resource, action) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func authorize(ctx *gin.Context, checker PermissionCheckerInterface, resource, action string) bool {
+	claims := Claims(ctx)
+
+	var allowed bool
+	if claims.ClientID != "" {
+		allowed = slices.Contains(strings.Fields(claims.Scope), resource+":"+action)
+	} else {
+		var err error
+		allowed, err = checker.RolesAllow(ctx.Request.Context(), claims.Roles, resource, action)
+		if err != nil {
+			slog.Error("failed to check permission", "error", err)
+			restErr := httperr.NewInternalServerError("failed to check permission")
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return false
+		}
+	}
+
+	if !allowed {
+		restErr := httperr.NewForbiddenError("missing permission " + resource + ":" + action)
+		ctx.AbortWithStatusJSON(restErr.Code, restErr)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"github.com/felipeversiane/auth-service/internal/infra/ratelimit"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/gin-gonic/gin"
+)
+
+type clientResolver struct {
+	tokens token.ManagerInterface
+}
+
+// NewClientResolver identifies OAuth clients and service accounts by the
+// client_id of a valid bearer token. Unverified client ids, such as the
+// ones sent to the token endpoint, are not trusted: anyone could spend
+// another client's quota with them.
+func NewClientResolver(tokens token.ManagerInterface) ratelimit.ClientResolverInterface {
+	return &clientResolver{
+		tokens: tokens,
+	}
+}
+
+func (r *clientResolver) ClientID(ctx *gin.Context) string {
+	raw, ok := BearerToken(ctx)
+	if !ok {
+		return ""
+	}
+
+	claims, err := r.tokens.ParseAccessToken(ctx.Request.Context(), raw)
+	if err != nil {
+		return ""
+	}
+
+	return claims.ClientID
+}
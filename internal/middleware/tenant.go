@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequireOrg must follow Authenticate. It requires an active organization in
+// the token and scopes the request context to it, so tenant-owned
+// repositories only ever see that organization's rows.
+func RequireOrg() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		orgID, ok := CurrentOrg(ctx)
+		if !ok {
+			restErr := httperr.NewForbiddenError("an active organization is required")
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(database.WithTenant(ctx.Request.Context(), orgID))
+		ctx.Next()
+	}
+}
+
+// CurrentOrg returns the organization a first-party access token is acting
+// in.
+func CurrentOrg(ctx *gin.Context) (uuid.UUID, bool) {
+	claims := Claims(ctx)
+	if claims == nil || claims.ClientID != "" || claims.OrgID == "" {
+		return uuid.Nil, false
+	}
+
+	orgID, err := uuid.Parse(claims.OrgID)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return orgID, true
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail must follow Authenticate. Unless the policy allows
+// unverified users everything, it keeps them away from account features such
+// as second factors. Tokens without the claim are treated as unverified.
+func RequireVerifiedEmail(account config.AccountConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if account.UnverifiedPolicy == config.UnverifiedPolicyAllow {
+			ctx.Next()
+			return
+		}
+
+		claims := Claims(ctx)
+		if claims == nil || claims.EmailVerified == nil || !*claims.EmailVerified {
+			restErr := httperr.NewForbiddenError("email address is not verified")
+			ctx.AbortWithStatusJSON(restErr.Code, restErr)
+			return
+		}
+
+		ctx.Next()
+	}
+}
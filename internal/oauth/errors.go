@@ -0,0 +1,49 @@
+package oauth
+
+import "net/http"
+
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorInvalidTarget           = "invalid_target"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorUnsupportedTokenType    = "unsupported_token_type"
+	ErrorInvalidRedirectURI      = "invalid_redirect_uri"
+	ErrorInvalidClientMetadata   = "invalid_client_metadata"
+	ErrorAccessDenied            = "access_denied"
+	ErrorLoginRequired           = "login_required"
+	ErrorInsufficientScope       = "insufficient_scope"
+	ErrorServerError             = "server_error"
+)
+
+// Error is the RFC 6749 error response. OAuth endpoints use it instead of
+// httperr.HttpError because clients parse the error code.
+type Error struct {
+	Status      int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Description
+}
+
+func newInvalidRequestError(description string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: ErrorInvalidRequest, Description: description}
+}
+
+func newInvalidClientError(description string) *Error {
+	return &Error{Status: http.StatusUnauthorized, Code: ErrorInvalidClient, Description: description}
+}
+
+func newInvalidGrantError(description string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: ErrorInvalidGrant, Description: description}
+}
+
+func newServerError() *Error {
+	return &Error{Status: http.StatusInternalServerError, Code: ErrorServerError, Description: "internal server error"}
+}
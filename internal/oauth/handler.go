@@ -0,0 +1,374 @@
+package oauth
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+//go:embed static/passkey.js
+var passkeyScript []byte
+
+type loginPage struct {
+	ClientName string
+	Request    AuthorizeRequest
+	Email      string
+	Error      string
+	// MFAToken switches the page to the second factor step.
+	MFAToken   string
+	RestartURL string
+}
+
+type handler struct {
+	config     config.OAuthConfig
+	keysConfig config.KeysConfig
+	service    ServiceInterface
+	tokens     token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Discovery(ctx *gin.Context)
+	AuthorizeForm(ctx *gin.Context)
+	Authorize(ctx *gin.Context)
+	PasskeyScript(ctx *gin.Context)
+	Token(ctx *gin.Context)
+	UserInfo(ctx *gin.Context)
+	Introspect(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+	RegisterClient(ctx *gin.Context)
+}
+
+func NewHandler(
+	config config.OAuthConfig,
+	keysConfig config.KeysConfig,
+	service ServiceInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		config:     config,
+		keysConfig: keysConfig,
+		service:    service,
+		tokens:     tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/.well-known/openid-configuration", h.Discovery)
+	router.GET("/authorize", h.AuthorizeForm)
+	router.POST("/authorize", h.Authorize)
+	router.GET("/authorize/passkey.js", h.PasskeyScript)
+	router.POST("/token", h.Token)
+	router.POST("/oauth/introspect", h.Introspect)
+	router.POST("/oauth/revoke", h.Revoke)
+	router.POST("/oauth/register", h.RegisterClient)
+
+	userInfo := router.Group("/userinfo", middleware.Authenticate(h.tokens))
+	{
+		userInfo.GET("", h.UserInfo)
+		userInfo.POST("", h.UserInfo)
+	}
+}
+
+func (h *handler) Discovery(ctx *gin.Context) {
+	issuer := strings.TrimSuffix(h.config.Issuer, "/")
+
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.JSON(http.StatusOK, DiscoveryResponse{
+		Issuer:                           issuer,
+		AuthorizationEndpoint:            issuer + "/authorize",
+		TokenEndpoint:                    issuer + "/token",
+		UserInfoEndpoint:                 issuer + "/userinfo",
+		JWKSURI:                          issuer + "/.well-known/jwks.json",
+		RegistrationEndpoint:             issuer + "/oauth/register",
+		IntrospectionEndpoint:            issuer + "/oauth/introspect",
+		RevocationEndpoint:               issuer + "/oauth/revoke",
+		ScopesSupported:                  supportedScopes,
+		ResponseTypesSupported:           []string{"code"},
+		ResponseModesSupported:           []string{"query"},
+		GrantTypesSupported:              []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{h.keysConfig.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{
+			AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodPrivateKeyJWT, AuthMethodNone,
+		},
+		TokenEndpointAuthSigningAlgs: []string{keys.AlgorithmRS256, keys.AlgorithmES256, keys.AlgorithmEdDSA},
+		IntrospectionAuthMethodsSupported: []string{
+			AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodPrivateKeyJWT,
+		},
+		RevocationAuthMethodsSupported: []string{
+			AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodPrivateKeyJWT, AuthMethodNone,
+		},
+		CodeChallengeMethodsSupported: []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"email", "email_verified", "name", "given_name", "family_name", "phone_number",
+		},
+		AuthorizationResponseIssParameter: true,
+	})
+}
+
+func (h *handler) AuthorizeForm(ctx *gin.Context) {
+	var req AuthorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		h.renderError(ctx, newInvalidRequestError("malformed authorization request"))
+		return
+	}
+
+	client, oauthErr := h.service.ValidateAuthorize(ctx.Request.Context(), req)
+	if oauthErr != nil {
+		h.handleAuthorizeError(ctx, req, oauthErr, client != nil)
+		return
+	}
+
+	h.render(ctx, http.StatusOK, "login.html", loginPage{
+		ClientName: client.GetName(),
+		Request:    req,
+	})
+}
+
+func (h *handler) Authorize(ctx *gin.Context) {
+	var form LoginForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		h.renderError(ctx, newInvalidRequestError("malformed authorization request"))
+		return
+	}
+
+	client, oauthErr := h.service.ValidateAuthorize(ctx.Request.Context(), form.AuthorizeRequest)
+	if oauthErr != nil {
+		h.handleAuthorizeError(ctx, form.AuthorizeRequest, oauthErr, client != nil)
+		return
+	}
+
+	location, challenge, restErr := h.service.Authorize(ctx.Request.Context(), form)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		status := restErr.Code
+		message := restErr.Message
+		if status >= http.StatusInternalServerError {
+			message = "something went wrong, please try again"
+		}
+		h.render(ctx, status, "login.html", loginPage{
+			ClientName: client.GetName(),
+			Request:    form.AuthorizeRequest,
+			Email:      form.Email,
+			Error:      message,
+			MFAToken:   form.MFAToken,
+			RestartURL: restartURL(form.AuthorizeRequest),
+		})
+		return
+	}
+
+	if challenge != "" {
+		h.render(ctx, http.StatusOK, "login.html", loginPage{
+			ClientName: client.GetName(),
+			Request:    form.AuthorizeRequest,
+			MFAToken:   challenge,
+			RestartURL: restartURL(form.AuthorizeRequest),
+		})
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, location)
+}
+
+func (h *handler) PasskeyScript(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.Data(http.StatusOK, "text/javascript; charset=utf-8", passkeyScript)
+}
+
+func (h *handler) Token(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req TokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		writeError(ctx, newInvalidRequestError("malformed token request"))
+		return
+	}
+
+	basicID, basicSecret := basicCredentials(ctx)
+
+	res, oauthErr := h.service.Token(ctx.Request.Context(), req, basicID, basicSecret)
+	if oauthErr != nil {
+		writeError(ctx, oauthErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) UserInfo(ctx *gin.Context) {
+	res, oauthErr := h.service.UserInfo(ctx.Request.Context(), middleware.Claims(ctx))
+	if oauthErr != nil {
+		ctx.Header("WWW-Authenticate", `Bearer error="`+oauthErr.Code+`"`)
+		writeError(ctx, oauthErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Introspect(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+
+	var req IntrospectionRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		writeError(ctx, newInvalidRequestError("malformed introspection request"))
+		return
+	}
+
+	basicID, basicSecret := basicCredentials(ctx)
+
+	res, oauthErr := h.service.Introspect(ctx.Request.Context(), req, basicID, basicSecret)
+	if oauthErr != nil {
+		writeError(ctx, oauthErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Revoke(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+
+	var req RevocationRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		writeError(ctx, newInvalidRequestError("malformed revocation request"))
+		return
+	}
+
+	basicID, basicSecret := basicCredentials(ctx)
+
+	if oauthErr := h.service.Revoke(ctx.Request.Context(), req, basicID, basicSecret); oauthErr != nil {
+		writeError(ctx, oauthErr)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (h *handler) RegisterClient(ctx *gin.Context) {
+	raw, ok := middleware.BearerToken(ctx)
+	if !ok || !h.service.CheckRegistrationToken(raw) {
+		restErr := httperr.NewUnauthorizedRequestError("a valid initial access token is required")
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	var req RegisterClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.RegisterClient(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) handleAuthorizeError(ctx *gin.Context, req AuthorizeRequest, oauthErr *Error, redirectable bool) {
+	if !redirectable {
+		h.renderError(ctx, oauthErr)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, h.service.RedirectURL(req.RedirectURI, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+		"state":             {req.State},
+	}))
+}
+
+func (h *handler) renderError(ctx *gin.Context, oauthErr *Error) {
+	status := oauthErr.Status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	h.render(ctx, status, "error.html", oauthErr)
+}
+
+func (h *handler) render(ctx *gin.Context, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.Error("failed to render template", "template", name, "error", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; script-src 'self'; connect-src 'self'; form-action 'self'; frame-ancestors 'none'")
+	ctx.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// restartURL points back at the authorization request so a user whose MFA
+// challenge expired can sign in again.
+func restartURL(req AuthorizeRequest) string {
+	query := url.Values{}
+	query.Set("response_type", req.ResponseType)
+	query.Set("client_id", req.ClientID)
+	query.Set("redirect_uri", req.RedirectURI)
+	query.Set("scope", req.Scope)
+	query.Set("state", req.State)
+	query.Set("nonce", req.Nonce)
+	query.Set("code_challenge", req.CodeChallenge)
+	query.Set("code_challenge_method", req.CodeChallengeMethod)
+
+	return "/authorize?" + query.Encode()
+}
+
+func writeError(ctx *gin.Context, oauthErr *Error) {
+	if oauthErr.Code == ErrorInvalidClient {
+		ctx.Header("WWW-Authenticate", `Basic realm="token"`)
+	}
+	status := oauthErr.Status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	ctx.JSON(status, oauthErr)
+}
+
+// basicCredentials decodes client_secret_basic credentials, which are form
+// encoded before being base64 encoded (RFC 6749 section 2.3.1).
+func basicCredentials(ctx *gin.Context) (string, string) {
+	id, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return "", ""
+	}
+
+	decodedID, err := url.QueryUnescape(id)
+	if err != nil {
+		return "", ""
+	}
+	decodedSecret, err := url.QueryUnescape(secret)
+	if err != nil {
+		return "", ""
+	}
+
+	return decodedID, decodedSecret
+}
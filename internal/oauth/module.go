@@ -0,0 +1,14 @@
+package oauth
+
+import (
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewService,
+		httpserver.AsRouter(NewHandler),
+	),
+)
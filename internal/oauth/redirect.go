@@ -0,0 +1,36 @@
+package oauth
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// validRedirectURI applies the redirect URI rules of RFC 8252 section 7:
+// https anywhere, http only to the loopback interface, and, for native
+// apps, which register as public clients, a private-use scheme named after
+// a domain they control, such as com.example.app. Anything else, including
+// javascript: and data:, is refused.
+func validRedirectURI(raw string, public bool) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Fragment != "" || parsed.Scheme == "" {
+		return false
+	}
+
+	switch scheme := strings.ToLower(parsed.Scheme); scheme {
+	case "https":
+		return parsed.Host != ""
+	case "http":
+		return isLoopback(parsed.Hostname())
+	default:
+		return public && strings.Contains(scheme, ".") && parsed.Opaque == ""
+	}
+}
+
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,39 @@
+package oauth
+
+import "testing"
+
+func TestValidRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri    string
+		public bool
+		want   bool
+	}{
+		{"https://app.example.com/callback", false, true},
+		{"https://app.example.com/callback?x=1", true, true},
+		{"HTTPS://app.example.com/callback", false, true},
+		{"http://127.0.0.1:8080/callback", true, true},
+		{"http://[::1]/callback", false, true},
+		{"http://localhost:3000/callback", false, true},
+		{"com.example.app:/oauth2redirect", true, true},
+
+		{"http://app.example.com/callback", false, false},
+		{"http://127.0.0.1.example.com/callback", false, false},
+		{"https:///callback", false, false},
+		{"https://app.example.com/callback#frag", false, false},
+		{"com.example.app:/oauth2redirect", false, false},
+		{"com.example.app:oauth2redirect", true, false},
+		{"myapp:/callback", true, false},
+		{"javascript:alert(document.cookie)", true, false},
+		{"JavaScript://app.example.com/%0aalert(1)", true, false},
+		{"data:text/html;base64,PHNjcmlwdD4=", true, false},
+		{"file:///etc/passwd", true, false},
+		{"/relative/callback", true, false},
+		{"", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := validRedirectURI(tt.uri, tt.public); got != tt.want {
+			t.Errorf("validRedirectURI(%q, public=%v) = %v, want %v", tt.uri, tt.public, got, tt.want)
+		}
+	}
+}
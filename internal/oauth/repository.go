@@ -0,0 +1,168 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrClientNotFound = errors.New("oauth client not found")
+	ErrCodeNotFound   = errors.New("authorization code not found")
+)
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	CreateClient(ctx context.Context, client domain.ClientInterface) error
+	FindClientByClientID(ctx context.Context, clientID string) (domain.ClientInterface, error)
+	CreateCode(ctx context.Context, code domain.AuthorizationCodeInterface) error
+	ConsumeCode(ctx context.Context, codeHash string) (domain.AuthorizationCodeInterface, error)
+	DeleteExpiredCodes(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateClient(ctx context.Context, client domain.ClientInterface) error {
+	query := `
+		INSERT INTO oauth_clients (id, client_id, secret_hash, name, redirect_uris, public, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		client.GetID(),
+		client.GetClientID(),
+		nullableString(client.GetSecretHash()),
+		client.GetName(),
+		client.GetRedirectURIs(),
+		client.IsPublic(),
+		client.GetCreatedAt(),
+		client.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert oauth client: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindClientByClientID(ctx context.Context, clientID string) (domain.ClientInterface, error) {
+	query := `
+		SELECT id, client_id, secret_hash, name, redirect_uris, public, created_at, updated_at
+		FROM oauth_clients
+		WHERE client_id = $1`
+
+	var (
+		id                   uuid.UUID
+		foundClientID, name  string
+		secretHash           *string
+		redirectURIs         []string
+		public               bool
+		createdAt, updatedAt time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, clientID).Scan(
+		&id, &foundClientID, &secretHash, &name, &redirectURIs, &public, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrClientNotFound
+		}
+		return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+	}
+
+	return domain.RestoreClient(id, foundClientID, valueOrEmpty(secretHash), name, redirectURIs, public, createdAt, updatedAt), nil
+}
+
+func (r *repository) CreateCode(ctx context.Context, code domain.AuthorizationCodeInterface) error {
+	query := `
+		INSERT INTO authorization_codes (
+			id, code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, auth_time, expires_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		code.GetID(),
+		code.GetCodeHash(),
+		code.GetClientID(),
+		code.GetUserID(),
+		code.GetRedirectURI(),
+		code.GetScope(),
+		nullableString(code.GetNonce()),
+		code.GetCodeChallenge(),
+		code.GetAuthTime(),
+		code.GetExpiresAt(),
+		code.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert authorization code: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeCode marks the code as used and returns it in a single statement so
+// that concurrent exchanges of the same code cannot both succeed.
+func (r *repository) ConsumeCode(ctx context.Context, codeHash string) (domain.AuthorizationCodeInterface, error) {
+	query := `
+		UPDATE authorization_codes
+		SET used_at = $2
+		WHERE code_hash = $1 AND used_at IS NULL
+		RETURNING id, code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge,
+			auth_time, expires_at, used_at, created_at`
+
+	var (
+		id, userID                                    uuid.UUID
+		hash, clientID, redirectURI, scope, challenge string
+		nonce                                         *string
+		authTime, expiresAt, createdAt                time.Time
+		usedAt                                        *time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, codeHash, time.Now().UTC()).Scan(
+		&id, &hash, &clientID, &userID, &redirectURI, &scope, &nonce, &challenge,
+		&authTime, &expiresAt, &usedAt, &createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to consume authorization code: %w", err)
+	}
+
+	return domain.RestoreAuthorizationCode(
+		id, hash, clientID, userID, redirectURI, scope, valueOrEmpty(nonce), challenge, authTime, expiresAt, usedAt, createdAt,
+	), nil
+}
+
+func (r *repository) DeleteExpiredCodes(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM authorization_codes WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired authorization codes: %w", err)
+	}
+	return nil
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
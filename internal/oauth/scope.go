@@ -0,0 +1,32 @@
+package oauth
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopePhone         = "phone"
+	ScopeOfflineAccess = "offline_access"
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone, ScopeOfflineAccess}
+
+// grantableScope keeps the supported scopes of a request, in request order,
+// dropping duplicates and anything we do not know about.
+func grantableScope(requested string) string {
+	granted := make([]string, 0, len(supportedScopes))
+	for _, scope := range strings.Fields(requested) {
+		if slices.Contains(supportedScopes, scope) && !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return strings.Join(granted, " ")
+}
+
+func hasScope(scope, want string) bool {
+	return slices.Contains(strings.Fields(scope), want)
+}
@@ -0,0 +1,741 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/mfa"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/serviceaccount"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	AuthMethodClientSecretPost  = "client_secret_post"
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"
+	AuthMethodNone              = "none"
+
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+type service struct {
+	config      config.OAuthConfig
+	tokenConfig config.TokenConfig
+	db          database.DatabaseInterface
+	repository  RepositoryInterface
+	users       user.RepositoryInterface
+	auth        auth.ServiceInterface
+	accounts    serviceaccount.ServiceInterface
+	tokens      token.ManagerInterface
+	audit       audit.RecorderInterface
+}
+
+type ServiceInterface interface {
+	ValidateAuthorize(ctx context.Context, req AuthorizeRequest) (domain.ClientInterface, *Error)
+	Authorize(ctx context.Context, form LoginForm) (string, string, *httperr.HttpError)
+	Token(ctx context.Context, req TokenRequest, basicID, basicSecret string) (*TokenResponse, *Error)
+	UserInfo(ctx context.Context, claims *token.Claims) (*UserInfoResponse, *Error)
+	Introspect(ctx context.Context, req IntrospectionRequest, basicID, basicSecret string) (*IntrospectionResponse, *Error)
+	Revoke(ctx context.Context, req RevocationRequest, basicID, basicSecret string) *Error
+	RegisterClient(ctx context.Context, req RegisterClientRequest) (*RegisterClientResponse, *httperr.HttpError)
+	CheckRegistrationToken(raw string) bool
+	RedirectURL(redirectURI string, params url.Values) string
+}
+
+func NewService(
+	config config.OAuthConfig,
+	tokenConfig config.TokenConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	auth auth.ServiceInterface,
+	accounts serviceaccount.ServiceInterface,
+	tokens token.ManagerInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:      config,
+		tokenConfig: tokenConfig,
+		db:          db,
+		repository:  repository,
+		users:       users,
+		auth:        auth,
+		accounts:    accounts,
+		tokens:      tokens,
+		audit:       audit,
+	}
+}
+
+// ValidateAuthorize checks an authorization request. Errors with a nil client
+// must be shown to the user; any other error is sent back to the redirect URI.
+func (s *service) ValidateAuthorize(ctx context.Context, req AuthorizeRequest) (domain.ClientInterface, *Error) {
+	client, err := s.repository.FindClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		if !errors.Is(err, ErrClientNotFound) {
+			slog.Error("failed to look up oauth client", "error", err)
+			return nil, newServerError()
+		}
+		return nil, newInvalidRequestError("unknown client_id")
+	}
+
+	if req.RedirectURI == "" || !client.HasRedirectURI(req.RedirectURI) {
+		return nil, newInvalidRequestError("redirect_uri is not registered for this client")
+	}
+
+	if req.ResponseType != "code" {
+		return client, &Error{Code: ErrorUnsupportedResponseType, Description: "only the code response type is supported"}
+	}
+
+	if req.CodeChallenge == "" {
+		return client, newInvalidRequestError("code_challenge is required")
+	}
+
+	if req.CodeChallengeMethod != domain.CodeChallengeMethodS256 {
+		return client, newInvalidRequestError("code_challenge_method must be S256")
+	}
+
+	if grantableScope(req.Scope) == "" {
+		return client, &Error{Code: ErrorInvalidScope, Description: "no supported scope was requested"}
+	}
+
+	if req.Prompt == "none" {
+		return client, &Error{Code: ErrorLoginRequired, Description: "interactive login is required"}
+	}
+
+	return client, nil
+}
+
+// Authorize authenticates the resource owner from the login form and returns
+// the URL to redirect the user agent to. Users with a second factor get an
+// MFA challenge token instead, and the form is posted again with their code.
+func (s *service) Authorize(ctx context.Context, form LoginForm) (string, string, *httperr.HttpError) {
+	userID, challenge, restErr := s.authenticateOwner(ctx, form)
+	if restErr != nil || challenge != "" {
+		return "", challenge, restErr
+	}
+
+	code, raw := domain.NewAuthorizationCode(
+		form.ClientID,
+		userID,
+		form.RedirectURI,
+		grantableScope(form.Scope),
+		form.Nonce,
+		form.CodeChallenge,
+		time.Duration(s.config.AuthorizationCodeTTL)*time.Second,
+	)
+
+	if err := s.repository.CreateCode(ctx, code); err != nil {
+		slog.Error("failed to store authorization code", "error", err)
+		return "", "", httperr.NewInternalServerError("failed to authorize")
+	}
+
+	if err := s.repository.DeleteExpiredCodes(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up authorization codes", "error", err)
+	}
+
+	return s.RedirectURL(form.RedirectURI, url.Values{
+		"code":  {raw},
+		"state": {form.State},
+	}), "", nil
+}
+
+func (s *service) Token(ctx context.Context, req TokenRequest, basicID, basicSecret string) (*TokenResponse, *Error) {
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		client, oauthErr := s.authenticateClient(ctx, req, basicID, basicSecret)
+		if oauthErr != nil {
+			return nil, oauthErr
+		}
+		return s.exchangeCode(ctx, client, req)
+	case GrantTypeRefreshToken:
+		client, oauthErr := s.authenticateClient(ctx, req, basicID, basicSecret)
+		if oauthErr != nil {
+			return nil, oauthErr
+		}
+		return s.refresh(ctx, client, req)
+	case GrantTypeClientCredentials:
+		return s.clientCredentials(ctx, req, basicID, basicSecret)
+	case "":
+		return nil, newInvalidRequestError("grant_type is required")
+	default:
+		return nil, &Error{Status: http.StatusBadRequest, Code: ErrorUnsupportedGrantType, Description: "unsupported grant_type"}
+	}
+}
+
+func (s *service) UserInfo(ctx context.Context, claims *token.Claims) (*UserInfoResponse, *Error) {
+	if !hasScope(claims.Scope, ScopeOpenID) {
+		return nil, &Error{Status: http.StatusForbidden, Code: ErrorInsufficientScope, Description: "the openid scope is required"}
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, &Error{Status: http.StatusUnauthorized, Code: "invalid_token", Description: "invalid subject"}
+	}
+
+	found, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, &Error{Status: http.StatusUnauthorized, Code: "invalid_token", Description: "unknown subject"}
+		}
+		slog.Error("failed to load user for userinfo", "error", err)
+		return nil, newServerError()
+	}
+
+	idClaims := profileClaims(found, claims.Scope)
+	return &UserInfoResponse{
+		Subject:       found.GetID().String(),
+		Email:         idClaims.Email,
+		EmailVerified: idClaims.EmailVerified,
+		Name:          idClaims.Name,
+		GivenName:     idClaims.GivenName,
+		FamilyName:    idClaims.FamilyName,
+		PhoneNumber:   idClaims.PhoneNumber,
+	}, nil
+}
+
+// Introspect reports whether a token is active (RFC 7662). Only confidential
+// clients and service accounts may call it; access tokens issued for any
+// audience are accepted so resource servers can check their own tokens.
+func (s *service) Introspect(ctx context.Context, req IntrospectionRequest, basicID, basicSecret string) (*IntrospectionResponse, *Error) {
+	if _, oauthErr := s.authenticateCaller(ctx, req.ClientAuthentication, basicID, basicSecret, false); oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	if req.Token == "" {
+		return nil, newInvalidRequestError("token is required")
+	}
+
+	if req.TokenTypeHint == TokenTypeHintRefreshToken {
+		if res := s.introspectRefreshToken(ctx, req.Token); res != nil {
+			return res, nil
+		}
+		return s.introspectAccessToken(ctx, req.Token), nil
+	}
+
+	if res := s.introspectAccessToken(ctx, req.Token); res.Active {
+		return res, nil
+	}
+	if res := s.introspectRefreshToken(ctx, req.Token); res != nil {
+		return res, nil
+	}
+	return &IntrospectionResponse{Active: false}, nil
+}
+
+// Revoke invalidates a token issued to the calling client (RFC 7009). Unknown
+// tokens and tokens owned by other clients succeed without effect.
+func (s *service) Revoke(ctx context.Context, req RevocationRequest, basicID, basicSecret string) *Error {
+	clientID, oauthErr := s.authenticateCaller(ctx, req.ClientAuthentication, basicID, basicSecret, true)
+	if oauthErr != nil {
+		return oauthErr
+	}
+
+	if req.Token == "" {
+		return newInvalidRequestError("token is required")
+	}
+
+	claims, err := s.tokens.IntrospectAccessToken(ctx, req.Token)
+	if err == nil {
+		if claims.ClientID != clientID {
+			return nil
+		}
+		// The denylist also caches the entry in memory, so the audit entry is
+		// written first and a failure leaves nothing half done.
+		err := s.db.WithTx(ctx, func(ctx context.Context) error {
+			err := s.audit.Record(ctx, audit.Entry{
+				Action:     audit.ActionTokenRevoke,
+				Outcome:    domain.AuditOutcomeSuccess,
+				TargetType: audit.TargetToken,
+				TargetID:   claims.ID,
+				Actor:      &requestinfo.Actor{Type: requestinfo.ActorClient, ID: clientID},
+				Metadata:   map[string]string{"token_type": TokenTypeHintAccessToken, "subject": claims.Subject},
+			})
+			if err != nil {
+				return err
+			}
+			return s.tokens.RevokeAccessToken(ctx, claims)
+		})
+		if err != nil {
+			slog.Error("failed to revoke access token", "error", err)
+			return newServerError()
+		}
+		return nil
+	}
+
+	if restErr := s.auth.RevokeRefreshToken(ctx, req.Token, clientID); restErr != nil {
+		return newServerError()
+	}
+
+	return nil
+}
+
+func (s *service) RegisterClient(ctx context.Context, req RegisterClientRequest) (*RegisterClientResponse, *httperr.HttpError) {
+	method := req.TokenEndpointAuthMethod
+	if method == "" {
+		method = AuthMethodClientSecretBasic
+	}
+	if method != AuthMethodClientSecretBasic && method != AuthMethodClientSecretPost && method != AuthMethodNone {
+		return nil, httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+			{Field: "token_endpoint_auth_method", Message: "is not supported"},
+		})
+	}
+
+	for _, redirectURI := range req.RedirectURIs {
+		if !validRedirectURI(redirectURI, method == AuthMethodNone) {
+			return nil, httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+				{Field: "redirect_uris", Message: "must be https, http on loopback, or a private-use scheme for public clients, without a fragment"},
+			})
+		}
+	}
+
+	client, secret := domain.NewClient(strings.TrimSpace(req.ClientName), req.RedirectURIs, method == AuthMethodNone)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateClient(ctx, client); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionClientRegister,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetClient,
+			TargetID:   client.GetClientID(),
+			Metadata:   map[string]string{"client_name": client.GetName()},
+		})
+	})
+	if err != nil {
+		slog.Error("failed to register oauth client", "error", err)
+		return nil, httperr.NewInternalServerError("failed to register client")
+	}
+
+	slog.Info("oauth client registered", slog.String("client_id", client.GetClientID()))
+
+	return &RegisterClientResponse{
+		ClientID:                client.GetClientID(),
+		ClientSecret:            secret,
+		ClientIDIssuedAt:        client.GetCreatedAt().Unix(),
+		ClientName:              client.GetName(),
+		RedirectURIs:            client.GetRedirectURIs(),
+		TokenEndpointAuthMethod: method,
+		GrantTypes:              []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		ResponseTypes:           []string{"code"},
+	}, nil
+}
+
+// CheckRegistrationToken validates the RFC 7591 initial access token. An
+// empty configuration disables dynamic registration entirely.
+func (s *service) CheckRegistrationToken(raw string) bool {
+	if s.config.RegistrationToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(raw), []byte(s.config.RegistrationToken)) == 1
+}
+
+// RedirectURL appends response parameters to a registered redirect URI,
+// including the RFC 9207 iss parameter.
+func (s *service) RedirectURL(redirectURI string, params url.Values) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	query.Set("iss", s.config.Issuer)
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+func (s *service) authenticateClient(ctx context.Context, req TokenRequest, basicID, basicSecret string) (domain.ClientInterface, *Error) {
+	clientID, secret := req.ClientID, req.ClientSecret
+	if basicID != "" {
+		if clientID != "" && clientID != basicID {
+			return nil, newInvalidRequestError("client_id does not match the authorization header")
+		}
+		clientID, secret = basicID, basicSecret
+	}
+
+	if clientID == "" {
+		return nil, newInvalidClientError("client authentication is required")
+	}
+
+	client, err := s.repository.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		if !errors.Is(err, ErrClientNotFound) {
+			slog.Error("failed to look up oauth client", "error", err)
+			return nil, newServerError()
+		}
+		return nil, newInvalidClientError("client authentication failed")
+	}
+
+	if client.IsPublic() {
+		if secret != "" {
+			return nil, newInvalidClientError("public clients must not send a secret")
+		}
+		return client, nil
+	}
+
+	if !client.CompareSecret(secret) {
+		return nil, newInvalidClientError("client authentication failed")
+	}
+
+	return client, nil
+}
+
+func (s *service) authenticateOwner(ctx context.Context, form LoginForm) (uuid.UUID, string, *httperr.HttpError) {
+	if form.MFAToken != "" {
+		proof := mfa.Proof{Code: form.OTP, RecoveryCode: form.RecoveryCode}
+		if form.Passkey != "" {
+			proof.Passkey = &passkey.FinishLoginRequest{
+				SessionToken: form.PasskeySession,
+				Credential:   json.RawMessage(form.Passkey),
+			}
+		}
+
+		userID, restErr := s.auth.VerifySecondFactor(ctx, form.MFAToken, proof)
+		return userID, "", restErr
+	}
+
+	found, restErr := s.auth.Authenticate(ctx, form.Email, form.Password)
+	if restErr != nil {
+		return uuid.Nil, "", restErr
+	}
+
+	challenge, _, restErr := s.auth.RequireSecondFactor(ctx, found)
+	if restErr != nil || challenge != "" {
+		return uuid.Nil, challenge, restErr
+	}
+
+	return found.GetID(), "", nil
+}
+
+// authenticateCaller authenticates either a registered OAuth client or a
+// service account and returns its client_id. Public clients are only
+// accepted when allowPublic is set.
+func (s *service) authenticateCaller(
+	ctx context.Context,
+	auth ClientAuthentication,
+	basicID, basicSecret string,
+	allowPublic bool,
+) (string, *Error) {
+	if auth.ClientAssertionType == "" {
+		client, oauthErr := s.authenticateClient(ctx, TokenRequest{
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+		}, basicID, basicSecret)
+		if oauthErr == nil {
+			if client.IsPublic() && !allowPublic {
+				return "", newInvalidClientError("public clients cannot use this endpoint")
+			}
+			return client.GetClientID(), nil
+		}
+		if oauthErr.Code != ErrorInvalidClient {
+			return "", oauthErr
+		}
+	}
+
+	credentials := serviceaccount.Credentials{
+		ClientID:      auth.ClientID,
+		ClientSecret:  auth.ClientSecret,
+		AssertionType: auth.ClientAssertionType,
+		Assertion:     auth.ClientAssertion,
+	}
+	if basicID != "" {
+		credentials.ClientID, credentials.ClientSecret = basicID, basicSecret
+	}
+
+	account, err := s.accounts.Authenticate(ctx, credentials)
+	if err != nil {
+		if errors.Is(err, serviceaccount.ErrInvalidCredentials) {
+			return "", newInvalidClientError("client authentication failed")
+		}
+		slog.Error("failed to authenticate service account", "error", err)
+		return "", newServerError()
+	}
+
+	return account.GetClientID(), nil
+}
+
+func (s *service) introspectAccessToken(ctx context.Context, raw string) *IntrospectionResponse {
+	claims, err := s.tokens.IntrospectAccessToken(ctx, raw)
+	if err != nil {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	res := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		JWTID:     claims.ID,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		res.NotBefore = claims.NotBefore.Unix()
+	}
+
+	return res
+}
+
+// introspectRefreshToken returns nil when the token is not a known refresh
+// token, so the caller can fall back to other token types.
+func (s *service) introspectRefreshToken(ctx context.Context, raw string) *IntrospectionResponse {
+	found, restErr := s.auth.FindRefreshToken(ctx, raw)
+	if restErr != nil {
+		return nil
+	}
+
+	if found.IsRevoked() || found.IsRotated() || found.IsExpired(time.Now().UTC()) {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     found.GetScope(),
+		ClientID:  found.GetClientID(),
+		TokenType: TokenTypeHintRefreshToken,
+		Subject:   found.GetUserID().String(),
+		Issuer:    s.config.Issuer,
+		ExpiresAt: found.GetExpiresAt().Unix(),
+		IssuedAt:  found.GetCreatedAt().Unix(),
+	}
+}
+
+func (s *service) exchangeCode(ctx context.Context, client domain.ClientInterface, req TokenRequest) (*TokenResponse, *Error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, newInvalidRequestError("code and code_verifier are required")
+	}
+
+	code, err := s.repository.ConsumeCode(ctx, domain.HashOpaqueToken(req.Code))
+	if err != nil {
+		if errors.Is(err, ErrCodeNotFound) {
+			return nil, newInvalidGrantError("invalid authorization code")
+		}
+		slog.Error("failed to consume authorization code", "error", err)
+		return nil, newServerError()
+	}
+
+	if code.GetClientID() != client.GetClientID() ||
+		code.GetRedirectURI() != req.RedirectURI ||
+		code.IsExpired(time.Now().UTC()) ||
+		!code.VerifyCodeVerifier(req.CodeVerifier) {
+		return nil, newInvalidGrantError("invalid authorization code")
+	}
+
+	found, err := s.users.FindByID(ctx, code.GetUserID())
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, newInvalidGrantError("invalid authorization code")
+		}
+		slog.Error("failed to load user for code exchange", "error", err)
+		return nil, newServerError()
+	}
+	// The code may have been issued before the account was deactivated.
+	if found.IsDeactivated() {
+		return nil, newInvalidGrantError("invalid authorization code")
+	}
+
+	return s.issueTokens(ctx, client, found, code.GetScope(), code.GetNonce(), code.GetAuthTime(), "")
+}
+
+func (s *service) refresh(ctx context.Context, client domain.ClientInterface, req TokenRequest) (*TokenResponse, *Error) {
+	if req.RefreshToken == "" {
+		return nil, newInvalidRequestError("refresh_token is required")
+	}
+
+	next, rawNext, restErr := s.auth.RotateRefreshToken(ctx, req.RefreshToken, client.GetClientID())
+	if restErr != nil {
+		if restErr.Code == http.StatusUnauthorized {
+			return nil, newInvalidGrantError("invalid refresh token")
+		}
+		return nil, newServerError()
+	}
+
+	found, err := s.users.FindByID(ctx, next.GetUserID())
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, newInvalidGrantError("invalid refresh token")
+		}
+		slog.Error("failed to load user for refresh", "error", err)
+		return nil, newServerError()
+	}
+	// Not every deactivation path revokes the account's refresh tokens.
+	if found.IsDeactivated() {
+		return nil, newInvalidGrantError("invalid refresh token")
+	}
+
+	return s.issueTokens(ctx, client, found, next.GetScope(), "", time.Time{}, rawNext)
+}
+
+// clientCredentials issues an access token to a service account. Requested
+// audiences and scopes must be a subset of what the account was granted;
+// omitting them grants everything the account is allowed.
+func (s *service) clientCredentials(ctx context.Context, req TokenRequest, basicID, basicSecret string) (*TokenResponse, *Error) {
+	credentials := serviceaccount.Credentials{
+		ClientID:      req.ClientID,
+		ClientSecret:  req.ClientSecret,
+		AssertionType: req.ClientAssertionType,
+		Assertion:     req.ClientAssertion,
+	}
+	if basicID != "" {
+		if credentials.ClientID != "" && credentials.ClientID != basicID {
+			return nil, newInvalidRequestError("client_id does not match the authorization header")
+		}
+		credentials.ClientID, credentials.ClientSecret = basicID, basicSecret
+	}
+
+	account, err := s.accounts.Authenticate(ctx, credentials)
+	if err != nil {
+		if errors.Is(err, serviceaccount.ErrInvalidCredentials) {
+			return nil, newInvalidClientError("client authentication failed")
+		}
+		slog.Error("failed to authenticate service account", "error", err)
+		return nil, newServerError()
+	}
+
+	audiences := append(append([]string{}, req.Audience...), req.Resource...)
+	if len(audiences) == 0 {
+		audiences = account.GetAudiences()
+	}
+	for _, audience := range audiences {
+		if !account.AllowsAudience(audience) {
+			return nil, &Error{Status: http.StatusBadRequest, Code: ErrorInvalidTarget, Description: "audience is not allowed for this client"}
+		}
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = account.GetScopes()
+	}
+	for _, scope := range scopes {
+		if !account.AllowsScope(scope) {
+			return nil, &Error{Status: http.StatusBadRequest, Code: ErrorInvalidScope, Description: "scope is not allowed for this client"}
+		}
+	}
+	scope := strings.Join(scopes, " ")
+
+	accessToken, claims, err := s.tokens.IssueAccessToken(ctx, token.AccessTokenParams{
+		Subject:  account.GetClientID(),
+		ClientID: account.GetClientID(),
+		Scope:    scope,
+		Audience: audiences,
+	})
+	if err != nil {
+		slog.Error("failed to issue access token", "error", err)
+		return nil, newServerError()
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(claims.ExpiresAt.Time).Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+func (s *service) issueTokens(
+	ctx context.Context,
+	client domain.ClientInterface,
+	found domain.UserInterface,
+	scope, nonce string,
+	authTime time.Time,
+	rawRefreshToken string,
+) (*TokenResponse, *Error) {
+	accessToken, claims, err := s.tokens.IssueAccessToken(ctx, token.AccessTokenParams{
+		Subject:  found.GetID().String(),
+		ClientID: client.GetClientID(),
+		Scope:    scope,
+	})
+	if err != nil {
+		slog.Error("failed to issue access token", "error", err)
+		return nil, newServerError()
+	}
+
+	res := &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(claims.ExpiresAt.Time).Seconds()),
+		Scope:        scope,
+		RefreshToken: rawRefreshToken,
+	}
+
+	if hasScope(scope, ScopeOpenID) {
+		idClaims := profileClaims(found, scope)
+		idClaims.Subject = found.GetID().String()
+		idClaims.Audience = jwt.ClaimStrings{client.GetClientID()}
+		idClaims.Nonce = nonce
+		if !authTime.IsZero() {
+			idClaims.AuthTime = authTime.Unix()
+		}
+
+		if res.IDToken, err = s.tokens.IssueIDToken(ctx, idClaims); err != nil {
+			slog.Error("failed to issue id token", "error", err)
+			return nil, newServerError()
+		}
+	}
+
+	if rawRefreshToken == "" && hasScope(scope, ScopeOfflineAccess) {
+		raw, restErr := s.auth.IssueRefreshToken(ctx, found.GetID(), client.GetClientID(), scope)
+		if restErr != nil {
+			return nil, newServerError()
+		}
+		res.RefreshToken = raw
+	}
+
+	return res, nil
+}
+
+// profileClaims maps the users columns onto the standard OIDC claims the
+// granted scope allows.
+func profileClaims(found domain.UserInterface, scope string) *token.IDTokenClaims {
+	claims := &token.IDTokenClaims{}
+
+	if hasScope(scope, ScopeEmail) {
+		emailVerified := found.IsEmailVerified()
+		claims.Email = found.GetEmail()
+		claims.EmailVerified = &emailVerified
+	}
+	if hasScope(scope, ScopeProfile) {
+		claims.GivenName = found.GetFirstName()
+		claims.FamilyName = found.GetLastName()
+		claims.Name = strings.TrimSpace(found.GetFirstName() + " " + found.GetLastName())
+	}
+	if hasScope(scope, ScopePhone) {
+		claims.PhoneNumber = found.GetPhone()
+	}
+
+	return claims
+}
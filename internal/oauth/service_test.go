@@ -0,0 +1,141 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r0wW1gFWFOEjXk"
+)
+
+type fakeRepository struct {
+	RepositoryInterface
+	client domain.ClientInterface
+	code   domain.AuthorizationCodeInterface
+}
+
+func (r *fakeRepository) FindClientByClientID(ctx context.Context, clientID string) (domain.ClientInterface, error) {
+	if clientID != r.client.GetClientID() {
+		return nil, ErrClientNotFound
+	}
+	return r.client, nil
+}
+
+func (r *fakeRepository) ConsumeCode(ctx context.Context, codeHash string) (domain.AuthorizationCodeInterface, error) {
+	if r.code == nil || codeHash != r.code.GetCodeHash() {
+		return nil, ErrCodeNotFound
+	}
+	code := r.code
+	r.code = nil
+	return code, nil
+}
+
+type fakeAuth struct {
+	auth.ServiceInterface
+	userID uuid.UUID
+}
+
+func (a *fakeAuth) RotateRefreshToken(ctx context.Context, rawToken, clientID string) (domain.RefreshTokenInterface, string, *httperr.HttpError) {
+	next, raw := domain.NewRefreshToken(a.userID, clientID, "profile", time.Hour)
+	return next, raw, nil
+}
+
+type fakeTokens struct {
+	token.ManagerInterface
+	issued int
+}
+
+func (m *fakeTokens) IssueAccessToken(ctx context.Context, params token.AccessTokenParams) (string, *token.Claims, error) {
+	m.issued++
+	claims := &token.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   params.Subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}}
+	return "access-token", claims, nil
+}
+
+func TestTokenRefusesDeactivatedAccounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		grantType   string
+		deactivated bool
+	}{
+		{"authorization code", GrantTypeAuthorizationCode, false},
+		{"authorization code after deactivation", GrantTypeAuthorizationCode, true},
+		{"refresh token", GrantTypeRefreshToken, false},
+		{"refresh token after deactivation", GrantTypeRefreshToken, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := domain.New("ada@example.com", "", "", "Ada", "Lovelace")
+			if err != nil {
+				t.Fatalf("domain.New() error = %v", err)
+			}
+			account.SetDeactivated(tt.deactivated)
+
+			client, _ := domain.NewClient("app", []string{testRedirectURI}, true)
+			sum := sha256.Sum256([]byte(testCodeVerifier))
+			challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+			code, rawCode := domain.NewAuthorizationCode(
+				client.GetClientID(), account.GetID(), testRedirectURI, "profile", "", challenge, time.Minute,
+			)
+
+			tokens := &fakeTokens{}
+			service := NewService(
+				config.OAuthConfig{},
+				config.TokenConfig{},
+				nil,
+				&fakeRepository{client: client, code: code},
+				testutil.NewUsers(account),
+				&fakeAuth{userID: account.GetID()},
+				nil,
+				tokens,
+				nil,
+			)
+
+			req := TokenRequest{GrantType: tt.grantType, ClientID: client.GetClientID()}
+			if tt.grantType == GrantTypeAuthorizationCode {
+				req.Code, req.RedirectURI, req.CodeVerifier = rawCode, testRedirectURI, testCodeVerifier
+			} else {
+				req.RefreshToken = "refresh-token"
+			}
+
+			res, oauthErr := service.Token(context.Background(), req, "", "")
+			if !tt.deactivated {
+				if oauthErr != nil {
+					t.Fatalf("Token() error = %v", oauthErr)
+				}
+				if res.AccessToken == "" {
+					t.Error("Token() returned no access token")
+				}
+				return
+			}
+
+			if oauthErr == nil {
+				t.Fatal("Token() issued tokens for a deactivated account")
+			}
+			if oauthErr.Code != ErrorInvalidGrant || oauthErr.Status != http.StatusBadRequest {
+				t.Errorf("Token() error = %d %s, want 400 %s", oauthErr.Status, oauthErr.Code, ErrorInvalidGrant)
+			}
+			if tokens.issued != 0 {
+				t.Errorf("issued %d access tokens, want 0", tokens.issued)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+(function () {
+  "use strict";
+
+  var button = document.getElementById("use-passkey");
+  if (!button) {
+    return;
+  }
+  if (!window.PublicKeyCredential) {
+    button.hidden = true;
+    return;
+  }
+
+  function decode(value) {
+    value = value.replace(/-/g, "+").replace(/_/g, "/");
+    while (value.length % 4) {
+      value += "=";
+    }
+    return Uint8Array.from(atob(value), function (c) { return c.charCodeAt(0); }).buffer;
+  }
+
+  function encode(buffer) {
+    return btoa(String.fromCharCode.apply(null, new Uint8Array(buffer)))
+      .replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+  }
+
+  button.addEventListener("click", function () {
+    var form = button.form;
+    button.disabled = true;
+
+    fetch("/api/v1/auth/mfa/passkey", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ mfa_token: form.elements.mfa_token.value })
+    })
+      .then(function (res) {
+        if (!res.ok) {
+          throw new Error("passkey sign-in is not available");
+        }
+        return res.json();
+      })
+      .then(function (begin) {
+        var options = begin.options.publicKey;
+        options.challenge = decode(options.challenge);
+        (options.allowCredentials || []).forEach(function (credential) {
+          credential.id = decode(credential.id);
+        });
+
+        return navigator.credentials.get({ publicKey: options }).then(function (credential) {
+          form.elements.passkey_session.value = begin.session_token;
+          form.elements.passkey.value = JSON.stringify({
+            id: credential.id,
+            rawId: encode(credential.rawId),
+            type: credential.type,
+            response: {
+              authenticatorData: encode(credential.response.authenticatorData),
+              clientDataJSON: encode(credential.response.clientDataJSON),
+              signature: encode(credential.response.signature),
+              userHandle: credential.response.userHandle ? encode(credential.response.userHandle) : null
+            }
+          });
+          form.submit();
+        });
+      })
+      .catch(function () {
+        button.textContent = "Passkey sign-in failed";
+      });
+  });
+})();
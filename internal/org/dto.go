@@ -0,0 +1,79 @@
+package org
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+	Slug string `json:"slug" binding:"required,min=2,max=64"`
+}
+
+type RoleNameRequest struct {
+	Role string `json:"role" binding:"required,max=64"`
+}
+
+type InviteRequest struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+	// Role defaults to the configured member role.
+	Role string `json:"role" binding:"max=64"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type OrganizationResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type MemberResponse struct {
+	UserID   string    `json:"user_id"`
+	Roles    []string  `json:"roles"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type InvitationResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewOrganizationResponse(organization domain.OrganizationInterface) OrganizationResponse {
+	return OrganizationResponse{
+		ID:        organization.GetID().String(),
+		Name:      organization.GetName(),
+		Slug:      organization.GetSlug(),
+		CreatedAt: organization.GetCreatedAt(),
+		UpdatedAt: organization.GetUpdatedAt(),
+	}
+}
+
+func NewMemberResponse(membership domain.MembershipInterface, roles []string) MemberResponse {
+	if roles == nil {
+		roles = []string{}
+	}
+	return MemberResponse{
+		UserID:   membership.GetUserID().String(),
+		Roles:    roles,
+		JoinedAt: membership.GetCreatedAt(),
+	}
+}
+
+func NewInvitationResponse(invitation domain.InvitationInterface, role string) InvitationResponse {
+	return InvitationResponse{
+		ID:        invitation.GetID().String(),
+		Email:     invitation.GetEmail(),
+		Role:      role,
+		ExpiresAt: invitation.GetExpiresAt(),
+		CreatedAt: invitation.GetCreatedAt(),
+	}
+}
@@ -0,0 +1,228 @@
+package org
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions on the active organization, granted through per-org roles.
+const (
+	ResourceOrg  = "org"
+	ActionRead   = "read"
+	ActionManage = "manage"
+)
+
+type handler struct {
+	account    config.AccountConfig
+	service    ServiceInterface
+	authorizer rbac.AuthorizerInterface
+	tokens     token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateOrganization(ctx *gin.Context)
+	ListOrganizations(ctx *gin.Context)
+	AcceptInvitation(ctx *gin.Context)
+	GetOrganization(ctx *gin.Context)
+	ListMembers(ctx *gin.Context)
+	RemoveMember(ctx *gin.Context)
+	AssignMemberRole(ctx *gin.Context)
+	UnassignMemberRole(ctx *gin.Context)
+	Invite(ctx *gin.Context)
+	ListInvitations(ctx *gin.Context)
+	RevokeInvitation(ctx *gin.Context)
+}
+
+func NewHandler(
+	account config.AccountConfig,
+	service ServiceInterface,
+	authorizer rbac.AuthorizerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		account:    account,
+		service:    service,
+		authorizer: authorizer,
+		tokens:     tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	orgs := router.Group(
+		"/api/v1/orgs",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+	)
+	{
+		verified := middleware.RequireVerifiedEmail(h.account)
+		orgs.POST("", verified, h.CreateOrganization)
+		orgs.GET("", h.ListOrganizations)
+		orgs.POST("/invitations/accept", h.AcceptInvitation)
+	}
+
+	read := middleware.RequirePermission(h.authorizer, ResourceOrg, ActionRead)
+	manage := middleware.RequirePermission(h.authorizer, ResourceOrg, ActionManage)
+
+	current := router.Group(
+		"/api/v1/orgs/current",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+		middleware.RequireOrg(),
+	)
+	{
+		current.GET("", read, h.GetOrganization)
+		current.GET("/members", read, h.ListMembers)
+		current.DELETE("/members/:user_id", manage, h.RemoveMember)
+		current.POST("/members/:user_id/roles", manage, h.AssignMemberRole)
+		current.DELETE("/members/:user_id/roles/:role_id", manage, h.UnassignMemberRole)
+		current.GET("/invitations", manage, h.ListInvitations)
+		current.POST("/invitations", manage, h.Invite)
+		current.DELETE("/invitations/:id", manage, h.RevokeInvitation)
+	}
+}
+
+func (h *handler) CreateOrganization(ctx *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+	res, restErr := h.service.CreateOrganization(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) ListOrganizations(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+	res, restErr := h.service.ListOrganizations(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) AcceptInvitation(ctx *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+	res, restErr := h.service.AcceptInvitation(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) GetOrganization(ctx *gin.Context) {
+	res, restErr := h.service.GetOrganization(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListMembers(ctx *gin.Context) {
+	res, restErr := h.service.ListMembers(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RemoveMember(ctx *gin.Context) {
+	if restErr := h.service.RemoveMember(ctx.Request.Context(), ctx.Param("user_id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) AssignMemberRole(ctx *gin.Context) {
+	var req RoleNameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	if restErr := h.service.AssignMemberRole(ctx.Request.Context(), ctx.Param("user_id"), req); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) UnassignMemberRole(ctx *gin.Context) {
+	restErr := h.service.UnassignMemberRole(ctx.Request.Context(), ctx.Param("user_id"), ctx.Param("role_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) Invite(ctx *gin.Context) {
+	var req InviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+	res, restErr := h.service.Invite(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) ListInvitations(ctx *gin.Context) {
+	res, restErr := h.service.ListInvitations(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RevokeInvitation(ctx *gin.Context) {
+	if restErr := h.service.RevokeInvitation(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,482 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrSlugAlreadyExists    = errors.New("organization slug already exists")
+	ErrMembershipNotFound   = errors.New("membership not found")
+	ErrAssignmentNotFound   = errors.New("role assignment not found")
+	ErrInvitationNotFound   = errors.New("invitation not found")
+	ErrTenantMismatch       = errors.New("record belongs to another tenant")
+)
+
+const selectInvitationColumns = `
+	SELECT id, org_id, email, role_id, invited_by, token_hash, expires_at, accepted_at, created_at
+	FROM invitations`
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+// RepositoryInterface stores organizations and everything they own. Apart
+// from creating an organization, listing a user's own organizations and
+// looking an invitation up by its secret token, every method is scoped to
+// the tenant in the context and fails with database.ErrTenantRequired
+// without one. Row-level security enforces the same scoping in Postgres:
+// the exceptions only work for the actor in the context.
+type RepositoryInterface interface {
+	CreateOrganization(ctx context.Context, organization domain.OrganizationInterface) error
+	FindOrganizationsByUser(ctx context.Context, userID uuid.UUID) ([]domain.OrganizationInterface, error)
+	FindInvitationByHash(ctx context.Context, tokenHash string) (domain.InvitationInterface, error)
+
+	FindOrganization(ctx context.Context) (domain.OrganizationInterface, error)
+	CreateMembership(ctx context.Context, membership domain.MembershipInterface) error
+	FindMembership(ctx context.Context, userID uuid.UUID) (domain.MembershipInterface, error)
+	FindMemberships(ctx context.Context) ([]domain.MembershipInterface, error)
+	DeleteMembership(ctx context.Context, userID uuid.UUID) error
+	AssignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error
+	UnassignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error
+	FindMemberRoleNames(ctx context.Context) (map[uuid.UUID][]string, error)
+	LockMembersWithRole(ctx context.Context, roleID uuid.UUID) ([]uuid.UUID, error)
+	CreateInvitation(ctx context.Context, invitation domain.InvitationInterface) error
+	FindPendingInvitations(ctx context.Context, now time.Time) ([]domain.InvitationInterface, error)
+	MarkInvitationAccepted(ctx context.Context, invitation domain.InvitationInterface) error
+	DeleteInvitation(ctx context.Context, id uuid.UUID) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateOrganization(ctx context.Context, organization domain.OrganizationInterface) error {
+	query := `
+		INSERT INTO organizations (id, name, slug, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		organization.GetID(),
+		organization.GetName(),
+		organization.GetSlug(),
+		organization.GetCreatedAt(),
+		organization.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrSlugAlreadyExists
+		}
+		return fmt.Errorf("failed to insert organization: %w", err)
+	}
+
+	return nil
+}
+
+// FindOrganizationsByUser lists the organizations the user belongs to. It
+// crosses tenants on purpose, which row-level security only allows when the
+// user is the actor in the context.
+func (r *repository) FindOrganizationsByUser(ctx context.Context, userID uuid.UUID) ([]domain.OrganizationInterface, error) {
+	query := `
+		SELECT o.id, o.name, o.slug, o.created_at, o.updated_at
+		FROM organizations o
+		JOIN memberships m ON m.org_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	organizations := make([]domain.OrganizationInterface, 0)
+	for rows.Next() {
+		organization, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, organization)
+	}
+
+	return organizations, rows.Err()
+}
+
+// FindInvitationByHash resolves an invitation from the token mailed to the
+// invitee, which is what tells us the tenant in the first place. Row-level
+// security only shows it to the actor it is addressed to.
+func (r *repository) FindInvitationByHash(ctx context.Context, tokenHash string) (domain.InvitationInterface, error) {
+	query := selectInvitationColumns + ` WHERE token_hash = $1`
+	return scanInvitation(r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash))
+}
+
+func (r *repository) FindOrganization(ctx context.Context) (domain.OrganizationInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, name, slug, created_at, updated_at
+		FROM organizations
+		WHERE id = $1`
+
+	return scanOrganization(r.db.GetQuerier(ctx).QueryRow(ctx, query, orgID))
+}
+
+func (r *repository) CreateMembership(ctx context.Context, membership domain.MembershipInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+	if membership.GetOrgID() != orgID {
+		return ErrTenantMismatch
+	}
+
+	query := `
+		INSERT INTO memberships (org_id, user_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, membership.GetUserID(), membership.GetCreatedAt()); err != nil {
+		return fmt.Errorf("failed to insert membership: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) FindMembership(ctx context.Context, userID uuid.UUID) (domain.MembershipInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT org_id, user_id, created_at FROM memberships WHERE org_id = $1 AND user_id = $2`
+
+	return scanMembership(r.db.GetQuerier(ctx).QueryRow(ctx, query, orgID, userID))
+}
+
+func (r *repository) FindMemberships(ctx context.Context) ([]domain.MembershipInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT org_id, user_id, created_at
+		FROM memberships
+		WHERE org_id = $1
+		ORDER BY created_at`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memberships: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := make([]domain.MembershipInterface, 0)
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, rows.Err()
+}
+
+func (r *repository) DeleteMembership(ctx context.Context, userID uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM memberships WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete membership: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMembershipNotFound
+	}
+	return nil
+}
+
+func (r *repository) AssignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO membership_roles (org_id, user_id, role_id, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, userID, roleID, time.Now().UTC()); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode {
+			return ErrMembershipNotFound
+		}
+		return fmt.Errorf("failed to assign member role: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) UnassignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM membership_roles WHERE org_id = $1 AND user_id = $2 AND role_id = $3`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign member role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// FindMemberRoleNames maps each member of the tenant to the roles granted
+// directly through the membership.
+func (r *repository) FindMemberRoleNames(ctx context.Context) (map[uuid.UUID][]string, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT mr.user_id, r.name
+		FROM membership_roles mr
+		JOIN roles r ON r.id = mr.role_id
+		WHERE mr.org_id = $1
+		ORDER BY r.name`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query member roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[uuid.UUID][]string)
+	for rows.Next() {
+		var (
+			userID uuid.UUID
+			name   string
+		)
+		if err := rows.Scan(&userID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan member role: %w", err)
+		}
+		roles[userID] = append(roles[userID], name)
+	}
+
+	return roles, rows.Err()
+}
+
+// LockMembersWithRole returns the members holding the role and locks their
+// assignments until the transaction ends, so two concurrent removals cannot
+// both see another owner left.
+func (r *repository) LockMembersWithRole(ctx context.Context, roleID uuid.UUID) ([]uuid.UUID, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT user_id
+		FROM membership_roles
+		WHERE org_id = $1 AND role_id = $2
+		FOR UPDATE`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock member roles: %w", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan member role: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// CreateInvitation stores the invitation and drops any pending one for the
+// same email, so only the most recent link works.
+func (r *repository) CreateInvitation(ctx context.Context, invitation domain.InvitationInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+	if invitation.GetOrgID() != orgID {
+		return ErrTenantMismatch
+	}
+
+	query := `
+		WITH superseded AS (
+			DELETE FROM invitations
+			WHERE org_id = $2 AND email = $3 AND accepted_at IS NULL
+		)
+		INSERT INTO invitations (id, org_id, email, role_id, invited_by, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		invitation.GetID(),
+		orgID,
+		invitation.GetEmail(),
+		invitation.GetRoleID(),
+		invitation.GetInvitedBy(),
+		invitation.GetTokenHash(),
+		invitation.GetExpiresAt(),
+		invitation.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert invitation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindPendingInvitations(ctx context.Context, now time.Time) ([]domain.InvitationInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectInvitationColumns + `
+		WHERE org_id = $1 AND accepted_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := make([]domain.InvitationInterface, 0)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// MarkInvitationAccepted fails with ErrInvitationNotFound when the invitation
+// was accepted in the meantime, so it can only be used once.
+func (r *repository) MarkInvitationAccepted(ctx context.Context, invitation domain.InvitationInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE invitations SET accepted_at = $3 WHERE org_id = $1 AND id = $2 AND accepted_at IS NULL`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, invitation.GetID(), invitation.GetAcceptedAt())
+	if err != nil {
+		return fmt.Errorf("failed to mark invitation as accepted: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteInvitation(ctx context.Context, id uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM invitations WHERE org_id = $1 AND id = $2 AND accepted_at IS NULL`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func scanOrganization(row pgx.Row) (domain.OrganizationInterface, error) {
+	var (
+		id                   uuid.UUID
+		name, slug           string
+		createdAt, updatedAt time.Time
+	)
+
+	if err := row.Scan(&id, &name, &slug, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to scan organization: %w", err)
+	}
+
+	return domain.RestoreOrganization(id, name, slug, createdAt, updatedAt), nil
+}
+
+func scanMembership(row pgx.Row) (domain.MembershipInterface, error) {
+	var (
+		orgID, userID uuid.UUID
+		createdAt     time.Time
+	)
+
+	if err := row.Scan(&orgID, &userID, &createdAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMembershipNotFound
+		}
+		return nil, fmt.Errorf("failed to scan membership: %w", err)
+	}
+
+	return domain.RestoreMembership(orgID, userID, createdAt), nil
+}
+
+func scanInvitation(row pgx.Row) (domain.InvitationInterface, error) {
+	var (
+		id, orgID            uuid.UUID
+		email, tokenHash     string
+		roleID, invitedBy    *uuid.UUID
+		expiresAt, createdAt time.Time
+		acceptedAt           *time.Time
+	)
+
+	err := row.Scan(&id, &orgID, &email, &roleID, &invitedBy, &tokenHash, &expiresAt, &acceptedAt, &createdAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to scan invitation: %w", err)
+	}
+
+	return domain.RestoreInvitation(id, orgID, email, roleID, invitedBy, tokenHash, expiresAt, acceptedAt, createdAt), nil
+}
@@ -0,0 +1,561 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/infra/mailer"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+const (
+	invalidInvitationMessage = "invalid or expired invitation"
+	lastOwnerMessage         = "an organization must keep at least one owner"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type service struct {
+	config     config.OrgConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	roles      rbac.RepositoryInterface
+	users      user.RepositoryInterface
+	mailer     mailer.MailerInterface
+	audit      audit.RecorderInterface
+}
+
+// ServiceInterface manages organizations. Methods without a userID argument
+// act on the tenant in the context, which RequireOrg sets from the active
+// organization of the caller's token.
+type ServiceInterface interface {
+	CreateOrganization(ctx context.Context, userID uuid.UUID, req CreateOrganizationRequest) (*OrganizationResponse, *httperr.HttpError)
+	ListOrganizations(ctx context.Context, userID uuid.UUID) ([]OrganizationResponse, *httperr.HttpError)
+	GetOrganization(ctx context.Context) (*OrganizationResponse, *httperr.HttpError)
+	ListMembers(ctx context.Context) ([]MemberResponse, *httperr.HttpError)
+	RemoveMember(ctx context.Context, userID string) *httperr.HttpError
+	AssignMemberRole(ctx context.Context, userID string, req RoleNameRequest) *httperr.HttpError
+	UnassignMemberRole(ctx context.Context, userID, roleID string) *httperr.HttpError
+	Invite(ctx context.Context, inviterID uuid.UUID, req InviteRequest) (*InvitationResponse, *httperr.HttpError)
+	ListInvitations(ctx context.Context) ([]InvitationResponse, *httperr.HttpError)
+	RevokeInvitation(ctx context.Context, id string) *httperr.HttpError
+	AcceptInvitation(ctx context.Context, userID uuid.UUID, req AcceptInvitationRequest) (*OrganizationResponse, *httperr.HttpError)
+	IsMember(ctx context.Context, orgID, userID uuid.UUID) (bool, *httperr.HttpError)
+}
+
+func NewService(
+	config config.OrgConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	roles rbac.RepositoryInterface,
+	users user.RepositoryInterface,
+	mailer mailer.MailerInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		roles:      roles,
+		users:      users,
+		mailer:     mailer,
+		audit:      audit,
+	}
+}
+
+// CreateOrganization creates a tenant and makes its creator the owner.
+func (s *service) CreateOrganization(ctx context.Context, userID uuid.UUID, req CreateOrganizationRequest) (*OrganizationResponse, *httperr.HttpError) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, invalidFieldError("slug", "must contain only lowercase letters, digits and single hyphens")
+	}
+
+	owner, restErr := s.findRoleByName(ctx, s.config.OwnerRole)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	organization := domain.NewOrganization(strings.TrimSpace(req.Name), slug)
+
+	ctx = database.WithTenant(ctx, organization.GetID())
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateOrganization(ctx, organization); err != nil {
+			return err
+		}
+
+		if err := s.repository.CreateMembership(ctx, domain.NewMembership(organization.GetID(), userID)); err != nil {
+			return err
+		}
+
+		if err := s.repository.AssignMemberRole(ctx, userID, owner.GetID()); err != nil {
+			return err
+		}
+
+		return s.record(ctx, audit.ActionOrgCreate, audit.TargetOrganization, organization.GetID(), map[string]string{"slug": slug})
+	})
+	if err != nil {
+		if errors.Is(err, ErrSlugAlreadyExists) {
+			return nil, invalidFieldError("slug", "is already taken")
+		}
+		slog.Error("failed to create organization", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create organization")
+	}
+
+	slog.Info("organization created", slog.String("org_id", organization.GetID().String()))
+
+	res := NewOrganizationResponse(organization)
+	return &res, nil
+}
+
+func (s *service) ListOrganizations(ctx context.Context, userID uuid.UUID) ([]OrganizationResponse, *httperr.HttpError) {
+	organizations, err := s.repository.FindOrganizationsByUser(database.WithActor(ctx, userID), userID)
+	if err != nil {
+		slog.Error("failed to list organizations", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list organizations")
+	}
+
+	res := make([]OrganizationResponse, 0, len(organizations))
+	for _, organization := range organizations {
+		res = append(res, NewOrganizationResponse(organization))
+	}
+
+	return res, nil
+}
+
+func (s *service) GetOrganization(ctx context.Context) (*OrganizationResponse, *httperr.HttpError) {
+	organization, err := s.repository.FindOrganization(ctx)
+	if err != nil {
+		if errors.Is(err, ErrOrganizationNotFound) {
+			return nil, httperr.NewNotFoundError("organization not found")
+		}
+		slog.Error("failed to load organization", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load organization")
+	}
+
+	res := NewOrganizationResponse(organization)
+	return &res, nil
+}
+
+func (s *service) ListMembers(ctx context.Context) ([]MemberResponse, *httperr.HttpError) {
+	memberships, err := s.repository.FindMemberships(ctx)
+	if err != nil {
+		slog.Error("failed to list members", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list members")
+	}
+
+	roles, err := s.repository.FindMemberRoleNames(ctx)
+	if err != nil {
+		slog.Error("failed to load member roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list members")
+	}
+
+	res := make([]MemberResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		res = append(res, NewMemberResponse(membership, roles[membership.GetUserID()]))
+	}
+
+	return res, nil
+}
+
+// RemoveMember drops the user from the organization together with the roles
+// granted there. The last owner cannot be removed.
+func (s *service) RemoveMember(ctx context.Context, userID string) *httperr.HttpError {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("member not found")
+	}
+
+	owner, restErr := s.findRoleByName(ctx, s.config.OwnerRole)
+	if restErr != nil {
+		return restErr
+	}
+
+	var lastOwner bool
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		owners, err := s.repository.LockMembersWithRole(ctx, owner.GetID())
+		if err != nil {
+			return err
+		}
+		if len(owners) == 1 && owners[0] == userUUID {
+			lastOwner = true
+			return nil
+		}
+
+		if err := s.repository.DeleteMembership(ctx, userUUID); err != nil {
+			return err
+		}
+
+		return s.record(ctx, audit.ActionMemberRemove, audit.TargetUser, userUUID, nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrMembershipNotFound) {
+			return httperr.NewNotFoundError("member not found")
+		}
+		slog.Error("failed to remove member", "error", err)
+		return httperr.NewInternalServerError("failed to remove member")
+	}
+
+	if lastOwner {
+		return httperr.NewBadRequestError(lastOwnerMessage)
+	}
+
+	slog.Info("member removed", slog.String("user_id", userUUID.String()))
+	return nil
+}
+
+func (s *service) AssignMemberRole(ctx context.Context, userID string, req RoleNameRequest) *httperr.HttpError {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("member not found")
+	}
+
+	role, restErr := s.findRoleByName(ctx, req.Role)
+	if restErr != nil {
+		return restErr
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.AssignMemberRole(ctx, userUUID, role.GetID()); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionMemberRoleAssign, audit.TargetUser, userUUID, map[string]string{
+			"role_id": role.GetID().String(),
+			"role":    role.GetName(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrMembershipNotFound) {
+			return httperr.NewNotFoundError("member not found")
+		}
+		slog.Error("failed to assign member role", "error", err)
+		return httperr.NewInternalServerError("failed to assign role")
+	}
+
+	slog.Info("member role assigned", slog.String("user_id", userUUID.String()), slog.String("role_id", role.GetID().String()))
+	return nil
+}
+
+// UnassignMemberRole removes a role granted in the organization. Taking the
+// owner role away from the last owner is refused.
+func (s *service) UnassignMemberRole(ctx context.Context, userID, roleID string) *httperr.HttpError {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("role assignment not found")
+	}
+	roleUUID, err := uuid.Parse(roleID)
+	if err != nil {
+		return httperr.NewNotFoundError("role assignment not found")
+	}
+
+	var lastOwner bool
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		holders, err := s.repository.LockMembersWithRole(ctx, roleUUID)
+		if err != nil {
+			return err
+		}
+
+		if len(holders) == 1 && holders[0] == userUUID {
+			role, err := s.roles.FindRoleByID(ctx, roleUUID)
+			if err != nil {
+				return err
+			}
+			if role.GetName() == s.config.OwnerRole {
+				lastOwner = true
+				return nil
+			}
+		}
+
+		if err := s.repository.UnassignMemberRole(ctx, userUUID, roleUUID); err != nil {
+			return err
+		}
+
+		return s.record(ctx, audit.ActionMemberRoleUnassign, audit.TargetUser, userUUID, map[string]string{"role_id": roleUUID.String()})
+	})
+	if err != nil {
+		if errors.Is(err, ErrAssignmentNotFound) || errors.Is(err, rbac.ErrRoleNotFound) {
+			return httperr.NewNotFoundError("role assignment not found")
+		}
+		slog.Error("failed to unassign member role", "error", err)
+		return httperr.NewInternalServerError("failed to unassign role")
+	}
+
+	if lastOwner {
+		return httperr.NewBadRequestError(lastOwnerMessage)
+	}
+
+	slog.Info("member role unassigned", slog.String("user_id", userUUID.String()), slog.String("role_id", roleUUID.String()))
+	return nil
+}
+
+// Invite mails a single-use invitation link. Inviting the same email again
+// replaces the pending invitation.
+func (s *service) Invite(ctx context.Context, inviterID uuid.UUID, req InviteRequest) (*InvitationResponse, *httperr.HttpError) {
+	roleName := strings.TrimSpace(req.Role)
+	if roleName == "" {
+		roleName = s.config.MemberRole
+	}
+
+	role, restErr := s.findRoleByName(ctx, roleName)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	organization, err := s.repository.FindOrganization(ctx)
+	if err != nil {
+		slog.Error("failed to load organization", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create invitation")
+	}
+
+	var inviterName string
+	if inviter, err := s.users.FindByID(ctx, inviterID); err == nil {
+		inviterName = strings.TrimSpace(inviter.GetFirstName() + " " + inviter.GetLastName())
+	}
+
+	ttl := time.Duration(s.config.InvitationTTL) * time.Second
+	roleID := role.GetID()
+	invitation, raw := domain.NewInvitation(organization.GetID(), normalizeEmail(req.Email), &roleID, &inviterID, ttl)
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionInvite, audit.TargetInvitation, invitation.GetID(), map[string]string{
+			"email": invitation.GetEmail(),
+			"role":  role.GetName(),
+		})
+	})
+	if err != nil {
+		slog.Error("failed to store invitation", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create invitation")
+	}
+
+	if err := s.sendInvitation(ctx, organization, invitation, inviterName, raw, ttl); err != nil {
+		slog.Error("failed to send invitation email", "error", err)
+		err := s.db.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.repository.DeleteInvitation(ctx, invitation.GetID()); err != nil {
+				return err
+			}
+			return s.record(ctx, audit.ActionInvitationRevoke, audit.TargetInvitation, invitation.GetID(), map[string]string{"reason": "undeliverable"})
+		})
+		if err != nil {
+			slog.Error("failed to discard unsent invitation", "error", err)
+		}
+		return nil, httperr.NewInternalServerError("failed to send invitation")
+	}
+
+	slog.Info("invitation created", slog.String("invitation_id", invitation.GetID().String()))
+
+	res := NewInvitationResponse(invitation, role.GetName())
+	return &res, nil
+}
+
+func (s *service) ListInvitations(ctx context.Context) ([]InvitationResponse, *httperr.HttpError) {
+	invitations, err := s.repository.FindPendingInvitations(ctx, time.Now().UTC())
+	if err != nil {
+		slog.Error("failed to list invitations", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list invitations")
+	}
+
+	roles, err := s.roles.FindAllRoles(ctx)
+	if err != nil {
+		slog.Error("failed to load roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list invitations")
+	}
+
+	names := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		names[role.GetID()] = role.GetName()
+	}
+
+	res := make([]InvitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		var name string
+		if roleID := invitation.GetRoleID(); roleID != nil {
+			name = names[*roleID]
+		}
+		res = append(res, NewInvitationResponse(invitation, name))
+	}
+
+	return res, nil
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, id string) *httperr.HttpError {
+	invitationID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("invitation not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteInvitation(ctx, invitationID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionInvitationRevoke, audit.TargetInvitation, invitationID, nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvitationNotFound) {
+			return httperr.NewNotFoundError("invitation not found")
+		}
+		slog.Error("failed to revoke invitation", "error", err)
+		return httperr.NewInternalServerError("failed to revoke invitation")
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the caller to the inviting organization. The
+// invitation only works for the account registered with the invited email,
+// and following the mailed link also proves control of that address.
+func (s *service) AcceptInvitation(ctx context.Context, userID uuid.UUID, req AcceptInvitationRequest) (*OrganizationResponse, *httperr.HttpError) {
+	var (
+		organization domain.OrganizationInterface
+		invalid      bool
+	)
+
+	err := s.db.WithTx(database.WithActor(ctx, userID), func(ctx context.Context) error {
+		invitation, err := s.repository.FindInvitationByHash(ctx, domain.HashOpaqueToken(req.Token))
+		if err != nil {
+			return err
+		}
+
+		found, err := s.users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if invitation.IsAccepted() || invitation.IsExpired(time.Now().UTC()) || found.GetEmail() != invitation.GetEmail() {
+			invalid = true
+			return nil
+		}
+
+		ctx = database.WithTenant(ctx, invitation.GetOrgID())
+
+		invitation.Accept()
+		if err := s.repository.MarkInvitationAccepted(ctx, invitation); err != nil {
+			return err
+		}
+
+		if err := s.repository.CreateMembership(ctx, domain.NewMembership(invitation.GetOrgID(), userID)); err != nil {
+			return err
+		}
+
+		if roleID := invitation.GetRoleID(); roleID != nil {
+			if err := s.repository.AssignMemberRole(ctx, userID, *roleID); err != nil {
+				return err
+			}
+		}
+
+		if !found.IsEmailVerified() {
+			found.VerifyEmail()
+			if err := s.users.MarkEmailVerified(ctx, found); err != nil {
+				return err
+			}
+		}
+
+		if err := s.record(ctx, audit.ActionInvitationAccept, audit.TargetInvitation, invitation.GetID(), nil); err != nil {
+			return err
+		}
+
+		organization, err = s.repository.FindOrganization(ctx)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvitationNotFound) {
+			return nil, httperr.NewBadRequestError(invalidInvitationMessage)
+		}
+		slog.Error("failed to accept invitation", "error", err)
+		return nil, httperr.NewInternalServerError("failed to accept invitation")
+	}
+
+	if invalid {
+		return nil, httperr.NewBadRequestError(invalidInvitationMessage)
+	}
+
+	slog.Info("invitation accepted", slog.String("org_id", organization.GetID().String()), slog.String("user_id", userID.String()))
+
+	res := NewOrganizationResponse(organization)
+	return &res, nil
+}
+
+// IsMember reports whether the user belongs to the organization; switching
+// the active organization depends on it.
+func (s *service) IsMember(ctx context.Context, orgID, userID uuid.UUID) (bool, *httperr.HttpError) {
+	_, err := s.repository.FindMembership(database.WithTenant(ctx, orgID), userID)
+	if err != nil {
+		if errors.Is(err, ErrMembershipNotFound) {
+			return false, nil
+		}
+		slog.Error("failed to look up membership", "error", err)
+		return false, httperr.NewInternalServerError("failed to look up membership")
+	}
+
+	return true, nil
+}
+
+func (s *service) sendInvitation(
+	ctx context.Context,
+	organization domain.OrganizationInterface,
+	invitation domain.InvitationInterface,
+	inviterName, raw string,
+	ttl time.Duration,
+) error {
+	link, err := mailer.LinkWithToken(s.config.InvitationURL, raw)
+	if err != nil {
+		return err
+	}
+
+	message, err := mailer.Compose(invitation.GetEmail(), mailer.TemplateInvitation, mailer.InvitationData{
+		OrgName:     organization.GetName(),
+		InviterName: inviterName,
+		Link:        link,
+		ExpiresIn:   ttl,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, message)
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+func (s *service) findRoleByName(ctx context.Context, name string) (domain.RoleInterface, *httperr.HttpError) {
+	role, err := s.roles.FindRoleByName(ctx, strings.TrimSpace(name))
+	if err != nil {
+		if errors.Is(err, rbac.ErrRoleNotFound) {
+			return nil, invalidFieldError("role", "does not exist")
+		}
+		slog.Error("failed to load role", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load role")
+	}
+
+	return role, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func invalidFieldError(field, message string) *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: field, Message: message},
+	})
+}
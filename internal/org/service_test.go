@@ -0,0 +1,428 @@
+package org
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/google/uuid"
+)
+
+var testConfig = config.OrgConfig{
+	InvitationURL: "https://app.example.com/invitations",
+	InvitationTTL: 3600,
+	OwnerRole:     "owner",
+	MemberRole:    "member",
+}
+
+// fakeRepository keeps memberships and member roles per tenant, so a call
+// made without the right tenant in the context finds nothing.
+type fakeRepository struct {
+	RepositoryInterface
+	organizations map[uuid.UUID]domain.OrganizationInterface
+	members       map[uuid.UUID]map[uuid.UUID]map[uuid.UUID]bool
+	invitations   map[string]domain.InvitationInterface
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		organizations: map[uuid.UUID]domain.OrganizationInterface{},
+		members:       map[uuid.UUID]map[uuid.UUID]map[uuid.UUID]bool{},
+		invitations:   map[string]domain.InvitationInterface{},
+	}
+}
+
+func (r *fakeRepository) tenant(ctx context.Context) (map[uuid.UUID]map[uuid.UUID]bool, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if r.members[orgID] == nil {
+		r.members[orgID] = map[uuid.UUID]map[uuid.UUID]bool{}
+	}
+	return r.members[orgID], nil
+}
+
+func (r *fakeRepository) FindOrganization(ctx context.Context) (domain.OrganizationInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	organization, ok := r.organizations[orgID]
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	return organization, nil
+}
+
+func (r *fakeRepository) FindInvitationByHash(_ context.Context, tokenHash string) (domain.InvitationInterface, error) {
+	invitation, ok := r.invitations[tokenHash]
+	if !ok {
+		return nil, ErrInvitationNotFound
+	}
+	return invitation, nil
+}
+
+func (r *fakeRepository) MarkInvitationAccepted(context.Context, domain.InvitationInterface) error {
+	return nil
+}
+
+func (r *fakeRepository) CreateMembership(ctx context.Context, membership domain.MembershipInterface) error {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	members[membership.GetUserID()] = map[uuid.UUID]bool{}
+	return nil
+}
+
+func (r *fakeRepository) FindMembership(ctx context.Context, userID uuid.UUID) (domain.MembershipInterface, error) {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := members[userID]; !ok {
+		return nil, ErrMembershipNotFound
+	}
+	orgID, _ := database.TenantFromContext(ctx)
+	return domain.NewMembership(orgID, userID), nil
+}
+
+func (r *fakeRepository) DeleteMembership(ctx context.Context, userID uuid.UUID) error {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := members[userID]; !ok {
+		return ErrMembershipNotFound
+	}
+	delete(members, userID)
+	return nil
+}
+
+func (r *fakeRepository) AssignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	roles, ok := members[userID]
+	if !ok {
+		return ErrMembershipNotFound
+	}
+	roles[roleID] = true
+	return nil
+}
+
+func (r *fakeRepository) UnassignMemberRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	if !members[userID][roleID] {
+		return ErrAssignmentNotFound
+	}
+	delete(members[userID], roleID)
+	return nil
+}
+
+func (r *fakeRepository) LockMembersWithRole(ctx context.Context, roleID uuid.UUID) ([]uuid.UUID, error) {
+	members, err := r.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var holders []uuid.UUID
+	for userID, roles := range members {
+		if roles[roleID] {
+			holders = append(holders, userID)
+		}
+	}
+	return holders, nil
+}
+
+type fakeRoles struct {
+	rbac.RepositoryInterface
+	roles []domain.RoleInterface
+}
+
+func (r *fakeRoles) FindRoleByName(_ context.Context, name string) (domain.RoleInterface, error) {
+	for _, role := range r.roles {
+		if role.GetName() == name {
+			return role, nil
+		}
+	}
+	return nil, rbac.ErrRoleNotFound
+}
+
+func (r *fakeRoles) FindRoleByID(_ context.Context, id uuid.UUID) (domain.RoleInterface, error) {
+	for _, role := range r.roles {
+		if role.GetID() == id {
+			return role, nil
+		}
+	}
+	return nil, rbac.ErrRoleNotFound
+}
+
+type testEnv struct {
+	service      ServiceInterface
+	repository   *fakeRepository
+	users        *testutil.Users
+	audit        *testutil.Recorder
+	organization domain.OrganizationInterface
+	ctx          context.Context
+	owner        domain.RoleInterface
+	member       domain.RoleInterface
+}
+
+func newTestEnv() *testEnv {
+	env := &testEnv{
+		repository:   newFakeRepository(),
+		users:        testutil.NewUsers(),
+		audit:        &testutil.Recorder{},
+		organization: domain.NewOrganization("Acme", "acme"),
+		owner:        domain.NewRole("owner", ""),
+		member:       domain.NewRole("member", ""),
+	}
+	env.repository.organizations[env.organization.GetID()] = env.organization
+	env.ctx = database.WithTenant(context.Background(), env.organization.GetID())
+	env.service = NewService(
+		testConfig,
+		testutil.DB{},
+		env.repository,
+		&fakeRoles{roles: []domain.RoleInterface{env.owner, env.member}},
+		env.users,
+		nil,
+		env.audit,
+	)
+	return env
+}
+
+// join makes userID a member of the test organization holding roles.
+func (env *testEnv) join(userID uuid.UUID, roles ...domain.RoleInterface) {
+	members, _ := env.repository.tenant(env.ctx)
+	members[userID] = map[uuid.UUID]bool{}
+	for _, role := range roles {
+		members[userID][role.GetID()] = true
+	}
+}
+
+func (env *testEnv) isMember(userID uuid.UUID) bool {
+	_, ok := env.repository.members[env.organization.GetID()][userID]
+	return ok
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		owners  int
+		remove  string
+		code    int
+		removed bool
+	}{
+		{name: "member", owners: 1, remove: "member", removed: true},
+		{name: "one of two owners", owners: 2, remove: "owner", removed: true},
+		{name: "last owner", owners: 1, remove: "owner", code: http.StatusBadRequest},
+		{name: "not a member", owners: 1, remove: "stranger", code: http.StatusNotFound},
+		{name: "malformed id", owners: 1, remove: "not-a-uuid", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			ids := map[string]uuid.UUID{"member": uuid.New(), "stranger": uuid.New()}
+			for i := 0; i < tt.owners; i++ {
+				ids["owner"] = uuid.New()
+				env.join(ids["owner"], env.owner)
+			}
+			env.join(ids["member"], env.member)
+
+			target := tt.remove
+			if id, ok := ids[tt.remove]; ok {
+				target = id.String()
+			}
+
+			restErr := env.service.RemoveMember(env.ctx, target)
+			if tt.code != 0 {
+				if restErr == nil || restErr.Code != tt.code {
+					t.Fatalf("RemoveMember() error = %v, want %d", restErr, tt.code)
+				}
+				if env.audit.Has(audit.ActionMemberRemove) {
+					t.Error("a refused removal was audited")
+				}
+			} else if restErr != nil {
+				t.Fatalf("RemoveMember() error = %v", restErr)
+			}
+
+			if id, ok := ids[tt.remove]; ok && tt.remove != "stranger" && env.isMember(id) == tt.removed {
+				t.Fatalf("member still present = %v, want %v", env.isMember(id), !tt.removed)
+			}
+			if tt.removed && !env.audit.Has(audit.ActionMemberRemove) {
+				t.Error("the removal was not audited")
+			}
+		})
+	}
+}
+
+func TestRemoveMemberRequiresTheTenant(t *testing.T) {
+	env := newTestEnv()
+	userID := uuid.New()
+	env.join(userID, env.member)
+
+	other := database.WithTenant(context.Background(), uuid.New())
+	if restErr := env.service.RemoveMember(other, userID.String()); restErr == nil || restErr.Code != http.StatusNotFound {
+		t.Fatalf("RemoveMember() in another organization error = %v, want 404", restErr)
+	}
+	if !env.isMember(userID) {
+		t.Fatal("a member was removed through another organization")
+	}
+}
+
+func TestUnassignMemberRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		holders int
+		role    string
+		code    int
+	}{
+		{name: "owner role with another owner", holders: 2, role: "owner"},
+		{name: "only holder of a plain role", holders: 1, role: "member"},
+		{name: "owner role from the last owner", holders: 1, role: "owner", code: http.StatusBadRequest},
+		{name: "role the member does not hold", holders: 0, role: "member", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			role := env.member
+			if tt.role == "owner" {
+				role = env.owner
+			}
+
+			userID := uuid.New()
+			env.join(userID)
+			for i := 0; i < tt.holders; i++ {
+				holder := userID
+				if i > 0 {
+					holder = uuid.New()
+					env.join(holder)
+				}
+				env.repository.members[env.organization.GetID()][holder][role.GetID()] = true
+			}
+
+			restErr := env.service.UnassignMemberRole(env.ctx, userID.String(), role.GetID().String())
+			if tt.code != 0 {
+				if restErr == nil || restErr.Code != tt.code {
+					t.Fatalf("UnassignMemberRole() error = %v, want %d", restErr, tt.code)
+				}
+			} else if restErr != nil {
+				t.Fatalf("UnassignMemberRole() error = %v", restErr)
+			}
+
+			held := env.repository.members[env.organization.GetID()][userID][role.GetID()]
+			if want := tt.code == http.StatusBadRequest; held != want {
+				t.Fatalf("role still held = %v, want %v", held, want)
+			}
+			if got := env.audit.Has(audit.ActionMemberRoleUnassign); got != (tt.code == 0) {
+				t.Errorf("audited = %v", got)
+			}
+		})
+	}
+}
+
+func TestAssignMemberRole(t *testing.T) {
+	env := newTestEnv()
+	userID := uuid.New()
+	env.join(userID, env.member)
+
+	if restErr := env.service.AssignMemberRole(env.ctx, userID.String(), RoleNameRequest{Role: " owner "}); restErr != nil {
+		t.Fatalf("AssignMemberRole() error = %v", restErr)
+	}
+	if !env.repository.members[env.organization.GetID()][userID][env.owner.GetID()] {
+		t.Fatal("the role was not assigned")
+	}
+
+	if restErr := env.service.AssignMemberRole(env.ctx, uuid.NewString(), RoleNameRequest{Role: "owner"}); restErr == nil || restErr.Code != http.StatusNotFound {
+		t.Fatalf("AssignMemberRole(non-member) error = %v, want 404", restErr)
+	}
+	if restErr := env.service.AssignMemberRole(env.ctx, userID.String(), RoleNameRequest{Role: "auditor"}); restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("AssignMemberRole(unknown role) error = %v, want 400", restErr)
+	}
+	if count := env.audit.Count(audit.ActionMemberRoleAssign); count != 1 {
+		t.Errorf("audited %d assignments, want 1", count)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		ttl     time.Duration
+		used    bool
+		token   string
+		invalid bool
+	}{
+		{name: "invited account", email: "ada@example.com", ttl: time.Hour},
+		{name: "another account", email: "grace@example.com", ttl: time.Hour, invalid: true},
+		{name: "expired", email: "ada@example.com", ttl: -time.Minute, invalid: true},
+		{name: "already accepted", email: "ada@example.com", ttl: time.Hour, used: true, invalid: true},
+		{name: "unknown token", email: "ada@example.com", ttl: time.Hour, token: "forged", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+
+			account, err := domain.New(tt.email, "", "", "Ada", "Lovelace")
+			if err != nil {
+				t.Fatalf("domain.New() error = %v", err)
+			}
+			env.users.Add(account)
+
+			roleID := env.member.GetID()
+			invitation, raw := domain.NewInvitation(env.organization.GetID(), "ada@example.com", &roleID, nil, tt.ttl)
+			if tt.used {
+				invitation.Accept()
+			}
+			env.repository.invitations[invitation.GetTokenHash()] = invitation
+			if tt.token != "" {
+				raw = tt.token
+			}
+
+			res, restErr := env.service.AcceptInvitation(context.Background(), account.GetID(), AcceptInvitationRequest{Token: raw})
+			if tt.invalid {
+				if restErr == nil || restErr.Code != http.StatusBadRequest {
+					t.Fatalf("AcceptInvitation() error = %v, want 400", restErr)
+				}
+				if env.isMember(account.GetID()) {
+					t.Fatal("a refused invitation created a membership")
+				}
+				return
+			}
+			if restErr != nil {
+				t.Fatalf("AcceptInvitation() error = %v", restErr)
+			}
+
+			if res.ID != env.organization.GetID().String() {
+				t.Errorf("AcceptInvitation() joined %s", res.ID)
+			}
+			if !env.repository.members[env.organization.GetID()][account.GetID()][roleID] {
+				t.Error("the invited role was not assigned")
+			}
+			if !invitation.IsAccepted() {
+				t.Error("the invitation can be used again")
+			}
+			if !account.IsEmailVerified() {
+				t.Error("following the invitation did not verify the email")
+			}
+			if !env.audit.Has(audit.ActionInvitationAccept) {
+				t.Error("the acceptance was not audited")
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Types of the domain events published to other services. They are part of
+// the public contract: rename one and every consumer breaks.
+const (
+	EventUserRegistered  = "user.registered"
+	EventPasswordChanged = "user.password_changed"
+	EventUserDeleted     = "user.deleted"
+	EventRoleGranted     = "user.role_granted"
+)
+
+// EventTypes lists every event type published.
+var EventTypes = []string{
+	EventUserRegistered,
+	EventPasswordChanged,
+	EventUserDeleted,
+	EventRoleGranted,
+}
+
+// EventInterface is the data of a domain event. Subject is the ID of the
+// entity the event is about; sinks that partition use it as the key, so
+// the events of one user stay in order.
+type EventInterface interface {
+	EventType() string
+	EventSubject() string
+}
+
+type UserRegistered struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+}
+
+func (e UserRegistered) EventType() string {
+	return EventUserRegistered
+}
+
+func (e UserRegistered) EventSubject() string {
+	return e.UserID.String()
+}
+
+// PasswordChanged carries no secret, only how the password was changed.
+type PasswordChanged struct {
+	UserID uuid.UUID `json:"user_id"`
+	Method string    `json:"method"`
+}
+
+func (e PasswordChanged) EventType() string {
+	return EventPasswordChanged
+}
+
+func (e PasswordChanged) EventSubject() string {
+	return e.UserID.String()
+}
+
+type UserDeleted struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+}
+
+func (e UserDeleted) EventType() string {
+	return EventUserDeleted
+}
+
+func (e UserDeleted) EventSubject() string {
+	return e.UserID.String()
+}
+
+type RoleGranted struct {
+	UserID uuid.UUID `json:"user_id"`
+	RoleID uuid.UUID `json:"role_id"`
+	Role   string    `json:"role"`
+}
+
+func (e RoleGranted) EventType() string {
+	return EventRoleGranted
+}
+
+func (e RoleGranted) EventSubject() string {
+	return e.UserID.String()
+}
+
+// Envelope is what every sink delivers. ID is stable across redeliveries,
+// which are possible, so consumers deduplicate on it.
+type Envelope struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	Subject    string          `json:"subject"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func NewEnvelope(event domain.OutboxEventInterface) Envelope {
+	return Envelope{
+		ID:         event.GetID(),
+		Type:       event.GetType(),
+		Subject:    event.GetSubject(),
+		OccurredAt: event.GetOccurredAt(),
+		Data:       event.GetPayload(),
+	}
+}
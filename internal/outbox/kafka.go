@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const kafkaClientID = "auth-service"
+
+// kafkaSink produces each event to OUTBOX_KAFKA_TOPIC, keyed by its subject
+// and with its type in a "type" header, and waits for every in-sync replica
+// to acknowledge it. Keys are hashed with murmur2 like the Java client's
+// default partitioner, so the events of a user stay in order and land where
+// other producers would put them.
+type kafkaSink struct {
+	client  *kgo.Client
+	topic   string
+	timeout time.Duration
+}
+
+func newKafkaSink(config config.OutboxConfig) (SinkInterface, error) {
+	timeout := time.Duration(config.KafkaTimeout) * time.Second
+	options := []kgo.Opt{
+		kgo.SeedBrokers(config.KafkaBrokers...),
+		kgo.ClientID(kafkaClientID),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.DialTimeout(timeout),
+		kgo.RecordDeliveryTimeout(timeout),
+	}
+	if config.KafkaTLS {
+		options = append(options, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+
+	// The client connects on first use, so an unreachable broker does not
+	// keep the service from starting.
+	client, err := kgo.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &kafkaSink{
+		client:  client,
+		topic:   config.KafkaTopic,
+		timeout: timeout,
+	}, nil
+}
+
+func (s *kafkaSink) Name() string {
+	return SinkKafka
+}
+
+func (s *kafkaSink) Deliver(ctx context.Context, envelope Envelope) error {
+	value, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	record := &kgo.Record{
+		Topic:   s.topic,
+		Key:     []byte(envelope.Subject),
+		Value:   value,
+		Headers: []kgo.RecordHeader{{Key: "type", Value: []byte(envelope.Type)}},
+	}
+	if err := s.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("failed to produce to kafka: %w", err)
+	}
+
+	return nil
+}
+
+func (s *kafkaSink) Close() error {
+	s.client.Close()
+	return nil
+}
@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/google/uuid"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const testKafkaTopic = "auth.events"
+
+func TestKafkaSinkDeliversKeyedRecords(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, testKafkaTopic))
+	if err != nil {
+		t.Fatalf("kfake.NewCluster() error = %v", err)
+	}
+	defer cluster.Close()
+
+	sink := newTestKafkaSink(t, cluster.ListenAddrs())
+	defer sink.Close()
+
+	ctx := context.Background()
+	sent := []Envelope{
+		testEnvelope("user.created", "user-1"),
+		testEnvelope("user.updated", "user-2"),
+		testEnvelope("user.deleted", "user-1"),
+	}
+	for _, envelope := range sent {
+		if err := sink.Deliver(ctx, envelope); err != nil {
+			t.Fatalf("Deliver(%s) error = %v", envelope.Type, err)
+		}
+	}
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(testKafkaTopic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		t.Fatalf("kgo.NewClient() error = %v", err)
+	}
+	defer consumer.Close()
+
+	records := pollRecords(t, consumer, len(sent))
+	byID := map[uuid.UUID]*kgo.Record{}
+	for _, record := range records {
+		var envelope Envelope
+		if err := json.Unmarshal(record.Value, &envelope); err != nil {
+			t.Fatalf("record value %q is not an envelope: %v", record.Value, err)
+		}
+		if string(record.Key) != envelope.Subject {
+			t.Fatalf("record key = %q, want %q", record.Key, envelope.Subject)
+		}
+		if len(record.Headers) != 1 || record.Headers[0].Key != "type" || string(record.Headers[0].Value) != envelope.Type {
+			t.Fatalf("record headers = %+v, want type %q", record.Headers, envelope.Type)
+		}
+		byID[envelope.ID] = record
+	}
+
+	first, last := byID[sent[0].ID], byID[sent[2].ID]
+	if first == nil || last == nil {
+		t.Fatalf("records = %d, missing events of user-1", len(records))
+	}
+	if first.Partition != last.Partition || first.Offset >= last.Offset {
+		t.Fatalf("user-1 events at %d/%d and %d/%d, want one partition in order",
+			first.Partition, first.Offset, last.Partition, last.Offset)
+	}
+}
+
+func TestKafkaSinkFailsWithoutBroker(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, testKafkaTopic))
+	if err != nil {
+		t.Fatalf("kfake.NewCluster() error = %v", err)
+	}
+	addrs := cluster.ListenAddrs()
+	cluster.Close()
+
+	sink := newTestKafkaSink(t, addrs)
+	defer sink.Close()
+
+	if err := sink.Deliver(context.Background(), testEnvelope("user.created", "user-1")); err == nil {
+		t.Fatal("Deliver() with the cluster down: no error")
+	}
+}
+
+func newTestKafkaSink(t *testing.T, brokers []string) SinkInterface {
+	t.Helper()
+
+	sink, err := newKafkaSink(config.OutboxConfig{
+		KafkaBrokers: brokers,
+		KafkaTopic:   testKafkaTopic,
+		KafkaTimeout: 2,
+	})
+	if err != nil {
+		t.Fatalf("newKafkaSink() error = %v", err)
+	}
+	return sink
+}
+
+func pollRecords(t *testing.T, consumer *kgo.Client, want int) []*kgo.Record {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var records []*kgo.Record
+	for len(records) < want {
+		fetches := consumer.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("consumed %d records, want %d", len(records), want)
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			t.Fatalf("fetch %s/%d error = %v", topic, partition, err)
+		})
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func testEnvelope(eventType, subject string) Envelope {
+	return Envelope{
+		ID:         uuid.New(),
+		Type:       eventType,
+		Subject:    subject,
+		OccurredAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:       json.RawMessage(`{"email":"jane@example.com"}`),
+	}
+}
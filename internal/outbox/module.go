@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewPublisher,
+		fx.Annotate(
+			NewSinks,
+			fx.ResultTags(`group:"outbox_sinks,flatten"`),
+		),
+		fx.Annotate(
+			NewRelay,
+			fx.ParamTags(``, ``, ``, `group:"outbox_sinks"`),
+		),
+	),
+	fx.Invoke(func(lc fx.Lifecycle, relay RelayInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return relay.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return relay.Stop(ctx)
+			},
+		})
+	}),
+)
+
+// AsSink adds the sink built by constructor to those the relay delivers to,
+// next to the one chosen by OUTBOX_SINK.
+func AsSink(constructor any) any {
+	return fx.Annotate(
+		constructor,
+		fx.As(new(SinkInterface)),
+		fx.ResultTags(`group:"outbox_sinks"`),
+	)
+}
@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/nats-io/nats.go"
+)
+
+const natsClientName = "auth-service"
+
+// natsSink publishes each event to "<OUTBOX_NATS_SUBJECT>.<type>" and
+// flushes, so the server's PONG confirms it processed the message. Core
+// NATS only reaches connected subscribers; a JetStream stream bound to the
+// subjects makes the events durable. Credentials and tls:// are taken from
+// OUTBOX_NATS_URL.
+type natsSink struct {
+	url     string
+	subject string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func newNATSSink(config config.OutboxConfig) SinkInterface {
+	return &natsSink{
+		url:     config.NATSURL,
+		subject: config.NATSSubject,
+		timeout: time.Duration(config.NATSTimeout) * time.Second,
+	}
+}
+
+func (s *natsSink) Name() string {
+	return SinkNATS
+}
+
+func (s *natsSink) Deliver(ctx context.Context, envelope Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	conn, err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := conn.Publish(s.subject+"."+envelope.Type, payload); err != nil {
+		return fmt.Errorf("failed to publish to nats: %w", err)
+	}
+	if err := conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to publish to nats: %w", err)
+	}
+
+	return nil
+}
+
+func (s *natsSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return nil
+}
+
+// connect dials on first use, so an unreachable server does not keep the
+// service from starting. The client reconnects on its own afterwards;
+// while it is disconnected publishing fails instead of buffering, and the
+// relay retries.
+func (s *natsSink) connect() (*nats.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil && !s.conn.IsClosed() {
+		return s.conn, nil
+	}
+
+	conn, err := nats.Connect(s.url,
+		nats.Name(natsClientName),
+		nats.Timeout(s.timeout),
+		nats.MaxReconnects(-1),
+		nats.ReconnectBufSize(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	s.conn = conn
+	return conn, nil
+}
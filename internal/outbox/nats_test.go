@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+func TestNATSSinkPublishesPerType(t *testing.T) {
+	server := natsserver.RunRandClientPortServer()
+	defer server.Shutdown()
+
+	subscriber, err := nats.Connect(server.ClientURL())
+	if err != nil {
+		t.Fatalf("nats.Connect() error = %v", err)
+	}
+	defer subscriber.Close()
+
+	messages := make(chan *nats.Msg, 4)
+	if _, err := subscriber.ChanSubscribe("auth.>", messages); err != nil {
+		t.Fatalf("ChanSubscribe() error = %v", err)
+	}
+	if err := subscriber.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	sink := newNATSSink(config.OutboxConfig{NATSURL: server.ClientURL(), NATSSubject: "auth", NATSTimeout: 2})
+	defer sink.Close()
+
+	sent := testEnvelope("user.created", "user-1")
+	if err := sink.Deliver(context.Background(), sent); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	select {
+	case msg := <-messages:
+		if msg.Subject != "auth.user.created" {
+			t.Fatalf("subject = %q, want auth.user.created", msg.Subject)
+		}
+		var got Envelope
+		if err := json.Unmarshal(msg.Data, &got); err != nil {
+			t.Fatalf("payload %q is not an envelope: %v", msg.Data, err)
+		}
+		if got.ID != sent.ID || got.Subject != sent.Subject {
+			t.Fatalf("envelope = %+v, want %+v", got, sent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestNATSSinkFailsWithoutServer(t *testing.T) {
+	server := natsserver.RunRandClientPortServer()
+	url := server.ClientURL()
+
+	sink := newNATSSink(config.OutboxConfig{NATSURL: url, NATSSubject: "auth", NATSTimeout: 1})
+	defer sink.Close()
+
+	ctx := context.Background()
+	if err := sink.Deliver(ctx, testEnvelope("user.created", "user-1")); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	server.Shutdown()
+	server.WaitForShutdown()
+	if err := sink.Deliver(ctx, testEnvelope("user.updated", "user-1")); err == nil {
+		t.Fatal("Deliver() with the server down: no error")
+	}
+}
@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+// maxNotifyPayload is the largest payload Postgres accepts in a
+// notification, less one byte for its terminator.
+const maxNotifyPayload = 7999
+
+type notifySink struct {
+	channel string
+	db      database.DatabaseInterface
+}
+
+func newNotifySink(config config.OutboxConfig, db database.DatabaseInterface) SinkInterface {
+	return &notifySink{
+		channel: config.NotifyChannel,
+		db:      db,
+	}
+}
+
+func (s *notifySink) Name() string {
+	return SinkNotify
+}
+
+// Deliver sends the envelope to the channel with pg_notify. It joins the
+// relay's transaction, so listeners get the event exactly when it is marked
+// delivered. Listeners that are not connected at that moment miss it.
+func (s *notifySink) Deliver(ctx context.Context, envelope Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	// Checked here rather than left to Postgres, whose error would abort
+	// the relay's transaction along with every other event in it.
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("event of %d bytes exceeds the notification limit", len(payload))
+	}
+
+	if _, err := s.db.GetQuerier(ctx).Exec(ctx, `SELECT pg_notify($1, $2)`, s.channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+
+	return nil
+}
+
+func (s *notifySink) Close() error {
+	return nil
+}
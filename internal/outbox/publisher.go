@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type publisher struct {
+	repository RepositoryInterface
+}
+
+// PublisherInterface writes domain events to the outbox. Publish must run in
+// the transaction of the change the event describes: the relay only sees the
+// event once that transaction commits, and never if it rolls back.
+type PublisherInterface interface {
+	Publish(ctx context.Context, event EventInterface) error
+}
+
+func NewPublisher(repository RepositoryInterface) PublisherInterface {
+	return &publisher{
+		repository: repository,
+	}
+}
+
+func (p *publisher) Publish(ctx context.Context, event EventInterface) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventType(), err)
+	}
+
+	return p.repository.Create(ctx, domain.NewOutboxEvent(event.EventType(), event.EventSubject(), payload))
+}
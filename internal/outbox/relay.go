@@ -0,0 +1,211 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+type relay struct {
+	config     config.OutboxConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	sinks      []SinkInterface
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// RelayInterface delivers committed outbox events to every sink, oldest
+// first. Delivery is at least once: an event is retried with exponential
+// backoff until all sinks accept it. Replicas relay concurrently without
+// delivering the same event twice at once, since each claims its batch
+// with row locks.
+type RelayInterface interface {
+	// Relay delivers one batch of due events and reports how many it
+	// claimed.
+	Relay(ctx context.Context) (int, error)
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewRelay(
+	config config.OutboxConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	sinks []SinkInterface,
+) RelayInterface {
+	return &relay{
+		config:     config,
+		db:         db,
+		repository: repository,
+		sinks:      sinks,
+	}
+}
+
+// Relay holds the batch's row locks while the sinks run, so a slow sink
+// delays the batch rather than letting another replica deliver it too.
+func (r *relay) Relay(ctx context.Context) (int, error) {
+	claimed := 0
+
+	err := r.db.WithTx(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+
+		events, err := r.repository.ClaimDue(ctx, now, r.config.MaxAttempts, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		claimed = len(events)
+
+		for _, event := range events {
+			if err := r.deliver(ctx, NewEnvelope(event)); err != nil {
+				event.Fail(err.Error(), now.Add(r.backoff(event.GetAttempts()+1)))
+				if r.config.MaxAttempts > 0 && event.GetAttempts() >= r.config.MaxAttempts {
+					slog.Error("giving up on outbox event",
+						slog.String("event_id", event.GetID().String()),
+						slog.String("type", event.GetType()),
+						slog.Int("attempts", event.GetAttempts()),
+						slog.String("error", err.Error()),
+					)
+				} else {
+					slog.Warn("failed to deliver outbox event",
+						slog.String("event_id", event.GetID().String()),
+						slog.String("type", event.GetType()),
+						slog.Int("attempts", event.GetAttempts()),
+						slog.String("error", err.Error()),
+					)
+				}
+			} else {
+				event.MarkPublished(now)
+			}
+
+			if err := r.repository.UpdateDelivery(ctx, event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return claimed, nil
+}
+
+func (r *relay) Start(ctx context.Context) error {
+	slog.Info("starting outbox relay", slog.Int("sinks", len(r.sinks)), slog.Int("interval", r.config.PollInterval))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.done.Add(1)
+	go r.run(runCtx)
+
+	return nil
+}
+
+func (r *relay) Stop(ctx context.Context) error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		r.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		for _, sink := range r.sinks {
+			if err := sink.Close(); err != nil {
+				slog.Warn("failed to close outbox sink", slog.String("sink", sink.Name()), "error", err)
+			}
+		}
+		slog.Info("outbox relay stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *relay) run(ctx context.Context) {
+	defer r.done.Done()
+
+	ticker := time.NewTicker(time.Duration(r.config.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.drain(ctx)
+			r.purge(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drain relays batches until one comes back short, so a backlog is worked
+// off without waiting a tick per batch.
+func (r *relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := r.Relay(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				slog.Error("failed to relay outbox events", "error", err)
+			}
+			return
+		}
+		if claimed < r.config.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *relay) purge(ctx context.Context) {
+	before := time.Now().UTC().Add(-time.Duration(r.config.Retention) * time.Second)
+
+	deleted, err := r.repository.DeletePublished(ctx, before)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Warn("failed to purge outbox events", "error", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		slog.Debug("purged outbox events", slog.Int64("count", deleted))
+	}
+}
+
+// deliver hands the envelope to every sink. Sinks that succeed get the event
+// again when another fails, which at-least-once delivery allows.
+func (r *relay) deliver(ctx context.Context, envelope Envelope) error {
+	for _, sink := range r.sinks {
+		if err := sink.Deliver(ctx, envelope); err != nil {
+			return errors.New(sink.Name() + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// backoff doubles the retry delay with each attempt, up to the maximum.
+func (r *relay) backoff(attempt int) time.Duration {
+	delay := time.Duration(r.config.RetryBackoff) * time.Second
+	limit := time.Duration(r.config.MaxRetryBackoff) * time.Second
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	return delay
+}
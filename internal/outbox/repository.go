@@ -0,0 +1,149 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrEventNotFound = errors.New("outbox event not found")
+
+const selectEventColumns = `
+	SELECT seq, id, type, subject, payload, occurred_at, attempts, last_error, next_attempt_at, published_at
+	FROM outbox_events`
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	// Create writes the event in the caller's transaction.
+	Create(ctx context.Context, event domain.OutboxEventInterface) error
+	// ClaimDue locks up to limit undelivered events due at now, oldest
+	// first, skipping those another relay holds. The locks last until the
+	// surrounding transaction ends.
+	ClaimDue(ctx context.Context, now time.Time, maxAttempts, limit int) ([]domain.OutboxEventInterface, error)
+	// UpdateDelivery stores the outcome of a delivery attempt.
+	UpdateDelivery(ctx context.Context, event domain.OutboxEventInterface) error
+	// DeletePublished drops events published before the given time.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, event domain.OutboxEventInterface) error {
+	query := `
+		INSERT INTO outbox_events (id, type, subject, payload, occurred_at, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		event.GetID(),
+		event.GetType(),
+		event.GetSubject(),
+		event.GetPayload(),
+		event.GetOccurredAt(),
+		event.GetNextAttemptAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) ClaimDue(ctx context.Context, now time.Time, maxAttempts, limit int) ([]domain.OutboxEventInterface, error) {
+	query := selectEventColumns + `
+		WHERE published_at IS NULL AND next_attempt_at <= $1 AND ($2 = 0 OR attempts < $2)
+		ORDER BY seq
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, now, maxAttempts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []domain.OutboxEventInterface
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate outbox events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *repository) UpdateDelivery(ctx context.Context, event domain.OutboxEventInterface) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = $2, last_error = $3, next_attempt_at = $4, published_at = $5
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		event.GetID(),
+		event.GetAttempts(),
+		event.GetLastError(),
+		event.GetNextAttemptAt(),
+		event.GetPublishedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox event: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE published_at < $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func scanEvent(row pgx.Row) (domain.OutboxEventInterface, error) {
+	var (
+		seq           int64
+		id            uuid.UUID
+		eventType     string
+		subject       string
+		payload       []byte
+		occurredAt    time.Time
+		attempts      int
+		lastError     string
+		nextAttemptAt time.Time
+		publishedAt   *time.Time
+	)
+
+	err := row.Scan(&seq, &id, &eventType, &subject, &payload, &occurredAt, &attempts, &lastError, &nextAttemptAt, &publishedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+	}
+
+	return domain.RestoreOutboxEvent(id, seq, eventType, subject, payload, occurredAt, attempts, lastError, nextAttemptAt, publishedAt), nil
+}
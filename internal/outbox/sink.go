@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const (
+	SinkNone    = "none"
+	SinkWebhook = "webhook"
+	SinkNATS    = "nats"
+	SinkKafka   = "kafka"
+	SinkNotify  = "notify"
+)
+
+// SinkInterface delivers events outside the service. Deliver returns only
+// once the destination has accepted the event; an error makes the relay
+// try again later, so a sink may deliver the same event more than once.
+type SinkInterface interface {
+	Name() string
+	Deliver(ctx context.Context, envelope Envelope) error
+	Close() error
+}
+
+// NewSinks builds the sink chosen by OUTBOX_SINK. With none, events are
+// still written and relayed, just to nowhere, so enabling a sink later
+// does not flush a backlog of stale events at it.
+func NewSinks(config config.OutboxConfig, db database.DatabaseInterface) ([]SinkInterface, error) {
+	switch config.Sink {
+	case SinkNone:
+		return nil, nil
+	case SinkWebhook:
+		if config.WebhookURL == "" {
+			return nil, fmt.Errorf("outbox webhook sink requires OUTBOX_WEBHOOK_URL")
+		}
+		return []SinkInterface{newWebhookSink(config)}, nil
+	case SinkNATS:
+		return []SinkInterface{newNATSSink(config)}, nil
+	case SinkKafka:
+		if len(config.KafkaBrokers) == 0 {
+			return nil, fmt.Errorf("outbox kafka sink requires OUTBOX_KAFKA_BROKERS")
+		}
+		sink, err := newKafkaSink(config)
+		if err != nil {
+			return nil, err
+		}
+		return []SinkInterface{sink}, nil
+	case SinkNotify:
+		return []SinkInterface{newNotifySink(config, db)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported outbox sink %q", config.Sink)
+	}
+}
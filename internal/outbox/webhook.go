@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+)
+
+// Headers of a webhook delivery.
+const (
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+	HeaderSignature = "X-Signature"
+)
+
+type webhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func newWebhookSink(config config.OutboxConfig) SinkInterface {
+	return &webhookSink{
+		url:    config.WebhookURL,
+		secret: config.WebhookSecret,
+		client: &http.Client{Timeout: time.Duration(config.WebhookTimeout) * time.Second},
+	}
+}
+
+func (s *webhookSink) Name() string {
+	return SinkWebhook
+}
+
+// Deliver posts the envelope as JSON. Any 2xx response accepts it.
+func (s *webhookSink) Deliver(ctx context.Context, envelope Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, envelope.ID.String())
+	req.Header.Set(HeaderEventType, envelope.Type)
+	if s.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(s.secret, time.Now().UTC(), body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Sign computes the signature header of a webhook body: "t=<unix seconds>,
+// v1=<hex HMAC-SHA256 of "<t>.<body>">". Receivers recompute it with the
+// shared secret and reject stale timestamps, which defeats replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
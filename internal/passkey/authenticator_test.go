@@ -0,0 +1,177 @@
+package passkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// Authenticator data flags, WebAuthn section 6.1.
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagBackupEligible   = 0x08
+	flagBackupState      = 0x10
+	flagAttestedCredData = 0x40
+)
+
+// softAuthenticator is a platform authenticator in software: an ES256 key
+// pair, a signature counter and "none" attestation. It answers the options
+// the service hands out the way a browser and authenticator would together.
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	origin       string
+	signCount    uint32
+	// userVerified is whether assertions report that the user was verified,
+	// as a biometric or PIN check would.
+	userVerified bool
+}
+
+func newSoftAuthenticator(t *testing.T, origin string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 32)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential id: %v", err)
+	}
+
+	return &softAuthenticator{t: t, key: key, credentialID: credentialID, origin: origin, userVerified: true}
+}
+
+// clone copies the key and counter, as an attacker who extracted the key
+// would.
+func (a *softAuthenticator) clone() *softAuthenticator {
+	copied := *a
+	return &copied
+}
+
+// create answers navigator.credentials.create.
+func (a *softAuthenticator) create(options any) json.RawMessage {
+	a.t.Helper()
+
+	creation, ok := options.(*protocol.CredentialCreation)
+	if !ok {
+		a.t.Fatalf("options are %T, want *protocol.CredentialCreation", options)
+	}
+	clientData := a.clientData(protocol.CreateCeremony, creation.Response.Challenge)
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatalf("encode public key: %v", err)
+	}
+
+	a.signCount++
+	authData := a.authData(creation.Response.RelyingParty.ID, flagAttestedCredData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(struct {
+		Format       string         `cbor:"fmt"`
+		AttStatement map[string]any `cbor:"attStmt"`
+		AuthData     []byte         `cbor:"authData"`
+	}{Format: "none", AttStatement: map[string]any{}, AuthData: authData})
+	if err != nil {
+		a.t.Fatalf("encode attestation object: %v", err)
+	}
+
+	return a.credential(map[string]any{
+		"clientDataJSON":    encode(clientData),
+		"attestationObject": encode(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// get answers navigator.credentials.get for a user whose handle is
+// userHandle.
+func (a *softAuthenticator) get(options any, userHandle []byte) json.RawMessage {
+	a.t.Helper()
+
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	if !ok {
+		a.t.Fatalf("options are %T, want *protocol.CredentialAssertion", options)
+	}
+	clientData := a.clientData(protocol.AssertCeremony, assertion.Response.Challenge)
+
+	a.signCount++
+	authData := a.authData(assertion.Response.RelyingPartyID, 0)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("sign assertion: %v", err)
+	}
+
+	return a.credential(map[string]any{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(userHandle),
+	})
+}
+
+func (a *softAuthenticator) clientData(ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":        string(ceremony),
+		"challenge":   challenge.String(),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		a.t.Fatalf("encode client data: %v", err)
+	}
+	return data
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte) []byte {
+	flags |= flagUserPresent | flagBackupEligible | flagBackupState
+	if a.userVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.signCount)
+}
+
+func (a *softAuthenticator) credential(response map[string]any) json.RawMessage {
+	data, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		a.t.Fatalf("encode credential: %v", err)
+	}
+	return data
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
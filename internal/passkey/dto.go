@@ -0,0 +1,34 @@
+package passkey
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BeginResponse carries the options for navigator.credentials.create/get and
+// the token that identifies the ceremony when it is finished.
+type BeginResponse struct {
+	SessionToken string `json:"session_token"`
+	Options      any    `json:"options"`
+}
+
+type FinishRegistrationRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Name         string          `json:"name" binding:"max=255"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+type FinishLoginRequest struct {
+	SessionToken string          `json:"session_token" binding:"required"`
+	Credential   json.RawMessage `json:"credential" binding:"required"`
+}
+
+type PasskeyResponse struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	AttestationType string     `json:"attestation_type"`
+	Transports      []string   `json:"transports"`
+	BackedUp        bool       `json:"backed_up"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -0,0 +1,103 @@
+package passkey
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	service ServiceInterface
+	tokens  token.ManagerInterface
+	account config.AccountConfig
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	BeginRegistration(ctx *gin.Context)
+	FinishRegistration(ctx *gin.Context)
+	List(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+func NewHandler(service ServiceInterface, tokens token.ManagerInterface, account config.AccountConfig) HandlerInterface {
+	return &handler{
+		service: service,
+		tokens:  tokens,
+		account: account,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	passkeys := router.Group(
+		"/api/v1/passkeys",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+		middleware.RequireVerifiedEmail(h.account),
+	)
+	{
+		passkeys.GET("", h.List)
+		passkeys.POST("/registration", h.BeginRegistration)
+		passkeys.POST("/registration/finish", h.FinishRegistration)
+		passkeys.DELETE("/:id", h.Delete)
+	}
+}
+
+func (h *handler) BeginRegistration(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.BeginRegistration(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) FinishRegistration(ctx *gin.Context) {
+	var req FinishRegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.FinishRegistration(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) List(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	res, restErr := h.service.List(ctx.Request.Context(), userID)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Delete(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	if restErr := h.service.Delete(ctx.Request.Context(), userID, ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
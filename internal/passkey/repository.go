@@ -0,0 +1,229 @@
+package passkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrPasskeyNotFound      = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists = errors.New("passkey already registered")
+	ErrSessionNotFound      = errors.New("webauthn session not found")
+)
+
+const selectColumns = `
+	SELECT id, user_id, name, credential_id, public_key, attestation_type, transports, aaguid,
+		sign_count, backup_eligible, backup_state, clone_warning, last_used_at, created_at
+	FROM passkeys`
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, passkey domain.PasskeyInterface) error
+	UpdateUsage(ctx context.Context, passkey domain.PasskeyInterface) error
+	FindByUserID(ctx context.Context, userID uuid.UUID) ([]domain.PasskeyInterface, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	CountByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	CreateSession(ctx context.Context, session domain.WebAuthnSessionInterface) error
+	ConsumeSession(ctx context.Context, tokenHash, ceremony string) (domain.WebAuthnSessionInterface, error)
+	DeleteExpiredSessions(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, passkey domain.PasskeyInterface) error {
+	query := `
+		INSERT INTO passkeys (
+			id, user_id, name, credential_id, public_key, attestation_type, transports, aaguid,
+			sign_count, backup_eligible, backup_state, clone_warning, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		passkey.GetID(),
+		passkey.GetUserID(),
+		passkey.GetName(),
+		passkey.GetCredentialID(),
+		passkey.GetPublicKey(),
+		passkey.GetAttestationType(),
+		passkey.GetTransports(),
+		passkey.GetAAGUID(),
+		int64(passkey.GetSignCount()),
+		passkey.IsBackupEligible(),
+		passkey.IsBackedUp(),
+		passkey.HasCloneWarning(),
+		passkey.GetCreatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrPasskeyAlreadyExists
+		}
+		return fmt.Errorf("failed to insert passkey: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateUsage(ctx context.Context, passkey domain.PasskeyInterface) error {
+	query := `
+		UPDATE passkeys
+		SET sign_count = $2, backup_state = $3, clone_warning = $4, last_used_at = $5
+		WHERE id = $1`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		passkey.GetID(),
+		int64(passkey.GetSignCount()),
+		passkey.IsBackedUp(),
+		passkey.HasCloneWarning(),
+		passkey.GetLastUsedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update passkey usage: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]domain.PasskeyInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, selectColumns+` WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query passkeys: %w", err)
+	}
+	defer rows.Close()
+
+	var passkeys []domain.PasskeyInterface
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, passkey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate passkeys: %w", err)
+	}
+
+	return passkeys, nil
+}
+
+func (r *repository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM passkeys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrPasskeyNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM passkeys WHERE user_id = $1 AND clone_warning = FALSE`
+
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count passkeys: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *repository) CreateSession(ctx context.Context, session domain.WebAuthnSessionInterface) error {
+	query := `
+		INSERT INTO webauthn_sessions (id, token_hash, user_id, ceremony, data, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		session.GetID(),
+		session.GetTokenHash(),
+		session.GetUserID(),
+		session.GetCeremony(),
+		session.GetData(),
+		session.GetExpiresAt(),
+		session.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webauthn session: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeSession deletes and returns a ceremony session so that each
+// challenge can be answered at most once.
+func (r *repository) ConsumeSession(ctx context.Context, tokenHash, ceremony string) (domain.WebAuthnSessionInterface, error) {
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE token_hash = $1 AND ceremony = $2
+		RETURNING id, user_id, data, expires_at, created_at`
+
+	var (
+		id                   uuid.UUID
+		userID               *uuid.UUID
+		data                 []byte
+		expiresAt, createdAt time.Time
+	)
+
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash, ceremony).Scan(&id, &userID, &data, &expiresAt, &createdAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to consume webauthn session: %w", err)
+	}
+
+	return domain.RestoreWebAuthnSession(id, tokenHash, userID, ceremony, data, expiresAt, createdAt), nil
+}
+
+func (r *repository) DeleteExpiredSessions(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM webauthn_sessions WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired webauthn sessions: %w", err)
+	}
+	return nil
+}
+
+func scanPasskey(row pgx.Row) (domain.PasskeyInterface, error) {
+	var (
+		id, userID                               uuid.UUID
+		name, attestationType                    string
+		credentialID, publicKey, aaguid          []byte
+		transports                               []string
+		signCount                                int64
+		backupEligible, backupState, cloneWarned bool
+		lastUsedAt                               *time.Time
+		createdAt                                time.Time
+	)
+
+	err := row.Scan(
+		&id, &userID, &name, &credentialID, &publicKey, &attestationType, &transports, &aaguid,
+		&signCount, &backupEligible, &backupState, &cloneWarned, &lastUsedAt, &createdAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan passkey: %w", err)
+	}
+
+	return domain.RestorePasskey(
+		id, userID, name, credentialID, publicKey, attestationType, transports, aaguid,
+		uint32(signCount), backupEligible, backupState, cloneWarned, lastUsedAt, createdAt,
+	), nil
+}
@@ -0,0 +1,448 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPasskeyName = "Passkey"
+
+	invalidSessionMessage = "invalid or expired webauthn session"
+	loginFailedMessage    = "passkey verification failed"
+)
+
+type service struct {
+	config     config.WebAuthnConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	events     security.EmitterInterface
+	audit      audit.RecorderInterface
+	webauthn   *webauthn.WebAuthn
+}
+
+type ServiceInterface interface {
+	BeginRegistration(ctx context.Context, userID uuid.UUID) (*BeginResponse, *httperr.HttpError)
+	FinishRegistration(ctx context.Context, userID uuid.UUID, req FinishRegistrationRequest) (*PasskeyResponse, *httperr.HttpError)
+	List(ctx context.Context, userID uuid.UUID) ([]PasskeyResponse, *httperr.HttpError)
+	Delete(ctx context.Context, userID uuid.UUID, id string) *httperr.HttpError
+	HasPasskeys(ctx context.Context, userID uuid.UUID) (bool, *httperr.HttpError)
+	BeginLogin(ctx context.Context, userID *uuid.UUID) (*BeginResponse, *httperr.HttpError)
+	FinishLogin(ctx context.Context, userID *uuid.UUID, req FinishLoginRequest) (uuid.UUID, *httperr.HttpError)
+}
+
+func NewService(
+	config config.WebAuthnConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	events security.EmitterInterface,
+	audit audit.RecorderInterface,
+) (ServiceInterface, error) {
+	attestation := protocol.ConveyancePreference(config.Attestation)
+	switch attestation {
+	case protocol.PreferNoAttestation, protocol.PreferIndirectAttestation,
+		protocol.PreferDirectAttestation, protocol.PreferEnterpriseAttestation:
+	default:
+		return nil, fmt.Errorf("unsupported webauthn attestation preference %q", config.Attestation)
+	}
+
+	ttl := time.Duration(config.SessionTTL) * time.Second
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: ttl, TimeoutUVD: ttl}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:                  config.RPID,
+		RPDisplayName:         config.RPDisplayName,
+		RPOrigins:             config.RPOrigins,
+		AttestationPreference: attestation,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure webauthn: %w", err)
+	}
+
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		users:      users,
+		events:     events,
+		audit:      audit,
+		webauthn:   wa,
+	}, nil
+}
+
+// BeginRegistration asks for a discoverable credential where the
+// authenticator supports one, so the passkey also works without a username.
+func (s *service) BeginRegistration(ctx context.Context, userID uuid.UUID) (*BeginResponse, *httperr.HttpError) {
+	account, restErr := s.loadUser(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(account.passkeys))
+	for _, passkey := range account.passkeys {
+		exclusions = append(exclusions, toCredential(passkey).Descriptor())
+	}
+
+	options, session, err := s.webauthn.BeginRegistration(account,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		slog.Error("failed to begin passkey registration", "error", err)
+		return nil, httperr.NewInternalServerError("failed to begin passkey registration")
+	}
+
+	return s.begin(ctx, &userID, domain.WebAuthnCeremonyRegistration, options, session)
+}
+
+func (s *service) FinishRegistration(ctx context.Context, userID uuid.UUID, req FinishRegistrationRequest) (*PasskeyResponse, *httperr.HttpError) {
+	session, restErr := s.consume(ctx, &userID, domain.WebAuthnCeremonyRegistration, req.SessionToken)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	account, restErr := s.loadUser(ctx, userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, httperr.NewBadRequestError("malformed passkey credential")
+	}
+
+	// CreateCredential verifies the client data, the authenticator data and
+	// the attestation statement for the configured conveyance preference.
+	credential, err := s.webauthn.CreateCredential(account, *session, parsed)
+	if err != nil {
+		slog.Warn("passkey registration rejected", "error", err)
+		return nil, httperr.NewBadRequestError("passkey registration failed")
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	passkey := domain.NewPasskey(
+		userID,
+		name,
+		credential.ID,
+		credential.PublicKey,
+		credential.AttestationType,
+		transports,
+		credential.Authenticator.AAGUID,
+		credential.Authenticator.SignCount,
+		credential.Flags.BackupEligible,
+		credential.Flags.BackupState,
+	)
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Create(ctx, passkey); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionPasskeyAdd,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetPasskey,
+			TargetID:   passkey.GetID().String(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasskeyAlreadyExists) {
+			return nil, httperr.NewBadRequestError("passkey is already registered")
+		}
+		slog.Error("failed to store passkey", "error", err)
+		return nil, httperr.NewInternalServerError("failed to register passkey")
+	}
+
+	return toResponse(passkey), nil
+}
+
+func (s *service) List(ctx context.Context, userID uuid.UUID) ([]PasskeyResponse, *httperr.HttpError) {
+	passkeys, err := s.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("failed to list passkeys", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list passkeys")
+	}
+
+	res := make([]PasskeyResponse, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		res = append(res, *toResponse(passkey))
+	}
+
+	return res, nil
+}
+
+func (s *service) Delete(ctx context.Context, userID uuid.UUID, id string) *httperr.HttpError {
+	passkeyID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("passkey not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Delete(ctx, userID, passkeyID); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionPasskeyRemove,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetPasskey,
+			TargetID:   passkeyID.String(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasskeyNotFound) {
+			return httperr.NewNotFoundError("passkey not found")
+		}
+		slog.Error("failed to delete passkey", "error", err)
+		return httperr.NewInternalServerError("failed to delete passkey")
+	}
+
+	return nil
+}
+
+func (s *service) HasPasskeys(ctx context.Context, userID uuid.UUID) (bool, *httperr.HttpError) {
+	count, err := s.repository.CountByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("failed to count passkeys", "error", err)
+		return false, httperr.NewInternalServerError("failed to check passkeys")
+	}
+
+	return count > 0, nil
+}
+
+// BeginLogin starts an assertion. Without a user it is a discoverable,
+// passwordless login and user verification is required; with a user it is
+// a second factor after the password.
+func (s *service) BeginLogin(ctx context.Context, userID *uuid.UUID) (*BeginResponse, *httperr.HttpError) {
+	if userID == nil {
+		options, session, err := s.webauthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+		if err != nil {
+			slog.Error("failed to begin passkey login", "error", err)
+			return nil, httperr.NewInternalServerError("failed to begin passkey login")
+		}
+		return s.begin(ctx, nil, domain.WebAuthnCeremonyLogin, options, session)
+	}
+
+	account, restErr := s.loadUser(ctx, *userID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	if len(account.WebAuthnCredentials()) == 0 {
+		return nil, httperr.NewBadRequestError("no passkey is registered")
+	}
+
+	options, session, err := s.webauthn.BeginLogin(account,
+		webauthn.WithUserVerification(protocol.VerificationPreferred),
+	)
+	if err != nil {
+		slog.Error("failed to begin passkey login", "error", err)
+		return nil, httperr.NewInternalServerError("failed to begin passkey login")
+	}
+
+	return s.begin(ctx, userID, domain.WebAuthnCeremonyLogin, options, session)
+}
+
+// FinishLogin verifies an assertion and returns the authenticated user. A
+// signature counter that did not increase means the credential may have been
+// cloned: it is disabled and the login rejected.
+func (s *service) FinishLogin(ctx context.Context, userID *uuid.UUID, req FinishLoginRequest) (uuid.UUID, *httperr.HttpError) {
+	session, restErr := s.consume(ctx, userID, domain.WebAuthnCeremonyLogin, req.SessionToken)
+	if restErr != nil {
+		return uuid.Nil, restErr
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return uuid.Nil, httperr.NewBadRequestError("malformed passkey assertion")
+	}
+
+	var (
+		account    *webAuthnUser
+		credential *webauthn.Credential
+	)
+
+	if userID == nil {
+		var resolved webauthn.User
+		resolved, credential, err = s.webauthn.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			handleID, err := uuid.FromBytes(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			found, restErr := s.loadUser(ctx, handleID)
+			if restErr != nil {
+				return nil, errors.New(restErr.Message)
+			}
+			return found, nil
+		}, *session, parsed)
+		if resolved != nil {
+			account = resolved.(*webAuthnUser)
+		}
+	} else {
+		account, restErr = s.loadUser(ctx, *userID)
+		if restErr != nil {
+			return uuid.Nil, restErr
+		}
+		credential, err = s.webauthn.ValidateLogin(account, *session, parsed)
+	}
+	if err != nil {
+		slog.Warn("passkey assertion rejected", "error", err)
+		return uuid.Nil, httperr.NewUnauthorizedRequestError(loginFailedMessage)
+	}
+
+	passkey := account.find(credential.ID)
+	if passkey == nil {
+		return uuid.Nil, httperr.NewUnauthorizedRequestError(loginFailedMessage)
+	}
+
+	if credential.Authenticator.CloneWarning {
+		passkey.MarkCloned()
+		err := s.db.WithTx(ctx, func(ctx context.Context) error {
+			if err := s.repository.UpdateUsage(ctx, passkey); err != nil {
+				return err
+			}
+			owner := passkey.GetUserID().String()
+			return s.audit.Record(ctx, audit.Entry{
+				Action:     audit.ActionPasskeyCloned,
+				Outcome:    domain.AuditOutcomeFailure,
+				TargetType: audit.TargetPasskey,
+				TargetID:   passkey.GetID().String(),
+				Actor:      &requestinfo.Actor{Type: requestinfo.ActorUser, ID: owner},
+			})
+		})
+		if err != nil {
+			slog.Error("failed to flag cloned passkey", "error", err)
+		}
+
+		s.events.Emit(ctx, security.Event{
+			Type:   security.EventPasskeyCloneDetected,
+			UserID: passkey.GetUserID().String(),
+			Attributes: map[string]string{
+				"passkey_id": passkey.GetID().String(),
+			},
+		})
+		return uuid.Nil, httperr.NewUnauthorizedRequestError(loginFailedMessage)
+	}
+
+	passkey.RecordUse(credential.Authenticator.SignCount, credential.Flags.BackupState, time.Now().UTC())
+	if err := s.repository.UpdateUsage(ctx, passkey); err != nil {
+		slog.Error("failed to record passkey use", "error", err)
+		return uuid.Nil, httperr.NewInternalServerError("failed to verify passkey")
+	}
+
+	return passkey.GetUserID(), nil
+}
+
+func (s *service) begin(
+	ctx context.Context,
+	userID *uuid.UUID,
+	ceremony string,
+	options any,
+	session *webauthn.SessionData,
+) (*BeginResponse, *httperr.HttpError) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		slog.Error("failed to encode webauthn session", "error", err)
+		return nil, httperr.NewInternalServerError("failed to start webauthn ceremony")
+	}
+
+	stored, raw := domain.NewWebAuthnSession(userID, ceremony, data, time.Duration(s.config.SessionTTL)*time.Second)
+	if err := s.repository.CreateSession(ctx, stored); err != nil {
+		slog.Error("failed to store webauthn session", "error", err)
+		return nil, httperr.NewInternalServerError("failed to start webauthn ceremony")
+	}
+
+	if err := s.repository.DeleteExpiredSessions(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up webauthn sessions", "error", err)
+	}
+
+	return &BeginResponse{SessionToken: raw, Options: options}, nil
+}
+
+// consume loads a ceremony session and checks it belongs to the same user (or
+// to no user for discoverable logins) as the request finishing it.
+func (s *service) consume(ctx context.Context, userID *uuid.UUID, ceremony, rawToken string) (*webauthn.SessionData, *httperr.HttpError) {
+	stored, err := s.repository.ConsumeSession(ctx, domain.HashOpaqueToken(rawToken), ceremony)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, httperr.NewBadRequestError(invalidSessionMessage)
+		}
+		slog.Error("failed to load webauthn session", "error", err)
+		return nil, httperr.NewInternalServerError("failed to finish webauthn ceremony")
+	}
+
+	owner := stored.GetUserID()
+	if (owner == nil) != (userID == nil) || (owner != nil && *owner != *userID) || stored.IsExpired(time.Now().UTC()) {
+		return nil, httperr.NewBadRequestError(invalidSessionMessage)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(stored.GetData(), &session); err != nil {
+		slog.Error("failed to decode webauthn session", "error", err)
+		return nil, httperr.NewInternalServerError("failed to finish webauthn ceremony")
+	}
+
+	return &session, nil
+}
+
+func (s *service) loadUser(ctx context.Context, userID uuid.UUID) (*webAuthnUser, *httperr.HttpError) {
+	found, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to load user for webauthn", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load user")
+	}
+
+	passkeys, err := s.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("failed to load passkeys", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load passkeys")
+	}
+
+	return &webAuthnUser{user: found, passkeys: passkeys}, nil
+}
+
+func toResponse(passkey domain.PasskeyInterface) *PasskeyResponse {
+	return &PasskeyResponse{
+		ID:              passkey.GetID().String(),
+		Name:            passkey.GetName(),
+		AttestationType: passkey.GetAttestationType(),
+		Transports:      passkey.GetTransports(),
+		BackedUp:        passkey.IsBackedUp(),
+		LastUsedAt:      passkey.GetLastUsedAt(),
+		CreatedAt:       passkey.GetCreatedAt(),
+	}
+}
@@ -0,0 +1,342 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/google/uuid"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+func TestRegisterAndLoginWithoutUsername(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+
+	passkeys, restErr := env.service.List(ctx, env.userID)
+	if restErr != nil {
+		t.Fatalf("List() error = %v", restErr)
+	}
+	if len(passkeys) != 1 || passkeys[0].AttestationType != "none" || passkeys[0].Name != "Laptop" || !passkeys[0].BackedUp {
+		t.Fatalf("List() = %+v", passkeys)
+	}
+
+	begin, restErr := env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	userID, restErr := env.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, env.userID[:]),
+	})
+	if restErr != nil {
+		t.Fatalf("FinishLogin() error = %v", restErr)
+	}
+	if userID != env.userID {
+		t.Fatalf("FinishLogin() = %s, want %s", userID, env.userID)
+	}
+
+	stored := env.repository.passkeys[0]
+	if stored.GetSignCount() != authenticator.signCount || stored.GetLastUsedAt() == nil {
+		t.Fatalf("sign count = %d, last used = %v; want %d and a time", stored.GetSignCount(), stored.GetLastUsedAt(), authenticator.signCount)
+	}
+}
+
+func TestLoginAsSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+
+	// User verification is only preferred when the password came first.
+	authenticator.userVerified = false
+
+	begin, restErr := env.service.BeginLogin(ctx, &env.userID)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	userID, restErr := env.service.FinishLogin(ctx, &env.userID, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, env.userID[:]),
+	})
+	if restErr != nil || userID != env.userID {
+		t.Fatalf("FinishLogin() = %s, %v", userID, restErr)
+	}
+}
+
+func TestLoginWithoutUsernameRequiresUserVerification(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+	authenticator.userVerified = false
+
+	begin, restErr := env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	_, restErr = env.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, env.userID[:]),
+	})
+	if restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FinishLogin() error = %v, want 401", restErr)
+	}
+}
+
+func TestCloneDetectionDisablesPasskey(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+	clone := authenticator.clone()
+
+	for range 2 {
+		env.login(authenticator)
+	}
+
+	// The clone's counter lags behind the genuine authenticator's.
+	begin, restErr := env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	_, restErr = env.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   clone.get(begin.Options, env.userID[:]),
+	})
+	if restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FinishLogin() with a clone: error = %v, want 401", restErr)
+	}
+
+	if !env.repository.passkeys[0].HasCloneWarning() {
+		t.Fatal("passkey not flagged as cloned")
+	}
+	if len(env.events.Events) != 1 || env.events.Events[0].Type != security.EventPasskeyCloneDetected {
+		t.Fatalf("events = %+v, want one clone detection", env.events.Events)
+	}
+	if !env.recorder.Has(audit.ActionPasskeyCloned) {
+		t.Fatal("clone detection was not audited")
+	}
+
+	// The flagged credential no longer opens the account, even from the
+	// genuine authenticator.
+	begin, restErr = env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	_, restErr = env.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, env.userID[:]),
+	})
+	if restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FinishLogin() after clone detection: error = %v, want 401", restErr)
+	}
+}
+
+func TestLoginRejectsAssertionFromAnotherOrigin(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+	authenticator.origin = "https://example.com.attacker.test"
+
+	begin, restErr := env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	_, restErr = env.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, env.userID[:]),
+	})
+	if restErr == nil || restErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FinishLogin() error = %v, want 401", restErr)
+	}
+}
+
+func TestSessionIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+
+	begin, restErr := env.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	req := FinishLoginRequest{SessionToken: begin.SessionToken, Credential: authenticator.get(begin.Options, env.userID[:])}
+	if _, restErr := env.service.FinishLogin(ctx, nil, req); restErr != nil {
+		t.Fatalf("FinishLogin() error = %v", restErr)
+	}
+
+	if _, restErr := env.service.FinishLogin(ctx, nil, req); restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("FinishLogin() replayed: error = %v, want 400", restErr)
+	}
+}
+
+func TestRegistrationRefusesKnownCredential(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	authenticator := newSoftAuthenticator(t, testOrigin)
+	env.register(authenticator)
+
+	begin, restErr := env.service.BeginRegistration(ctx, env.userID)
+	if restErr != nil {
+		t.Fatalf("BeginRegistration() error = %v", restErr)
+	}
+	_, restErr = env.service.FinishRegistration(ctx, env.userID, FinishRegistrationRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.create(begin.Options),
+	})
+	if restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("FinishRegistration() of a registered credential: error = %v, want 400", restErr)
+	}
+}
+
+type testEnv struct {
+	t          *testing.T
+	service    ServiceInterface
+	repository *fakeRepository
+	events     *testutil.Emitter
+	recorder   *testutil.Recorder
+	userID     uuid.UUID
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	account, err := domain.New("jane@example.com", "", "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+
+	env := &testEnv{
+		t:          t,
+		repository: &fakeRepository{sessions: map[string]domain.WebAuthnSessionInterface{}},
+		events:     &testutil.Emitter{},
+		recorder:   &testutil.Recorder{},
+		userID:     account.GetID(),
+	}
+
+	env.service, err = NewService(
+		config.WebAuthnConfig{RPID: testRPID, RPDisplayName: "Example", RPOrigins: []string{testOrigin}, Attestation: "none", SessionTTL: 300},
+		testutil.DB{},
+		env.repository,
+		testutil.NewUsers(account),
+		env.events,
+		env.recorder,
+	)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return env
+}
+
+func (e *testEnv) register(authenticator *softAuthenticator) {
+	e.t.Helper()
+
+	ctx := context.Background()
+	begin, restErr := e.service.BeginRegistration(ctx, e.userID)
+	if restErr != nil {
+		e.t.Fatalf("BeginRegistration() error = %v", restErr)
+	}
+	if _, restErr := e.service.FinishRegistration(ctx, e.userID, FinishRegistrationRequest{
+		SessionToken: begin.SessionToken,
+		Name:         "Laptop",
+		Credential:   authenticator.create(begin.Options),
+	}); restErr != nil {
+		e.t.Fatalf("FinishRegistration() error = %v", restErr)
+	}
+}
+
+func (e *testEnv) login(authenticator *softAuthenticator) {
+	e.t.Helper()
+
+	ctx := context.Background()
+	begin, restErr := e.service.BeginLogin(ctx, nil)
+	if restErr != nil {
+		e.t.Fatalf("BeginLogin() error = %v", restErr)
+	}
+	if _, restErr := e.service.FinishLogin(ctx, nil, FinishLoginRequest{
+		SessionToken: begin.SessionToken,
+		Credential:   authenticator.get(begin.Options, e.userID[:]),
+	}); restErr != nil {
+		e.t.Fatalf("FinishLogin() error = %v", restErr)
+	}
+}
+
+type fakeRepository struct {
+	passkeys []domain.PasskeyInterface
+	sessions map[string]domain.WebAuthnSessionInterface
+}
+
+func (r *fakeRepository) Create(_ context.Context, passkey domain.PasskeyInterface) error {
+	for _, existing := range r.passkeys {
+		if bytes.Equal(existing.GetCredentialID(), passkey.GetCredentialID()) {
+			return ErrPasskeyAlreadyExists
+		}
+	}
+	r.passkeys = append(r.passkeys, passkey)
+	return nil
+}
+
+// UpdateUsage has nothing to do: the service updates the stored passkey
+// in place.
+func (r *fakeRepository) UpdateUsage(context.Context, domain.PasskeyInterface) error {
+	return nil
+}
+
+func (r *fakeRepository) FindByUserID(_ context.Context, userID uuid.UUID) ([]domain.PasskeyInterface, error) {
+	var found []domain.PasskeyInterface
+	for _, passkey := range r.passkeys {
+		if passkey.GetUserID() == userID {
+			found = append(found, passkey)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeRepository) Delete(_ context.Context, userID, id uuid.UUID) error {
+	for i, passkey := range r.passkeys {
+		if passkey.GetUserID() == userID && passkey.GetID() == id {
+			r.passkeys = append(r.passkeys[:i], r.passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return ErrPasskeyNotFound
+}
+
+func (r *fakeRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	found, err := r.FindByUserID(ctx, userID)
+	return len(found), err
+}
+
+func (r *fakeRepository) CreateSession(_ context.Context, session domain.WebAuthnSessionInterface) error {
+	r.sessions[session.GetTokenHash()] = session
+	return nil
+}
+
+func (r *fakeRepository) ConsumeSession(_ context.Context, tokenHash, ceremony string) (domain.WebAuthnSessionInterface, error) {
+	session, ok := r.sessions[tokenHash]
+	if !ok || session.GetCeremony() != ceremony {
+		return nil, ErrSessionNotFound
+	}
+	delete(r.sessions, tokenHash)
+	return session, nil
+}
+
+func (r *fakeRepository) DeleteExpiredSessions(context.Context, time.Time) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package passkey
+
+import (
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// webAuthnUser adapts a user and their passkeys to the webauthn.User
+// interface. The user handle is the raw user ID, so a discoverable login
+// resolves straight to the account.
+type webAuthnUser struct {
+	user     domain.UserInterface
+	passkeys []domain.PasskeyInterface
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	id := u.user.GetID()
+	return id[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.GetEmail()
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return strings.TrimSpace(u.user.GetFirstName() + " " + u.user.GetLastName())
+}
+
+// WebAuthnCredentials leaves out credentials flagged as cloned so they can
+// never satisfy a login.
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.passkeys))
+	for _, passkey := range u.passkeys {
+		if passkey.HasCloneWarning() {
+			continue
+		}
+		credentials = append(credentials, toCredential(passkey))
+	}
+	return credentials
+}
+
+func (u *webAuthnUser) find(credentialID []byte) domain.PasskeyInterface {
+	for _, passkey := range u.passkeys {
+		if string(passkey.GetCredentialID()) == string(credentialID) {
+			return passkey
+		}
+	}
+	return nil
+}
+
+func toCredential(passkey domain.PasskeyInterface) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, len(passkey.GetTransports()))
+	for i, transport := range passkey.GetTransports() {
+		transports[i] = protocol.AuthenticatorTransport(transport)
+	}
+
+	return webauthn.Credential{
+		ID:              passkey.GetCredentialID(),
+		PublicKey:       passkey.GetPublicKey(),
+		AttestationType: passkey.GetAttestationType(),
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: passkey.IsBackupEligible(),
+			BackupState:    passkey.IsBackedUp(),
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    passkey.GetAAGUID(),
+			SignCount: passkey.GetSignCount(),
+		},
+	}
+}
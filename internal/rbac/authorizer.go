@@ -0,0 +1,95 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+)
+
+// authorizer answers permission checks from an in-memory snapshot of every
+// role's effective permissions. Changes made through this instance take
+// effect at once; changes made by other instances within the cache TTL.
+type authorizer struct {
+	config     config.RBACConfig
+	repository RepositoryInterface
+
+	mu          sync.RWMutex
+	permissions map[string][]domain.PermissionInterface
+	loadedAt    time.Time
+}
+
+type AuthorizerInterface interface {
+	RolesAllow(ctx context.Context, roles []string, resource, action string) (bool, error)
+	Invalidate()
+}
+
+func NewAuthorizer(config config.RBACConfig, repository RepositoryInterface) AuthorizerInterface {
+	return &authorizer{
+		config:     config,
+		repository: repository,
+	}
+}
+
+// RolesAllow reports whether any of the roles, directly or through
+// inheritance, holds a permission matching the resource and action.
+func (a *authorizer) RolesAllow(ctx context.Context, roles []string, resource, action string) (bool, error) {
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	permissions, err := a.snapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		for _, permission := range permissions[role] {
+			if permission.Allows(resource, action) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (a *authorizer) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.permissions = nil
+}
+
+func (a *authorizer) snapshot(ctx context.Context) (map[string][]domain.PermissionInterface, error) {
+	a.mu.RLock()
+	if a.isFresh() {
+		permissions := a.permissions
+		a.mu.RUnlock()
+		return permissions, nil
+	}
+	a.mu.RUnlock()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.isFresh() {
+		return a.permissions, nil
+	}
+
+	permissions, err := a.repository.FindEffectivePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a.permissions = permissions
+	a.loadedAt = time.Now()
+
+	return permissions, nil
+}
+
+func (a *authorizer) isFresh() bool {
+	return a.permissions != nil && time.Since(a.loadedAt) < time.Duration(a.config.CacheTTL)*time.Second
+}
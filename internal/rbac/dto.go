@@ -0,0 +1,92 @@
+package rbac
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=1024"`
+}
+
+type PermissionRequest struct {
+	Resource    string `json:"resource" binding:"required,max=128"`
+	Action      string `json:"action" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=1024"`
+}
+
+type RoleNameRequest struct {
+	Role string `json:"role" binding:"required,max=64"`
+}
+
+type CheckRequest struct {
+	Subject  string `json:"subject" binding:"required"`
+	OrgID    string `json:"org_id" binding:"omitempty,uuid"`
+	Resource string `json:"resource" binding:"required,max=128"`
+	Action   string `json:"action" binding:"required,max=64"`
+}
+
+type BatchCheckRequest struct {
+	Checks []CheckRequest `json:"checks" binding:"required,min=1,max=100,dive"`
+}
+
+type CheckResponse struct {
+	Subject  string `json:"subject"`
+	OrgID    string `json:"org_id,omitempty"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Allowed  bool   `json:"allowed"`
+}
+
+type BatchCheckResponse struct {
+	Results []CheckResponse `json:"results"`
+}
+
+type PermissionResponse struct {
+	ID          string `json:"id"`
+	Permission  string `json:"permission"`
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description,omitempty"`
+}
+
+type RoleResponse struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Parents     []string             `json:"parents,omitempty"`
+	Permissions []PermissionResponse `json:"permissions,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+func NewPermissionResponse(permission domain.PermissionInterface) PermissionResponse {
+	return PermissionResponse{
+		ID:          permission.GetID().String(),
+		Permission:  permission.String(),
+		Resource:    permission.GetResource(),
+		Action:      permission.GetAction(),
+		Description: permission.GetDescription(),
+	}
+}
+
+func NewRoleResponse(role domain.RoleInterface, parents []domain.RoleInterface, permissions []domain.PermissionInterface) RoleResponse {
+	res := RoleResponse{
+		ID:          role.GetID().String(),
+		Name:        role.GetName(),
+		Description: role.GetDescription(),
+		CreatedAt:   role.GetCreatedAt(),
+		UpdatedAt:   role.GetUpdatedAt(),
+	}
+
+	for _, parent := range parents {
+		res.Parents = append(res.Parents, parent.GetName())
+	}
+	for _, permission := range permissions {
+		res.Permissions = append(res.Permissions, NewPermissionResponse(permission))
+	}
+
+	return res
+}
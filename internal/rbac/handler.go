@@ -0,0 +1,273 @@
+package rbac
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the RBAC API itself.
+const (
+	ResourceRBAC  = "rbac"
+	ResourceAuthz = "authz"
+	ActionManage  = "manage"
+	ActionCheck   = "check"
+)
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+	authorizer  AuthorizerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateRole(ctx *gin.Context)
+	GetRole(ctx *gin.Context)
+	ListRoles(ctx *gin.Context)
+	DeleteRole(ctx *gin.Context)
+	ListPermissions(ctx *gin.Context)
+	GrantPermission(ctx *gin.Context)
+	RevokePermission(ctx *gin.Context)
+	AddParent(ctx *gin.Context)
+	RemoveParent(ctx *gin.Context)
+	ListUserRoles(ctx *gin.Context)
+	AssignRole(ctx *gin.Context)
+	UnassignRole(ctx *gin.Context)
+	Check(ctx *gin.Context)
+	CheckBatch(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	service ServiceInterface,
+	authorizer AuthorizerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+		authorizer:  authorizer,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	manage := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.authorizer, ResourceRBAC, ActionManage)
+
+	roles := router.Group("/api/v1/roles", manage)
+	{
+		roles.POST("", h.CreateRole)
+		roles.GET("", h.ListRoles)
+		roles.GET("/:id", h.GetRole)
+		roles.DELETE("/:id", h.DeleteRole)
+		roles.POST("/:id/permissions", h.GrantPermission)
+		roles.DELETE("/:id/permissions/:permission_id", h.RevokePermission)
+		roles.POST("/:id/parents", h.AddParent)
+		roles.DELETE("/:id/parents/:parent_id", h.RemoveParent)
+	}
+
+	router.GET("/api/v1/permissions", manage, h.ListPermissions)
+
+	userRoles := router.Group("/api/v1/users/:id/roles", manage)
+	{
+		userRoles.GET("", h.ListUserRoles)
+		userRoles.POST("", h.AssignRole)
+		userRoles.DELETE("/:role_id", h.UnassignRole)
+	}
+
+	authz := router.Group(
+		"/api/v1/authz",
+		middleware.Authenticate(h.tokens),
+		middleware.RequirePermission(h.authorizer, ResourceAuthz, ActionCheck),
+	)
+	{
+		authz.POST("/check", h.Check)
+		authz.POST("/check/batch", h.CheckBatch)
+	}
+}
+
+func (h *handler) CreateRole(ctx *gin.Context) {
+	var req CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.CreateRole(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) GetRole(ctx *gin.Context) {
+	res, restErr := h.service.GetRole(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListRoles(ctx *gin.Context) {
+	res, restErr := h.service.ListRoles(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) DeleteRole(ctx *gin.Context) {
+	if restErr := h.service.DeleteRole(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) ListPermissions(ctx *gin.Context) {
+	res, restErr := h.service.ListPermissions(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) GrantPermission(ctx *gin.Context) {
+	var req PermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.GrantPermission(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RevokePermission(ctx *gin.Context) {
+	restErr := h.service.RevokePermission(ctx.Request.Context(), ctx.Param("id"), ctx.Param("permission_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) AddParent(ctx *gin.Context) {
+	var req RoleNameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.AddParent(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RemoveParent(ctx *gin.Context) {
+	restErr := h.service.RemoveParent(ctx.Request.Context(), ctx.Param("id"), ctx.Param("parent_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) ListUserRoles(ctx *gin.Context) {
+	res, restErr := h.service.ListUserRoles(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) AssignRole(ctx *gin.Context) {
+	var req RoleNameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	if restErr := h.service.AssignRole(ctx.Request.Context(), ctx.Param("id"), req); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) UnassignRole(ctx *gin.Context) {
+	restErr := h.service.UnassignRole(ctx.Request.Context(), ctx.Param("id"), ctx.Param("role_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) Check(ctx *gin.Context) {
+	var req CheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.Check(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) CheckBatch(ctx *gin.Context) {
+	var req BatchCheckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.CheckBatch(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
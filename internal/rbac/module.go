@@ -0,0 +1,19 @@
+package rbac
+
+import (
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewAuthorizer,
+		func(authorizer AuthorizerInterface) middleware.PermissionCheckerInterface {
+			return authorizer
+		},
+		NewService,
+		httpserver.AsRouter(NewHandler),
+	),
+)
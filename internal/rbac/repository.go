@@ -0,0 +1,445 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleAlreadyExists  = errors.New("role already exists")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrParentNotFound     = errors.New("parent role not found")
+	ErrAssignmentNotFound = errors.New("role assignment not found")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+const selectRoleColumns = `
+	SELECT id, name, description, created_at, updated_at
+	FROM roles`
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	CreateRole(ctx context.Context, role domain.RoleInterface) error
+	FindRoleByID(ctx context.Context, id uuid.UUID) (domain.RoleInterface, error)
+	FindRoleByName(ctx context.Context, name string) (domain.RoleInterface, error)
+	FindAllRoles(ctx context.Context) ([]domain.RoleInterface, error)
+	DeleteRole(ctx context.Context, id uuid.UUID) error
+	SavePermission(ctx context.Context, permission domain.PermissionInterface) (domain.PermissionInterface, error)
+	FindAllPermissions(ctx context.Context) ([]domain.PermissionInterface, error)
+	FindPermissionsByRole(ctx context.Context, roleID uuid.UUID) ([]domain.PermissionInterface, error)
+	GrantPermission(ctx context.Context, roleID, permissionID uuid.UUID) error
+	RevokePermission(ctx context.Context, roleID, permissionID uuid.UUID) error
+	FindParents(ctx context.Context, roleID uuid.UUID) ([]domain.RoleInterface, error)
+	LockInheritance(ctx context.Context) error
+	IsAncestor(ctx context.Context, roleID, ancestorID uuid.UUID) (bool, error)
+	AddParent(ctx context.Context, roleID, parentID uuid.UUID) error
+	RemoveParent(ctx context.Context, roleID, parentID uuid.UUID) error
+	// AssignRole reports whether the user did not hold the role yet.
+	AssignRole(ctx context.Context, userID, roleID uuid.UUID) (bool, error)
+	UnassignRole(ctx context.Context, userID, roleID uuid.UUID) error
+	FindRolesByUser(ctx context.Context, userID uuid.UUID) ([]domain.RoleInterface, error)
+	FindEffectiveRoleNames(ctx context.Context, userID uuid.UUID) ([]string, error)
+	FindEffectivePermissions(ctx context.Context) (map[string][]domain.PermissionInterface, error)
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateRole(ctx context.Context, role domain.RoleInterface) error {
+	query := `
+		INSERT INTO roles (id, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		role.GetID(),
+		role.GetName(),
+		role.GetDescription(),
+		role.GetCreatedAt(),
+		role.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrRoleAlreadyExists
+		}
+		return fmt.Errorf("failed to insert role: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindRoleByID(ctx context.Context, id uuid.UUID) (domain.RoleInterface, error) {
+	return scanRole(r.db.GetQuerier(ctx).QueryRow(ctx, selectRoleColumns+` WHERE id = $1`, id))
+}
+
+func (r *repository) FindRoleByName(ctx context.Context, name string) (domain.RoleInterface, error) {
+	return scanRole(r.db.GetQuerier(ctx).QueryRow(ctx, selectRoleColumns+` WHERE name = $1`, name))
+}
+
+func (r *repository) FindAllRoles(ctx context.Context) ([]domain.RoleInterface, error) {
+	return r.queryRoles(ctx, selectRoleColumns+` ORDER BY name`)
+}
+
+func (r *repository) DeleteRole(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// SavePermission returns the stored permission for the resource and action,
+// creating it on first use. A non-empty description replaces the old one.
+func (r *repository) SavePermission(ctx context.Context, permission domain.PermissionInterface) (domain.PermissionInterface, error) {
+	query := `
+		INSERT INTO permissions (id, resource, action, description, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (resource, action) DO UPDATE
+		SET description = CASE WHEN EXCLUDED.description <> '' THEN EXCLUDED.description ELSE permissions.description END
+		RETURNING id, resource, action, description, created_at`
+
+	return scanPermission(r.db.GetQuerier(ctx).QueryRow(ctx, query,
+		permission.GetID(),
+		permission.GetResource(),
+		permission.GetAction(),
+		permission.GetDescription(),
+		permission.GetCreatedAt(),
+	))
+}
+
+func (r *repository) FindAllPermissions(ctx context.Context) ([]domain.PermissionInterface, error) {
+	query := `
+		SELECT id, resource, action, description, created_at
+		FROM permissions
+		ORDER BY resource, action`
+
+	return r.queryPermissions(ctx, query)
+}
+
+func (r *repository) FindPermissionsByRole(ctx context.Context, roleID uuid.UUID) ([]domain.PermissionInterface, error) {
+	query := `
+		SELECT p.id, p.resource, p.action, p.description, p.created_at
+		FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id = $1
+		ORDER BY p.resource, p.action`
+
+	return r.queryPermissions(ctx, query, roleID)
+}
+
+func (r *repository) GrantPermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, roleID, permissionID, time.Now().UTC()); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrRoleNotFound
+		}
+		return fmt.Errorf("failed to grant permission: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RevokePermission(ctx context.Context, roleID, permissionID uuid.UUID) error {
+	query := `DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, roleID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke permission: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPermissionNotFound
+	}
+	return nil
+}
+
+func (r *repository) FindParents(ctx context.Context, roleID uuid.UUID) ([]domain.RoleInterface, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at, r.updated_at
+		FROM roles r
+		JOIN role_inheritance ri ON ri.parent_id = r.id
+		WHERE ri.role_id = $1
+		ORDER BY r.name`
+
+	return r.queryRoles(ctx, query, roleID)
+}
+
+// IsAncestor reports whether ancestorID is reachable from roleID through the
+// inheritance graph, a role counting as its own ancestor.
+func (r *repository) IsAncestor(ctx context.Context, roleID, ancestorID uuid.UUID) (bool, error) {
+	query := `
+		WITH RECURSIVE ancestors (id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT ri.parent_id
+			FROM role_inheritance ri
+			JOIN ancestors a ON ri.role_id = a.id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+
+	var found bool
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, query, roleID, ancestorID).Scan(&found); err != nil {
+		return false, fmt.Errorf("failed to walk role inheritance: %w", err)
+	}
+	return found, nil
+}
+
+// LockInheritance serializes edits of the inheritance graph for the rest of
+// the transaction, so two concurrent edits cannot close a cycle between them.
+func (r *repository) LockInheritance(ctx context.Context) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `LOCK TABLE role_inheritance IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock role inheritance: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) AddParent(ctx context.Context, roleID, parentID uuid.UUID) error {
+	query := `
+		INSERT INTO role_inheritance (role_id, parent_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, roleID, parentID, time.Now().UTC()); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrParentNotFound
+		}
+		return fmt.Errorf("failed to add parent role: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RemoveParent(ctx context.Context, roleID, parentID uuid.UUID) error {
+	query := `DELETE FROM role_inheritance WHERE role_id = $1 AND parent_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, roleID, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to remove parent role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrParentNotFound
+	}
+	return nil
+}
+
+func (r *repository) AssignRole(ctx context.Context, userID, roleID uuid.UUID) (bool, error) {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, userID, roleID, time.Now().UTC())
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("failed to assign role: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *repository) UnassignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, userID, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+func (r *repository) FindRolesByUser(ctx context.Context, userID uuid.UUID) ([]domain.RoleInterface, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.created_at, r.updated_at
+		FROM roles r
+		JOIN user_roles ur ON ur.role_id = r.id
+		WHERE ur.user_id = $1
+		ORDER BY r.name`
+
+	return r.queryRoles(ctx, query, userID)
+}
+
+// FindEffectiveRoleNames returns the roles assigned to the user together
+// with every role they inherit from. When the context names a tenant, the
+// roles granted through the user's membership there are included too.
+func (r *repository) FindEffectiveRoleNames(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var orgID *uuid.UUID
+	if tenant, ok := database.TenantFromContext(ctx); ok {
+		orgID = &tenant
+	}
+
+	query := `
+		WITH RECURSIVE effective (id) AS (
+			SELECT role_id FROM user_roles WHERE user_id = $1
+			UNION
+			SELECT role_id FROM membership_roles WHERE user_id = $1 AND org_id = $2
+			UNION
+			SELECT ri.parent_id
+			FROM role_inheritance ri
+			JOIN effective e ON ri.role_id = e.id
+		)
+		SELECT r.name
+		FROM roles r
+		JOIN effective e ON e.id = r.id
+		ORDER BY r.name`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, userID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query effective roles: %w", err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan role name: %w", err)
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// FindEffectivePermissions maps every role name to its own permissions plus
+// the ones it inherits.
+func (r *repository) FindEffectivePermissions(ctx context.Context) (map[string][]domain.PermissionInterface, error) {
+	query := `
+		WITH RECURSIVE closure (role_id, ancestor_id) AS (
+			SELECT id, id FROM roles
+			UNION
+			SELECT c.role_id, ri.parent_id
+			FROM closure c
+			JOIN role_inheritance ri ON ri.role_id = c.ancestor_id
+		)
+		SELECT DISTINCT r.name, p.id, p.resource, p.action, p.description, p.created_at
+		FROM closure c
+		JOIN roles r ON r.id = c.role_id
+		JOIN role_permissions rp ON rp.role_id = c.ancestor_id
+		JOIN permissions p ON p.id = rp.permission_id`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query effective permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make(map[string][]domain.PermissionInterface)
+	for rows.Next() {
+		var (
+			roleName, resource, action, description string
+			id                                      uuid.UUID
+			createdAt                               time.Time
+		)
+		if err := rows.Scan(&roleName, &id, &resource, &action, &description, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions[roleName] = append(permissions[roleName], domain.RestorePermission(id, resource, action, description, createdAt))
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *repository) queryRoles(ctx context.Context, query string, args ...any) ([]domain.RoleInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make([]domain.RoleInterface, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *repository) queryPermissions(ctx context.Context, query string, args ...any) ([]domain.PermissionInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := make([]domain.PermissionInterface, 0)
+	for rows.Next() {
+		permission, err := scanPermission(rows)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+func scanRole(row pgx.Row) (domain.RoleInterface, error) {
+	var (
+		id                   uuid.UUID
+		name, description    string
+		createdAt, updatedAt time.Time
+	)
+
+	if err := row.Scan(&id, &name, &description, &createdAt, &updatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+
+	return domain.RestoreRole(id, name, description, createdAt, updatedAt), nil
+}
+
+func scanPermission(row pgx.Row) (domain.PermissionInterface, error) {
+	var (
+		id                            uuid.UUID
+		resource, action, description string
+		createdAt                     time.Time
+	)
+
+	if err := row.Scan(&id, &resource, &action, &description, &createdAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, fmt.Errorf("failed to scan permission: %w", err)
+	}
+
+	return domain.RestorePermission(id, resource, action, description, createdAt), nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode
+}
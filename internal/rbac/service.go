@@ -0,0 +1,516 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+type service struct {
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	authorizer AuthorizerInterface
+	outbox     outbox.PublisherInterface
+	audit      audit.RecorderInterface
+}
+
+type ServiceInterface interface {
+	CreateRole(ctx context.Context, req CreateRoleRequest) (*RoleResponse, *httperr.HttpError)
+	GetRole(ctx context.Context, id string) (*RoleResponse, *httperr.HttpError)
+	ListRoles(ctx context.Context) ([]RoleResponse, *httperr.HttpError)
+	DeleteRole(ctx context.Context, id string) *httperr.HttpError
+	ListPermissions(ctx context.Context) ([]PermissionResponse, *httperr.HttpError)
+	GrantPermission(ctx context.Context, roleID string, req PermissionRequest) (*RoleResponse, *httperr.HttpError)
+	RevokePermission(ctx context.Context, roleID, permissionID string) *httperr.HttpError
+	AddParent(ctx context.Context, roleID string, req RoleNameRequest) (*RoleResponse, *httperr.HttpError)
+	RemoveParent(ctx context.Context, roleID, parentID string) *httperr.HttpError
+	ListUserRoles(ctx context.Context, userID string) ([]RoleResponse, *httperr.HttpError)
+	AssignRole(ctx context.Context, userID string, req RoleNameRequest) *httperr.HttpError
+	UnassignRole(ctx context.Context, userID, roleID string) *httperr.HttpError
+	UserRoles(ctx context.Context, userID uuid.UUID) ([]string, *httperr.HttpError)
+	Check(ctx context.Context, req CheckRequest) (*CheckResponse, *httperr.HttpError)
+	CheckBatch(ctx context.Context, req BatchCheckRequest) (*BatchCheckResponse, *httperr.HttpError)
+}
+
+func NewService(
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	authorizer AuthorizerInterface,
+	outbox outbox.PublisherInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		db:         db,
+		repository: repository,
+		authorizer: authorizer,
+		outbox:     outbox,
+		audit:      audit,
+	}
+}
+
+func (s *service) CreateRole(ctx context.Context, req CreateRoleRequest) (*RoleResponse, *httperr.HttpError) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, invalidFieldError("name", "is required")
+	}
+
+	role := domain.NewRole(name, strings.TrimSpace(req.Description))
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateRole(ctx, role); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionRoleCreate, audit.TargetRole, role.GetID(), map[string]string{"name": name})
+	})
+	if err != nil {
+		if errors.Is(err, ErrRoleAlreadyExists) {
+			return nil, invalidFieldError("name", "is already in use")
+		}
+		slog.Error("failed to create role", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create role")
+	}
+
+	res := NewRoleResponse(role, nil, nil)
+	return &res, nil
+}
+
+func (s *service) GetRole(ctx context.Context, id string) (*RoleResponse, *httperr.HttpError) {
+	role, restErr := s.findRole(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return s.describe(ctx, role)
+}
+
+func (s *service) ListRoles(ctx context.Context) ([]RoleResponse, *httperr.HttpError) {
+	roles, err := s.repository.FindAllRoles(ctx)
+	if err != nil {
+		slog.Error("failed to list roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list roles")
+	}
+
+	res := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, NewRoleResponse(role, nil, nil))
+	}
+
+	return res, nil
+}
+
+func (s *service) DeleteRole(ctx context.Context, id string) *httperr.HttpError {
+	roleID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("role not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteRole(ctx, roleID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionRoleDelete, audit.TargetRole, roleID, nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return httperr.NewNotFoundError("role not found")
+		}
+		slog.Error("failed to delete role", "error", err)
+		return httperr.NewInternalServerError("failed to delete role")
+	}
+
+	s.authorizer.Invalidate()
+	return nil
+}
+
+func (s *service) ListPermissions(ctx context.Context) ([]PermissionResponse, *httperr.HttpError) {
+	permissions, err := s.repository.FindAllPermissions(ctx)
+	if err != nil {
+		slog.Error("failed to list permissions", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list permissions")
+	}
+
+	res := make([]PermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		res = append(res, NewPermissionResponse(permission))
+	}
+
+	return res, nil
+}
+
+func (s *service) GrantPermission(ctx context.Context, roleID string, req PermissionRequest) (*RoleResponse, *httperr.HttpError) {
+	role, restErr := s.findRole(ctx, roleID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	resource, action := strings.TrimSpace(req.Resource), strings.TrimSpace(req.Action)
+	if resource == "" {
+		return nil, invalidFieldError("resource", "is required")
+	}
+	if action == "" {
+		return nil, invalidFieldError("action", "is required")
+	}
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		permission, err := s.repository.SavePermission(ctx, domain.NewPermission(resource, action, strings.TrimSpace(req.Description)))
+		if err != nil {
+			return err
+		}
+		if err := s.repository.GrantPermission(ctx, role.GetID(), permission.GetID()); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionPermissionGrant, audit.TargetRole, role.GetID(), map[string]string{
+			"permission_id": permission.GetID().String(),
+			"permission":    resource + ":" + action,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return nil, httperr.NewNotFoundError("role not found")
+		}
+		slog.Error("failed to grant permission", "error", err)
+		return nil, httperr.NewInternalServerError("failed to grant permission")
+	}
+
+	s.authorizer.Invalidate()
+	return s.describe(ctx, role)
+}
+
+func (s *service) RevokePermission(ctx context.Context, roleID, permissionID string) *httperr.HttpError {
+	role, restErr := s.findRole(ctx, roleID)
+	if restErr != nil {
+		return restErr
+	}
+
+	id, err := uuid.Parse(permissionID)
+	if err != nil {
+		return httperr.NewNotFoundError("permission not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.RevokePermission(ctx, role.GetID(), id); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionPermissionRevoke, audit.TargetRole, role.GetID(), map[string]string{"permission_id": id.String()})
+	})
+	if err != nil {
+		if errors.Is(err, ErrPermissionNotFound) {
+			return httperr.NewNotFoundError("permission not found")
+		}
+		slog.Error("failed to revoke permission", "error", err)
+		return httperr.NewInternalServerError("failed to revoke permission")
+	}
+
+	s.authorizer.Invalidate()
+	return nil
+}
+
+// AddParent makes the role inherit every permission of the named parent.
+// Edges that would close a cycle are rejected.
+func (s *service) AddParent(ctx context.Context, roleID string, req RoleNameRequest) (*RoleResponse, *httperr.HttpError) {
+	role, restErr := s.findRole(ctx, roleID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	parent, restErr := s.findRoleByName(ctx, req.Role)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	var cycle bool
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.LockInheritance(ctx); err != nil {
+			return err
+		}
+
+		// Inheriting from a role that already inherits from this one, or
+		// from itself, would close a cycle.
+		found, err := s.repository.IsAncestor(ctx, parent.GetID(), role.GetID())
+		if err != nil {
+			return err
+		}
+		if found {
+			cycle = true
+			return nil
+		}
+
+		if err := s.repository.AddParent(ctx, role.GetID(), parent.GetID()); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionParentAdd, audit.TargetRole, role.GetID(), map[string]string{"parent_id": parent.GetID().String()})
+	})
+	if err != nil {
+		if errors.Is(err, ErrParentNotFound) {
+			return nil, httperr.NewNotFoundError("role not found")
+		}
+		slog.Error("failed to add parent role", "error", err)
+		return nil, httperr.NewInternalServerError("failed to add parent role")
+	}
+	if cycle {
+		return nil, invalidFieldError("role", "would create an inheritance cycle")
+	}
+
+	s.authorizer.Invalidate()
+	return s.describe(ctx, role)
+}
+
+func (s *service) RemoveParent(ctx context.Context, roleID, parentID string) *httperr.HttpError {
+	role, restErr := s.findRole(ctx, roleID)
+	if restErr != nil {
+		return restErr
+	}
+
+	id, err := uuid.Parse(parentID)
+	if err != nil {
+		return httperr.NewNotFoundError("parent role not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.RemoveParent(ctx, role.GetID(), id); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionParentRemove, audit.TargetRole, role.GetID(), map[string]string{"parent_id": id.String()})
+	})
+	if err != nil {
+		if errors.Is(err, ErrParentNotFound) {
+			return httperr.NewNotFoundError("parent role not found")
+		}
+		slog.Error("failed to remove parent role", "error", err)
+		return httperr.NewInternalServerError("failed to remove parent role")
+	}
+
+	s.authorizer.Invalidate()
+	return nil
+}
+
+func (s *service) ListUserRoles(ctx context.Context, userID string) ([]RoleResponse, *httperr.HttpError) {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("user not found")
+	}
+
+	roles, err := s.repository.FindRolesByUser(ctx, id)
+	if err != nil {
+		slog.Error("failed to list user roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list user roles")
+	}
+
+	res := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, NewRoleResponse(role, nil, nil))
+	}
+
+	return res, nil
+}
+
+// AssignRole grants the named role to a user. It shows up in the user's
+// tokens from their next sign-in or refresh.
+func (s *service) AssignRole(ctx context.Context, userID string, req RoleNameRequest) *httperr.HttpError {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("user not found")
+	}
+
+	role, restErr := s.findRoleByName(ctx, req.Role)
+	if restErr != nil {
+		return restErr
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		granted, err := s.repository.AssignRole(ctx, id, role.GetID())
+		if err != nil {
+			return err
+		}
+		// Assigning a role the user already holds changes nothing, so
+		// consumers are not told about it again.
+		if granted {
+			err := s.outbox.Publish(ctx, outbox.RoleGranted{UserID: id, RoleID: role.GetID(), Role: role.GetName()})
+			if err != nil {
+				return err
+			}
+		}
+		return s.record(ctx, audit.ActionRoleAssign, audit.TargetUser, id, map[string]string{
+			"role_id": role.GetID().String(),
+			"role":    role.GetName(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to assign role", "error", err)
+		return httperr.NewInternalServerError("failed to assign role")
+	}
+
+	slog.Info("role assigned", slog.String("user_id", id.String()), slog.String("role", role.GetName()))
+	return nil
+}
+
+func (s *service) UnassignRole(ctx context.Context, userID, roleID string) *httperr.HttpError {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("user not found")
+	}
+	roleUUID, err := uuid.Parse(roleID)
+	if err != nil {
+		return httperr.NewNotFoundError("role assignment not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.UnassignRole(ctx, userUUID, roleUUID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionRoleUnassign, audit.TargetUser, userUUID, map[string]string{"role_id": roleUUID.String()})
+	})
+	if err != nil {
+		if errors.Is(err, ErrAssignmentNotFound) {
+			return httperr.NewNotFoundError("role assignment not found")
+		}
+		slog.Error("failed to unassign role", "error", err)
+		return httperr.NewInternalServerError("failed to unassign role")
+	}
+
+	slog.Info("role unassigned", slog.String("user_id", userUUID.String()), slog.String("role_id", roleUUID.String()))
+	return nil
+}
+
+// UserRoles returns the user's effective role names, which are embedded in
+// first-party access tokens. Roles granted in an organization are included
+// only when the context is scoped to it.
+func (s *service) UserRoles(ctx context.Context, userID uuid.UUID) ([]string, *httperr.HttpError) {
+	roles, err := s.repository.FindEffectiveRoleNames(ctx, userID)
+	if err != nil {
+		slog.Error("failed to load user roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load user roles")
+	}
+	return roles, nil
+}
+
+// Check answers whether a user may perform an action on a resource, using
+// the roles currently stored rather than the ones in any issued token.
+// Subjects that are not users are never allowed. With an org_id the roles
+// the user holds in that organization count as well.
+func (s *service) Check(ctx context.Context, req CheckRequest) (*CheckResponse, *httperr.HttpError) {
+	res, restErr := s.CheckBatch(ctx, BatchCheckRequest{Checks: []CheckRequest{req}})
+	if restErr != nil {
+		return nil, restErr
+	}
+	return &res.Results[0], nil
+}
+
+func (s *service) CheckBatch(ctx context.Context, req BatchCheckRequest) (*BatchCheckResponse, *httperr.HttpError) {
+	subjects := make(map[string][]string)
+	results := make([]CheckResponse, 0, len(req.Checks))
+
+	for _, check := range req.Checks {
+		key := check.Subject + "@" + check.OrgID
+		roles, loaded := subjects[key]
+		if !loaded {
+			scoped := ctx
+			if check.OrgID != "" {
+				orgID, err := uuid.Parse(check.OrgID)
+				if err != nil {
+					return nil, httperr.NewBadRequestError("org_id must be a uuid")
+				}
+				scoped = database.WithTenant(ctx, orgID)
+			}
+
+			if userID, err := uuid.Parse(check.Subject); err == nil {
+				found, restErr := s.UserRoles(scoped, userID)
+				if restErr != nil {
+					return nil, restErr
+				}
+				roles = found
+			}
+			subjects[key] = roles
+		}
+
+		allowed, err := s.authorizer.RolesAllow(ctx, roles, check.Resource, check.Action)
+		if err != nil {
+			slog.Error("failed to check permission", "error", err)
+			return nil, httperr.NewInternalServerError("failed to check permission")
+		}
+
+		results = append(results, CheckResponse{
+			Subject:  check.Subject,
+			OrgID:    check.OrgID,
+			Resource: check.Resource,
+			Action:   check.Action,
+			Allowed:  allowed,
+		})
+	}
+
+	return &BatchCheckResponse{Results: results}, nil
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+func (s *service) describe(ctx context.Context, role domain.RoleInterface) (*RoleResponse, *httperr.HttpError) {
+	parents, err := s.repository.FindParents(ctx, role.GetID())
+	if err != nil {
+		slog.Error("failed to load parent roles", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load role")
+	}
+
+	permissions, err := s.repository.FindPermissionsByRole(ctx, role.GetID())
+	if err != nil {
+		slog.Error("failed to load role permissions", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load role")
+	}
+
+	res := NewRoleResponse(role, parents, permissions)
+	return &res, nil
+}
+
+func (s *service) findRole(ctx context.Context, id string) (domain.RoleInterface, *httperr.HttpError) {
+	roleID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("role not found")
+	}
+
+	role, err := s.repository.FindRoleByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return nil, httperr.NewNotFoundError("role not found")
+		}
+		slog.Error("failed to load role", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load role")
+	}
+
+	return role, nil
+}
+
+func (s *service) findRoleByName(ctx context.Context, name string) (domain.RoleInterface, *httperr.HttpError) {
+	role, err := s.repository.FindRoleByName(ctx, strings.TrimSpace(name))
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return nil, invalidFieldError("role", "does not exist")
+		}
+		slog.Error("failed to load role", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load role")
+	}
+
+	return role, nil
+}
+
+func invalidFieldError(field, message string) *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: field, Message: message},
+	})
+}
@@ -0,0 +1,182 @@
+package rbac
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+)
+
+// fakeRepository resolves roles and permissions the way the queries do once
+// inheritance is flattened: each role lists its own permissions plus the
+// ones it inherits.
+type fakeRepository struct {
+	RepositoryInterface
+	roles       map[uuid.UUID][]string
+	orgRoles    map[uuid.UUID]map[uuid.UUID][]string
+	permissions map[string][]domain.PermissionInterface
+	roleLoads   int
+	loads       int
+}
+
+func (r *fakeRepository) FindEffectiveRoleNames(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	r.roleLoads++
+	roles := append([]string(nil), r.roles[userID]...)
+	if orgID, ok := database.TenantFromContext(ctx); ok {
+		roles = append(roles, r.orgRoles[orgID][userID]...)
+	}
+	return roles, nil
+}
+
+func (r *fakeRepository) FindEffectivePermissions(context.Context) (map[string][]domain.PermissionInterface, error) {
+	r.loads++
+	return r.permissions, nil
+}
+
+func TestCheckRejectsMalformedOrg(t *testing.T) {
+	service := NewService(nil, nil, nil, nil, nil)
+
+	_, restErr := service.Check(context.Background(), CheckRequest{
+		Subject:  uuid.NewString(),
+		OrgID:    "not-a-uuid",
+		Resource: "users",
+		Action:   "read",
+	})
+	if restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("Check(malformed org_id) error = %v, want 400", restErr)
+	}
+}
+
+func TestCheckResolvesPermissions(t *testing.T) {
+	reader, admin, member, nobody := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	orgID := uuid.New()
+
+	repository := &fakeRepository{
+		roles: map[uuid.UUID][]string{
+			reader: {"reader"},
+			// editor inherits from reader, so both names are effective.
+			admin: {"editor", "reader"},
+		},
+		orgRoles: map[uuid.UUID]map[uuid.UUID][]string{
+			orgID: {member: {"billing"}},
+		},
+		permissions: map[string][]domain.PermissionInterface{
+			"reader":  {domain.NewPermission("documents", "read", "")},
+			"editor":  {domain.NewPermission("documents", "read", ""), domain.NewPermission("documents.*", domain.PermissionWildcard, "")},
+			"billing": {domain.NewPermission("invoices", domain.PermissionWildcard, "")},
+		},
+	}
+	service := NewService(nil, repository, NewAuthorizer(config.RBACConfig{CacheTTL: 60}, repository), nil, nil)
+
+	tests := []struct {
+		name    string
+		req     CheckRequest
+		allowed bool
+	}{
+		{"direct permission", CheckRequest{Subject: reader.String(), Resource: "documents", Action: "read"}, true},
+		{"other action", CheckRequest{Subject: reader.String(), Resource: "documents", Action: "delete"}, false},
+		{"inherited permission", CheckRequest{Subject: admin.String(), Resource: "documents", Action: "read"}, true},
+		{"resource prefix and any action", CheckRequest{Subject: admin.String(), Resource: "documents.drafts", Action: "delete"}, true},
+		{"prefix does not match the bare resource", CheckRequest{Subject: admin.String(), Resource: "documents", Action: "delete"}, false},
+		{"no roles", CheckRequest{Subject: nobody.String(), Resource: "documents", Action: "read"}, false},
+		{"subject that is not a user", CheckRequest{Subject: "client:reports", Resource: "documents", Action: "read"}, false},
+		{"organization role inside it", CheckRequest{Subject: member.String(), OrgID: orgID.String(), Resource: "invoices", Action: "pay"}, true},
+		{"organization role outside it", CheckRequest{Subject: member.String(), Resource: "invoices", Action: "pay"}, false},
+		{"organization role in another one", CheckRequest{Subject: member.String(), OrgID: uuid.NewString(), Resource: "invoices", Action: "pay"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, restErr := service.Check(context.Background(), tt.req)
+			if restErr != nil {
+				t.Fatalf("Check() error = %v", restErr)
+			}
+			if res.Allowed != tt.allowed {
+				t.Fatalf("Check() allowed = %v, want %v", res.Allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestCheckBatchLoadsEachSubjectOnce(t *testing.T) {
+	userID := uuid.New()
+	orgID := uuid.NewString()
+	repository := &fakeRepository{
+		roles:       map[uuid.UUID][]string{userID: {"reader"}},
+		permissions: map[string][]domain.PermissionInterface{"reader": {domain.NewPermission("documents", "read", "")}},
+	}
+	service := NewService(nil, repository, NewAuthorizer(config.RBACConfig{CacheTTL: 60}, repository), nil, nil)
+
+	res, restErr := service.CheckBatch(context.Background(), BatchCheckRequest{Checks: []CheckRequest{
+		{Subject: userID.String(), Resource: "documents", Action: "read"},
+		{Subject: userID.String(), Resource: "documents", Action: "write"},
+		{Subject: userID.String(), OrgID: orgID, Resource: "documents", Action: "read"},
+		{Subject: userID.String(), OrgID: orgID, Resource: "documents", Action: "write"},
+	}})
+	if restErr != nil {
+		t.Fatalf("CheckBatch() error = %v", restErr)
+	}
+
+	want := []bool{true, false, true, false}
+	for i, result := range res.Results {
+		if result.Allowed != want[i] {
+			t.Errorf("result %d allowed = %v, want %v", i, result.Allowed, want[i])
+		}
+	}
+	// Once without an organization and once scoped to it.
+	if repository.roleLoads != 2 {
+		t.Errorf("loaded roles %d times, want 2", repository.roleLoads)
+	}
+	if repository.loads != 1 {
+		t.Errorf("loaded permissions %d times, want 1", repository.loads)
+	}
+}
+
+func TestAuthorizerReloadsAfterInvalidate(t *testing.T) {
+	repository := &fakeRepository{
+		permissions: map[string][]domain.PermissionInterface{"reader": {domain.NewPermission("documents", "read", "")}},
+	}
+	authorizer := NewAuthorizer(config.RBACConfig{CacheTTL: 60}, repository)
+	ctx := context.Background()
+
+	allowed := func() bool {
+		t.Helper()
+		ok, err := authorizer.RolesAllow(ctx, []string{"reader"}, "documents", "write")
+		if err != nil {
+			t.Fatalf("RolesAllow() error = %v", err)
+		}
+		return ok
+	}
+
+	if allowed() {
+		t.Fatal("RolesAllow() allowed a permission the role does not hold")
+	}
+
+	// A grant is not seen while the snapshot is fresh...
+	repository.permissions = map[string][]domain.PermissionInterface{
+		"reader": {domain.NewPermission("documents", domain.PermissionWildcard, "")},
+	}
+	if allowed() {
+		t.Fatal("RolesAllow() read past the cached snapshot")
+	}
+	if repository.loads != 1 {
+		t.Fatalf("loaded permissions %d times, want 1", repository.loads)
+	}
+
+	// ...and is once the snapshot is invalidated.
+	authorizer.Invalidate()
+	if !allowed() {
+		t.Fatal("RolesAllow() kept the stale snapshot after Invalidate()")
+	}
+	if repository.loads != 2 {
+		t.Fatalf("loaded permissions %d times, want 2", repository.loads)
+	}
+
+	if ok, _ := authorizer.RolesAllow(ctx, nil, "documents", "read"); ok {
+		t.Error("RolesAllow() allowed a subject without roles")
+	}
+}
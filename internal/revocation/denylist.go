@@ -0,0 +1,220 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const (
+	purgeInterval      = time.Minute
+	reconnectBaseDelay = time.Second
+	reconnectMaxDelay  = 30 * time.Second
+)
+
+type denylist struct {
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+
+	mu      sync.RWMutex
+	entries map[string]time.Time
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// DenylistInterface tracks revoked access token IDs until they expire. Reads
+// are served from memory; instances keep each other in sync through
+// Postgres LISTEN/NOTIFY and reload everything after reconnecting.
+type DenylistInterface interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(jti string) bool
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewDenylist(db database.DatabaseInterface, repository RepositoryInterface) DenylistInterface {
+	return &denylist{
+		db:         db,
+		repository: repository,
+		entries:    make(map[string]time.Time),
+	}
+}
+
+func (d *denylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if !time.Now().Before(expiresAt) {
+		return nil
+	}
+
+	if err := d.repository.Create(ctx, jti, expiresAt.UTC()); err != nil {
+		return err
+	}
+
+	d.add(jti, expiresAt)
+	return nil
+}
+
+func (d *denylist) IsRevoked(jti string) bool {
+	d.mu.RLock()
+	expiresAt, ok := d.entries[jti]
+	d.mu.RUnlock()
+
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (d *denylist) Start(ctx context.Context) error {
+	slog.Info("starting token denylist")
+
+	if err := d.reload(ctx); err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.done.Add(2)
+	go d.listen(runCtx)
+	go d.purge(runCtx)
+
+	return nil
+}
+
+func (d *denylist) Stop(ctx context.Context) error {
+	if d.cancel != nil {
+		d.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		d.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		slog.Info("token denylist stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *denylist) reload(ctx context.Context) error {
+	entries, err := d.repository.FindActive(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.entries = entries
+	d.mu.Unlock()
+
+	return nil
+}
+
+func (d *denylist) add(jti string, expiresAt time.Time) {
+	d.mu.Lock()
+	d.entries[jti] = expiresAt
+	d.mu.Unlock()
+}
+
+func (d *denylist) listen(ctx context.Context) {
+	defer d.done.Done()
+
+	delay := reconnectBaseDelay
+	for {
+		err := d.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("token denylist listener disconnected", "error", err, slog.Duration("retry_in", delay))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+func (d *denylist) listenOnce(ctx context.Context) error {
+	conn, err := d.db.GetDB().Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listener connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return fmt.Errorf("failed to listen for revocations: %w", err)
+	}
+
+	// Revocations published while we were disconnected were not delivered.
+	if err := d.reload(ctx); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		jti, expiresAt, err := parsePayload(notification.Payload)
+		if err != nil {
+			slog.Warn("ignoring malformed revocation notification", "error", err)
+			continue
+		}
+
+		d.add(jti, expiresAt)
+	}
+}
+
+func (d *denylist) purge(ctx context.Context) {
+	defer d.done.Done()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+
+			d.mu.Lock()
+			for jti, expiresAt := range d.entries {
+				if !now.Before(expiresAt) {
+					delete(d.entries, jti)
+				}
+			}
+			d.mu.Unlock()
+
+			if err := d.repository.DeleteExpired(ctx, now.UTC()); err != nil {
+				slog.Warn("failed to purge expired revocations", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func parsePayload(payload string) (string, time.Time, error) {
+	jti, rawExpiresAt, found := strings.Cut(payload, " ")
+	if !found || jti == "" {
+		return "", time.Time{}, fmt.Errorf("unexpected payload %q", payload)
+	}
+
+	unix, err := strconv.ParseInt(rawExpiresAt, 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid expiry in payload %q: %w", payload, err)
+	}
+
+	return jti, time.Unix(unix, 0), nil
+}
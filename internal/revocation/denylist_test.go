@@ -0,0 +1,148 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeRepository struct {
+	RepositoryInterface
+	entries map[string]time.Time
+	err     error
+}
+
+func (r *fakeRepository) Create(_ context.Context, jti string, expiresAt time.Time) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.entries[jti] = expiresAt
+	return nil
+}
+
+func (r *fakeRepository) FindActive(_ context.Context, now time.Time) (map[string]time.Time, error) {
+	active := make(map[string]time.Time)
+	for jti, expiresAt := range r.entries {
+		if expiresAt.After(now) {
+			active[jti] = expiresAt
+		}
+	}
+	return active, nil
+}
+
+func newTestDenylist() (*denylist, *fakeRepository) {
+	repository := &fakeRepository{entries: map[string]time.Time{}}
+	return NewDenylist(nil, repository).(*denylist), repository
+}
+
+func TestRevoke(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		stored    bool
+	}{
+		{"live token", now.Add(time.Hour), true},
+		{"expired token", now.Add(-time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, repository := newTestDenylist()
+
+			if err := d.Revoke(context.Background(), "jti-1", tt.expiresAt); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+
+			if _, ok := repository.entries["jti-1"]; ok != tt.stored {
+				t.Fatalf("stored = %v, want %v", ok, tt.stored)
+			}
+			if d.IsRevoked("jti-1") != tt.stored {
+				t.Fatalf("IsRevoked() = %v, want %v", d.IsRevoked("jti-1"), tt.stored)
+			}
+			if d.IsRevoked("jti-2") {
+				t.Fatal("IsRevoked() reported a token that was never revoked")
+			}
+		})
+	}
+}
+
+func TestRevokeKeepsMemoryInStepWithTheDatabase(t *testing.T) {
+	d, repository := newTestDenylist()
+	repository.err = errors.New("connection refused")
+
+	if err := d.Revoke(context.Background(), "jti-1", time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("Revoke() ignored the repository error")
+	}
+	if d.IsRevoked("jti-1") {
+		t.Fatal("a revocation that was not stored is served from memory")
+	}
+}
+
+func TestIsRevokedForgetsExpiredEntries(t *testing.T) {
+	d, _ := newTestDenylist()
+
+	// The entry outlives its token until the next purge, but no longer
+	// counts once the token has expired.
+	d.add("jti-1", time.Now().Add(-time.Second))
+	if d.IsRevoked("jti-1") {
+		t.Fatal("IsRevoked() reported a token that has already expired")
+	}
+}
+
+func TestReloadReplacesEntries(t *testing.T) {
+	d, repository := newTestDenylist()
+	now := time.Now()
+
+	d.add("stale", now.Add(time.Hour))
+	repository.entries["elsewhere"] = now.Add(time.Hour)
+	repository.entries["expired"] = now.Add(-time.Minute)
+
+	if err := d.reload(context.Background()); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+
+	for jti, want := range map[string]bool{"elsewhere": true, "expired": false, "stale": false} {
+		if got := d.IsRevoked(jti); got != want {
+			t.Errorf("IsRevoked(%q) = %v, want %v", jti, got, want)
+		}
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		payload string
+		wantJTI string
+		wantErr bool
+	}{
+		// The format Create sends with pg_notify.
+		{name: "valid", payload: fmt.Sprintf("%s %d", "jti-1", expiresAt.Unix()), wantJTI: "jti-1"},
+		{name: "no separator", payload: "jti-1", wantErr: true},
+		{name: "empty jti", payload: fmt.Sprintf(" %d", expiresAt.Unix()), wantErr: true},
+		{name: "bad expiry", payload: "jti-1 tomorrow", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jti, got, err := parsePayload(tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePayload(%q) accepted a malformed payload", tt.payload)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePayload() error = %v", err)
+			}
+			if jti != tt.wantJTI || !got.Equal(expiresAt) {
+				t.Fatalf("parsePayload() = %q, %s, want %q, %s", jti, got, tt.wantJTI, expiresAt)
+			}
+		})
+	}
+}
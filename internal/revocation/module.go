@@ -0,0 +1,24 @@
+package revocation
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewDenylist,
+	),
+	fx.Invoke(func(lc fx.Lifecycle, denylist DenylistInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return denylist.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return denylist.Stop(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,82 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const notifyChannel = "revoked_tokens"
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, jti string, expiresAt time.Time) error
+	FindActive(ctx context.Context, now time.Time) (map[string]time.Time, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+// Create stores the revocation and notifies every instance in the same
+// transaction, so listeners never hear about a row they cannot read.
+func (r *repository) Create(ctx context.Context, jti string, expiresAt time.Time) error {
+	return r.db.WithTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO revoked_tokens (jti, expires_at, revoked_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (jti) DO NOTHING`
+
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, jti, expiresAt, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to insert revoked token: %w", err)
+		}
+
+		payload := fmt.Sprintf("%s %d", jti, expiresAt.Unix())
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, payload); err != nil {
+			return fmt.Errorf("failed to notify token revocation: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *repository) FindActive(ctx context.Context, now time.Time) (map[string]time.Time, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > $1`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revoked tokens: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[string]time.Time)
+	for rows.Next() {
+		var (
+			jti       string
+			expiresAt time.Time
+		)
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked token: %w", err)
+		}
+		entries[jti] = expiresAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate revoked tokens: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) error {
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+	return nil
+}
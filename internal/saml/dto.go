@@ -0,0 +1,108 @@
+package saml
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+// AttributeMapping names the assertion attributes each profile column is
+// read from; an empty name leaves the column alone.
+type AttributeMapping struct {
+	Email     string `json:"email" binding:"max=255"`
+	FirstName string `json:"first_name" binding:"max=255"`
+	LastName  string `json:"last_name" binding:"max=255"`
+	Phone     string `json:"phone" binding:"max=255"`
+}
+
+// CreateConnectionRequest configures the identity provider either from its
+// metadata or from its individual fields; fields given alongside metadata
+// override it. A connection is enabled unless Enabled says otherwise.
+type CreateConnectionRequest struct {
+	MetadataXML                string            `json:"metadata_xml" binding:"max=1048576"`
+	IdPEntityID                string            `json:"idp_entity_id" binding:"max=1024"`
+	IdPSSOURL                  string            `json:"idp_sso_url" binding:"omitempty,url,max=2048"`
+	IdPCertificates            []string          `json:"idp_certificates" binding:"omitempty,dive,required"`
+	AttributeMapping           *AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated          bool              `json:"allow_idp_initiated"`
+	JITProvisioning            bool              `json:"jit_provisioning"`
+	RequireEncryptedAssertions bool              `json:"require_encrypted_assertions"`
+	Enabled                    *bool             `json:"enabled"`
+}
+
+// UpdateConnectionRequest changes the fields it carries. Metadata replaces
+// the identity provider fields it describes.
+type UpdateConnectionRequest struct {
+	MetadataXML                *string           `json:"metadata_xml" binding:"omitempty,max=1048576"`
+	IdPEntityID                *string           `json:"idp_entity_id" binding:"omitempty,max=1024"`
+	IdPSSOURL                  *string           `json:"idp_sso_url" binding:"omitempty,url,max=2048"`
+	IdPCertificates            []string          `json:"idp_certificates" binding:"omitempty,min=1,dive,required"`
+	AttributeMapping           *AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated          *bool             `json:"allow_idp_initiated"`
+	JITProvisioning            *bool             `json:"jit_provisioning"`
+	RequireEncryptedAssertions *bool             `json:"require_encrypted_assertions"`
+	Enabled                    *bool             `json:"enabled"`
+}
+
+// ConnectionResponse carries what the identity provider must be told about
+// the service provider along with the configuration.
+type ConnectionResponse struct {
+	ID                         string           `json:"id"`
+	IdPEntityID                string           `json:"idp_entity_id"`
+	IdPSSOURL                  string           `json:"idp_sso_url"`
+	IdPCertificates            []string         `json:"idp_certificates"`
+	SPEntityID                 string           `json:"sp_entity_id"`
+	SPACSURL                   string           `json:"sp_acs_url"`
+	SPLoginURL                 string           `json:"sp_login_url"`
+	SPCertificate              string           `json:"sp_certificate"`
+	AttributeMapping           AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated          bool             `json:"allow_idp_initiated"`
+	JITProvisioning            bool             `json:"jit_provisioning"`
+	RequireEncryptedAssertions bool             `json:"require_encrypted_assertions"`
+	Enabled                    bool             `json:"enabled"`
+	CreatedAt                  time.Time        `json:"created_at"`
+	UpdatedAt                  time.Time        `json:"updated_at"`
+}
+
+// ACSRequest is the form an identity provider posts to the assertion
+// consumer service.
+type ACSRequest struct {
+	SAMLResponse string `form:"SAMLResponse" binding:"required"`
+	RelayState   string `form:"RelayState" binding:"max=1024"`
+}
+
+// ACSResponse carries the session, or the second factor challenge, along
+// with the RelayState the login was started with.
+type ACSResponse struct {
+	*auth.LoginResponse
+	RelayState string `json:"relay_state,omitempty"`
+}
+
+func NewConnectionResponse(connection domain.SAMLConnectionInterface, urls endpoints) ConnectionResponse {
+	settings := connection.GetSettings()
+	mapping := settings.AttributeMapping
+
+	return ConnectionResponse{
+		ID:              connection.GetID().String(),
+		IdPEntityID:     settings.IdPEntityID,
+		IdPSSOURL:       settings.IdPSSOURL,
+		IdPCertificates: settings.IdPCertificates,
+		SPEntityID:      urls.EntityID,
+		SPACSURL:        urls.ACSURL,
+		SPLoginURL:      urls.LoginURL,
+		SPCertificate:   connection.GetSPCertificate(),
+		AttributeMapping: AttributeMapping{
+			Email:     mapping.Email,
+			FirstName: mapping.FirstName,
+			LastName:  mapping.LastName,
+			Phone:     mapping.Phone,
+		},
+		AllowIdPInitiated:          settings.AllowIdPInitiated,
+		JITProvisioning:            settings.JITProvisioning,
+		RequireEncryptedAssertions: settings.RequireEncryptedAssertions,
+		Enabled:                    settings.Enabled,
+		CreatedAt:                  connection.GetCreatedAt(),
+		UpdatedAt:                  connection.GetUpdatedAt(),
+	}
+}
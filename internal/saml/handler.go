@@ -0,0 +1,168 @@
+package saml
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBodySize bounds the metadata and responses posted; both are small
+// unless an identity provider stuffs them with certificates.
+const maxBodySize = 2 << 20
+
+type handler struct {
+	service ServiceInterface
+	checker middleware.PermissionCheckerInterface
+	tokens  token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateConnection(ctx *gin.Context)
+	GetConnection(ctx *gin.Context)
+	UpdateConnection(ctx *gin.Context)
+	DeleteConnection(ctx *gin.Context)
+	Metadata(ctx *gin.Context)
+	Login(ctx *gin.Context)
+	ACS(ctx *gin.Context)
+}
+
+func NewHandler(
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		service: service,
+		checker: checker,
+		tokens:  tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	connection := router.Group(
+		"/api/v1/orgs/current/saml",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+		middleware.RequireOrg(),
+		middleware.RequirePermission(h.checker, org.ResourceOrg, org.ActionManage),
+		limitBody,
+	)
+	{
+		connection.POST("", h.CreateConnection)
+		connection.GET("", h.GetConnection)
+		connection.PATCH("", h.UpdateConnection)
+		connection.DELETE("", h.DeleteConnection)
+	}
+
+	// The browser reaches these on its way to and back from the identity
+	// provider, so they are open; the connection tells the organization.
+	saml := router.Group("/api/v1/saml/:connection_id", limitBody)
+	{
+		saml.GET("/metadata", h.Metadata)
+		saml.GET("/login", h.Login)
+		saml.POST("/acs", h.ACS)
+	}
+}
+
+func limitBody(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodySize)
+	ctx.Next()
+}
+
+func (h *handler) CreateConnection(ctx *gin.Context) {
+	var req CreateConnectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.CreateConnection(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) GetConnection(ctx *gin.Context) {
+	res, restErr := h.service.GetConnection(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) UpdateConnection(ctx *gin.Context) {
+	var req UpdateConnectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.UpdateConnection(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) DeleteConnection(ctx *gin.Context) {
+	if restErr := h.service.DeleteConnection(ctx.Request.Context()); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) Metadata(ctx *gin.Context) {
+	res, restErr := h.service.Metadata(ctx.Request.Context(), ctx.Param("connection_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/samlmetadata+xml", res)
+}
+
+func (h *handler) Login(ctx *gin.Context) {
+	location, restErr := h.service.BeginLogin(ctx.Request.Context(), ctx.Param("connection_id"), ctx.Query("RelayState"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, location)
+}
+
+func (h *handler) ACS(ctx *gin.Context) {
+	var req ACSRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.ConsumeResponse(ctx.Request.Context(), ctx.Param("connection_id"), req)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
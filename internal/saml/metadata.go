@@ -0,0 +1,123 @@
+package saml
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+)
+
+const (
+	namespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	namespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	namespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	nameIDFormatEmail       = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	nameIDFormatPersistent  = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	nameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// identityProvider is what the metadata of an identity provider tells.
+type identityProvider struct {
+	EntityID     string
+	SSOURL       string
+	Certificates []string
+}
+
+// parseIdentityProvider reads the entity ID, the HTTP-Redirect single
+// sign-on endpoint and the signing certificates out of identity provider
+// metadata. An EntitiesDescriptor is accepted when it holds a single
+// identity provider.
+func parseIdentityProvider(data []byte) (identityProvider, error) {
+	root, err := xmlsec.Parse(data)
+	if err != nil {
+		return identityProvider{}, err
+	}
+
+	var descriptors []*etree.Element
+	xmlsec.Walk(root, func(el *etree.Element) {
+		if xmlsec.Is(el, namespaceMetadata, "EntityDescriptor") && xmlsec.Child(el, namespaceMetadata, "IDPSSODescriptor") != nil {
+			descriptors = append(descriptors, el)
+		}
+	})
+	if len(descriptors) != 1 {
+		return identityProvider{}, errors.New("metadata must describe exactly one identity provider")
+	}
+	entity := descriptors[0]
+	descriptor := xmlsec.Child(entity, namespaceMetadata, "IDPSSODescriptor")
+
+	idp := identityProvider{EntityID: xmlsec.Attr(entity, "entityID")}
+	if idp.EntityID == "" {
+		return identityProvider{}, errors.New("metadata has no entityID")
+	}
+
+	for _, service := range xmlsec.Children(descriptor, namespaceMetadata, "SingleSignOnService") {
+		if xmlsec.Attr(service, "Binding") == bindingHTTPRedirect {
+			idp.SSOURL = xmlsec.Attr(service, "Location")
+			break
+		}
+	}
+	if idp.SSOURL == "" {
+		return identityProvider{}, errors.New("metadata has no HTTP-Redirect single sign-on service")
+	}
+
+	for _, key := range xmlsec.Children(descriptor, namespaceMetadata, "KeyDescriptor") {
+		if use := xmlsec.Attr(key, "use"); use != "" && use != "signing" {
+			continue
+		}
+		x509Data := xmlsec.Child(xmlsec.Child(key, xmlsec.NamespaceDSig, "KeyInfo"), xmlsec.NamespaceDSig, "X509Data")
+		for _, cert := range xmlsec.Children(x509Data, xmlsec.NamespaceDSig, "X509Certificate") {
+			parsed, err := xmlsec.ParseCertificate(xmlsec.Text(cert))
+			if err != nil {
+				return identityProvider{}, fmt.Errorf("metadata has an invalid certificate: %w", err)
+			}
+			idp.Certificates = append(idp.Certificates, base64.StdEncoding.EncodeToString(parsed.Raw))
+		}
+	}
+	if len(idp.Certificates) == 0 {
+		return identityProvider{}, errors.New("metadata has no signing certificate")
+	}
+
+	return idp, nil
+}
+
+// serviceProviderMetadata describes the service provider of a connection
+// to its identity provider.
+func serviceProviderMetadata(connection domain.SAMLConnectionInterface, entityID, acsURL string) []byte {
+	entity := xmlsec.NewElement(namespaceMetadata, "md", "EntityDescriptor")
+	entity.CreateAttr("entityID", entityID)
+
+	descriptor := xmlsec.NewChild(entity, "SPSSODescriptor")
+	descriptor.CreateAttr("AuthnRequestsSigned", "false")
+	descriptor.CreateAttr("WantAssertionsSigned", "true")
+	descriptor.CreateAttr("protocolSupportEnumeration", namespaceProtocol)
+
+	for _, use := range []string{"signing", "encryption"} {
+		key := xmlsec.NewChild(descriptor, "KeyDescriptor")
+		key.CreateAttr("use", use)
+		keyInfo := xmlsec.NewElement(xmlsec.NamespaceDSig, "ds", "KeyInfo")
+		key.AddChild(keyInfo)
+		xmlsec.NewTextChild(xmlsec.NewChild(keyInfo, "X509Data"), "X509Certificate", connection.GetSPCertificate())
+		if use == "encryption" {
+			for _, method := range []string{xmlsec.BlockAES256GCM, xmlsec.BlockAES128GCM, xmlsec.BlockAES256CBC, xmlsec.KeyTransportRSAOAEPMGF1P} {
+				xmlsec.NewChild(key, "EncryptionMethod").CreateAttr("Algorithm", method)
+			}
+		}
+	}
+
+	xmlsec.NewTextChild(descriptor, "NameIDFormat", nameIDFormatPersistent)
+	xmlsec.NewTextChild(descriptor, "NameIDFormat", nameIDFormatEmail)
+	acs := xmlsec.NewChild(descriptor, "AssertionConsumerService")
+	acs.CreateAttr("Binding", bindingHTTPPost)
+	acs.CreateAttr("Location", acsURL)
+	acs.CreateAttr("index", "0")
+	acs.CreateAttr("isDefault", "true")
+
+	return append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"), xmlsec.Bytes(entity)...)
+}
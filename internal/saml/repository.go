@@ -0,0 +1,392 @@
+package saml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/secretbox"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrConnectionNotFound = errors.New("saml connection not found")
+	ErrConnectionExists   = errors.New("organization already has a saml connection")
+	ErrIdentityNotFound   = errors.New("saml identity not found")
+	ErrRequestNotFound    = errors.New("saml request not found")
+	// ErrAssertionReplayed is returned when an assertion was consumed
+	// before.
+	ErrAssertionReplayed = errors.New("saml assertion already consumed")
+)
+
+const selectConnectionColumns = `
+	SELECT id, org_id, idp_entity_id, idp_sso_url, idp_certificates, sp_private_key, sp_certificate,
+		attribute_mapping, allow_idp_initiated, jit_provisioning, require_encrypted_assertions, enabled,
+		created_at, updated_at
+	FROM saml_connections`
+
+type repository struct {
+	db  database.DatabaseInterface
+	box secretbox.BoxInterface
+}
+
+// RepositoryInterface stores SAML connections and the state of their
+// logins. Apart from FindConnection, which the public endpoints use to
+// learn the tenant, every method is scoped to the tenant in the context.
+type RepositoryInterface interface {
+	CreateConnection(ctx context.Context, connection domain.SAMLConnectionInterface) error
+	FindConnection(ctx context.Context, id uuid.UUID) (domain.SAMLConnectionInterface, error)
+	// FindOrgConnection returns the connection of the organization.
+	FindOrgConnection(ctx context.Context) (domain.SAMLConnectionInterface, error)
+	UpdateConnection(ctx context.Context, connection domain.SAMLConnectionInterface) error
+	DeleteConnection(ctx context.Context, id uuid.UUID) error
+
+	// FindIdentity returns the user the subject is linked to.
+	FindIdentity(ctx context.Context, connectionID uuid.UUID, nameID string) (uuid.UUID, error)
+	CreateIdentity(ctx context.Context, connectionID uuid.UUID, nameID string, userID uuid.UUID) error
+	TouchIdentity(ctx context.Context, connectionID uuid.UUID, nameID string) error
+
+	CreateRequest(ctx context.Context, connectionID uuid.UUID, id string, expiresAt time.Time) error
+	// ConsumeRequest deletes the request, so it is answered once, and
+	// returns ErrRequestNotFound when it is unknown or expired.
+	ConsumeRequest(ctx context.Context, connectionID uuid.UUID, id string, now time.Time) error
+	// RecordAssertion remembers an assertion until it expires, and returns
+	// ErrAssertionReplayed when it was already recorded.
+	RecordAssertion(ctx context.Context, connectionID uuid.UUID, id string, expiresAt time.Time) error
+	// DeleteExpired drops the requests and assertions of the organization
+	// that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface, box secretbox.BoxInterface) RepositoryInterface {
+	return &repository{
+		db:  db,
+		box: box,
+	}
+}
+
+func (r *repository) CreateConnection(ctx context.Context, connection domain.SAMLConnectionInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := r.box.Seal(connection.GetSPPrivateKey(), privateKeyAD(connection.GetID()))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt saml private key: %w", err)
+	}
+
+	settings := connection.GetSettings()
+	mapping, err := json.Marshal(settings.AttributeMapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode attribute mapping: %w", err)
+	}
+
+	query := `
+		INSERT INTO saml_connections (
+			id, org_id, idp_entity_id, idp_sso_url, idp_certificates, sp_private_key, sp_certificate,
+			attribute_mapping, allow_idp_initiated, jit_provisioning, require_encrypted_assertions, enabled,
+			created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		connection.GetID(),
+		orgID,
+		settings.IdPEntityID,
+		settings.IdPSSOURL,
+		settings.IdPCertificates,
+		sealed,
+		connection.GetSPCertificate(),
+		mapping,
+		settings.AllowIdPInitiated,
+		settings.JITProvisioning,
+		settings.RequireEncryptedAssertions,
+		settings.Enabled,
+		connection.GetCreatedAt(),
+		connection.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrConnectionExists
+		}
+		return fmt.Errorf("failed to insert saml connection: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindConnection(ctx context.Context, id uuid.UUID) (domain.SAMLConnectionInterface, error) {
+	query := selectConnectionColumns + ` WHERE id = $1`
+
+	return r.scanConnection(r.db.GetQuerier(ctx).QueryRow(ctx, query, id))
+}
+
+func (r *repository) FindOrgConnection(ctx context.Context) (domain.SAMLConnectionInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectConnectionColumns + ` WHERE org_id = $1`
+
+	return r.scanConnection(r.db.GetQuerier(ctx).QueryRow(ctx, query, orgID))
+}
+
+func (r *repository) UpdateConnection(ctx context.Context, connection domain.SAMLConnectionInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	settings := connection.GetSettings()
+	mapping, err := json.Marshal(settings.AttributeMapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode attribute mapping: %w", err)
+	}
+
+	query := `
+		UPDATE saml_connections
+		SET idp_entity_id = $3, idp_sso_url = $4, idp_certificates = $5, attribute_mapping = $6,
+			allow_idp_initiated = $7, jit_provisioning = $8, require_encrypted_assertions = $9, enabled = $10,
+			updated_at = $11
+		WHERE id = $1 AND org_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		connection.GetID(),
+		orgID,
+		settings.IdPEntityID,
+		settings.IdPSSOURL,
+		settings.IdPCertificates,
+		mapping,
+		settings.AllowIdPInitiated,
+		settings.JITProvisioning,
+		settings.RequireEncryptedAssertions,
+		settings.Enabled,
+		connection.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update saml connection: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConnectionNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteConnection(ctx context.Context, id uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM saml_connections WHERE id = $1 AND org_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete saml connection: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrConnectionNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) FindIdentity(ctx context.Context, connectionID uuid.UUID, nameID string) (uuid.UUID, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	query := `
+		SELECT user_id FROM saml_identities
+		WHERE connection_id = $1 AND name_id = $2 AND org_id = $3`
+
+	var userID uuid.UUID
+	err = r.db.GetQuerier(ctx).QueryRow(ctx, query, connectionID, nameID, orgID).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrIdentityNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to find saml identity: %w", err)
+	}
+
+	return userID, nil
+}
+
+func (r *repository) CreateIdentity(ctx context.Context, connectionID uuid.UUID, nameID string, userID uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saml_identities (connection_id, name_id, user_id, org_id, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query, connectionID, nameID, userID, orgID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert saml identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) TouchIdentity(ctx context.Context, connectionID uuid.UUID, nameID string) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE saml_identities SET last_login_at = $4
+		WHERE connection_id = $1 AND name_id = $2 AND org_id = $3`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query, connectionID, nameID, orgID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update saml identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) CreateRequest(ctx context.Context, connectionID uuid.UUID, id string, expiresAt time.Time) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saml_requests (id, connection_id, org_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query, id, connectionID, orgID, expiresAt, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert saml request: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) ConsumeRequest(ctx context.Context, connectionID uuid.UUID, id string, now time.Time) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM saml_requests
+		WHERE id = $1 AND connection_id = $2 AND org_id = $3 AND expires_at > $4`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, id, connectionID, orgID, now)
+	if err != nil {
+		return fmt.Errorf("failed to consume saml request: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRequestNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) RecordAssertion(ctx context.Context, connectionID uuid.UUID, id string, expiresAt time.Time) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO saml_assertions (connection_id, assertion_id, org_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (connection_id, assertion_id) DO NOTHING`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, connectionID, id, orgID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record saml assertion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAssertionReplayed
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		`DELETE FROM saml_requests WHERE org_id = $1 AND expires_at <= $2`,
+		`DELETE FROM saml_assertions WHERE org_id = $1 AND expires_at <= $2`,
+	} {
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, orgID, now); err != nil {
+			return fmt.Errorf("failed to delete expired saml state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) scanConnection(row pgx.Row) (domain.SAMLConnectionInterface, error) {
+	var (
+		id            uuid.UUID
+		orgID         uuid.UUID
+		settings      domain.SAMLConnectionSettings
+		sealed        []byte
+		spCertificate string
+		mapping       []byte
+		createdAt     time.Time
+		updatedAt     time.Time
+	)
+
+	err := row.Scan(
+		&id,
+		&orgID,
+		&settings.IdPEntityID,
+		&settings.IdPSSOURL,
+		&settings.IdPCertificates,
+		&sealed,
+		&spCertificate,
+		&mapping,
+		&settings.AllowIdPInitiated,
+		&settings.JITProvisioning,
+		&settings.RequireEncryptedAssertions,
+		&settings.Enabled,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrConnectionNotFound
+		}
+		return nil, fmt.Errorf("failed to scan saml connection: %w", err)
+	}
+
+	if err := json.Unmarshal(mapping, &settings.AttributeMapping); err != nil {
+		return nil, fmt.Errorf("failed to decode attribute mapping: %w", err)
+	}
+
+	privateKey, err := r.box.Open(sealed, privateKeyAD(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt saml private key: %w", err)
+	}
+
+	return domain.RestoreSAMLConnection(id, orgID, settings, privateKey, spCertificate, createdAt, updatedAt), nil
+}
+
+func privateKeyAD(id uuid.UUID) []byte {
+	return []byte("saml:" + id.String())
+}
@@ -0,0 +1,76 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+)
+
+// newMessageID returns an ID for a request or assertion. IDs must not
+// start with a digit, hence the underscore.
+func newMessageID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "_" + hex.EncodeToString(b)
+}
+
+// authnRequest is an AuthnRequest asking for a persistent subject, to be
+// answered at acsURL over HTTP-POST.
+func authnRequest(id, entityID, acsURL, destination string, now time.Time) *etree.Element {
+	request := xmlsec.NewElement(namespaceProtocol, "samlp", "AuthnRequest")
+	request.CreateAttr("xmlns:saml", namespaceAssertion)
+	request.CreateAttr("ID", id)
+	request.CreateAttr("Version", "2.0")
+	request.CreateAttr("IssueInstant", formatInstant(now))
+	request.CreateAttr("Destination", destination)
+	request.CreateAttr("AssertionConsumerServiceURL", acsURL)
+	request.CreateAttr("ProtocolBinding", bindingHTTPPost)
+
+	request.CreateElement("saml:Issuer").SetText(entityID)
+
+	policy := xmlsec.NewChild(request, "NameIDPolicy")
+	policy.CreateAttr("Format", nameIDFormatPersistent)
+	policy.CreateAttr("AllowCreate", "true")
+
+	return request
+}
+
+// redirectURL encodes a message for the HTTP-Redirect binding: deflated,
+// base64 encoded and added to the query of the endpoint.
+func redirectURL(endpoint, parameter string, message *etree.Element, relayState string) (string, error) {
+	var deflated bytes.Buffer
+	writer, err := flate.NewWriter(&deflated, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(xmlsec.Bytes(message)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set(parameter, base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func formatInstant(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
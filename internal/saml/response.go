@@ -0,0 +1,285 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+)
+
+const (
+	statusSuccess        = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmationBearer   = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	maxAssertionLifetime = 24 * time.Hour
+)
+
+// ErrInvalidResponse is wrapped by every reason a response is refused.
+var ErrInvalidResponse = errors.New("invalid saml response")
+
+// assertion is what a validated response asserts about its subject.
+type assertion struct {
+	ID           string
+	InResponseTo string
+	NameID       string
+	NameIDFormat string
+	Attributes   map[string][]string
+	// ExpiresAt is when the assertion stops being acceptable, which is as
+	// long as it must be remembered to refuse a replay.
+	ExpiresAt time.Time
+}
+
+// Attribute returns the first value of the named attribute.
+func (a *assertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// validator checks responses meant for one service provider.
+type validator struct {
+	EntityID          string
+	ACSURL            string
+	IdPEntityID       string
+	Certificates      []*x509.Certificate
+	DecryptionKey     *rsa.PrivateKey
+	RequireEncryption bool
+	ClockSkew         time.Duration
+	Now               time.Time
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidResponse, fmt.Sprintf(format, args...))
+}
+
+// Validate checks a Response to an authentication request and returns its
+// assertion. The response or the assertion must be signed by the identity
+// provider, and everything is read from what goxmldsig hands back as
+// signed, so nothing unsigned can be slipped in next to it.
+func (v validator) Validate(data []byte) (*assertion, error) {
+	root, err := xmlsec.Parse(data)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	if !xmlsec.Is(root, namespaceProtocol, "Response") {
+		return nil, invalid("not a response")
+	}
+
+	root, responseSigned, err := v.verify(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if version := xmlsec.Attr(root, "Version"); version != "2.0" {
+		return nil, invalid("unsupported version %q", version)
+	}
+	if destination := xmlsec.Attr(root, "Destination"); destination != "" && destination != v.ACSURL {
+		return nil, invalid("response is meant for %s", destination)
+	}
+	if err := v.checkIssuer(root); err != nil {
+		return nil, err
+	}
+
+	status := xmlsec.Child(xmlsec.Child(root, namespaceProtocol, "Status"), namespaceProtocol, "StatusCode")
+	if value := xmlsec.Attr(status, "Value"); value != statusSuccess {
+		return nil, invalid("identity provider answered %s", value)
+	}
+
+	plain := xmlsec.Children(root, namespaceAssertion, "Assertion")
+	encrypted := xmlsec.Children(root, namespaceAssertion, "EncryptedAssertion")
+	if len(plain)+len(encrypted) != 1 {
+		return nil, invalid("a single assertion is required")
+	}
+
+	var el *etree.Element
+	if len(encrypted) == 1 {
+		if v.DecryptionKey == nil {
+			return nil, invalid("encrypted assertions are not expected")
+		}
+		el, err = xmlsec.Decrypt(encrypted[0], v.DecryptionKey)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		if !xmlsec.Is(el, namespaceAssertion, "Assertion") {
+			return nil, invalid("encrypted element is not an assertion")
+		}
+	} else {
+		if v.RequireEncryption {
+			return nil, invalid("assertion is not encrypted")
+		}
+		el = plain[0]
+	}
+
+	el, assertionSigned, err := v.verify(el)
+	if err != nil {
+		return nil, err
+	}
+	if !responseSigned && !assertionSigned {
+		return nil, invalid("neither the response nor the assertion is signed")
+	}
+
+	return v.readAssertion(el, xmlsec.Attr(root, "InResponseTo"))
+}
+
+// verify checks the signature of el, if any, and returns el as it was
+// signed along with whether it had a signature at all.
+func (v validator) verify(el *etree.Element) (*etree.Element, bool, error) {
+	signed, err := xmlsec.Verify(el, v.Certificates, v.Now)
+	if errors.Is(err, xmlsec.ErrNoSignature) {
+		return el, false, nil
+	}
+	if err != nil {
+		return nil, false, invalid("%v", err)
+	}
+	return signed, true, nil
+}
+
+func (v validator) checkIssuer(el *etree.Element) error {
+	issuer := xmlsec.Child(el, namespaceAssertion, "Issuer")
+	if issuer == nil {
+		return nil
+	}
+	if value := strings.TrimSpace(xmlsec.Text(issuer)); value != v.IdPEntityID {
+		return invalid("issued by %s", value)
+	}
+	return nil
+}
+
+func (v validator) readAssertion(el *etree.Element, inResponseTo string) (*assertion, error) {
+	a := &assertion{
+		ID:           xmlsec.Attr(el, "ID"),
+		InResponseTo: inResponseTo,
+		Attributes:   map[string][]string{},
+	}
+	if a.ID == "" {
+		return nil, invalid("assertion has no ID")
+	}
+	if xmlsec.Child(el, namespaceAssertion, "Issuer") == nil {
+		return nil, invalid("assertion has no issuer")
+	}
+	if err := v.checkIssuer(el); err != nil {
+		return nil, err
+	}
+
+	subject := xmlsec.Child(el, namespaceAssertion, "Subject")
+	nameID := xmlsec.Child(subject, namespaceAssertion, "NameID")
+	a.NameID = strings.TrimSpace(xmlsec.Text(nameID))
+	a.NameIDFormat = xmlsec.Attr(nameID, "Format")
+	if a.NameID == "" {
+		return nil, invalid("assertion has no subject")
+	}
+
+	confirmedUntil, err := v.checkConfirmation(subject, inResponseTo)
+	if err != nil {
+		return nil, err
+	}
+
+	conditionsUntil, err := v.checkConditions(xmlsec.Child(el, namespaceAssertion, "Conditions"))
+	if err != nil {
+		return nil, err
+	}
+
+	a.ExpiresAt = confirmedUntil
+	if conditionsUntil.Before(a.ExpiresAt) {
+		a.ExpiresAt = conditionsUntil
+	}
+	a.ExpiresAt = a.ExpiresAt.Add(v.ClockSkew)
+
+	for _, statement := range xmlsec.Children(el, namespaceAssertion, "AttributeStatement") {
+		for _, attribute := range xmlsec.Children(statement, namespaceAssertion, "Attribute") {
+			var values []string
+			for _, value := range xmlsec.Children(attribute, namespaceAssertion, "AttributeValue") {
+				values = append(values, xmlsec.Text(value))
+			}
+			for _, name := range []string{xmlsec.Attr(attribute, "Name"), xmlsec.Attr(attribute, "FriendlyName")} {
+				if name != "" {
+					a.Attributes[name] = append(a.Attributes[name], values...)
+				}
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// checkConfirmation requires a bearer confirmation for this service
+// provider, still valid and answering the same request as the response,
+// and returns until when it is.
+func (v validator) checkConfirmation(subject *etree.Element, inResponseTo string) (time.Time, error) {
+	for _, confirmation := range xmlsec.Children(subject, namespaceAssertion, "SubjectConfirmation") {
+		if xmlsec.Attr(confirmation, "Method") != confirmationBearer {
+			continue
+		}
+		data := xmlsec.Child(confirmation, namespaceAssertion, "SubjectConfirmationData")
+		if data == nil || xmlsec.Attr(data, "Recipient") != v.ACSURL || xmlsec.Attr(data, "InResponseTo") != inResponseTo {
+			continue
+		}
+		if xmlsec.Attr(data, "NotBefore") != "" {
+			continue
+		}
+		notOnOrAfter, err := parseInstant(xmlsec.Attr(data, "NotOnOrAfter"))
+		if err != nil || !v.Now.Before(notOnOrAfter.Add(v.ClockSkew)) {
+			continue
+		}
+		return notOnOrAfter, nil
+	}
+
+	return time.Time{}, invalid("no valid bearer confirmation for %s", v.ACSURL)
+}
+
+// checkConditions requires the assertion to be valid now and meant for
+// this service provider, and returns until when it is valid.
+func (v validator) checkConditions(conditions *etree.Element) (time.Time, error) {
+	if conditions == nil {
+		return time.Time{}, invalid("assertion has no conditions")
+	}
+
+	if value := xmlsec.Attr(conditions, "NotBefore"); value != "" {
+		notBefore, err := parseInstant(value)
+		if err != nil {
+			return time.Time{}, invalid("invalid NotBefore")
+		}
+		if v.Now.Add(v.ClockSkew).Before(notBefore) {
+			return time.Time{}, invalid("assertion is not valid yet")
+		}
+	}
+
+	until := v.Now.Add(maxAssertionLifetime)
+	if value := xmlsec.Attr(conditions, "NotOnOrAfter"); value != "" {
+		notOnOrAfter, err := parseInstant(value)
+		if err != nil {
+			return time.Time{}, invalid("invalid NotOnOrAfter")
+		}
+		if !v.Now.Before(notOnOrAfter.Add(v.ClockSkew)) {
+			return time.Time{}, invalid("assertion has expired")
+		}
+		until = notOnOrAfter
+	}
+
+	restrictions := xmlsec.Children(conditions, namespaceAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return time.Time{}, invalid("assertion has no audience")
+	}
+	for _, restriction := range restrictions {
+		matched := false
+		for _, audience := range xmlsec.Children(restriction, namespaceAssertion, "Audience") {
+			if strings.TrimSpace(xmlsec.Text(audience)) == v.EntityID {
+				matched = true
+			}
+		}
+		if !matched {
+			return time.Time{}, invalid("assertion is meant for another audience")
+		}
+	}
+
+	return until, nil
+}
+
+func parseInstant(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+}
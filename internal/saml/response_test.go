@@ -0,0 +1,403 @@
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	testEntityID    = "https://sp.example.com/saml/metadata"
+	testACSURL      = "https://sp.example.com/saml/acs"
+	testIdPEntityID = "https://idp.example.com"
+	testRequestID   = "_request"
+)
+
+var testNow = time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+
+func TestValidateSignedResponse(t *testing.T) {
+	idp := newTestIdP(t)
+
+	for _, signed := range []string{"response", "assertion", "both"} {
+		t.Run(signed, func(t *testing.T) {
+			f := idp.fixture()
+			f.SignResponse = signed != "assertion"
+			f.SignAssertion = signed != "response"
+
+			got, err := idp.validator().Validate(f.build(t))
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got.ID != "_assertion" || got.NameID != "jane@example.com" || got.InResponseTo != testRequestID {
+				t.Fatalf("assertion = %+v", got)
+			}
+			if got.Attribute("email") != "jane@example.com" || got.Attribute("givenName") != "Jane" {
+				t.Fatalf("attributes = %v", got.Attributes)
+			}
+			if want := testNow.Add(5*time.Minute + time.Minute); !got.ExpiresAt.Equal(want) {
+				t.Fatalf("ExpiresAt = %v, want %v", got.ExpiresAt, want)
+			}
+		})
+	}
+}
+
+func TestValidateEncryptedAssertion(t *testing.T) {
+	idp := newTestIdP(t)
+	spKey, spCert := newTestKeyPair(t)
+
+	f := idp.fixture()
+	f.SignAssertion = true
+	f.EncryptTo = spCert
+	data := f.build(t)
+	if strings.Contains(string(data), "jane@example.com") {
+		t.Fatal("the encrypted fixture carries the subject in clear")
+	}
+
+	v := idp.validator()
+	v.DecryptionKey = spKey
+	v.RequireEncryption = true
+	got, err := v.Validate(data)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got.NameID != "jane@example.com" {
+		t.Fatalf("NameID = %q", got.NameID)
+	}
+
+	if _, err := idp.validator().Validate(data); !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("Validate() without a decryption key error = %v", err)
+	}
+
+	plain := idp.fixture()
+	plain.SignAssertion = true
+	if _, err := v.Validate(plain.build(t)); !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("Validate() of a plain assertion with encryption required error = %v", err)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	idp := newTestIdP(t)
+	foreign := newTestIdP(t)
+
+	tests := []struct {
+		name  string
+		build func(t *testing.T) []byte
+	}{
+		{
+			name: "unsigned",
+			build: func(t *testing.T) []byte {
+				return idp.fixture().build(t)
+			},
+		},
+		{
+			name: "signed by a foreign key",
+			build: func(t *testing.T) []byte {
+				f := foreign.fixture()
+				f.SignResponse, f.SignAssertion = true, true
+				return f.build(t)
+			},
+		},
+		{
+			name: "wrong audience",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				f.Audience = "https://other.example.com"
+				return f.build(t)
+			},
+		},
+		{
+			name: "expired",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				f.NotOnOrAfter = testNow.Add(-2 * time.Minute)
+				return f.build(t)
+			},
+		},
+		{
+			name: "not valid yet",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				f.NotBefore = testNow.Add(10 * time.Minute)
+				return f.build(t)
+			},
+		},
+		{
+			name: "wrong destination",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignResponse = true
+				f.Destination = "https://other.example.com/saml/acs"
+				return f.build(t)
+			},
+		},
+		{
+			name: "wrong recipient",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				f.Recipient = "https://other.example.com/saml/acs"
+				return f.build(t)
+			},
+		},
+		{
+			name: "confirmation for another request",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				f.ConfirmationInResponseTo = "_other"
+				return f.build(t)
+			},
+		},
+		{
+			name: "wrong issuer",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignResponse = true
+				f.Issuer = "https://evil.example.com"
+				return f.build(t)
+			},
+		},
+		{
+			name: "tampered after signing",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				data := f.build(t)
+				return []byte(strings.Replace(string(data), ">jane@example.com<", ">admin@example.com<", 1))
+			},
+		},
+		{
+			name: "signed assertion wrapped next to a forged one",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignAssertion = true
+				return wrapAssertion(t, f.build(t))
+			},
+		},
+		{
+			name: "two assertions",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignResponse = true
+				f.Extra = `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_second"/>`
+				return f.build(t)
+			},
+		},
+		{
+			name: "failed status",
+			build: func(t *testing.T) []byte {
+				f := idp.fixture()
+				f.SignResponse = true
+				f.Status = "urn:oasis:names:tc:SAML:2.0:status:Responder"
+				return f.build(t)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := idp.validator().Validate(tt.build(t)); !errors.Is(err, ErrInvalidResponse) {
+				t.Fatalf("Validate() = %+v, %v, want ErrInvalidResponse", got, err)
+			}
+		})
+	}
+}
+
+// testIdP is an identity provider with a locally generated key pair.
+type testIdP struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+
+	key, cert := newTestKeyPair(t)
+	return &testIdP{key: key, cert: cert}
+}
+
+func (idp *testIdP) validator() validator {
+	return validator{
+		EntityID:     testEntityID,
+		ACSURL:       testACSURL,
+		IdPEntityID:  testIdPEntityID,
+		Certificates: []*x509.Certificate{idp.cert},
+		ClockSkew:    time.Minute,
+		Now:          testNow,
+	}
+}
+
+// fixture returns a valid, unsigned response to testRequestID.
+func (idp *testIdP) fixture() *responseFixture {
+	return &responseFixture{
+		idp:                      idp,
+		Destination:              testACSURL,
+		Issuer:                   testIdPEntityID,
+		Status:                   statusSuccess,
+		Audience:                 testEntityID,
+		Recipient:                testACSURL,
+		ConfirmationInResponseTo: testRequestID,
+		NotBefore:                testNow.Add(-time.Minute),
+		NotOnOrAfter:             testNow.Add(5 * time.Minute),
+	}
+}
+
+type responseFixture struct {
+	idp *testIdP
+
+	Destination              string
+	Issuer                   string
+	Status                   string
+	Audience                 string
+	Recipient                string
+	ConfirmationInResponseTo string
+	NotBefore                time.Time
+	NotOnOrAfter             time.Time
+	// Extra is appended to the response after the assertion.
+	Extra string
+
+	SignResponse  bool
+	SignAssertion bool
+	// EncryptTo, when set, encrypts the assertion to the certificate.
+	EncryptTo *x509.Certificate
+}
+
+func (f *responseFixture) build(t *testing.T) []byte {
+	t.Helper()
+
+	assertion := fmt.Sprintf(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_assertion" Version="2.0" IssueInstant="%[1]s">`+
+		`<saml:Issuer>%[2]s</saml:Issuer>`+
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">jane@example.com</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">`+
+		`<saml:SubjectConfirmationData InResponseTo="%[3]s" Recipient="%[4]s" NotOnOrAfter="%[5]s"/>`+
+		`</saml:SubjectConfirmation></saml:Subject>`+
+		`<saml:Conditions NotBefore="%[6]s" NotOnOrAfter="%[5]s"><saml:AudienceRestriction><saml:Audience>%[7]s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AttributeStatement>`+
+		`<saml:Attribute Name="email"><saml:AttributeValue>jane@example.com</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="givenName"><saml:AttributeValue>Jane</saml:AttributeValue></saml:Attribute>`+
+		`</saml:AttributeStatement></saml:Assertion>`,
+		instant(testNow), f.Issuer, f.ConfirmationInResponseTo, f.Recipient, instant(f.NotOnOrAfter), instant(f.NotBefore), f.Audience)
+	if f.SignAssertion {
+		assertion = string(f.idp.sign(t, assertion))
+	}
+	if f.EncryptTo != nil {
+		data, err := xmlsec.Encrypt([]byte(assertion), f.EncryptTo)
+		if err != nil {
+			t.Fatalf("xmlsec.Encrypt() error = %v", err)
+		}
+		assertion = `<saml:EncryptedAssertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">` + string(xmlsec.Bytes(data)) + `</saml:EncryptedAssertion>`
+	}
+
+	response := fmt.Sprintf(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response" Version="2.0" IssueInstant="%s" Destination="%s" InResponseTo="%s">`+
+		`<saml:Issuer>%s</saml:Issuer>`+
+		`<samlp:Status><samlp:StatusCode Value="%s"/></samlp:Status>`+
+		`%s%s</samlp:Response>`,
+		instant(testNow), f.Destination, testRequestID, f.Issuer, f.Status, assertion, f.Extra)
+	if f.SignResponse {
+		return f.idp.sign(t, response)
+	}
+	return []byte(response)
+}
+
+// sign adds an enveloped signature to the root of document with goxmldsig,
+// as an identity provider would.
+func (idp *testIdP) sign(t *testing.T, document string) []byte {
+	t.Helper()
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(document); err != nil {
+		t.Fatalf("ReadFromString() error = %v", err)
+	}
+	ctx, err := dsig.NewSigningContext(idp.key, [][]byte{idp.cert.Raw})
+	if err != nil {
+		t.Fatalf("NewSigningContext() error = %v", err)
+	}
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	signed, err := ctx.SignEnveloped(doc.Root())
+	if err != nil {
+		t.Fatalf("SignEnveloped() error = %v", err)
+	}
+	doc.SetRoot(signed)
+
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatalf("WriteToBytes() error = %v", err)
+	}
+	return data
+}
+
+// wrapAssertion moves the signed assertion of a response into its
+// Extensions and puts a forged copy, carrying the same ID and signature,
+// in its place.
+func wrapAssertion(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		t.Fatalf("ReadFromBytes() error = %v", err)
+	}
+	response := doc.Root()
+	original := response.FindElement("./saml:Assertion")
+	if original == nil {
+		t.Fatal("fixture has no assertion")
+	}
+
+	forged := original.Copy()
+	forged.FindElement(".//saml:NameID").SetText("admin@example.com")
+
+	response.RemoveChild(original)
+	extensions := etree.NewElement("samlp:Extensions")
+	extensions.AddChild(original)
+	response.InsertChildAt(response.FindElement("./saml:Issuer").Index()+1, extensions)
+	response.AddChild(forged)
+
+	out, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatalf("WriteToBytes() error = %v", err)
+	}
+	return out
+}
+
+func instant(at time.Time) string {
+	return at.UTC().Format(time.RFC3339)
+}
+
+func newTestKeyPair(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    testNow.Add(-time.Hour),
+		NotAfter:     testNow.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return key, cert
+}
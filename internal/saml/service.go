@@ -0,0 +1,782 @@
+package saml
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+	"github.com/google/uuid"
+)
+
+const (
+	// loginMethodSAML is recorded with the logins and the accounts of this
+	// package.
+	loginMethodSAML = "saml"
+
+	invalidResponseMessage = "invalid saml response"
+
+	spKeyBits          = 2048
+	spCertificateValid = 10 * 365 * 24 * time.Hour
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// endpoints are the URLs of the service provider of a connection. The
+// entity ID is the metadata URL, as is customary.
+type endpoints struct {
+	EntityID    string
+	MetadataURL string
+	LoginURL    string
+	ACSURL      string
+}
+
+type service struct {
+	config     config.SAMLConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	accounts   user.ServiceInterface
+	orgs       org.RepositoryInterface
+	sessions   auth.ServiceInterface
+	audit      audit.RecorderInterface
+}
+
+// ServiceInterface configures the SAML connection of the organization in
+// the context and runs the service provider side of its logins, which
+// learn the organization from the connection in their URL.
+type ServiceInterface interface {
+	CreateConnection(ctx context.Context, req CreateConnectionRequest) (*ConnectionResponse, *httperr.HttpError)
+	GetConnection(ctx context.Context) (*ConnectionResponse, *httperr.HttpError)
+	UpdateConnection(ctx context.Context, req UpdateConnectionRequest) (*ConnectionResponse, *httperr.HttpError)
+	DeleteConnection(ctx context.Context) *httperr.HttpError
+
+	// Metadata returns the service provider metadata of a connection.
+	Metadata(ctx context.Context, connectionID string) ([]byte, *httperr.HttpError)
+	// BeginLogin returns where to send the browser to authenticate at the
+	// identity provider.
+	BeginLogin(ctx context.Context, connectionID, relayState string) (string, *httperr.HttpError)
+	// ConsumeResponse validates a response posted by the identity provider
+	// and signs its subject in to the organization, linking or, when
+	// allowed, provisioning an account on first sight.
+	ConsumeResponse(ctx context.Context, connectionID string, req ACSRequest) (*ACSResponse, *httperr.HttpError)
+}
+
+func NewService(
+	config config.SAMLConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	accounts user.ServiceInterface,
+	orgs org.RepositoryInterface,
+	sessions auth.ServiceInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		users:      users,
+		accounts:   accounts,
+		orgs:       orgs,
+		sessions:   sessions,
+		audit:      audit,
+	}
+}
+
+func (s *service) CreateConnection(ctx context.Context, req CreateConnectionRequest) (*ConnectionResponse, *httperr.HttpError) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, httperr.NewForbiddenError("an active organization is required")
+	}
+
+	settings := domain.SAMLConnectionSettings{
+		AttributeMapping:           domain.DefaultSAMLAttributeMapping(),
+		AllowIdPInitiated:          req.AllowIdPInitiated,
+		JITProvisioning:            req.JITProvisioning,
+		RequireEncryptedAssertions: req.RequireEncryptedAssertions,
+		Enabled:                    req.Enabled == nil || *req.Enabled,
+	}
+	if req.MetadataXML != "" {
+		if restErr := applyMetadata(&settings, req.MetadataXML); restErr != nil {
+			return nil, restErr
+		}
+	}
+	applyIdentityProvider(&settings, req.IdPEntityID, req.IdPSSOURL, req.IdPCertificates)
+	if req.AttributeMapping != nil {
+		settings.AttributeMapping = toDomainMapping(*req.AttributeMapping)
+	}
+	if restErr := validateSettings(&settings); restErr != nil {
+		return nil, restErr
+	}
+
+	privateKey, certificate, err := newServiceProviderKey()
+	if err != nil {
+		slog.Error("failed to generate saml key pair", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create saml connection")
+	}
+
+	connection := domain.NewSAMLConnection(orgID, settings, privateKey, certificate)
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateConnection(ctx, connection); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLConnectionCreate, audit.TargetSAMLConnection, connection.GetID(), describeConnection(settings))
+	})
+	if err != nil {
+		if errors.Is(err, ErrConnectionExists) {
+			return nil, httperr.NewBadRequestError("the organization already has a saml connection")
+		}
+		slog.Error("failed to create saml connection", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create saml connection")
+	}
+
+	slog.Info("saml connection created", slog.String("connection_id", connection.GetID().String()))
+
+	res := NewConnectionResponse(connection, s.endpoints(connection.GetID()))
+	return &res, nil
+}
+
+func (s *service) GetConnection(ctx context.Context) (*ConnectionResponse, *httperr.HttpError) {
+	connection, err := s.repository.FindOrgConnection(ctx)
+	if err != nil {
+		return nil, connectionError("get", err)
+	}
+
+	res := NewConnectionResponse(connection, s.endpoints(connection.GetID()))
+	return &res, nil
+}
+
+func (s *service) UpdateConnection(ctx context.Context, req UpdateConnectionRequest) (*ConnectionResponse, *httperr.HttpError) {
+	var (
+		connection domain.SAMLConnectionInterface
+		restErr    *httperr.HttpError
+	)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.repository.FindOrgConnection(ctx)
+		if err != nil {
+			return err
+		}
+		connection = found
+
+		settings := connection.GetSettings()
+		if req.MetadataXML != nil {
+			if restErr = applyMetadata(&settings, *req.MetadataXML); restErr != nil {
+				return nil
+			}
+		}
+		var entityID, ssoURL string
+		if req.IdPEntityID != nil {
+			entityID = *req.IdPEntityID
+		}
+		if req.IdPSSOURL != nil {
+			ssoURL = *req.IdPSSOURL
+		}
+		applyIdentityProvider(&settings, entityID, ssoURL, req.IdPCertificates)
+		if req.AttributeMapping != nil {
+			settings.AttributeMapping = toDomainMapping(*req.AttributeMapping)
+		}
+		if req.AllowIdPInitiated != nil {
+			settings.AllowIdPInitiated = *req.AllowIdPInitiated
+		}
+		if req.JITProvisioning != nil {
+			settings.JITProvisioning = *req.JITProvisioning
+		}
+		if req.RequireEncryptedAssertions != nil {
+			settings.RequireEncryptedAssertions = *req.RequireEncryptedAssertions
+		}
+		if req.Enabled != nil {
+			settings.Enabled = *req.Enabled
+		}
+		if restErr = validateSettings(&settings); restErr != nil {
+			return nil
+		}
+
+		connection.Update(settings)
+		if err := s.repository.UpdateConnection(ctx, connection); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLConnectionUpdate, audit.TargetSAMLConnection, connection.GetID(), describeConnection(settings))
+	})
+	if err != nil {
+		return nil, connectionError("update", err)
+	}
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewConnectionResponse(connection, s.endpoints(connection.GetID()))
+	return &res, nil
+}
+
+func (s *service) DeleteConnection(ctx context.Context) *httperr.HttpError {
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		connection, err := s.repository.FindOrgConnection(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.repository.DeleteConnection(ctx, connection.GetID()); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLConnectionDelete, audit.TargetSAMLConnection, connection.GetID(), nil)
+	})
+	if err != nil {
+		return connectionError("delete", err)
+	}
+
+	return nil
+}
+
+func (s *service) Metadata(ctx context.Context, connectionID string) ([]byte, *httperr.HttpError) {
+	connection, restErr := s.findConnection(ctx, connectionID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	urls := s.endpoints(connection.GetID())
+	return serviceProviderMetadata(connection, urls.EntityID, urls.ACSURL), nil
+}
+
+func (s *service) BeginLogin(ctx context.Context, connectionID, relayState string) (string, *httperr.HttpError) {
+	connection, restErr := s.findConnection(ctx, connectionID)
+	if restErr != nil {
+		return "", restErr
+	}
+	if !connection.GetSettings().Enabled {
+		return "", httperr.NewForbiddenError("saml connection is disabled")
+	}
+	ctx = database.WithTenant(ctx, connection.GetOrgID())
+
+	now := time.Now().UTC()
+	urls := s.endpoints(connection.GetID())
+	settings := connection.GetSettings()
+
+	id := newMessageID()
+	location, err := redirectURL(settings.IdPSSOURL, "SAMLRequest", authnRequest(id, urls.EntityID, urls.ACSURL, settings.IdPSSOURL, now), relayState)
+	if err != nil {
+		slog.Error("failed to encode saml request", "error", err)
+		return "", httperr.NewInternalServerError("failed to start saml login")
+	}
+
+	if err := s.repository.CreateRequest(ctx, connection.GetID(), id, now.Add(s.requestTTL())); err != nil {
+		slog.Error("failed to store saml request", "error", err)
+		return "", httperr.NewInternalServerError("failed to start saml login")
+	}
+
+	return location, nil
+}
+
+func (s *service) ConsumeResponse(ctx context.Context, connectionID string, req ACSRequest) (*ACSResponse, *httperr.HttpError) {
+	connection, restErr := s.findConnection(ctx, connectionID)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if !connection.GetSettings().Enabled {
+		return nil, httperr.NewForbiddenError("saml connection is disabled")
+	}
+	ctx = database.WithTenant(ctx, connection.GetOrgID())
+
+	v, err := s.validator(connection)
+	if err != nil {
+		slog.Error("failed to load saml connection keys", "error", err, "connection_id", connection.GetID())
+		return nil, httperr.NewInternalServerError("failed to process saml response")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(req.SAMLResponse), ""))
+	if err != nil {
+		s.recordFailure(ctx, connection, "response is not base64")
+		return nil, httperr.NewUnauthorizedRequestError(invalidResponseMessage)
+	}
+
+	asserted, err := v.Validate(data)
+	if err != nil {
+		slog.Info("saml response refused", "error", err, "connection_id", connection.GetID())
+		s.recordFailure(ctx, connection, err.Error())
+		return nil, httperr.NewUnauthorizedRequestError(invalidResponseMessage)
+	}
+
+	var userID uuid.UUID
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteExpired(ctx, v.Now); err != nil {
+			slog.Warn("failed to clean up saml state", "error", err)
+		}
+
+		if asserted.InResponseTo != "" {
+			if err := s.repository.ConsumeRequest(ctx, connection.GetID(), asserted.InResponseTo, v.Now); err != nil {
+				if errors.Is(err, ErrRequestNotFound) {
+					return rejection("response answers no pending request")
+				}
+				return err
+			}
+		} else if !connection.GetSettings().AllowIdPInitiated {
+			return rejection("unsolicited responses are not allowed")
+		}
+
+		if err := s.repository.RecordAssertion(ctx, connection.GetID(), asserted.ID, asserted.ExpiresAt); err != nil {
+			if errors.Is(err, ErrAssertionReplayed) {
+				return rejection("assertion was already used")
+			}
+			return err
+		}
+
+		found, err := s.resolveUser(ctx, connection, asserted)
+		if err != nil {
+			return err
+		}
+		userID = found
+		return nil
+	})
+	if err != nil {
+		var refused *refusal
+		if errors.As(err, &refused) {
+			s.recordFailure(ctx, connection, refused.reason)
+			return nil, refused.restErr
+		}
+		var restErr *httperr.HttpError
+		if errors.As(err, &restErr) {
+			return nil, restErr
+		}
+		slog.Error("failed to sign in over saml", "error", err)
+		return nil, httperr.NewInternalServerError("failed to process saml response")
+	}
+
+	// The identity provider's own second factor is not known here, so a
+	// user enrolled in one still has to pass it.
+	orgID := connection.GetOrgID()
+	res, restErr := s.sessions.CompleteLogin(ctx, userID, &orgID, loginMethodSAML)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	return &ACSResponse{LoginResponse: res, RelayState: req.RelayState}, nil
+}
+
+// resolveUser returns the account the subject signs in to. A subject seen
+// before keeps its account. Otherwise an account with the asserted email is
+// linked only if it already belongs to the organization, so an identity
+// provider cannot take over accounts it does not manage; failing that, one
+// is provisioned when the connection allows it.
+func (s *service) resolveUser(ctx context.Context, connection domain.SAMLConnectionInterface, asserted *assertion) (uuid.UUID, error) {
+	settings := connection.GetSettings()
+	profile := mapProfile(settings.AttributeMapping, asserted)
+
+	var found domain.UserInterface
+	userID, err := s.repository.FindIdentity(ctx, connection.GetID(), asserted.NameID)
+	switch {
+	case err == nil:
+		if err := s.repository.TouchIdentity(ctx, connection.GetID(), asserted.NameID); err != nil {
+			return uuid.Nil, err
+		}
+		if found, err = s.users.FindByID(ctx, userID); err != nil {
+			return uuid.Nil, err
+		}
+	case errors.Is(err, ErrIdentityNotFound):
+		if found, err = s.link(ctx, connection, asserted, profile); err != nil {
+			return uuid.Nil, err
+		}
+	default:
+		return uuid.Nil, err
+	}
+
+	_, err = s.orgs.FindMembership(ctx, found.GetID())
+	if errors.Is(err, org.ErrMembershipNotFound) {
+		if !settings.JITProvisioning {
+			return uuid.Nil, rejection("account is not a member of the organization")
+		}
+		err = s.orgs.CreateMembership(ctx, domain.NewMembership(connection.GetOrgID(), found.GetID()))
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if profile.changes(found) {
+		found.UpdateProfile(found.GetEmail(), profile.phoneOr(found), profile.firstNameOr(found), profile.lastNameOr(found))
+		if err := s.users.UpdateProfile(ctx, found); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return found.GetID(), nil
+}
+
+// link finds or provisions the account of a subject seen for the first
+// time and links the subject to it.
+func (s *service) link(
+	ctx context.Context,
+	connection domain.SAMLConnectionInterface,
+	asserted *assertion,
+	profile profile,
+) (domain.UserInterface, error) {
+	if profile.Email == "" {
+		return nil, rejection("assertion carries no email")
+	}
+
+	found, err := s.users.FindByEmail(ctx, profile.Email)
+	switch {
+	case err == nil:
+		if _, err := s.orgs.FindMembership(ctx, found.GetID()); err != nil {
+			if errors.Is(err, org.ErrMembershipNotFound) {
+				return nil, rejection("an account outside the organization has this email")
+			}
+			return nil, err
+		}
+	case errors.Is(err, user.ErrUserNotFound):
+		if !connection.GetSettings().JITProvisioning {
+			return nil, rejection("no account matches the assertion")
+		}
+		provisioned, restErr := s.accounts.Provision(ctx, user.ProvisionRequest{
+			Email:     profile.Email,
+			Phone:     profile.Phone,
+			FirstName: profile.FirstName,
+			LastName:  profile.LastName,
+			Method:    loginMethodSAML,
+		})
+		if restErr != nil {
+			return nil, restErr
+		}
+		found = provisioned
+	default:
+		return nil, err
+	}
+
+	if err := s.repository.CreateIdentity(ctx, connection.GetID(), asserted.NameID, found.GetID()); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{"connection_id": connection.GetID().String(), "name_id": asserted.NameID}
+	if err := s.record(ctx, audit.ActionSAMLIdentityLink, audit.TargetUser, found.GetID(), metadata); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (s *service) findConnection(ctx context.Context, connectionID string) (domain.SAMLConnectionInterface, *httperr.HttpError) {
+	id, err := uuid.Parse(connectionID)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("saml connection not found")
+	}
+
+	connection, err := s.repository.FindConnection(ctx, id)
+	if err != nil {
+		return nil, connectionError("find", err)
+	}
+
+	return connection, nil
+}
+
+func (s *service) validator(connection domain.SAMLConnectionInterface) (validator, error) {
+	settings := connection.GetSettings()
+	urls := s.endpoints(connection.GetID())
+
+	certs := make([]*x509.Certificate, 0, len(settings.IdPCertificates))
+	for _, encoded := range settings.IdPCertificates {
+		cert, err := xmlsec.ParseCertificate(encoded)
+		if err != nil {
+			return validator{}, err
+		}
+		certs = append(certs, cert)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(connection.GetSPPrivateKey())
+	if err != nil {
+		return validator{}, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return validator{}, errors.New("service provider key is not an RSA key")
+	}
+
+	return validator{
+		EntityID:          urls.EntityID,
+		ACSURL:            urls.ACSURL,
+		IdPEntityID:       settings.IdPEntityID,
+		Certificates:      certs,
+		DecryptionKey:     rsaKey,
+		RequireEncryption: settings.RequireEncryptedAssertions,
+		ClockSkew:         time.Duration(s.config.ClockSkew) * time.Second,
+		Now:               time.Now().UTC(),
+	}, nil
+}
+
+func (s *service) endpoints(id uuid.UUID) endpoints {
+	base := strings.TrimRight(s.config.BaseURL, "/") + "/" + id.String()
+
+	return endpoints{
+		EntityID:    base + "/metadata",
+		MetadataURL: base + "/metadata",
+		LoginURL:    base + "/login",
+		ACSURL:      base + "/acs",
+	}
+}
+
+func (s *service) requestTTL() time.Duration {
+	return time.Duration(s.config.RequestTTL) * time.Second
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+// recordFailure audits a refused login. Like the other logins, a failure
+// to audit it is logged rather than refused.
+func (s *service) recordFailure(ctx context.Context, connection domain.SAMLConnectionInterface, reason string) {
+	err := s.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionLogin,
+		Outcome:    domain.AuditOutcomeFailure,
+		TargetType: audit.TargetSAMLConnection,
+		TargetID:   connection.GetID().String(),
+		Metadata:   map[string]string{"method": loginMethodSAML, "reason": reason},
+	})
+	if err != nil {
+		slog.Error("failed to audit login", "error", err)
+	}
+}
+
+// refusal aborts a login for a reason that is audited but not told to the
+// caller.
+type refusal struct {
+	reason  string
+	restErr *httperr.HttpError
+}
+
+func (r *refusal) Error() string {
+	return r.reason
+}
+
+func rejection(reason string) error {
+	return &refusal{reason: reason, restErr: httperr.NewForbiddenError("saml login refused")}
+}
+
+// profile holds the columns an assertion maps onto; empty ones are left
+// alone.
+type profile struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Phone     string
+}
+
+func mapProfile(mapping domain.SAMLAttributeMapping, asserted *assertion) profile {
+	p := profile{}
+	if mapping.Email != "" {
+		p.Email = asserted.Attribute(mapping.Email)
+	}
+	if p.Email == "" && asserted.NameIDFormat == nameIDFormatEmail {
+		p.Email = asserted.NameID
+	}
+	if address, err := mail.ParseAddress(p.Email); err != nil || address.Address != p.Email || len(p.Email) > 255 {
+		p.Email = ""
+	}
+	p.Email = strings.ToLower(p.Email)
+
+	if mapping.FirstName != "" {
+		p.FirstName = truncate(asserted.Attribute(mapping.FirstName), 255)
+	}
+	if mapping.LastName != "" {
+		p.LastName = truncate(asserted.Attribute(mapping.LastName), 255)
+	}
+	if mapping.Phone != "" {
+		if phone := asserted.Attribute(mapping.Phone); e164.MatchString(phone) {
+			p.Phone = phone
+		}
+	}
+
+	return p
+}
+
+// changes tells whether the mapped columns differ from the account. The
+// email is not synchronized: it is what other logins find the account by.
+func (p profile) changes(found domain.UserInterface) bool {
+	return p.firstNameOr(found) != found.GetFirstName() ||
+		p.lastNameOr(found) != found.GetLastName() ||
+		p.phoneOr(found) != found.GetPhone()
+}
+
+func (p profile) firstNameOr(found domain.UserInterface) string {
+	if p.FirstName == "" {
+		return found.GetFirstName()
+	}
+	return p.FirstName
+}
+
+func (p profile) lastNameOr(found domain.UserInterface) string {
+	if p.LastName == "" {
+		return found.GetLastName()
+	}
+	return p.LastName
+}
+
+func (p profile) phoneOr(found domain.UserInterface) string {
+	if p.Phone == "" {
+		return found.GetPhone()
+	}
+	return p.Phone
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+func applyMetadata(settings *domain.SAMLConnectionSettings, metadata string) *httperr.HttpError {
+	idp, err := parseIdentityProvider([]byte(metadata))
+	if err != nil {
+		return invalidFieldError("metadata_xml", err.Error())
+	}
+
+	settings.IdPEntityID = idp.EntityID
+	settings.IdPSSOURL = idp.SSOURL
+	settings.IdPCertificates = idp.Certificates
+	return nil
+}
+
+func applyIdentityProvider(settings *domain.SAMLConnectionSettings, entityID, ssoURL string, certificates []string) {
+	if entityID = strings.TrimSpace(entityID); entityID != "" {
+		settings.IdPEntityID = entityID
+	}
+	if ssoURL = strings.TrimSpace(ssoURL); ssoURL != "" {
+		settings.IdPSSOURL = ssoURL
+	}
+	if len(certificates) > 0 {
+		settings.IdPCertificates = certificates
+	}
+}
+
+// validateSettings checks the identity provider is fully described, and
+// normalizes its certificates to plain base64 DER.
+func validateSettings(settings *domain.SAMLConnectionSettings) *httperr.HttpError {
+	if settings.IdPEntityID == "" {
+		return invalidFieldError("idp_entity_id", "is required without metadata_xml")
+	}
+
+	u, err := url.Parse(settings.IdPSSOURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return invalidFieldError("idp_sso_url", "must be an http or https URL")
+	}
+
+	if len(settings.IdPCertificates) == 0 {
+		return invalidFieldError("idp_certificates", "is required without metadata_xml")
+	}
+	normalized := make([]string, 0, len(settings.IdPCertificates))
+	for _, encoded := range settings.IdPCertificates {
+		cert, err := xmlsec.ParseCertificate(stripPEM(encoded))
+		if err != nil {
+			return invalidFieldError("idp_certificates", "must be base64 DER or PEM certificates")
+		}
+		normalized = append(normalized, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	settings.IdPCertificates = normalized
+
+	return nil
+}
+
+// stripPEM drops the armor of a PEM certificate, leaving its base64 body.
+func stripPEM(encoded string) string {
+	var body []string
+	for _, line := range strings.Split(encoded, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "-----") {
+			body = append(body, line)
+		}
+	}
+	return strings.Join(body, "")
+}
+
+func toDomainMapping(mapping AttributeMapping) domain.SAMLAttributeMapping {
+	return domain.SAMLAttributeMapping{
+		Email:     strings.TrimSpace(mapping.Email),
+		FirstName: strings.TrimSpace(mapping.FirstName),
+		LastName:  strings.TrimSpace(mapping.LastName),
+		Phone:     strings.TrimSpace(mapping.Phone),
+	}
+}
+
+// newServiceProviderKey generates the key pair of a connection, returning
+// the PKCS#8 private key and a self-signed certificate, base64 DER. The
+// certificate only carries the key: identity providers do not check it
+// against any authority.
+func newServiceProviderKey() ([]byte, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, spKeyBits)
+	if err != nil {
+		return nil, "", err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "SAML service provider"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(spCertificateValid),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return privateKey, base64.StdEncoding.EncodeToString(der), nil
+}
+
+func describeConnection(settings domain.SAMLConnectionSettings) map[string]string {
+	return map[string]string{
+		"idp_entity_id":                settings.IdPEntityID,
+		"allow_idp_initiated":          strconv.FormatBool(settings.AllowIdPInitiated),
+		"jit_provisioning":             strconv.FormatBool(settings.JITProvisioning),
+		"require_encrypted_assertions": strconv.FormatBool(settings.RequireEncryptedAssertions),
+		"enabled":                      strconv.FormatBool(settings.Enabled),
+	}
+}
+
+func connectionError(op string, err error) *httperr.HttpError {
+	switch {
+	case errors.Is(err, ErrConnectionNotFound):
+		return httperr.NewNotFoundError("saml connection not found")
+	case errors.Is(err, database.ErrTenantRequired):
+		return httperr.NewForbiddenError("an active organization is required")
+	}
+	slog.Error("failed to "+op+" saml connection", "error", err)
+	return httperr.NewInternalServerError("failed to " + op + " saml connection")
+}
+
+func invalidFieldError(field, message string) *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: field, Message: message},
+	})
+}
@@ -0,0 +1,153 @@
+package samlidp
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+// AttributeMapping names the assertion attributes each profile column is
+// given in; an empty name leaves the column out.
+type AttributeMapping struct {
+	Email     string `json:"email" binding:"max=255"`
+	FirstName string `json:"first_name" binding:"max=255"`
+	LastName  string `json:"last_name" binding:"max=255"`
+	Phone     string `json:"phone" binding:"max=255"`
+}
+
+// CreateServiceProviderRequest registers a service provider either from
+// its metadata or from its individual fields; fields given alongside
+// metadata override it. A service provider is enabled unless Enabled says
+// otherwise.
+type CreateServiceProviderRequest struct {
+	Name                  string            `json:"name" binding:"required,max=255"`
+	MetadataXML           string            `json:"metadata_xml" binding:"max=1048576"`
+	EntityID              string            `json:"entity_id" binding:"max=1024"`
+	ACSURL                string            `json:"acs_url" binding:"omitempty,url,max=2048"`
+	SLOURL                string            `json:"slo_url" binding:"omitempty,url,max=2048"`
+	Certificate           string            `json:"certificate" binding:"max=16384"`
+	NameIDFormat          string            `json:"name_id_format" binding:"omitempty,oneof=persistent email"`
+	AttributeMapping      *AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated     bool              `json:"allow_idp_initiated"`
+	RequireSignedRequests bool              `json:"require_signed_requests"`
+	EncryptAssertions     bool              `json:"encrypt_assertions"`
+	Enabled               *bool             `json:"enabled"`
+}
+
+// UpdateServiceProviderRequest changes the fields it carries. Metadata
+// replaces the fields it describes; an empty slo_url or certificate
+// removes it.
+type UpdateServiceProviderRequest struct {
+	Name                  *string           `json:"name" binding:"omitempty,min=1,max=255"`
+	MetadataXML           *string           `json:"metadata_xml" binding:"omitempty,max=1048576"`
+	EntityID              *string           `json:"entity_id" binding:"omitempty,min=1,max=1024"`
+	ACSURL                *string           `json:"acs_url" binding:"omitempty,url,max=2048"`
+	SLOURL                *string           `json:"slo_url" binding:"omitempty,max=2048"`
+	Certificate           *string           `json:"certificate" binding:"omitempty,max=16384"`
+	NameIDFormat          *string           `json:"name_id_format" binding:"omitempty,oneof=persistent email"`
+	AttributeMapping      *AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated     *bool             `json:"allow_idp_initiated"`
+	RequireSignedRequests *bool             `json:"require_signed_requests"`
+	EncryptAssertions     *bool             `json:"encrypt_assertions"`
+	Enabled               *bool             `json:"enabled"`
+}
+
+// ServiceProviderResponse carries what the service provider must be told
+// about the identity provider along with the registration.
+type ServiceProviderResponse struct {
+	ID                    string           `json:"id"`
+	Name                  string           `json:"name"`
+	EntityID              string           `json:"entity_id"`
+	ACSURL                string           `json:"acs_url"`
+	SLOURL                string           `json:"slo_url,omitempty"`
+	Certificate           string           `json:"certificate,omitempty"`
+	NameIDFormat          string           `json:"name_id_format"`
+	AttributeMapping      AttributeMapping `json:"attribute_mapping"`
+	AllowIdPInitiated     bool             `json:"allow_idp_initiated"`
+	RequireSignedRequests bool             `json:"require_signed_requests"`
+	EncryptAssertions     bool             `json:"encrypt_assertions"`
+	Enabled               bool             `json:"enabled"`
+	IdPEntityID           string           `json:"idp_entity_id"`
+	IdPMetadataURL        string           `json:"idp_metadata_url"`
+	IdPSSOURL             string           `json:"idp_sso_url"`
+	IdPInitiatedURL       string           `json:"idp_initiated_url,omitempty"`
+	CreatedAt             time.Time        `json:"created_at"`
+	UpdatedAt             time.Time        `json:"updated_at"`
+}
+
+// Message is a protocol message as either binding carries it. For the
+// HTTP-Redirect binding, RawQuery holds the query it came in, which the
+// signature covers as sent.
+type Message struct {
+	SAMLRequest  string `form:"SAMLRequest"`
+	SAMLResponse string `form:"SAMLResponse"`
+	RelayState   string `form:"RelayState" binding:"max=1024"`
+	SigAlg       string `form:"SigAlg"`
+	Signature    string `form:"Signature"`
+	RawQuery     string `form:"-"`
+}
+
+// LoginForm is posted by the sign-in page, first with the credentials and
+// then, for users with a second factor, with its code.
+type LoginForm struct {
+	Request      string `form:"request" binding:"required,max=64"`
+	Email        string `form:"email"`
+	Password     string `form:"password"`
+	MFAToken     string `form:"mfa_token"`
+	OTP          string `form:"otp"`
+	RecoveryCode string `form:"recovery_code"`
+	// PasskeySession and Passkey carry a WebAuthn assertion posted by the
+	// passkey script of the authorization endpoint.
+	PasskeySession string `form:"passkey_session"`
+	Passkey        string `form:"passkey"`
+}
+
+// Prompt is a sign-in waiting for the credentials of the user.
+type Prompt struct {
+	Request         string
+	ServiceProvider string
+}
+
+// PostForm is a message for the browser to post to a service provider, as
+// the HTTP-POST binding does.
+type PostForm struct {
+	URL        string
+	Parameter  string
+	Message    string
+	RelayState string
+}
+
+func NewServiceProviderResponse(provider domain.SAMLServiceProviderInterface, urls endpoints) ServiceProviderResponse {
+	settings := provider.GetSettings()
+	mapping := settings.AttributeMapping
+
+	res := ServiceProviderResponse{
+		ID:           provider.GetID().String(),
+		Name:         settings.Name,
+		EntityID:     settings.EntityID,
+		ACSURL:       settings.ACSURL,
+		SLOURL:       settings.SLOURL,
+		Certificate:  settings.Certificate,
+		NameIDFormat: settings.NameIDFormat,
+		AttributeMapping: AttributeMapping{
+			Email:     mapping.Email,
+			FirstName: mapping.FirstName,
+			LastName:  mapping.LastName,
+			Phone:     mapping.Phone,
+		},
+		AllowIdPInitiated:     settings.AllowIdPInitiated,
+		RequireSignedRequests: settings.RequireSignedRequests,
+		EncryptAssertions:     settings.EncryptAssertions,
+		Enabled:               settings.Enabled,
+		IdPEntityID:           urls.EntityID,
+		IdPMetadataURL:        urls.MetadataURL,
+		IdPSSOURL:             urls.SSOURL,
+		CreatedAt:             provider.GetCreatedAt(),
+		UpdatedAt:             provider.GetUpdatedAt(),
+	}
+	if settings.AllowIdPInitiated {
+		res.IdPInitiatedURL = urls.initiatedURL(provider.GetID())
+	}
+
+	return res
+}
@@ -0,0 +1,332 @@
+package samlidp
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the service provider API.
+const (
+	ResourceSAMLServiceProviders = "saml_service_providers"
+	ActionManage                 = "manage"
+)
+
+// maxBodySize bounds the metadata and messages posted; both are small
+// unless a service provider stuffs them with certificates.
+const maxBodySize = 2 << 20
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+//go:embed static/autosubmit.js
+var autosubmitScript []byte
+
+type loginPage struct {
+	ServiceProvider string
+	Request         string
+	Email           string
+	Error           string
+	// MFAToken switches the page to the second factor step.
+	MFAToken string
+}
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+	checker     middleware.PermissionCheckerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateServiceProvider(ctx *gin.Context)
+	GetServiceProvider(ctx *gin.Context)
+	ListServiceProviders(ctx *gin.Context)
+	UpdateServiceProvider(ctx *gin.Context)
+	DeleteServiceProvider(ctx *gin.Context)
+	Metadata(ctx *gin.Context)
+	SSO(ctx *gin.Context)
+	InitiateSSO(ctx *gin.Context)
+	Login(ctx *gin.Context)
+	SLO(ctx *gin.Context)
+	AutosubmitScript(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+		checker:     checker,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	manage := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.checker, ResourceSAMLServiceProviders, ActionManage)
+
+	providers := router.Group("/api/v1/saml/idp/service-providers", manage, limitBody)
+	{
+		providers.POST("", h.CreateServiceProvider)
+		providers.GET("", h.ListServiceProviders)
+		providers.GET("/:id", h.GetServiceProvider)
+		providers.PATCH("/:id", h.UpdateServiceProvider)
+		providers.DELETE("/:id", h.DeleteServiceProvider)
+	}
+
+	// The browser carries the messages of service providers here, so these
+	// are open; the messages tell the service provider.
+	idp := router.Group("/api/v1/saml/idp", limitBody)
+	{
+		idp.GET("/metadata", h.Metadata)
+		idp.GET("/sso", h.SSO)
+		idp.POST("/sso", h.SSO)
+		idp.GET("/sso/:service_provider_id", h.InitiateSSO)
+		idp.POST("/login", h.Login)
+		idp.GET("/slo", h.SLO)
+		idp.POST("/slo", h.SLO)
+		idp.GET("/autosubmit.js", h.AutosubmitScript)
+	}
+}
+
+func limitBody(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodySize)
+	ctx.Next()
+}
+
+func (h *handler) CreateServiceProvider(ctx *gin.Context) {
+	var req CreateServiceProviderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.CreateServiceProvider(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) GetServiceProvider(ctx *gin.Context) {
+	res, restErr := h.service.GetServiceProvider(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListServiceProviders(ctx *gin.Context) {
+	res, restErr := h.service.ListServiceProviders(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) UpdateServiceProvider(ctx *gin.Context) {
+	var req UpdateServiceProviderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.UpdateServiceProvider(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) DeleteServiceProvider(ctx *gin.Context) {
+	if restErr := h.service.DeleteServiceProvider(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) Metadata(ctx *gin.Context) {
+	res, restErr := h.service.Metadata(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.Data(http.StatusOK, "application/samlmetadata+xml", res)
+}
+
+func (h *handler) SSO(ctx *gin.Context) {
+	msg, ok := h.bindMessage(ctx)
+	if !ok {
+		return
+	}
+
+	prompt, post, restErr := h.service.BeginSSO(ctx.Request.Context(), msg)
+	switch {
+	case restErr != nil:
+		h.renderError(ctx, restErr)
+	case post != nil:
+		h.renderPost(ctx, post)
+	default:
+		h.render(ctx, http.StatusOK, "login.html", loginPage{ServiceProvider: prompt.ServiceProvider, Request: prompt.Request})
+	}
+}
+
+func (h *handler) InitiateSSO(ctx *gin.Context) {
+	relayState := ctx.Query("RelayState")
+	if len(relayState) > 1024 {
+		h.renderError(ctx, httperr.NewBadRequestError("the relay state is too long"))
+		return
+	}
+
+	prompt, restErr := h.service.InitiateSSO(ctx.Request.Context(), ctx.Param("service_provider_id"), relayState)
+	if restErr != nil {
+		h.renderError(ctx, restErr)
+		return
+	}
+
+	h.render(ctx, http.StatusOK, "login.html", loginPage{ServiceProvider: prompt.ServiceProvider, Request: prompt.Request})
+}
+
+func (h *handler) Login(ctx *gin.Context) {
+	var form LoginForm
+	if err := ctx.ShouldBind(&form); err != nil {
+		h.renderError(ctx, httperr.NewBadRequestError("malformed sign-in"))
+		return
+	}
+
+	prompt, restErr := h.service.FindPrompt(ctx.Request.Context(), form.Request)
+	if restErr != nil {
+		h.renderError(ctx, restErr)
+		return
+	}
+
+	post, challenge, restErr := h.service.SignIn(ctx.Request.Context(), form)
+	if restErr != nil {
+		restErr.WriteHeaders(ctx.Writer.Header())
+		message := restErr.Message
+		if restErr.Code >= http.StatusInternalServerError {
+			message = "something went wrong, please try again"
+		}
+		h.render(ctx, restErr.Code, "login.html", loginPage{
+			ServiceProvider: prompt.ServiceProvider,
+			Request:         prompt.Request,
+			Email:           form.Email,
+			Error:           message,
+			MFAToken:        form.MFAToken,
+		})
+		return
+	}
+
+	if challenge != "" {
+		h.render(ctx, http.StatusOK, "login.html", loginPage{
+			ServiceProvider: prompt.ServiceProvider,
+			Request:         prompt.Request,
+			MFAToken:        challenge,
+		})
+		return
+	}
+
+	h.renderPost(ctx, post)
+}
+
+func (h *handler) SLO(ctx *gin.Context) {
+	msg, ok := h.bindMessage(ctx)
+	if !ok {
+		return
+	}
+
+	post, restErr := h.service.Logout(ctx.Request.Context(), msg)
+	if restErr != nil {
+		h.renderError(ctx, restErr)
+		return
+	}
+
+	h.renderPost(ctx, post)
+}
+
+func (h *handler) AutosubmitScript(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=3600")
+	ctx.Data(http.StatusOK, "text/javascript; charset=utf-8", autosubmitScript)
+}
+
+// bindMessage reads a message from the query for the HTTP-Redirect binding
+// or from the form for HTTP-POST.
+func (h *handler) bindMessage(ctx *gin.Context) (Message, bool) {
+	var msg Message
+	if err := ctx.ShouldBind(&msg); err != nil {
+		h.renderError(ctx, httperr.NewBadRequestError("malformed saml message"))
+		return Message{}, false
+	}
+	if ctx.Request.Method == http.MethodGet {
+		msg.RawQuery = ctx.Request.URL.RawQuery
+	}
+
+	return msg, true
+}
+
+func (h *handler) renderError(ctx *gin.Context, restErr *httperr.HttpError) {
+	restErr.WriteHeaders(ctx.Writer.Header())
+	h.render(ctx, restErr.Code, "error.html", restErr)
+}
+
+// renderPost sends the browser on to a service provider with a form it
+// submits by itself, which the policy lets post to that service provider
+// only.
+func (h *handler) renderPost(ctx *gin.Context, post *PostForm) {
+	target, err := url.Parse(post.URL)
+	if err != nil {
+		h.renderError(ctx, httperr.NewInternalServerError("failed to reach the service provider"))
+		return
+	}
+
+	h.renderWithPolicy(ctx, http.StatusOK, "post.html", post, target.Scheme+"://"+target.Host)
+}
+
+func (h *handler) render(ctx *gin.Context, status int, name string, data any) {
+	h.renderWithPolicy(ctx, status, name, data, "'self'")
+}
+
+func (h *handler) renderWithPolicy(ctx *gin.Context, status int, name string, data any, formAction string) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.Error("failed to render template", "template", name, "error", err)
+		ctx.Status(http.StatusInternalServerError)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; script-src 'self'; connect-src 'self'; form-action "+formAction+"; frame-ancestors 'none'")
+	ctx.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,268 @@
+package samlidp
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+)
+
+const (
+	statusSuccess            = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	statusRequester          = "urn:oasis:names:tc:SAML:2.0:status:Requester"
+	statusNoPassive          = "urn:oasis:names:tc:SAML:2.0:status:NoPassive"
+	statusInvalidNameID      = "urn:oasis:names:tc:SAML:2.0:status:InvalidNameIDPolicy"
+	statusPartialLogout      = "urn:oasis:names:tc:SAML:2.0:status:PartialLogout"
+	confirmationBearer       = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	authnContextPassword     = "urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport"
+	attributeNameFormatBasic = "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"
+
+	parameterRequest  = "SAMLRequest"
+	parameterResponse = "SAMLResponse"
+
+	// maxMessageSize bounds what an HTTP-Redirect message may inflate to.
+	maxMessageSize = 1 << 20
+	// logoutRequestLifetime bounds how long a service provider may take to
+	// act on a logout request.
+	logoutRequestLifetime = 5 * time.Minute
+)
+
+// ErrInvalidMessage is wrapped by every reason a message is refused.
+var ErrInvalidMessage = errors.New("invalid saml message")
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMessage, fmt.Sprintf(format, args...))
+}
+
+// newMessageID returns an ID for a message or assertion. IDs must not
+// start with a digit, hence the underscore.
+func newMessageID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "_" + hex.EncodeToString(b)
+}
+
+func formatInstant(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+func parseInstant(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+}
+
+// decode reads the message carried in parameter, deflated for the
+// HTTP-Redirect binding and plain for HTTP-POST.
+func (m Message) decode(parameter string) (*etree.Element, error) {
+	value := m.SAMLRequest
+	if parameter == parameterResponse {
+		value = m.SAMLResponse
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, invalid("message is not base64")
+	}
+
+	if m.RawQuery != "" {
+		inflated, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(data)), maxMessageSize+1))
+		if err != nil {
+			return nil, invalid("message is not deflated")
+		}
+		if len(inflated) > maxMessageSize {
+			return nil, invalid("message is too large")
+		}
+		data = inflated
+	}
+
+	root, err := xmlsec.Parse(data)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	if version := xmlsec.Attr(root, "Version"); version != "2.0" {
+		return nil, invalid("unsupported version %q", version)
+	}
+
+	return root, nil
+}
+
+// verify checks the signature of a message with the certificate of the
+// service provider that sent it: over the query for the HTTP-Redirect
+// binding, enveloped in the message for HTTP-POST. It returns the message
+// as it was signed, which for HTTP-POST is what goxmldsig hands back, or
+// xmlsec.ErrNoSignature when the message is not signed.
+func (m Message) verify(parameter string, root *etree.Element, cert *x509.Certificate, now time.Time) (*etree.Element, error) {
+	if m.RawQuery == "" {
+		return xmlsec.Verify(root, []*x509.Certificate{cert}, now)
+	}
+
+	if m.Signature == "" {
+		return nil, xmlsec.ErrNoSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(m.Signature), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64", xmlsec.ErrInvalidSignature)
+	}
+
+	// The signature covers the parameters as they were encoded by the
+	// sender, which decoding and encoding again need not reproduce.
+	raw := map[string]string{}
+	for _, pair := range strings.Split(m.RawQuery, "&") {
+		name, value, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			if _, seen := raw[unescaped]; !seen {
+				raw[unescaped] = value
+			}
+		}
+	}
+	signed := []string{parameter + "=" + raw[parameter]}
+	if value, ok := raw["RelayState"]; ok {
+		signed = append(signed, "RelayState="+value)
+	}
+	signed = append(signed, "SigAlg="+raw["SigAlg"])
+
+	if err := xmlsec.VerifyDetached(m.SigAlg, cert, []byte(strings.Join(signed, "&")), signature); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// issuerOf returns the issuer of a message.
+func issuerOf(root *etree.Element) string {
+	return strings.TrimSpace(xmlsec.Text(xmlsec.Child(root, namespaceAssertion, "Issuer")))
+}
+
+// newProtocolMessage starts a message of the protocol namespace, issued by
+// the identity provider.
+func newProtocolMessage(name, issuer, destination string, now time.Time) *etree.Element {
+	message := xmlsec.NewElement(namespaceProtocol, "samlp", name)
+	message.CreateAttr("xmlns:saml", namespaceAssertion)
+	message.CreateAttr("ID", newMessageID())
+	message.CreateAttr("Version", "2.0")
+	message.CreateAttr("IssueInstant", formatInstant(now))
+	message.CreateAttr("Destination", destination)
+	assertionChild(message, "Issuer").SetText(issuer)
+	return message
+}
+
+// newStatusResponse starts a response of the given name carrying a
+// status, with a second-level code when sub is set.
+func newStatusResponse(name, issuer, destination, inResponseTo, code, sub string, now time.Time) *etree.Element {
+	response := newProtocolMessage(name, issuer, destination, now)
+	if inResponseTo != "" {
+		response.CreateAttr("InResponseTo", inResponseTo)
+	}
+
+	status := xmlsec.NewChild(xmlsec.NewChild(response, "Status"), "StatusCode")
+	status.CreateAttr("Value", code)
+	if sub != "" {
+		xmlsec.NewChild(status, "StatusCode").CreateAttr("Value", sub)
+	}
+
+	return response
+}
+
+// logoutRequest asks a service provider to end the session it was given
+// for a subject.
+func logoutRequest(issuer, destination string, session Session, now time.Time) *etree.Element {
+	request := newProtocolMessage("LogoutRequest", issuer, destination, now)
+	request.CreateAttr("NotOnOrAfter", formatInstant(now.Add(logoutRequestLifetime)))
+
+	nameID := assertionChild(request, "NameID")
+	nameID.CreateAttr("Format", session.NameIDFormat)
+	nameID.SetText(session.NameID)
+	xmlsec.NewTextChild(request, "SessionIndex", session.SessionIndex)
+
+	return request
+}
+
+// attribute is a value the assertion tells about its subject.
+type attribute struct {
+	Name  string
+	Value string
+}
+
+// assertionParams is what an assertion is built from.
+type assertionParams struct {
+	Issuer              string
+	Audience            string
+	Recipient           string
+	InResponseTo        string
+	NameID              string
+	NameIDFormat        string
+	SessionIndex        string
+	Attributes          []attribute
+	Now                 time.Time
+	NotOnOrAfter        time.Time
+	SessionNotOnOrAfter time.Time
+}
+
+// newAssertion builds an unsigned bearer assertion for a service
+// provider. It declares its own namespace, so it can be encrypted as is.
+func newAssertion(p assertionParams) *etree.Element {
+	a := xmlsec.NewElement(namespaceAssertion, "saml", "Assertion")
+	a.CreateAttr("ID", newMessageID())
+	a.CreateAttr("Version", "2.0")
+	a.CreateAttr("IssueInstant", formatInstant(p.Now))
+	xmlsec.NewTextChild(a, "Issuer", p.Issuer)
+
+	subject := xmlsec.NewChild(a, "Subject")
+	nameID := xmlsec.NewTextChild(subject, "NameID", p.NameID)
+	nameID.CreateAttr("Format", p.NameIDFormat)
+	if p.NameIDFormat == nameIDFormatPersistent {
+		nameID.CreateAttr("NameQualifier", p.Issuer)
+		nameID.CreateAttr("SPNameQualifier", p.Audience)
+	}
+	confirmation := xmlsec.NewChild(subject, "SubjectConfirmation")
+	confirmation.CreateAttr("Method", confirmationBearer)
+	data := xmlsec.NewChild(confirmation, "SubjectConfirmationData")
+	if p.InResponseTo != "" {
+		data.CreateAttr("InResponseTo", p.InResponseTo)
+	}
+	data.CreateAttr("NotOnOrAfter", formatInstant(p.NotOnOrAfter))
+	data.CreateAttr("Recipient", p.Recipient)
+
+	conditions := xmlsec.NewChild(a, "Conditions")
+	conditions.CreateAttr("NotBefore", formatInstant(p.Now))
+	conditions.CreateAttr("NotOnOrAfter", formatInstant(p.NotOnOrAfter))
+	xmlsec.NewTextChild(xmlsec.NewChild(conditions, "AudienceRestriction"), "Audience", p.Audience)
+
+	statement := xmlsec.NewChild(a, "AuthnStatement")
+	statement.CreateAttr("AuthnInstant", formatInstant(p.Now))
+	statement.CreateAttr("SessionIndex", p.SessionIndex)
+	statement.CreateAttr("SessionNotOnOrAfter", formatInstant(p.SessionNotOnOrAfter))
+	xmlsec.NewTextChild(xmlsec.NewChild(statement, "AuthnContext"), "AuthnContextClassRef", authnContextPassword)
+
+	if len(p.Attributes) > 0 {
+		attributes := xmlsec.NewChild(a, "AttributeStatement")
+		for _, attr := range p.Attributes {
+			el := xmlsec.NewChild(attributes, "Attribute")
+			el.CreateAttr("Name", attr.Name)
+			el.CreateAttr("NameFormat", attributeNameFormatBasic)
+			xmlsec.NewTextChild(el, "AttributeValue", attr.Value)
+		}
+	}
+
+	return a
+}
+
+// assertionChild appends an element of the assertion namespace to a
+// protocol message, which declares the saml prefix.
+func assertionChild(parent *etree.Element, name string) *etree.Element {
+	return parent.CreateElement("saml:" + name)
+}
+
+// encode base64 encodes a message for the HTTP-POST binding.
+func encode(message *etree.Element) string {
+	return base64.StdEncoding.EncodeToString(xmlsec.Bytes(message))
+}
@@ -0,0 +1,140 @@
+package samlidp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+)
+
+const (
+	namespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	namespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	namespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	nameIDFormatEmail       = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	nameIDFormatPersistent  = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	nameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// serviceProvider is what the metadata of a service provider tells.
+type serviceProvider struct {
+	EntityID    string
+	ACSURL      string
+	SLOURL      string
+	Certificate string
+}
+
+// parseServiceProvider reads the entity ID, the HTTP-POST assertion
+// consumer and single logout services and the certificate out of service
+// provider metadata. The signing certificate is preferred, as it is the
+// one requests are checked with; an EntitiesDescriptor is accepted when it
+// holds a single service provider.
+func parseServiceProvider(data []byte) (serviceProvider, error) {
+	root, err := xmlsec.Parse(data)
+	if err != nil {
+		return serviceProvider{}, err
+	}
+
+	var descriptors []*etree.Element
+	xmlsec.Walk(root, func(el *etree.Element) {
+		if xmlsec.Is(el, namespaceMetadata, "EntityDescriptor") && xmlsec.Child(el, namespaceMetadata, "SPSSODescriptor") != nil {
+			descriptors = append(descriptors, el)
+		}
+	})
+	if len(descriptors) != 1 {
+		return serviceProvider{}, errors.New("metadata must describe exactly one service provider")
+	}
+	entity := descriptors[0]
+	descriptor := xmlsec.Child(entity, namespaceMetadata, "SPSSODescriptor")
+
+	sp := serviceProvider{EntityID: xmlsec.Attr(entity, "entityID")}
+	if sp.EntityID == "" {
+		return serviceProvider{}, errors.New("metadata has no entityID")
+	}
+
+	for _, service := range xmlsec.Children(descriptor, namespaceMetadata, "AssertionConsumerService") {
+		if xmlsec.Attr(service, "Binding") != bindingHTTPPost {
+			continue
+		}
+		if sp.ACSURL == "" || xmlsec.Attr(service, "isDefault") == "true" {
+			sp.ACSURL = xmlsec.Attr(service, "Location")
+		}
+	}
+	if sp.ACSURL == "" {
+		return serviceProvider{}, errors.New("metadata has no HTTP-POST assertion consumer service")
+	}
+
+	for _, service := range xmlsec.Children(descriptor, namespaceMetadata, "SingleLogoutService") {
+		if xmlsec.Attr(service, "Binding") == bindingHTTPPost {
+			sp.SLOURL = xmlsec.Attr(service, "Location")
+			break
+		}
+	}
+
+	var fallback string
+	for _, key := range xmlsec.Children(descriptor, namespaceMetadata, "KeyDescriptor") {
+		keyInfo := xmlsec.Child(key, xmlsec.NamespaceDSig, "KeyInfo")
+		cert := xmlsec.Child(xmlsec.Child(keyInfo, xmlsec.NamespaceDSig, "X509Data"), xmlsec.NamespaceDSig, "X509Certificate")
+		if cert == nil {
+			continue
+		}
+		parsed, err := xmlsec.ParseCertificate(xmlsec.Text(cert))
+		if err != nil {
+			return serviceProvider{}, fmt.Errorf("metadata has an invalid certificate: %w", err)
+		}
+		encoded := base64.StdEncoding.EncodeToString(parsed.Raw)
+		if use := xmlsec.Attr(key, "use"); use == "" || use == "signing" {
+			sp.Certificate = encoded
+			break
+		}
+		if fallback == "" {
+			fallback = encoded
+		}
+	}
+	if sp.Certificate == "" {
+		sp.Certificate = fallback
+	}
+
+	return sp, nil
+}
+
+// identityProviderMetadata describes the identity provider, with every
+// published signing key, so service providers that refresh the metadata
+// trust the next key before it signs anything.
+func identityProviderMetadata(entityID, ssoURL, sloURL string, certificates [][]byte) []byte {
+	entity := xmlsec.NewElement(namespaceMetadata, "md", "EntityDescriptor")
+	entity.CreateAttr("entityID", entityID)
+
+	descriptor := xmlsec.NewChild(entity, "IDPSSODescriptor")
+	descriptor.CreateAttr("WantAuthnRequestsSigned", "false")
+	descriptor.CreateAttr("protocolSupportEnumeration", namespaceProtocol)
+
+	for _, certificate := range certificates {
+		key := xmlsec.NewChild(descriptor, "KeyDescriptor")
+		key.CreateAttr("use", "signing")
+		keyInfo := xmlsec.NewElement(xmlsec.NamespaceDSig, "ds", "KeyInfo")
+		key.AddChild(keyInfo)
+		xmlsec.NewTextChild(xmlsec.NewChild(keyInfo, "X509Data"), "X509Certificate", base64.StdEncoding.EncodeToString(certificate))
+	}
+
+	for _, binding := range []string{bindingHTTPRedirect, bindingHTTPPost} {
+		service := xmlsec.NewChild(descriptor, "SingleLogoutService")
+		service.CreateAttr("Binding", binding)
+		service.CreateAttr("Location", sloURL)
+	}
+	xmlsec.NewTextChild(descriptor, "NameIDFormat", nameIDFormatPersistent)
+	xmlsec.NewTextChild(descriptor, "NameIDFormat", nameIDFormatEmail)
+	for _, binding := range []string{bindingHTTPRedirect, bindingHTTPPost} {
+		service := xmlsec.NewChild(descriptor, "SingleSignOnService")
+		service.CreateAttr("Binding", binding)
+		service.CreateAttr("Location", ssoURL)
+	}
+
+	return append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"), xmlsec.Bytes(entity)...)
+}
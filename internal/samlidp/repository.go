@@ -0,0 +1,565 @@
+package samlidp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrServiceProviderNotFound = errors.New("saml service provider not found")
+	ErrServiceProviderExists   = errors.New("a saml service provider with this entity id already exists")
+	ErrCertificateNotFound     = errors.New("saml certificate not found")
+	ErrRequestNotFound         = errors.New("saml sign-in request not found")
+	ErrLogoutNotFound          = errors.New("saml logout not found")
+)
+
+const selectServiceProviderColumns = `
+	SELECT id, name, entity_id, acs_url, slo_url, certificate, name_id_format, attribute_mapping,
+		allow_idp_initiated, require_signed_requests, encrypt_assertions, enabled, created_at, updated_at
+	FROM saml_service_providers`
+
+// PendingRequest is a sign-in waiting for the user to authenticate.
+// RequestID is the ID of the AuthnRequest it answers, empty when the
+// sign-in was started at the identity provider.
+type PendingRequest struct {
+	ID                string
+	ServiceProviderID uuid.UUID
+	RequestID         string
+	NameIDFormat      string
+	RelayState        string
+	ExpiresAt         time.Time
+}
+
+// Session is what a service provider was given by an assertion.
+type Session struct {
+	SessionIndex      string
+	ServiceProviderID uuid.UUID
+	UserID            uuid.UUID
+	NameID            string
+	NameIDFormat      string
+	ExpiresAt         time.Time
+}
+
+// Logout is a single logout under way. RequestID is the LogoutRequest of
+// the service provider that asked, answered once every other session is
+// ended; PendingRequestID is the LogoutRequest awaiting an answer from the
+// service provider the browser was last sent to.
+type Logout struct {
+	ID                string
+	ServiceProviderID uuid.UUID
+	UserID            uuid.UUID
+	RequestID         string
+	RelayState        string
+	PendingRequestID  string
+	// Partial is set when a service provider could not be logged out.
+	Partial   bool
+	ExpiresAt time.Time
+}
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+// RepositoryInterface stores the service providers of the identity
+// provider and the state of their sign-ins and logouts. None of it belongs
+// to an organization.
+type RepositoryInterface interface {
+	CreateServiceProvider(ctx context.Context, provider domain.SAMLServiceProviderInterface) error
+	FindServiceProvider(ctx context.Context, id uuid.UUID) (domain.SAMLServiceProviderInterface, error)
+	FindServiceProviderByEntityID(ctx context.Context, entityID string) (domain.SAMLServiceProviderInterface, error)
+	FindServiceProviders(ctx context.Context) ([]domain.SAMLServiceProviderInterface, error)
+	UpdateServiceProvider(ctx context.Context, provider domain.SAMLServiceProviderInterface) error
+	DeleteServiceProvider(ctx context.Context, id uuid.UUID) error
+
+	// FindCertificate returns the DER certificate of a signing key.
+	FindCertificate(ctx context.Context, keyID string) ([]byte, error)
+	// CreateCertificate stores the certificate of a signing key unless one
+	// was stored first, which is then the one to use.
+	CreateCertificate(ctx context.Context, keyID string, certificate []byte) error
+
+	CreateRequest(ctx context.Context, request PendingRequest) error
+	// FindRequest returns a sign-in that has not expired.
+	FindRequest(ctx context.Context, id string, now time.Time) (*PendingRequest, error)
+	// ConsumeRequest deletes the sign-in, so it is answered once, and
+	// returns ErrRequestNotFound when it is unknown or expired.
+	ConsumeRequest(ctx context.Context, id string, now time.Time) (*PendingRequest, error)
+
+	CreateSession(ctx context.Context, session Session) error
+	// FindSessions returns the live sessions of the user, oldest first.
+	FindSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]Session, error)
+	// FindSubjectSessions returns the live sessions the service provider
+	// was given for the subject.
+	FindSubjectSessions(ctx context.Context, serviceProviderID uuid.UUID, nameID string, now time.Time) ([]Session, error)
+	DeleteSession(ctx context.Context, sessionIndex string) error
+
+	CreateLogout(ctx context.Context, logout Logout) error
+	FindLogout(ctx context.Context, id string, now time.Time) (*Logout, error)
+	UpdateLogout(ctx context.Context, logout Logout) error
+	DeleteLogout(ctx context.Context, id string) error
+
+	// DeleteExpired drops the sign-ins, sessions and logouts that expired
+	// before now.
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateServiceProvider(ctx context.Context, provider domain.SAMLServiceProviderInterface) error {
+	settings := provider.GetSettings()
+	mapping, err := json.Marshal(settings.AttributeMapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode attribute mapping: %w", err)
+	}
+
+	query := `
+		INSERT INTO saml_service_providers (
+			id, name, entity_id, acs_url, slo_url, certificate, name_id_format, attribute_mapping,
+			allow_idp_initiated, require_signed_requests, encrypt_assertions, enabled, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		provider.GetID(),
+		settings.Name,
+		settings.EntityID,
+		settings.ACSURL,
+		settings.SLOURL,
+		settings.Certificate,
+		settings.NameIDFormat,
+		mapping,
+		settings.AllowIdPInitiated,
+		settings.RequireSignedRequests,
+		settings.EncryptAssertions,
+		settings.Enabled,
+		provider.GetCreatedAt(),
+		provider.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrServiceProviderExists
+		}
+		return fmt.Errorf("failed to insert saml service provider: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindServiceProvider(ctx context.Context, id uuid.UUID) (domain.SAMLServiceProviderInterface, error) {
+	query := selectServiceProviderColumns + ` WHERE id = $1`
+
+	return scanServiceProvider(r.db.GetQuerier(ctx).QueryRow(ctx, query, id))
+}
+
+func (r *repository) FindServiceProviderByEntityID(ctx context.Context, entityID string) (domain.SAMLServiceProviderInterface, error) {
+	query := selectServiceProviderColumns + ` WHERE entity_id = $1`
+
+	return scanServiceProvider(r.db.GetQuerier(ctx).QueryRow(ctx, query, entityID))
+}
+
+func (r *repository) FindServiceProviders(ctx context.Context) ([]domain.SAMLServiceProviderInterface, error) {
+	query := selectServiceProviderColumns + ` ORDER BY created_at, id`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saml service providers: %w", err)
+	}
+	defer rows.Close()
+
+	providers := make([]domain.SAMLServiceProviderInterface, 0)
+	for rows.Next() {
+		provider, err := scanServiceProvider(rows)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate saml service providers: %w", err)
+	}
+
+	return providers, nil
+}
+
+func (r *repository) UpdateServiceProvider(ctx context.Context, provider domain.SAMLServiceProviderInterface) error {
+	settings := provider.GetSettings()
+	mapping, err := json.Marshal(settings.AttributeMapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode attribute mapping: %w", err)
+	}
+
+	query := `
+		UPDATE saml_service_providers
+		SET name = $2, entity_id = $3, acs_url = $4, slo_url = $5, certificate = $6, name_id_format = $7,
+			attribute_mapping = $8, allow_idp_initiated = $9, require_signed_requests = $10,
+			encrypt_assertions = $11, enabled = $12, updated_at = $13
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		provider.GetID(),
+		settings.Name,
+		settings.EntityID,
+		settings.ACSURL,
+		settings.SLOURL,
+		settings.Certificate,
+		settings.NameIDFormat,
+		mapping,
+		settings.AllowIdPInitiated,
+		settings.RequireSignedRequests,
+		settings.EncryptAssertions,
+		settings.Enabled,
+		provider.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrServiceProviderExists
+		}
+		return fmt.Errorf("failed to update saml service provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceProviderNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteServiceProvider(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM saml_service_providers WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saml service provider: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrServiceProviderNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) FindCertificate(ctx context.Context, keyID string) ([]byte, error) {
+	query := `SELECT certificate FROM saml_idp_certificates WHERE key_id = $1`
+
+	var certificate []byte
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, keyID).Scan(&certificate)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCertificateNotFound
+		}
+		return nil, fmt.Errorf("failed to find saml certificate: %w", err)
+	}
+
+	return certificate, nil
+}
+
+func (r *repository) CreateCertificate(ctx context.Context, keyID string, certificate []byte) error {
+	query := `
+		INSERT INTO saml_idp_certificates (key_id, certificate, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key_id) DO NOTHING`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query, keyID, certificate, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert saml certificate: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) CreateRequest(ctx context.Context, request PendingRequest) error {
+	query := `
+		INSERT INTO saml_idp_requests (
+			id, service_provider_id, request_id, name_id_format, relay_state, expires_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		request.ID,
+		request.ServiceProviderID,
+		request.RequestID,
+		request.NameIDFormat,
+		request.RelayState,
+		request.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert saml sign-in request: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindRequest(ctx context.Context, id string, now time.Time) (*PendingRequest, error) {
+	query := `
+		SELECT id, service_provider_id, request_id, name_id_format, relay_state, expires_at
+		FROM saml_idp_requests
+		WHERE id = $1 AND expires_at > $2`
+
+	return scanRequest(r.db.GetQuerier(ctx).QueryRow(ctx, query, id, now))
+}
+
+func (r *repository) ConsumeRequest(ctx context.Context, id string, now time.Time) (*PendingRequest, error) {
+	query := `
+		DELETE FROM saml_idp_requests
+		WHERE id = $1 AND expires_at > $2
+		RETURNING id, service_provider_id, request_id, name_id_format, relay_state, expires_at`
+
+	return scanRequest(r.db.GetQuerier(ctx).QueryRow(ctx, query, id, now))
+}
+
+func (r *repository) CreateSession(ctx context.Context, session Session) error {
+	query := `
+		INSERT INTO saml_idp_sessions (
+			session_index, service_provider_id, user_id, name_id, name_id_format, expires_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		session.SessionIndex,
+		session.ServiceProviderID,
+		session.UserID,
+		session.NameID,
+		session.NameIDFormat,
+		session.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert saml session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]Session, error) {
+	query := `
+		SELECT session_index, service_provider_id, user_id, name_id, name_id_format, expires_at
+		FROM saml_idp_sessions
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY created_at, session_index`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saml sessions: %w", err)
+	}
+
+	return collectSessions(rows)
+}
+
+func (r *repository) FindSubjectSessions(ctx context.Context, serviceProviderID uuid.UUID, nameID string, now time.Time) ([]Session, error) {
+	query := `
+		SELECT session_index, service_provider_id, user_id, name_id, name_id_format, expires_at
+		FROM saml_idp_sessions
+		WHERE service_provider_id = $1 AND name_id = $2 AND expires_at > $3
+		ORDER BY created_at, session_index`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, serviceProviderID, nameID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saml sessions: %w", err)
+	}
+
+	return collectSessions(rows)
+}
+
+func (r *repository) DeleteSession(ctx context.Context, sessionIndex string) error {
+	query := `DELETE FROM saml_idp_sessions WHERE session_index = $1`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, sessionIndex); err != nil {
+		return fmt.Errorf("failed to delete saml session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) CreateLogout(ctx context.Context, logout Logout) error {
+	query := `
+		INSERT INTO saml_idp_logouts (
+			id, service_provider_id, user_id, request_id, relay_state, pending_request_id, partial,
+			expires_at, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		logout.ID,
+		logout.ServiceProviderID,
+		logout.UserID,
+		logout.RequestID,
+		logout.RelayState,
+		logout.PendingRequestID,
+		logout.Partial,
+		logout.ExpiresAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert saml logout: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindLogout(ctx context.Context, id string, now time.Time) (*Logout, error) {
+	query := `
+		SELECT id, service_provider_id, user_id, request_id, relay_state, pending_request_id, partial, expires_at
+		FROM saml_idp_logouts
+		WHERE id = $1 AND expires_at > $2`
+
+	var logout Logout
+	err := r.db.GetQuerier(ctx).QueryRow(ctx, query, id, now).Scan(
+		&logout.ID,
+		&logout.ServiceProviderID,
+		&logout.UserID,
+		&logout.RequestID,
+		&logout.RelayState,
+		&logout.PendingRequestID,
+		&logout.Partial,
+		&logout.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLogoutNotFound
+		}
+		return nil, fmt.Errorf("failed to find saml logout: %w", err)
+	}
+
+	return &logout, nil
+}
+
+func (r *repository) UpdateLogout(ctx context.Context, logout Logout) error {
+	query := `UPDATE saml_idp_logouts SET pending_request_id = $2, partial = $3 WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, logout.ID, logout.PendingRequestID, logout.Partial)
+	if err != nil {
+		return fmt.Errorf("failed to update saml logout: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLogoutNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteLogout(ctx context.Context, id string) error {
+	query := `DELETE FROM saml_idp_logouts WHERE id = $1`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to delete saml logout: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) error {
+	for _, query := range []string{
+		`DELETE FROM saml_idp_requests WHERE expires_at <= $1`,
+		`DELETE FROM saml_idp_sessions WHERE expires_at <= $1`,
+		`DELETE FROM saml_idp_logouts WHERE expires_at <= $1`,
+	} {
+		if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, now); err != nil {
+			return fmt.Errorf("failed to delete expired saml state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func scanServiceProvider(row pgx.Row) (domain.SAMLServiceProviderInterface, error) {
+	var (
+		id        uuid.UUID
+		settings  domain.SAMLServiceProviderSettings
+		mapping   []byte
+		createdAt time.Time
+		updatedAt time.Time
+	)
+
+	err := row.Scan(
+		&id,
+		&settings.Name,
+		&settings.EntityID,
+		&settings.ACSURL,
+		&settings.SLOURL,
+		&settings.Certificate,
+		&settings.NameIDFormat,
+		&mapping,
+		&settings.AllowIdPInitiated,
+		&settings.RequireSignedRequests,
+		&settings.EncryptAssertions,
+		&settings.Enabled,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrServiceProviderNotFound
+		}
+		return nil, fmt.Errorf("failed to scan saml service provider: %w", err)
+	}
+
+	if err := json.Unmarshal(mapping, &settings.AttributeMapping); err != nil {
+		return nil, fmt.Errorf("failed to decode attribute mapping: %w", err)
+	}
+
+	return domain.RestoreSAMLServiceProvider(id, settings, createdAt, updatedAt), nil
+}
+
+func scanRequest(row pgx.Row) (*PendingRequest, error) {
+	var request PendingRequest
+	err := row.Scan(
+		&request.ID,
+		&request.ServiceProviderID,
+		&request.RequestID,
+		&request.NameIDFormat,
+		&request.RelayState,
+		&request.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to scan saml sign-in request: %w", err)
+	}
+
+	return &request, nil
+}
+
+func collectSessions(rows pgx.Rows) ([]Session, error) {
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(
+			&session.SessionIndex,
+			&session.ServiceProviderID,
+			&session.UserID,
+			&session.NameID,
+			&session.NameIDFormat,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saml session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate saml sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,1154 @@
+package samlidp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/mfa"
+	"github.com/felipeversiane/auth-service/internal/passkey"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	invalidRequestMessage = "invalid saml request"
+	invalidLogoutMessage  = "invalid saml logout message"
+	expiredSignInMessage  = "this sign-in has expired, please start again from the application"
+
+	certificateValid = 10 * 365 * 24 * time.Hour
+)
+
+// endpoints are the URLs of the identity provider. The entity ID is the
+// metadata URL, as is customary.
+type endpoints struct {
+	EntityID    string
+	MetadataURL string
+	SSOURL      string
+	SLOURL      string
+	LoginURL    string
+}
+
+// initiatedURL is where a user starts a sign-in to a service provider from
+// the identity provider.
+func (e endpoints) initiatedURL(id uuid.UUID) string {
+	return e.SSOURL + "/" + id.String()
+}
+
+type service struct {
+	config     config.SAMLConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	auth       auth.ServiceInterface
+	keyring    keys.KeyringInterface
+	audit      audit.RecorderInterface
+
+	mu           sync.Mutex
+	certificates map[string][]byte
+}
+
+// ServiceInterface registers the service providers of the identity
+// provider and runs their sign-ins and single logouts. Assertions are
+// signed with the token signing keys, wrapped in certificates.
+type ServiceInterface interface {
+	CreateServiceProvider(ctx context.Context, req CreateServiceProviderRequest) (*ServiceProviderResponse, *httperr.HttpError)
+	GetServiceProvider(ctx context.Context, id string) (*ServiceProviderResponse, *httperr.HttpError)
+	ListServiceProviders(ctx context.Context) ([]ServiceProviderResponse, *httperr.HttpError)
+	UpdateServiceProvider(ctx context.Context, id string, req UpdateServiceProviderRequest) (*ServiceProviderResponse, *httperr.HttpError)
+	DeleteServiceProvider(ctx context.Context, id string) *httperr.HttpError
+
+	// Metadata returns the identity provider metadata.
+	Metadata(ctx context.Context) ([]byte, *httperr.HttpError)
+	// BeginSSO checks an authentication request of a service provider and
+	// returns the sign-in to prompt the user for, or, when the request
+	// cannot be honored, the response telling the service provider so.
+	BeginSSO(ctx context.Context, msg Message) (*Prompt, *PostForm, *httperr.HttpError)
+	// InitiateSSO starts a sign-in to a service provider that did not ask
+	// for one.
+	InitiateSSO(ctx context.Context, serviceProviderID, relayState string) (*Prompt, *httperr.HttpError)
+	// FindPrompt returns a sign-in still waiting for the user.
+	FindPrompt(ctx context.Context, request string) (*Prompt, *httperr.HttpError)
+	// SignIn authenticates the user from the sign-in form and returns the
+	// signed response to post to the service provider. Users with a second
+	// factor get an MFA challenge token instead, and the form is posted
+	// again with their code.
+	SignIn(ctx context.Context, form LoginForm) (*PostForm, string, *httperr.HttpError)
+	// Logout handles the logout requests of service providers and their
+	// answers to the ones sent on, and returns the next message to post.
+	Logout(ctx context.Context, msg Message) (*PostForm, *httperr.HttpError)
+}
+
+func NewService(
+	config config.SAMLConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	auth auth.ServiceInterface,
+	keyring keys.KeyringInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:       config,
+		db:           db,
+		repository:   repository,
+		users:        users,
+		auth:         auth,
+		keyring:      keyring,
+		audit:        audit,
+		certificates: map[string][]byte{},
+	}
+}
+
+func (s *service) CreateServiceProvider(ctx context.Context, req CreateServiceProviderRequest) (*ServiceProviderResponse, *httperr.HttpError) {
+	settings := domain.SAMLServiceProviderSettings{
+		Name:                  strings.TrimSpace(req.Name),
+		NameIDFormat:          domain.SAMLNameIDFormatPersistent,
+		AttributeMapping:      domain.DefaultSAMLAttributeMapping(),
+		AllowIdPInitiated:     req.AllowIdPInitiated,
+		RequireSignedRequests: req.RequireSignedRequests,
+		EncryptAssertions:     req.EncryptAssertions,
+		Enabled:               req.Enabled == nil || *req.Enabled,
+	}
+	if req.MetadataXML != "" {
+		if restErr := applyMetadata(&settings, req.MetadataXML); restErr != nil {
+			return nil, restErr
+		}
+	}
+	applyFields(&settings, &req.EntityID, &req.ACSURL, nilIfEmpty(req.SLOURL), nilIfEmpty(req.Certificate))
+	if req.NameIDFormat != "" {
+		settings.NameIDFormat = req.NameIDFormat
+	}
+	if req.AttributeMapping != nil {
+		settings.AttributeMapping = toDomainMapping(*req.AttributeMapping)
+	}
+	if restErr := validateSettings(&settings); restErr != nil {
+		return nil, restErr
+	}
+
+	provider := domain.NewSAMLServiceProvider(settings)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateServiceProvider(ctx, provider); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLServiceProviderCreate, audit.TargetSAMLServiceProvider, provider.GetID(), describeServiceProvider(settings))
+	})
+	if err != nil {
+		return nil, serviceProviderError("create", err)
+	}
+
+	slog.Info("saml service provider registered", slog.String("service_provider_id", provider.GetID().String()))
+
+	res := NewServiceProviderResponse(provider, s.endpoints())
+	return &res, nil
+}
+
+func (s *service) GetServiceProvider(ctx context.Context, id string) (*ServiceProviderResponse, *httperr.HttpError) {
+	provider, restErr := s.findServiceProvider(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewServiceProviderResponse(provider, s.endpoints())
+	return &res, nil
+}
+
+func (s *service) ListServiceProviders(ctx context.Context) ([]ServiceProviderResponse, *httperr.HttpError) {
+	providers, err := s.repository.FindServiceProviders(ctx)
+	if err != nil {
+		return nil, serviceProviderError("list", err)
+	}
+
+	urls := s.endpoints()
+	res := make([]ServiceProviderResponse, 0, len(providers))
+	for _, provider := range providers {
+		res = append(res, NewServiceProviderResponse(provider, urls))
+	}
+
+	return res, nil
+}
+
+func (s *service) UpdateServiceProvider(ctx context.Context, id string, req UpdateServiceProviderRequest) (*ServiceProviderResponse, *httperr.HttpError) {
+	providerID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("saml service provider not found")
+	}
+
+	var (
+		provider domain.SAMLServiceProviderInterface
+		restErr  *httperr.HttpError
+	)
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.repository.FindServiceProvider(ctx, providerID)
+		if err != nil {
+			return err
+		}
+		provider = found
+
+		settings := provider.GetSettings()
+		if req.Name != nil {
+			settings.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.MetadataXML != nil {
+			if restErr = applyMetadata(&settings, *req.MetadataXML); restErr != nil {
+				return nil
+			}
+		}
+		applyFields(&settings, req.EntityID, req.ACSURL, req.SLOURL, req.Certificate)
+		if req.NameIDFormat != nil {
+			settings.NameIDFormat = *req.NameIDFormat
+		}
+		if req.AttributeMapping != nil {
+			settings.AttributeMapping = toDomainMapping(*req.AttributeMapping)
+		}
+		if req.AllowIdPInitiated != nil {
+			settings.AllowIdPInitiated = *req.AllowIdPInitiated
+		}
+		if req.RequireSignedRequests != nil {
+			settings.RequireSignedRequests = *req.RequireSignedRequests
+		}
+		if req.EncryptAssertions != nil {
+			settings.EncryptAssertions = *req.EncryptAssertions
+		}
+		if req.Enabled != nil {
+			settings.Enabled = *req.Enabled
+		}
+		if restErr = validateSettings(&settings); restErr != nil {
+			return nil
+		}
+
+		provider.Update(settings)
+		if err := s.repository.UpdateServiceProvider(ctx, provider); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLServiceProviderUpdate, audit.TargetSAMLServiceProvider, provider.GetID(), describeServiceProvider(settings))
+	})
+	if err != nil {
+		return nil, serviceProviderError("update", err)
+	}
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewServiceProviderResponse(provider, s.endpoints())
+	return &res, nil
+}
+
+func (s *service) DeleteServiceProvider(ctx context.Context, id string) *httperr.HttpError {
+	providerID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("saml service provider not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteServiceProvider(ctx, providerID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSAMLServiceProviderDelete, audit.TargetSAMLServiceProvider, providerID, nil)
+	})
+	if err != nil {
+		return serviceProviderError("delete", err)
+	}
+
+	return nil
+}
+
+func (s *service) Metadata(ctx context.Context) ([]byte, *httperr.HttpError) {
+	published, err := s.keyring.PublishedKeys(ctx)
+	if err != nil {
+		slog.Error("failed to load signing keys", "error", err)
+		return nil, httperr.NewInternalServerError("failed to describe the identity provider")
+	}
+
+	certificates := make([][]byte, 0, len(published))
+	for _, key := range published {
+		certificate, err := s.certificate(ctx, key)
+		if err != nil {
+			slog.Error("failed to load saml certificate", "error", err, "kid", key.GetID())
+			return nil, httperr.NewInternalServerError("failed to describe the identity provider")
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	urls := s.endpoints()
+	return identityProviderMetadata(urls.EntityID, urls.SSOURL, urls.SLOURL, certificates), nil
+}
+
+func (s *service) BeginSSO(ctx context.Context, msg Message) (*Prompt, *PostForm, *httperr.HttpError) {
+	if msg.SAMLRequest == "" {
+		return nil, nil, httperr.NewBadRequestError("a saml request is required")
+	}
+
+	root, err := msg.decode(parameterRequest)
+	if err != nil || !xmlsec.Is(root, namespaceProtocol, "AuthnRequest") {
+		slog.Info("saml request refused", "error", err)
+		return nil, nil, httperr.NewBadRequestError(invalidRequestMessage)
+	}
+
+	provider, err := s.repository.FindServiceProviderByEntityID(ctx, issuerOf(root))
+	if err != nil {
+		if errors.Is(err, ErrServiceProviderNotFound) {
+			return nil, nil, httperr.NewBadRequestError("unknown saml service provider")
+		}
+		return nil, nil, serviceProviderError("find", err)
+	}
+	settings := provider.GetSettings()
+	if !settings.Enabled {
+		return nil, nil, httperr.NewForbiddenError("saml service provider is disabled")
+	}
+
+	root, err = s.checkSignature(msg, parameterRequest, root, settings, settings.RequireSignedRequests)
+	if err != nil {
+		slog.Info("saml request refused", "error", err, "service_provider_id", provider.GetID())
+		return nil, nil, httperr.NewUnauthorizedRequestError(invalidRequestMessage)
+	}
+
+	// Until the request is known to be answered at the registered assertion
+	// consumer service, errors are shown rather than sent back.
+	urls := s.endpoints()
+	requestID := xmlsec.Attr(root, "ID")
+	switch {
+	case requestID == "" || len(requestID) > 256:
+		return nil, nil, httperr.NewBadRequestError(invalidRequestMessage)
+	case xmlsec.Attr(root, "Destination") != "" && xmlsec.Attr(root, "Destination") != urls.SSOURL:
+		return nil, nil, httperr.NewBadRequestError("the saml request is meant for another identity provider")
+	case xmlsec.Attr(root, "AssertionConsumerServiceURL") != "" && xmlsec.Attr(root, "AssertionConsumerServiceURL") != settings.ACSURL:
+		return nil, nil, httperr.NewBadRequestError("the assertion consumer service is not registered")
+	case xmlsec.Attr(root, "ProtocolBinding") != "" && xmlsec.Attr(root, "ProtocolBinding") != bindingHTTPPost:
+		return nil, nil, httperr.NewBadRequestError("only the HTTP-POST binding is supported for responses")
+	}
+
+	// There is no session at the identity provider, so every sign-in asks
+	// for credentials.
+	if xmlsec.Attr(root, "IsPassive") == "true" {
+		form, restErr := s.refuse(ctx, provider, requestID, msg.RelayState, statusNoPassive)
+		return nil, form, restErr
+	}
+
+	format, ok := nameIDFormatFor(settings, xmlsec.Attr(xmlsec.Child(root, namespaceProtocol, "NameIDPolicy"), "Format"))
+	if !ok {
+		form, restErr := s.refuse(ctx, provider, requestID, msg.RelayState, statusInvalidNameID)
+		return nil, form, restErr
+	}
+
+	prompt, restErr := s.prompt(ctx, provider, requestID, format, msg.RelayState)
+	return prompt, nil, restErr
+}
+
+func (s *service) InitiateSSO(ctx context.Context, serviceProviderID, relayState string) (*Prompt, *httperr.HttpError) {
+	provider, restErr := s.findServiceProvider(ctx, serviceProviderID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	settings := provider.GetSettings()
+	if !settings.Enabled {
+		return nil, httperr.NewForbiddenError("saml service provider is disabled")
+	}
+	if !settings.AllowIdPInitiated {
+		return nil, httperr.NewForbiddenError("sign-ins to this service provider must start at the service provider")
+	}
+
+	return s.prompt(ctx, provider, "", settings.NameIDFormat, relayState)
+}
+
+func (s *service) FindPrompt(ctx context.Context, request string) (*Prompt, *httperr.HttpError) {
+	pending, restErr := s.findRequest(ctx, request)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	provider, err := s.repository.FindServiceProvider(ctx, pending.ServiceProviderID)
+	if err != nil {
+		return nil, serviceProviderError("find", err)
+	}
+
+	return &Prompt{Request: pending.ID, ServiceProvider: provider.GetSettings().Name}, nil
+}
+
+func (s *service) SignIn(ctx context.Context, form LoginForm) (*PostForm, string, *httperr.HttpError) {
+	pending, restErr := s.findRequest(ctx, form.Request)
+	if restErr != nil {
+		return nil, "", restErr
+	}
+
+	provider, err := s.repository.FindServiceProvider(ctx, pending.ServiceProviderID)
+	if err != nil {
+		return nil, "", serviceProviderError("find", err)
+	}
+	if !provider.GetSettings().Enabled {
+		return nil, "", httperr.NewForbiddenError("saml service provider is disabled")
+	}
+
+	userID, challenge, restErr := s.authenticateOwner(ctx, form)
+	if restErr != nil || challenge != "" {
+		return nil, challenge, restErr
+	}
+
+	var post *PostForm
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		if _, err := s.repository.ConsumeRequest(ctx, pending.ID, now); err != nil {
+			return err
+		}
+
+		found, err := s.users.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		post, err = s.issue(ctx, provider, pending, found, now)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotFound) {
+			return nil, "", httperr.NewBadRequestError(expiredSignInMessage)
+		}
+		slog.Error("failed to issue saml assertion", "error", err, "service_provider_id", provider.GetID())
+		return nil, "", httperr.NewInternalServerError("failed to sign in")
+	}
+
+	return post, "", nil
+}
+
+func (s *service) Logout(ctx context.Context, msg Message) (*PostForm, *httperr.HttpError) {
+	switch {
+	case msg.SAMLRequest != "":
+		return s.handleLogoutRequest(ctx, msg)
+	case msg.SAMLResponse != "":
+		return s.handleLogoutResponse(ctx, msg)
+	}
+	return nil, httperr.NewBadRequestError("a saml logout request or response is required")
+}
+
+// handleLogoutRequest ends the sessions a service provider asks to, then
+// sends the browser around the other service providers of the user before
+// answering.
+func (s *service) handleLogoutRequest(ctx context.Context, msg Message) (*PostForm, *httperr.HttpError) {
+	root, err := msg.decode(parameterRequest)
+	if err != nil || !xmlsec.Is(root, namespaceProtocol, "LogoutRequest") {
+		slog.Info("saml logout request refused", "error", err)
+		return nil, httperr.NewBadRequestError(invalidLogoutMessage)
+	}
+
+	provider, root, restErr := s.logoutSender(ctx, msg, parameterRequest, root)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	now := time.Now().UTC()
+	if value := xmlsec.Attr(root, "NotOnOrAfter"); value != "" {
+		notOnOrAfter, err := parseInstant(value)
+		if err != nil || !now.Before(notOnOrAfter.Add(s.clockSkew())) {
+			return nil, httperr.NewBadRequestError("the saml logout request has expired")
+		}
+	}
+
+	requestID := xmlsec.Attr(root, "ID")
+	nameID := strings.TrimSpace(xmlsec.Text(xmlsec.Child(root, namespaceAssertion, "NameID")))
+	if requestID == "" || len(requestID) > 256 || nameID == "" {
+		return nil, httperr.NewBadRequestError(invalidLogoutMessage)
+	}
+	indexes := map[string]bool{}
+	for _, index := range xmlsec.Children(root, namespaceProtocol, "SessionIndex") {
+		indexes[strings.TrimSpace(xmlsec.Text(index))] = true
+	}
+
+	var post *PostForm
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteExpired(ctx, now); err != nil {
+			slog.Warn("failed to clean up saml state", "error", err)
+		}
+
+		sessions, err := s.repository.FindSubjectSessions(ctx, provider.GetID(), nameID, now)
+		if err != nil {
+			return err
+		}
+
+		var ended []Session
+		for _, session := range sessions {
+			if len(indexes) > 0 && !indexes[session.SessionIndex] {
+				continue
+			}
+			if err := s.repository.DeleteSession(ctx, session.SessionIndex); err != nil {
+				return err
+			}
+			ended = append(ended, session)
+		}
+
+		logout := &Logout{
+			ID:                newMessageID(),
+			ServiceProviderID: provider.GetID(),
+			RequestID:         requestID,
+			RelayState:        msg.RelayState,
+			ExpiresAt:         now.Add(s.requestTTL()),
+		}
+
+		// A subject without sessions was logged out already; there is no one
+		// else to tell.
+		if len(ended) == 0 {
+			post, err = s.answerLogout(ctx, provider, logout, now)
+			return err
+		}
+
+		logout.UserID = ended[0].UserID
+		if err := s.repository.CreateLogout(ctx, *logout); err != nil {
+			return err
+		}
+
+		metadata := map[string]string{
+			"service_provider_id": provider.GetID().String(),
+			"sessions":            strconv.Itoa(len(ended)),
+		}
+		if err := s.record(ctx, audit.ActionSAMLLogout, audit.TargetUser, logout.UserID, metadata); err != nil {
+			return err
+		}
+
+		post, err = s.continueLogout(ctx, logout, now)
+		return err
+	})
+	if err != nil {
+		slog.Error("failed to log out over saml", "error", err, "service_provider_id", provider.GetID())
+		return nil, httperr.NewInternalServerError("failed to log out")
+	}
+
+	return post, nil
+}
+
+// handleLogoutResponse takes the answer of a service provider the browser
+// was sent to and moves on to the next.
+func (s *service) handleLogoutResponse(ctx context.Context, msg Message) (*PostForm, *httperr.HttpError) {
+	root, err := msg.decode(parameterResponse)
+	if err != nil || !xmlsec.Is(root, namespaceProtocol, "LogoutResponse") {
+		slog.Info("saml logout response refused", "error", err)
+		return nil, httperr.NewBadRequestError(invalidLogoutMessage)
+	}
+
+	now := time.Now().UTC()
+	logout, err := s.repository.FindLogout(ctx, msg.RelayState, now)
+	if err != nil {
+		if errors.Is(err, ErrLogoutNotFound) {
+			return nil, httperr.NewBadRequestError("this logout has expired")
+		}
+		slog.Error("failed to find saml logout", "error", err)
+		return nil, httperr.NewInternalServerError("failed to log out")
+	}
+
+	provider, root, restErr := s.logoutSender(ctx, msg, parameterResponse, root)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if logout.PendingRequestID == "" || xmlsec.Attr(root, "InResponseTo") != logout.PendingRequestID {
+		return nil, httperr.NewBadRequestError(invalidLogoutMessage)
+	}
+
+	if code := xmlsec.Attr(xmlsec.Child(xmlsec.Child(root, namespaceProtocol, "Status"), namespaceProtocol, "StatusCode"), "Value"); code != statusSuccess {
+		slog.Info("saml service provider did not log out", "service_provider_id", provider.GetID(), "status", code)
+		logout.Partial = true
+	}
+
+	var post *PostForm
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		post, err = s.continueLogout(ctx, logout, now)
+		return err
+	})
+	if err != nil {
+		slog.Error("failed to log out over saml", "error", err)
+		return nil, httperr.NewInternalServerError("failed to log out")
+	}
+
+	return post, nil
+}
+
+// logoutSender returns the service provider a logout message comes from,
+// which must have signed it, along with the message as it was signed.
+func (s *service) logoutSender(ctx context.Context, msg Message, parameter string, root *etree.Element) (domain.SAMLServiceProviderInterface, *etree.Element, *httperr.HttpError) {
+	provider, err := s.repository.FindServiceProviderByEntityID(ctx, issuerOf(root))
+	if err != nil {
+		if errors.Is(err, ErrServiceProviderNotFound) {
+			return nil, nil, httperr.NewBadRequestError("unknown saml service provider")
+		}
+		return nil, nil, serviceProviderError("find", err)
+	}
+
+	settings := provider.GetSettings()
+	if settings.SLOURL == "" {
+		return nil, nil, httperr.NewBadRequestError("single logout is not configured for this service provider")
+	}
+	if destination := xmlsec.Attr(root, "Destination"); destination != "" && destination != s.endpoints().SLOURL {
+		return nil, nil, httperr.NewBadRequestError("the saml logout message is meant for another identity provider")
+	}
+	root, err = s.checkSignature(msg, parameter, root, settings, true)
+	if err != nil {
+		slog.Info("saml logout message refused", "error", err, "service_provider_id", provider.GetID())
+		return nil, nil, httperr.NewUnauthorizedRequestError(invalidLogoutMessage)
+	}
+
+	return provider, root, nil
+}
+
+// continueLogout ends the next session of the user and sends its service
+// provider a logout request, or answers the service provider that asked
+// once there is none left. Service providers without single logout cannot
+// be told, which makes the logout partial.
+func (s *service) continueLogout(ctx context.Context, logout *Logout, now time.Time) (*PostForm, error) {
+	sessions, err := s.repository.FindSessions(ctx, logout.UserID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if err := s.repository.DeleteSession(ctx, session.SessionIndex); err != nil {
+			return nil, err
+		}
+
+		provider, err := s.repository.FindServiceProvider(ctx, session.ServiceProviderID)
+		if err != nil {
+			return nil, err
+		}
+		settings := provider.GetSettings()
+		if settings.SLOURL == "" || !settings.Enabled {
+			logout.Partial = true
+			continue
+		}
+
+		request := logoutRequest(s.endpoints().EntityID, settings.SLOURL, session, now)
+		if err := s.sign(ctx, request); err != nil {
+			return nil, err
+		}
+
+		logout.PendingRequestID = xmlsec.Attr(request, "ID")
+		if err := s.repository.UpdateLogout(ctx, *logout); err != nil {
+			return nil, err
+		}
+
+		return &PostForm{URL: settings.SLOURL, Parameter: parameterRequest, Message: encode(request), RelayState: logout.ID}, nil
+	}
+
+	origin, err := s.repository.FindServiceProvider(ctx, logout.ServiceProviderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.DeleteLogout(ctx, logout.ID); err != nil {
+		return nil, err
+	}
+
+	return s.answerLogout(ctx, origin, logout, now)
+}
+
+// answerLogout tells the service provider that asked for a logout that it
+// is done.
+func (s *service) answerLogout(ctx context.Context, provider domain.SAMLServiceProviderInterface, logout *Logout, now time.Time) (*PostForm, error) {
+	sloURL := provider.GetSettings().SLOURL
+
+	var partial string
+	if logout.Partial {
+		partial = statusPartialLogout
+	}
+	response := newStatusResponse("LogoutResponse", s.endpoints().EntityID, sloURL, logout.RequestID, statusSuccess, partial, now)
+	if err := s.sign(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return &PostForm{URL: sloURL, Parameter: parameterResponse, Message: encode(response), RelayState: logout.RelayState}, nil
+}
+
+// issue builds the signed response carrying the assertion about the user
+// and records the session it gives the service provider.
+func (s *service) issue(
+	ctx context.Context,
+	provider domain.SAMLServiceProviderInterface,
+	pending *PendingRequest,
+	found domain.UserInterface,
+	now time.Time,
+) (*PostForm, error) {
+	settings := provider.GetSettings()
+	urls := s.endpoints()
+
+	nameID, nameIDFormat := subjectOf(provider, pending.NameIDFormat, found)
+	session := Session{
+		SessionIndex:      newMessageID(),
+		ServiceProviderID: provider.GetID(),
+		UserID:            found.GetID(),
+		NameID:            nameID,
+		NameIDFormat:      nameIDFormat,
+		ExpiresAt:         now.Add(time.Duration(s.config.SessionTTL) * time.Second),
+	}
+	if err := s.repository.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	assertion := newAssertion(assertionParams{
+		Issuer:              urls.EntityID,
+		Audience:            settings.EntityID,
+		Recipient:           settings.ACSURL,
+		InResponseTo:        pending.RequestID,
+		NameID:              nameID,
+		NameIDFormat:        nameIDFormat,
+		SessionIndex:        session.SessionIndex,
+		Attributes:          attributesOf(settings.AttributeMapping, found),
+		Now:                 now,
+		NotOnOrAfter:        now.Add(time.Duration(s.config.AssertionTTL) * time.Second),
+		SessionNotOnOrAfter: session.ExpiresAt,
+	})
+	if err := s.sign(ctx, assertion); err != nil {
+		return nil, err
+	}
+
+	response := newStatusResponse("Response", urls.EntityID, settings.ACSURL, pending.RequestID, statusSuccess, "", now)
+	if settings.EncryptAssertions {
+		cert, err := xmlsec.ParseCertificate(settings.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := xmlsec.Encrypt(xmlsec.Bytes(assertion), cert)
+		if err != nil {
+			return nil, err
+		}
+		assertionChild(response, "EncryptedAssertion").AddChild(encrypted)
+	} else {
+		response.AddChild(assertion)
+	}
+	if err := s.sign(ctx, response); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		"service_provider_id": provider.GetID().String(),
+		"session_index":       session.SessionIndex,
+	}
+	if err := s.record(ctx, audit.ActionSAMLAssertionIssue, audit.TargetUser, found.GetID(), metadata); err != nil {
+		return nil, err
+	}
+
+	return &PostForm{URL: settings.ACSURL, Parameter: parameterResponse, Message: encode(response), RelayState: pending.RelayState}, nil
+}
+
+// refuse builds the response telling a service provider its request
+// cannot be honored.
+func (s *service) refuse(ctx context.Context, provider domain.SAMLServiceProviderInterface, requestID, relayState, reason string) (*PostForm, *httperr.HttpError) {
+	acsURL := provider.GetSettings().ACSURL
+	response := newStatusResponse("Response", s.endpoints().EntityID, acsURL, requestID, statusRequester, reason, time.Now().UTC())
+	if err := s.sign(ctx, response); err != nil {
+		slog.Error("failed to sign saml response", "error", err)
+		return nil, httperr.NewInternalServerError("failed to answer the saml request")
+	}
+
+	return &PostForm{URL: acsURL, Parameter: parameterResponse, Message: encode(response), RelayState: relayState}, nil
+}
+
+// prompt stores a sign-in until the user authenticates.
+func (s *service) prompt(ctx context.Context, provider domain.SAMLServiceProviderInterface, requestID, nameIDFormat, relayState string) (*Prompt, *httperr.HttpError) {
+	now := time.Now().UTC()
+	pending := PendingRequest{
+		ID:                newMessageID(),
+		ServiceProviderID: provider.GetID(),
+		RequestID:         requestID,
+		NameIDFormat:      nameIDFormat,
+		RelayState:        relayState,
+		ExpiresAt:         now.Add(s.requestTTL()),
+	}
+
+	if err := s.repository.DeleteExpired(ctx, now); err != nil {
+		slog.Warn("failed to clean up saml state", "error", err)
+	}
+	if err := s.repository.CreateRequest(ctx, pending); err != nil {
+		slog.Error("failed to store saml sign-in", "error", err)
+		return nil, httperr.NewInternalServerError("failed to start the sign-in")
+	}
+
+	return &Prompt{Request: pending.ID, ServiceProvider: provider.GetSettings().Name}, nil
+}
+
+func (s *service) findRequest(ctx context.Context, request string) (*PendingRequest, *httperr.HttpError) {
+	pending, err := s.repository.FindRequest(ctx, request, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, ErrRequestNotFound) {
+			return nil, httperr.NewBadRequestError(expiredSignInMessage)
+		}
+		slog.Error("failed to find saml sign-in", "error", err)
+		return nil, httperr.NewInternalServerError("failed to sign in")
+	}
+
+	return pending, nil
+}
+
+func (s *service) authenticateOwner(ctx context.Context, form LoginForm) (uuid.UUID, string, *httperr.HttpError) {
+	if form.MFAToken != "" {
+		proof := mfa.Proof{Code: form.OTP, RecoveryCode: form.RecoveryCode}
+		if form.Passkey != "" {
+			proof.Passkey = &passkey.FinishLoginRequest{
+				SessionToken: form.PasskeySession,
+				Credential:   json.RawMessage(form.Passkey),
+			}
+		}
+
+		userID, restErr := s.auth.VerifySecondFactor(ctx, form.MFAToken, proof)
+		return userID, "", restErr
+	}
+
+	found, restErr := s.auth.Authenticate(ctx, form.Email, form.Password)
+	if restErr != nil {
+		return uuid.Nil, "", restErr
+	}
+
+	challenge, _, restErr := s.auth.RequireSecondFactor(ctx, found)
+	if restErr != nil || challenge != "" {
+		return uuid.Nil, challenge, restErr
+	}
+
+	return found.GetID(), "", nil
+}
+
+// checkSignature checks the signature of a message of a service provider
+// and returns the message as it was signed, which is what to read from then on.
+// An unsigned message passes unless required; a service provider without a
+// certificate cannot sign, so its signatures are ignored.
+func (s *service) checkSignature(msg Message, parameter string, root *etree.Element, settings domain.SAMLServiceProviderSettings, required bool) (*etree.Element, error) {
+	if settings.Certificate == "" {
+		if required {
+			return nil, errors.New("the service provider has no certificate to check signatures with")
+		}
+		return root, nil
+	}
+
+	cert, err := xmlsec.ParseCertificate(settings.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := msg.verify(parameter, root, cert, time.Now())
+	if errors.Is(err, xmlsec.ErrNoSignature) && !required {
+		return root, nil
+	}
+	return signed, err
+}
+
+// sign adds an enveloped signature made by goxmldsig, right after its
+// issuer, to a message or assertion with the active signing key.
+func (s *service) sign(ctx context.Context, el *etree.Element) error {
+	key, err := s.keyring.ActiveKey(ctx)
+	if err != nil {
+		return err
+	}
+	// XML signatures have no Ed25519 method goxmldsig or service providers
+	// know of.
+	if key.GetAlgorithm() == keys.AlgorithmEdDSA {
+		return fmt.Errorf("saml messages cannot be signed with %s keys, set KEYS_SIGNING_ALGORITHM to %s or %s",
+			keys.AlgorithmEdDSA, keys.AlgorithmRS256, keys.AlgorithmES256)
+	}
+
+	certificate, err := s.certificate(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	signing, err := dsig.NewSigningContext(key.GetPrivateKey(), [][]byte{certificate})
+	if err != nil {
+		return err
+	}
+	signing.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	signature, err := signing.ConstructSignature(el, true)
+	if err != nil {
+		return err
+	}
+	el.InsertChildAt(xmlsec.Child(el, namespaceAssertion, "Issuer").Index()+1, signature)
+
+	return nil
+}
+
+// certificate returns the certificate of a signing key, making it the
+// first time the key signs for a service provider.
+func (s *service) certificate(ctx context.Context, key domain.SigningKeyInterface) ([]byte, error) {
+	s.mu.Lock()
+	cached, ok := s.certificates[key.GetID()]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	certificate, err := s.repository.FindCertificate(ctx, key.GetID())
+	if errors.Is(err, ErrCertificateNotFound) {
+		var created []byte
+		if created, err = newCertificate(key); err != nil {
+			return nil, err
+		}
+		if err = s.repository.CreateCertificate(ctx, key.GetID(), created); err != nil {
+			return nil, err
+		}
+		certificate, err = s.repository.FindCertificate(ctx, key.GetID())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.certificates[key.GetID()] = certificate
+	s.mu.Unlock()
+
+	return certificate, nil
+}
+
+func (s *service) findServiceProvider(ctx context.Context, id string) (domain.SAMLServiceProviderInterface, *httperr.HttpError) {
+	providerID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("saml service provider not found")
+	}
+
+	provider, err := s.repository.FindServiceProvider(ctx, providerID)
+	if err != nil {
+		return nil, serviceProviderError("find", err)
+	}
+
+	return provider, nil
+}
+
+func (s *service) endpoints() endpoints {
+	base := strings.TrimRight(s.config.BaseURL, "/") + "/idp"
+
+	return endpoints{
+		EntityID:    base + "/metadata",
+		MetadataURL: base + "/metadata",
+		SSOURL:      base + "/sso",
+		SLOURL:      base + "/slo",
+		LoginURL:    base + "/login",
+	}
+}
+
+func (s *service) requestTTL() time.Duration {
+	return time.Duration(s.config.RequestTTL) * time.Second
+}
+
+func (s *service) clockSkew() time.Duration {
+	return time.Duration(s.config.ClockSkew) * time.Second
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+// newCertificate wraps a signing key in a self-signed certificate, which
+// is all service providers need to trust it. It is valid from the creation
+// of the key and long after the key is retired, as the key is what is
+// trusted.
+func newCertificate(key domain.SigningKeyInterface) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "SAML identity provider " + key.GetID()},
+		NotBefore:    key.GetCreatedAt().Add(-time.Hour),
+		NotAfter:     key.GetCreatedAt().Add(certificateValid),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	return x509.CreateCertificate(rand.Reader, template, template, key.GetPublicKey(), key.GetPrivateKey())
+}
+
+// nameIDFormatFor picks the format of the subject among those a request
+// accepts: the registered one unless the request names another supported
+// format.
+func nameIDFormatFor(settings domain.SAMLServiceProviderSettings, requested string) (string, bool) {
+	switch requested {
+	case "", nameIDFormatUnspecified:
+		return settings.NameIDFormat, true
+	case nameIDFormatPersistent:
+		return domain.SAMLNameIDFormatPersistent, true
+	case nameIDFormatEmail:
+		return domain.SAMLNameIDFormatEmail, true
+	}
+	return "", false
+}
+
+// subjectOf returns the NameID of the user for a service provider and its
+// format. Persistent NameIDs differ between service providers, so they
+// cannot tell they share a user.
+func subjectOf(provider domain.SAMLServiceProviderInterface, format string, found domain.UserInterface) (string, string) {
+	if format == domain.SAMLNameIDFormatEmail {
+		return found.GetEmail(), nameIDFormatEmail
+	}
+
+	userID := found.GetID()
+	return uuid.NewSHA1(provider.GetID(), userID[:]).String(), nameIDFormatPersistent
+}
+
+// attributesOf returns the profile of the user under the attribute names
+// of the mapping, leaving out unmapped and empty columns.
+func attributesOf(mapping domain.SAMLAttributeMapping, found domain.UserInterface) []attribute {
+	var attributes []attribute
+	for _, column := range []attribute{
+		{Name: mapping.Email, Value: found.GetEmail()},
+		{Name: mapping.FirstName, Value: found.GetFirstName()},
+		{Name: mapping.LastName, Value: found.GetLastName()},
+		{Name: mapping.Phone, Value: found.GetPhone()},
+	} {
+		if column.Name != "" && column.Value != "" {
+			attributes = append(attributes, column)
+		}
+	}
+	return attributes
+}
+
+func applyMetadata(settings *domain.SAMLServiceProviderSettings, metadata string) *httperr.HttpError {
+	sp, err := parseServiceProvider([]byte(metadata))
+	if err != nil {
+		return invalidFieldError("metadata_xml", err.Error())
+	}
+
+	settings.EntityID = sp.EntityID
+	settings.ACSURL = sp.ACSURL
+	settings.SLOURL = sp.SLOURL
+	settings.Certificate = sp.Certificate
+	return nil
+}
+
+// applyFields sets the fields given; an empty SLO URL or certificate
+// clears it.
+func applyFields(settings *domain.SAMLServiceProviderSettings, entityID, acsURL, sloURL, certificate *string) {
+	if entityID != nil && strings.TrimSpace(*entityID) != "" {
+		settings.EntityID = strings.TrimSpace(*entityID)
+	}
+	if acsURL != nil && strings.TrimSpace(*acsURL) != "" {
+		settings.ACSURL = strings.TrimSpace(*acsURL)
+	}
+	if sloURL != nil {
+		settings.SLOURL = strings.TrimSpace(*sloURL)
+	}
+	if certificate != nil {
+		settings.Certificate = strings.TrimSpace(*certificate)
+	}
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// validateSettings checks the service provider is fully described, and
+// normalizes its certificate to plain base64 DER.
+func validateSettings(settings *domain.SAMLServiceProviderSettings) *httperr.HttpError {
+	if settings.Name == "" {
+		return invalidFieldError("name", "is required")
+	}
+	if settings.EntityID == "" {
+		return invalidFieldError("entity_id", "is required without metadata_xml")
+	}
+	if !isHTTPURL(settings.ACSURL) {
+		return invalidFieldError("acs_url", "must be an http or https URL")
+	}
+	if settings.SLOURL != "" && !isHTTPURL(settings.SLOURL) {
+		return invalidFieldError("slo_url", "must be an http or https URL")
+	}
+
+	if settings.Certificate == "" {
+		switch {
+		case settings.SLOURL != "":
+			return invalidFieldError("certificate", "is required for single logout")
+		case settings.RequireSignedRequests:
+			return invalidFieldError("certificate", "is required to check signed requests")
+		case settings.EncryptAssertions:
+			return invalidFieldError("certificate", "is required to encrypt assertions")
+		}
+		return nil
+	}
+
+	cert, err := xmlsec.ParseCertificate(stripPEM(settings.Certificate))
+	if err != nil {
+		return invalidFieldError("certificate", "must be a base64 DER or PEM certificate")
+	}
+	if _, ok := cert.PublicKey.(*rsa.PublicKey); settings.EncryptAssertions && !ok {
+		return invalidFieldError("certificate", "must hold an RSA key to encrypt assertions")
+	}
+	settings.Certificate = base64.StdEncoding.EncodeToString(cert.Raw)
+
+	return nil
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// stripPEM drops the armor of a PEM certificate, leaving its base64 body.
+func stripPEM(encoded string) string {
+	var body []string
+	for _, line := range strings.Split(encoded, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "-----") {
+			body = append(body, line)
+		}
+	}
+	return strings.Join(body, "")
+}
+
+func toDomainMapping(mapping AttributeMapping) domain.SAMLAttributeMapping {
+	return domain.SAMLAttributeMapping{
+		Email:     strings.TrimSpace(mapping.Email),
+		FirstName: strings.TrimSpace(mapping.FirstName),
+		LastName:  strings.TrimSpace(mapping.LastName),
+		Phone:     strings.TrimSpace(mapping.Phone),
+	}
+}
+
+func describeServiceProvider(settings domain.SAMLServiceProviderSettings) map[string]string {
+	return map[string]string{
+		"entity_id":               settings.EntityID,
+		"acs_url":                 settings.ACSURL,
+		"name_id_format":          settings.NameIDFormat,
+		"allow_idp_initiated":     strconv.FormatBool(settings.AllowIdPInitiated),
+		"require_signed_requests": strconv.FormatBool(settings.RequireSignedRequests),
+		"encrypt_assertions":      strconv.FormatBool(settings.EncryptAssertions),
+		"enabled":                 strconv.FormatBool(settings.Enabled),
+	}
+}
+
+func serviceProviderError(op string, err error) *httperr.HttpError {
+	switch {
+	case errors.Is(err, ErrServiceProviderNotFound):
+		return httperr.NewNotFoundError("saml service provider not found")
+	case errors.Is(err, ErrServiceProviderExists):
+		return httperr.NewBadRequestError("a saml service provider with this entity id already exists")
+	}
+	slog.Error("failed to "+op+" saml service provider", "error", err)
+	return httperr.NewInternalServerError("failed to " + op + " saml service provider")
+}
+
+func invalidFieldError(field, message string) *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: field, Message: message},
+	})
+}
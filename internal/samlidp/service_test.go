@@ -0,0 +1,211 @@
+package samlidp
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/felipeversiane/auth-service/pkg/xmlsec"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	testBaseURL  = "https://auth.example.com"
+	testEntityID = "https://app.example.com/saml"
+	testACSURL   = "https://app.example.com/saml/acs"
+)
+
+type fakeRepository struct {
+	RepositoryInterface
+	certificates map[string][]byte
+	sessions     []Session
+}
+
+func (r *fakeRepository) FindCertificate(ctx context.Context, keyID string) ([]byte, error) {
+	certificate, ok := r.certificates[keyID]
+	if !ok {
+		return nil, ErrCertificateNotFound
+	}
+	return certificate, nil
+}
+
+func (r *fakeRepository) CreateCertificate(ctx context.Context, keyID string, certificate []byte) error {
+	if _, ok := r.certificates[keyID]; !ok {
+		r.certificates[keyID] = certificate
+	}
+	return nil
+}
+
+func (r *fakeRepository) CreateSession(ctx context.Context, session Session) error {
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+type fakeKeyring struct {
+	keys.KeyringInterface
+	key domain.SigningKeyInterface
+}
+
+func (k *fakeKeyring) ActiveKey(ctx context.Context) (domain.SigningKeyInterface, error) {
+	return k.key, nil
+}
+
+func newTestService(t *testing.T, algorithm string) (*service, *fakeRepository, *testutil.Recorder) {
+	t.Helper()
+
+	signer, err := keys.GenerateKey(algorithm)
+	if err != nil {
+		t.Fatalf("keys.GenerateKey() error = %v", err)
+	}
+	repository := &fakeRepository{certificates: map[string][]byte{}}
+	recorder := &testutil.Recorder{}
+
+	s := NewService(
+		config.SAMLConfig{BaseURL: testBaseURL, AssertionTTL: 300, SessionTTL: 3600},
+		nil,
+		repository,
+		nil,
+		nil,
+		&fakeKeyring{key: domain.NewSigningKey(uuid.NewString(), algorithm, signer)},
+		recorder,
+	).(*service)
+
+	return s, repository, recorder
+}
+
+func TestIssuedResponseValidatesWithGoxmldsig(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		encrypt   bool
+	}{
+		{"rsa", keys.AlgorithmRS256, false},
+		{"ecdsa", keys.AlgorithmES256, false},
+		{"encrypted assertion", keys.AlgorithmRS256, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repository, recorder := newTestService(t, tt.algorithm)
+
+			settings := domain.SAMLServiceProviderSettings{
+				EntityID:          testEntityID,
+				ACSURL:            testACSURL,
+				NameIDFormat:      domain.SAMLNameIDFormatEmail,
+				AttributeMapping:  domain.DefaultSAMLAttributeMapping(),
+				EncryptAssertions: tt.encrypt,
+				Enabled:           true,
+			}
+			var decryptionKey *rsa.PrivateKey
+			if tt.encrypt {
+				signer, err := keys.GenerateKey(keys.AlgorithmRS256)
+				if err != nil {
+					t.Fatalf("keys.GenerateKey() error = %v", err)
+				}
+				certificate, err := newCertificate(domain.NewSigningKey("sp", keys.AlgorithmRS256, signer))
+				if err != nil {
+					t.Fatalf("newCertificate() error = %v", err)
+				}
+				settings.Certificate = base64.StdEncoding.EncodeToString(certificate)
+				decryptionKey = signer.(*rsa.PrivateKey)
+			}
+			provider := domain.NewSAMLServiceProvider(settings)
+
+			account, err := domain.New("ada@example.com", "", "", "Ada", "Lovelace")
+			if err != nil {
+				t.Fatalf("domain.New() error = %v", err)
+			}
+			pending := &PendingRequest{RequestID: "_request", NameIDFormat: domain.SAMLNameIDFormatEmail, RelayState: "state"}
+
+			form, err := s.issue(context.Background(), provider, pending, account, time.Now().UTC())
+			if err != nil {
+				t.Fatalf("issue() error = %v", err)
+			}
+			if form.URL != testACSURL || form.Parameter != parameterResponse || form.RelayState != "state" {
+				t.Errorf("issue() form = %+v", form)
+			}
+
+			data, err := base64.StdEncoding.DecodeString(form.Message)
+			if err != nil {
+				t.Fatalf("message is not base64: %v", err)
+			}
+			doc := etree.NewDocument()
+			if err := doc.ReadFromBytes(data); err != nil {
+				t.Fatalf("ReadFromBytes() error = %v", err)
+			}
+
+			cert, err := x509.ParseCertificate(repository.certificates[s.keyring.(*fakeKeyring).key.GetID()])
+			if err != nil {
+				t.Fatalf("x509.ParseCertificate() error = %v", err)
+			}
+			validator := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{cert}})
+
+			response, err := validator.Validate(doc.Root())
+			if err != nil {
+				t.Fatalf("goxmldsig rejected the response: %v\n%s", err, data)
+			}
+			if got := xmlsec.Attr(response, "InResponseTo"); got != "_request" {
+				t.Errorf("InResponseTo = %q", got)
+			}
+
+			assertion := xmlsec.Child(response, namespaceAssertion, "Assertion")
+			if tt.encrypt {
+				if assertion != nil {
+					t.Fatal("the assertion was sent in the clear")
+				}
+				assertion, err = xmlsec.Decrypt(xmlsec.Child(response, namespaceAssertion, "EncryptedAssertion"), decryptionKey)
+				if err != nil {
+					t.Fatalf("Decrypt() error = %v", err)
+				}
+			}
+			if assertion == nil {
+				t.Fatalf("the response carries no assertion:\n%s", data)
+			}
+
+			// The assertion is signed on its own, for service providers that
+			// only check the assertion.
+			signed, err := validator.Validate(assertion)
+			if err != nil {
+				t.Fatalf("goxmldsig rejected the assertion: %v", err)
+			}
+			nameID := xmlsec.Child(xmlsec.Child(signed, namespaceAssertion, "Subject"), namespaceAssertion, "NameID")
+			if got := strings.TrimSpace(xmlsec.Text(nameID)); got != "ada@example.com" {
+				t.Errorf("NameID = %q", got)
+			}
+			audience := xmlsec.Child(xmlsec.Child(xmlsec.Child(signed, namespaceAssertion, "Conditions"), namespaceAssertion, "AudienceRestriction"), namespaceAssertion, "Audience")
+			if got := xmlsec.Text(audience); got != testEntityID {
+				t.Errorf("Audience = %q", got)
+			}
+
+			if len(repository.sessions) != 1 {
+				t.Errorf("created %d sessions, want 1", len(repository.sessions))
+			}
+			if len(recorder.Entries) != 1 || !recorder.Has(audit.ActionSAMLAssertionIssue) {
+				t.Errorf("recorded %+v", recorder.Entries)
+			}
+		})
+	}
+}
+
+func TestSignRefusesEdDSAKeys(t *testing.T) {
+	s, repository, _ := newTestService(t, keys.AlgorithmEdDSA)
+
+	message := newStatusResponse("Response", testBaseURL+"/idp/metadata", testACSURL, "_request", statusSuccess, "", time.Now())
+	if err := s.sign(context.Background(), message); err == nil {
+		t.Fatal("sign() signed with an EdDSA key")
+	}
+	if len(repository.certificates) != 0 {
+		t.Error("sign() made a certificate for an EdDSA key")
+	}
+}
@@ -0,0 +1,8 @@
+(function () {
+  "use strict";
+
+  var form = document.getElementById("saml-post");
+  if (form) {
+    form.submit();
+  }
+})();
@@ -0,0 +1,70 @@
+package scim
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type CreateTokenRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type TokenResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Token     string     `json:"token,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ListRequest holds the query parameters of a SCIM list request. startIndex
+// is 1-based.
+type ListRequest struct {
+	Filter             string `form:"filter"`
+	StartIndex         int    `form:"startIndex"`
+	Count              *int   `form:"count"`
+	Attributes         string `form:"attributes"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+type ListResponse struct {
+	Schemas      []string         `json:"schemas"`
+	TotalResults int              `json:"totalResults"`
+	StartIndex   int              `json:"startIndex"`
+	ItemsPerPage int              `json:"itemsPerPage"`
+	Resources    []map[string]any `json:"Resources"`
+}
+
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Resource is a rendered SCIM resource along with its version, sent as the
+// ETag.
+type Resource struct {
+	Doc     map[string]any
+	Version string
+}
+
+func NewTokenResponse(token domain.SCIMTokenInterface, raw string) TokenResponse {
+	res := TokenResponse{
+		ID:        token.GetID().String(),
+		Name:      token.GetName(),
+		Token:     raw,
+		CreatedAt: token.GetCreatedAt(),
+		RevokedAt: token.GetRevokedAt(),
+	}
+	if createdBy := token.GetCreatedBy(); createdBy != nil {
+		res.CreatedBy = createdBy.String()
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package scim
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Detail error codes of RFC 7644 section 3.12.
+const (
+	ErrorInvalidFilter = "invalidFilter"
+	ErrorUniqueness    = "uniqueness"
+	ErrorMutability    = "mutability"
+	ErrorInvalidSyntax = "invalidSyntax"
+	ErrorInvalidPath   = "invalidPath"
+	ErrorNoTarget      = "noTarget"
+	ErrorInvalidValue  = "invalidValue"
+	ErrorInvalidVers   = "invalidVers"
+)
+
+// Error is the SCIM error response. The SCIM API uses it instead of
+// httperr.HttpError because provisioning clients parse scimType.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+	code     int
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Code() int {
+	return e.code
+}
+
+func newError(code int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+		code:     code,
+	}
+}
+
+func newBadRequestError(scimType, detail string) *Error {
+	return newError(http.StatusBadRequest, scimType, detail)
+}
+
+func newNotFoundError(detail string) *Error {
+	return newError(http.StatusNotFound, "", detail)
+}
+
+func newConflictError(detail string) *Error {
+	return newError(http.StatusConflict, ErrorUniqueness, detail)
+}
+
+func newPreconditionFailedError() *Error {
+	return newError(http.StatusPreconditionFailed, ErrorInvalidVers, "resource version does not match If-Match")
+}
+
+func newUnauthorizedError(detail string) *Error {
+	return newError(http.StatusUnauthorized, "", detail)
+}
+
+func newServerError() *Error {
+	return newError(http.StatusInternalServerError, "", "internal server error")
+}
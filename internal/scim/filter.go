@@ -0,0 +1,515 @@
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Comparison operators of RFC 7644 section 3.4.2.2.
+const (
+	opEqual          = "eq"
+	opNotEqual       = "ne"
+	opContains       = "co"
+	opStartsWith     = "sw"
+	opEndsWith       = "ew"
+	opPresent        = "pr"
+	opGreater        = "gt"
+	opGreaterOrEqual = "ge"
+	opLess           = "lt"
+	opLessOrEqual    = "le"
+)
+
+// attrPath names an attribute, optionally qualified by the URN of its
+// schema and narrowed to a sub-attribute. Names compare case-insensitively.
+type attrPath struct {
+	schema string
+	name   string
+	sub    string
+}
+
+// key is the lower-case form attributes are looked up by. Attributes of
+// the core schemas go unqualified.
+func (p attrPath) key() string {
+	name := strings.ToLower(p.name)
+	if p.sub != "" {
+		name += "." + strings.ToLower(p.sub)
+	}
+	if p.schema == "" {
+		return name
+	}
+	return strings.ToLower(p.schema) + ":" + name
+}
+
+// child resolves a path used inside a value filter, such as type in
+// emails[type eq "work"], against its parent attribute.
+func (p attrPath) child(sub attrPath) attrPath {
+	return attrPath{schema: p.schema, name: p.name, sub: sub.name}
+}
+
+func parseAttrPath(raw string) (attrPath, bool) {
+	var path attrPath
+
+	if strings.HasPrefix(strings.ToLower(raw), "urn:") {
+		i := strings.LastIndex(raw, ":")
+		path.schema, raw = raw[:i], raw[i+1:]
+		if isCoreSchema(path.schema) {
+			path.schema = ""
+		}
+	}
+
+	path.name, path.sub, _ = strings.Cut(raw, ".")
+	if !validAttrName(path.name) || (path.sub != "" && !validAttrName(path.sub)) {
+		return attrPath{}, false
+	}
+
+	return path, true
+}
+
+func isCoreSchema(schema string) bool {
+	return strings.EqualFold(schema, SchemaUser) || strings.EqualFold(schema, SchemaGroup)
+}
+
+// validAttrName follows ATTRNAME of RFC 7644, plus the $ref of references.
+func validAttrName(name string) bool {
+	if name == "$ref" {
+		return true
+	}
+	if name == "" || !isAlpha(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if !isAlpha(c) && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expression is a node of a parsed filter.
+type expression interface {
+	// matches evaluates the filter against one value of a multi-valued
+	// attribute, as PATCH paths such as members[value eq "..."] require.
+	matches(value map[string]any) bool
+}
+
+type logicalExpression struct {
+	and         bool
+	left, right expression
+}
+
+type notExpression struct {
+	inner expression
+}
+
+type compareExpression struct {
+	path  attrPath
+	op    string
+	value any
+}
+
+// valuePathExpression filters on the values of a multi-valued attribute,
+// such as emails[type eq "work"].
+type valuePathExpression struct {
+	path   attrPath
+	filter expression
+}
+
+func (e logicalExpression) matches(value map[string]any) bool {
+	if e.and {
+		return e.left.matches(value) && e.right.matches(value)
+	}
+	return e.left.matches(value) || e.right.matches(value)
+}
+
+func (e notExpression) matches(value map[string]any) bool {
+	return !e.inner.matches(value)
+}
+
+func (e valuePathExpression) matches(value map[string]any) bool {
+	for _, item := range asList(lookup(value, e.path.name)) {
+		if object, ok := item.(map[string]any); ok && e.filter.matches(object) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e compareExpression) matches(value map[string]any) bool {
+	actual := lookup(value, e.path.name)
+	if e.path.sub != "" {
+		object, _ := actual.(map[string]any)
+		actual = lookup(object, e.path.sub)
+	}
+
+	if e.op == opPresent {
+		return actual != nil && actual != ""
+	}
+
+	switch expected := e.value.(type) {
+	case string:
+		text, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		return compareStrings(strings.ToLower(text), e.op, strings.ToLower(expected))
+	case bool:
+		flag, ok := asBool(actual)
+		if !ok {
+			return false
+		}
+		return (e.op == opEqual) == (flag == expected)
+	case nil:
+		return (e.op == opEqual) == (actual == nil)
+	default:
+		return false
+	}
+}
+
+func compareStrings(actual, op, expected string) bool {
+	switch op {
+	case opEqual:
+		return actual == expected
+	case opNotEqual:
+		return actual != expected
+	case opContains:
+		return strings.Contains(actual, expected)
+	case opStartsWith:
+		return strings.HasPrefix(actual, expected)
+	case opEndsWith:
+		return strings.HasSuffix(actual, expected)
+	case opGreater:
+		return actual > expected
+	case opGreaterOrEqual:
+		return actual >= expected
+	case opLess:
+		return actual < expected
+	case opLessOrEqual:
+		return actual <= expected
+	}
+	return false
+}
+
+// parseFilter parses the filter grammar of RFC 7644 section 3.4.2.2.
+func parseFilter(raw string) (expression, *Error) {
+	tokens, scimErr := tokenize(raw)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	p := &parser{tokens: tokens}
+	expr, scimErr := p.parseOr()
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	if !p.done() {
+		return nil, invalidFilterError("unexpected " + p.peek().text)
+	}
+
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type lexeme struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(raw string) ([]lexeme, *Error) {
+	var tokens []lexeme
+
+	for i := 0; i < len(raw); {
+		switch c := raw[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, lexeme{kind: tokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, lexeme{kind: tokenClose, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, lexeme{kind: tokenOpenBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, lexeme{kind: tokenCloseBracket, text: "]"})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(raw) && raw[end] != '"' {
+				if raw[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(raw) {
+				return nil, invalidFilterError("unterminated string")
+			}
+			var text string
+			if err := json.Unmarshal([]byte(raw[i:end+1]), &text); err != nil {
+				return nil, invalidFilterError("invalid string " + raw[i:end+1])
+			}
+			tokens = append(tokens, lexeme{kind: tokenString, text: text})
+			i = end + 1
+		default:
+			end := i
+			for end < len(raw) && !strings.ContainsRune(" \t\n\r()[]\"", rune(raw[end])) {
+				end++
+			}
+			tokens = append(tokens, lexeme{kind: tokenWord, text: raw[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []lexeme
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() lexeme {
+	if p.done() {
+		return lexeme{kind: -1, text: "end of filter"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() lexeme {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) expect(kind tokenKind, text string) *Error {
+	if t := p.next(); t.kind != kind {
+		return invalidFilterError("expected " + text + " but found " + t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (expression, *Error) {
+	left, scimErr := p.parseAnd()
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	for p.peekKeyword("or") {
+		p.next()
+		right, scimErr := p.parseAnd()
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		left = logicalExpression{and: false, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expression, *Error) {
+	left, scimErr := p.parseNot()
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	for p.peekKeyword("and") {
+		p.next()
+		right, scimErr := p.parseNot()
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		left = logicalExpression{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (expression, *Error) {
+	if !p.peekKeyword("not") {
+		return p.parseAtom()
+	}
+	p.next()
+
+	if scimErr := p.expect(tokenOpen, "("); scimErr != nil {
+		return nil, scimErr
+	}
+	inner, scimErr := p.parseOr()
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	if scimErr := p.expect(tokenClose, ")"); scimErr != nil {
+		return nil, scimErr
+	}
+
+	return notExpression{inner: inner}, nil
+}
+
+func (p *parser) parseAtom() (expression, *Error) {
+	t := p.next()
+
+	if t.kind == tokenOpen {
+		inner, scimErr := p.parseOr()
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		if scimErr := p.expect(tokenClose, ")"); scimErr != nil {
+			return nil, scimErr
+		}
+		return inner, nil
+	}
+
+	if t.kind != tokenWord {
+		return nil, invalidFilterError("expected an attribute but found " + t.text)
+	}
+	path, ok := parseAttrPath(t.text)
+	if !ok {
+		return nil, invalidFilterError("invalid attribute " + t.text)
+	}
+
+	if p.peek().kind == tokenOpenBracket {
+		p.next()
+		if path.sub != "" {
+			return nil, invalidFilterError("invalid attribute " + t.text)
+		}
+		filter, scimErr := p.parseOr()
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		if scimErr := p.expect(tokenCloseBracket, "]"); scimErr != nil {
+			return nil, scimErr
+		}
+		return valuePathExpression{path: path, filter: filter}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenWord {
+		return nil, invalidFilterError("expected an operator but found " + op.text)
+	}
+
+	switch operator := strings.ToLower(op.text); operator {
+	case opPresent:
+		return compareExpression{path: path, op: operator}, nil
+	case opEqual, opNotEqual, opContains, opStartsWith, opEndsWith, opGreater, opGreaterOrEqual, opLess, opLessOrEqual:
+		value, scimErr := p.parseValue()
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		return compareExpression{path: path, op: operator, value: value}, nil
+	default:
+		return nil, invalidFilterError("unknown operator " + op.text)
+	}
+}
+
+func (p *parser) parseValue() (any, *Error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenString:
+		return t.text, nil
+	case t.kind != tokenWord:
+		return nil, invalidFilterError("expected a value but found " + t.text)
+	case t.text == "true":
+		return true, nil
+	case t.text == "false":
+		return false, nil
+	case t.text == "null":
+		return nil, nil
+	}
+
+	number, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, invalidFilterError("invalid value " + t.text)
+	}
+	return number, nil
+}
+
+func invalidFilterError(detail string) *Error {
+	return newBadRequestError(ErrorInvalidFilter, "invalid filter: "+detail)
+}
+
+// lookup reads an attribute of a resource, matching its name
+// case-insensitively as SCIM requires.
+func lookup(object map[string]any, name string) any {
+	if value, ok := object[name]; ok {
+		return value
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// lookupKey returns the key object stores the attribute under, or name
+// when it has none.
+func lookupKey(object map[string]any, name string) string {
+	if _, ok := object[name]; ok {
+		return name
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+func asList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
+
+// asBool accepts booleans and, as some identity providers send them, their
+// string forms.
+func asBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		flag, err := strconv.ParseBool(strings.ToLower(v))
+		return flag, err == nil
+	}
+	return false, false
+}
+
+func asTime(value any) (time.Time, bool) {
+	text, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	return parsed.UTC(), err == nil
+}
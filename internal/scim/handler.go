@@ -0,0 +1,393 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/requestinfo"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBodySize bounds SCIM request bodies; a group with every member of a
+// large organization is the biggest expected.
+const maxBodySize = 4 << 20
+
+type handler struct {
+	service ServiceInterface
+	checker middleware.PermissionCheckerInterface
+	tokens  token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateToken(ctx *gin.Context)
+	ListTokens(ctx *gin.Context)
+	RevokeToken(ctx *gin.Context)
+	GetServiceProviderConfig(ctx *gin.Context)
+	ListSchemas(ctx *gin.Context)
+	GetSchema(ctx *gin.Context)
+	ListResourceTypes(ctx *gin.Context)
+	GetResourceType(ctx *gin.Context)
+	CreateUser(ctx *gin.Context)
+	GetUser(ctx *gin.Context)
+	ListUsers(ctx *gin.Context)
+	ReplaceUser(ctx *gin.Context)
+	PatchUser(ctx *gin.Context)
+	DeleteUser(ctx *gin.Context)
+	CreateGroup(ctx *gin.Context)
+	GetGroup(ctx *gin.Context)
+	ListGroups(ctx *gin.Context)
+	ReplaceGroup(ctx *gin.Context)
+	PatchGroup(ctx *gin.Context)
+	DeleteGroup(ctx *gin.Context)
+}
+
+func NewHandler(
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		service: service,
+		checker: checker,
+		tokens:  tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	tokens := router.Group(
+		"/api/v1/orgs/current/scim/tokens",
+		middleware.Authenticate(h.tokens),
+		middleware.RequireUser(),
+		middleware.RequireOrg(),
+		middleware.RequirePermission(h.checker, org.ResourceOrg, org.ActionManage),
+	)
+	{
+		tokens.POST("", h.CreateToken)
+		tokens.GET("", h.ListTokens)
+		tokens.DELETE("/:id", h.RevokeToken)
+	}
+
+	// Discovery describes the server, not an organization, and is open.
+	discovery := router.Group("/scim/v2")
+	{
+		discovery.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+		discovery.GET("/Schemas", h.ListSchemas)
+		discovery.GET("/Schemas/:id", h.GetSchema)
+		discovery.GET("/ResourceTypes", h.ListResourceTypes)
+		discovery.GET("/ResourceTypes/:id", h.GetResourceType)
+	}
+
+	users := router.Group("/scim/v2/Users", h.authenticate())
+	{
+		users.POST("", h.CreateUser)
+		users.GET("", h.ListUsers)
+		users.GET("/:id", h.GetUser)
+		users.PUT("/:id", h.ReplaceUser)
+		users.PATCH("/:id", h.PatchUser)
+		users.DELETE("/:id", h.DeleteUser)
+	}
+
+	groups := router.Group("/scim/v2/Groups", h.authenticate())
+	{
+		groups.POST("", h.CreateGroup)
+		groups.GET("", h.ListGroups)
+		groups.GET("/:id", h.GetGroup)
+		groups.PUT("/:id", h.ReplaceGroup)
+		groups.PATCH("/:id", h.PatchGroup)
+		groups.DELETE("/:id", h.DeleteGroup)
+	}
+}
+
+// authenticate resolves the SCIM token to its organization, which every
+// query of the request is then scoped to.
+func (h *handler) authenticate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		raw, ok := middleware.BearerToken(ctx)
+		if !ok {
+			ctx.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			abort(ctx, newUnauthorizedError("missing bearer token"))
+			return
+		}
+
+		scimToken, scimErr := h.service.Authenticate(ctx.Request.Context(), raw)
+		if scimErr != nil {
+			ctx.Header("WWW-Authenticate", `Bearer realm="scim", error="invalid_token"`)
+			abort(ctx, scimErr)
+			return
+		}
+
+		reqCtx := database.WithTenant(ctx.Request.Context(), scimToken.GetOrgID())
+		reqCtx = requestinfo.WithActor(reqCtx, requestinfo.Actor{Type: requestinfo.ActorSCIM, ID: scimToken.GetID().String()})
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
+
+func (h *handler) CreateToken(ctx *gin.Context) {
+	var req CreateTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	userID, _ := middleware.CurrentUser(ctx)
+	res, restErr := h.service.CreateToken(ctx.Request.Context(), userID, req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) ListTokens(ctx *gin.Context) {
+	res, restErr := h.service.ListTokens(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RevokeToken(ctx *gin.Context) {
+	if restErr := h.service.RevokeToken(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) GetServiceProviderConfig(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, h.service.ServiceProviderConfig())
+}
+
+func (h *handler) ListSchemas(ctx *gin.Context) {
+	schemas := h.service.Schemas()
+	respond(ctx, http.StatusOK, listOf(schemas))
+}
+
+func (h *handler) GetSchema(ctx *gin.Context) {
+	for _, schema := range h.service.Schemas() {
+		if schema.ID == ctx.Param("id") {
+			respond(ctx, http.StatusOK, schema)
+			return
+		}
+	}
+	abort(ctx, newNotFoundError("schema "+ctx.Param("id")+" not found"))
+}
+
+func (h *handler) ListResourceTypes(ctx *gin.Context) {
+	resourceTypes := h.service.ResourceTypes()
+	respond(ctx, http.StatusOK, listOf(resourceTypes))
+}
+
+func (h *handler) GetResourceType(ctx *gin.Context) {
+	for _, resourceType := range h.service.ResourceTypes() {
+		if resourceType.ID == ctx.Param("id") {
+			respond(ctx, http.StatusOK, resourceType)
+			return
+		}
+	}
+	abort(ctx, newNotFoundError("resource type "+ctx.Param("id")+" not found"))
+}
+
+func (h *handler) CreateUser(ctx *gin.Context) {
+	var doc map[string]any
+	if scimErr := bind(ctx, &doc); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.CreateUser(ctx.Request.Context(), doc)
+	respondResource(ctx, http.StatusCreated, res, scimErr)
+}
+
+func (h *handler) GetUser(ctx *gin.Context) {
+	res, scimErr := h.service.GetUser(ctx.Request.Context(), ctx.Param("id"), projection(ctx))
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) ListUsers(ctx *gin.Context) {
+	var req ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abort(ctx, newBadRequestError(ErrorInvalidValue, "invalid query parameters"))
+		return
+	}
+
+	res, scimErr := h.service.ListUsers(ctx.Request.Context(), req)
+	if scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	respond(ctx, http.StatusOK, res)
+}
+
+func (h *handler) ReplaceUser(ctx *gin.Context) {
+	var doc map[string]any
+	if scimErr := bind(ctx, &doc); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.ReplaceUser(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match"), doc)
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) PatchUser(ctx *gin.Context) {
+	var req PatchRequest
+	if scimErr := bind(ctx, &req); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.PatchUser(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match"), req)
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) DeleteUser(ctx *gin.Context) {
+	if scimErr := h.service.DeleteUser(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match")); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) CreateGroup(ctx *gin.Context) {
+	var doc map[string]any
+	if scimErr := bind(ctx, &doc); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.CreateGroup(ctx.Request.Context(), doc)
+	respondResource(ctx, http.StatusCreated, res, scimErr)
+}
+
+func (h *handler) GetGroup(ctx *gin.Context) {
+	res, scimErr := h.service.GetGroup(ctx.Request.Context(), ctx.Param("id"), projection(ctx))
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) ListGroups(ctx *gin.Context) {
+	var req ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		abort(ctx, newBadRequestError(ErrorInvalidValue, "invalid query parameters"))
+		return
+	}
+
+	res, scimErr := h.service.ListGroups(ctx.Request.Context(), req)
+	if scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	respond(ctx, http.StatusOK, res)
+}
+
+func (h *handler) ReplaceGroup(ctx *gin.Context) {
+	var doc map[string]any
+	if scimErr := bind(ctx, &doc); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.ReplaceGroup(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match"), doc)
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) PatchGroup(ctx *gin.Context) {
+	var req PatchRequest
+	if scimErr := bind(ctx, &req); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	res, scimErr := h.service.PatchGroup(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match"), req)
+	respondResource(ctx, http.StatusOK, res, scimErr)
+}
+
+func (h *handler) DeleteGroup(ctx *gin.Context) {
+	if scimErr := h.service.DeleteGroup(ctx.Request.Context(), ctx.Param("id"), ctx.GetHeader("If-Match")); scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// bind decodes a JSON body whatever its declared media type, since clients
+// send both application/json and application/scim+json.
+func bind(ctx *gin.Context, v any) *Error {
+	decoder := json.NewDecoder(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxBodySize))
+	if err := decoder.Decode(v); err != nil {
+		return newBadRequestError(ErrorInvalidSyntax, "request body is not valid JSON")
+	}
+	return nil
+}
+
+func projection(ctx *gin.Context) Projection {
+	return Projection{
+		Attributes: splitAttributes(ctx.Query("attributes")),
+		Excluded:   splitAttributes(ctx.Query("excludedAttributes")),
+	}
+}
+
+// respondResource writes a single resource with its ETag, or nothing but
+// 304 when the client's copy, named by If-None-Match, is still current.
+func respondResource(ctx *gin.Context, status int, res *Resource, scimErr *Error) {
+	if scimErr != nil {
+		abort(ctx, scimErr)
+		return
+	}
+
+	ctx.Header("ETag", res.Version)
+	if status == http.StatusCreated {
+		if meta, ok := res.Doc["meta"].(map[string]any); ok {
+			ctx.Header("Location", meta["location"].(string))
+		}
+	}
+
+	if ctx.Request.Method == http.MethodGet {
+		for _, tag := range strings.Split(ctx.GetHeader("If-None-Match"), ",") {
+			if strings.TrimSpace(tag) == res.Version {
+				ctx.Status(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	respond(ctx, status, res.Doc)
+}
+
+func respond(ctx *gin.Context, status int, body any) {
+	ctx.Header("Content-Type", ContentType)
+	ctx.JSON(status, body)
+}
+
+func abort(ctx *gin.Context, scimErr *Error) {
+	ctx.Header("Content-Type", ContentType)
+	ctx.AbortWithStatusJSON(scimErr.Code(), scimErr)
+}
+
+// listOf wraps the discovery resources in a list response.
+func listOf[T any](resources []T) map[string]any {
+	return map[string]any{
+		"schemas":      []string{SchemaListResponse},
+		"totalResults": len(resources),
+		"startIndex":   1,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	}
+}
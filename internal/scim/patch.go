@@ -0,0 +1,363 @@
+package scim
+
+import (
+	"reflect"
+	"strings"
+)
+
+// PATCH operation types; clients vary in how they capitalize them.
+const (
+	patchAdd     = "add"
+	patchReplace = "replace"
+	patchRemove  = "remove"
+)
+
+// patchPath is the target of a PATCH operation: an attribute, optionally
+// narrowed to the values of a multi-valued attribute matching filter, and
+// to a sub-attribute of those.
+type patchPath struct {
+	attr   attrPath
+	filter expression
+	sub    string
+}
+
+func parsePatchPath(raw string) (*patchPath, *Error) {
+	raw = strings.TrimSpace(raw)
+
+	open := strings.IndexByte(raw, '[')
+	if open < 0 {
+		attr, ok := parseAttrPath(raw)
+		if !ok {
+			return nil, invalidPathError(raw)
+		}
+		return &patchPath{attr: attr}, nil
+	}
+
+	closing := strings.LastIndexByte(raw, ']')
+	if closing < open {
+		return nil, invalidPathError(raw)
+	}
+
+	attr, ok := parseAttrPath(raw[:open])
+	if !ok || attr.sub != "" {
+		return nil, invalidPathError(raw)
+	}
+
+	filter, scimErr := parseFilter(raw[open+1 : closing])
+	if scimErr != nil {
+		return nil, newBadRequestError(ErrorInvalidPath, scimErr.Detail)
+	}
+
+	path := &patchPath{attr: attr, filter: filter}
+	if rest := raw[closing+1:]; rest != "" {
+		sub, found := strings.CutPrefix(rest, ".")
+		if !found || !validAttrName(sub) {
+			return nil, invalidPathError(raw)
+		}
+		path.sub = sub
+	}
+
+	return path, nil
+}
+
+// applyPatch applies the operations of a PATCH request, in order, to the
+// document of a resource. The result is parsed and validated as a whole
+// afterwards, so the operations only need to get the shape right.
+func applyPatch(doc map[string]any, operations []PatchOperation, multiValued map[string]bool) *Error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != patchAdd && op != patchReplace && op != patchRemove {
+			return newBadRequestError(ErrorInvalidSyntax, "unsupported operation "+operation.Op)
+		}
+
+		if strings.TrimSpace(operation.Path) == "" {
+			if scimErr := applyWithoutPath(doc, op, operation.Value, multiValued); scimErr != nil {
+				return scimErr
+			}
+			continue
+		}
+
+		path, scimErr := parsePatchPath(operation.Path)
+		if scimErr != nil {
+			return scimErr
+		}
+		if scimErr := applyAt(doc, op, path, operation.Value, multiValued); scimErr != nil {
+			return scimErr
+		}
+	}
+
+	return nil
+}
+
+// applyWithoutPath handles an operation whose value holds the attributes
+// to change. Their names may themselves be paths, which is how some clients
+// send sub-attributes and extension attributes.
+func applyWithoutPath(doc map[string]any, op string, value any, multiValued map[string]bool) *Error {
+	if op == patchRemove {
+		return newBadRequestError(ErrorNoTarget, "remove requires a path")
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return newBadRequestError(ErrorInvalidSyntax, "an operation without a path takes an object")
+	}
+
+	for key, attrValue := range object {
+		if strings.EqualFold(key, SchemaEnterpriseUser) {
+			extension, ok := attrValue.(map[string]any)
+			if !ok {
+				return newBadRequestError(ErrorInvalidValue, key+" must be an object")
+			}
+			for name, extensionValue := range extension {
+				path := &patchPath{attr: attrPath{schema: SchemaEnterpriseUser, name: name}}
+				if scimErr := applyAt(doc, op, path, extensionValue, multiValued); scimErr != nil {
+					return scimErr
+				}
+			}
+			continue
+		}
+
+		path, scimErr := parsePatchPath(key)
+		if scimErr != nil {
+			return scimErr
+		}
+		if scimErr := applyAt(doc, op, path, attrValue, multiValued); scimErr != nil {
+			return scimErr
+		}
+	}
+
+	return nil
+}
+
+func applyAt(doc map[string]any, op string, path *patchPath, value any, multiValued map[string]bool) *Error {
+	switch strings.ToLower(path.attr.name) {
+	case "id", "meta", "schemas":
+		if path.attr.schema == "" {
+			return newBadRequestError(ErrorMutability, path.attr.name+" is read-only")
+		}
+	}
+
+	container := doc
+	if path.attr.schema != "" {
+		key := lookupKey(doc, path.attr.schema)
+		extension, _ := doc[key].(map[string]any)
+		if extension == nil {
+			if op == patchRemove {
+				return nil
+			}
+			extension = map[string]any{}
+			doc[key] = extension
+		}
+		container = extension
+	}
+
+	key := lookupKey(container, path.attr.name)
+	isMulti := path.attr.schema == "" && multiValued[strings.ToLower(path.attr.name)]
+
+	if path.filter != nil {
+		return applyToValues(container, key, op, path, value)
+	}
+
+	if path.attr.sub != "" {
+		return applyToSub(container, key, op, path.attr.sub, value)
+	}
+
+	switch op {
+	case patchRemove:
+		if isMulti && value != nil {
+			container[key] = removeValues(asList(container[key]), asList(value))
+			return nil
+		}
+		delete(container, key)
+	case patchAdd:
+		if isMulti {
+			container[key] = appendValues(asList(container[key]), asList(value))
+			return nil
+		}
+		container[key] = merge(container[key], value)
+	case patchReplace:
+		if isMulti {
+			container[key] = asList(value)
+			return nil
+		}
+		container[key] = merge(container[key], value)
+	}
+
+	return nil
+}
+
+// applyToSub changes a sub-attribute of a complex attribute, or of every
+// value of a multi-valued one.
+func applyToSub(container map[string]any, key, op, sub string, value any) *Error {
+	if list, ok := container[key].([]any); ok {
+		for _, item := range list {
+			if object, ok := item.(map[string]any); ok {
+				setSub(object, op, sub, value)
+			}
+		}
+		return nil
+	}
+
+	object, _ := container[key].(map[string]any)
+	if object == nil {
+		if op == patchRemove {
+			return nil
+		}
+		object = map[string]any{}
+		container[key] = object
+	}
+	setSub(object, op, sub, value)
+
+	return nil
+}
+
+func setSub(object map[string]any, op, sub string, value any) {
+	subKey := lookupKey(object, sub)
+	if op == patchRemove {
+		delete(object, subKey)
+		return
+	}
+	object[subKey] = value
+}
+
+// applyToValues changes the values of a multi-valued attribute that match
+// the path's filter. Adding or replacing a sub-attribute where none match
+// creates the value the filter describes, such as phoneNumbers[type eq
+// "work"].value on a user without one.
+func applyToValues(container map[string]any, key, op string, path *patchPath, value any) *Error {
+	list := asList(container[key])
+
+	kept := make([]any, 0, len(list))
+	matched := false
+	for _, item := range list {
+		object, ok := item.(map[string]any)
+		if !ok || !path.filter.matches(object) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == patchRemove && path.sub == "":
+			continue
+		case path.sub != "":
+			setSub(object, op, path.sub, value)
+		default:
+			if replacement, ok := value.(map[string]any); ok {
+				for k, v := range replacement {
+					object[lookupKey(object, k)] = v
+				}
+			}
+		}
+		kept = append(kept, object)
+	}
+
+	if !matched && op != patchRemove {
+		created, ok := valueFromFilter(path.filter)
+		if !ok {
+			return newBadRequestError(ErrorNoTarget, "no value matches "+path.attr.name)
+		}
+		if path.sub != "" {
+			created[path.sub] = value
+		} else if replacement, ok := value.(map[string]any); ok {
+			for k, v := range replacement {
+				created[k] = v
+			}
+		}
+		kept = append(kept, created)
+	}
+
+	container[key] = kept
+	return nil
+}
+
+// valueFromFilter builds the value an equality filter, or a conjunction of
+// them, selects.
+func valueFromFilter(filter expression) (map[string]any, bool) {
+	switch e := filter.(type) {
+	case compareExpression:
+		if e.op != opEqual || e.path.sub != "" {
+			return nil, false
+		}
+		return map[string]any{e.path.name: e.value}, true
+	case logicalExpression:
+		if !e.and {
+			return nil, false
+		}
+		left, ok := valueFromFilter(e.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := valueFromFilter(e.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+// merge combines complex values sub-attribute by sub-attribute, as add and
+// replace do; anything else is replaced.
+func merge(current, value any) any {
+	existing, ok := current.(map[string]any)
+	if !ok {
+		return value
+	}
+	update, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	for k, v := range update {
+		existing[lookupKey(existing, k)] = v
+	}
+	return existing
+}
+
+// appendValues adds the values not already present, comparing by their
+// value sub-attribute when they have one.
+func appendValues(list, values []any) []any {
+	for _, value := range values {
+		if !containsValue(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func removeValues(list, values []any) []any {
+	kept := make([]any, 0, len(list))
+	for _, item := range list {
+		if !containsValue(values, item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func containsValue(list []any, value any) bool {
+	id := valueID(value)
+	for _, item := range list {
+		if id != "" && strings.EqualFold(valueID(item), id) {
+			return true
+		}
+		if id == "" && reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func valueID(value any) string {
+	object, _ := value.(map[string]any)
+	id, _ := lookup(object, "value").(string)
+	return id
+}
+
+func invalidPathError(path string) *Error {
+	return newBadRequestError(ErrorInvalidPath, "invalid path "+path)
+}
@@ -0,0 +1,235 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+type columnKind int
+
+const (
+	// kindText compares case-insensitively, like most SCIM strings.
+	kindText columnKind = iota
+	// kindExactText compares as is, for caseExact attributes.
+	kindExactText
+	kindBoolean
+	kindTimestamp
+)
+
+// column is the SQL an attribute is filtered on. A column of a multi-valued
+// attribute stored apart sets within, an EXISTS wrapper taking the
+// condition.
+type column struct {
+	expr   string
+	kind   columnKind
+	within string
+}
+
+var enterpriseKey = strings.ToLower(SchemaEnterpriseUser) + ":"
+
+// userColumns maps the filterable attributes of a user. Every user has one
+// work email, which is also the primary one.
+var userColumns = map[string]column{
+	"id":                             {expr: "u.id::text"},
+	"username":                       {expr: "s.user_name"},
+	"externalid":                     {expr: "s.external_id", kind: kindExactText},
+	"name.givenname":                 {expr: "u.first_name"},
+	"name.familyname":                {expr: "u.last_name"},
+	"name.formatted":                 {expr: "(u.first_name || ' ' || u.last_name)"},
+	"displayname":                    {expr: "(u.first_name || ' ' || u.last_name)"},
+	"emails":                         {expr: "u.email"},
+	"emails.value":                   {expr: "u.email"},
+	"emails.type":                    {expr: "'work'"},
+	"emails.primary":                 {expr: "TRUE", kind: kindBoolean},
+	"phonenumbers":                   {expr: "u.phone"},
+	"phonenumbers.value":             {expr: "u.phone"},
+	"active":                         {expr: "s.active", kind: kindBoolean},
+	"meta.created":                   {expr: "s.created_at", kind: kindTimestamp},
+	"meta.lastmodified":              {expr: "s.updated_at", kind: kindTimestamp},
+	enterpriseKey + "employeenumber": {expr: "(s.extension->>'employeeNumber')"},
+	enterpriseKey + "costcenter":     {expr: "(s.extension->>'costCenter')"},
+	enterpriseKey + "organization":   {expr: "(s.extension->>'organization')"},
+	enterpriseKey + "division":       {expr: "(s.extension->>'division')"},
+	enterpriseKey + "department":     {expr: "(s.extension->>'department')"},
+	enterpriseKey + "manager":        {expr: "(s.extension->'manager'->>'value')"},
+	enterpriseKey + "manager.value":  {expr: "(s.extension->'manager'->>'value')"},
+}
+
+const groupMembersWithin = "EXISTS (SELECT 1 FROM scim_group_members gm WHERE gm.group_id = g.id AND %s)"
+
+var groupColumns = map[string]column{
+	"id":                {expr: "g.id::text"},
+	"displayname":       {expr: "g.display_name"},
+	"externalid":        {expr: "g.external_id", kind: kindExactText},
+	"members":           {expr: "gm.user_id::text", within: groupMembersWithin},
+	"members.value":     {expr: "gm.user_id::text", within: groupMembersWithin},
+	"meta.created":      {expr: "g.created_at", kind: kindTimestamp},
+	"meta.lastmodified": {expr: "g.updated_at", kind: kindTimestamp},
+}
+
+// compiler turns a filter into a SQL condition whose values are bound as
+// arguments following those already in args.
+type compiler struct {
+	columns map[string]column
+	args    []any
+}
+
+func compileFilter(expr expression, columns map[string]column, args []any) (string, []any, *Error) {
+	c := &compiler{columns: columns, args: args}
+
+	condition, scimErr := c.compile(expr, attrPath{})
+	if scimErr != nil {
+		return "", nil, scimErr
+	}
+
+	return condition, c.args, nil
+}
+
+func (c *compiler) bind(value any) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+// compile translates expr; parent is set inside a value filter, whose
+// attributes are relative to it.
+func (c *compiler) compile(expr expression, parent attrPath) (string, *Error) {
+	switch e := expr.(type) {
+	case logicalExpression:
+		left, scimErr := c.compile(e.left, parent)
+		if scimErr != nil {
+			return "", scimErr
+		}
+		right, scimErr := c.compile(e.right, parent)
+		if scimErr != nil {
+			return "", scimErr
+		}
+		operator := " OR "
+		if e.and {
+			operator = " AND "
+		}
+		return "(" + left + operator + right + ")", nil
+
+	case notExpression:
+		inner, scimErr := c.compile(e.inner, parent)
+		if scimErr != nil {
+			return "", scimErr
+		}
+		return "NOT COALESCE(" + inner + ", FALSE)", nil
+
+	case valuePathExpression:
+		if parent.name != "" {
+			return "", invalidFilterError("value filters cannot be nested")
+		}
+		return c.compile(e.filter, e.path)
+
+	case compareExpression:
+		path := e.path
+		if parent.name != "" {
+			if path.sub != "" || path.schema != "" {
+				return "", invalidFilterError("invalid attribute in value filter")
+			}
+			path = parent.child(path)
+		}
+		return c.compare(path, e.op, e.value)
+	}
+
+	return "", invalidFilterError("unsupported expression")
+}
+
+func (c *compiler) compare(path attrPath, op string, value any) (string, *Error) {
+	col, ok := c.columns[path.key()]
+	if !ok {
+		return "", invalidFilterError("unsupported attribute " + path.key())
+	}
+
+	condition, scimErr := c.condition(col, op, value)
+	if scimErr != nil {
+		return "", scimErr
+	}
+
+	if col.within != "" {
+		return fmt.Sprintf(col.within, condition), nil
+	}
+	return condition, nil
+}
+
+func (c *compiler) condition(col column, op string, value any) (string, *Error) {
+	if op == opPresent {
+		if col.kind == kindText || col.kind == kindExactText {
+			return "(" + col.expr + " IS NOT NULL AND " + col.expr + " <> '')", nil
+		}
+		return col.expr + " IS NOT NULL", nil
+	}
+
+	switch col.kind {
+	case kindBoolean:
+		flag, ok := value.(bool)
+		if !ok || (op != opEqual && op != opNotEqual) {
+			return "", invalidFilterError("boolean attributes only support eq and ne with true or false")
+		}
+		if op == opEqual {
+			return col.expr + " = " + c.bind(flag), nil
+		}
+		return col.expr + " IS DISTINCT FROM " + c.bind(flag), nil
+
+	case kindTimestamp:
+		at, ok := asTime(value)
+		if !ok {
+			return "", invalidFilterError("date attributes take an RFC 3339 date")
+		}
+		operator, ok := orderingOperators[op]
+		if !ok {
+			return "", invalidFilterError("date attributes do not support " + op)
+		}
+		return col.expr + " " + operator + " " + c.bind(at), nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		if value == nil && (op == opEqual || op == opNotEqual) {
+			if op == opEqual {
+				return "(" + col.expr + " IS NULL OR " + col.expr + " = '')", nil
+			}
+			return "(" + col.expr + " IS NOT NULL AND " + col.expr + " <> '')", nil
+		}
+		return "", invalidFilterError("string attributes take a string")
+	}
+
+	expr := col.expr
+	if col.kind == kindText {
+		expr = "lower(" + expr + ")"
+		text = strings.ToLower(text)
+	}
+
+	switch op {
+	case opContains:
+		return expr + " LIKE " + c.bind("%"+escapeLike(text)+"%"), nil
+	case opStartsWith:
+		return expr + " LIKE " + c.bind(escapeLike(text)+"%"), nil
+	case opEndsWith:
+		return expr + " LIKE " + c.bind("%"+escapeLike(text)), nil
+	case opNotEqual:
+		return expr + " IS DISTINCT FROM " + c.bind(text), nil
+	}
+
+	operator, ok := orderingOperators[op]
+	if !ok {
+		return "", invalidFilterError("unsupported operator " + op)
+	}
+	return expr + " " + operator + " " + c.bind(text), nil
+}
+
+var orderingOperators = map[string]string{
+	opEqual:          "=",
+	opNotEqual:       "<>",
+	opGreater:        ">",
+	opGreaterOrEqual: ">=",
+	opLess:           "<",
+	opLessOrEqual:    "<=",
+}
+
+// escapeLike makes LIKE match value literally, with the default backslash
+// escape.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,646 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrTokenNotFound    = errors.New("scim token not found")
+	ErrUserNotFound     = errors.New("scim user not found")
+	ErrGroupNotFound    = errors.New("scim group not found")
+	ErrUserNameTaken    = errors.New("scim user name already exists")
+	ErrDisplayNameTaken = errors.New("scim group display name already exists")
+	// ErrVersionConflict is returned when a resource changed since it was
+	// read.
+	ErrVersionConflict = errors.New("scim resource was modified concurrently")
+)
+
+const selectTokenColumns = `
+	SELECT id, org_id, name, token_hash, created_by, created_at, revoked_at
+	FROM scim_tokens`
+
+const selectUserColumns = `
+	SELECT u.id, u.email, u.password, u.phone, u.first_name, u.last_name, u.email_verified_at, u.deactivated_at, u.created_at, u.updated_at,
+		s.org_id, s.user_name, s.external_id, s.active, s.extension, s.version, s.created_at, s.updated_at
+	FROM scim_users s
+	JOIN users u ON u.id = s.user_id`
+
+const selectGroupColumns = `
+	SELECT g.id, g.org_id, g.display_name, g.external_id, g.version, g.created_at, g.updated_at
+	FROM scim_groups g`
+
+// Page selects a slice of the resources matching Filter, a condition whose
+// placeholders are bound to Args. An empty Filter matches everything and a
+// zero Limit only counts.
+type Page struct {
+	Filter string
+	Args   []any
+	Offset int
+	Limit  int
+}
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	CreateToken(ctx context.Context, token domain.SCIMTokenInterface) error
+	// FindTokenByHash looks across organizations, as a token is what tells
+	// which organization a SCIM request is for.
+	FindTokenByHash(ctx context.Context, tokenHash string) (domain.SCIMTokenInterface, error)
+	FindToken(ctx context.Context, id uuid.UUID) (domain.SCIMTokenInterface, error)
+	FindTokens(ctx context.Context) ([]domain.SCIMTokenInterface, error)
+	UpdateToken(ctx context.Context, token domain.SCIMTokenInterface) error
+
+	CreateUser(ctx context.Context, provisioned domain.SCIMUserInterface) error
+	FindUser(ctx context.Context, userID uuid.UUID) (UserRecord, error)
+	// FindUserPage returns the users of the page, oldest first, and how
+	// many match in all.
+	FindUserPage(ctx context.Context, page Page) ([]UserRecord, int, error)
+	// FindUserIDs returns which of the given users were provisioned into
+	// the organization.
+	FindUserIDs(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	// UpdateUser saves the user if it is still at the version before this
+	// change, and returns ErrVersionConflict otherwise.
+	UpdateUser(ctx context.Context, provisioned domain.SCIMUserInterface) error
+
+	CreateGroup(ctx context.Context, group domain.SCIMGroupInterface) error
+	FindGroup(ctx context.Context, id uuid.UUID) (domain.SCIMGroupInterface, error)
+	FindGroupPage(ctx context.Context, page Page) ([]domain.SCIMGroupInterface, int, error)
+	// UpdateGroup saves the group if it is still at the version before this
+	// change, and returns ErrVersionConflict otherwise.
+	UpdateGroup(ctx context.Context, group domain.SCIMGroupInterface) error
+	DeleteGroup(ctx context.Context, id uuid.UUID) error
+	// FindMembers returns the members of each group, in the order they
+	// were added.
+	FindMembers(ctx context.Context, groupIDs []uuid.UUID) (map[uuid.UUID][]Member, error)
+	AddMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+	RemoveMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) CreateToken(ctx context.Context, token domain.SCIMTokenInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO scim_tokens (id, org_id, name, token_hash, created_by, created_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		token.GetID(),
+		orgID,
+		token.GetName(),
+		token.GetTokenHash(),
+		token.GetCreatedBy(),
+		token.GetCreatedAt(),
+		token.GetRevokedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert scim token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindTokenByHash(ctx context.Context, tokenHash string) (domain.SCIMTokenInterface, error) {
+	query := selectTokenColumns + ` WHERE token_hash = $1`
+
+	return scanToken(r.db.GetQuerier(ctx).QueryRow(ctx, query, tokenHash))
+}
+
+func (r *repository) FindToken(ctx context.Context, id uuid.UUID) (domain.SCIMTokenInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectTokenColumns + ` WHERE id = $1 AND org_id = $2`
+
+	return scanToken(r.db.GetQuerier(ctx).QueryRow(ctx, query, id, orgID))
+}
+
+func (r *repository) FindTokens(ctx context.Context) ([]domain.SCIMTokenInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectTokenColumns + ` WHERE org_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]domain.SCIMTokenInterface, 0)
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *repository) UpdateToken(ctx context.Context, token domain.SCIMTokenInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE scim_tokens SET name = $3, revoked_at = $4 WHERE id = $1 AND org_id = $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, token.GetID(), orgID, token.GetName(), token.GetRevokedAt())
+	if err != nil {
+		return fmt.Errorf("failed to update scim token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) CreateUser(ctx context.Context, provisioned domain.SCIMUserInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	extension, err := json.Marshal(provisioned.GetExtension())
+	if err != nil {
+		return fmt.Errorf("failed to encode scim extension: %w", err)
+	}
+
+	query := `
+		INSERT INTO scim_users (user_id, org_id, user_name, external_id, active, extension, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		provisioned.GetUserID(),
+		orgID,
+		provisioned.GetUserName(),
+		provisioned.GetExternalID(),
+		provisioned.IsActive(),
+		extension,
+		provisioned.GetVersion(),
+		provisioned.GetCreatedAt(),
+		provisioned.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrUserNameTaken
+		}
+		return fmt.Errorf("failed to insert scim user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindUser(ctx context.Context, userID uuid.UUID) (UserRecord, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return UserRecord{}, err
+	}
+
+	query := selectUserColumns + ` WHERE s.user_id = $1 AND s.org_id = $2`
+
+	return scanUserRecord(r.db.GetQuerier(ctx).QueryRow(ctx, query, userID, orgID))
+}
+
+func (r *repository) FindUserPage(ctx context.Context, page Page) ([]UserRecord, int, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := pageCondition("s.org_id", orgID, page)
+
+	var total int
+	countQuery := `SELECT count(*) FROM scim_users s JOIN users u ON u.id = s.user_id` + where
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim users: %w", err)
+	}
+
+	records := make([]UserRecord, 0)
+	if page.Limit == 0 || total <= page.Offset {
+		return records, total, nil
+	}
+
+	query := selectUserColumns + where + fmt.Sprintf(` ORDER BY s.created_at, s.user_id OFFSET $%d LIMIT $%d`, len(args)+1, len(args)+2)
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, append(args, page.Offset, page.Limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query scim users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanUserRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, record)
+	}
+
+	return records, total, rows.Err()
+}
+
+func (r *repository) FindUserIDs(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT user_id FROM scim_users WHERE org_id = $1 AND user_id = ANY($2)`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim users: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[uuid.UUID]bool, len(userIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan scim user: %w", err)
+		}
+		found[id] = true
+	}
+
+	return found, rows.Err()
+}
+
+func (r *repository) UpdateUser(ctx context.Context, provisioned domain.SCIMUserInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	extension, err := json.Marshal(provisioned.GetExtension())
+	if err != nil {
+		return fmt.Errorf("failed to encode scim extension: %w", err)
+	}
+
+	query := `
+		UPDATE scim_users
+		SET user_name = $3, external_id = $4, active = $5, extension = $6, version = $7, updated_at = $8
+		WHERE user_id = $1 AND org_id = $2 AND version = $7 - 1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		provisioned.GetUserID(),
+		orgID,
+		provisioned.GetUserName(),
+		provisioned.GetExternalID(),
+		provisioned.IsActive(),
+		extension,
+		provisioned.GetVersion(),
+		provisioned.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrUserNameTaken
+		}
+		return fmt.Errorf("failed to update scim user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+func (r *repository) CreateGroup(ctx context.Context, group domain.SCIMGroupInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO scim_groups (id, org_id, display_name, external_id, version, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		group.GetID(),
+		orgID,
+		group.GetDisplayName(),
+		group.GetExternalID(),
+		group.GetVersion(),
+		group.GetCreatedAt(),
+		group.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrDisplayNameTaken
+		}
+		return fmt.Errorf("failed to insert scim group: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindGroup(ctx context.Context, id uuid.UUID) (domain.SCIMGroupInterface, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := selectGroupColumns + ` WHERE g.id = $1 AND g.org_id = $2`
+
+	return scanGroup(r.db.GetQuerier(ctx).QueryRow(ctx, query, id, orgID))
+}
+
+func (r *repository) FindGroupPage(ctx context.Context, page Page) ([]domain.SCIMGroupInterface, int, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where, args := pageCondition("g.org_id", orgID, page)
+
+	var total int
+	countQuery := `SELECT count(*) FROM scim_groups g` + where
+	if err := r.db.GetQuerier(ctx).QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim groups: %w", err)
+	}
+
+	groups := make([]domain.SCIMGroupInterface, 0)
+	if page.Limit == 0 || total <= page.Offset {
+		return groups, total, nil
+	}
+
+	query := selectGroupColumns + where + fmt.Sprintf(` ORDER BY g.created_at, g.id OFFSET $%d LIMIT $%d`, len(args)+1, len(args)+2)
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, append(args, page.Offset, page.Limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query scim groups: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, total, rows.Err()
+}
+
+func (r *repository) UpdateGroup(ctx context.Context, group domain.SCIMGroupInterface) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE scim_groups
+		SET display_name = $3, external_id = $4, version = $5, updated_at = $6
+		WHERE id = $1 AND org_id = $2 AND version = $5 - 1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		group.GetID(),
+		orgID,
+		group.GetDisplayName(),
+		group.GetExternalID(),
+		group.GetVersion(),
+		group.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrDisplayNameTaken
+		}
+		return fmt.Errorf("failed to update scim group: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrVersionConflict
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteGroup(ctx context.Context, id uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM scim_groups WHERE id = $1 AND org_id = $2`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scim group: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) FindMembers(ctx context.Context, groupIDs []uuid.UUID) (map[uuid.UUID][]Member, error) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT gm.group_id, gm.user_id, COALESCE(NULLIF(trim(u.first_name || ' ' || u.last_name), ''), s.user_name)
+		FROM scim_group_members gm
+		JOIN scim_users s ON s.user_id = gm.user_id
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.org_id = $1 AND gm.group_id = ANY($2)
+		ORDER BY gm.created_at, gm.user_id`
+
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, orgID, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID][]Member, len(groupIDs))
+	for rows.Next() {
+		var (
+			groupID uuid.UUID
+			member  Member
+		)
+		if err := rows.Scan(&groupID, &member.UserID, &member.Display); err != nil {
+			return nil, fmt.Errorf("failed to scan scim group member: %w", err)
+		}
+		members[groupID] = append(members[groupID], member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *repository) AddMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO scim_group_members (group_id, user_id, org_id, created_at)
+		SELECT $1, user_id, org_id, $4
+		FROM scim_users
+		WHERE org_id = $2 AND user_id = ANY($3)
+		ON CONFLICT DO NOTHING`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, groupID, orgID, userIDs, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert scim group members: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) RemoveMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM scim_group_members WHERE group_id = $1 AND org_id = $2 AND user_id = ANY($3)`
+
+	if _, err := r.db.GetQuerier(ctx).Exec(ctx, query, groupID, orgID, userIDs); err != nil {
+		return fmt.Errorf("failed to delete scim group members: %w", err)
+	}
+
+	return nil
+}
+
+// pageCondition scopes the page's filter to the organization, binding the
+// organization after the filter's own arguments.
+func pageCondition(orgColumn string, orgID uuid.UUID, page Page) (string, []any) {
+	args := append(append(make([]any, 0, len(page.Args)+3), page.Args...), orgID)
+
+	where := fmt.Sprintf(` WHERE %s = $%d`, orgColumn, len(args))
+	if page.Filter != "" {
+		where += ` AND (` + page.Filter + `)`
+	}
+
+	return where, args
+}
+
+func scanToken(row pgx.Row) (domain.SCIMTokenInterface, error) {
+	var (
+		id        uuid.UUID
+		orgID     uuid.UUID
+		name      string
+		tokenHash string
+		createdBy *uuid.UUID
+		createdAt time.Time
+		revokedAt *time.Time
+	)
+
+	err := row.Scan(&id, &orgID, &name, &tokenHash, &createdBy, &createdAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to scan scim token: %w", err)
+	}
+
+	return domain.RestoreSCIMToken(id, orgID, name, tokenHash, createdBy, createdAt, revokedAt), nil
+}
+
+func scanUserRecord(row pgx.Row) (UserRecord, error) {
+	var (
+		id              uuid.UUID
+		email           string
+		password        *string
+		phone           *string
+		firstName       string
+		lastName        string
+		emailVerifiedAt *time.Time
+		deactivatedAt   *time.Time
+		createdAt       time.Time
+		updatedAt       time.Time
+
+		orgID       uuid.UUID
+		userName    string
+		externalID  string
+		active      bool
+		extension   []byte
+		version     int64
+		provisioned time.Time
+		modified    time.Time
+	)
+
+	err := row.Scan(
+		&id, &email, &password, &phone, &firstName, &lastName, &emailVerifiedAt, &deactivatedAt, &createdAt, &updatedAt,
+		&orgID, &userName, &externalID, &active, &extension, &version, &provisioned, &modified,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return UserRecord{}, ErrUserNotFound
+		}
+		return UserRecord{}, fmt.Errorf("failed to scan scim user: %w", err)
+	}
+
+	attributes := map[string]any{}
+	if err := json.Unmarshal(extension, &attributes); err != nil {
+		return UserRecord{}, fmt.Errorf("failed to decode scim extension: %w", err)
+	}
+
+	var passwordHash, phoneNumber string
+	if password != nil {
+		passwordHash = *password
+	}
+	if phone != nil {
+		phoneNumber = *phone
+	}
+
+	return UserRecord{
+		Account: domain.Restore(id, email, passwordHash, phoneNumber, firstName, lastName, emailVerifiedAt, deactivatedAt, createdAt, updatedAt),
+		SCIM:    domain.RestoreSCIMUser(orgID, id, userName, externalID, active, attributes, version, provisioned, modified),
+	}, nil
+}
+
+func scanGroup(row pgx.Row) (domain.SCIMGroupInterface, error) {
+	var (
+		id          uuid.UUID
+		orgID       uuid.UUID
+		displayName string
+		externalID  string
+		version     int64
+		createdAt   time.Time
+		updatedAt   time.Time
+	)
+
+	err := row.Scan(&id, &orgID, &displayName, &externalID, &version, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to scan scim group: %w", err)
+	}
+
+	return domain.RestoreSCIMGroup(id, orgID, displayName, externalID, version, createdAt, updatedAt), nil
+}
@@ -0,0 +1,371 @@
+package scim
+
+import (
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/google/uuid"
+)
+
+// UserRecord is a provisioned user: the account and what SCIM keeps of it.
+type UserRecord struct {
+	Account domain.UserInterface
+	SCIM    domain.SCIMUserInterface
+}
+
+// GroupRecord is a group and, unless left out, its members.
+type GroupRecord struct {
+	Group   domain.SCIMGroupInterface
+	Members []Member
+}
+
+type Member struct {
+	UserID  uuid.UUID
+	Display string
+}
+
+// userAttributes is what a client asks a user to be. An empty password
+// leaves the current one alone.
+type userAttributes struct {
+	userName   string
+	externalID string
+	email      string
+	phone      string
+	givenName  string
+	familyName string
+	active     bool
+	password   string
+	extension  map[string]any
+}
+
+type groupAttributes struct {
+	displayName string
+	externalID  string
+	members     []uuid.UUID
+}
+
+// Multi-valued attributes of each resource type, by lower-case name. PATCH
+// appends to these rather than replacing them.
+var (
+	userMultiValued  = map[string]bool{"emails": true, "phonenumbers": true}
+	groupMultiValued = map[string]bool{"members": true}
+)
+
+// etag is the weak entity tag of a resource version.
+func etag(version int64) string {
+	return `W/"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func userDocument(record UserRecord, baseURL string) map[string]any {
+	account, provisioned := record.Account, record.SCIM
+	id := account.GetID().String()
+
+	doc := map[string]any{
+		"schemas":  []any{SchemaUser},
+		"id":       id,
+		"userName": provisioned.GetUserName(),
+		"name": map[string]any{
+			"givenName":  account.GetFirstName(),
+			"familyName": account.GetLastName(),
+			"formatted":  displayName(account),
+		},
+		"displayName": displayName(account),
+		"emails": []any{
+			map[string]any{"value": account.GetEmail(), "type": "work", "primary": true},
+		},
+		"active": provisioned.IsActive(),
+		"meta":   meta(ResourceTypeUser, baseURL+"/Users/"+id, provisioned.GetCreatedAt(), provisioned.GetUpdatedAt(), provisioned.GetVersion()),
+	}
+	if externalID := provisioned.GetExternalID(); externalID != "" {
+		doc["externalId"] = externalID
+	}
+	if phone := account.GetPhone(); phone != "" {
+		doc["phoneNumbers"] = []any{
+			map[string]any{"value": phone, "type": "work", "primary": true},
+		}
+	}
+	if extension := provisioned.GetExtension(); len(extension) > 0 {
+		doc["schemas"] = []any{SchemaUser, SchemaEnterpriseUser}
+		doc[SchemaEnterpriseUser] = extension
+	}
+
+	return doc
+}
+
+func groupDocument(record GroupRecord, baseURL string) map[string]any {
+	group := record.Group
+	id := group.GetID().String()
+
+	members := make([]any, 0, len(record.Members))
+	for _, member := range record.Members {
+		memberID := member.UserID.String()
+		members = append(members, map[string]any{
+			"value":   memberID,
+			"$ref":    baseURL + "/Users/" + memberID,
+			"display": member.Display,
+		})
+	}
+
+	doc := map[string]any{
+		"schemas":     []any{SchemaGroup},
+		"id":          id,
+		"displayName": group.GetDisplayName(),
+		"members":     members,
+		"meta":        meta(ResourceTypeGroup, baseURL+"/Groups/"+id, group.GetCreatedAt(), group.GetUpdatedAt(), group.GetVersion()),
+	}
+	if externalID := group.GetExternalID(); externalID != "" {
+		doc["externalId"] = externalID
+	}
+
+	return doc
+}
+
+func meta(resourceType, location string, created, lastModified time.Time, version int64) map[string]any {
+	return map[string]any{
+		"resourceType": resourceType,
+		"created":      created.UTC().Format(time.RFC3339),
+		"lastModified": lastModified.UTC().Format(time.RFC3339),
+		"location":     location,
+		"version":      etag(version),
+	}
+}
+
+func displayName(account domain.UserInterface) string {
+	return strings.TrimSpace(account.GetFirstName() + " " + account.GetLastName())
+}
+
+// parseUser reads the attributes a user document asks for. The primary
+// email, or else the first one, is the account's email; without any, a
+// userName shaped like an email stands in.
+func parseUser(doc map[string]any) (userAttributes, *Error) {
+	attrs := userAttributes{active: true}
+
+	var scimErr *Error
+	if attrs.userName, scimErr = stringAttr(doc, "userName"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if attrs.userName = strings.TrimSpace(attrs.userName); attrs.userName == "" {
+		return attrs, newBadRequestError(ErrorInvalidValue, "userName is required")
+	}
+	if attrs.externalID, scimErr = stringAttr(doc, "externalId"); scimErr != nil {
+		return attrs, scimErr
+	}
+
+	name, _ := lookup(doc, "name").(map[string]any)
+	if attrs.givenName, scimErr = stringAttr(name, "givenName"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if attrs.familyName, scimErr = stringAttr(name, "familyName"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if attrs.givenName == "" && attrs.familyName == "" {
+		formatted, _ := stringAttr(name, "formatted")
+		if formatted == "" {
+			formatted, _ = stringAttr(doc, "displayName")
+		}
+		attrs.givenName, attrs.familyName, _ = strings.Cut(strings.TrimSpace(formatted), " ")
+	}
+	attrs.givenName, attrs.familyName = strings.TrimSpace(attrs.givenName), strings.TrimSpace(attrs.familyName)
+
+	email := primaryValue(lookup(doc, "emails"))
+	if email == "" && strings.Contains(attrs.userName, "@") {
+		email = attrs.userName
+	}
+	if _, err := mail.ParseAddress(email); err != nil || email == "" {
+		return attrs, newBadRequestError(ErrorInvalidValue, "a valid email is required in emails or userName")
+	}
+	attrs.email = strings.ToLower(strings.TrimSpace(email))
+	attrs.phone = strings.TrimSpace(primaryValue(lookup(doc, "phoneNumbers")))
+
+	if value := lookup(doc, "active"); value != nil {
+		active, ok := asBool(value)
+		if !ok {
+			return attrs, newBadRequestError(ErrorInvalidValue, "active must be a boolean")
+		}
+		attrs.active = active
+	}
+
+	if attrs.password, scimErr = stringAttr(doc, "password"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if attrs.password != "" && (len(attrs.password) < 8 || len(attrs.password) > 72) {
+		return attrs, newBadRequestError(ErrorInvalidValue, "password must be between 8 and 72 characters")
+	}
+
+	if attrs.extension, scimErr = parseEnterpriseExtension(lookup(doc, SchemaEnterpriseUser)); scimErr != nil {
+		return attrs, scimErr
+	}
+
+	if len(attrs.userName) > 255 || len(attrs.externalID) > 255 || len(attrs.email) > 255 ||
+		len(attrs.givenName) > 255 || len(attrs.familyName) > 255 || len(attrs.phone) > 255 {
+		return attrs, newBadRequestError(ErrorInvalidValue, "attributes are limited to 255 characters")
+	}
+
+	return attrs, nil
+}
+
+// parseEnterpriseExtension keeps the known attributes of the enterprise
+// extension. A manager given as a bare id, as some clients send it, is
+// stored in its complex form.
+func parseEnterpriseExtension(value any) (map[string]any, *Error) {
+	extension := map[string]any{}
+	if value == nil {
+		return extension, nil
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, newBadRequestError(ErrorInvalidValue, SchemaEnterpriseUser+" must be an object")
+	}
+
+	for _, name := range enterpriseAttributes {
+		attr := lookup(object, name)
+		if attr == nil || attr == "" {
+			continue
+		}
+
+		if name != "manager" {
+			text, ok := attr.(string)
+			if !ok {
+				return nil, newBadRequestError(ErrorInvalidValue, name+" must be a string")
+			}
+			extension[name] = text
+			continue
+		}
+
+		switch manager := attr.(type) {
+		case string:
+			extension[name] = map[string]any{"value": manager}
+		case map[string]any:
+			id, _ := lookup(manager, "value").(string)
+			if id == "" {
+				continue
+			}
+			kept := map[string]any{"value": id}
+			if display, ok := lookup(manager, "displayName").(string); ok && display != "" {
+				kept["displayName"] = display
+			}
+			extension[name] = kept
+		default:
+			return nil, newBadRequestError(ErrorInvalidValue, "manager must be an object")
+		}
+	}
+
+	return extension, nil
+}
+
+func parseGroup(doc map[string]any) (groupAttributes, *Error) {
+	var attrs groupAttributes
+
+	var scimErr *Error
+	if attrs.displayName, scimErr = stringAttr(doc, "displayName"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if attrs.displayName = strings.TrimSpace(attrs.displayName); attrs.displayName == "" {
+		return attrs, newBadRequestError(ErrorInvalidValue, "displayName is required")
+	}
+	if attrs.externalID, scimErr = stringAttr(doc, "externalId"); scimErr != nil {
+		return attrs, scimErr
+	}
+	if len(attrs.displayName) > 255 || len(attrs.externalID) > 255 {
+		return attrs, newBadRequestError(ErrorInvalidValue, "attributes are limited to 255 characters")
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, item := range asList(lookup(doc, "members")) {
+		member, _ := item.(map[string]any)
+		value, _ := lookup(member, "value").(string)
+
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return attrs, newBadRequestError(ErrorInvalidValue, "member "+value+" is not a user id")
+		}
+		if !seen[id] {
+			seen[id] = true
+			attrs.members = append(attrs.members, id)
+		}
+	}
+
+	return attrs, nil
+}
+
+// stringAttr reads an optional string attribute.
+func stringAttr(object map[string]any, name string) (string, *Error) {
+	switch value := lookup(object, name).(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	default:
+		return "", newBadRequestError(ErrorInvalidValue, name+" must be a string")
+	}
+}
+
+// primaryValue returns the value of the primary entry of a multi-valued
+// attribute, or of its first entry when none is primary.
+func primaryValue(attr any) string {
+	var first string
+	for i, item := range asList(attr) {
+		entry, _ := item.(map[string]any)
+		value, _ := lookup(entry, "value").(string)
+		if primary, _ := asBool(lookup(entry, "primary")); primary {
+			return value
+		}
+		if i == 0 {
+			first = value
+		}
+	}
+	return first
+}
+
+// project applies the attributes and excludedAttributes parameters to the
+// top-level attributes of a document. schemas, id and meta are always
+// returned.
+func project(doc map[string]any, attributes, excluded []string) map[string]any {
+	if len(attributes) == 0 && len(excluded) == 0 {
+		return doc
+	}
+
+	names := func(list []string) map[string]bool {
+		set := map[string]bool{}
+		for _, raw := range list {
+			if path, ok := parseAttrPath(strings.TrimSpace(raw)); ok {
+				if path.schema != "" {
+					set[strings.ToLower(path.schema)] = true
+				} else {
+					set[strings.ToLower(path.name)] = true
+				}
+			} else if strings.HasPrefix(strings.ToLower(raw), "urn:") {
+				set[strings.ToLower(strings.TrimSpace(raw))] = true
+			}
+		}
+		return set
+	}
+	include, exclude := names(attributes), names(excluded)
+
+	projected := make(map[string]any, len(doc))
+	for key, value := range doc {
+		lower := strings.ToLower(key)
+		switch {
+		case lower == "schemas" || lower == "id" || lower == "meta":
+		case len(include) > 0 && !include[lower]:
+			continue
+		case exclude[lower]:
+			continue
+		}
+		projected[key] = value
+	}
+
+	return projected
+}
+
+// excludes tells whether the parameters leave attribute out, so it need
+// not be loaded.
+func excludes(attributes, excluded []string, attribute string) bool {
+	doc := project(map[string]any{attribute: true}, attributes, excluded)
+	_, kept := doc[attribute]
+	return !kept
+}
@@ -0,0 +1,289 @@
+package scim
+
+import "time"
+
+// ContentType is the media type of every SCIM request and response body.
+const ContentType = "application/scim+json"
+
+// Schema and message URNs of RFC 7643 and RFC 7644.
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Resource types served.
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// enterpriseAttributes are the attributes of the enterprise extension kept
+// for a user; others sent under it are dropped.
+var enterpriseAttributes = []string{"employeeNumber", "costCenter", "organization", "division", "department", "manager"}
+
+// Attribute describes one attribute of a schema, per RFC 7643 section 7.
+type Attribute struct {
+	Name           string      `json:"name"`
+	Type           string      `json:"type"`
+	MultiValued    bool        `json:"multiValued"`
+	Description    string      `json:"description,omitempty"`
+	Required       bool        `json:"required"`
+	CaseExact      bool        `json:"caseExact"`
+	Mutability     string      `json:"mutability"`
+	Returned       string      `json:"returned"`
+	Uniqueness     string      `json:"uniqueness"`
+	SubAttributes  []Attribute `json:"subAttributes,omitempty"`
+	ReferenceTypes []string    `json:"referenceTypes,omitempty"`
+}
+
+type SchemaResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Attributes  []Attribute  `json:"attributes"`
+	Meta        ResourceMeta `json:"meta"`
+}
+
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+type ResourceTypeResource struct {
+	Schemas          []string          `json:"schemas"`
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             ResourceMeta      `json:"meta"`
+}
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ServiceProviderConfigResource struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  ResourceMeta           `json:"meta"`
+}
+
+// ResourceMeta is the meta attribute of every resource.
+type ResourceMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+	Version      string     `json:"version,omitempty"`
+}
+
+func newServiceProviderConfig(baseURL string, maxResults int) ServiceProviderConfigResource {
+	return ServiceProviderConfigResource{
+		Schemas:        []string{SchemaServiceProviderConfig},
+		Patch:          Supported{Supported: true},
+		Bulk:           BulkSupport{Supported: false},
+		Filter:         FilterSupport{Supported: true, MaxResults: maxResults},
+		ChangePassword: Supported{Supported: true},
+		Sort:           Supported{Supported: false},
+		ETag:           Supported{Supported: true},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "A SCIM token issued to the organization, sent in the Authorization header.",
+			Primary:     true,
+		}},
+		Meta: ResourceMeta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+func newResourceTypes(baseURL string) []ResourceTypeResource {
+	return []ResourceTypeResource{
+		{
+			Schemas:          []string{SchemaResourceType},
+			ID:               ResourceTypeUser,
+			Name:             ResourceTypeUser,
+			Endpoint:         "/Users",
+			Description:      "User Account",
+			Schema:           SchemaUser,
+			SchemaExtensions: []SchemaExtension{{Schema: SchemaEnterpriseUser, Required: false}},
+			Meta:             ResourceMeta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/" + ResourceTypeUser},
+		},
+		{
+			Schemas:     []string{SchemaResourceType},
+			ID:          ResourceTypeGroup,
+			Name:        ResourceTypeGroup,
+			Endpoint:    "/Groups",
+			Description: "Group",
+			Schema:      SchemaGroup,
+			Meta:        ResourceMeta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/" + ResourceTypeGroup},
+		},
+	}
+}
+
+func newSchemas(baseURL string) []SchemaResource {
+	schemas := []SchemaResource{
+		{
+			ID:          SchemaUser,
+			Name:        ResourceTypeUser,
+			Description: "User Account",
+			Attributes: []Attribute{
+				stringAttribute("userName", "Unique identifier of the user, assigned by the identity provider.", true, "server"),
+				complexAttribute("name", "The user's name.", false,
+					stringAttribute("formatted", "The full name.", false, "none"),
+					stringAttribute("familyName", "The family name.", false, "none"),
+					stringAttribute("givenName", "The given name.", false, "none"),
+				),
+				stringAttribute("displayName", "The name displayed for the user; derived from name.", false, "none"),
+				{
+					Name: "emails", Type: "complex", MultiValued: true, Required: true,
+					Description: "Email addresses; the primary one is the address the user signs in with.",
+					Mutability:  "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []Attribute{
+						stringAttribute("value", "The email address.", true, "none"),
+						stringAttribute("type", "Always work.", false, "none"),
+						booleanAttribute("primary", "Always true."),
+					},
+				},
+				{
+					Name: "phoneNumbers", Type: "complex", MultiValued: true,
+					Description: "Phone numbers; only the primary one is kept.",
+					Mutability:  "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []Attribute{
+						stringAttribute("value", "The phone number.", false, "none"),
+						stringAttribute("type", "Always work.", false, "none"),
+						booleanAttribute("primary", "Always true."),
+					},
+				},
+				booleanAttribute("active", "Whether the user is a member of the organization."),
+				{
+					Name: "password", Type: "string", Description: "The user's password.",
+					Mutability: "writeOnly", Returned: "never", Uniqueness: "none",
+				},
+			},
+		},
+		{
+			ID:          SchemaEnterpriseUser,
+			Name:        "EnterpriseUser",
+			Description: "Enterprise User",
+			Attributes: []Attribute{
+				stringAttribute("employeeNumber", "Numeric or alphanumeric identifier assigned to the user.", false, "none"),
+				stringAttribute("costCenter", "Name of the cost center.", false, "none"),
+				stringAttribute("organization", "Name of the organization.", false, "none"),
+				stringAttribute("division", "Name of the division.", false, "none"),
+				stringAttribute("department", "Name of the department.", false, "none"),
+				complexAttribute("manager", "The user's manager.", false,
+					stringAttribute("value", "The id of the manager.", false, "none"),
+					stringAttribute("displayName", "The name of the manager.", false, "none"),
+				),
+			},
+		},
+		{
+			ID:          SchemaGroup,
+			Name:        ResourceTypeGroup,
+			Description: "Group",
+			Attributes: []Attribute{
+				stringAttribute("displayName", "Name of the group, unique within the organization.", true, "server"),
+				{
+					Name: "members", Type: "complex", MultiValued: true,
+					Description: "Users in the group.",
+					Mutability:  "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []Attribute{
+						{
+							Name: "value", Type: "string", Description: "The id of the member.",
+							Mutability: "immutable", Returned: "default", Uniqueness: "none",
+						},
+						{
+							Name: "$ref", Type: "reference", Description: "The URI of the member.",
+							Mutability: "immutable", Returned: "default", Uniqueness: "none",
+							ReferenceTypes: []string{ResourceTypeUser},
+						},
+						{
+							Name: "display", Type: "string", Description: "The name of the member.",
+							Mutability: "readOnly", Returned: "default", Uniqueness: "none",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for i := range schemas {
+		schemas[i].Schemas = []string{SchemaSchema}
+		schemas[i].Meta = ResourceMeta{ResourceType: "Schema", Location: baseURL + "/Schemas/" + schemas[i].ID}
+	}
+
+	return schemas
+}
+
+func stringAttribute(name, description string, required bool, uniqueness string) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        "string",
+		Description: description,
+		Required:    required,
+		Mutability:  "readWrite",
+		Returned:    "default",
+		Uniqueness:  uniqueness,
+	}
+}
+
+func booleanAttribute(name, description string) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        "boolean",
+		Description: description,
+		Mutability:  "readWrite",
+		Returned:    "default",
+		Uniqueness:  "none",
+	}
+}
+
+func complexAttribute(name, description string, required bool, subAttributes ...Attribute) Attribute {
+	return Attribute{
+		Name:          name,
+		Type:          "complex",
+		Description:   description,
+		Required:      required,
+		Mutability:    "readWrite",
+		Returned:      "default",
+		Uniqueness:    "none",
+		SubAttributes: subAttributes,
+	}
+}
@@ -0,0 +1,860 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+// passwordChangeSCIM is the PasswordChanged method of passwords set by an
+// identity provider.
+const passwordChangeSCIM = "scim"
+
+// Projection holds the attributes and excludedAttributes parameters.
+type Projection struct {
+	Attributes []string
+	Excluded   []string
+}
+
+type service struct {
+	config     config.SCIMConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	users      user.RepositoryInterface
+	sessions   auth.RepositoryInterface
+	orgs       org.RepositoryInterface
+	outbox     outbox.PublisherInterface
+	audit      audit.RecorderInterface
+}
+
+// ServiceInterface serves SCIM for the organization in the context. Only
+// accounts created over SCIM are visible to it: a user that signed up by
+// themselves is never taken over by an identity provider. ifMatch is the
+// If-Match header, empty when absent.
+type ServiceInterface interface {
+	CreateToken(ctx context.Context, createdBy uuid.UUID, req CreateTokenRequest) (*TokenResponse, *httperr.HttpError)
+	ListTokens(ctx context.Context) ([]TokenResponse, *httperr.HttpError)
+	RevokeToken(ctx context.Context, id string) *httperr.HttpError
+	// Authenticate returns the live token with the given raw value.
+	Authenticate(ctx context.Context, raw string) (domain.SCIMTokenInterface, *Error)
+
+	ServiceProviderConfig() ServiceProviderConfigResource
+	Schemas() []SchemaResource
+	ResourceTypes() []ResourceTypeResource
+
+	CreateUser(ctx context.Context, doc map[string]any) (*Resource, *Error)
+	GetUser(ctx context.Context, id string, projection Projection) (*Resource, *Error)
+	ListUsers(ctx context.Context, req ListRequest) (*ListResponse, *Error)
+	ReplaceUser(ctx context.Context, id, ifMatch string, doc map[string]any) (*Resource, *Error)
+	PatchUser(ctx context.Context, id, ifMatch string, req PatchRequest) (*Resource, *Error)
+	// DeleteUser deletes the account itself.
+	DeleteUser(ctx context.Context, id, ifMatch string) *Error
+
+	CreateGroup(ctx context.Context, doc map[string]any) (*Resource, *Error)
+	GetGroup(ctx context.Context, id string, projection Projection) (*Resource, *Error)
+	ListGroups(ctx context.Context, req ListRequest) (*ListResponse, *Error)
+	ReplaceGroup(ctx context.Context, id, ifMatch string, doc map[string]any) (*Resource, *Error)
+	PatchGroup(ctx context.Context, id, ifMatch string, req PatchRequest) (*Resource, *Error)
+	DeleteGroup(ctx context.Context, id, ifMatch string) *Error
+}
+
+func NewService(
+	config config.SCIMConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	users user.RepositoryInterface,
+	sessions auth.RepositoryInterface,
+	orgs org.RepositoryInterface,
+	outbox outbox.PublisherInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		users:      users,
+		sessions:   sessions,
+		orgs:       orgs,
+		outbox:     outbox,
+		audit:      audit,
+	}
+}
+
+func (s *service) CreateToken(ctx context.Context, createdBy uuid.UUID, req CreateTokenRequest) (*TokenResponse, *httperr.HttpError) {
+	orgID, err := database.RequireTenant(ctx)
+	if err != nil {
+		return nil, httperr.NewForbiddenError("an active organization is required")
+	}
+
+	token, raw := domain.NewSCIMToken(orgID, strings.TrimSpace(req.Name), &createdBy)
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateToken(ctx, token); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSCIMTokenCreate, audit.TargetSCIMToken, token.GetID(), map[string]string{"name": token.GetName()})
+	})
+	if err != nil {
+		slog.Error("failed to create scim token", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create scim token")
+	}
+
+	res := NewTokenResponse(token, raw)
+	return &res, nil
+}
+
+func (s *service) ListTokens(ctx context.Context) ([]TokenResponse, *httperr.HttpError) {
+	tokens, err := s.repository.FindTokens(ctx)
+	if err != nil {
+		slog.Error("failed to list scim tokens", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list scim tokens")
+	}
+
+	res := make([]TokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		res = append(res, NewTokenResponse(token, ""))
+	}
+	return res, nil
+}
+
+func (s *service) RevokeToken(ctx context.Context, id string) *httperr.HttpError {
+	tokenID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("scim token not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.repository.FindToken(ctx, tokenID)
+		if err != nil {
+			return err
+		}
+		if token.IsRevoked() {
+			return nil
+		}
+
+		token.Revoke()
+		if err := s.repository.UpdateToken(ctx, token); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSCIMTokenRevoke, audit.TargetSCIMToken, token.GetID(), nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return httperr.NewNotFoundError("scim token not found")
+		}
+		slog.Error("failed to revoke scim token", "error", err)
+		return httperr.NewInternalServerError("failed to revoke scim token")
+	}
+
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, raw string) (domain.SCIMTokenInterface, *Error) {
+	token, err := s.repository.FindTokenByHash(ctx, domain.HashOpaqueToken(raw))
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, newUnauthorizedError("invalid bearer token")
+		}
+		slog.Error("failed to find scim token", "error", err)
+		return nil, newServerError()
+	}
+	if token.IsRevoked() {
+		return nil, newUnauthorizedError("invalid bearer token")
+	}
+
+	return token, nil
+}
+
+func (s *service) ServiceProviderConfig() ServiceProviderConfigResource {
+	return newServiceProviderConfig(s.config.BaseURL, s.config.MaxResults)
+}
+
+func (s *service) Schemas() []SchemaResource {
+	return newSchemas(s.config.BaseURL)
+}
+
+func (s *service) ResourceTypes() []ResourceTypeResource {
+	return newResourceTypes(s.config.BaseURL)
+}
+
+func (s *service) CreateUser(ctx context.Context, doc map[string]any) (*Resource, *Error) {
+	attrs, scimErr := parseUser(doc)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	var record UserRecord
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		orgID, err := database.RequireTenant(ctx)
+		if err != nil {
+			return err
+		}
+
+		account, err := domain.New(attrs.email, attrs.password, attrs.phone, attrs.givenName, attrs.familyName)
+		if err != nil {
+			return err
+		}
+		account.SetDeactivated(!attrs.active)
+		if err := s.users.Create(ctx, account); err != nil {
+			return err
+		}
+
+		provisioned := domain.NewSCIMUser(orgID, account.GetID(), attrs.userName, attrs.externalID, attrs.active, attrs.extension)
+		if err := s.repository.CreateUser(ctx, provisioned); err != nil {
+			return err
+		}
+
+		if attrs.active {
+			if err := s.orgs.CreateMembership(ctx, domain.NewMembership(orgID, account.GetID())); err != nil {
+				return err
+			}
+		}
+
+		err = s.outbox.Publish(ctx, outbox.UserRegistered{
+			UserID:    account.GetID(),
+			Email:     account.GetEmail(),
+			FirstName: account.GetFirstName(),
+			LastName:  account.GetLastName(),
+		})
+		if err != nil {
+			return err
+		}
+
+		record = UserRecord{Account: account, SCIM: provisioned}
+		return s.record(ctx, audit.ActionSCIMUserCreate, audit.TargetUser, account.GetID(), describeUser(provisioned))
+	})
+	if err != nil {
+		return nil, s.userError("create", err)
+	}
+
+	slog.Info("scim user created", slog.String("user_id", record.Account.GetID().String()))
+	return s.userResource(record, Projection{}), nil
+}
+
+func (s *service) GetUser(ctx context.Context, id string, projection Projection) (*Resource, *Error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, newNotFoundError("user " + id + " not found")
+	}
+
+	record, err := s.repository.FindUser(ctx, userID)
+	if err != nil {
+		return nil, s.userError("get", err)
+	}
+
+	return s.userResource(record, projection), nil
+}
+
+func (s *service) ListUsers(ctx context.Context, req ListRequest) (*ListResponse, *Error) {
+	page, projection, scimErr := s.page(req, userColumns)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	records, total, err := s.repository.FindUserPage(ctx, page)
+	if err != nil {
+		slog.Error("failed to list scim users", "error", err)
+		return nil, newServerError()
+	}
+
+	resources := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		resources = append(resources, s.userResource(record, projection).Doc)
+	}
+
+	return newListResponse(total, page, resources), nil
+}
+
+func (s *service) ReplaceUser(ctx context.Context, id, ifMatch string, doc map[string]any) (*Resource, *Error) {
+	attrs, scimErr := parseUser(doc)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	return s.updateUser(ctx, id, ifMatch, func(UserRecord) (userAttributes, *Error) {
+		return attrs, nil
+	})
+}
+
+func (s *service) PatchUser(ctx context.Context, id, ifMatch string, req PatchRequest) (*Resource, *Error) {
+	if scimErr := validatePatch(req); scimErr != nil {
+		return nil, scimErr
+	}
+
+	return s.updateUser(ctx, id, ifMatch, func(record UserRecord) (userAttributes, *Error) {
+		doc := userDocument(record, s.config.BaseURL)
+		if scimErr := applyPatch(doc, req.Operations, userMultiValued); scimErr != nil {
+			return userAttributes{}, scimErr
+		}
+		return parseUser(doc)
+	})
+}
+
+// updateUser applies the attributes change computes from the current user.
+func (s *service) updateUser(
+	ctx context.Context,
+	id, ifMatch string,
+	change func(record UserRecord) (userAttributes, *Error),
+) (*Resource, *Error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, newNotFoundError("user " + id + " not found")
+	}
+
+	var (
+		record  UserRecord
+		scimErr *Error
+	)
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.repository.FindUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		record = found
+		if scimErr = checkVersion(ifMatch, record.SCIM.GetVersion()); scimErr != nil {
+			return nil
+		}
+
+		var attrs userAttributes
+		if attrs, scimErr = change(record); scimErr != nil {
+			return nil
+		}
+
+		return s.saveUser(ctx, record, attrs)
+	})
+	if err != nil {
+		return nil, s.userError("update", err)
+	}
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	slog.Info("scim user updated", slog.String("user_id", userID.String()))
+	return s.userResource(record, Projection{}), nil
+}
+
+// saveUser writes the account, then the SCIM attributes, and adds the user
+// to the organization or removes it when active changes. Deactivating the
+// user or setting its password ends every session it has.
+func (s *service) saveUser(ctx context.Context, record UserRecord, attrs userAttributes) error {
+	account, provisioned := record.Account, record.SCIM
+
+	if attrs.email != account.GetEmail() || attrs.phone != account.GetPhone() ||
+		attrs.givenName != account.GetFirstName() || attrs.familyName != account.GetLastName() {
+		account.UpdateProfile(attrs.email, attrs.phone, attrs.givenName, attrs.familyName)
+		if err := s.users.UpdateProfile(ctx, account); err != nil {
+			return err
+		}
+	}
+
+	if attrs.password != "" {
+		if err := account.ChangePassword(attrs.password); err != nil {
+			return err
+		}
+		if err := s.users.UpdatePassword(ctx, account); err != nil {
+			return err
+		}
+		if err := s.sessions.RevokeAllForUser(ctx, account.GetID()); err != nil {
+			return err
+		}
+		if err := s.outbox.Publish(ctx, outbox.PasswordChanged{UserID: account.GetID(), Method: passwordChangeSCIM}); err != nil {
+			return err
+		}
+	}
+
+	if attrs.active == account.IsDeactivated() {
+		account.SetDeactivated(!attrs.active)
+		if err := s.users.UpdateDeactivated(ctx, account); err != nil {
+			return err
+		}
+		if !attrs.active {
+			if err := s.sessions.RevokeAllForUser(ctx, account.GetID()); err != nil {
+				return err
+			}
+		}
+	}
+
+	wasActive := provisioned.IsActive()
+	provisioned.Update(attrs.userName, attrs.externalID, attrs.active, attrs.extension)
+	if err := s.repository.UpdateUser(ctx, provisioned); err != nil {
+		return err
+	}
+
+	switch {
+	case attrs.active && !wasActive:
+		if err := s.orgs.CreateMembership(ctx, domain.NewMembership(provisioned.GetOrgID(), account.GetID())); err != nil {
+			return err
+		}
+	case !attrs.active && wasActive:
+		if err := s.orgs.DeleteMembership(ctx, account.GetID()); err != nil && !errors.Is(err, org.ErrMembershipNotFound) {
+			return err
+		}
+	}
+
+	metadata := describeUser(provisioned)
+	if attrs.password != "" {
+		metadata["password_changed"] = "true"
+	}
+	return s.record(ctx, audit.ActionSCIMUserUpdate, audit.TargetUser, account.GetID(), metadata)
+}
+
+func (s *service) DeleteUser(ctx context.Context, id, ifMatch string) *Error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return newNotFoundError("user " + id + " not found")
+	}
+
+	var scimErr *Error
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		record, err := s.repository.FindUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if scimErr = checkVersion(ifMatch, record.SCIM.GetVersion()); scimErr != nil {
+			return nil
+		}
+
+		if err := s.users.Delete(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := s.outbox.Publish(ctx, outbox.UserDeleted{UserID: userID, Email: record.Account.GetEmail()}); err != nil {
+			return err
+		}
+
+		return s.record(ctx, audit.ActionUserDelete, audit.TargetUser, userID, map[string]string{"user_name": record.SCIM.GetUserName()})
+	})
+	if err != nil {
+		return s.userError("delete", err)
+	}
+	if scimErr != nil {
+		return scimErr
+	}
+
+	slog.Info("scim user deleted", slog.String("user_id", userID.String()))
+	return nil
+}
+
+func (s *service) CreateGroup(ctx context.Context, doc map[string]any) (*Resource, *Error) {
+	attrs, scimErr := parseGroup(doc)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	var record GroupRecord
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		orgID, err := database.RequireTenant(ctx)
+		if err != nil {
+			return err
+		}
+
+		if scimErr = s.checkMembers(ctx, attrs.members); scimErr != nil {
+			return nil
+		}
+
+		group := domain.NewSCIMGroup(orgID, attrs.displayName, attrs.externalID)
+		if err := s.repository.CreateGroup(ctx, group); err != nil {
+			return err
+		}
+		if len(attrs.members) > 0 {
+			if err := s.repository.AddMembers(ctx, group.GetID(), attrs.members); err != nil {
+				return err
+			}
+		}
+
+		members, err := s.repository.FindMembers(ctx, []uuid.UUID{group.GetID()})
+		if err != nil {
+			return err
+		}
+
+		record = GroupRecord{Group: group, Members: members[group.GetID()]}
+		return s.record(ctx, audit.ActionSCIMGroupCreate, audit.TargetGroup, group.GetID(), map[string]string{
+			"display_name": group.GetDisplayName(),
+			"members":      strconv.Itoa(len(attrs.members)),
+		})
+	})
+	if err != nil {
+		return nil, s.groupError("create", err)
+	}
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	slog.Info("scim group created", slog.String("group_id", record.Group.GetID().String()))
+	return s.groupResource(record, Projection{}), nil
+}
+
+func (s *service) GetGroup(ctx context.Context, id string, projection Projection) (*Resource, *Error) {
+	groupID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, newNotFoundError("group " + id + " not found")
+	}
+
+	group, err := s.repository.FindGroup(ctx, groupID)
+	if err != nil {
+		return nil, s.groupError("get", err)
+	}
+
+	record := GroupRecord{Group: group}
+	if !excludes(projection.Attributes, projection.Excluded, "members") {
+		members, err := s.repository.FindMembers(ctx, []uuid.UUID{groupID})
+		if err != nil {
+			return nil, s.groupError("get", err)
+		}
+		record.Members = members[groupID]
+	}
+
+	return s.groupResource(record, projection), nil
+}
+
+func (s *service) ListGroups(ctx context.Context, req ListRequest) (*ListResponse, *Error) {
+	page, projection, scimErr := s.page(req, groupColumns)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	groups, total, err := s.repository.FindGroupPage(ctx, page)
+	if err != nil {
+		slog.Error("failed to list scim groups", "error", err)
+		return nil, newServerError()
+	}
+
+	var members map[uuid.UUID][]Member
+	if len(groups) > 0 && !excludes(projection.Attributes, projection.Excluded, "members") {
+		ids := make([]uuid.UUID, 0, len(groups))
+		for _, group := range groups {
+			ids = append(ids, group.GetID())
+		}
+		if members, err = s.repository.FindMembers(ctx, ids); err != nil {
+			slog.Error("failed to list scim group members", "error", err)
+			return nil, newServerError()
+		}
+	}
+
+	resources := make([]map[string]any, 0, len(groups))
+	for _, group := range groups {
+		record := GroupRecord{Group: group, Members: members[group.GetID()]}
+		resources = append(resources, s.groupResource(record, projection).Doc)
+	}
+
+	return newListResponse(total, page, resources), nil
+}
+
+func (s *service) ReplaceGroup(ctx context.Context, id, ifMatch string, doc map[string]any) (*Resource, *Error) {
+	attrs, scimErr := parseGroup(doc)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	return s.updateGroup(ctx, id, ifMatch, func(GroupRecord) (groupAttributes, *Error) {
+		return attrs, nil
+	})
+}
+
+func (s *service) PatchGroup(ctx context.Context, id, ifMatch string, req PatchRequest) (*Resource, *Error) {
+	if scimErr := validatePatch(req); scimErr != nil {
+		return nil, scimErr
+	}
+
+	return s.updateGroup(ctx, id, ifMatch, func(record GroupRecord) (groupAttributes, *Error) {
+		doc := groupDocument(record, s.config.BaseURL)
+		if scimErr := applyPatch(doc, req.Operations, groupMultiValued); scimErr != nil {
+			return groupAttributes{}, scimErr
+		}
+		return parseGroup(doc)
+	})
+}
+
+// updateGroup applies the attributes change computes from the current
+// group, adding and removing members by difference.
+func (s *service) updateGroup(
+	ctx context.Context,
+	id, ifMatch string,
+	change func(record GroupRecord) (groupAttributes, *Error),
+) (*Resource, *Error) {
+	groupID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, newNotFoundError("group " + id + " not found")
+	}
+
+	var (
+		record  GroupRecord
+		scimErr *Error
+	)
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		group, err := s.repository.FindGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		if scimErr = checkVersion(ifMatch, group.GetVersion()); scimErr != nil {
+			return nil
+		}
+
+		members, err := s.repository.FindMembers(ctx, []uuid.UUID{groupID})
+		if err != nil {
+			return err
+		}
+
+		var attrs groupAttributes
+		if attrs, scimErr = change(GroupRecord{Group: group, Members: members[groupID]}); scimErr != nil {
+			return nil
+		}
+
+		current := make(map[uuid.UUID]bool, len(members[groupID]))
+		for _, member := range members[groupID] {
+			current[member.UserID] = true
+		}
+		var added, removed []uuid.UUID
+		for _, userID := range attrs.members {
+			if !current[userID] {
+				added = append(added, userID)
+			}
+			delete(current, userID)
+		}
+		for userID := range current {
+			removed = append(removed, userID)
+		}
+
+		if scimErr = s.checkMembers(ctx, added); scimErr != nil {
+			return nil
+		}
+
+		group.Update(attrs.displayName, attrs.externalID)
+		if err := s.repository.UpdateGroup(ctx, group); err != nil {
+			return err
+		}
+		if len(added) > 0 {
+			if err := s.repository.AddMembers(ctx, groupID, added); err != nil {
+				return err
+			}
+		}
+		if len(removed) > 0 {
+			if err := s.repository.RemoveMembers(ctx, groupID, removed); err != nil {
+				return err
+			}
+		}
+
+		if members, err = s.repository.FindMembers(ctx, []uuid.UUID{groupID}); err != nil {
+			return err
+		}
+
+		record = GroupRecord{Group: group, Members: members[groupID]}
+		return s.record(ctx, audit.ActionSCIMGroupUpdate, audit.TargetGroup, groupID, map[string]string{
+			"display_name":    group.GetDisplayName(),
+			"members_added":   strconv.Itoa(len(added)),
+			"members_removed": strconv.Itoa(len(removed)),
+		})
+	})
+	if err != nil {
+		return nil, s.groupError("update", err)
+	}
+	if scimErr != nil {
+		return nil, scimErr
+	}
+
+	slog.Info("scim group updated", slog.String("group_id", groupID.String()))
+	return s.groupResource(record, Projection{}), nil
+}
+
+func (s *service) DeleteGroup(ctx context.Context, id, ifMatch string) *Error {
+	groupID, err := uuid.Parse(id)
+	if err != nil {
+		return newNotFoundError("group " + id + " not found")
+	}
+
+	var scimErr *Error
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		group, err := s.repository.FindGroup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		if scimErr = checkVersion(ifMatch, group.GetVersion()); scimErr != nil {
+			return nil
+		}
+
+		if err := s.repository.DeleteGroup(ctx, groupID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionSCIMGroupDelete, audit.TargetGroup, groupID, map[string]string{"display_name": group.GetDisplayName()})
+	})
+	if err != nil {
+		return s.groupError("delete", err)
+	}
+	if scimErr != nil {
+		return scimErr
+	}
+
+	slog.Info("scim group deleted", slog.String("group_id", groupID.String()))
+	return nil
+}
+
+// checkMembers rejects users that were not provisioned into the
+// organization.
+func (s *service) checkMembers(ctx context.Context, userIDs []uuid.UUID) *Error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	known, err := s.repository.FindUserIDs(ctx, userIDs)
+	if err != nil {
+		slog.Error("failed to find scim users", "error", err)
+		return newServerError()
+	}
+	for _, userID := range userIDs {
+		if !known[userID] {
+			return newBadRequestError(ErrorInvalidValue, "member "+userID.String()+" is not a user of this organization")
+		}
+	}
+
+	return nil
+}
+
+// page turns the parameters of a list request into the page to fetch.
+func (s *service) page(req ListRequest, columns map[string]column) (Page, Projection, *Error) {
+	page := Page{Offset: max(req.StartIndex, 1) - 1, Limit: s.config.MaxResults}
+	if req.Count != nil {
+		page.Limit = min(max(*req.Count, 0), s.config.MaxResults)
+	}
+
+	if filter := strings.TrimSpace(req.Filter); filter != "" {
+		expr, scimErr := parseFilter(filter)
+		if scimErr != nil {
+			return Page{}, Projection{}, scimErr
+		}
+		if page.Filter, page.Args, scimErr = compileFilter(expr, columns, nil); scimErr != nil {
+			return Page{}, Projection{}, scimErr
+		}
+	}
+
+	projection := Projection{
+		Attributes: splitAttributes(req.Attributes),
+		Excluded:   splitAttributes(req.ExcludedAttributes),
+	}
+	return page, projection, nil
+}
+
+func (s *service) userResource(record UserRecord, projection Projection) *Resource {
+	doc := userDocument(record, s.config.BaseURL)
+	return &Resource{
+		Doc:     project(doc, projection.Attributes, projection.Excluded),
+		Version: etag(record.SCIM.GetVersion()),
+	}
+}
+
+func (s *service) groupResource(record GroupRecord, projection Projection) *Resource {
+	doc := groupDocument(record, s.config.BaseURL)
+	return &Resource{
+		Doc:     project(doc, projection.Attributes, projection.Excluded),
+		Version: etag(record.Group.GetVersion()),
+	}
+}
+
+func (s *service) userError(operation string, err error) *Error {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return newNotFoundError("user not found")
+	case errors.Is(err, user.ErrEmailAlreadyExists):
+		return newConflictError("a user with this email already exists")
+	case errors.Is(err, ErrUserNameTaken):
+		return newConflictError("a user with this userName already exists")
+	case errors.Is(err, ErrVersionConflict):
+		return newPreconditionFailedError()
+	}
+
+	slog.Error("failed to "+operation+" scim user", "error", err)
+	return newServerError()
+}
+
+func (s *service) groupError(operation string, err error) *Error {
+	switch {
+	case errors.Is(err, ErrGroupNotFound):
+		return newNotFoundError("group not found")
+	case errors.Is(err, ErrDisplayNameTaken):
+		return newConflictError("a group with this displayName already exists")
+	case errors.Is(err, ErrVersionConflict):
+		return newPreconditionFailedError()
+	}
+
+	slog.Error("failed to "+operation+" scim group", "error", err)
+	return newServerError()
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+func describeUser(provisioned domain.SCIMUserInterface) map[string]string {
+	return map[string]string{
+		"user_name":   provisioned.GetUserName(),
+		"external_id": provisioned.GetExternalID(),
+		"active":      strconv.FormatBool(provisioned.IsActive()),
+	}
+}
+
+func newListResponse(total int, page Page, resources []map[string]any) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   page.Offset + 1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func validatePatch(req PatchRequest) *Error {
+	if len(req.Operations) == 0 {
+		return newBadRequestError(ErrorInvalidSyntax, "Operations is required")
+	}
+	for _, schema := range req.Schemas {
+		if schema == SchemaPatchOp {
+			return nil
+		}
+	}
+	return newBadRequestError(ErrorInvalidSyntax, "schemas must include "+SchemaPatchOp)
+}
+
+// checkVersion compares an If-Match header against the resource version.
+// Strong and weak forms of a tag are taken alike.
+func checkVersion(ifMatch string, version int64) *Error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	current := strings.TrimPrefix(etag(version), "W/")
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == current {
+			return nil
+		}
+	}
+
+	return newPreconditionFailedError()
+}
+
+func splitAttributes(raw string) []string {
+	var attributes []string
+	for _, attribute := range strings.Split(raw, ",") {
+		if attribute = strings.TrimSpace(attribute); attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
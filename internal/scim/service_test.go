@@ -0,0 +1,157 @@
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"github.com/felipeversiane/auth-service/internal/auth"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/org"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+	"github.com/google/uuid"
+)
+
+func TestPatchUserInactiveDeactivatesAndEndsSessions(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, scimErr := env.service.PatchUser(context.Background(), env.userID.String(), "", PatchRequest{
+		Schemas:    []string{SchemaPatchOp},
+		Operations: []PatchOperation{{Op: "replace", Path: "active", Value: false}},
+	})
+	if scimErr != nil {
+		t.Fatalf("PatchUser() error = %v", scimErr)
+	}
+
+	if !env.record.Account.IsDeactivated() || env.users.DeactivationUpdates != 1 {
+		t.Fatalf("account deactivated = %t, saved %d times", env.record.Account.IsDeactivated(), env.users.DeactivationUpdates)
+	}
+	if env.sessions.revoked != 1 {
+		t.Fatalf("sessions revoked %d times, want 1", env.sessions.revoked)
+	}
+	if env.orgs.deleted != 1 {
+		t.Fatalf("memberships deleted %d times, want 1", env.orgs.deleted)
+	}
+
+	_, scimErr = env.service.PatchUser(context.Background(), env.userID.String(), "", PatchRequest{
+		Schemas:    []string{SchemaPatchOp},
+		Operations: []PatchOperation{{Op: "replace", Path: "active", Value: true}},
+	})
+	if scimErr != nil {
+		t.Fatalf("PatchUser() error = %v", scimErr)
+	}
+	if env.record.Account.IsDeactivated() || env.users.DeactivationUpdates != 2 || env.sessions.revoked != 1 {
+		t.Fatalf("reactivated: deactivated = %t, saved %d times, revoked %d times",
+			env.record.Account.IsDeactivated(), env.users.DeactivationUpdates, env.sessions.revoked)
+	}
+}
+
+func TestPatchUserPasswordEndsSessions(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, scimErr := env.service.PatchUser(context.Background(), env.userID.String(), "", PatchRequest{
+		Schemas:    []string{SchemaPatchOp},
+		Operations: []PatchOperation{{Op: "replace", Path: "password", Value: "a new passphrase"}},
+	})
+	if scimErr != nil {
+		t.Fatalf("PatchUser() error = %v", scimErr)
+	}
+
+	if !env.record.Account.ComparePassword("a new passphrase") || env.users.PasswordUpdates != 1 {
+		t.Fatalf("password saved %d times", env.users.PasswordUpdates)
+	}
+	if env.sessions.revoked != 1 {
+		t.Fatalf("sessions revoked %d times, want 1", env.sessions.revoked)
+	}
+	if env.record.Account.IsDeactivated() || env.users.DeactivationUpdates != 0 {
+		t.Fatal("a password change touched the deactivation")
+	}
+}
+
+type testEnv struct {
+	service  ServiceInterface
+	userID   uuid.UUID
+	record   UserRecord
+	users    *testutil.Users
+	sessions *fakeSessions
+	orgs     *fakeOrgs
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	account, err := domain.New("jane@example.com", "", "", "Jane", "Doe")
+	if err != nil {
+		t.Fatalf("domain.New() error = %v", err)
+	}
+	record := UserRecord{
+		Account: account,
+		SCIM:    domain.NewSCIMUser(uuid.New(), account.GetID(), "jane@example.com", "", true, nil),
+	}
+
+	env := &testEnv{
+		userID:   account.GetID(),
+		record:   record,
+		users:    testutil.NewUsers(account),
+		sessions: &fakeSessions{},
+		orgs:     &fakeOrgs{},
+	}
+	env.service = NewService(
+		config.SCIMConfig{},
+		testutil.DB{},
+		&fakeRepository{record: record},
+		env.users,
+		env.sessions,
+		env.orgs,
+		fakePublisher{},
+		&testutil.Recorder{},
+	)
+	return env
+}
+
+type fakeRepository struct {
+	RepositoryInterface
+	record UserRecord
+}
+
+func (r *fakeRepository) FindUser(_ context.Context, userID uuid.UUID) (UserRecord, error) {
+	if userID != r.record.Account.GetID() {
+		return UserRecord{}, ErrUserNotFound
+	}
+	return r.record, nil
+}
+
+func (r *fakeRepository) UpdateUser(context.Context, domain.SCIMUserInterface) error {
+	return nil
+}
+
+type fakeSessions struct {
+	auth.RepositoryInterface
+	revoked int
+}
+
+func (s *fakeSessions) RevokeAllForUser(context.Context, uuid.UUID) error {
+	s.revoked++
+	return nil
+}
+
+type fakeOrgs struct {
+	org.RepositoryInterface
+	deleted int
+}
+
+func (o *fakeOrgs) CreateMembership(context.Context, domain.MembershipInterface) error {
+	return nil
+}
+
+func (o *fakeOrgs) DeleteMembership(context.Context, uuid.UUID) error {
+	o.deleted++
+	return nil
+}
+
+type fakePublisher struct{}
+
+func (fakePublisher) Publish(context.Context, outbox.EventInterface) error {
+	return nil
+}
@@ -0,0 +1,9 @@
+package security
+
+import "go.uber.org/fx"
+
+var Module = fx.Options(
+	fx.Provide(
+		New,
+	),
+)
@@ -0,0 +1,68 @@
+package security
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	EventRefreshTokenReuse    = "refresh_token_reuse"
+	EventMFAChallengeLocked   = "mfa_challenge_locked"
+	EventMFARecoveryCodeUsed  = "mfa_recovery_code_used"
+	EventMFADisabled          = "mfa_disabled"
+	EventPasskeyCloneDetected = "passkey_clone_detected"
+	EventPasswordReset        = "password_reset"
+	EventLoginLockout         = "login_lockout"
+)
+
+type Event struct {
+	Type       string
+	UserID     string
+	Attributes map[string]string
+}
+
+type emitter struct {
+	counter metric.Int64Counter
+}
+
+type EmitterInterface interface {
+	Emit(ctx context.Context, event Event)
+}
+
+func New() (EmitterInterface, error) {
+	counter, err := otel.Meter("auth-service/security").Int64Counter(
+		"security.events",
+		metric.WithDescription("Number of security events emitted by type"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &emitter{
+		counter: counter,
+	}, nil
+}
+
+func (e *emitter) Emit(ctx context.Context, event Event) {
+	attrs := []attribute.KeyValue{
+		attribute.String("event.type", event.Type),
+		attribute.String("user.id", event.UserID),
+	}
+	logAttrs := []any{
+		slog.String("event", event.Type),
+		slog.String("user_id", event.UserID),
+	}
+	for key, value := range event.Attributes {
+		attrs = append(attrs, attribute.String(key, value))
+		logAttrs = append(logAttrs, slog.String(key, value))
+	}
+
+	slog.WarnContext(ctx, "security event", logAttrs...)
+	trace.SpanFromContext(ctx).AddEvent("security."+event.Type, trace.WithAttributes(attrs...))
+	e.counter.Add(ctx, 1, metric.WithAttributes(attribute.String("event.type", event.Type)))
+}
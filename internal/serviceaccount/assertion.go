@@ -0,0 +1,115 @@
+package serviceaccount
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	maxAssertionLifetime = 5 * time.Minute
+)
+
+// replayCache remembers assertion IDs until they expire so a captured
+// private_key_jwt assertion cannot be used twice against this instance.
+type replayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (c *replayCache) add(id string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for seenID, seenExpiresAt := range c.seen {
+		if now.After(seenExpiresAt) {
+			delete(c.seen, seenID)
+		}
+	}
+
+	if _, ok := c.seen[id]; ok {
+		return false
+	}
+
+	c.seen[id] = expiresAt
+	return true
+}
+
+// verifyAssertion checks an RFC 7523 client assertion against the public key
+// registered for the service account.
+func verifyAssertion(account domain.ServiceAccountInterface, assertion string, audiences []string, cache *replayCache) error {
+	if account.GetPublicKey() == "" {
+		return errors.New("service account has no registered public key")
+	}
+
+	publicKey, algorithm, err := keys.ParsePublicKey([]byte(account.GetPublicKey()))
+	if err != nil {
+		return err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (any, error) {
+		return publicKey, nil
+	},
+		jwt.WithValidMethods([]string{algorithm}),
+		jwt.WithIssuer(account.GetClientID()),
+		jwt.WithSubject(account.GetClientID()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid client assertion: %w", err)
+	}
+
+	if !audienceMatches(claims.Audience, audiences) {
+		return errors.New("client assertion has an invalid audience")
+	}
+
+	if claims.ID == "" {
+		return errors.New("client assertion must have a jti")
+	}
+
+	if time.Until(claims.ExpiresAt.Time) > maxAssertionLifetime {
+		return errors.New("client assertion lifetime is too long")
+	}
+
+	if !cache.add(account.GetClientID()+":"+claims.ID, claims.ExpiresAt.Time) {
+		return errors.New("client assertion was already used")
+	}
+
+	return nil
+}
+
+func audienceMatches(got jwt.ClaimStrings, accepted []string) bool {
+	for _, audience := range got {
+		for _, want := range accepted {
+			if audience == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AssertionIssuer reads the unverified iss claim so the matching service
+// account can be loaded before the signature is checked.
+func AssertionIssuer(assertion string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, claims); err != nil {
+		return "", fmt.Errorf("malformed client assertion: %w", err)
+	}
+	return claims.Issuer, nil
+}
@@ -0,0 +1,48 @@
+package serviceaccount
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type CreateRequest struct {
+	Name      string   `json:"name" binding:"required,max=255"`
+	Audiences []string `json:"audiences" binding:"required,min=1,dive,required"`
+	Scopes    []string `json:"scopes" binding:"dive,required"`
+	PublicKey string   `json:"public_key"`
+}
+
+type PublicKeyRequest struct {
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+type ServiceAccountResponse struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	ClientID     string     `json:"client_id"`
+	ClientSecret string     `json:"client_secret,omitempty"`
+	HasSecret    bool       `json:"has_secret"`
+	PublicKey    string     `json:"public_key,omitempty"`
+	Audiences    []string   `json:"audiences"`
+	Scopes       []string   `json:"scopes"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func NewServiceAccountResponse(account domain.ServiceAccountInterface, secret string) ServiceAccountResponse {
+	return ServiceAccountResponse{
+		ID:           account.GetID().String(),
+		Name:         account.GetName(),
+		ClientID:     account.GetClientID(),
+		ClientSecret: secret,
+		HasSecret:    account.HasSecret(),
+		PublicKey:    account.GetPublicKey(),
+		Audiences:    account.GetAudiences(),
+		Scopes:       account.GetScopes(),
+		DisabledAt:   account.GetDisabledAt(),
+		CreatedAt:    account.GetCreatedAt(),
+		UpdatedAt:    account.GetUpdatedAt(),
+	}
+}
@@ -0,0 +1,120 @@
+package serviceaccount
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Create(ctx *gin.Context)
+	Get(ctx *gin.Context)
+	List(ctx *gin.Context)
+	RotateSecret(ctx *gin.Context)
+	SetPublicKey(ctx *gin.Context)
+	Disable(ctx *gin.Context)
+}
+
+func NewHandler(adminConfig config.AdminConfig, service ServiceInterface) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	accounts := router.Group("/api/v1/service-accounts", middleware.RequireAdmin(h.adminConfig))
+	{
+		accounts.POST("", h.Create)
+		accounts.GET("", h.List)
+		accounts.GET("/:id", h.Get)
+		accounts.POST("/:id/secret", h.RotateSecret)
+		accounts.PUT("/:id/public-key", h.SetPublicKey)
+		accounts.POST("/:id/disable", h.Disable)
+	}
+}
+
+func (h *handler) Create(ctx *gin.Context) {
+	var req CreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.Create(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) Get(ctx *gin.Context) {
+	res, restErr := h.service.Get(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) List(ctx *gin.Context) {
+	res, restErr := h.service.List(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) RotateSecret(ctx *gin.Context) {
+	res, restErr := h.service.RotateSecret(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) SetPublicKey(ctx *gin.Context) {
+	var req PublicKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.SetPublicKey(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Disable(ctx *gin.Context) {
+	res, restErr := h.service.Disable(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
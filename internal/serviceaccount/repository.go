@@ -0,0 +1,151 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrServiceAccountNotFound = errors.New("service account not found")
+
+const selectColumns = `
+	SELECT id, name, client_id, secret_hash, public_key, audiences, scopes, disabled_at, created_at, updated_at
+	FROM service_accounts`
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, account domain.ServiceAccountInterface) error
+	Update(ctx context.Context, account domain.ServiceAccountInterface) error
+	FindByID(ctx context.Context, id uuid.UUID) (domain.ServiceAccountInterface, error)
+	FindByClientID(ctx context.Context, clientID string) (domain.ServiceAccountInterface, error)
+	FindAll(ctx context.Context) ([]domain.ServiceAccountInterface, error)
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, account domain.ServiceAccountInterface) error {
+	query := `
+		INSERT INTO service_accounts (
+			id, name, client_id, secret_hash, public_key, audiences, scopes, disabled_at, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		account.GetID(),
+		account.GetName(),
+		account.GetClientID(),
+		nullableString(account.GetSecretHash()),
+		nullableString(account.GetPublicKey()),
+		account.GetAudiences(),
+		account.GetScopes(),
+		account.GetDisabledAt(),
+		account.GetCreatedAt(),
+		account.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert service account: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) Update(ctx context.Context, account domain.ServiceAccountInterface) error {
+	query := `
+		UPDATE service_accounts
+		SET secret_hash = $2, public_key = $3, disabled_at = $4, updated_at = $5
+		WHERE id = $1`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		account.GetID(),
+		nullableString(account.GetSecretHash()),
+		nullableString(account.GetPublicKey()),
+		account.GetDisabledAt(),
+		account.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update service account: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindByID(ctx context.Context, id uuid.UUID) (domain.ServiceAccountInterface, error) {
+	return scanServiceAccount(r.db.GetQuerier(ctx).QueryRow(ctx, selectColumns+` WHERE id = $1`, id))
+}
+
+func (r *repository) FindByClientID(ctx context.Context, clientID string) (domain.ServiceAccountInterface, error) {
+	return scanServiceAccount(r.db.GetQuerier(ctx).QueryRow(ctx, selectColumns+` WHERE client_id = $1`, clientID))
+}
+
+func (r *repository) FindAll(ctx context.Context) ([]domain.ServiceAccountInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, selectColumns+` ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []domain.ServiceAccountInterface
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate service accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func scanServiceAccount(row pgx.Row) (domain.ServiceAccountInterface, error) {
+	var (
+		id                    uuid.UUID
+		name, clientID        string
+		secretHash, publicKey *string
+		audiences, scopes     []string
+		disabledAt            *time.Time
+		createdAt, updatedAt  time.Time
+	)
+
+	err := row.Scan(&id, &name, &clientID, &secretHash, &publicKey, &audiences, &scopes, &disabledAt, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to scan service account: %w", err)
+	}
+
+	return domain.RestoreServiceAccount(
+		id, name, clientID, valueOrEmpty(secretHash), valueOrEmpty(publicKey), audiences, scopes, disabledAt, createdAt, updatedAt,
+	), nil
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,265 @@
+package serviceaccount
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCredentials = errors.New("invalid service account credentials")
+
+type Credentials struct {
+	ClientID      string
+	ClientSecret  string
+	AssertionType string
+	Assertion     string
+}
+
+type service struct {
+	config     config.OAuthConfig
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	audit      audit.RecorderInterface
+	replay     *replayCache
+}
+
+type ServiceInterface interface {
+	Create(ctx context.Context, req CreateRequest) (*ServiceAccountResponse, *httperr.HttpError)
+	Get(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError)
+	List(ctx context.Context) ([]ServiceAccountResponse, *httperr.HttpError)
+	RotateSecret(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError)
+	SetPublicKey(ctx context.Context, id string, req PublicKeyRequest) (*ServiceAccountResponse, *httperr.HttpError)
+	Disable(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError)
+	Authenticate(ctx context.Context, credentials Credentials) (domain.ServiceAccountInterface, error)
+}
+
+func NewService(
+	config config.OAuthConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:     config,
+		db:         db,
+		repository: repository,
+		audit:      audit,
+		replay:     newReplayCache(),
+	}
+}
+
+func (s *service) Create(ctx context.Context, req CreateRequest) (*ServiceAccountResponse, *httperr.HttpError) {
+	if req.PublicKey != "" {
+		if _, _, err := keys.ParsePublicKey([]byte(req.PublicKey)); err != nil {
+			return nil, invalidPublicKeyError()
+		}
+	}
+
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	account, secret := domain.NewServiceAccount(strings.TrimSpace(req.Name), req.Audiences, scopes, req.PublicKey)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Create(ctx, account); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionServiceAccountCreate, account)
+	})
+	if err != nil {
+		slog.Error("failed to create service account", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create service account")
+	}
+
+	slog.Info("service account created", slog.String("client_id", account.GetClientID()))
+
+	res := NewServiceAccountResponse(account, secret)
+	return &res, nil
+}
+
+func (s *service) Get(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError) {
+	account, restErr := s.find(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewServiceAccountResponse(account, "")
+	return &res, nil
+}
+
+func (s *service) List(ctx context.Context) ([]ServiceAccountResponse, *httperr.HttpError) {
+	accounts, err := s.repository.FindAll(ctx)
+	if err != nil {
+		slog.Error("failed to list service accounts", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list service accounts")
+	}
+
+	res := make([]ServiceAccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		res = append(res, NewServiceAccountResponse(account, ""))
+	}
+
+	return res, nil
+}
+
+func (s *service) RotateSecret(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError) {
+	account, restErr := s.find(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	secret := account.RotateSecret()
+	if restErr := s.save(ctx, account, audit.ActionServiceAccountRotate); restErr != nil {
+		return nil, restErr
+	}
+
+	slog.Info("service account secret rotated", slog.String("client_id", account.GetClientID()))
+
+	res := NewServiceAccountResponse(account, secret)
+	return &res, nil
+}
+
+func (s *service) SetPublicKey(ctx context.Context, id string, req PublicKeyRequest) (*ServiceAccountResponse, *httperr.HttpError) {
+	if _, _, err := keys.ParsePublicKey([]byte(req.PublicKey)); err != nil {
+		return nil, invalidPublicKeyError()
+	}
+
+	account, restErr := s.find(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	account.SetPublicKey(req.PublicKey)
+	if restErr := s.save(ctx, account, audit.ActionServiceAccountPublicKey); restErr != nil {
+		return nil, restErr
+	}
+
+	slog.Info("service account public key rotated", slog.String("client_id", account.GetClientID()))
+
+	res := NewServiceAccountResponse(account, "")
+	return &res, nil
+}
+
+func (s *service) Disable(ctx context.Context, id string) (*ServiceAccountResponse, *httperr.HttpError) {
+	account, restErr := s.find(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	if !account.IsDisabled() {
+		account.Disable()
+		if restErr := s.save(ctx, account, audit.ActionServiceAccountDisable); restErr != nil {
+			return nil, restErr
+		}
+		slog.Info("service account disabled", slog.String("client_id", account.GetClientID()))
+	}
+
+	res := NewServiceAccountResponse(account, "")
+	return &res, nil
+}
+
+// Authenticate verifies client_secret_basic, client_secret_post or
+// private_key_jwt credentials. Every failure maps to ErrInvalidCredentials.
+func (s *service) Authenticate(ctx context.Context, credentials Credentials) (domain.ServiceAccountInterface, error) {
+	clientID := credentials.ClientID
+	if credentials.Assertion != "" {
+		if credentials.AssertionType != ClientAssertionTypeJWTBearer {
+			return nil, ErrInvalidCredentials
+		}
+		issuer, err := AssertionIssuer(credentials.Assertion)
+		if err != nil || (clientID != "" && clientID != issuer) {
+			return nil, ErrInvalidCredentials
+		}
+		clientID = issuer
+	}
+
+	if clientID == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	account, err := s.repository.FindByClientID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, ErrServiceAccountNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if account.IsDisabled() {
+		return nil, ErrInvalidCredentials
+	}
+
+	if credentials.Assertion != "" {
+		issuer := strings.TrimSuffix(s.config.Issuer, "/")
+		if err := verifyAssertion(account, credentials.Assertion, []string{issuer, issuer + "/token"}, s.replay); err != nil {
+			slog.Warn("service account assertion rejected", slog.String("client_id", clientID), "error", err)
+			return nil, ErrInvalidCredentials
+		}
+		return account, nil
+	}
+
+	if !account.CompareSecret(credentials.ClientSecret) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return account, nil
+}
+
+func (s *service) find(ctx context.Context, id string) (domain.ServiceAccountInterface, *httperr.HttpError) {
+	accountID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("service account not found")
+	}
+
+	account, err := s.repository.FindByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, ErrServiceAccountNotFound) {
+			return nil, httperr.NewNotFoundError("service account not found")
+		}
+		slog.Error("failed to load service account", "error", err)
+		return nil, httperr.NewInternalServerError("failed to load service account")
+	}
+
+	return account, nil
+}
+
+// save stores the account and audits the change that was made to it.
+func (s *service) save(ctx context.Context, account domain.ServiceAccountInterface, action string) *httperr.HttpError {
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Update(ctx, account); err != nil {
+			return err
+		}
+		return s.record(ctx, action, account)
+	})
+	if err != nil {
+		slog.Error("failed to update service account", "error", err)
+		return httperr.NewInternalServerError("failed to update service account")
+	}
+	return nil
+}
+
+func (s *service) record(ctx context.Context, action string, account domain.ServiceAccountInterface) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: audit.TargetServiceAccount,
+		TargetID:   account.GetID().String(),
+		Metadata:   map[string]string{"client_id": account.GetClientID()},
+	})
+}
+
+func invalidPublicKeyError() *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: "public_key", Message: "must be a PEM encoded RSA (2048+), P-256 or Ed25519 public key"},
+	})
+}
@@ -0,0 +1,280 @@
+package serviceaccount
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testIssuer = "https://auth.example.com/"
+
+type fakeRepository struct {
+	RepositoryInterface
+	accounts []domain.ServiceAccountInterface
+}
+
+func (r *fakeRepository) FindByClientID(_ context.Context, clientID string) (domain.ServiceAccountInterface, error) {
+	for _, account := range r.accounts {
+		if account.GetClientID() == clientID {
+			return account, nil
+		}
+	}
+	return nil, ErrServiceAccountNotFound
+}
+
+func newTestService(accounts ...domain.ServiceAccountInterface) ServiceInterface {
+	return NewService(config.OAuthConfig{Issuer: testIssuer}, nil, &fakeRepository{accounts: accounts}, nil)
+}
+
+// newKeyPair returns a signer and its public key in the PEM form service
+// accounts register.
+func newKeyPair(t *testing.T) (crypto.Signer, string) {
+	t.Helper()
+
+	signer, err := keys.GenerateKey(keys.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("keys.GenerateKey() error = %v", err)
+	}
+	publicKey, err := keys.MarshalPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("keys.MarshalPublicKey() error = %v", err)
+	}
+	return signer, string(publicKey)
+}
+
+func sign(t *testing.T, signer crypto.Signer, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(signer)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return assertion
+}
+
+func TestAuthenticateWithSecret(t *testing.T) {
+	account, secret := domain.NewServiceAccount("reports", nil, nil, "")
+	disabled, disabledSecret := domain.NewServiceAccount("legacy", nil, nil, "")
+	disabled.Disable()
+	service := newTestService(account, disabled)
+
+	tests := []struct {
+		name        string
+		credentials Credentials
+		ok          bool
+	}{
+		{"right secret", Credentials{ClientID: account.GetClientID(), ClientSecret: secret}, true},
+		{"wrong secret", Credentials{ClientID: account.GetClientID(), ClientSecret: secret + "x"}, false},
+		{"empty secret", Credentials{ClientID: account.GetClientID()}, false},
+		{"unknown client", Credentials{ClientID: uuid.NewString(), ClientSecret: secret}, false},
+		{"no client", Credentials{ClientSecret: secret}, false},
+		{"disabled account", Credentials{ClientID: disabled.GetClientID(), ClientSecret: disabledSecret}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := service.Authenticate(context.Background(), tt.credentials)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Authenticate() error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if found.GetID() != account.GetID() {
+				t.Fatalf("Authenticate() = %s, want %s", found.GetClientID(), account.GetClientID())
+			}
+		})
+	}
+}
+
+func TestAuthenticateRotatedSecret(t *testing.T) {
+	account, old := domain.NewServiceAccount("reports", nil, nil, "")
+	secret := account.RotateSecret()
+	service := newTestService(account)
+
+	if _, err := service.Authenticate(context.Background(), Credentials{ClientID: account.GetClientID(), ClientSecret: old}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate(old secret) error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := service.Authenticate(context.Background(), Credentials{ClientID: account.GetClientID(), ClientSecret: secret}); err != nil {
+		t.Fatalf("Authenticate(new secret) error = %v", err)
+	}
+}
+
+func TestAuthenticateWithAssertion(t *testing.T) {
+	signer, publicKey := newKeyPair(t)
+	other, _ := newKeyPair(t)
+
+	tests := []struct {
+		name string
+		// edit changes the valid claims, credentials or account under test.
+		edit func(claims *jwt.RegisteredClaims, credentials *Credentials, account domain.ServiceAccountInterface) crypto.Signer
+		ok   bool
+	}{
+		{name: "valid", ok: true},
+		{
+			name: "token endpoint audience",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.Audience = jwt.ClaimStrings{"https://auth.example.com/token"}
+				return nil
+			},
+			ok: true,
+		},
+		{
+			name: "matching client_id",
+			edit: func(_ *jwt.RegisteredClaims, credentials *Credentials, account domain.ServiceAccountInterface) crypto.Signer {
+				credentials.ClientID = account.GetClientID()
+				return nil
+			},
+			ok: true,
+		},
+		{
+			name: "another client_id",
+			edit: func(_ *jwt.RegisteredClaims, credentials *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				credentials.ClientID = uuid.NewString()
+				return nil
+			},
+		},
+		{
+			name: "another assertion type",
+			edit: func(_ *jwt.RegisteredClaims, credentials *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				credentials.AssertionType = "urn:ietf:params:oauth:client-assertion-type:saml2-bearer"
+				return nil
+			},
+		},
+		{
+			name: "signed with another key",
+			edit: func(*jwt.RegisteredClaims, *Credentials, domain.ServiceAccountInterface) crypto.Signer {
+				return other
+			},
+		},
+		{
+			name: "no registered key",
+			edit: func(_ *jwt.RegisteredClaims, _ *Credentials, account domain.ServiceAccountInterface) crypto.Signer {
+				account.SetPublicKey("")
+				return nil
+			},
+		},
+		{
+			name: "subject is not the client",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.Subject = "someone-else"
+				return nil
+			},
+		},
+		{
+			name: "another audience",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.Audience = jwt.ClaimStrings{"https://api.example.com"}
+				return nil
+			},
+		},
+		{
+			name: "no jti",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.ID = ""
+				return nil
+			},
+		},
+		{
+			name: "expired",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return nil
+			},
+		},
+		{
+			name: "no expiry",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.ExpiresAt = nil
+				return nil
+			},
+		},
+		{
+			name: "lifetime too long",
+			edit: func(claims *jwt.RegisteredClaims, _ *Credentials, _ domain.ServiceAccountInterface) crypto.Signer {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+				return nil
+			},
+		},
+		{
+			name: "disabled account",
+			edit: func(_ *jwt.RegisteredClaims, _ *Credentials, account domain.ServiceAccountInterface) crypto.Signer {
+				account.Disable()
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, _ := domain.NewServiceAccount("reports", nil, nil, publicKey)
+			service := newTestService(account)
+
+			claims := jwt.RegisteredClaims{
+				Issuer:    account.GetClientID(),
+				Subject:   account.GetClientID(),
+				Audience:  jwt.ClaimStrings{"https://auth.example.com"},
+				ID:        uuid.NewString(),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			}
+			credentials := Credentials{AssertionType: ClientAssertionTypeJWTBearer}
+			key := signer
+			if tt.edit != nil {
+				if replaced := tt.edit(&claims, &credentials, account); replaced != nil {
+					key = replaced
+				}
+			}
+			credentials.Assertion = sign(t, key, claims)
+
+			found, err := service.Authenticate(context.Background(), credentials)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("Authenticate() error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if found.GetID() != account.GetID() {
+				t.Fatalf("Authenticate() = %s, want %s", found.GetClientID(), account.GetClientID())
+			}
+		})
+	}
+}
+
+func TestAuthenticateRefusesReplayedAssertion(t *testing.T) {
+	signer, publicKey := newKeyPair(t)
+	account, _ := domain.NewServiceAccount("reports", nil, nil, publicKey)
+	service := newTestService(account)
+
+	credentials := Credentials{
+		AssertionType: ClientAssertionTypeJWTBearer,
+		Assertion: sign(t, signer, jwt.RegisteredClaims{
+			Issuer:    account.GetClientID(),
+			Subject:   account.GetClientID(),
+			Audience:  jwt.ClaimStrings{"https://auth.example.com"},
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}),
+	}
+
+	if _, err := service.Authenticate(context.Background(), credentials); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), credentials); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate(replayed) error = %v, want ErrInvalidCredentials", err)
+	}
+}
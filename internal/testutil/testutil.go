@@ -0,0 +1,128 @@
+// Package testutil holds the fakes service tests share: a database that
+// runs transactions in place, an audit recorder and a security event
+// emitter that keep what they are given, and an in-memory user repository.
+package testutil
+
+import (
+	"context"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/security"
+	"github.com/felipeversiane/auth-service/internal/user"
+	"github.com/google/uuid"
+)
+
+// DB runs each transaction directly in the calling context.
+type DB struct {
+	database.DatabaseInterface
+}
+
+func (DB) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// Recorder keeps the audit entries it records.
+type Recorder struct {
+	Entries []audit.Entry
+}
+
+func (r *Recorder) Record(_ context.Context, entry audit.Entry) error {
+	r.Entries = append(r.Entries, entry)
+	return nil
+}
+
+// Count returns how many entries were recorded for action.
+func (r *Recorder) Count(action string) int {
+	count := 0
+	for _, entry := range r.Entries {
+		if entry.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Has tells whether an entry was recorded for action.
+func (r *Recorder) Has(action string) bool {
+	return r.Count(action) > 0
+}
+
+// Failures returns how many entries record a failure.
+func (r *Recorder) Failures() int {
+	count := 0
+	for _, entry := range r.Entries {
+		if entry.Outcome == domain.AuditOutcomeFailure {
+			count++
+		}
+	}
+	return count
+}
+
+// Emitter keeps the security events it is given.
+type Emitter struct {
+	Events []security.Event
+}
+
+func (e *Emitter) Emit(_ context.Context, event security.Event) {
+	e.Events = append(e.Events, event)
+}
+
+// Users is a user repository over the accounts it holds. The accounts are
+// changed in place, so writes only count how often they were saved.
+type Users struct {
+	user.RepositoryInterface
+	Accounts            map[uuid.UUID]domain.UserInterface
+	ProfileUpdates      int
+	PasswordUpdates     int
+	DeactivationUpdates int
+}
+
+// NewUsers returns a repository holding accounts.
+func NewUsers(accounts ...domain.UserInterface) *Users {
+	u := &Users{Accounts: map[uuid.UUID]domain.UserInterface{}}
+	for _, account := range accounts {
+		u.Add(account)
+	}
+	return u
+}
+
+func (u *Users) Add(account domain.UserInterface) {
+	u.Accounts[account.GetID()] = account
+}
+
+func (u *Users) FindByID(_ context.Context, id uuid.UUID) (domain.UserInterface, error) {
+	if account, ok := u.Accounts[id]; ok {
+		return account, nil
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (u *Users) FindByEmail(_ context.Context, email string) (domain.UserInterface, error) {
+	for _, account := range u.Accounts {
+		if account.GetEmail() == email {
+			return account, nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (u *Users) MarkEmailVerified(context.Context, domain.UserInterface) error {
+	return nil
+}
+
+func (u *Users) UpdateProfile(context.Context, domain.UserInterface) error {
+	u.ProfileUpdates++
+	return nil
+}
+
+func (u *Users) UpdatePassword(context.Context, domain.UserInterface) error {
+	u.PasswordUpdates++
+	return nil
+}
+
+func (u *Users) UpdateDeactivated(context.Context, domain.UserInterface) error {
+	u.DeactivationUpdates++
+	return nil
+}
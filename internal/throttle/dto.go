@@ -0,0 +1,55 @@
+package throttle
+
+import "time"
+
+type LockoutRequest struct {
+	Email string `json:"email" form:"email" binding:"required_without=IP,omitempty,email,max=255"`
+	IP    string `json:"ip" form:"ip" binding:"omitempty,ip"`
+}
+
+type LockoutResponse struct {
+	Scope       string    `json:"scope"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+type FailuresResponse struct {
+	Pair    int `json:"pair"`
+	Account int `json:"account"`
+	IP      int `json:"ip"`
+}
+
+type StatusResponse struct {
+	Email      string            `json:"email,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	Locked     bool              `json:"locked"`
+	RetryAfter int               `json:"retry_after"`
+	Lockouts   []LockoutResponse `json:"lockouts"`
+	Failures   FailuresResponse  `json:"failures"`
+}
+
+func NewStatusResponse(req LockoutRequest, status *Status) StatusResponse {
+	now := time.Now().UTC()
+	lockouts := make([]LockoutResponse, 0, 3)
+	for _, lockout := range []LockoutResponse{
+		{Scope: ScopePair, LockedUntil: status.Lockouts.Pair},
+		{Scope: ScopeAccount, LockedUntil: status.Lockouts.Account},
+		{Scope: ScopeIP, LockedUntil: status.Lockouts.IP},
+	} {
+		if lockout.LockedUntil.After(now) {
+			lockouts = append(lockouts, lockout)
+		}
+	}
+
+	return StatusResponse{
+		Email:      normalizeEmail(req.Email),
+		IP:         req.IP,
+		Locked:     len(lockouts) > 0,
+		RetryAfter: int((status.Wait + time.Second - 1) / time.Second),
+		Lockouts:   lockouts,
+		Failures: FailuresResponse{
+			Pair:    status.Failures.Pair,
+			Account: status.Failures.Account,
+			IP:      status.Failures.IP,
+		},
+	}
+}
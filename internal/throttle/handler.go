@@ -0,0 +1,96 @@
+package throttle
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/rbac"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the lockout API.
+const (
+	ResourceLockouts = "lockouts"
+	ActionRead       = "read"
+	ActionUnlock     = "unlock"
+)
+
+type handler struct {
+	adminConfig config.AdminConfig
+	tracker     TrackerInterface
+	authorizer  rbac.AuthorizerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	GetStatus(ctx *gin.Context)
+	Unlock(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	tracker TrackerInterface,
+	authorizer rbac.AuthorizerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		tracker:     tracker,
+		authorizer:  authorizer,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	read := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.authorizer, ResourceLockouts, ActionRead)
+	unlock := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.authorizer, ResourceLockouts, ActionUnlock)
+
+	lockouts := router.Group("/api/v1/lockouts")
+	{
+		lockouts.GET("", read, h.GetStatus)
+		lockouts.POST("/unlock", unlock, h.Unlock)
+	}
+}
+
+func (h *handler) GetStatus(ctx *gin.Context) {
+	var req LockoutRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	status, err := h.tracker.Status(ctx.Request.Context(), req.Email, req.IP)
+	if err != nil {
+		slog.Error("failed to read login throttle status", "error", err)
+		restErr := httperr.NewInternalServerError("failed to read lockout status")
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewStatusResponse(req, status))
+}
+
+func (h *handler) Unlock(ctx *gin.Context) {
+	var req LockoutRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	if err := h.tracker.Unlock(ctx.Request.Context(), req.Email, req.IP); err != nil {
+		slog.Error("failed to unlock login", "error", err)
+		restErr := httperr.NewInternalServerError("failed to unlock")
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
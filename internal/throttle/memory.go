@@ -0,0 +1,199 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type pair struct {
+	email string
+	ip    string
+}
+
+// memoryStore keeps failures per (email, ip) pair with indexes by email and
+// by address, so each scope is counted without scanning every attempt.
+type memoryStore struct {
+	mu       sync.Mutex
+	failures map[pair][]time.Time
+	byEmail  map[string]map[pair]struct{}
+	byIP     map[string]map[pair]struct{}
+	lockouts map[pair]time.Time
+}
+
+func newMemoryStore() StoreInterface {
+	return &memoryStore{
+		failures: make(map[pair][]time.Time),
+		byEmail:  make(map[string]map[pair]struct{}),
+		byIP:     make(map[string]map[pair]struct{}),
+		lockouts: make(map[pair]time.Time),
+	}
+}
+
+func (s *memoryStore) AddFailure(_ context.Context, email, ip string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pair{email: email, ip: ip}
+	s.failures[key] = append(s.failures[key], at)
+	index(s.byEmail, email, key)
+	index(s.byIP, ip, key)
+
+	return nil
+}
+
+func (s *memoryStore) CountFailures(_ context.Context, email, ip string, since Times) (Counts, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		counts Counts
+		last   time.Time
+	)
+
+	if email != "" {
+		for key := range s.byEmail[email] {
+			counts.Account += countAfter(s.failures[key], since.Account)
+		}
+	}
+	if ip != "" {
+		for key := range s.byIP[ip] {
+			counts.IP += countAfter(s.failures[key], since.IP)
+		}
+	}
+	if email != "" && ip != "" {
+		attempts := s.failures[pair{email: email, ip: ip}]
+		counts.Pair = countAfter(attempts, since.Pair)
+		if len(attempts) > 0 {
+			last = attempts[len(attempts)-1]
+		}
+	}
+
+	return counts, last, nil
+}
+
+func (s *memoryStore) FindLockouts(_ context.Context, email, ip string) (Times, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lockouts Times
+	if email != "" {
+		lockouts.Account = s.lockouts[pair{email: email}]
+	}
+	if ip != "" {
+		lockouts.IP = s.lockouts[pair{ip: ip}]
+	}
+	if email != "" && ip != "" {
+		lockouts.Pair = s.lockouts[pair{email: email, ip: ip}]
+	}
+
+	return lockouts, nil
+}
+
+func (s *memoryStore) Lock(_ context.Context, email, ip string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := pair{email: email, ip: ip}
+	if until.After(s.lockouts[key]) {
+		s.lockouts[key] = until
+	}
+
+	return nil
+}
+
+func (s *memoryStore) ClearFailures(_ context.Context, email, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeFailures(pair{email: email, ip: ip})
+	return nil
+}
+
+func (s *memoryStore) Unlock(_ context.Context, email, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if email != "" {
+		for key := range s.byEmail[email] {
+			s.removeFailures(key)
+		}
+	}
+	if ip != "" {
+		for key := range s.byIP[ip] {
+			s.removeFailures(key)
+		}
+	}
+
+	for key := range s.lockouts {
+		if (email != "" && key.email == email) || (ip != "" && key.ip == ip) {
+			delete(s.lockouts, key)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempts := range s.failures {
+		// Attempts are appended in order, so everything from the first
+		// recent one on is kept.
+		first := len(attempts)
+		for i, at := range attempts {
+			if !at.Before(before) {
+				first = i
+				break
+			}
+		}
+
+		if first == len(attempts) {
+			s.removeFailures(key)
+		} else if first > 0 {
+			s.failures[key] = append([]time.Time(nil), attempts[first:]...)
+		}
+	}
+
+	for key, until := range s.lockouts {
+		if until.Before(before) {
+			delete(s.lockouts, key)
+		}
+	}
+
+	return nil
+}
+
+func (s *memoryStore) removeFailures(key pair) {
+	delete(s.failures, key)
+	unindex(s.byEmail, key.email, key)
+	unindex(s.byIP, key.ip, key)
+}
+
+func index(entries map[string]map[pair]struct{}, value string, key pair) {
+	if value == "" {
+		return
+	}
+	if entries[value] == nil {
+		entries[value] = make(map[pair]struct{})
+	}
+	entries[value][key] = struct{}{}
+}
+
+func unindex(entries map[string]map[pair]struct{}, value string, key pair) {
+	delete(entries[value], key)
+	if len(entries[value]) == 0 {
+		delete(entries, value)
+	}
+}
+
+func countAfter(attempts []time.Time, since time.Time) int {
+	count := 0
+	for _, at := range attempts {
+		if at.After(since) {
+			count++
+		}
+	}
+	return count
+}
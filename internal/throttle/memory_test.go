@@ -0,0 +1,142 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreCountsEachScope(t *testing.T) {
+	store := newMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for _, a := range []attempt{
+		{testEmail, testIP},
+		{testEmail, testIP},
+		{testEmail, "198.51.100.9"},
+		{"other@example.com", testIP},
+	} {
+		if err := store.AddFailure(ctx, a.email, a.ip, now); err != nil {
+			t.Fatalf("AddFailure() error = %v", err)
+		}
+	}
+
+	since := Times{Pair: now.Add(-time.Minute), Account: now.Add(-time.Minute), IP: now.Add(-time.Minute)}
+	tests := []struct {
+		name      string
+		email, ip string
+		want      Counts
+	}{
+		{"pair", testEmail, testIP, Counts{Pair: 2, Account: 3, IP: 3}},
+		{"account alone", testEmail, "", Counts{Account: 3}},
+		{"address alone", "", testIP, Counts{IP: 3}},
+		{"unknown", "nobody@example.com", "192.0.2.1", Counts{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, last, err := store.CountFailures(ctx, tt.email, tt.ip, since)
+			if err != nil {
+				t.Fatalf("CountFailures() error = %v", err)
+			}
+			if counts != tt.want {
+				t.Fatalf("CountFailures() = %+v, want %+v", counts, tt.want)
+			}
+			if wantLast := tt.want.Pair > 0; wantLast != !last.IsZero() {
+				t.Fatalf("CountFailures() last = %s", last)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreLockKeepsTheLatestEnd(t *testing.T) {
+	store := newMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_ = store.Lock(ctx, testEmail, "", now.Add(time.Hour))
+	_ = store.Lock(ctx, testEmail, "", now.Add(time.Minute))
+	_ = store.Lock(ctx, "", testIP, now.Add(time.Minute))
+
+	lockouts, err := store.FindLockouts(ctx, testEmail, testIP)
+	if err != nil {
+		t.Fatalf("FindLockouts() error = %v", err)
+	}
+	if !lockouts.Account.Equal(now.Add(time.Hour)) || !lockouts.IP.Equal(now.Add(time.Minute)) || !lockouts.Pair.IsZero() {
+		t.Fatalf("FindLockouts() = %+v", lockouts)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	store := newMemoryStore()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_ = store.AddFailure(ctx, testEmail, testIP, now.Add(-2*time.Hour))
+	_ = store.AddFailure(ctx, testEmail, testIP, now)
+	_ = store.AddFailure(ctx, "other@example.com", "198.51.100.9", now.Add(-2*time.Hour))
+	_ = store.Lock(ctx, testEmail, "", now.Add(-time.Hour-time.Minute))
+	_ = store.Lock(ctx, "", testIP, now.Add(time.Minute))
+
+	if err := store.Purge(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	counts, _, _ := store.CountFailures(ctx, testEmail, testIP, Times{})
+	if want := (Counts{Pair: 1, Account: 1, IP: 1}); counts != want {
+		t.Errorf("CountFailures() after Purge() = %+v, want %+v", counts, want)
+	}
+	if counts, _, _ := store.CountFailures(ctx, "other@example.com", "198.51.100.9", Times{}); counts != (Counts{}) {
+		t.Errorf("old failures survived Purge(): %+v", counts)
+	}
+	lockouts, _ := store.FindLockouts(ctx, testEmail, testIP)
+	if !lockouts.Account.IsZero() || lockouts.IP.IsZero() {
+		t.Errorf("FindLockouts() after Purge() = %+v", lockouts)
+	}
+}
+
+func TestMemoryStoreUnlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		email, ip string
+		// want is what is left for (testEmail, testIP) and for the other
+		// account from the other address.
+		want, wantOther Counts
+	}{
+		{"account", testEmail, "", Counts{IP: 1}, Counts{Pair: 1, Account: 2, IP: 1}},
+		{"address", "", testIP, Counts{Account: 1}, Counts{Pair: 1, Account: 1, IP: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			for _, a := range []attempt{
+				{testEmail, testIP},
+				{testEmail, "198.51.100.9"},
+				{"other@example.com", testIP},
+				{"other@example.com", "192.0.2.1"},
+			} {
+				_ = store.AddFailure(ctx, a.email, a.ip, now)
+			}
+			_ = store.Lock(ctx, testEmail, testIP, now.Add(time.Hour))
+
+			if err := store.Unlock(ctx, tt.email, tt.ip); err != nil {
+				t.Fatalf("Unlock() error = %v", err)
+			}
+
+			if counts, _, _ := store.CountFailures(ctx, testEmail, testIP, Times{}); counts != tt.want {
+				t.Errorf("CountFailures() = %+v, want %+v", counts, tt.want)
+			}
+			if counts, _, _ := store.CountFailures(ctx, "other@example.com", "192.0.2.1", Times{}); counts != tt.wantOther {
+				t.Errorf("CountFailures() of the others = %+v, want %+v", counts, tt.wantOther)
+			}
+			if lockouts, _ := store.FindLockouts(ctx, testEmail, testIP); !lockouts.Pair.IsZero() {
+				t.Errorf("the pair is still locked: %+v", lockouts)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package throttle
+
+import (
+	"context"
+
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewStore,
+		NewTracker,
+		httpserver.AsRouter(NewHandler),
+	),
+	fx.Invoke(func(lc fx.Lifecycle, tracker TrackerInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return tracker.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return tracker.Stop(ctx)
+			},
+		})
+	}),
+)
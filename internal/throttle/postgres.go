@@ -0,0 +1,145 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+type postgresStore struct {
+	db database.DatabaseInterface
+}
+
+func newPostgresStore(db database.DatabaseInterface) StoreInterface {
+	return &postgresStore{
+		db: db,
+	}
+}
+
+func (s *postgresStore) AddFailure(ctx context.Context, email, ip string, at time.Time) error {
+	query := `INSERT INTO login_failures (email, ip, failed_at) VALUES ($1, $2, $3)`
+
+	if _, err := s.db.GetQuerier(ctx).Exec(ctx, query, email, ip, at); err != nil {
+		return fmt.Errorf("failed to insert login failure: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) CountFailures(ctx context.Context, email, ip string, since Times) (Counts, time.Time, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE $1 <> '' AND email = $1 AND failed_at > $3),
+			COUNT(*) FILTER (WHERE $2 <> '' AND ip = $2 AND failed_at > $4),
+			COUNT(*) FILTER (WHERE $1 <> '' AND $2 <> '' AND email = $1 AND ip = $2 AND failed_at > $5),
+			COALESCE(MAX(failed_at) FILTER (WHERE $1 <> '' AND $2 <> '' AND email = $1 AND ip = $2), 'epoch')
+		FROM login_failures
+		WHERE ($1 <> '' AND email = $1) OR ($2 <> '' AND ip = $2)`
+
+	var (
+		counts Counts
+		last   time.Time
+	)
+	err := s.db.GetQuerier(ctx).QueryRow(ctx, query, email, ip, since.Account, since.IP, since.Pair).
+		Scan(&counts.Account, &counts.IP, &counts.Pair, &last)
+	if err != nil {
+		return Counts{}, time.Time{}, fmt.Errorf("failed to count login failures: %w", err)
+	}
+
+	return counts, last, nil
+}
+
+func (s *postgresStore) FindLockouts(ctx context.Context, email, ip string) (Times, error) {
+	query := `
+		SELECT email, ip, locked_until
+		FROM login_lockouts
+		WHERE ($1 <> '' AND email = $1 AND ip = '')
+			OR ($2 <> '' AND email = '' AND ip = $2)
+			OR ($1 <> '' AND $2 <> '' AND email = $1 AND ip = $2)`
+
+	rows, err := s.db.GetQuerier(ctx).Query(ctx, query, email, ip)
+	if err != nil {
+		return Times{}, fmt.Errorf("failed to query login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var lockouts Times
+	for rows.Next() {
+		var (
+			lockedEmail string
+			lockedIP    string
+			lockedUntil time.Time
+		)
+		if err := rows.Scan(&lockedEmail, &lockedIP, &lockedUntil); err != nil {
+			return Times{}, fmt.Errorf("failed to scan login lockout: %w", err)
+		}
+
+		switch {
+		case lockedIP == "":
+			lockouts.Account = lockedUntil
+		case lockedEmail == "":
+			lockouts.IP = lockedUntil
+		default:
+			lockouts.Pair = lockedUntil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return Times{}, fmt.Errorf("failed to iterate login lockouts: %w", err)
+	}
+
+	return lockouts, nil
+}
+
+func (s *postgresStore) Lock(ctx context.Context, email, ip string, until time.Time) error {
+	query := `
+		INSERT INTO login_lockouts (email, ip, locked_until, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (email, ip) DO UPDATE
+		SET locked_until = GREATEST(login_lockouts.locked_until, EXCLUDED.locked_until)`
+
+	if _, err := s.db.GetQuerier(ctx).Exec(ctx, query, email, ip, until, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert login lockout: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) ClearFailures(ctx context.Context, email, ip string) error {
+	query := `DELETE FROM login_failures WHERE email = $1 AND ip = $2`
+
+	if _, err := s.db.GetQuerier(ctx).Exec(ctx, query, email, ip); err != nil {
+		return fmt.Errorf("failed to delete login failures: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) Unlock(ctx context.Context, email, ip string) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		match := `($1 <> '' AND email = $1) OR ($2 <> '' AND ip = $2)`
+
+		if _, err := s.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM login_failures WHERE `+match, email, ip); err != nil {
+			return fmt.Errorf("failed to delete login failures: %w", err)
+		}
+
+		if _, err := s.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM login_lockouts WHERE `+match, email, ip); err != nil {
+			return fmt.Errorf("failed to delete login lockouts: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *postgresStore) Purge(ctx context.Context, before time.Time) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := s.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM login_failures WHERE failed_at < $1`, before); err != nil {
+			return fmt.Errorf("failed to purge login failures: %w", err)
+		}
+
+		if _, err := s.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM login_lockouts WHERE locked_until < $1`, before); err != nil {
+			return fmt.Errorf("failed to purge login lockouts: %w", err)
+		}
+
+		return nil
+	})
+}
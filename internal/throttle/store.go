@@ -0,0 +1,75 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Scopes a failure counter or a lockout applies to: one account from one
+// address, one account from anywhere, or one address for every account.
+const (
+	ScopePair    = "pair"
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// Counts holds the number of failures in each scope.
+type Counts struct {
+	Pair    int
+	Account int
+	IP      int
+}
+
+// Times holds one instant per scope, zero where it does not apply.
+type Times struct {
+	Pair    time.Time
+	Account time.Time
+	IP      time.Time
+}
+
+// StoreInterface keeps failed login attempts and lockouts. Every failure is
+// stored once with its email and address, so the account, address and pair
+// counters are all views over the same attempts. Lockouts are keyed by
+// (email, ip) where an empty side means any: (email, "") locks the account
+// and ("", ip) locks the address. An empty email or ip passed to a lookup
+// skips the scopes that need it.
+type StoreInterface interface {
+	AddFailure(ctx context.Context, email, ip string, at time.Time) error
+	// CountFailures counts the failures after since in each scope and
+	// returns the time of the latest failure for the pair.
+	CountFailures(ctx context.Context, email, ip string, since Times) (Counts, time.Time, error)
+	// FindLockouts returns when each scope's lockout ends, including
+	// lockouts that have already ended and not yet been purged.
+	FindLockouts(ctx context.Context, email, ip string) (Times, error)
+	Lock(ctx context.Context, email, ip string, until time.Time) error
+	// ClearFailures forgets the failures of exactly this email and address.
+	ClearFailures(ctx context.Context, email, ip string) error
+	// Unlock removes every failure and lockout involving the email or the
+	// address.
+	Unlock(ctx context.Context, email, ip string) error
+	// Purge drops failures and lockouts that ended before the given time.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// NewStore picks the backend from LOGIN_THROTTLE_BACKEND. The memory store
+// only sees the attempts made against this instance, so clusters should
+// share counters through Postgres.
+func NewStore(config config.LoginThrottleConfig, db database.DatabaseInterface) (StoreInterface, error) {
+	switch config.Backend {
+	case BackendMemory:
+		return newMemoryStore(), nil
+	case BackendPostgres:
+		return newPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported login throttle backend %q", config.Backend)
+	}
+}
@@ -0,0 +1,285 @@
+package throttle
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+)
+
+const purgeInterval = time.Minute
+
+type tracker struct {
+	config config.LoginThrottleConfig
+	db     database.DatabaseInterface
+	store  StoreInterface
+	audit  audit.RecorderInterface
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// Status is what the tracker knows about an email and address right now.
+type Status struct {
+	Lockouts Times
+	Failures Counts
+	// Wait is how long until the next attempt is accepted, from either a
+	// lockout or the progressive delay.
+	Wait time.Duration
+}
+
+// TrackerInterface throttles password logins over a sliding window. Failures
+// for the same email from the same address are slowed down with a delay
+// that doubles after the free attempts and, like failures for the email
+// alone or the address alone, lock the scope out once its limit is reached.
+// An empty ip, as for calls made outside a request, only tracks the account.
+type TrackerInterface interface {
+	// Check returns how long the client must wait before it may try to sign
+	// in as email; zero means it may try now.
+	Check(ctx context.Context, email, ip string) (time.Duration, error)
+	// RecordFailure counts a failed attempt and returns the scopes it locked.
+	RecordFailure(ctx context.Context, email, ip string) ([]string, error)
+	// RecordSuccess forgets the failures of this email from this address.
+	RecordSuccess(ctx context.Context, email, ip string) error
+	Status(ctx context.Context, email, ip string) (*Status, error)
+	Unlock(ctx context.Context, email, ip string) error
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewTracker(
+	config config.LoginThrottleConfig,
+	db database.DatabaseInterface,
+	store StoreInterface,
+	audit audit.RecorderInterface,
+) TrackerInterface {
+	return &tracker{
+		config: config,
+		db:     db,
+		store:  store,
+		audit:  audit,
+	}
+}
+
+func (t *tracker) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	status, err := t.Status(ctx, email, ip)
+	if err != nil {
+		return 0, err
+	}
+	return status.Wait, nil
+}
+
+func (t *tracker) RecordFailure(ctx context.Context, email, ip string) ([]string, error) {
+	email = normalizeEmail(email)
+	now := time.Now().UTC()
+
+	if err := t.store.AddFailure(ctx, email, ip, now); err != nil {
+		return nil, err
+	}
+
+	lockouts, err := t.store.FindLockouts(ctx, email, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, _, err := t.store.CountFailures(ctx, email, ip, t.since(now, lockouts))
+	if err != nil {
+		return nil, err
+	}
+
+	until := now.Add(t.seconds(t.config.Lockout))
+	limits := []struct {
+		scope    string
+		failures int
+		limit    int
+		email    string
+		ip       string
+	}{
+		{ScopePair, failures.Pair, t.config.PairLimit, email, ip},
+		{ScopeAccount, failures.Account, t.config.AccountLimit, email, ""},
+		{ScopeIP, failures.IP, t.config.IPLimit, "", ip},
+	}
+
+	var locked []string
+	for _, l := range limits {
+		if l.limit <= 0 || l.failures < l.limit || !applies(l.scope, email, ip) {
+			continue
+		}
+
+		if err := t.store.Lock(ctx, l.email, l.ip, until); err != nil {
+			return locked, err
+		}
+		locked = append(locked, l.scope)
+	}
+
+	return locked, nil
+}
+
+func (t *tracker) RecordSuccess(ctx context.Context, email, ip string) error {
+	return t.store.ClearFailures(ctx, normalizeEmail(email), ip)
+}
+
+func (t *tracker) Status(ctx context.Context, email, ip string) (*Status, error) {
+	email = normalizeEmail(email)
+	now := time.Now().UTC()
+
+	lockouts, err := t.store.FindLockouts(ctx, email, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, last, err := t.store.CountFailures(ctx, email, ip, t.since(now, lockouts))
+	if err != nil {
+		return nil, err
+	}
+
+	var wait time.Duration
+	for _, until := range []time.Time{lockouts.Pair, lockouts.Account, lockouts.IP} {
+		wait = max(wait, until.Sub(now))
+	}
+	if delay := t.delay(failures.Pair); delay > 0 {
+		wait = max(wait, last.Add(delay).Sub(now))
+	}
+
+	return &Status{
+		Lockouts: lockouts,
+		Failures: failures,
+		Wait:     wait,
+	}, nil
+}
+
+// Unlock lifts lockouts and audits it. The entry is written first because
+// the memory store cannot take part in the transaction.
+func (t *tracker) Unlock(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+	err := t.db.WithTx(ctx, func(ctx context.Context) error {
+		err := t.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionLoginUnlock,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetLogin,
+			TargetID:   email,
+			Metadata:   map[string]string{"ip": ip},
+		})
+		if err != nil {
+			return err
+		}
+		return t.store.Unlock(ctx, email, ip)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("login unlocked", slog.Bool("account", email != ""), slog.String("ip", ip))
+	return nil
+}
+
+func (t *tracker) Start(ctx context.Context) error {
+	slog.Info("starting login throttle", slog.String("backend", t.config.Backend))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	t.done.Add(1)
+	go t.purge(runCtx)
+
+	return nil
+}
+
+func (t *tracker) Stop(ctx context.Context) error {
+	if t.cancel != nil {
+		t.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		t.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		slog.Info("login throttle stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *tracker) purge(ctx context.Context) {
+	defer t.done.Done()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			before := time.Now().UTC().Add(-t.seconds(t.config.Window))
+			if err := t.store.Purge(ctx, before); err != nil {
+				slog.Warn("failed to purge login failures", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// since starts each scope's window at the end of its last lockout, so a
+// lockout that has run its course starts the count over.
+func (t *tracker) since(now time.Time, lockouts Times) Times {
+	start := now.Add(-t.seconds(t.config.Window))
+	return Times{
+		Pair:    latest(start, lockouts.Pair),
+		Account: latest(start, lockouts.Account),
+		IP:      latest(start, lockouts.IP),
+	}
+}
+
+// delay is zero for the free attempts and then doubles from the base delay
+// with every failure, up to the maximum.
+func (t *tracker) delay(failures int) time.Duration {
+	excess := failures - t.config.FreeAttempts
+	if excess <= 0 || t.config.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := t.seconds(t.config.BaseDelay)
+	maxDelay := t.seconds(t.config.MaxDelay)
+	for i := 1; i < excess && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
+}
+
+func (t *tracker) seconds(value int) time.Duration {
+	return time.Duration(value) * time.Second
+}
+
+func applies(scope, email, ip string) bool {
+	switch scope {
+	case ScopePair:
+		return email != "" && ip != ""
+	case ScopeAccount:
+		return email != ""
+	default:
+		return ip != ""
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
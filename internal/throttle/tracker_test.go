@@ -0,0 +1,258 @@
+package throttle
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/testutil"
+)
+
+const (
+	testEmail = "jane@example.com"
+	testIP    = "203.0.113.7"
+)
+
+var testConfig = config.LoginThrottleConfig{
+	Backend:      BackendMemory,
+	Window:       900,
+	FreeAttempts: 2,
+	BaseDelay:    10,
+	MaxDelay:     60,
+	PairLimit:    3,
+	AccountLimit: 5,
+	IPLimit:      4,
+	Lockout:      300,
+}
+
+func newTestTracker(cfg config.LoginThrottleConfig) (TrackerInterface, StoreInterface, *testutil.Recorder) {
+	store := newMemoryStore()
+	recorder := &testutil.Recorder{}
+	return NewTracker(cfg, testutil.DB{}, store, recorder), store, recorder
+}
+
+type attempt struct {
+	email string
+	ip    string
+}
+
+func TestRecordFailureLocksScopes(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts []attempt
+		// want is what the last attempt locks; the ones before lock nothing.
+		want []string
+	}{
+		{
+			name:     "pair",
+			attempts: []attempt{{testEmail, testIP}, {testEmail, testIP}, {testEmail, testIP}},
+			want:     []string{ScopePair},
+		},
+		{
+			name: "account from several addresses",
+			attempts: []attempt{
+				{testEmail, "198.51.100.1"}, {testEmail, "198.51.100.2"}, {testEmail, "198.51.100.3"},
+				{testEmail, "198.51.100.4"}, {testEmail, "198.51.100.5"},
+			},
+			want: []string{ScopeAccount},
+		},
+		{
+			name: "address trying several accounts",
+			attempts: []attempt{
+				{"a@example.com", testIP}, {"b@example.com", testIP}, {"c@example.com", testIP}, {"d@example.com", testIP},
+			},
+			want: []string{ScopeIP},
+		},
+		{
+			name:     "emails are compared normalized",
+			attempts: []attempt{{testEmail, testIP}, {" Jane@Example.com", testIP}, {"JANE@EXAMPLE.COM ", testIP}},
+			want:     []string{ScopePair},
+		},
+		{
+			name:     "without an address only the account is tracked",
+			attempts: []attempt{{testEmail, ""}, {testEmail, ""}, {testEmail, ""}, {testEmail, ""}, {testEmail, ""}},
+			want:     []string{ScopeAccount},
+		},
+		{
+			name:     "below every limit",
+			attempts: []attempt{{testEmail, testIP}, {testEmail, testIP}},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _, _ := newTestTracker(testConfig)
+			ctx := context.Background()
+
+			for i, a := range tt.attempts {
+				locked, err := tracker.RecordFailure(ctx, a.email, a.ip)
+				if err != nil {
+					t.Fatalf("RecordFailure() error = %v", err)
+				}
+				if i < len(tt.attempts)-1 && len(locked) > 0 {
+					t.Fatalf("attempt %d locked %v", i+1, locked)
+				}
+				if i == len(tt.attempts)-1 && !slices.Equal(locked, tt.want) {
+					t.Fatalf("RecordFailure() locked %v, want %v", locked, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckReturnsTheLockout(t *testing.T) {
+	tracker, _, _ := newTestTracker(testConfig)
+	ctx := context.Background()
+
+	for i := 0; i < testConfig.AccountLimit; i++ {
+		if _, err := tracker.RecordFailure(ctx, testEmail, ""); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+
+	lockout := time.Duration(testConfig.Lockout) * time.Second
+	for _, ip := range []string{"", testIP, "198.51.100.9"} {
+		wait, err := tracker.Check(ctx, testEmail, ip)
+		if err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if wait <= lockout-time.Second || wait > lockout {
+			t.Errorf("Check(%q) = %s, want about %s", ip, wait, lockout)
+		}
+	}
+
+	if wait, _ := tracker.Check(ctx, "someone@example.com", testIP); wait != 0 {
+		t.Errorf("Check() of another account = %s, want 0", wait)
+	}
+}
+
+func TestStatusWaitDoublesAfterFreeAttempts(t *testing.T) {
+	cfg := testConfig
+	cfg.PairLimit, cfg.AccountLimit, cfg.IPLimit = 0, 0, 0
+	tracker, _, _ := newTestTracker(cfg)
+	ctx := context.Background()
+
+	// FreeAttempts 2, BaseDelay 10s, MaxDelay 60s.
+	want := []time.Duration{0, 0, 10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second}
+	for i, delay := range want {
+		if _, err := tracker.RecordFailure(ctx, testEmail, testIP); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+
+		status, err := tracker.Status(ctx, testEmail, testIP)
+		if err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+		if status.Failures.Pair != i+1 {
+			t.Fatalf("after %d failures, counted %d", i+1, status.Failures.Pair)
+		}
+		if status.Wait > delay || (delay > 0 && status.Wait <= delay-time.Second) {
+			t.Fatalf("after %d failures, wait = %s, want about %s", i+1, status.Wait, delay)
+		}
+	}
+
+	// The delay only slows down the pair.
+	if wait, _ := tracker.Check(ctx, testEmail, "198.51.100.9"); wait != 0 {
+		t.Errorf("Check() from another address = %s, want 0", wait)
+	}
+}
+
+func TestFailuresExpire(t *testing.T) {
+	tests := []struct {
+		name string
+		seed func(store StoreInterface, now time.Time)
+		want Counts
+	}{
+		{
+			name: "outside the window",
+			seed: func(store StoreInterface, now time.Time) {
+				old := now.Add(-time.Duration(testConfig.Window)*time.Second - time.Minute)
+				_ = store.AddFailure(context.Background(), testEmail, testIP, old)
+				_ = store.AddFailure(context.Background(), testEmail, testIP, now.Add(-time.Minute))
+			},
+			want: Counts{Pair: 1, Account: 1, IP: 1},
+		},
+		{
+			name: "before a lockout that has ended",
+			seed: func(store StoreInterface, now time.Time) {
+				ctx := context.Background()
+				_ = store.AddFailure(ctx, testEmail, testIP, now.Add(-3*time.Minute))
+				_ = store.AddFailure(ctx, testEmail, "198.51.100.9", now.Add(-3*time.Minute))
+				_ = store.Lock(ctx, testEmail, testIP, now.Add(-2*time.Minute))
+				_ = store.AddFailure(ctx, testEmail, testIP, now.Add(-time.Minute))
+			},
+			// Only the pair was locked, so only its count starts over.
+			want: Counts{Pair: 1, Account: 3, IP: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, store, _ := newTestTracker(testConfig)
+			tt.seed(store, time.Now().UTC())
+
+			status, err := tracker.Status(context.Background(), testEmail, testIP)
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			if status.Failures != tt.want {
+				t.Fatalf("Failures = %+v, want %+v", status.Failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordSuccessForgetsOnlyThePair(t *testing.T) {
+	tracker, _, _ := newTestTracker(testConfig)
+	ctx := context.Background()
+
+	for _, ip := range []string{testIP, testIP, "198.51.100.9"} {
+		if _, err := tracker.RecordFailure(ctx, testEmail, ip); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+	if err := tracker.RecordSuccess(ctx, " JANE@example.com", testIP); err != nil {
+		t.Fatalf("RecordSuccess() error = %v", err)
+	}
+
+	status, err := tracker.Status(ctx, testEmail, testIP)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if want := (Counts{Pair: 0, Account: 1, IP: 0}); status.Failures != want {
+		t.Fatalf("Failures = %+v, want %+v", status.Failures, want)
+	}
+}
+
+func TestUnlockLiftsLockoutsAndAudits(t *testing.T) {
+	tracker, _, recorder := newTestTracker(testConfig)
+	ctx := context.Background()
+
+	for i := 0; i < testConfig.PairLimit; i++ {
+		if _, err := tracker.RecordFailure(ctx, testEmail, testIP); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+	if wait, _ := tracker.Check(ctx, testEmail, testIP); wait == 0 {
+		t.Fatal("the pair was not locked")
+	}
+
+	if err := tracker.Unlock(ctx, testEmail, ""); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	status, err := tracker.Status(ctx, testEmail, testIP)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Wait != 0 || status.Failures != (Counts{}) {
+		t.Fatalf("after Unlock() status = %+v", status)
+	}
+	if !recorder.Has(audit.ActionLoginUnlock) {
+		t.Error("Unlock() was not audited")
+	}
+}
@@ -0,0 +1,193 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/keys"
+	"github.com/felipeversiane/auth-service/internal/revocation"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	// EmailVerified, Roles and OrgID are only present on first-party user
+	// tokens. Roles include the ones granted in the active organization.
+	EmailVerified *bool    `json:"email_verified,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	OrgID         string   `json:"org_id,omitempty"`
+}
+
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce,omitempty"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	GivenName     string `json:"given_name,omitempty"`
+	FamilyName    string `json:"family_name,omitempty"`
+	PhoneNumber   string `json:"phone_number,omitempty"`
+}
+
+type AccessTokenParams struct {
+	Subject  string
+	ClientID string
+	Scope    string
+	// Audience defaults to the configured token audience.
+	Audience []string
+	// EmailVerified, Roles and OrgID are set for first-party user sessions
+	// only.
+	EmailVerified *bool
+	Roles         []string
+	OrgID         string
+}
+
+type manager struct {
+	config   config.TokenConfig
+	keyring  keys.KeyringInterface
+	denylist revocation.DenylistInterface
+}
+
+type ManagerInterface interface {
+	IssueAccessToken(ctx context.Context, params AccessTokenParams) (string, *Claims, error)
+	IssueIDToken(ctx context.Context, claims *IDTokenClaims) (string, error)
+	ParseAccessToken(ctx context.Context, tokenString string) (*Claims, error)
+	IntrospectAccessToken(ctx context.Context, tokenString string) (*Claims, error)
+	RevokeAccessToken(ctx context.Context, claims *Claims) error
+}
+
+func New(config config.TokenConfig, keyring keys.KeyringInterface, denylist revocation.DenylistInterface) ManagerInterface {
+	return &manager{
+		config:   config,
+		keyring:  keyring,
+		denylist: denylist,
+	}
+}
+
+func (m *manager) IssueAccessToken(ctx context.Context, params AccessTokenParams) (string, *Claims, error) {
+	now := time.Now().UTC()
+
+	audience := jwt.ClaimStrings{m.config.Audience}
+	if len(params.Audience) > 0 {
+		audience = params.Audience
+	}
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   params.Subject,
+			Issuer:    m.config.Issuer,
+			Audience:  audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(m.config.AccessTokenTTL) * time.Second)),
+		},
+		Scope:         params.Scope,
+		ClientID:      params.ClientID,
+		EmailVerified: params.EmailVerified,
+		Roles:         params.Roles,
+		OrgID:         params.OrgID,
+	}
+
+	signed, err := m.sign(ctx, claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return signed, claims, nil
+}
+
+// IssueIDToken fills in the registered claims; the caller provides subject,
+// audience and the profile claims allowed by the granted scopes.
+func (m *manager) IssueIDToken(ctx context.Context, claims *IDTokenClaims) (string, error) {
+	now := time.Now().UTC()
+
+	claims.ID = uuid.NewString()
+	claims.Issuer = m.config.Issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(m.config.IDTokenTTL) * time.Second))
+
+	signed, err := m.sign(ctx, claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign id token: %w", err)
+	}
+
+	return signed, nil
+}
+
+func (m *manager) ParseAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	return m.parse(ctx, tokenString, jwt.WithAudience(m.config.Audience))
+}
+
+// IntrospectAccessToken validates a token issued for any audience, which is
+// what resource servers asking about their own tokens need.
+func (m *manager) IntrospectAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	return m.parse(ctx, tokenString)
+}
+
+func (m *manager) RevokeAccessToken(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing jti or exp", ErrInvalidToken)
+	}
+
+	return m.denylist.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+func (m *manager) parse(ctx context.Context, tokenString string, options ...jwt.ParserOption) (*Claims, error) {
+	claims := &Claims{}
+
+	options = append([]jwt.ParserOption{
+		jwt.WithValidMethods([]string{keys.AlgorithmRS256, keys.AlgorithmES256, keys.AlgorithmEdDSA}),
+		jwt.WithIssuer(m.config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}, options...)
+
+	if _, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc(ctx), options...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if m.denylist.IsRevoked(claims.ID) {
+		return nil, fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+func (m *manager) sign(ctx context.Context, claims jwt.Claims) (string, error) {
+	key, err := m.keyring.ActiveKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.GetAlgorithm()), claims)
+	token.Header["kid"] = key.GetID()
+
+	return token.SignedString(key.GetPrivateKey())
+}
+
+func (m *manager) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := m.keyring.FindKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.GetAlgorithm() {
+			return nil, fmt.Errorf("token algorithm %s does not match key %s", token.Method.Alg(), kid)
+		}
+
+		return key.GetPublicKey(), nil
+	}
+}
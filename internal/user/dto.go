@@ -0,0 +1,57 @@
+package user
+
+import (
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type RegisterRequest struct {
+	Email     string `json:"email" binding:"required,email,max=255"`
+	Password  string `json:"password" binding:"required,min=8,max=72,maxbytes=72"`
+	Phone     string `json:"phone" binding:"omitempty,e164"`
+	FirstName string `json:"first_name" binding:"required,max=255"`
+	LastName  string `json:"last_name" binding:"required,max=255"`
+}
+
+// ProvisionRequest describes an account an identity provider asks for.
+// Method names the provider kind, such as "saml", for the audit trail.
+type ProvisionRequest struct {
+	Email     string
+	Phone     string
+	FirstName string
+	LastName  string
+	Method    string
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UserResponse struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	EmailVerified   bool       `json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	Phone           string     `json:"phone,omitempty"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	HasPassword     bool       `json:"has_password"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func NewUserResponse(user domain.UserInterface) UserResponse {
+	return UserResponse{
+		ID:              user.GetID().String(),
+		Email:           user.GetEmail(),
+		EmailVerified:   user.IsEmailVerified(),
+		EmailVerifiedAt: user.GetEmailVerifiedAt(),
+		Phone:           user.GetPhone(),
+		FirstName:       user.GetFirstName(),
+		LastName:        user.GetLastName(),
+		HasPassword:     user.HasPassword(),
+		CreatedAt:       user.GetCreatedAt(),
+		UpdatedAt:       user.GetUpdatedAt(),
+	}
+}
@@ -0,0 +1,119 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the administration of user accounts.
+const (
+	ResourceUsers = "users"
+	ActionDelete  = "delete"
+)
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+	checker     middleware.PermissionCheckerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Register(ctx *gin.Context)
+	VerifyEmail(ctx *gin.Context)
+	ResendVerification(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+		checker:     checker,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	users := router.Group("/api/v1/users")
+	{
+		users.POST("", h.Register)
+		users.POST("/verify-email", h.VerifyEmail)
+		users.POST(
+			"/verify-email/resend",
+			middleware.Authenticate(h.tokens),
+			middleware.RequireUser(),
+			h.ResendVerification,
+		)
+		users.DELETE(
+			"/:id",
+			middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.checker, ResourceUsers, ActionDelete),
+			h.Delete,
+		)
+	}
+}
+
+func (h *handler) Register(ctx *gin.Context) {
+	var req RegisterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	user, restErr := h.service.Register(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, NewUserResponse(user))
+}
+
+func (h *handler) VerifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	user, restErr := h.service.VerifyEmail(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+func (h *handler) ResendVerification(ctx *gin.Context) {
+	userID, _ := middleware.CurrentUser(ctx)
+
+	if restErr := h.service.ResendVerification(ctx.Request.Context(), userID); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (h *handler) Delete(ctx *gin.Context) {
+	if restErr := h.service.Delete(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
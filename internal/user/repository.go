@@ -0,0 +1,214 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const uniqueViolationCode = "23505"
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrEmailAlreadyExists = errors.New("email already exists")
+)
+
+type repository struct {
+	db database.DatabaseInterface
+}
+
+type RepositoryInterface interface {
+	Create(ctx context.Context, user domain.UserInterface) error
+	FindByID(ctx context.Context, id uuid.UUID) (domain.UserInterface, error)
+	FindByEmail(ctx context.Context, email string) (domain.UserInterface, error)
+	UpdatePassword(ctx context.Context, user domain.UserInterface) error
+	MarkEmailVerified(ctx context.Context, user domain.UserInterface) error
+	UpdateProfile(ctx context.Context, user domain.UserInterface) error
+	UpdateDeactivated(ctx context.Context, user domain.UserInterface) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+func NewRepository(db database.DatabaseInterface) RepositoryInterface {
+	return &repository{
+		db: db,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, user domain.UserInterface) error {
+	query := `
+		INSERT INTO users (id, first_name, last_name, phone, email, password, deactivated_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		user.GetID(),
+		user.GetFirstName(),
+		user.GetLastName(),
+		nullableString(user.GetPhone()),
+		user.GetEmail(),
+		nullableString(user.GetPassword()),
+		user.GetDeactivatedAt(),
+		user.GetCreatedAt(),
+		user.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrEmailAlreadyExists
+		}
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindByID(ctx context.Context, id uuid.UUID) (domain.UserInterface, error) {
+	query := `
+		SELECT id, email, password, phone, first_name, last_name, email_verified_at, deactivated_at, created_at, updated_at
+		FROM users
+		WHERE id = $1`
+
+	return scanUser(r.db.GetQuerier(ctx).QueryRow(ctx, query, id))
+}
+
+func (r *repository) FindByEmail(ctx context.Context, email string) (domain.UserInterface, error) {
+	query := `
+		SELECT id, email, password, phone, first_name, last_name, email_verified_at, deactivated_at, created_at, updated_at
+		FROM users
+		WHERE email = $1`
+
+	return scanUser(r.db.GetQuerier(ctx).QueryRow(ctx, query, email))
+}
+
+func (r *repository) UpdatePassword(ctx context.Context, user domain.UserInterface) error {
+	query := `UPDATE users SET password = $2, updated_at = $3 WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, user.GetID(), nullableString(user.GetPassword()), user.GetUpdatedAt())
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) MarkEmailVerified(ctx context.Context, user domain.UserInterface) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, $2), updated_at = $3
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, user.GetID(), user.GetEmailVerifiedAt(), user.GetUpdatedAt())
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateProfile(ctx context.Context, user domain.UserInterface) error {
+	query := `
+		UPDATE users
+		SET email = $2, email_verified_at = $3, phone = $4, first_name = $5, last_name = $6, updated_at = $7
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		user.GetID(),
+		user.GetEmail(),
+		user.GetEmailVerifiedAt(),
+		nullableString(user.GetPhone()),
+		user.GetFirstName(),
+		user.GetLastName(),
+		user.GetUpdatedAt(),
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			return ErrEmailAlreadyExists
+		}
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) UpdateDeactivated(ctx context.Context, user domain.UserInterface) error {
+	query := `UPDATE users SET deactivated_at = $2, updated_at = $3 WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, user.GetID(), user.GetDeactivatedAt(), user.GetUpdatedAt())
+	if err != nil {
+		return fmt.Errorf("failed to update user deactivation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the user; everything owned by the account goes with it
+// through the foreign keys.
+func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row pgx.Row) (domain.UserInterface, error) {
+	var (
+		id              uuid.UUID
+		email           string
+		password        *string
+		phone           *string
+		firstName       string
+		lastName        string
+		emailVerifiedAt *time.Time
+		deactivatedAt   *time.Time
+		createdAt       time.Time
+		updatedAt       time.Time
+	)
+
+	err := row.Scan(&id, &email, &password, &phone, &firstName, &lastName, &emailVerifiedAt, &deactivatedAt, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	return domain.Restore(id, email, valueOrEmpty(password), valueOrEmpty(phone), firstName, lastName, emailVerifiedAt, deactivatedAt, createdAt, updatedAt), nil
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,311 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/accounttoken"
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/infra/mailer"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+const invalidVerificationTokenMessage = "invalid or expired verification token"
+
+type service struct {
+	config        config.AccountConfig
+	db            database.DatabaseInterface
+	repository    RepositoryInterface
+	accountTokens accounttoken.RepositoryInterface
+	mailer        mailer.MailerInterface
+	outbox        outbox.PublisherInterface
+	audit         audit.RecorderInterface
+}
+
+type ServiceInterface interface {
+	Register(ctx context.Context, req RegisterRequest) (domain.UserInterface, *httperr.HttpError)
+	// Provision registers an account on behalf of an identity provider,
+	// which vouches for the email: it starts verified, without a password.
+	// It joins the transaction in the context, if any.
+	Provision(ctx context.Context, req ProvisionRequest) (domain.UserInterface, *httperr.HttpError)
+	VerifyEmail(ctx context.Context, req VerifyEmailRequest) (domain.UserInterface, *httperr.HttpError)
+	ResendVerification(ctx context.Context, userID uuid.UUID) *httperr.HttpError
+	Delete(ctx context.Context, userID string) *httperr.HttpError
+}
+
+func NewService(
+	config config.AccountConfig,
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	accountTokens accounttoken.RepositoryInterface,
+	mailer mailer.MailerInterface,
+	outbox outbox.PublisherInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		config:        config,
+		db:            db,
+		repository:    repository,
+		accountTokens: accountTokens,
+		mailer:        mailer,
+		outbox:        outbox,
+		audit:         audit,
+	}
+}
+
+func (s *service) Register(ctx context.Context, req RegisterRequest) (domain.UserInterface, *httperr.HttpError) {
+	email := normalizeEmail(req.Email)
+
+	_, err := s.repository.FindByEmail(ctx, email)
+	if err == nil {
+		return nil, emailAlreadyExistsError()
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		slog.Error("failed to look up user by email", "error", err)
+		return nil, httperr.NewInternalServerError("failed to register user")
+	}
+
+	user, err := domain.New(
+		email,
+		req.Password,
+		strings.TrimSpace(req.Phone),
+		strings.TrimSpace(req.FirstName),
+		strings.TrimSpace(req.LastName),
+	)
+	if err != nil {
+		if errors.Is(err, domain.ErrPasswordTooLong) {
+			return nil, httperr.NewBadRequestError(domain.ErrPasswordTooLong.Error())
+		}
+		slog.Error("failed to create user", "error", err)
+		return nil, httperr.NewInternalServerError("failed to register user")
+	}
+
+	if err := s.create(ctx, user, nil); err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) {
+			return nil, emailAlreadyExistsError()
+		}
+		slog.Error("failed to create user", "error", err)
+		return nil, httperr.NewInternalServerError("failed to register user")
+	}
+
+	slog.Info("user registered", slog.String("user_id", user.GetID().String()))
+
+	// The account exists either way; a lost email can be sent again from
+	// the resend endpoint.
+	_ = s.sendVerification(ctx, user)
+
+	return user, nil
+}
+
+func (s *service) Provision(ctx context.Context, req ProvisionRequest) (domain.UserInterface, *httperr.HttpError) {
+	user, err := domain.New(
+		normalizeEmail(req.Email),
+		"",
+		strings.TrimSpace(req.Phone),
+		strings.TrimSpace(req.FirstName),
+		strings.TrimSpace(req.LastName),
+	)
+	if err != nil {
+		slog.Error("failed to provision user", "error", err)
+		return nil, httperr.NewInternalServerError("failed to provision user")
+	}
+	user.VerifyEmail()
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.create(ctx, user, map[string]string{"method": req.Method}); err != nil {
+			return err
+		}
+		return s.repository.MarkEmailVerified(ctx, user)
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmailAlreadyExists) {
+			return nil, emailAlreadyExistsError()
+		}
+		slog.Error("failed to provision user", "error", err)
+		return nil, httperr.NewInternalServerError("failed to provision user")
+	}
+
+	slog.Info("user provisioned", slog.String("user_id", user.GetID().String()), slog.String("method", req.Method))
+	return user, nil
+}
+
+func (s *service) VerifyEmail(ctx context.Context, req VerifyEmailRequest) (domain.UserInterface, *httperr.HttpError) {
+	var found domain.UserInterface
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.accountTokens.Consume(ctx, domain.HashOpaqueToken(req.Token), domain.AccountTokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		found, err = s.repository.FindByID(ctx, token.GetUserID())
+		if err != nil {
+			return err
+		}
+
+		found.VerifyEmail()
+		if err := s.repository.MarkEmailVerified(ctx, found); err != nil {
+			return err
+		}
+
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionUserVerifyEmail,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetUser,
+			TargetID:   found.GetID().String(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, accounttoken.ErrAccountTokenNotFound) || errors.Is(err, ErrUserNotFound) {
+			return nil, httperr.NewBadRequestError(invalidVerificationTokenMessage)
+		}
+		slog.Error("failed to verify email", "error", err)
+		return nil, httperr.NewInternalServerError("failed to verify email")
+	}
+
+	slog.Info("email verified", slog.String("user_id", found.GetID().String()))
+	return found, nil
+}
+
+func (s *service) ResendVerification(ctx context.Context, userID uuid.UUID) *httperr.HttpError {
+	found, err := s.repository.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to find user", "error", err)
+		return httperr.NewInternalServerError("failed to send verification email")
+	}
+
+	if found.IsEmailVerified() {
+		return httperr.NewBadRequestError("email is already verified")
+	}
+
+	return s.sendVerification(ctx, found)
+}
+
+// Delete removes a user account for good. Sessions end with it, since the
+// refresh tokens are deleted along with the user; access tokens already
+// issued run until they expire.
+func (s *service) Delete(ctx context.Context, userID string) *httperr.HttpError {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return httperr.NewNotFoundError("user not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		found, err := s.repository.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repository.Delete(ctx, id); err != nil {
+			return err
+		}
+
+		if err := s.outbox.Publish(ctx, outbox.UserDeleted{UserID: id, Email: found.GetEmail()}); err != nil {
+			return err
+		}
+
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionUserDelete,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetUser,
+			TargetID:   id.String(),
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return httperr.NewNotFoundError("user not found")
+		}
+		slog.Error("failed to delete user", "error", err)
+		return httperr.NewInternalServerError("failed to delete user")
+	}
+
+	slog.Info("user deleted", slog.String("user_id", id.String()))
+	return nil
+}
+
+// create stores a new account and announces it, as one transaction.
+func (s *service) create(ctx context.Context, user domain.UserInterface, metadata map[string]string) error {
+	return s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.Create(ctx, user); err != nil {
+			return err
+		}
+
+		err := s.outbox.Publish(ctx, outbox.UserRegistered{
+			UserID:    user.GetID(),
+			Email:     user.GetEmail(),
+			FirstName: user.GetFirstName(),
+			LastName:  user.GetLastName(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionUserRegister,
+			Outcome:    domain.AuditOutcomeSuccess,
+			TargetType: audit.TargetUser,
+			TargetID:   user.GetID().String(),
+			Metadata:   metadata,
+		})
+	})
+}
+
+// sendVerification issues a fresh link, which also invalidates any link sent
+// earlier.
+func (s *service) sendVerification(ctx context.Context, user domain.UserInterface) *httperr.HttpError {
+	ttl := time.Duration(s.config.EmailVerificationTTL) * time.Second
+	token, raw := domain.NewAccountToken(user.GetID(), domain.AccountTokenPurposeEmailVerification, ttl)
+
+	if err := s.accountTokens.Create(ctx, token); err != nil {
+		slog.Error("failed to store verification token", "error", err)
+		return httperr.NewInternalServerError("failed to send verification email")
+	}
+
+	if err := s.accountTokens.DeleteExpired(ctx, time.Now().UTC()); err != nil {
+		slog.Warn("failed to clean up account tokens", "error", err)
+	}
+
+	link, err := mailer.LinkWithToken(s.config.VerifyEmailURL, raw)
+	if err != nil {
+		slog.Error("failed to build verification link", "error", err)
+		return httperr.NewInternalServerError("failed to send verification email")
+	}
+
+	message, err := mailer.Compose(user.GetEmail(), mailer.TemplateVerifyEmail, mailer.LinkData{
+		FirstName: user.GetFirstName(),
+		Link:      link,
+		ExpiresIn: ttl,
+	})
+	if err != nil {
+		slog.Error("failed to compose verification email", "error", err)
+		return httperr.NewInternalServerError("failed to send verification email")
+	}
+
+	if err := s.mailer.Send(ctx, message); err != nil {
+		slog.Error("failed to send verification email", "error", err)
+		return httperr.NewInternalServerError("failed to send verification email")
+	}
+
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func emailAlreadyExistsError() *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: "email", Message: "is already in use"},
+	})
+}
@@ -0,0 +1,324 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/google/uuid"
+)
+
+// HeaderDeliveryID identifies the delivery, which stays the same across
+// its attempts; X-Event-ID is shared by the deliveries of one event.
+const HeaderDeliveryID = "X-Delivery-ID"
+
+// maxResponseExcerpt bounds how much of a failed response body is kept in
+// the delivery log.
+const maxResponseExcerpt = 512
+
+var errPrivateAddress = errors.New("webhook endpoint resolves to a private address")
+
+type dispatcher struct {
+	config     config.WebhookConfig
+	repository RepositoryInterface
+	client     *http.Client
+
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// DispatcherInterface sends queued deliveries to their endpoints. A failed
+// attempt is retried with exponential backoff and jitter until the
+// delivery succeeds or runs out of attempts and is left dead. Replicas
+// dispatch concurrently without sending the same delivery twice at once,
+// since each leases its batch before sending it.
+type DispatcherInterface interface {
+	// Dispatch sends one batch of due deliveries and reports how many it
+	// leased.
+	Dispatch(ctx context.Context) (int, error)
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+func NewDispatcher(config config.WebhookConfig, repository RepositoryInterface) DispatcherInterface {
+	return &dispatcher{
+		config:     config,
+		repository: repository,
+		client:     newClient(config),
+	}
+}
+
+// Dispatch sends the batch outside any transaction. The lease outlasts the
+// request timeout, so a delivery whose dispatcher dies midway is picked up
+// again once it expires.
+func (d *dispatcher) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	lease := now.Add(2 * time.Duration(d.config.Timeout) * time.Second)
+
+	deliveries, err := d.repository.LeaseDueDeliveries(ctx, now, lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	endpoints := make(map[uuid.UUID]domain.WebhookEndpointInterface)
+	for _, delivery := range deliveries {
+		id := delivery.GetEndpointID()
+		if _, ok := endpoints[id]; ok {
+			continue
+		}
+		endpoint, err := d.repository.FindEndpoint(ctx, id)
+		if err != nil && !errors.Is(err, ErrEndpointNotFound) {
+			return 0, err
+		}
+		endpoints[id] = endpoint
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		endpoint := endpoints[delivery.GetEndpointID()]
+		if endpoint == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.attempt(ctx, endpoint, delivery)
+		}()
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+func (d *dispatcher) Start(ctx context.Context) error {
+	slog.Info("starting webhook dispatcher", slog.Int("interval", d.config.PollInterval))
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.done.Add(1)
+	go d.run(runCtx)
+
+	return nil
+}
+
+func (d *dispatcher) Stop(ctx context.Context) error {
+	if d.cancel != nil {
+		d.cancel()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		d.done.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		d.client.CloseIdleConnections()
+		slog.Info("webhook dispatcher stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dispatcher) run(ctx context.Context) {
+	defer d.done.Done()
+
+	ticker := time.NewTicker(time.Duration(d.config.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.drain(ctx)
+			d.purge(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// drain dispatches batches until one comes back short.
+func (d *dispatcher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		leased, err := d.Dispatch(ctx)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				slog.Error("failed to dispatch webhook deliveries", "error", err)
+			}
+			return
+		}
+		if leased < d.config.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *dispatcher) purge(ctx context.Context) {
+	before := time.Now().UTC().Add(-time.Duration(d.config.Retention) * time.Second)
+
+	deleted, err := d.repository.DeleteFinishedDeliveries(ctx, before)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Warn("failed to purge webhook deliveries", "error", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		slog.Debug("purged webhook deliveries", slog.Int64("count", deleted))
+	}
+}
+
+// attempt sends the delivery once and records the outcome. Shutdown
+// cancels the request, which then counts as a failed attempt like any
+// other.
+func (d *dispatcher) attempt(ctx context.Context, endpoint domain.WebhookEndpointInterface, delivery domain.WebhookDeliveryInterface) {
+	status, err := d.send(ctx, endpoint, delivery)
+	now := time.Now().UTC()
+
+	if err == nil {
+		delivery.Succeed(now, status)
+	} else {
+		var retryAt *time.Time
+		if d.config.MaxAttempts == 0 || delivery.GetAttempts()+1 < d.config.MaxAttempts {
+			next := now.Add(d.backoff(delivery.GetAttempts() + 1))
+			retryAt = &next
+		}
+		delivery.Fail(now, status, err.Error(), retryAt)
+
+		logger := slog.Warn
+		message := "failed to deliver webhook"
+		if retryAt == nil {
+			logger = slog.Error
+			message = "giving up on webhook delivery"
+		}
+		logger(message,
+			slog.String("delivery_id", delivery.GetID().String()),
+			slog.String("endpoint_id", endpoint.GetID().String()),
+			slog.String("event_type", delivery.GetEventType()),
+			slog.Int("attempts", delivery.GetAttempts()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	// The outcome is saved even when shutdown cancelled the attempt.
+	if err := d.repository.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		slog.Error("failed to record webhook delivery", "error", err, slog.String("delivery_id", delivery.GetID().String()))
+	}
+}
+
+// send posts the payload and returns the response status, zero when none
+// came back. Any 2xx response accepts the delivery.
+func (d *dispatcher) send(ctx context.Context, endpoint domain.WebhookEndpointInterface, delivery domain.WebhookDeliveryInterface) (int, error) {
+	body := delivery.GetPayload()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.GetURL(), bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, delivery.GetID().String())
+	req.Header.Set(outbox.HeaderEventID, delivery.GetEventID().String())
+	req.Header.Set(outbox.HeaderEventType, delivery.GetEventType())
+	req.Header.Set(outbox.HeaderSignature, outbox.Sign(endpoint.GetSecret(), time.Now().UTC(), body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer res.Body.Close()
+
+	excerpt, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseExcerpt))
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		reason := fmt.Sprintf("webhook responded with status %d", res.StatusCode)
+		if text := strings.ToValidUTF8(strings.TrimSpace(string(excerpt)), ""); text != "" {
+			reason += ": " + text
+		}
+		return res.StatusCode, errors.New(reason)
+	}
+
+	return res.StatusCode, nil
+}
+
+// backoff doubles the retry delay with each attempt, up to the maximum, and
+// picks at random from its upper half so endpoints recovering from an
+// outage are not hit by every retry at once.
+func (d *dispatcher) backoff(attempt int) time.Duration {
+	delay := time.Duration(d.config.RetryBackoff) * time.Second
+	limit := time.Duration(d.config.MaxRetryBackoff) * time.Second
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// newClient builds the client deliveries go out through. It does not follow
+// redirects or use a proxy, and unless private networks are allowed it
+// refuses to connect to loopback, private and link-local addresses, which
+// are checked once resolved so DNS cannot sneak them past.
+func newClient(config config.WebhookConfig) *http.Client {
+	dialer := &net.Dialer{Timeout: time.Duration(config.Timeout) * time.Second}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: time.Duration(config.Timeout) * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: time.Duration(config.Timeout) * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,354 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/google/uuid"
+)
+
+const testEventType = "user.created"
+
+func TestDispatchDeliversSignedPayload(t *testing.T) {
+	receiver := newReceiver(t)
+	env := newDispatchEnv(t, receiver.URL(), testConfig())
+	delivery := env.queue(t)
+
+	leased, err := env.dispatcher.Dispatch(context.Background())
+	if err != nil || leased != 1 {
+		t.Fatalf("Dispatch() = %d, %v; want 1 leased", leased, err)
+	}
+
+	requests := receiver.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.header.Get(HeaderDeliveryID) != delivery.GetID().String() ||
+		req.header.Get(outbox.HeaderEventID) != delivery.GetEventID().String() ||
+		req.header.Get(outbox.HeaderEventType) != testEventType ||
+		req.header.Get("Content-Type") != "application/json" {
+		t.Fatalf("headers = %v", req.header)
+	}
+	if string(req.body) != string(delivery.GetPayload()) {
+		t.Fatalf("body = %s, want %s", req.body, delivery.GetPayload())
+	}
+	verifySignature(t, env.endpoint.GetSecret(), req.header.Get(outbox.HeaderSignature), req.body)
+
+	if delivery.GetStatus() != domain.WebhookDeliverySucceeded || delivery.GetAttempts() != 1 ||
+		delivery.GetResponseStatus() != http.StatusNoContent || delivery.GetDeliveredAt() == nil {
+		t.Fatalf("delivery = %s after %d attempts, status %d", delivery.GetStatus(), delivery.GetAttempts(), delivery.GetResponseStatus())
+	}
+}
+
+func TestDispatchRetriesUntilDeadThenRedelivers(t *testing.T) {
+	receiver := newReceiver(t)
+	receiver.Respond(http.StatusServiceUnavailable, "down for maintenance")
+
+	cfg := testConfig()
+	cfg.MaxAttempts = 3
+	env := newDispatchEnv(t, receiver.URL(), cfg)
+	delivery := env.queue(t)
+
+	for attempt := 1; attempt <= cfg.MaxAttempts; attempt++ {
+		before := time.Now().UTC()
+		if _, err := env.dispatcher.Dispatch(context.Background()); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+		if delivery.GetAttempts() != attempt || delivery.GetResponseStatus() != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: attempts = %d, status = %d", attempt, delivery.GetAttempts(), delivery.GetResponseStatus())
+		}
+		if !strings.Contains(delivery.GetLastError(), "status 503: down for maintenance") {
+			t.Fatalf("attempt %d: last error = %q", attempt, delivery.GetLastError())
+		}
+		if attempt < cfg.MaxAttempts {
+			if delivery.GetStatus() != domain.WebhookDeliveryPending {
+				t.Fatalf("attempt %d: status = %s, want pending", attempt, delivery.GetStatus())
+			}
+			if delivery.GetNextAttemptAt().Before(before) {
+				t.Fatalf("attempt %d: retry at %s, before the attempt", attempt, delivery.GetNextAttemptAt())
+			}
+		}
+	}
+	if delivery.GetStatus() != domain.WebhookDeliveryDead {
+		t.Fatalf("status = %s after %d attempts, want dead", delivery.GetStatus(), delivery.GetAttempts())
+	}
+
+	leased, err := env.dispatcher.Dispatch(context.Background())
+	if err != nil || leased != 0 {
+		t.Fatalf("Dispatch() of a dead delivery = %d, %v; want none leased", leased, err)
+	}
+	if got := len(receiver.Requests()); got != cfg.MaxAttempts {
+		t.Fatalf("receiver got %d requests, want %d", got, cfg.MaxAttempts)
+	}
+
+	receiver.Respond(http.StatusOK, "")
+	delivery.Redeliver(time.Now().UTC())
+	if _, err := env.dispatcher.Dispatch(context.Background()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if delivery.GetStatus() != domain.WebhookDeliverySucceeded || delivery.GetAttempts() != 1 || delivery.GetLastError() != "" {
+		t.Fatalf("redelivered: status = %s, attempts = %d, error = %q", delivery.GetStatus(), delivery.GetAttempts(), delivery.GetLastError())
+	}
+}
+
+func TestDispatchRefusesPrivateAddresses(t *testing.T) {
+	receiver := newReceiver(t)
+
+	cfg := testConfig()
+	cfg.AllowPrivateNetworks = false
+	env := newDispatchEnv(t, receiver.URL(), cfg)
+	delivery := env.queue(t)
+
+	if _, err := env.dispatcher.Dispatch(context.Background()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if len(receiver.Requests()) != 0 {
+		t.Fatal("receiver on loopback was reached")
+	}
+	if delivery.GetStatus() != domain.WebhookDeliveryPending || delivery.GetResponseStatus() != 0 ||
+		!strings.Contains(delivery.GetLastError(), errPrivateAddress.Error()) {
+		t.Fatalf("delivery = %s, status %d, error %q", delivery.GetStatus(), delivery.GetResponseStatus(), delivery.GetLastError())
+	}
+}
+
+func TestBackoffGrowsWithJitterUpToLimit(t *testing.T) {
+	d := &dispatcher{config: config.WebhookConfig{RetryBackoff: 2, MaxRetryBackoff: 10}}
+
+	for attempt, want := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 3: 8 * time.Second, 4: 10 * time.Second, 9: 10 * time.Second} {
+		for range 20 {
+			if got := d.backoff(attempt); got < want/2 || got > want {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, got, want/2, want)
+			}
+		}
+	}
+}
+
+func TestSinkQueuesOneDeliveryPerSubscriber(t *testing.T) {
+	repository := newFakeRepository()
+	subscribed := domain.NewWebhookEndpoint("https://a.example.com/hook", "", []string{testEventType})
+	other := domain.NewWebhookEndpoint("https://b.example.com/hook", "", []string{"user.deleted"})
+	repository.endpoints[subscribed.GetID()] = subscribed
+	repository.endpoints[other.GetID()] = other
+
+	envelope := outbox.Envelope{ID: uuid.New(), Type: testEventType, Subject: "user-1", OccurredAt: time.Now().UTC()}
+	if err := NewSink(repository).Deliver(context.Background(), envelope); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	if len(repository.deliveries) != 1 {
+		t.Fatalf("queued %d deliveries, want 1", len(repository.deliveries))
+	}
+	delivery := repository.deliveries[0]
+	var payload outbox.Envelope
+	if err := json.Unmarshal(delivery.GetPayload(), &payload); err != nil {
+		t.Fatalf("payload is not an envelope: %v", err)
+	}
+	if delivery.GetEndpointID() != subscribed.GetID() || delivery.GetEventID() != envelope.ID || payload.ID != envelope.ID {
+		t.Fatalf("delivery for endpoint %s, event %s", delivery.GetEndpointID(), delivery.GetEventID())
+	}
+}
+
+// verifySignature checks the header the way a receiver would, without
+// going through outbox.Sign.
+func verifySignature(t *testing.T, secret, header string, body []byte) {
+	t.Helper()
+
+	timestamp, signature, ok := strings.Cut(header, ",")
+	if !ok || !strings.HasPrefix(timestamp, "t=") || !strings.HasPrefix(signature, "v1=") {
+		t.Fatalf("signature header = %q", header)
+	}
+	timestamp = strings.TrimPrefix(timestamp, "t=")
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		t.Fatalf("signature timestamp %q: %v", timestamp, err)
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age < -time.Minute || age > time.Minute {
+		t.Fatalf("signature timestamp is %s old", age)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); !hmac.Equal([]byte(strings.TrimPrefix(signature, "v1=")), []byte(want)) {
+		t.Fatalf("signature = %s, want v1=%s", signature, want)
+	}
+}
+
+func testConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		PollInterval:         1,
+		BatchSize:            10,
+		Timeout:              2,
+		RetryBackoff:         1,
+		MaxRetryBackoff:      4,
+		Retention:            3600,
+		AllowPrivateNetworks: true,
+	}
+}
+
+type dispatchEnv struct {
+	dispatcher DispatcherInterface
+	repository *fakeRepository
+	endpoint   domain.WebhookEndpointInterface
+}
+
+func newDispatchEnv(t *testing.T, url string, cfg config.WebhookConfig) *dispatchEnv {
+	t.Helper()
+
+	repository := newFakeRepository()
+	endpoint := domain.NewWebhookEndpoint(url, "test receiver", []string{testEventType})
+	repository.endpoints[endpoint.GetID()] = endpoint
+
+	dispatcher := NewDispatcher(cfg, repository)
+	t.Cleanup(func() {
+		if err := dispatcher.Stop(context.Background()); err != nil {
+			t.Errorf("Stop() error = %v", err)
+		}
+	})
+
+	return &dispatchEnv{dispatcher: dispatcher, repository: repository, endpoint: endpoint}
+}
+
+func (e *dispatchEnv) queue(t *testing.T) domain.WebhookDeliveryInterface {
+	t.Helper()
+
+	payload, err := json.Marshal(outbox.Envelope{ID: uuid.New(), Type: testEventType, Subject: "user-1", OccurredAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	delivery := domain.NewWebhookDelivery(e.endpoint.GetID(), uuid.New(), testEventType, payload)
+	if err := e.repository.CreateDelivery(context.Background(), delivery); err != nil {
+		t.Fatalf("CreateDelivery() error = %v", err)
+	}
+	return delivery
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local endpoint that records what it is sent and answers
+// with a configurable status.
+type receiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	reply    string
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T) *receiver {
+	t.Helper()
+
+	r := &receiver{status: http.StatusNoContent}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		status, reply := r.status, r.reply
+		r.mu.Unlock()
+
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, reply)
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *receiver) URL() string {
+	return r.server.URL
+}
+
+func (r *receiver) Respond(status int, reply string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status, r.reply = status, reply
+}
+
+func (r *receiver) Requests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+// fakeRepository leases every pending delivery whether or not it is due,
+// so tests do not wait out the backoff between attempts.
+type fakeRepository struct {
+	RepositoryInterface
+
+	mu         sync.Mutex
+	endpoints  map[uuid.UUID]domain.WebhookEndpointInterface
+	deliveries []domain.WebhookDeliveryInterface
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{endpoints: map[uuid.UUID]domain.WebhookEndpointInterface{}}
+}
+
+func (r *fakeRepository) FindEndpoint(_ context.Context, id uuid.UUID) (domain.WebhookEndpointInterface, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endpoint, ok := r.endpoints[id]
+	if !ok {
+		return nil, ErrEndpointNotFound
+	}
+	return endpoint, nil
+}
+
+func (r *fakeRepository) FindSubscribed(_ context.Context, eventType string) ([]domain.WebhookEndpointInterface, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var endpoints []domain.WebhookEndpointInterface
+	for _, endpoint := range r.endpoints {
+		if endpoint.IsEnabled() && endpoint.Subscribes(eventType) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+func (r *fakeRepository) CreateDelivery(_ context.Context, delivery domain.WebhookDeliveryInterface) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+func (r *fakeRepository) LeaseDueDeliveries(_ context.Context, _, _ time.Time, limit int) ([]domain.WebhookDeliveryInterface, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var leased []domain.WebhookDeliveryInterface
+	for _, delivery := range r.deliveries {
+		if delivery.GetStatus() == domain.WebhookDeliveryPending && len(leased) < limit {
+			leased = append(leased, delivery)
+		}
+	}
+	return leased, nil
+}
+
+func (r *fakeRepository) UpdateDelivery(context.Context, domain.WebhookDeliveryInterface) error {
+	return nil
+}
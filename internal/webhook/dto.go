@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+)
+
+type CreateEndpointRequest struct {
+	URL         string   `json:"url" binding:"required,max=2048"`
+	Description string   `json:"description" binding:"max=255"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1,dive,required,max=64"`
+}
+
+// UpdateEndpointRequest replaces the fields it sets and leaves the others
+// untouched.
+type UpdateEndpointRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,max=2048"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,min=1,dive,required,max=64"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+type ListDeliveriesRequest struct {
+	Status    string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	EventType string `form:"event_type" binding:"max=64"`
+	EventID   string `form:"event_id" binding:"omitempty,uuid"`
+	Cursor    int64  `form:"cursor" binding:"omitempty,min=1"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type EndpointResponse struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	EventTypes  []string   `json:"event_types"`
+	Enabled     bool       `json:"enabled"`
+	Secret      string     `json:"secret,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type DeliveryResponse struct {
+	Seq            int64           `json:"seq"`
+	ID             string          `json:"id"`
+	EndpointID     string          `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+}
+
+type ListDeliveriesResponse struct {
+	Deliveries []DeliveryResponse `json:"deliveries"`
+	// NextCursor fetches the next, older page; it is empty on the last one.
+	NextCursor int64 `json:"next_cursor,omitempty"`
+}
+
+// NewEndpointResponse includes the secret only when given one, which
+// happens when it was just generated.
+func NewEndpointResponse(endpoint domain.WebhookEndpointInterface, secret string) EndpointResponse {
+	return EndpointResponse{
+		ID:          endpoint.GetID().String(),
+		URL:         endpoint.GetURL(),
+		Description: endpoint.GetDescription(),
+		EventTypes:  endpoint.GetEventTypes(),
+		Enabled:     endpoint.IsEnabled(),
+		Secret:      secret,
+		DisabledAt:  endpoint.GetDisabledAt(),
+		CreatedAt:   endpoint.GetCreatedAt(),
+		UpdatedAt:   endpoint.GetUpdatedAt(),
+	}
+}
+
+// NewDeliveryResponse leaves the payload out of the delivery log unless
+// asked for it.
+func NewDeliveryResponse(delivery domain.WebhookDeliveryInterface, withPayload bool) DeliveryResponse {
+	res := DeliveryResponse{
+		Seq:            delivery.GetSeq(),
+		ID:             delivery.GetID().String(),
+		EndpointID:     delivery.GetEndpointID().String(),
+		EventID:        delivery.GetEventID().String(),
+		EventType:      delivery.GetEventType(),
+		Status:         delivery.GetStatus(),
+		Attempts:       delivery.GetAttempts(),
+		LastAttemptAt:  delivery.GetLastAttemptAt(),
+		ResponseStatus: delivery.GetResponseStatus(),
+		LastError:      delivery.GetLastError(),
+		CreatedAt:      delivery.GetCreatedAt(),
+		DeliveredAt:    delivery.GetDeliveredAt(),
+	}
+	if delivery.GetStatus() == domain.WebhookDeliveryPending {
+		next := delivery.GetNextAttemptAt()
+		res.NextAttemptAt = &next
+	}
+	if withPayload {
+		res.Payload = delivery.GetPayload()
+	}
+	return res
+}
@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/felipeversiane/auth-service/internal/infra/config"
+	"github.com/felipeversiane/auth-service/internal/middleware"
+	"github.com/felipeversiane/auth-service/internal/token"
+	"github.com/felipeversiane/auth-service/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions guarding the webhook API.
+const (
+	ResourceWebhooks = "webhooks"
+	ActionManage     = "manage"
+)
+
+type handler struct {
+	adminConfig config.AdminConfig
+	service     ServiceInterface
+	checker     middleware.PermissionCheckerInterface
+	tokens      token.ManagerInterface
+}
+
+type HandlerInterface interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	CreateEndpoint(ctx *gin.Context)
+	GetEndpoint(ctx *gin.Context)
+	ListEndpoints(ctx *gin.Context)
+	UpdateEndpoint(ctx *gin.Context)
+	DeleteEndpoint(ctx *gin.Context)
+	RotateSecret(ctx *gin.Context)
+	ListDeliveries(ctx *gin.Context)
+	GetDelivery(ctx *gin.Context)
+	Redeliver(ctx *gin.Context)
+}
+
+func NewHandler(
+	adminConfig config.AdminConfig,
+	service ServiceInterface,
+	checker middleware.PermissionCheckerInterface,
+	tokens token.ManagerInterface,
+) HandlerInterface {
+	return &handler{
+		adminConfig: adminConfig,
+		service:     service,
+		checker:     checker,
+		tokens:      tokens,
+	}
+}
+
+func (h *handler) RegisterRoutes(router *gin.RouterGroup) {
+	manage := middleware.RequireAdminOrPermission(h.adminConfig, h.tokens, h.checker, ResourceWebhooks, ActionManage)
+
+	webhooks := router.Group("/api/v1/webhooks", manage)
+	{
+		webhooks.POST("", h.CreateEndpoint)
+		webhooks.GET("", h.ListEndpoints)
+		webhooks.GET("/:id", h.GetEndpoint)
+		webhooks.PATCH("/:id", h.UpdateEndpoint)
+		webhooks.DELETE("/:id", h.DeleteEndpoint)
+		webhooks.POST("/:id/rotate-secret", h.RotateSecret)
+		webhooks.GET("/:id/deliveries", h.ListDeliveries)
+		webhooks.GET("/:id/deliveries/:delivery_id", h.GetDelivery)
+		webhooks.POST("/:id/deliveries/:delivery_id/redeliver", h.Redeliver)
+	}
+}
+
+func (h *handler) CreateEndpoint(ctx *gin.Context) {
+	var req CreateEndpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.CreateEndpoint(ctx.Request.Context(), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (h *handler) GetEndpoint(ctx *gin.Context) {
+	res, restErr := h.service.GetEndpoint(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListEndpoints(ctx *gin.Context) {
+	res, restErr := h.service.ListEndpoints(ctx.Request.Context())
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) UpdateEndpoint(ctx *gin.Context) {
+	var req UpdateEndpointRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.UpdateEndpoint(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) DeleteEndpoint(ctx *gin.Context) {
+	if restErr := h.service.DeleteEndpoint(ctx.Request.Context(), ctx.Param("id")); restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *handler) RotateSecret(ctx *gin.Context) {
+	res, restErr := h.service.RotateSecret(ctx.Request.Context(), ctx.Param("id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) ListDeliveries(ctx *gin.Context) {
+	var req ListDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		restErr := validation.ValidateBinding(err)
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	res, restErr := h.service.ListDeliveries(ctx.Request.Context(), ctx.Param("id"), req)
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) GetDelivery(ctx *gin.Context) {
+	res, restErr := h.service.GetDelivery(ctx.Request.Context(), ctx.Param("id"), ctx.Param("delivery_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (h *handler) Redeliver(ctx *gin.Context) {
+	res, restErr := h.service.Redeliver(ctx.Request.Context(), ctx.Param("id"), ctx.Param("delivery_id"))
+	if restErr != nil {
+		ctx.JSON(restErr.Code, restErr)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, res)
+}
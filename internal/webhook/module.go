@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"context"
+
+	httpserver "github.com/felipeversiane/auth-service/internal/infra/http"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewService,
+		NewDispatcher,
+		outbox.AsSink(NewSink),
+		httpserver.AsRouter(NewHandler),
+	),
+	fx.Invoke(func(lc fx.Lifecycle, dispatcher DispatcherInterface) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				return dispatcher.Start(ctx)
+			},
+			OnStop: func(ctx context.Context) error {
+				return dispatcher.Stop(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,403 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/pkg/secretbox"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+const selectEndpointColumns = `
+	SELECT id, url, description, event_types, secret, disabled_at, created_at, updated_at
+	FROM webhook_endpoints`
+
+const deliveryColumns = `
+	d.seq, d.id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_attempt_at, d.response_status, d.last_error, d.created_at, d.delivered_at`
+
+// DeliveryFilter narrows the delivery log of an endpoint. Zero fields match
+// everything; BeforeSeq bounds the sequence exclusively.
+type DeliveryFilter struct {
+	EndpointID uuid.UUID
+	Status     string
+	EventType  string
+	EventID    *uuid.UUID
+	BeforeSeq  int64
+}
+
+type repository struct {
+	db  database.DatabaseInterface
+	box secretbox.BoxInterface
+}
+
+type RepositoryInterface interface {
+	CreateEndpoint(ctx context.Context, endpoint domain.WebhookEndpointInterface) error
+	FindEndpoint(ctx context.Context, id uuid.UUID) (domain.WebhookEndpointInterface, error)
+	FindEndpoints(ctx context.Context) ([]domain.WebhookEndpointInterface, error)
+	// FindSubscribed returns the endpoints subscribed to eventType, enabled
+	// or not.
+	FindSubscribed(ctx context.Context, eventType string) ([]domain.WebhookEndpointInterface, error)
+	UpdateEndpoint(ctx context.Context, endpoint domain.WebhookEndpointInterface) error
+	DeleteEndpoint(ctx context.Context, id uuid.UUID) error
+	// CreateDelivery queues a delivery unless the endpoint already has one
+	// for the event.
+	CreateDelivery(ctx context.Context, delivery domain.WebhookDeliveryInterface) error
+	FindDelivery(ctx context.Context, id uuid.UUID) (domain.WebhookDeliveryInterface, error)
+	// FindDeliveryPage returns up to limit deliveries, newest first.
+	FindDeliveryPage(ctx context.Context, filter DeliveryFilter, limit int) ([]domain.WebhookDeliveryInterface, error)
+	// LeaseDueDeliveries takes up to limit pending deliveries of enabled
+	// endpoints due at now, oldest first, and pushes their next attempt to
+	// leaseUntil, so other dispatchers leave them alone while they are sent
+	// and pick them up again should this one die midway.
+	LeaseDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDeliveryInterface, error)
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDeliveryInterface) error
+	// DeleteFinishedDeliveries drops succeeded and dead deliveries created
+	// before the given time.
+	DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewRepository(db database.DatabaseInterface, box secretbox.BoxInterface) RepositoryInterface {
+	return &repository{
+		db:  db,
+		box: box,
+	}
+}
+
+func (r *repository) CreateEndpoint(ctx context.Context, endpoint domain.WebhookEndpointInterface) error {
+	sealed, err := r.box.Seal([]byte(endpoint.GetSecret()), secretAD(endpoint.GetID()))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_endpoints (id, url, description, event_types, secret, disabled_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = r.db.GetQuerier(ctx).Exec(ctx, query,
+		endpoint.GetID(),
+		endpoint.GetURL(),
+		endpoint.GetDescription(),
+		endpoint.GetEventTypes(),
+		sealed,
+		endpoint.GetDisabledAt(),
+		endpoint.GetCreatedAt(),
+		endpoint.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindEndpoint(ctx context.Context, id uuid.UUID) (domain.WebhookEndpointInterface, error) {
+	query := selectEndpointColumns + ` WHERE id = $1`
+
+	return r.scanEndpoint(r.db.GetQuerier(ctx).QueryRow(ctx, query, id))
+}
+
+func (r *repository) FindEndpoints(ctx context.Context) ([]domain.WebhookEndpointInterface, error) {
+	query := selectEndpointColumns + ` ORDER BY created_at`
+
+	return r.queryEndpoints(ctx, query)
+}
+
+func (r *repository) FindSubscribed(ctx context.Context, eventType string) ([]domain.WebhookEndpointInterface, error) {
+	query := selectEndpointColumns + `
+		WHERE $1 = ANY(event_types) OR $2 = ANY(event_types)
+		ORDER BY created_at`
+
+	return r.queryEndpoints(ctx, query, eventType, domain.WebhookAllEvents)
+}
+
+func (r *repository) UpdateEndpoint(ctx context.Context, endpoint domain.WebhookEndpointInterface) error {
+	sealed, err := r.box.Seal([]byte(endpoint.GetSecret()), secretAD(endpoint.GetID()))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+
+	query := `
+		UPDATE webhook_endpoints
+		SET url = $2, description = $3, event_types = $4, secret = $5, disabled_at = $6, updated_at = $7
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		endpoint.GetID(),
+		endpoint.GetURL(),
+		endpoint.GetDescription(),
+		endpoint.GetEventTypes(),
+		sealed,
+		endpoint.GetDisabledAt(),
+		endpoint.GetUpdatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEndpointNotFound
+	}
+
+	return nil
+}
+
+// DeleteEndpoint removes the endpoint along with its delivery log.
+func (r *repository) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEndpointNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) CreateDelivery(ctx context.Context, delivery domain.WebhookDeliveryInterface) error {
+	query := `
+		INSERT INTO webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+
+	_, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		delivery.GetID(),
+		delivery.GetEndpointID(),
+		delivery.GetEventID(),
+		delivery.GetEventType(),
+		delivery.GetPayload(),
+		delivery.GetStatus(),
+		delivery.GetAttempts(),
+		delivery.GetNextAttemptAt(),
+		delivery.GetCreatedAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *repository) FindDelivery(ctx context.Context, id uuid.UUID) (domain.WebhookDeliveryInterface, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d WHERE d.id = $1`
+
+	return scanDelivery(r.db.GetQuerier(ctx).QueryRow(ctx, query, id))
+}
+
+func (r *repository) FindDeliveryPage(ctx context.Context, filter DeliveryFilter, limit int) ([]domain.WebhookDeliveryInterface, error) {
+	where, args := filter.build()
+	args = append(args, limit)
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d` + where +
+		` ORDER BY d.seq DESC LIMIT $` + strconv.Itoa(len(args))
+
+	return r.queryDeliveries(ctx, query, args...)
+}
+
+func (r *repository) LeaseDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDeliveryInterface, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $3
+		WHERE d.id IN (
+			SELECT due.id
+			FROM webhook_deliveries due
+			JOIN webhook_endpoints e ON e.id = due.endpoint_id
+			WHERE due.status = $1 AND due.next_attempt_at <= $2 AND e.disabled_at IS NULL
+			ORDER BY due.seq
+			LIMIT $4
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+
+	return r.queryDeliveries(ctx, query, domain.WebhookDeliveryPending, now, leaseUntil, limit)
+}
+
+func (r *repository) UpdateDelivery(ctx context.Context, delivery domain.WebhookDeliveryInterface) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+			response_status = $6, last_error = $7, delivered_at = $8
+		WHERE id = $1`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query,
+		delivery.GetID(),
+		delivery.GetStatus(),
+		delivery.GetAttempts(),
+		delivery.GetNextAttemptAt(),
+		delivery.GetLastAttemptAt(),
+		delivery.GetResponseStatus(),
+		delivery.GetLastError(),
+		delivery.GetDeliveredAt(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM webhook_deliveries WHERE status <> $1 AND created_at < $2`
+
+	tag, err := r.db.GetQuerier(ctx).Exec(ctx, query, domain.WebhookDeliveryPending, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *repository) queryEndpoints(ctx context.Context, query string, args ...any) ([]domain.WebhookEndpointInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []domain.WebhookEndpointInterface
+	for rows.Next() {
+		endpoint, err := r.scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (r *repository) queryDeliveries(ctx context.Context, query string, args ...any) ([]domain.WebhookDeliveryInterface, error) {
+	rows, err := r.db.GetQuerier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.WebhookDeliveryInterface
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *repository) scanEndpoint(row pgx.Row) (domain.WebhookEndpointInterface, error) {
+	var (
+		id          uuid.UUID
+		url         string
+		description string
+		eventTypes  []string
+		sealed      []byte
+		disabledAt  *time.Time
+		createdAt   time.Time
+		updatedAt   time.Time
+	)
+
+	err := row.Scan(&id, &url, &description, &eventTypes, &sealed, &disabledAt, &createdAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEndpointNotFound
+		}
+		return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+	}
+
+	secret, err := r.box.Open(sealed, secretAD(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+
+	return domain.RestoreWebhookEndpoint(id, url, description, eventTypes, string(secret), disabledAt, createdAt, updatedAt), nil
+}
+
+func scanDelivery(row pgx.Row) (domain.WebhookDeliveryInterface, error) {
+	var (
+		seq            int64
+		id             uuid.UUID
+		endpointID     uuid.UUID
+		eventID        uuid.UUID
+		eventType      string
+		payload        []byte
+		status         string
+		attempts       int
+		nextAttemptAt  time.Time
+		lastAttemptAt  *time.Time
+		responseStatus int
+		lastError      string
+		createdAt      time.Time
+		deliveredAt    *time.Time
+	)
+
+	err := row.Scan(
+		&seq, &id, &endpointID, &eventID, &eventType, &payload, &status, &attempts,
+		&nextAttemptAt, &lastAttemptAt, &responseStatus, &lastError, &createdAt, &deliveredAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+
+	return domain.RestoreWebhookDelivery(
+		id, seq, endpointID, eventID, eventType, payload, status, attempts,
+		nextAttemptAt, lastAttemptAt, responseStatus, lastError, createdAt, deliveredAt,
+	), nil
+}
+
+func (f DeliveryFilter) build() (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	add("d.endpoint_id = $%d", f.EndpointID)
+	if f.Status != "" {
+		add("d.status = $%d", f.Status)
+	}
+	if f.EventType != "" {
+		add("d.event_type = $%d", f.EventType)
+	}
+	if f.EventID != nil {
+		add("d.event_id = $%d", *f.EventID)
+	}
+	if f.BeforeSeq > 0 {
+		add("d.seq < $%d", f.BeforeSeq)
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// secretAD binds a sealed secret to its endpoint, so it cannot be swapped
+// into another row.
+func secretAD(id uuid.UUID) []byte {
+	return []byte("webhook:" + id.String())
+}
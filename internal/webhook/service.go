@@ -0,0 +1,395 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/felipeversiane/auth-service/internal/audit"
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/infra/database"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/google/uuid"
+)
+
+const defaultPageSize = 100
+
+type service struct {
+	db         database.DatabaseInterface
+	repository RepositoryInterface
+	audit      audit.RecorderInterface
+}
+
+type ServiceInterface interface {
+	// CreateEndpoint returns the signing secret, which is never shown
+	// again.
+	CreateEndpoint(ctx context.Context, req CreateEndpointRequest) (*EndpointResponse, *httperr.HttpError)
+	GetEndpoint(ctx context.Context, id string) (*EndpointResponse, *httperr.HttpError)
+	ListEndpoints(ctx context.Context) ([]EndpointResponse, *httperr.HttpError)
+	UpdateEndpoint(ctx context.Context, id string, req UpdateEndpointRequest) (*EndpointResponse, *httperr.HttpError)
+	DeleteEndpoint(ctx context.Context, id string) *httperr.HttpError
+	RotateSecret(ctx context.Context, id string) (*EndpointResponse, *httperr.HttpError)
+	ListDeliveries(ctx context.Context, endpointID string, req ListDeliveriesRequest) (*ListDeliveriesResponse, *httperr.HttpError)
+	GetDelivery(ctx context.Context, endpointID, id string) (*DeliveryResponse, *httperr.HttpError)
+	// Redeliver queues a succeeded or dead delivery again, with the same
+	// payload and a fresh round of attempts.
+	Redeliver(ctx context.Context, endpointID, id string) (*DeliveryResponse, *httperr.HttpError)
+}
+
+func NewService(
+	db database.DatabaseInterface,
+	repository RepositoryInterface,
+	audit audit.RecorderInterface,
+) ServiceInterface {
+	return &service{
+		db:         db,
+		repository: repository,
+		audit:      audit,
+	}
+}
+
+func (s *service) CreateEndpoint(ctx context.Context, req CreateEndpointRequest) (*EndpointResponse, *httperr.HttpError) {
+	endpointURL, restErr := validateURL(req.URL)
+	if restErr != nil {
+		return nil, restErr
+	}
+	eventTypes, restErr := validateEventTypes(req.EventTypes)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	endpoint := domain.NewWebhookEndpoint(endpointURL, strings.TrimSpace(req.Description), eventTypes)
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.CreateEndpoint(ctx, endpoint); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionWebhookCreate, audit.TargetWebhook, endpoint.GetID(), describe(endpoint))
+	})
+	if err != nil {
+		slog.Error("failed to create webhook endpoint", "error", err)
+		return nil, httperr.NewInternalServerError("failed to create webhook endpoint")
+	}
+
+	res := NewEndpointResponse(endpoint, endpoint.GetSecret())
+	return &res, nil
+}
+
+func (s *service) GetEndpoint(ctx context.Context, id string) (*EndpointResponse, *httperr.HttpError) {
+	endpoint, restErr := s.findEndpoint(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewEndpointResponse(endpoint, "")
+	return &res, nil
+}
+
+func (s *service) ListEndpoints(ctx context.Context) ([]EndpointResponse, *httperr.HttpError) {
+	endpoints, err := s.repository.FindEndpoints(ctx)
+	if err != nil {
+		slog.Error("failed to list webhook endpoints", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list webhook endpoints")
+	}
+
+	res := make([]EndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		res = append(res, NewEndpointResponse(endpoint, ""))
+	}
+
+	return res, nil
+}
+
+func (s *service) UpdateEndpoint(ctx context.Context, id string, req UpdateEndpointRequest) (*EndpointResponse, *httperr.HttpError) {
+	endpoint, restErr := s.findEndpoint(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	endpointURL, description, eventTypes := endpoint.GetURL(), endpoint.GetDescription(), endpoint.GetEventTypes()
+	if req.URL != nil {
+		if endpointURL, restErr = validateURL(*req.URL); restErr != nil {
+			return nil, restErr
+		}
+	}
+	if req.Description != nil {
+		description = strings.TrimSpace(*req.Description)
+	}
+	if req.EventTypes != nil {
+		if eventTypes, restErr = validateEventTypes(req.EventTypes); restErr != nil {
+			return nil, restErr
+		}
+	}
+
+	endpoint.Update(endpointURL, description, eventTypes)
+	if req.Enabled != nil {
+		endpoint.SetEnabled(*req.Enabled)
+	}
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdateEndpoint(ctx, endpoint); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionWebhookUpdate, audit.TargetWebhook, endpoint.GetID(), describe(endpoint))
+	})
+	if err != nil {
+		if errors.Is(err, ErrEndpointNotFound) {
+			return nil, httperr.NewNotFoundError("webhook endpoint not found")
+		}
+		slog.Error("failed to update webhook endpoint", "error", err)
+		return nil, httperr.NewInternalServerError("failed to update webhook endpoint")
+	}
+
+	res := NewEndpointResponse(endpoint, "")
+	return &res, nil
+}
+
+func (s *service) DeleteEndpoint(ctx context.Context, id string) *httperr.HttpError {
+	endpointID, err := uuid.Parse(id)
+	if err != nil {
+		return httperr.NewNotFoundError("webhook endpoint not found")
+	}
+
+	err = s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.DeleteEndpoint(ctx, endpointID); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionWebhookDelete, audit.TargetWebhook, endpointID, nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrEndpointNotFound) {
+			return httperr.NewNotFoundError("webhook endpoint not found")
+		}
+		slog.Error("failed to delete webhook endpoint", "error", err)
+		return httperr.NewInternalServerError("failed to delete webhook endpoint")
+	}
+
+	return nil
+}
+
+// RotateSecret takes effect with the next attempt, including retries of
+// deliveries queued before the rotation.
+func (s *service) RotateSecret(ctx context.Context, id string) (*EndpointResponse, *httperr.HttpError) {
+	endpoint, restErr := s.findEndpoint(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	secret := endpoint.RotateSecret()
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdateEndpoint(ctx, endpoint); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionWebhookRotateSecret, audit.TargetWebhook, endpoint.GetID(), nil)
+	})
+	if err != nil {
+		if errors.Is(err, ErrEndpointNotFound) {
+			return nil, httperr.NewNotFoundError("webhook endpoint not found")
+		}
+		slog.Error("failed to rotate webhook secret", "error", err)
+		return nil, httperr.NewInternalServerError("failed to rotate webhook secret")
+	}
+
+	res := NewEndpointResponse(endpoint, secret)
+	return &res, nil
+}
+
+func (s *service) ListDeliveries(ctx context.Context, endpointID string, req ListDeliveriesRequest) (*ListDeliveriesResponse, *httperr.HttpError) {
+	var eventID *uuid.UUID
+	if req.EventID != "" {
+		parsed, err := uuid.Parse(req.EventID)
+		if err != nil {
+			return nil, httperr.NewBadRequestError("event_id must be a uuid")
+		}
+		eventID = &parsed
+	}
+
+	endpoint, restErr := s.findEndpoint(ctx, endpointID)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	filter := DeliveryFilter{
+		EndpointID: endpoint.GetID(),
+		Status:     req.Status,
+		EventType:  req.EventType,
+		BeforeSeq:  req.Cursor,
+		EventID:    eventID,
+	}
+
+	// One extra row tells whether there is a next page.
+	deliveries, err := s.repository.FindDeliveryPage(ctx, filter, limit+1)
+	if err != nil {
+		slog.Error("failed to list webhook deliveries", "error", err)
+		return nil, httperr.NewInternalServerError("failed to list webhook deliveries")
+	}
+
+	res := &ListDeliveriesResponse{Deliveries: make([]DeliveryResponse, 0, min(len(deliveries), limit))}
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		res.NextCursor = deliveries[limit-1].GetSeq()
+	}
+	for _, delivery := range deliveries {
+		res.Deliveries = append(res.Deliveries, NewDeliveryResponse(delivery, false))
+	}
+
+	return res, nil
+}
+
+func (s *service) GetDelivery(ctx context.Context, endpointID, id string) (*DeliveryResponse, *httperr.HttpError) {
+	delivery, restErr := s.findDelivery(ctx, endpointID, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+
+	res := NewDeliveryResponse(delivery, true)
+	return &res, nil
+}
+
+// Redeliver refuses pending deliveries, which may be on their way already
+// and are retried anyway.
+func (s *service) Redeliver(ctx context.Context, endpointID, id string) (*DeliveryResponse, *httperr.HttpError) {
+	delivery, restErr := s.findDelivery(ctx, endpointID, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if delivery.GetStatus() == domain.WebhookDeliveryPending {
+		return nil, httperr.NewBadRequestError("webhook delivery is already pending")
+	}
+
+	previous := delivery.GetStatus()
+	delivery.Redeliver(time.Now().UTC())
+
+	err := s.db.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.repository.UpdateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		return s.record(ctx, audit.ActionWebhookRedeliver, audit.TargetDelivery, delivery.GetID(), map[string]string{
+			"endpoint_id":     delivery.GetEndpointID().String(),
+			"event_id":        delivery.GetEventID().String(),
+			"previous_status": previous,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			return nil, httperr.NewNotFoundError("webhook delivery not found")
+		}
+		slog.Error("failed to redeliver webhook", "error", err)
+		return nil, httperr.NewInternalServerError("failed to redeliver webhook")
+	}
+
+	res := NewDeliveryResponse(delivery, false)
+	return &res, nil
+}
+
+func (s *service) findEndpoint(ctx context.Context, id string) (domain.WebhookEndpointInterface, *httperr.HttpError) {
+	endpointID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("webhook endpoint not found")
+	}
+
+	endpoint, err := s.repository.FindEndpoint(ctx, endpointID)
+	if err != nil {
+		if errors.Is(err, ErrEndpointNotFound) {
+			return nil, httperr.NewNotFoundError("webhook endpoint not found")
+		}
+		slog.Error("failed to find webhook endpoint", "error", err)
+		return nil, httperr.NewInternalServerError("failed to find webhook endpoint")
+	}
+
+	return endpoint, nil
+}
+
+// findDelivery looks the delivery up within the endpoint's log.
+func (s *service) findDelivery(ctx context.Context, endpointID, id string) (domain.WebhookDeliveryInterface, *httperr.HttpError) {
+	parentID, err := uuid.Parse(endpointID)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("webhook delivery not found")
+	}
+	deliveryID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, httperr.NewNotFoundError("webhook delivery not found")
+	}
+
+	delivery, err := s.repository.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			return nil, httperr.NewNotFoundError("webhook delivery not found")
+		}
+		slog.Error("failed to find webhook delivery", "error", err)
+		return nil, httperr.NewInternalServerError("failed to find webhook delivery")
+	}
+	if delivery.GetEndpointID() != parentID {
+		return nil, httperr.NewNotFoundError("webhook delivery not found")
+	}
+
+	return delivery, nil
+}
+
+func (s *service) record(ctx context.Context, action, targetType string, targetID uuid.UUID, metadata map[string]string) error {
+	return s.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		Outcome:    domain.AuditOutcomeSuccess,
+		TargetType: targetType,
+		TargetID:   targetID.String(),
+		Metadata:   metadata,
+	})
+}
+
+func describe(endpoint domain.WebhookEndpointInterface) map[string]string {
+	enabled := "true"
+	if !endpoint.IsEnabled() {
+		enabled = "false"
+	}
+	return map[string]string{
+		"url":         endpoint.GetURL(),
+		"event_types": strings.Join(endpoint.GetEventTypes(), ","),
+		"enabled":     enabled,
+	}
+}
+
+// validateURL accepts absolute http and https URLs. Where they resolve to
+// is checked when connecting, since DNS may change in between.
+func validateURL(raw string) (string, *httperr.HttpError) {
+	raw = strings.TrimSpace(raw)
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return "", invalidFieldError("url", "must be an absolute http or https URL")
+	}
+	if parsed.User != nil {
+		return "", invalidFieldError("url", "must not contain credentials")
+	}
+
+	return raw, nil
+}
+
+func validateEventTypes(eventTypes []string) ([]string, *httperr.HttpError) {
+	valid := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		eventType = strings.TrimSpace(eventType)
+		if eventType != domain.WebhookAllEvents && !slices.Contains(outbox.EventTypes, eventType) {
+			return nil, invalidFieldError("event_types", "unknown event type "+eventType)
+		}
+		if !slices.Contains(valid, eventType) {
+			valid = append(valid, eventType)
+		}
+	}
+
+	return valid, nil
+}
+
+func invalidFieldError(field, message string) *httperr.HttpError {
+	return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+		{Field: field, Message: message},
+	})
+}
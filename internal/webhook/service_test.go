@@ -0,0 +1,16 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestListDeliveriesRejectsMalformedEventID(t *testing.T) {
+	service := NewService(nil, nil, nil)
+
+	_, restErr := service.ListDeliveries(context.Background(), "endpoint", ListDeliveriesRequest{EventID: "not-a-uuid"})
+	if restErr == nil || restErr.Code != http.StatusBadRequest {
+		t.Fatalf("ListDeliveries(malformed event_id) error = %v, want 400", restErr)
+	}
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/felipeversiane/auth-service/internal/domain"
+	"github.com/felipeversiane/auth-service/internal/outbox"
+)
+
+// SinkName identifies the sink among those of the outbox relay.
+const SinkName = "webhooks"
+
+type sink struct {
+	repository RepositoryInterface
+}
+
+// NewSink fans outbox events out to the subscribed endpoints. It only
+// queues a delivery per endpoint, in the relay's transaction, and leaves
+// sending to the dispatcher, so one slow receiver never holds up the relay
+// or the other receivers.
+func NewSink(repository RepositoryInterface) outbox.SinkInterface {
+	return &sink{
+		repository: repository,
+	}
+}
+
+func (s *sink) Name() string {
+	return SinkName
+}
+
+// Deliver may run again for an event whose batch failed on another sink;
+// the delivery log keeps one delivery per endpoint and event regardless.
+func (s *sink) Deliver(ctx context.Context, envelope outbox.Envelope) error {
+	endpoints, err := s.repository.FindSubscribed(ctx, envelope.Type)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	for _, endpoint := range endpoints {
+		delivery := domain.NewWebhookDelivery(endpoint.GetID(), envelope.ID, envelope.Type, payload)
+		if err := s.repository.CreateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *sink) Close() error {
+	return nil
+}
@@ -0,0 +1,12 @@
+CREATE TABLE signing_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    state VARCHAR(16) NOT NULL CHECK (state IN ('next', 'active', 'retired')),
+    private_key BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    activated_at TIMESTAMP,
+    retired_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_signing_keys_single_active ON signing_keys (state) WHERE state = 'active';
@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens
+    ADD COLUMN client_id VARCHAR(64) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    ADD COLUMN scope TEXT NOT NULL DEFAULT '';
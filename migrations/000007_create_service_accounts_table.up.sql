@@ -0,0 +1,12 @@
+CREATE TABLE service_accounts (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    client_id VARCHAR(64) UNIQUE NOT NULL,
+    secret_hash VARCHAR(64),
+    public_key TEXT,
+    audiences TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    disabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,8 @@
+CREATE TABLE permissions (
+    id UUID PRIMARY KEY,
+    resource VARCHAR(128) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (resource, action)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE role_inheritance (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    parent_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role_id, parent_id),
+    CHECK (role_id <> parent_id)
+);
+
+CREATE INDEX idx_role_inheritance_parent_id ON role_inheritance (parent_id);
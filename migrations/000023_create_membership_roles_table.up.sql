@@ -0,0 +1,10 @@
+CREATE TABLE membership_roles (
+    org_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id, role_id),
+    FOREIGN KEY (org_id, user_id) REFERENCES memberships (org_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_membership_roles_role_id ON membership_roles (role_id);
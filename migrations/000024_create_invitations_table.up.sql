@@ -0,0 +1,14 @@
+CREATE TABLE invitations (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role_id UUID REFERENCES roles(id) ON DELETE SET NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_invitations_org_id_email ON invitations (org_id, email);
+CREATE INDEX idx_invitations_expires_at ON invitations (expires_at);
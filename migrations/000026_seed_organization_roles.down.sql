@@ -0,0 +1,2 @@
+DELETE FROM roles WHERE name IN ('org_owner', 'org_member');
+DELETE FROM permissions WHERE resource = 'org' AND action IN ('*', 'read');
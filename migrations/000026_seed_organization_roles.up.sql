@@ -0,0 +1,19 @@
+INSERT INTO roles (id, name, description)
+VALUES
+    (gen_random_uuid(), 'org_owner', 'Full control over an organization'),
+    (gen_random_uuid(), 'org_member', 'Read access to an organization')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (id, resource, action, description)
+VALUES
+    (gen_random_uuid(), 'org', '*', 'Every action on the active organization'),
+    (gen_random_uuid(), 'org', 'read', 'View the active organization and its members')
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.resource = 'org'
+    AND p.action = CASE r.name WHEN 'org_owner' THEN '*' ELSE 'read' END
+WHERE r.name IN ('org_owner', 'org_member')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,19 @@
+DROP POLICY IF EXISTS invitee_read ON invitations;
+DROP POLICY IF EXISTS tenant_isolation ON invitations;
+DROP POLICY IF EXISTS tenant_isolation ON membership_roles;
+DROP POLICY IF EXISTS own_read ON memberships;
+DROP POLICY IF EXISTS tenant_isolation ON memberships;
+DROP POLICY IF EXISTS member_read ON organizations;
+DROP POLICY IF EXISTS tenant_isolation ON organizations;
+
+ALTER TABLE invitations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invitations DISABLE ROW LEVEL SECURITY;
+ALTER TABLE membership_roles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE membership_roles DISABLE ROW LEVEL SECURITY;
+ALTER TABLE memberships NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE memberships DISABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE organizations DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS app_current_user_id();
+DROP FUNCTION IF EXISTS app_current_org_id();
@@ -0,0 +1,49 @@
+-- Tenant isolation enforced by Postgres. The application sets these session
+-- variables transaction-locally before every statement (see
+-- internal/infra/database/scope.go). Unset variables match no rows.
+CREATE FUNCTION app_current_org_id() RETURNS UUID
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('app.current_org_id', true), '')::UUID $$;
+
+CREATE FUNCTION app_current_user_id() RETURNS UUID
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('app.current_user_id', true), '')::UUID $$;
+
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations FORCE ROW LEVEL SECURITY;
+ALTER TABLE memberships ENABLE ROW LEVEL SECURITY;
+ALTER TABLE memberships FORCE ROW LEVEL SECURITY;
+ALTER TABLE membership_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE membership_roles FORCE ROW LEVEL SECURITY;
+ALTER TABLE invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE invitations FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON organizations
+    USING (id = app_current_org_id())
+    WITH CHECK (id = app_current_org_id());
+
+-- Lets a user list the organizations they belong to.
+CREATE POLICY member_read ON organizations
+    FOR SELECT
+    USING (id IN (SELECT org_id FROM memberships WHERE user_id = app_current_user_id()));
+
+CREATE POLICY tenant_isolation ON memberships
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
+
+CREATE POLICY own_read ON memberships
+    FOR SELECT
+    USING (user_id = app_current_user_id());
+
+CREATE POLICY tenant_isolation ON membership_roles
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
+
+CREATE POLICY tenant_isolation ON invitations
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
+
+-- Lets the invitee look an invitation up before joining its organization.
+CREATE POLICY invitee_read ON invitations
+    FOR SELECT
+    USING (email = (SELECT email FROM users WHERE id = app_current_user_id()));
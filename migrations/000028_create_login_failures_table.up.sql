@@ -0,0 +1,10 @@
+CREATE TABLE login_failures (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    failed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_login_failures_email_failed_at ON login_failures (email, failed_at);
+CREATE INDEX idx_login_failures_ip_failed_at ON login_failures (ip, failed_at);
+CREATE INDEX idx_login_failures_failed_at ON login_failures (failed_at);
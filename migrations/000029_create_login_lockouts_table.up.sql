@@ -0,0 +1,12 @@
+-- An empty email or ip is a wildcard: ('', ip) locks an address for every
+-- account and (email, '') locks an account from every address.
+CREATE TABLE login_lockouts (
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    locked_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (email, ip)
+);
+
+CREATE INDEX idx_login_lockouts_ip ON login_lockouts (ip);
+CREATE INDEX idx_login_lockouts_locked_until ON login_lockouts (locked_until);
@@ -0,0 +1,37 @@
+CREATE TABLE audit_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID UNIQUE NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    actor_type VARCHAR(16) NOT NULL DEFAULT '',
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    target_type VARCHAR(64) NOT NULL DEFAULT '',
+    target_id VARCHAR(255) NOT NULL DEFAULT '',
+    org_id UUID,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    trace_id VARCHAR(32) NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX idx_audit_events_action ON audit_events (action, seq);
+CREATE INDEX idx_audit_events_actor ON audit_events (actor_id, seq);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id, seq);
+CREATE INDEX idx_audit_events_org_id ON audit_events (org_id, seq);
+
+-- The log is append-only: rows can be inserted and read, never changed.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,6 @@
+-- Each event stores the hash of the one before it and its own hash over
+-- both, so editing or removing a row breaks every later link. Events
+-- written before this migration stay unchained.
+ALTER TABLE audit_events
+    ADD COLUMN prev_hash BYTEA,
+    ADD COLUMN hash BYTEA;
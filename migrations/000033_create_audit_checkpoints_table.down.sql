@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS audit_checkpoints;
+DROP FUNCTION IF EXISTS audit_checkpoints_append_only();
@@ -0,0 +1,14 @@
+-- Tokens are looked up by hash before the tenant is known, so like
+-- refresh_tokens this table is left out of row-level security; the
+-- repository scopes every other query to the tenant itself.
+CREATE TABLE scim_tokens (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_scim_tokens_org_id ON scim_tokens (org_id);
@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON scim_users;
+DROP TABLE IF EXISTS scim_users;
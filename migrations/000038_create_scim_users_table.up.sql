@@ -0,0 +1,24 @@
+-- A user provisioned over SCIM belongs to the organization that created it,
+-- hence one row per user.
+CREATE TABLE scim_users (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    extension JSONB NOT NULL DEFAULT '{}',
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_scim_users_org_id_user_name ON scim_users (org_id, lower(user_name));
+CREATE INDEX idx_scim_users_org_id_external_id ON scim_users (org_id, external_id);
+CREATE INDEX idx_scim_users_org_id_created_at ON scim_users (org_id, created_at);
+
+ALTER TABLE scim_users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scim_users FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON scim_users
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON scim_groups;
+DROP TABLE IF EXISTS scim_groups;
@@ -0,0 +1,20 @@
+CREATE TABLE scim_groups (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    display_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_scim_groups_org_id_display_name ON scim_groups (org_id, lower(display_name));
+CREATE INDEX idx_scim_groups_org_id_external_id ON scim_groups (org_id, external_id);
+CREATE INDEX idx_scim_groups_org_id_created_at ON scim_groups (org_id, created_at);
+
+ALTER TABLE scim_groups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scim_groups FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON scim_groups
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
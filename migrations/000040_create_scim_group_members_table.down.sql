@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON scim_group_members;
+DROP TABLE IF EXISTS scim_group_members;
@@ -0,0 +1,16 @@
+CREATE TABLE scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES scim_users(user_id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX idx_scim_group_members_user_id ON scim_group_members (user_id);
+
+ALTER TABLE scim_group_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scim_group_members FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON scim_group_members
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
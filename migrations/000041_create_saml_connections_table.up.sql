@@ -0,0 +1,19 @@
+-- The public SAML endpoints look a connection up by id before the tenant
+-- is known, so like scim_tokens this table is left out of row-level
+-- security; the repository scopes every other query to the tenant itself.
+CREATE TABLE saml_connections (
+    id UUID PRIMARY KEY,
+    org_id UUID UNIQUE NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    idp_entity_id VARCHAR(1024) NOT NULL,
+    idp_sso_url VARCHAR(2048) NOT NULL,
+    idp_certificates TEXT[] NOT NULL,
+    sp_private_key BYTEA NOT NULL,
+    sp_certificate TEXT NOT NULL,
+    attribute_mapping JSONB NOT NULL DEFAULT '{}',
+    allow_idp_initiated BOOLEAN NOT NULL DEFAULT FALSE,
+    jit_provisioning BOOLEAN NOT NULL DEFAULT FALSE,
+    require_encrypted_assertions BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
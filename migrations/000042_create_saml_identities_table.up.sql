@@ -0,0 +1,20 @@
+-- Links the subject an identity provider asserts to the account it signs
+-- in, so a later change of email at the provider keeps the same account.
+CREATE TABLE saml_identities (
+    connection_id UUID NOT NULL REFERENCES saml_connections(id) ON DELETE CASCADE,
+    name_id VARCHAR(1024) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (connection_id, name_id)
+);
+
+CREATE UNIQUE INDEX idx_saml_identities_connection_id_user_id ON saml_identities (connection_id, user_id);
+
+ALTER TABLE saml_identities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE saml_identities FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON saml_identities
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
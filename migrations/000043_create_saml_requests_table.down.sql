@@ -0,0 +1,2 @@
+DROP POLICY IF EXISTS tenant_isolation ON saml_requests;
+DROP TABLE IF EXISTS saml_requests;
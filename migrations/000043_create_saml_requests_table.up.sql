@@ -0,0 +1,18 @@
+-- Authentication requests awaiting a response, so a response can only
+-- answer a request this service made, and only once.
+CREATE TABLE saml_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    connection_id UUID NOT NULL REFERENCES saml_connections(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_saml_requests_expires_at ON saml_requests (expires_at);
+
+ALTER TABLE saml_requests ENABLE ROW LEVEL SECURITY;
+ALTER TABLE saml_requests FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON saml_requests
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
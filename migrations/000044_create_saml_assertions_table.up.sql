@@ -0,0 +1,18 @@
+-- Assertions already consumed, kept until they expire so a captured
+-- response cannot be replayed.
+CREATE TABLE saml_assertions (
+    connection_id UUID NOT NULL REFERENCES saml_connections(id) ON DELETE CASCADE,
+    assertion_id VARCHAR(255) NOT NULL,
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (connection_id, assertion_id)
+);
+
+CREATE INDEX idx_saml_assertions_expires_at ON saml_assertions (expires_at);
+
+ALTER TABLE saml_assertions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE saml_assertions FORCE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON saml_assertions
+    USING (org_id = app_current_org_id())
+    WITH CHECK (org_id = app_current_org_id());
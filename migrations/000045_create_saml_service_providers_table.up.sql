@@ -0,0 +1,18 @@
+-- Service providers the identity provider signs users in to. They are
+-- registered by admins and shared by every organization, like webhooks.
+CREATE TABLE saml_service_providers (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    entity_id VARCHAR(1024) UNIQUE NOT NULL,
+    acs_url VARCHAR(2048) NOT NULL,
+    slo_url VARCHAR(2048) NOT NULL DEFAULT '',
+    certificate TEXT NOT NULL DEFAULT '',
+    name_id_format VARCHAR(16) NOT NULL CHECK (name_id_format IN ('persistent', 'email')),
+    attribute_mapping JSONB NOT NULL DEFAULT '{}',
+    allow_idp_initiated BOOLEAN NOT NULL DEFAULT FALSE,
+    require_signed_requests BOOLEAN NOT NULL DEFAULT FALSE,
+    encrypt_assertions BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,8 @@
+-- Self-signed certificates wrapping the token signing keys, which is how
+-- service providers expect to be given the identity provider keys. They
+-- are made once per key and shared, so every replica publishes the same.
+CREATE TABLE saml_idp_certificates (
+    key_id VARCHAR(64) PRIMARY KEY REFERENCES signing_keys(id) ON DELETE CASCADE,
+    certificate BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,13 @@
+-- Sign-ins awaiting the credentials of the user, for a request of a
+-- service provider or one started at the identity provider.
+CREATE TABLE saml_idp_requests (
+    id VARCHAR(64) PRIMARY KEY,
+    service_provider_id UUID NOT NULL REFERENCES saml_service_providers(id) ON DELETE CASCADE,
+    request_id VARCHAR(256) NOT NULL DEFAULT '',
+    name_id_format VARCHAR(16) NOT NULL,
+    relay_state TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_saml_idp_requests_expires_at ON saml_idp_requests (expires_at);
@@ -0,0 +1,14 @@
+-- Sessions service providers were given by an assertion, so single logout
+-- knows which of them to tell when the user signs out of one.
+CREATE TABLE saml_idp_sessions (
+    session_index VARCHAR(64) PRIMARY KEY,
+    service_provider_id UUID NOT NULL REFERENCES saml_service_providers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name_id VARCHAR(1024) NOT NULL,
+    name_id_format VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_saml_idp_sessions_user_id ON saml_idp_sessions (user_id);
+CREATE INDEX idx_saml_idp_sessions_expires_at ON saml_idp_sessions (expires_at);
@@ -0,0 +1,15 @@
+-- Single logouts going around the service providers of a user, one at a
+-- time, before the one that asked is answered.
+CREATE TABLE saml_idp_logouts (
+    id VARCHAR(64) PRIMARY KEY,
+    service_provider_id UUID NOT NULL REFERENCES saml_service_providers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    request_id VARCHAR(256) NOT NULL,
+    relay_state TEXT NOT NULL DEFAULT '',
+    pending_request_id VARCHAR(64) NOT NULL DEFAULT '',
+    partial BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_saml_idp_logouts_expires_at ON saml_idp_logouts (expires_at);
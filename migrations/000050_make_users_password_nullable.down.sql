@@ -0,0 +1,6 @@
+-- An empty hash matches no password, so passwordless accounts stay locked
+-- to their identity providers.
+UPDATE users SET password = '' WHERE password IS NULL;
+
+ALTER TABLE users
+    ALTER COLUMN password SET NOT NULL;
@@ -0,0 +1,4 @@
+-- Accounts that only sign in through an external identity provider have
+-- no password.
+ALTER TABLE users
+    ALTER COLUMN password DROP NOT NULL;
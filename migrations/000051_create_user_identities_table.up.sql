@@ -0,0 +1,15 @@
+-- Links the subject an external identity provider knows a user by to the
+-- account it signs in, so a later change of email at the provider keeps
+-- the same account.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_login_at TIMESTAMP NOT NULL,
+    UNIQUE (provider, subject)
+);
+
+CREATE UNIQUE INDEX idx_user_identities_user_id_provider ON user_identities (user_id, provider);
@@ -0,0 +1,16 @@
+-- Authorization requests awaiting their callback, keyed by the state sent
+-- to the provider, so a callback can only answer a request this service
+-- made, and only once. user_id is set when a signed-in user links a
+-- provider rather than signing in with it.
+CREATE TABLE federation_requests (
+    state VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    return_to TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_federation_requests_expires_at ON federation_requests (expires_at);
@@ -0,0 +1,11 @@
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    REVOKE USAGE, SELECT ON SEQUENCES FROM auth_service_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM auth_service_app;
+
+REVOKE USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public FROM auth_service_app;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public FROM auth_service_app;
+REVOKE USAGE ON SCHEMA public FROM auth_service_app;
+
+-- The role is left in place: login roles are members of it and roles are
+-- shared by every database in the cluster.
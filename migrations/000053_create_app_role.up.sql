@@ -0,0 +1,31 @@
+-- The role the server connects as. Row-level security does not apply to
+-- superusers or to the owner of a table without FORCE, so the server gets a
+-- role of its own that owns nothing and only holds the privileges below.
+-- Login roles join it with CREATE ROLE ... IN ROLE auth_service_app (see
+-- deployment/postgres/init-app-role.sh).
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'auth_service_app') THEN
+        CREATE ROLE auth_service_app NOLOGIN NOSUPERUSER NOBYPASSRLS;
+    END IF;
+END
+$$;
+
+GRANT USAGE ON SCHEMA public TO auth_service_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO auth_service_app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO auth_service_app;
+
+-- Tables created by later migrations, which run as the same owner.
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO auth_service_app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public
+    GRANT USAGE, SELECT ON SEQUENCES TO auth_service_app;
+
+-- The migration history belongs to the migrator alone.
+DO $$
+BEGIN
+    IF to_regclass('public.schema_migrations') IS NOT NULL THEN
+        REVOKE ALL ON schema_migrations FROM auth_service_app;
+    END IF;
+END
+$$;
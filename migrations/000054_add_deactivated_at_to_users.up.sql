@@ -0,0 +1,11 @@
+-- Set while an identity provider has the account turned off over SCIM.
+-- Sign-in runs outside any tenant, where scim_users shows no rows, so the
+-- flag lives on the user. The backfill reads every organization and only
+-- does so as a role that bypasses row security, like the migrator's.
+ALTER TABLE users
+    ADD COLUMN deactivated_at TIMESTAMP;
+
+UPDATE users u
+SET deactivated_at = s.updated_at
+FROM scim_users s
+WHERE s.user_id = u.id AND NOT s.active;
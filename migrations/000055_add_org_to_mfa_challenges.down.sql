@@ -0,0 +1,2 @@
+ALTER TABLE mfa_challenges
+    DROP COLUMN IF EXISTS org_id;
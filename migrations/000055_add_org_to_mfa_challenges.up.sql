@@ -0,0 +1,4 @@
+-- The organization a login through an organization's identity provider
+-- acts in, carried over to the session once the second factor is passed.
+ALTER TABLE mfa_challenges
+    ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE CASCADE;
@@ -1,12 +1,19 @@
 package httperr
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
 
 type HttpError struct {
 	Message string   `json:"message"`
 	Err     string   `json:"error"`
 	Code    int      `json:"code"`
 	Causes  []Causes `json:"causes"`
+	// RetryAfter is the number of seconds the client should wait before
+	// trying again. It is only set on 429 responses.
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 type Causes struct {
@@ -18,6 +25,14 @@ func (r *HttpError) Error() string {
 	return r.Message
 }
 
+// WriteHeaders sets the response headers the error implies, such as
+// Retry-After. Call it before writing the body.
+func (r *HttpError) WriteHeaders(header http.Header) {
+	if r.RetryAfter > 0 {
+		header.Set("Retry-After", strconv.Itoa(r.RetryAfter))
+	}
+}
+
 func NewBadRequestError(message string) *HttpError {
 	return &HttpError{
 		Message: message,
@@ -65,4 +80,16 @@ func NewForbiddenError(message string) *HttpError {
 		Err:     "forbidden",
 		Code:    http.StatusForbidden,
 	}
-}
\ No newline at end of file
+}
+
+// NewTooManyRequestsError rejects a request until retryAfter has passed,
+// rounded up to whole seconds.
+func NewTooManyRequestsError(message string, retryAfter time.Duration) *HttpError {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	return &HttpError{
+		Message:    message,
+		Err:        "too_many_requests",
+		Code:       http.StatusTooManyRequests,
+		RetryAfter: max(seconds, 1),
+	}
+}
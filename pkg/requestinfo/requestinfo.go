@@ -0,0 +1,52 @@
+package requestinfo
+
+import "context"
+
+// Kinds of principal a request can act as.
+const (
+	ActorUser   = "user"
+	ActorClient = "client"
+	ActorAdmin  = "admin"
+	ActorSCIM   = "scim"
+)
+
+type (
+	infoKey  struct{}
+	actorKey struct{}
+)
+
+// Info describes where a request came from. IP is resolved by the HTTP
+// server from its trusted proxies.
+type Info struct {
+	IP        string
+	UserAgent string
+}
+
+// Actor is the principal a request authenticated as: a user, an OAuth
+// client or service account by client_id, the admin API token, or an
+// organization's SCIM token by its id.
+type Actor struct {
+	Type string
+	ID   string
+}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the request's origin, or the zero Info outside of a
+// request.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns who the request authenticated as, if anyone.
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/felipeversiane/auth-service/pkg/httperr"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func Setup() {
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(jsonTagName)
+		_ = engine.RegisterValidation("maxbytes", maxBytes)
+	}
+}
+
+func ValidateBinding(err error) *httperr.HttpError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return httperr.NewBadRequestValidationError("some fields are invalid", []httperr.Causes{
+			{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type.String())},
+		})
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		causes := make([]httperr.Causes, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			causes = append(causes, httperr.Causes{
+				Field:   fieldErr.Field(),
+				Message: message(fieldErr),
+			})
+		}
+		return httperr.NewBadRequestValidationError("some fields are invalid", causes)
+	}
+
+	return httperr.NewBadRequestError("invalid request body")
+}
+
+func message(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "e164":
+		return "must be a valid phone number in E.164 format"
+	case "min":
+		return fmt.Sprintf("must have at least %s characters", fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("must have at most %s characters", fieldErr.Param())
+	case "maxbytes":
+		return fmt.Sprintf("must have at most %s bytes", fieldErr.Param())
+	case "len":
+		return fmt.Sprintf("must have exactly %s characters", fieldErr.Param())
+	case "numeric":
+		return "must contain only digits"
+	case "ip":
+		return "must be a valid IP address"
+	case "required_without":
+		return fmt.Sprintf("is required when %s is missing", strings.ToLower(fieldErr.Param()))
+	default:
+		return fmt.Sprintf("failed on the '%s' validation", fieldErr.Tag())
+	}
+}
+
+// maxBytes bounds the length of a string in bytes, where max counts
+// characters; bcrypt, for one, stops at 72 bytes.
+func maxBytes(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+	return len(fl.Field().String()) <= limit
+}
+
+func jsonTagName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
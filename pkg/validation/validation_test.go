@@ -0,0 +1,29 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestMaxBytesCountsBytes(t *testing.T) {
+	Setup()
+
+	type request struct {
+		Password string `json:"password" binding:"max=72,maxbytes=72"`
+	}
+
+	if err := binding.Validator.ValidateStruct(request{Password: strings.Repeat("a", 72)}); err != nil {
+		t.Fatalf("72 ASCII characters: error = %v", err)
+	}
+
+	err := binding.Validator.ValidateStruct(request{Password: strings.Repeat("é", 40)})
+	if err == nil {
+		t.Fatal("40 two-byte characters: no error")
+	}
+	restErr := ValidateBinding(err)
+	if len(restErr.Causes) != 1 || restErr.Causes[0].Field != "password" || restErr.Causes[0].Message != "must have at most 72 bytes" {
+		t.Fatalf("causes = %+v", restErr.Causes)
+	}
+}
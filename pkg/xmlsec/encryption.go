@@ -0,0 +1,246 @@
+package xmlsec
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	NamespaceXMLEnc   = "http://www.w3.org/2001/04/xmlenc#"
+	NamespaceXMLEnc11 = "http://www.w3.org/2009/xmlenc11#"
+)
+
+// Key transport and block encryption methods. RSA PKCS#1 v1.5 key
+// transport is deliberately missing: it is open to padding oracles.
+// crewjam/saml's xmlenc accepts it, and its GCM encryption does not work,
+// hence these few functions.
+const (
+	KeyTransportRSAOAEPMGF1P = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	KeyTransportRSAOAEP      = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+
+	BlockAES128CBC = "http://www.w3.org/2001/04/xmlenc#aes128-cbc"
+	BlockAES192CBC = "http://www.w3.org/2001/04/xmlenc#aes192-cbc"
+	BlockAES256CBC = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	BlockAES128GCM = "http://www.w3.org/2009/xmlenc11#aes128-gcm"
+	BlockAES192GCM = "http://www.w3.org/2009/xmlenc11#aes192-gcm"
+	BlockAES256GCM = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+
+	mgf1SHA1   = "http://www.w3.org/2009/xmlenc11#mgf1sha1"
+	mgf1SHA256 = "http://www.w3.org/2009/xmlenc11#mgf1sha256"
+	mgf1SHA512 = "http://www.w3.org/2009/xmlenc11#mgf1sha512"
+
+	digestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	digestSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	digestSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var ErrDecryption = errors.New("decryption failed")
+
+// Decrypt decrypts the EncryptedData below container, an element such as
+// a SAML EncryptedAssertion, with key, and parses the plaintext. The
+// EncryptedKey is looked up in the KeyInfo of the data, or next to it.
+func Decrypt(container *etree.Element, key *rsa.PrivateKey) (*etree.Element, error) {
+	data := Child(container, NamespaceXMLEnc, "EncryptedData")
+	if data == nil {
+		return nil, fmt.Errorf("%w: no encrypted data", ErrDecryption)
+	}
+
+	encryptedKey := Child(Child(data, NamespaceDSig, "KeyInfo"), NamespaceXMLEnc, "EncryptedKey")
+	if encryptedKey == nil {
+		encryptedKey = Child(container, NamespaceXMLEnc, "EncryptedKey")
+	}
+	if encryptedKey == nil {
+		return nil, fmt.Errorf("%w: no encrypted key", ErrDecryption)
+	}
+
+	sessionKey, err := decryptKey(encryptedKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decryptData(data, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	el, err := Parse(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+	return el, nil
+}
+
+func decryptKey(encryptedKey *etree.Element, key *rsa.PrivateKey) ([]byte, error) {
+	method := Child(encryptedKey, NamespaceXMLEnc, "EncryptionMethod")
+
+	options := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	switch algorithm := Attr(method, "Algorithm"); algorithm {
+	case KeyTransportRSAOAEPMGF1P:
+	case KeyTransportRSAOAEP:
+		switch mgf := Attr(Child(method, NamespaceXMLEnc11, "MGF"), "Algorithm"); mgf {
+		case "", mgf1SHA1:
+		case mgf1SHA256:
+			options.MGFHash = crypto.SHA256
+		case mgf1SHA512:
+			options.MGFHash = crypto.SHA512
+		default:
+			return nil, fmt.Errorf("%w: unsupported mask generation function %s", ErrDecryption, mgf)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key transport %s", ErrDecryption, algorithm)
+	}
+
+	if digest := Child(method, NamespaceDSig, "DigestMethod"); digest != nil {
+		switch algorithm := Attr(digest, "Algorithm"); algorithm {
+		case digestSHA1:
+		case digestSHA256:
+			options.Hash = crypto.SHA256
+		case digestSHA512:
+			options.Hash = crypto.SHA512
+		default:
+			return nil, fmt.Errorf("%w: unsupported digest method %s", ErrDecryption, algorithm)
+		}
+	}
+
+	ciphertext, err := decodeBase64(cipherValue(encryptedKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: key is not base64", ErrDecryption)
+	}
+
+	sessionKey, err := key.Decrypt(nil, ciphertext, options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+	return sessionKey, nil
+}
+
+func decryptData(data *etree.Element, sessionKey []byte) ([]byte, error) {
+	algorithm := Attr(Child(data, NamespaceXMLEnc, "EncryptionMethod"), "Algorithm")
+	size, gcm := blockMethod(algorithm)
+	if size == 0 {
+		return nil, fmt.Errorf("%w: unsupported block encryption %s", ErrDecryption, algorithm)
+	}
+	if len(sessionKey) != size {
+		return nil, fmt.Errorf("%w: key size does not match %s", ErrDecryption, algorithm)
+	}
+
+	ciphertext, err := decodeBase64(cipherValue(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: data is not base64", ErrDecryption)
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+	}
+
+	if gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+		}
+		if len(ciphertext) < aead.NonceSize() {
+			return nil, fmt.Errorf("%w: data too short", ErrDecryption)
+		}
+		plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecryption, err)
+		}
+		return plaintext, nil
+	}
+
+	// CBC data is the IV followed by the blocks; the last byte of the
+	// plaintext counts the padding, whose other bytes are arbitrary.
+	if len(ciphertext) < 2*aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: data has an invalid length", ErrDecryption)
+	}
+	plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, ciphertext[:aes.BlockSize]).CryptBlocks(plaintext, ciphertext[aes.BlockSize:])
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("%w: invalid padding", ErrDecryption)
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func cipherValue(el *etree.Element) string {
+	return Text(Child(Child(el, NamespaceXMLEnc, "CipherData"), NamespaceXMLEnc, "CipherValue"))
+}
+
+// blockMethod returns the key size of a block encryption method, zero when
+// unsupported, and whether it is GCM.
+func blockMethod(algorithm string) (int, bool) {
+	switch algorithm {
+	case BlockAES128CBC:
+		return 16, false
+	case BlockAES192CBC:
+		return 24, false
+	case BlockAES256CBC:
+		return 32, false
+	case BlockAES128GCM:
+		return 16, true
+	case BlockAES192GCM:
+		return 24, true
+	case BlockAES256GCM:
+		return 32, true
+	}
+	return 0, false
+}
+
+// Encrypt encrypts plaintext with AES-256-GCM under a fresh key, which is
+// transported with RSA-OAEP to the key of cert, and returns the
+// EncryptedData element.
+func Encrypt(plaintext []byte, cert *x509.Certificate) (*etree.Element, error) {
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("encryption requires an RSA certificate")
+	}
+
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, nil)
+
+	encryptedKey, err := rsa.EncryptOAEP(crypto.SHA1.New(), rand.Reader, publicKey, sessionKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data := NewElement(NamespaceXMLEnc, "xenc", "EncryptedData")
+	data.CreateAttr("Type", "http://www.w3.org/2001/04/xmlenc#Element")
+	NewChild(data, "EncryptionMethod").CreateAttr("Algorithm", BlockAES256GCM)
+
+	keyInfo := NewElement(NamespaceDSig, "ds", "KeyInfo")
+	data.AddChild(keyInfo)
+	keyElement := NewElement(NamespaceXMLEnc, "xenc", "EncryptedKey")
+	keyInfo.AddChild(keyElement)
+	NewChild(keyElement, "EncryptionMethod").CreateAttr("Algorithm", KeyTransportRSAOAEPMGF1P)
+	NewTextChild(NewChild(keyElement, "CipherData"), "CipherValue", base64.StdEncoding.EncodeToString(encryptedKey))
+
+	NewTextChild(NewChild(data, "CipherData"), "CipherValue", base64.StdEncoding.EncodeToString(ciphertext))
+
+	return data, nil
+}
@@ -0,0 +1,320 @@
+package xmlsec
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const testDocument = `<Response xmlns="urn:test" xmlns:x="urn:x" ID="_r"><Issuer>https://idp.example.com</Issuer><x:Subject x:format="email">jane@example.com</x:Subject></Response>`
+
+func TestVerifyGoxmldsigSignatures(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+
+	methods := []string{
+		dsig.RSASHA1SignatureMethod,
+		dsig.RSASHA256SignatureMethod,
+		dsig.RSASHA512SignatureMethod,
+	}
+	canonicalizers := []dsig.Canonicalizer{
+		dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList(""),
+		dsig.MakeC14N10RecCanonicalizer(),
+		dsig.MakeC14N11Canonicalizer(),
+	}
+
+	for _, method := range methods {
+		for _, canonicalizer := range canonicalizers {
+			root, err := Parse(signWithGoxmldsig(t, testDocument, key, cert, method, canonicalizer))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			signed, err := Verify(root, []*x509.Certificate{cert}, time.Now())
+			if err != nil {
+				t.Errorf("Verify(%s, %s) error = %v", method, canonicalizer.Algorithm(), err)
+				continue
+			}
+			if Child(signed, NamespaceDSig, "Signature") != nil {
+				t.Error("Verify() returned the signature along with the signed element")
+			}
+			if got := Text(Child(signed, "urn:x", "Subject")); got != "jane@example.com" {
+				t.Errorf("signed Subject = %q", got)
+			}
+		}
+	}
+}
+
+func TestVerifyInheritedNamespaces(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+
+	// The assertion is signed on its own, then placed in a response that
+	// declares the namespace it uses, as identity providers do.
+	assertion := signWithGoxmldsig(t, `<saml:Assertion xmlns:saml="urn:saml" ID="_a"><saml:Issuer>idp</saml:Issuer></saml:Assertion>`,
+		key, cert, dsig.RSASHA256SignatureMethod, dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList(""))
+	inner := strings.Replace(string(assertion), ` xmlns:saml="urn:saml"`, "", 1)
+	root, err := Parse([]byte(`<saml:Response xmlns:saml="urn:saml" ID="_r">` + inner + `</saml:Response>`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	signed, err := Verify(Child(root, "urn:saml", "Assertion"), []*x509.Certificate{cert}, time.Now())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !Is(signed, "urn:saml", "Assertion") || Text(Child(signed, "urn:saml", "Issuer")) != "idp" {
+		t.Fatalf("signed = %s", Bytes(signed))
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+	_, otherCert := newTestKeyPair(t)
+	signed := signWithGoxmldsig(t, testDocument, key, cert, dsig.RSASHA256SignatureMethod,
+		dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList(""))
+
+	tests := []struct {
+		name   string
+		certs  []*x509.Certificate
+		now    time.Time
+		tamper func(root *etree.Element)
+		want   error
+	}{
+		{
+			name: "tampered content",
+			tamper: func(root *etree.Element) {
+				Child(root, "urn:test", "Issuer").SetText("https://evil.example.com")
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "content added after signing",
+			tamper: func(root *etree.Element) {
+				NewTextChild(root, "Issuer", "https://evil.example.com")
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "tampered signed info",
+			tamper: func(root *etree.Element) {
+				signedInfo := Child(Child(root, NamespaceDSig, "Signature"), NamespaceDSig, "SignedInfo")
+				Child(signedInfo, NamespaceDSig, "SignatureMethod").CreateAttr("Algorithm", dsig.RSASHA512SignatureMethod)
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "tampered signature value",
+			tamper: func(root *etree.Element) {
+				value := Child(Child(root, NamespaceDSig, "Signature"), NamespaceDSig, "SignatureValue")
+				value.SetText("AAAA" + Text(value)[4:])
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name:   "untrusted certificate",
+			certs:  []*x509.Certificate{otherCert},
+			tamper: func(root *etree.Element) {},
+			want:   ErrInvalidSignature,
+		},
+		{
+			name:   "expired certificate",
+			now:    time.Now().Add(2 * time.Hour),
+			tamper: func(root *etree.Element) {},
+			want:   ErrInvalidSignature,
+		},
+		{
+			name: "reference to another element",
+			tamper: func(root *etree.Element) {
+				root.CreateAttr("ID", "_other")
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "no ID",
+			tamper: func(root *etree.Element) {
+				root.RemoveAttr("ID")
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "no signature",
+			tamper: func(root *etree.Element) {
+				root.RemoveChild(Child(root, NamespaceDSig, "Signature"))
+			},
+			want: ErrNoSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := Parse(signed)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			certs := tt.certs
+			if certs == nil {
+				certs = []*x509.Certificate{cert}
+			}
+			now := tt.now
+			if now.IsZero() {
+				now = time.Now()
+			}
+
+			tt.tamper(root)
+			if _, err := Verify(root, certs, now); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAcceptsAnyTrustedCertificate(t *testing.T) {
+	key, cert := newTestKeyPair(t)
+	_, otherCert := newTestKeyPair(t)
+
+	root, err := Parse(signWithGoxmldsig(t, testDocument, key, cert, dsig.RSASHA256SignatureMethod,
+		dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// Without a KeyInfo the certificate is not named, so each is tried.
+	signature := Child(root, NamespaceDSig, "Signature")
+	signature.RemoveChild(Child(signature, NamespaceDSig, "KeyInfo"))
+
+	if _, err := Verify(root, []*x509.Certificate{otherCert, cert}, time.Now()); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestVerifyDetached(t *testing.T) {
+	rsaKey, rsaCert := newTestKeyPair(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	ecCert := newTestCertificate(t, ecKey)
+
+	message := []byte("SAMLRequest=abc&RelayState=xyz&SigAlg=alg")
+	digest := sha256.Sum256(message)
+
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("rsa.SignPKCS1v15() error = %v", err)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatalf("ecdsa.Sign() error = %v", err)
+	}
+	ecSignature := make([]byte, 64)
+	r.FillBytes(ecSignature[:32])
+	s.FillBytes(ecSignature[32:])
+
+	tests := []struct {
+		name      string
+		algorithm string
+		cert      *x509.Certificate
+		message   []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{"rsa", dsig.RSASHA256SignatureMethod, rsaCert, message, rsaSignature, false},
+		{"ecdsa", dsig.ECDSASHA256SignatureMethod, ecCert, message, ecSignature, false},
+		{"tampered message", dsig.RSASHA256SignatureMethod, rsaCert, []byte("SAMLRequest=abd"), rsaSignature, true},
+		{"other key", dsig.RSASHA256SignatureMethod, ecCert, message, rsaSignature, true},
+		{"algorithm of another key type", dsig.ECDSASHA256SignatureMethod, rsaCert, message, rsaSignature, true},
+		{"unsupported algorithm", "http://www.w3.org/2000/09/xmldsig#dsa-sha1", rsaCert, message, rsaSignature, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyDetached(tt.algorithm, tt.cert, tt.message, tt.signature)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("VerifyDetached() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("VerifyDetached() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestParseRefusesDocumentTypes(t *testing.T) {
+	_, err := Parse([]byte(`<!DOCTYPE r [<!ENTITY e "x">]><r>&e;</r>`))
+	if !errors.Is(err, ErrMalformed) {
+		t.Fatalf("Parse() error = %v, want ErrMalformed", err)
+	}
+}
+
+// signWithGoxmldsig adds an enveloped signature to the root of document
+// with goxmldsig, as an identity provider would.
+func signWithGoxmldsig(t *testing.T, document string, key *rsa.PrivateKey, cert *x509.Certificate, method string, canonicalizer dsig.Canonicalizer) []byte {
+	t.Helper()
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(document); err != nil {
+		t.Fatalf("ReadFromString() error = %v", err)
+	}
+
+	ctx, err := dsig.NewSigningContext(key, [][]byte{cert.Raw})
+	if err != nil {
+		t.Fatalf("NewSigningContext() error = %v", err)
+	}
+	if err := ctx.SetSignatureMethod(method); err != nil {
+		t.Fatalf("SetSignatureMethod() error = %v", err)
+	}
+	ctx.Canonicalizer = canonicalizer
+
+	signed, err := ctx.SignEnveloped(doc.Root())
+	if err != nil {
+		t.Fatalf("SignEnveloped() error = %v", err)
+	}
+	doc.SetRoot(signed)
+
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatalf("WriteToBytes() error = %v", err)
+	}
+	return data
+}
+
+func newTestKeyPair(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	return key, newTestCertificate(t, key)
+}
+
+func newTestCertificate(t *testing.T, signer crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate() error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate() error = %v", err)
+	}
+	return cert
+}